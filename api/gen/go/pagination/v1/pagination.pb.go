@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.10
+// 	protoc-gen-go v1.36.11
 // 	protoc        (unknown)
 // source: pagination/v1/pagination.proto
 
@@ -344,12 +344,72 @@ func (Sorting_Order) EnumDescriptor() ([]byte, []int) {
 	return file_pagination_v1_pagination_proto_rawDescGZIP(), []int{0, 0}
 }
 
+// 空值位置（默认使用数据库的默认行为）
+type Sorting_NullsPosition int32
+
+const (
+	Sorting_NULLS_DEFAULT Sorting_NullsPosition = 0
+	Sorting_NULLS_FIRST   Sorting_NullsPosition = 1
+	Sorting_NULLS_LAST    Sorting_NullsPosition = 2
+)
+
+// Enum value maps for Sorting_NullsPosition.
+var (
+	Sorting_NullsPosition_name = map[int32]string{
+		0: "NULLS_DEFAULT",
+		1: "NULLS_FIRST",
+		2: "NULLS_LAST",
+	}
+	Sorting_NullsPosition_value = map[string]int32{
+		"NULLS_DEFAULT": 0,
+		"NULLS_FIRST":   1,
+		"NULLS_LAST":    2,
+	}
+)
+
+func (x Sorting_NullsPosition) Enum() *Sorting_NullsPosition {
+	p := new(Sorting_NullsPosition)
+	*p = x
+	return p
+}
+
+func (x Sorting_NullsPosition) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Sorting_NullsPosition) Descriptor() protoreflect.EnumDescriptor {
+	return file_pagination_v1_pagination_proto_enumTypes[4].Descriptor()
+}
+
+func (Sorting_NullsPosition) Type() protoreflect.EnumType {
+	return &file_pagination_v1_pagination_proto_enumTypes[4]
+}
+
+func (x Sorting_NullsPosition) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Sorting_NullsPosition.Descriptor instead.
+func (Sorting_NullsPosition) EnumDescriptor() ([]byte, []int) {
+	return file_pagination_v1_pagination_proto_rawDescGZIP(), []int{0, 1}
+}
+
 // 排序规则（分页场景通常需配合排序保证结果稳定）
 type Sorting struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// 排序字段（如"id"、"create_time"）
-	Field         string        `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
-	Order         Sorting_Order `protobuf:"varint,2,opt,name=order,proto3,enum=pagination.Sorting_Order" json:"order,omitempty"`
+	Field string        `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Order Sorting_Order `protobuf:"varint,2,opt,name=order,proto3,enum=pagination.Sorting_Order" json:"order,omitempty"`
+	// 空值排在最前或最后
+	Nulls Sorting_NullsPosition `protobuf:"varint,3,opt,name=nulls,proto3,enum=pagination.Sorting_NullsPosition" json:"nulls,omitempty"`
+	// 忽略大小写（按 LOWER(field) 排序）
+	CaseInsensitive bool `protobuf:"varint,4,opt,name=case_insensitive,json=caseInsensitive,proto3" json:"case_insensitive,omitempty"`
+	// 排序规则（如 Postgres 的 "C"、MySQL 的 "utf8mb4_unicode_ci"、SQLite 的 "NOCASE"）
+	Collation *string `protobuf:"bytes,5,opt,name=collation,proto3,oneof" json:"collation,omitempty"`
+	// JSON 列内的路径，以点分隔（如 field 为 "metadata"、json_path 为 "priority"）
+	JsonPath *string `protobuf:"bytes,6,opt,name=json_path,json=jsonPath,proto3,oneof" json:"json_path,omitempty"`
+	// 按日期部分排序（如按 create_time 的月份）
+	DatePart      DatePart `protobuf:"varint,7,opt,name=date_part,json=datePart,proto3,enum=pagination.DatePart" json:"date_part,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -398,6 +458,41 @@ func (x *Sorting) GetOrder() Sorting_Order {
 	return Sorting_ASC
 }
 
+func (x *Sorting) GetNulls() Sorting_NullsPosition {
+	if x != nil {
+		return x.Nulls
+	}
+	return Sorting_NULLS_DEFAULT
+}
+
+func (x *Sorting) GetCaseInsensitive() bool {
+	if x != nil {
+		return x.CaseInsensitive
+	}
+	return false
+}
+
+func (x *Sorting) GetCollation() string {
+	if x != nil && x.Collation != nil {
+		return *x.Collation
+	}
+	return ""
+}
+
+func (x *Sorting) GetJsonPath() string {
+	if x != nil && x.JsonPath != nil {
+		return *x.JsonPath
+	}
+	return ""
+}
+
+func (x *Sorting) GetDatePart() DatePart {
+	if x != nil {
+		return x.DatePart
+	}
+	return DatePart_DATE_PART_UNSPECIFIED
+}
+
 // 单个条件
 type Condition struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -1291,13 +1386,27 @@ var File_pagination_v1_pagination_proto protoreflect.FileDescriptor
 const file_pagination_v1_pagination_proto_rawDesc = "" +
 	"\n" +
 	"\x1epagination/v1/pagination.proto\x12\n" +
-	"pagination\x1a google/protobuf/field_mask.proto\x1a\x1egoogle/protobuf/wrappers.proto\x1a\x19google/protobuf/any.proto\x1a$gnostic/openapi/v3/annotations.proto\"l\n" +
+	"pagination\x1a google/protobuf/field_mask.proto\x1a\x1egoogle/protobuf/wrappers.proto\x1a\x19google/protobuf/any.proto\x1a$gnostic/openapi/v3/annotations.proto\"\xa9\x03\n" +
 	"\aSorting\x12\x14\n" +
 	"\x05field\x18\x01 \x01(\tR\x05field\x12/\n" +
-	"\x05order\x18\x02 \x01(\x0e2\x19.pagination.Sorting.OrderR\x05order\"\x1a\n" +
+	"\x05order\x18\x02 \x01(\x0e2\x19.pagination.Sorting.OrderR\x05order\x127\n" +
+	"\x05nulls\x18\x03 \x01(\x0e2!.pagination.Sorting.NullsPositionR\x05nulls\x12)\n" +
+	"\x10case_insensitive\x18\x04 \x01(\bR\x0fcaseInsensitive\x12!\n" +
+	"\tcollation\x18\x05 \x01(\tH\x00R\tcollation\x88\x01\x01\x12 \n" +
+	"\tjson_path\x18\x06 \x01(\tH\x01R\bjsonPath\x88\x01\x01\x121\n" +
+	"\tdate_part\x18\a \x01(\x0e2\x14.pagination.DatePartR\bdatePart\"\x1a\n" +
 	"\x05Order\x12\a\n" +
 	"\x03ASC\x10\x00\x12\b\n" +
-	"\x04DESC\x10\x01\"\x84\x01\n" +
+	"\x04DESC\x10\x01\"C\n" +
+	"\rNullsPosition\x12\x11\n" +
+	"\rNULLS_DEFAULT\x10\x00\x12\x0f\n" +
+	"\vNULLS_FIRST\x10\x01\x12\x0e\n" +
+	"\n" +
+	"NULLS_LAST\x10\x02B\f\n" +
+	"\n" +
+	"_collationB\f\n" +
+	"\n" +
+	"_json_path\"\x84\x01\n" +
 	"\tCondition\x12\x14\n" +
 	"\x05field\x18\x01 \x01(\tR\x05field\x12$\n" +
 	"\x02op\x18\x02 \x01(\x0e2\x14.pagination.OperatorR\x02op\x12\x19\n" +
@@ -1473,58 +1582,61 @@ func file_pagination_v1_pagination_proto_rawDescGZIP() []byte {
 	return file_pagination_v1_pagination_proto_rawDescData
 }
 
-var file_pagination_v1_pagination_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_pagination_v1_pagination_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
 var file_pagination_v1_pagination_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_pagination_v1_pagination_proto_goTypes = []any{
 	(Operator)(0),                  // 0: pagination.Operator
 	(DatePart)(0),                  // 1: pagination.DatePart
 	(ExprType)(0),                  // 2: pagination.ExprType
 	(Sorting_Order)(0),             // 3: pagination.Sorting.Order
-	(*Sorting)(nil),                // 4: pagination.Sorting
-	(*Condition)(nil),              // 5: pagination.Condition
-	(*FilterExpr)(nil),             // 6: pagination.FilterExpr
-	(*PageBasedPagination)(nil),    // 7: pagination.PageBasedPagination
-	(*OffsetBasedPagination)(nil),  // 8: pagination.OffsetBasedPagination
-	(*TokenBasedPagination)(nil),   // 9: pagination.TokenBasedPagination
-	(*NoPaging)(nil),               // 10: pagination.NoPaging
-	(*PagingRequest)(nil),          // 11: pagination.PagingRequest
-	(*PaginationResponseMeta)(nil), // 12: pagination.PaginationResponseMeta
-	(*PagingResponse)(nil),         // 13: pagination.PagingResponse
-	(*PaginationRequest)(nil),      // 14: pagination.PaginationRequest
-	(*PaginationResponse)(nil),     // 15: pagination.PaginationResponse
-	(*fieldmaskpb.FieldMask)(nil),  // 16: google.protobuf.FieldMask
-	(*wrapperspb.UInt64Value)(nil), // 17: google.protobuf.UInt64Value
-	(*wrapperspb.UInt32Value)(nil), // 18: google.protobuf.UInt32Value
-	(*anypb.Any)(nil),              // 19: google.protobuf.Any
+	(Sorting_NullsPosition)(0),     // 4: pagination.Sorting.NullsPosition
+	(*Sorting)(nil),                // 5: pagination.Sorting
+	(*Condition)(nil),              // 6: pagination.Condition
+	(*FilterExpr)(nil),             // 7: pagination.FilterExpr
+	(*PageBasedPagination)(nil),    // 8: pagination.PageBasedPagination
+	(*OffsetBasedPagination)(nil),  // 9: pagination.OffsetBasedPagination
+	(*TokenBasedPagination)(nil),   // 10: pagination.TokenBasedPagination
+	(*NoPaging)(nil),               // 11: pagination.NoPaging
+	(*PagingRequest)(nil),          // 12: pagination.PagingRequest
+	(*PaginationResponseMeta)(nil), // 13: pagination.PaginationResponseMeta
+	(*PagingResponse)(nil),         // 14: pagination.PagingResponse
+	(*PaginationRequest)(nil),      // 15: pagination.PaginationRequest
+	(*PaginationResponse)(nil),     // 16: pagination.PaginationResponse
+	(*fieldmaskpb.FieldMask)(nil),  // 17: google.protobuf.FieldMask
+	(*wrapperspb.UInt64Value)(nil), // 18: google.protobuf.UInt64Value
+	(*wrapperspb.UInt32Value)(nil), // 19: google.protobuf.UInt32Value
+	(*anypb.Any)(nil),              // 20: google.protobuf.Any
 }
 var file_pagination_v1_pagination_proto_depIdxs = []int32{
 	3,  // 0: pagination.Sorting.order:type_name -> pagination.Sorting.Order
-	0,  // 1: pagination.Condition.op:type_name -> pagination.Operator
-	2,  // 2: pagination.FilterExpr.type:type_name -> pagination.ExprType
-	5,  // 3: pagination.FilterExpr.conditions:type_name -> pagination.Condition
-	6,  // 4: pagination.FilterExpr.groups:type_name -> pagination.FilterExpr
-	4,  // 5: pagination.PagingRequest.sorting:type_name -> pagination.Sorting
-	6,  // 6: pagination.PagingRequest.filter_expr:type_name -> pagination.FilterExpr
-	16, // 7: pagination.PagingRequest.field_mask:type_name -> google.protobuf.FieldMask
-	17, // 8: pagination.PaginationResponseMeta.total:type_name -> google.protobuf.UInt64Value
-	18, // 9: pagination.PaginationResponseMeta.total_pages:type_name -> google.protobuf.UInt32Value
-	18, // 10: pagination.PaginationResponseMeta.current_page:type_name -> google.protobuf.UInt32Value
-	17, // 11: pagination.PaginationResponseMeta.current_offset:type_name -> google.protobuf.UInt64Value
-	17, // 12: pagination.PagingResponse.total:type_name -> google.protobuf.UInt64Value
-	7,  // 13: pagination.PaginationRequest.page_based:type_name -> pagination.PageBasedPagination
-	8,  // 14: pagination.PaginationRequest.offset_based:type_name -> pagination.OffsetBasedPagination
-	9,  // 15: pagination.PaginationRequest.token_based:type_name -> pagination.TokenBasedPagination
-	10, // 16: pagination.PaginationRequest.no_paging:type_name -> pagination.NoPaging
-	4,  // 17: pagination.PaginationRequest.sorting:type_name -> pagination.Sorting
-	6,  // 18: pagination.PaginationRequest.filter_expr:type_name -> pagination.FilterExpr
-	16, // 19: pagination.PaginationRequest.field_mask:type_name -> google.protobuf.FieldMask
-	12, // 20: pagination.PaginationResponse.meta:type_name -> pagination.PaginationResponseMeta
-	19, // 21: pagination.PaginationResponse.data:type_name -> google.protobuf.Any
-	22, // [22:22] is the sub-list for method output_type
-	22, // [22:22] is the sub-list for method input_type
-	22, // [22:22] is the sub-list for extension type_name
-	22, // [22:22] is the sub-list for extension extendee
-	0,  // [0:22] is the sub-list for field type_name
+	4,  // 1: pagination.Sorting.nulls:type_name -> pagination.Sorting.NullsPosition
+	1,  // 2: pagination.Sorting.date_part:type_name -> pagination.DatePart
+	0,  // 3: pagination.Condition.op:type_name -> pagination.Operator
+	2,  // 4: pagination.FilterExpr.type:type_name -> pagination.ExprType
+	6,  // 5: pagination.FilterExpr.conditions:type_name -> pagination.Condition
+	7,  // 6: pagination.FilterExpr.groups:type_name -> pagination.FilterExpr
+	5,  // 7: pagination.PagingRequest.sorting:type_name -> pagination.Sorting
+	7,  // 8: pagination.PagingRequest.filter_expr:type_name -> pagination.FilterExpr
+	17, // 9: pagination.PagingRequest.field_mask:type_name -> google.protobuf.FieldMask
+	18, // 10: pagination.PaginationResponseMeta.total:type_name -> google.protobuf.UInt64Value
+	19, // 11: pagination.PaginationResponseMeta.total_pages:type_name -> google.protobuf.UInt32Value
+	19, // 12: pagination.PaginationResponseMeta.current_page:type_name -> google.protobuf.UInt32Value
+	18, // 13: pagination.PaginationResponseMeta.current_offset:type_name -> google.protobuf.UInt64Value
+	18, // 14: pagination.PagingResponse.total:type_name -> google.protobuf.UInt64Value
+	8,  // 15: pagination.PaginationRequest.page_based:type_name -> pagination.PageBasedPagination
+	9,  // 16: pagination.PaginationRequest.offset_based:type_name -> pagination.OffsetBasedPagination
+	10, // 17: pagination.PaginationRequest.token_based:type_name -> pagination.TokenBasedPagination
+	11, // 18: pagination.PaginationRequest.no_paging:type_name -> pagination.NoPaging
+	5,  // 19: pagination.PaginationRequest.sorting:type_name -> pagination.Sorting
+	7,  // 20: pagination.PaginationRequest.filter_expr:type_name -> pagination.FilterExpr
+	17, // 21: pagination.PaginationRequest.field_mask:type_name -> google.protobuf.FieldMask
+	13, // 22: pagination.PaginationResponse.meta:type_name -> pagination.PaginationResponseMeta
+	20, // 23: pagination.PaginationResponse.data:type_name -> google.protobuf.Any
+	24, // [24:24] is the sub-list for method output_type
+	24, // [24:24] is the sub-list for method input_type
+	24, // [24:24] is the sub-list for extension type_name
+	24, // [24:24] is the sub-list for extension extendee
+	0,  // [0:24] is the sub-list for field type_name
 }
 
 func init() { file_pagination_v1_pagination_proto_init() }
@@ -1532,6 +1644,7 @@ func file_pagination_v1_pagination_proto_init() {
 	if File_pagination_v1_pagination_proto != nil {
 		return
 	}
+	file_pagination_v1_pagination_proto_msgTypes[0].OneofWrappers = []any{}
 	file_pagination_v1_pagination_proto_msgTypes[1].OneofWrappers = []any{}
 	file_pagination_v1_pagination_proto_msgTypes[7].OneofWrappers = []any{}
 	file_pagination_v1_pagination_proto_msgTypes[8].OneofWrappers = []any{}
@@ -1547,7 +1660,7 @@ func file_pagination_v1_pagination_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pagination_v1_pagination_proto_rawDesc), len(file_pagination_v1_pagination_proto_rawDesc)),
-			NumEnums:      4,
+			NumEnums:      5,
 			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   0,
@@ -2,6 +2,8 @@ package cassandra
 
 import (
 	"github.com/gocql/gocql"
+
+	"github.com/tx7do/go-crud/tracing"
 )
 
 func NewCassandraClient(opts ...Option) *gocql.Session {
@@ -33,6 +35,15 @@ func NewCassandraClient(opts ...Option) *gocql.Session {
 	// 禁止主机查找
 	clusterConfig.DisableInitialHostLookup = o.DisableInitialHostLookup
 
+	// 链路追踪
+	if o.EnableTrace {
+		observer := newTracingObserver(tracing.NewTracer("cassandra",
+			append([]tracing.Option{tracing.WithNamespace(o.Keyspace)}, o.TracingOptions...)...,
+		))
+		clusterConfig.QueryObserver = observer
+		clusterConfig.BatchObserver = observer
+	}
+
 	session, err := clusterConfig.CreateSession()
 	if err != nil {
 		o.Logger.Fatalf("failed opening connection to cassandra: %v", err)
@@ -5,6 +5,10 @@ import (
 	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tx7do/go-crud/tracing"
 )
 
 type options struct {
@@ -25,6 +29,9 @@ type options struct {
 
 	DisableInitialHostLookup bool
 	IgnorePeerAddr           bool
+
+	EnableTrace    bool
+	TracingOptions []tracing.Option
 }
 
 type Option func(o *options)
@@ -94,3 +101,33 @@ func WithIgnorePeerAddr(ignore bool) Option {
 		o.IgnorePeerAddr = ignore
 	}
 }
+
+func WithEnableTrace(enable bool) Option {
+	return func(o *options) {
+		o.EnableTrace = enable
+	}
+}
+
+func WithTracingOptions(opts ...tracing.Option) Option {
+	return func(o *options) {
+		o.TracingOptions = append(o.TracingOptions, opts...)
+	}
+}
+
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.TracingOptions = append(o.TracingOptions, tracing.WithTracerProvider(provider))
+	}
+}
+
+func WithTracingAttributes(attrs ...attribute.KeyValue) Option {
+	return func(o *options) {
+		o.TracingOptions = append(o.TracingOptions, tracing.WithAttributes(attrs...))
+	}
+}
+
+func WithTracingDBSystem(name string) Option {
+	return func(o *options) {
+		o.TracingOptions = append(o.TracingOptions, tracing.WithDBSystem(name))
+	}
+}
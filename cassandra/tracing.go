@@ -0,0 +1,59 @@
+package cassandra
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/tx7do/go-crud/tracing"
+)
+
+// AttemptKey 查询的重试序号，首次执行为 0
+const AttemptKey = attribute.Key("db.cassandra.attempt")
+
+// tracingObserver 通过 gocql 的 QueryObserver/BatchObserver 为每次查询与批处理记录 span
+type tracingObserver struct {
+	tracer *tracing.Tracer
+}
+
+func newTracingObserver(tracer *tracing.Tracer) *tracingObserver {
+	return &tracingObserver{tracer: tracer}
+}
+
+// ObserveQuery 实现 gocql.QueryObserver，分页查询的每一页各记录一个 span
+func (o *tracingObserver) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	operation, table := tracing.ParseStatement(q.Statement)
+
+	_, span := o.tracer.StartAt(ctx, q.Start, operation, table, observedAttributes(q.Keyspace, q.Host, q.Attempt)...)
+	span.SetStatement(q.Statement)
+	if operation == "SELECT" {
+		span.SetReturnedRows(int64(q.Rows))
+	}
+	span.EndAt(q.Err, q.End)
+}
+
+// ObserveBatch 实现 gocql.BatchObserver，整个批处理记录一个 span
+func (o *tracingObserver) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	var table string
+	if len(b.Statements) > 0 {
+		_, table = tracing.ParseStatement(b.Statements[0])
+	}
+
+	_, span := o.tracer.StartAt(ctx, b.Start, "BATCH", table, observedAttributes(b.Keyspace, b.Host, b.Attempt)...)
+	span.SetStatement(strings.Join(b.Statements, "; "))
+	span.SetAttributes(attribute.Int("db.operation.batch.size", len(b.Statements)))
+	span.EndAt(b.Err, b.End)
+}
+
+func observedAttributes(keyspace string, host *gocql.HostInfo, attempt int) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{AttemptKey.Int(attempt)}
+	if keyspace != "" {
+		attrs = append(attrs, attribute.String("db.namespace", keyspace))
+	}
+	if host != nil {
+		attrs = append(attrs, attribute.String("server.address", host.ConnectAddress().String()))
+	}
+	return attrs
+}
@@ -4,15 +4,91 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	clickhouseV2 "github.com/ClickHouse/clickhouse-go/v2"
 	driverV2 "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/tx7do/go-crud/clickhouse/schema"
+	"github.com/tx7do/go-crud/metrics"
+)
+
+const (
+	defaultBatchSize       = 1000
+	defaultBatchMaxRetries = 3
+	defaultBatchMinBackoff = 100 * time.Millisecond
+	defaultBatchMaxBackoff = 5 * time.Second
 )
 
-// BatchInserter 批量插入器
+// BatchFailureHandler 接收重试后仍写入失败的行，在提交所在的协程中同步调用，不应阻塞
+type BatchFailureHandler func(rows []interface{}, err error)
+
+type BatchOption func(bi *BatchInserter)
+
+// WithBatchBackground 启用后台提交：Add 只负责缓冲，批次由后台协程提交，使用完毕后必须调用 Close 以退出后台协程
+func WithBatchBackground() BatchOption {
+	return func(bi *BatchInserter) {
+		bi.background = true
+	}
+}
+
+// WithBatchFlushInterval 缓冲中的行最长等待时间，到期后在后台提交；0 表示只按数量提交。设置后启用后台提交
+func WithBatchFlushInterval(interval time.Duration) BatchOption {
+	return func(bi *BatchInserter) {
+		if interval > 0 {
+			bi.flushInterval = interval
+			bi.background = true
+		}
+	}
+}
+
+// WithBatchRetry 设置网络错误与可重试的服务端异常的最大重试次数及指数退避的初始、最大间隔
+func WithBatchRetry(maxRetries int, minBackoff, maxBackoff time.Duration) BatchOption {
+	return func(bi *BatchInserter) {
+		if maxRetries >= 0 {
+			bi.maxRetries = maxRetries
+		}
+		if minBackoff > 0 {
+			bi.minBackoff = minBackoff
+		}
+		if maxBackoff >= bi.minBackoff {
+			bi.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithBatchFailureHandler 设置失败回调，接收被拒绝的行。
+// 未设置时，同步模式下可重试错误导致失败的行保留在缓冲中，由下一次 Flush 重新提交；
+// 不可重试的失败（如行为 nil 或列无对应字段）则丢弃该批次，并通过 *BatchRejectedError 返回被丢弃的行
+func WithBatchFailureHandler(fn BatchFailureHandler) BatchOption {
+	return func(bi *BatchInserter) {
+		bi.onFailure = fn
+	}
+}
+
+// WithBatchFlushers 并发提交的批次数，行的反射取值并行执行，PrepareBatch、Append 与 Send 按入队顺序进行。设置后启用后台提交
+func WithBatchFlushers(n int) BatchOption {
+	return func(bi *BatchInserter) {
+		if n > 0 {
+			bi.flushers = n
+			bi.background = true
+		}
+	}
+}
+
+// BatchInserter 批量插入器：行缓冲达到 batchSize 时封装为批次提交，
+// 可重试的错误按指数退避重试，最终失败的行交给失败回调；同一插入器的批次按封装顺序提交。
+//
+// 默认在调用 Add/Flush/Close 的协程中同步提交并返回提交错误，不启动后台协程。
+// 通过 WithBatchBackground、WithBatchFlushInterval 或 WithBatchFlushers 启用后台提交后，
+// 批次由后台协程提交，提交错误由之后的第一次 Add、Flush 或 Close 返回（每个错误只返回一次），
+// 且必须调用 Close 以提交剩余数据并退出后台协程
 type BatchInserter struct {
 	conn       clickhouseV2.Conn
 	tableName  string
@@ -23,18 +99,64 @@ type BatchInserter struct {
 	mu         sync.Mutex
 	ctx        context.Context
 	cancel     context.CancelFunc
+	metrics    atomic.Pointer[metrics.Metrics]
+
+	flushInterval time.Duration
+	maxRetries    int
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	onFailure     BatchFailureHandler
+	flushers      int
+	background    bool
+
+	closed   bool
+	batches  chan *pendingBatch
+	inflight sync.WaitGroup
+	workers  sync.WaitGroup
+	stop     chan struct{}
+
+	// seq 为下一个封装的批次序号，nextSend 为下一个允许 Send 的序号
+	seq      uint64
+	sendMu   sync.Mutex
+	sendCond *sync.Cond
+	nextSend uint64
+	queued   atomic.Int64
+
+	errMu sync.Mutex
+	err   error
+}
+
+// BatchRejectedError 同步模式下未设置失败回调时，因不可重试的错误而被丢弃的批次
+type BatchRejectedError struct {
+	Rows []interface{}
+	Err  error
+}
+
+func (e *BatchRejectedError) Error() string {
+	return fmt.Sprintf("batch of %d rows rejected: %v", len(e.Rows), e.Err)
+}
+
+func (e *BatchRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// pendingBatch 已封装、等待提交的批次
+type pendingBatch struct {
+	seq  uint64
+	rows []interface{}
 }
 
-// NewBatchInserter 创建新的批量插入器
+// NewBatchInserter 创建新的批量插入器；启用后台提交时同时启动后台协程，使用完毕后必须调用 Close
 func NewBatchInserter(
 	ctx context.Context,
 	conn clickhouseV2.Conn,
 	tableName string,
 	batchSize int,
 	columns []string,
+	opts ...BatchOption,
 ) (*BatchInserter, error) {
 	if batchSize <= 0 {
-		batchSize = 1000 // 默认批量大小
+		batchSize = defaultBatchSize
 	}
 
 	if len(columns) == 0 {
@@ -56,7 +178,7 @@ func NewBatchInserter(
 
 	ctx, cancel := context.WithCancel(ctx)
 
-	return &BatchInserter{
+	bi := &BatchInserter{
 		conn:       conn,
 		tableName:  tableName,
 		columns:    columns,
@@ -65,10 +187,43 @@ func NewBatchInserter(
 		insertStmt: insertStmt,
 		ctx:        ctx,
 		cancel:     cancel,
-	}, nil
+
+		maxRetries: defaultBatchMaxRetries,
+		minBackoff: defaultBatchMinBackoff,
+		maxBackoff: defaultBatchMaxBackoff,
+		flushers:   1,
+
+		stop: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(bi)
+	}
+	bi.sendCond = sync.NewCond(&bi.sendMu)
+	if !bi.background {
+		return bi, nil
+	}
+
+	bi.batches = make(chan *pendingBatch, bi.flushers)
+
+	for i := 0; i < bi.flushers; i++ {
+		bi.workers.Add(1)
+		go bi.runFlusher()
+	}
+	if bi.flushInterval > 0 {
+		bi.workers.Add(1)
+		go bi.runTicker()
+	}
+
+	return bi, nil
+}
+
+// SetMetrics 设置指标，记录缓冲与待提交的行数以及每次提交的耗时
+func (bi *BatchInserter) SetMetrics(m *metrics.Metrics) {
+	bi.metrics.Store(m)
 }
 
-// Add 添加数据行
+// Add 添加数据行，达到批量大小时提交，同步模式下返回本次提交的错误；
+// 后台模式下封装为批次交给后台提交（待提交的批次已满时阻塞），并返回此前尚未返回的提交错误
 func (bi *BatchInserter) Add(row interface{}) error {
 	bi.mu.Lock()
 	defer bi.mu.Unlock()
@@ -77,123 +232,344 @@ func (bi *BatchInserter) Add(row interface{}) error {
 	if bi.ctx.Err() != nil {
 		return bi.ctx.Err()
 	}
+	if bi.closed {
+		return context.Canceled
+	}
 
 	bi.rows = append(bi.rows, row)
+	bi.setQueueDepth(1)
 
 	// 达到批量大小时自动提交
 	if len(bi.rows) >= bi.batchSize {
-		return bi.flush()
+		if err := bi.seal(); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return bi.takeErr()
 }
 
-// Flush 强制提交当前批次
+// Flush 提交当前缓冲并等待所有已封装的批次完成，返回本次或此前尚未返回的第一个提交错误
 func (bi *BatchInserter) Flush() error {
 	bi.mu.Lock()
-	defer bi.mu.Unlock()
+	var err error
+	if !bi.closed {
+		err = bi.seal()
+	}
+	bi.mu.Unlock()
 
-	return bi.flush()
+	bi.inflight.Wait()
+	return errors.Join(err, bi.takeErr())
 }
 
-// Close 关闭插入器并提交剩余数据
+// Close 关闭插入器：提交剩余数据，后台模式下等待后台协程退出；返回本次或此前尚未返回的第一个提交错误
 func (bi *BatchInserter) Close() error {
 	defer bi.cancel()
 
 	bi.mu.Lock()
-	defer bi.mu.Unlock()
+	if bi.closed {
+		bi.mu.Unlock()
+		return nil
+	}
+	bi.closed = true
+	err := bi.seal()
+	if bi.background {
+		close(bi.batches)
+	}
+	bi.mu.Unlock()
 
-	return bi.flush()
+	if bi.background {
+		close(bi.stop)
+		bi.workers.Wait()
+	}
+	return errors.Join(err, bi.takeErr())
 }
 
-// flush 内部提交方法
-func (bi *BatchInserter) flush() error {
+// seal 将缓冲中的行封装为批次：后台模式下交给后台提交，同步模式下直接提交并返回错误。调用方需持有 mu
+func (bi *BatchInserter) seal() error {
 	if len(bi.rows) == 0 {
 		return nil
 	}
 
-	// 创建批量
+	b := &pendingBatch{seq: bi.seq, rows: bi.rows}
+	bi.seq++
+	bi.rows = make([]interface{}, 0, bi.batchSize)
+
+	bi.inflight.Add(1)
+	if bi.background {
+		bi.batches <- b
+		return nil
+	}
+
+	err := bi.flush(b)
+	switch {
+	case bi.requeue(err):
+		// 保留失败的行，由下一次 Flush 重新提交
+		bi.rows = append(b.rows, bi.rows...)
+	case err != nil && bi.onFailure == nil:
+		// 不可重试的行重新提交也会失败，丢弃后返回给调用方，避免阻塞之后的行
+		return &BatchRejectedError{Rows: b.rows, Err: err}
+	}
+	return err
+}
+
+// requeue 判断失败的批次是否放回缓冲：仅同步模式、未设置失败回调且错误可重试时放回
+func (bi *BatchInserter) requeue(err error) bool {
+	return err != nil && !bi.background && bi.onFailure == nil && isRetryableBatchError(err)
+}
+
+// runTicker 按 flushInterval 提交缓冲中的行
+func (bi *BatchInserter) runTicker() {
+	defer bi.workers.Done()
+
+	ticker := time.NewTicker(bi.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			bi.mu.Lock()
+			if !bi.closed {
+				bi.seal()
+			}
+			bi.mu.Unlock()
+		case <-bi.stop:
+			return
+		}
+	}
+}
+
+// runFlusher 提交协程
+func (bi *BatchInserter) runFlusher() {
+	defer bi.workers.Done()
+
+	for b := range bi.batches {
+		if err := bi.flush(b); err != nil {
+			bi.errMu.Lock()
+			if bi.err == nil {
+				bi.err = err
+			}
+			bi.errMu.Unlock()
+		}
+	}
+}
+
+// flush 带重试地提交一个批次，最终失败时交给失败回调并返回错误
+func (bi *BatchInserter) flush(b *pendingBatch) error {
+	defer bi.inflight.Done()
+
+	start := time.Now()
+	err := bi.sendWithRetry(b)
+
+	bi.metrics.Load().ObserveBatchFlush(metrics.BackendClickHouse, bi.tableName, time.Since(start), err)
+
+	// 放回缓冲的行仍计入队列深度
+	if !bi.requeue(err) {
+		bi.setQueueDepth(-len(b.rows))
+	}
+	if err != nil && bi.onFailure != nil {
+		bi.onFailure(b.rows, err)
+	}
+	return err
+}
+
+// sendWithRetry 准备并发送批次。行的反射取值可与其它批次并行；PrepareBatch 会占用连接池中的连接，
+// 因此轮到该批次的序号后才准备，避免等待发送顺序的批次占满连接池而死锁。
+// 重试期间保持发送权，保证同一插入器的批次按顺序提交
+func (bi *BatchInserter) sendWithRetry(b *pendingBatch) error {
+	values, err := rowValues(b.rows, bi.columns)
+
+	bi.waitTurn(b.seq)
+	defer bi.releaseTurn()
+
+	var batch driverV2.Batch
+	if err == nil {
+		batch, err = bi.prepare(values)
+	}
+
+	backoff := bi.minBackoff
+	for attempt := 0; ; attempt++ {
+		if err == nil {
+			if err = batch.Send(); err != nil {
+				err = errors.Join(ErrBatchSendFailed, err)
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		if attempt >= bi.maxRetries || !isRetryableBatchError(err) || bi.ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-bi.ctx.Done():
+			timer.Stop()
+			return err
+		}
+		if backoff *= 2; backoff > bi.maxBackoff {
+			backoff = bi.maxBackoff
+		}
+
+		// 发送失败的批次不可复用，重新准备
+		batch, err = bi.prepare(values)
+	}
+}
+
+// prepare 创建批次并追加所有行
+func (bi *BatchInserter) prepare(values [][]any) (driverV2.Batch, error) {
 	batch, err := bi.conn.PrepareBatch(bi.ctx, bi.insertStmt)
 	if err != nil {
-		return ErrBatchPrepareFailed
+		return nil, errors.Join(ErrBatchPrepareFailed, err)
 	}
 
-	// 添加所有行
-	for _, row := range bi.rows {
-		// 使用反射获取字段值
-		if err = appendStructToBatch(batch, row, bi.columns); err != nil {
-			return ErrBatchAppendFailed
+	for _, v := range values {
+		if err = batch.Append(v...); err != nil {
+			_ = batch.Abort()
+			return nil, errors.Join(ErrBatchAppendFailed, err)
 		}
 	}
+	return batch, nil
+}
 
-	// 提交批量
-	if err = batch.Send(); err != nil {
-		return ErrBatchSendFailed
+// rowValues 按列顺序取出每行结构体的字段值
+func rowValues(rows []interface{}, columns []string) ([][]any, error) {
+	values := make([][]any, 0, len(rows))
+	for _, row := range rows {
+		v, err := structValues(row, columns)
+		if err != nil {
+			return nil, errors.Join(ErrBatchAppendFailed, err)
+		}
+		values = append(values, v)
 	}
+	return values, nil
+}
 
-	// 清空批次
-	bi.rows = bi.rows[:0]
-	return nil
+func (bi *BatchInserter) waitTurn(seq uint64) {
+	bi.sendMu.Lock()
+	for bi.nextSend != seq {
+		bi.sendCond.Wait()
+	}
+	bi.sendMu.Unlock()
 }
 
-// appendStructToBatch 使用反射将结构体字段添加到批次
-func appendStructToBatch(batch driverV2.Batch, obj interface{}, columns []string) error {
-	v := reflect.ValueOf(obj)
+func (bi *BatchInserter) releaseTurn() {
+	bi.sendMu.Lock()
+	bi.nextSend++
+	bi.sendMu.Unlock()
+	bi.sendCond.Broadcast()
+}
 
-	// 如果是指针，获取指针指向的值
-	if v.Kind() == reflect.Ptr {
-		if v.IsNil() {
-			return errors.New("nil指针")
-		}
-		v = v.Elem()
+// setQueueDepth 调整缓冲与待提交的总行数并上报
+func (bi *BatchInserter) setQueueDepth(delta int) {
+	depth := bi.queued.Add(int64(delta))
+	bi.metrics.Load().SetBatchQueueDepth(metrics.BackendClickHouse, bi.tableName, int(depth))
+}
+
+// takeErr 返回并清除记录的第一个提交错误
+func (bi *BatchInserter) takeErr() error {
+	bi.errMu.Lock()
+	defer bi.errMu.Unlock()
+
+	err := bi.err
+	bi.err = nil
+	return err
+}
+
+// retryableExceptionCodes 可重试的 ClickHouse 异常码：超时、并发查询过多、网络错误、分区过多与内存不足
+var retryableExceptionCodes = map[int32]bool{
+	159: true, // TIMEOUT_EXCEEDED
+	202: true, // TOO_MANY_SIMULTANEOUS_QUERIES
+	209: true, // SOCKET_TIMEOUT
+	210: true, // NETWORK_ERROR
+	241: true, // MEMORY_LIMIT_EXCEEDED
+	252: true, // TOO_MANY_PARTS
+}
+
+// isRetryableBatchError 追加行失败不可重试；网络错误与 retryableExceptionCodes 中的异常可以重试
+func isRetryableBatchError(err error) bool {
+	if errors.Is(err, ErrBatchAppendFailed) {
+		return false
 	}
 
-	// 必须是结构体
-	if v.Kind() != reflect.Struct {
-		return fmt.Errorf("期望结构体类型，得到 %v", v.Kind())
+	var ex *clickhouseV2.Exception
+	if errors.As(err, &ex) {
+		return retryableExceptionCodes[ex.Code]
 	}
 
-	// 获取结构体类型
-	t := v.Type()
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// batchFieldCache 结构体类型与列集合 -> 各列对应的字段下标
+var batchFieldCache sync.Map
 
-	// 准备参数值
-	values := make([]interface{}, len(columns))
+type batchFieldKey struct {
+	typ     reflect.Type
+	columns string
+}
+
+// batchFields 解析并缓存列与结构体字段的对应关系，匹配规则见 findBatchField
+func batchFields(t reflect.Type, columns []string) ([]int, error) {
+	key := batchFieldKey{typ: t, columns: strings.Join(columns, ",")}
+	if v, ok := batchFieldCache.Load(key); ok {
+		return v.([]int), nil
+	}
 
-	// 映射列名到结构体字段
+	fields := make([]int, len(columns))
 	for i, col := range columns {
-		// 查找匹配的字段
-		found := false
-		for j := 0; j < v.NumField(); j++ {
-			field := t.Field(j)
-
-			// 检查ch标签
-			if tag := field.Tag.Get("ch"); strings.TrimSpace(tag) == col {
-				values[i] = v.Field(j).Interface()
-				found = true
-				break
-			}
+		if fields[i] = findBatchField(t, col); fields[i] < 0 {
+			return nil, fmt.Errorf("未找到列 %s 对应的结构体字段", col)
+		}
+	}
 
-			// 检查json标签
-			jsonTags := strings.Split(field.Tag.Get("json"), ",")
-			if len(jsonTags) > 0 && strings.TrimSpace(jsonTags[0]) == col {
-				values[i] = v.Field(j).Interface()
-				found = true
-				break
-			}
+	v, _ := batchFieldCache.LoadOrStore(key, fields)
+	return v.([]int), nil
+}
 
-			// 检查字段名
-			if field.Name == col {
-				values[i] = v.Field(j).Interface()
-				found = true
-				break
+// findBatchField 查找列对应的字段下标，先按 schema.ColumnName 匹配，再按字段名（不区分大小写）匹配，未找到时返回 -1
+func findBatchField(t reflect.Type, col string) int {
+	matchers := []func(sf reflect.StructField) bool{
+		func(sf reflect.StructField) bool { return schema.ColumnName(sf) == col },
+		func(sf reflect.StructField) bool { return sf.Name == col },
+		func(sf reflect.StructField) bool { return strings.EqualFold(sf.Name, col) },
+	}
+	for _, match := range matchers {
+		for j := 0; j < t.NumField(); j++ {
+			if sf := t.Field(j); sf.IsExported() && match(sf) {
+				return j
 			}
 		}
+	}
+	return -1
+}
 
-		if !found {
-			return fmt.Errorf("未找到列 %s 对应的结构体字段", col)
+// structValues 使用缓存的字段元数据按列顺序取出结构体字段值
+func structValues(obj interface{}, columns []string) ([]any, error) {
+	v := reflect.ValueOf(obj)
+
+	// 如果是指针，获取指针指向的值
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, errors.New("nil指针")
 		}
+		v = v.Elem()
+	}
+
+	// 必须是结构体
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("期望结构体类型，得到 %v", v.Kind())
 	}
 
-	// 添加到批次
-	return batch.Append(values...)
+	fields, err := batchFields(v.Type(), columns)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(fields))
+	for i, idx := range fields {
+		values[i] = v.Field(idx).Interface()
+	}
+	return values, nil
 }
@@ -0,0 +1,227 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	clickhouseV2 "github.com/ClickHouse/clickhouse-go/v2"
+	driverV2 "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type batchRow struct {
+	ID    uint64 `ch:"id"`
+	Name  string `db:"name"`
+	Score float64
+}
+
+// fakeBatchConn 记录每次 Send 的行，sendErrs 按顺序作为 Send 的返回值
+type fakeBatchConn struct {
+	clickhouseV2.Conn
+
+	mu       sync.Mutex
+	sent     [][][]any
+	sendErrs []error
+	prepared int
+
+	// open/maxOpen 未 Send 或 Abort 的批次数（即占用的连接数）及其峰值
+	open    int
+	maxOpen int
+
+	// sendDelay 每次 Send 的耗时
+	sendDelay time.Duration
+}
+
+type fakeBatch struct {
+	driverV2.Batch
+
+	conn *fakeBatchConn
+	rows [][]any
+}
+
+func (c *fakeBatchConn) PrepareBatch(context.Context, string, ...driverV2.PrepareBatchOption) (driverV2.Batch, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prepared++
+	c.open++
+	c.maxOpen = max(c.maxOpen, c.open)
+	return &fakeBatch{conn: c}, nil
+}
+
+func (c *fakeBatchConn) sentIDs() [][]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ids [][]uint64
+	for _, batch := range c.sent {
+		var b []uint64
+		for _, row := range batch {
+			b = append(b, row[0].(uint64))
+		}
+		ids = append(ids, b)
+	}
+	return ids
+}
+
+func (b *fakeBatch) Append(v ...any) error {
+	b.rows = append(b.rows, v)
+	return nil
+}
+
+func (b *fakeBatch) Abort() error {
+	b.conn.mu.Lock()
+	defer b.conn.mu.Unlock()
+
+	b.conn.open--
+	return nil
+}
+
+func (b *fakeBatch) Send() error {
+	time.Sleep(b.conn.sendDelay)
+
+	b.conn.mu.Lock()
+	defer b.conn.mu.Unlock()
+
+	b.conn.open--
+	if len(b.conn.sendErrs) > 0 {
+		err := b.conn.sendErrs[0]
+		b.conn.sendErrs = b.conn.sendErrs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	b.conn.sent = append(b.conn.sent, b.rows)
+	return nil
+}
+
+func TestBatchInserter_OrderedFlushers(t *testing.T) {
+	conn := &fakeBatchConn{sendDelay: 5 * time.Millisecond}
+	bi, err := NewBatchInserter(context.Background(), conn, "events", 2, []string{"id", "name", "Score"}, WithBatchFlushers(4))
+	require.NoError(t, err)
+
+	for i := uint64(1); i <= 9; i++ {
+		require.NoError(t, bi.Add(&batchRow{ID: i, Name: "n", Score: float64(i)}))
+	}
+	require.NoError(t, bi.Close())
+
+	assert.Equal(t, [][]uint64{{1, 2}, {3, 4}, {5, 6}, {7, 8}, {9}}, conn.sentIDs())
+	assert.Equal(t, []any{uint64(1), "n", float64(1)}, conn.sent[0][0])
+	// 等待发送顺序的批次不占用连接
+	assert.Equal(t, 1, conn.maxOpen)
+	assert.ErrorIs(t, bi.Add(&batchRow{}), context.Canceled)
+}
+
+func TestBatchInserter_IntervalFlush(t *testing.T) {
+	conn := &fakeBatchConn{}
+	bi, err := NewBatchInserter(context.Background(), conn, "events", 100, []string{"id", "name", "score"},
+		WithBatchFlushInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer func() { _ = bi.Close() }()
+
+	require.NoError(t, bi.Add(batchRow{ID: 1}))
+	assert.Eventually(t, func() bool { return len(conn.sentIDs()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestBatchInserter_RetryAndFailureHandler(t *testing.T) {
+	conn := &fakeBatchConn{sendErrs: []error{
+		&clickhouseV2.Exception{Code: 252, Name: "TOO_MANY_PARTS"},
+		nil,
+		&clickhouseV2.Exception{Code: 62, Name: "SYNTAX_ERROR"},
+	}}
+
+	var rejected []any
+	var rejectErr error
+	bi, err := NewBatchInserter(context.Background(), conn, "events", 10, []string{"id", "name", "score"},
+		WithBatchRetry(2, time.Millisecond, time.Millisecond),
+		WithBatchFailureHandler(func(rows []any, err error) {
+			rejected, rejectErr = rows, err
+		}),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, bi.Add(&batchRow{ID: 1}))
+	require.NoError(t, bi.Flush())
+	assert.Equal(t, [][]uint64{{1}}, conn.sentIDs())
+	assert.Equal(t, 2, conn.prepared)
+
+	require.NoError(t, bi.Add(&batchRow{ID: 2}))
+	err = bi.Close()
+	assert.ErrorIs(t, err, ErrBatchSendFailed)
+	assert.Len(t, rejected, 1)
+
+	var ex *clickhouseV2.Exception
+	require.True(t, errors.As(rejectErr, &ex))
+	assert.Equal(t, int32(62), ex.Code)
+}
+
+func TestBatchInserter_SyncSurfacesSendError(t *testing.T) {
+	tooManyParts := &clickhouseV2.Exception{Code: 252, Name: "TOO_MANY_PARTS"}
+	conn := &fakeBatchConn{sendErrs: []error{tooManyParts}}
+	bi, err := NewBatchInserter(context.Background(), conn, "events", 2, []string{"id", "name", "score"},
+		WithBatchRetry(0, time.Millisecond, time.Millisecond))
+	require.NoError(t, err)
+	assert.Nil(t, bi.batches, "sync mode must not start background flushers")
+
+	require.NoError(t, bi.Add(&batchRow{ID: 1}))
+	assert.ErrorIs(t, bi.Add(&batchRow{ID: 2}), ErrBatchSendFailed)
+
+	// 可重试错误导致失败的行保留在缓冲中，下一次 Flush 重新提交
+	require.NoError(t, bi.Flush())
+	assert.Equal(t, [][]uint64{{1, 2}}, conn.sentIDs())
+	require.NoError(t, bi.Close())
+}
+
+func TestBatchInserter_SyncDropsRejectedRows(t *testing.T) {
+	conn := &fakeBatchConn{}
+	bi, err := NewBatchInserter(context.Background(), conn, "events", 2, []string{"id", "name", "score"})
+	require.NoError(t, err)
+
+	require.NoError(t, bi.Add(&batchRow{ID: 1}))
+	err = bi.Add(nil)
+	assert.ErrorIs(t, err, ErrBatchAppendFailed)
+
+	// 不可重试的批次被丢弃并随错误返回，不影响之后的行
+	var rejected *BatchRejectedError
+	require.True(t, errors.As(err, &rejected))
+	assert.Len(t, rejected.Rows, 2)
+
+	require.NoError(t, bi.Add(&batchRow{ID: 3}))
+	require.NoError(t, bi.Add(&batchRow{ID: 4}))
+	require.NoError(t, bi.Close())
+	assert.Equal(t, [][]uint64{{3, 4}}, conn.sentIDs())
+	assert.Zero(t, bi.queued.Load())
+}
+
+func TestBatchInserter_BackgroundSurfacesSendErrorOnAdd(t *testing.T) {
+	conn := &fakeBatchConn{sendErrs: []error{&clickhouseV2.Exception{Code: 62, Name: "SYNTAX_ERROR"}}}
+	bi, err := NewBatchInserter(context.Background(), conn, "events", 1, []string{"id", "name", "score"}, WithBatchBackground())
+	require.NoError(t, err)
+
+	require.NoError(t, bi.Add(&batchRow{ID: 1}))
+	bi.inflight.Wait()
+
+	assert.ErrorIs(t, bi.Add(&batchRow{ID: 2}), ErrBatchSendFailed)
+	// 错误只返回一次
+	require.NoError(t, bi.Close())
+	assert.Equal(t, [][]uint64{{2}}, conn.sentIDs())
+}
+
+func TestBatchFields_Cache(t *testing.T) {
+	rt := reflect.TypeOf(batchRow{})
+	fields, err := batchFields(rt, []string{"score", "id", "name"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 0, 1}, fields)
+
+	cached, err := batchFields(rt, []string{"score", "id", "name"})
+	require.NoError(t, err)
+	assert.Same(t, &fields[0], &cached[0])
+
+	_, err = batchFields(rt, []string{"missing"})
+	assert.Error(t, err)
+}
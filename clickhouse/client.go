@@ -9,6 +9,11 @@ import (
 
 	clickhouseV2 "github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/go-crud/clickhouse/schema"
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 )
 
 type Client struct {
@@ -17,6 +22,14 @@ type Client struct {
 
 	options *clickhouseV2.Options
 
+	enableTrace    bool
+	tracingOptions []tracing.Option
+	tracer         *tracing.Tracer
+
+	enableMetrics bool
+	metrics       *metrics.Metrics
+	slowLog       *slowlog.Logger
+
 	logger *log.Helper
 }
 
@@ -37,6 +50,22 @@ func NewClient(opts ...Option) (*Client, error) {
 		return nil, err
 	}
 
+	if c.enableTrace {
+		opts := append([]tracing.Option{tracing.WithNamespace(c.options.Auth.Database)}, c.tracingOptions...)
+		c.tracer = tracing.NewTracer("clickhouse", opts...)
+		c.conn = &tracedConn{Conn: c.conn, tracer: c.tracer}
+	}
+
+	// 未通过 WithMetrics 共享指标时，注册到 prometheus 默认 Registerer
+	if c.enableMetrics && c.metrics == nil {
+		m, err := metrics.New(nil)
+		if err != nil {
+			c.logger.Errorf("failed to register clickhouse metrics: %v", err)
+			return nil, err
+		}
+		c.metrics = m
+	}
+
 	return c, nil
 }
 
@@ -100,7 +129,7 @@ func (c *Client) CheckConnection(ctx context.Context) error {
 	return nil
 }
 
-// Query 执行查询并返回结果
+// Query 执行查询并返回结果，ctx 中通过 WithQueryOptions 附加的设置、查询 ID 与 KILL QUERY 对 QueryRow/Select/Exec 同样生效
 func (c *Client) Query(ctx context.Context, creator Creator, results *[]any, query string, args ...any) error {
 	if c.conn == nil {
 		c.logger.Error("clickhouse client is not initialized")
@@ -127,6 +156,9 @@ func (c *Client) Query(ctx context.Context, creator Creator, results *[]any, que
 		}
 	}
 
+	ctx, done := c.prepareQuery(ctx)
+	defer done()
+
 	rows, err := c.conn.Query(ctx, query, args...)
 	if err != nil {
 		c.logger.Errorf("query failed: %v", err)
@@ -158,6 +190,9 @@ func (c *Client) Query(ctx context.Context, creator Creator, results *[]any, que
 
 // QueryRow 执行查询并返回单行结果
 func (c *Client) QueryRow(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, done := c.prepareQuery(ctx)
+	defer done()
+
 	row := c.conn.QueryRow(ctx, query, args...)
 	if row == nil {
 		c.logger.Error("query row returned nil")
@@ -179,6 +214,9 @@ func (c *Client) Select(ctx context.Context, dest any, query string, args ...any
 		return ErrClientNotInitialized
 	}
 
+	ctx, done := c.prepareQuery(ctx)
+	defer done()
+
 	err := c.conn.Select(ctx, dest, query, args...)
 	if err != nil {
 		c.logger.Errorf("select failed: %v", err)
@@ -195,6 +233,9 @@ func (c *Client) Exec(ctx context.Context, query string, args ...any) error {
 		return ErrClientNotInitialized
 	}
 
+	ctx, done := c.prepareQuery(ctx)
+	defer done()
+
 	if err := c.conn.Exec(ctx, query, args...); err != nil {
 		c.logger.Errorf("exec failed: %v", err)
 		return ErrExecutionFailed
@@ -221,23 +262,7 @@ func (c *Client) prepareInsertData(data any) (string, string, []any, error) {
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
 
-		// 优先获取 `ch` 标签，其次获取 `json` 标签，最后使用字段名
-		columnName := field.Tag.Get("ch")
-		if columnName == "" {
-			jsonTag := field.Tag.Get("json")
-			if jsonTag != "" {
-				tags := strings.Split(jsonTag, ",") // 只取逗号前的部分
-				if len(tags) > 0 {
-					columnName = tags[0]
-				}
-			}
-		}
-		if columnName == "" {
-			columnName = field.Name
-		}
-		//columnName = strings.TrimSpace(columnName)
-
-		columns = append(columns, columnName)
+		columns = append(columns, schema.ColumnName(field))
 		placeholders = append(placeholders, "?")
 	}
 
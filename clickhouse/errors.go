@@ -1,6 +1,10 @@
 package clickhouse
 
-import "github.com/go-kratos/kratos/v2/errors"
+import (
+	stderrors "errors"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
 
 var (
 	// ErrInvalidColumnName is returned when an invalid column name is used.
@@ -89,3 +93,54 @@ var (
 
 	ErrInvalidArgument = errors.BadRequest("INVALID_ARGUMENT", "invalid argument provided")
 )
+
+// 仓库自身返回的错误，在 init 中按分类注册到 metrics，作为 class 标签
+var (
+	// 参数缺失或不合法
+	errClientIsNil              = stderrors.New("clickhouse client is nil")
+	errDTOIsNil                 = stderrors.New("dto is nil")
+	errDedupKeyColumnsNotFound  = stderrors.New("dedup key columns not found")
+	errEntityHasNoColumns       = stderrors.New("entity has no columns")
+	errEntityIsNil              = stderrors.New("entity is nil")
+	errEntityNotStruct          = stderrors.New("entity must be a struct")
+	errEntityNotStructOrPointer = stderrors.New("entity must be a struct or pointer to struct")
+	errEntityNotStructType      = stderrors.New("entity must be a struct type")
+	errNoColumnsToInsert        = stderrors.New("no columns to insert")
+	errNoColumnsToUpdate        = stderrors.New("no columns to update")
+	errPagingRequestIsNil       = stderrors.New("paging request is nil")
+	errPrimaryKeyNotFound       = stderrors.New("primary key field not found; cannot determine WHERE clause")
+	errSoftDeleteUnsupported    = stderrors.New("soft delete not supported: deleted_at field not found on entity")
+	errTableDefinitionIsNil     = stderrors.New("table definition is nil")
+	errTableIsEmpty             = stderrors.New("table is empty")
+	errUpsertKeyColumnsNotFound = stderrors.New("upsert key columns not found")
+	errUpsertModeIsNotVersioned = stderrors.New("upsert mode is not versioned")
+	errUpsertModeUnsupported    = stderrors.New("upsert mode is not supported by clickhouse repository")
+
+	// 底层存储执行失败，原始错误已记录日志
+	errBatchCreateFailed          = stderrors.New("batch create failed")
+	errCountQueryFailed           = stderrors.New("count query failed")
+	errCreateFailed               = stderrors.New("create failed")
+	errDeleteByFilterFailed       = stderrors.New("delete by filter failed")
+	errDeleteFailed               = stderrors.New("delete failed")
+	errExistsQueryFailed          = stderrors.New("exists query failed")
+	errExplainQueryFailed         = stderrors.New("explain query failed")
+	errGetQueryFailed             = stderrors.New("get query failed")
+	errHistogramQueryFailed       = stderrors.New("histogram query failed")
+	errListQueryFailed            = stderrors.New("list query failed")
+	errQueryServerTimeFailed      = stderrors.New("query server time failed")
+	errQuerySystemMutationsFailed = stderrors.New("query system.mutations failed")
+	errReadCurrentVersionFailed   = stderrors.New("read current version failed")
+	errReadUpdatedRecordFailed    = stderrors.New("read updated record failed")
+	errReadUpsertedRecordFailed   = stderrors.New("read upserted record failed")
+	errRowsIterationError         = stderrors.New("rows iteration error")
+	errScanCountFailed            = stderrors.New("scan count failed")
+	errScanExplainRowFailed       = stderrors.New("scan explain row failed")
+	errScanHistogramRowFailed     = stderrors.New("scan histogram row failed")
+	errUnexpectedResultType       = stderrors.New("unexpected result type")
+	errUnexpectedUpsertResultType = stderrors.New("unexpected result type after upsert")
+	errUpdateByFilterFailed       = stderrors.New("update by filter failed")
+	errUpdateFailed               = stderrors.New("update failed")
+	errUpsertInsertFailed         = stderrors.New("upsert insert failed")
+	errUpsertPrimaryKeyNotFound   = stderrors.New("upsert failed and primary key not found")
+	errUpsertUpdateFailed         = stderrors.New("upsert update failed")
+)
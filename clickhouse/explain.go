@@ -0,0 +1,80 @@
+package clickhouse
+
+import (
+	"context"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/explain"
+)
+
+// ToQuery 返回 ListWithPaging 针对 req 生成的列表与计数 SQL 及参数（不访问数据库）
+func (r *Repository[DTO, ENTITY]) ToQuery(req *paginationV1.PagingRequest) (*explain.Query, error) {
+	if r.table == "" {
+		return nil, errTableIsEmpty
+	}
+	if req == nil {
+		return nil, errPagingRequestIsNil
+	}
+
+	req, err := r.limitPolicy.ApplyPaging(r.entityName, req)
+	if err != nil {
+		return nil, err
+	}
+
+	qb := r.buildPagingQuery(req)
+
+	where, whereArgs := qb.BuildWhereParam()
+	countSQL := "SELECT COUNT(1) FROM " + r.readSource(false)
+	if where != "" {
+		countSQL += " WHERE " + where
+	}
+
+	listSQL, listArgs := qb.Build()
+
+	return &explain.Query{
+		Statement:      listSQL,
+		Args:           listArgs,
+		CountStatement: countSQL,
+		CountArgs:      whereArgs,
+	}, nil
+}
+
+// Explain 在 ToQuery 的基础上对列表 SQL 执行 EXPLAIN，返回 ClickHouse 的执行计划（不执行列表查询本身）
+func (r *Repository[DTO, ENTITY]) Explain(ctx context.Context, req *paginationV1.PagingRequest) (*explain.Query, error) {
+	if r.client == nil {
+		return nil, errClientIsNil
+	}
+
+	q, err := r.ToQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.client.conn.Query(ctx, "EXPLAIN "+q.Statement, q.Args...)
+	if err != nil {
+		r.log.Errorf("explain query failed: %v", err)
+		return nil, errExplainQueryFailed
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			r.log.Errorf("failed to close rows: %v", cerr)
+		}
+	}()
+
+	var values [][]any
+	for rows.Next() {
+		var line string
+		if err = rows.Scan(&line); err != nil {
+			r.log.Errorf("scan explain row failed: %v", err)
+			return nil, errScanExplainRowFailed
+		}
+		values = append(values, []any{line})
+	}
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("rows iteration error: %v", err)
+		return nil, errRowsIterationError
+	}
+
+	q.Plan = explain.FormatTable(rows.Columns(), values)
+	return q, nil
+}
@@ -52,8 +52,7 @@ func (sf *QueryStringFilter) BuildSelectors(builder *query.Builder, andFilterJso
 	}
 
 	if andBuilder != nil {
-		sql, args := andBuilder.Build()
-		where := extractWhere(sql)
+		where, args := andBuilder.BuildWhereParam()
 		if strings.TrimSpace(where) != "" {
 			if len(args) > 0 {
 				builder.Where(where, args...)
@@ -64,8 +63,7 @@ func (sf *QueryStringFilter) BuildSelectors(builder *query.Builder, andFilterJso
 	}
 
 	if orBuilder != nil {
-		sql, args := orBuilder.Build()
-		where := extractWhere(sql)
+		where, args := orBuilder.BuildWhereParam()
 		if strings.TrimSpace(where) != "" {
 			if len(args) > 0 {
 				builder.Where(where, args...)
@@ -122,12 +120,11 @@ func (sf *QueryStringFilter) makeClosureFromMaps(builder *query.Builder, maps []
 		return nil, fmt.Errorf("builder is nil")
 	}
 
-	// helper: 使用 Processor 在临时 builder 上生成子表达式与 args（从临时 SQL 中提取 WHERE 部分）
+	// helper: 使用 Processor 在临时 builder 上生成子表达式与 args（直接取临时 builder 的 WHERE 条件，不解析 SQL 文本）
 	buildWithProcessor := func(field string, op pagination.Operator, val string, vals []string) (string, []interface{}) {
 		tmp := query.NewQueryBuilder("", nil)
 		sf.processor.Process(tmp, op, field, val, vals)
-		sql, args := tmp.Build()
-		where := extractWhere(sql)
+		where, args := tmp.BuildWhereParam()
 		if strings.TrimSpace(where) == "" {
 			return "", nil
 		}
@@ -232,29 +229,6 @@ func (sf *QueryStringFilter) makeClosureFromMaps(builder *query.Builder, maps []
 	return builder, nil
 }
 
-// extractWhere 从完整 SQL 中提取 WHERE 子句（去掉后续的 ORDER/LIMIT/GROUP 等）
-func extractWhere(sql string) string {
-	up := strings.ToUpper(sql)
-	idx := strings.Index(up, "WHERE")
-	if idx == -1 {
-		return ""
-	}
-	start := idx + len("WHERE")
-	restUp := up[start:]
-	rest := sql[start:]
-
-	// 寻找下一个可能的子句关键字
-	candidates := []string{"ORDER BY", "LIMIT", "GROUP BY", "HAVING", "FORMAT"}
-	end := len(rest)
-	for _, kw := range candidates {
-		pos := strings.Index(restUp, kw)
-		if pos >= 0 && pos < end {
-			end = pos
-		}
-	}
-	return strings.TrimSpace(rest[:end])
-}
-
 // splitQueryKey 分割查询键
 func (sf *QueryStringFilter) splitQueryKey(key string) []string {
 	return strings.Split(key, QueryDelimiter)
@@ -0,0 +1,37 @@
+package clickhouse
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tx7do/go-crud/guard"
+)
+
+// SetGuardOptions 设置批量更新/删除的安全保护。
+// 默认拒绝不带 WHERE 条件的更新与删除（包括 Delete 的 TRUNCATE 与全表软删除）；
+// ClickHouse 没有事务，MaxAffectedRows 大于 0 时在执行 mutation 前按相同条件计数，超出上限则拒绝执行。
+func (r *Repository[DTO, ENTITY]) SetGuardOptions(opts guard.Options) {
+	r.guardOptions = opts
+}
+
+// checkGuard 校验过滤条件；countRows 为 true 或配置了 MaxAffectedRows 时在 from 上按 where 计数，并校验上限，
+// 否则不执行计数并返回 0。from 应与读取一致（见 fromClause/readSource），
+// 使 Replacing/CollapsingMergeTree 表按去重后的有效行而不是所有版本计数
+func (r *Repository[DTO, ENTITY]) checkGuard(ctx context.Context, from string, countRows bool, where string, whereArgs ...any) (uint64, error) {
+	if err := r.guardOptions.CheckFilter(strings.TrimSpace(where) != ""); err != nil {
+		return 0, err
+	}
+	if !countRows && r.guardOptions.MaxAffectedRows <= 0 {
+		return 0, nil
+	}
+
+	count, err := r.countFrom(r.queryContext(ctx), from, where, whereArgs...)
+	if err != nil {
+		return 0, err
+	}
+	if err = r.guardOptions.CheckAffected(int64(count)); err != nil {
+		r.log.Errorf("guard rejected mutation on %s: %v", r.table, err)
+		return 0, err
+	}
+	return count, nil
+}
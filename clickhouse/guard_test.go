@@ -0,0 +1,34 @@
+package clickhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/tx7do/go-utils/mapper"
+
+	"github.com/tx7do/go-crud/guard"
+)
+
+func TestRepository_CheckGuard(t *testing.T) {
+	ctx := context.Background()
+	// client 为 nil，执行计数时返回错误
+	repo := NewRepository[NoDeleted, NoDeleted](nil, mapper.NewCopierMapper[NoDeleted, NoDeleted](), "tmp", log.NewHelper(log.DefaultLogger))
+
+	_, err := repo.checkGuard(ctx, "tmp", false, "")
+	assert.ErrorIs(t, err, guard.ErrFullTableOperation)
+
+	// 未配置 MaxAffectedRows 且不需要计数时不执行 Count
+	repo.SetGuardOptions(guard.Options{AllowFullTable: true})
+	n, err := repo.checkGuard(ctx, "tmp", false, "")
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	_, err = repo.checkGuard(ctx, "tmp", true, "id = ?", 1)
+	assert.EqualError(t, err, "clickhouse client is nil")
+
+	repo.SetGuardOptions(guard.Options{AllowFullTable: true, MaxAffectedRows: 10})
+	_, err = repo.checkGuard(ctx, "tmp", false, "")
+	assert.EqualError(t, err, "clickhouse client is nil")
+}
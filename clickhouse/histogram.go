@@ -0,0 +1,128 @@
+package clickhouse
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tx7do/go-crud/dialect"
+	"github.com/tx7do/go-crud/histogram"
+)
+
+// Histogram 时间直方图：使用 toStartOfInterval、toMonday、toStartOfMonth 等带时区的函数分桶，返回补零且有序的桶。
+// final 为 true 时查询添加 FINAL 修饰符
+func (r *Repository[DTO, ENTITY]) Histogram(ctx context.Context, hreq *histogram.Request, final bool) (ret *histogram.Result, err error) {
+	obs := r.observe("Histogram")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Buckets)))
+		}
+		obs.End(err)
+	}()
+
+	if r.client == nil {
+		return nil, errClientIsNil
+	}
+	if r.table == "" {
+		return nil, errTableIsEmpty
+	}
+
+	aSql, args, err := r.buildHistogramSQL(hreq, final)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := r.startSpan(ctx, "Histogram", filterOptionsAttributes(histogramFilterOptions(hreq, final))...)
+	defer func() { span.End(err) }()
+
+	collector, err := histogram.NewCollector(hreq)
+	if err != nil {
+		return nil, err
+	}
+
+	obs.SetStatement(aSql, args...)
+	rows, err := r.client.conn.Query(ctx, aSql, args...)
+	if err != nil {
+		r.log.Errorf("histogram query failed: %v", err)
+		return nil, errHistogramQueryFailed
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			r.log.Errorf("close histogram rows failed: %v", cerr)
+		}
+	}()
+
+	var key string
+	var count float64
+	values := make([]float64, len(hreq.Aggregates))
+	dest := []any{&key, &count}
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			r.log.Errorf("scan histogram row failed: %v", err)
+			return nil, errScanHistogramRowFailed
+		}
+		row := make([]any, len(values))
+		for i, v := range values {
+			row[i] = v
+		}
+		if err = collector.Add(key, count, row...); err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("histogram query failed: %v", err)
+		return nil, errHistogramQueryFailed
+	}
+
+	ret, err = collector.Result()
+	if ret != nil {
+		span.SetReturnedRows(int64(len(ret.Buckets)))
+	}
+	return ret, err
+}
+
+// buildHistogramSQL 构造按分桶键分组的查询，列顺序为分桶键、文档数与各聚合（均为 Float64）
+func (r *Repository[DTO, ENTITY]) buildHistogramSQL(hreq *histogram.Request, final bool) (string, []any, error) {
+	s, err := histogram.BuildSQL(dialect.ClickHouse, hreq)
+	if err != nil {
+		return "", nil, err
+	}
+
+	opts := histogramFilterOptions(hreq, final)
+	where, args, err := r.buildWhere(opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var conds []string
+	if strings.TrimSpace(where) != "" {
+		conds = append(conds, "("+where+")")
+	}
+	if s.Where != "" {
+		conds = append(conds, s.Where)
+		args = append(args, s.WhereArgs...)
+	}
+
+	aSql := "SELECT " + s.Select() +
+		" FROM " + r.fromClause(opts) +
+		" WHERE " + whereOrTrue(strings.Join(conds, " AND ")) +
+		" GROUP BY " + s.GroupBy +
+		" ORDER BY " + s.OrderBy
+	return aSql, args, nil
+}
+
+// histogramFilterOptions 将直方图请求中的过滤条件转为 FilterOptions
+func histogramFilterOptions(hreq *histogram.Request, final bool) *FilterOptions {
+	if hreq == nil {
+		return nil
+	}
+	return &FilterOptions{
+		Query:      hreq.Query,
+		OrQuery:    hreq.OrQuery,
+		FilterExpr: hreq.FilterExpr,
+		Final:      final,
+	}
+}
@@ -0,0 +1,38 @@
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/mapper"
+
+	"github.com/tx7do/go-crud/histogram"
+)
+
+func TestRepository_BuildHistogramSQL(t *testing.T) {
+	repo := NewRepository[NoDeleted, NoDeleted](nil, mapper.NewCopierMapper[NoDeleted, NoDeleted](), "candles", log.NewHelper(log.DefaultLogger))
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	aSql, args, err := repo.buildHistogramSQL(&histogram.Request{
+		Field:      "ts",
+		Interval:   "1w",
+		Timezone:   "Asia/Shanghai",
+		Start:      start,
+		Query:      `{"symbol":"AAPL"}`,
+		Aggregates: []histogram.Aggregate{{Func: histogram.FuncSum, Field: "volume"}},
+	}, true)
+	require.NoError(t, err)
+
+	assert.Contains(t, aSql, "formatDateTime(toDateTime(toMonday(ts, 'Asia/Shanghai'), 'Asia/Shanghai'), '%Y-%m-%d %H:%i:%S', 'Asia/Shanghai') AS _bucket")
+	assert.Contains(t, aSql, "toFloat64(count()) AS _count")
+	assert.Contains(t, aSql, "toFloat64(sum(volume)) AS sum_volume")
+	assert.Contains(t, aSql, "FROM candles FINAL WHERE (")
+	assert.Contains(t, aSql, ") AND ts >= ? GROUP BY _bucket ORDER BY _bucket")
+	assert.Equal(t, []any{"AAPL", start}, args)
+
+	_, _, err = repo.buildHistogramSQL(&histogram.Request{Field: "ts", Interval: "7m"}, false)
+	assert.ErrorIs(t, err, histogram.ErrInvalidInterval)
+}
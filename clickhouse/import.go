@@ -0,0 +1,81 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"reflect"
+
+	"github.com/tx7do/go-crud/clickhouse/schema"
+	"github.com/tx7do/go-crud/importer"
+)
+
+// Import 从 CSV/JSONL 批量导入数据，每个分块通过 BatchInserter 以一个批次写入。
+// ClickHouse 没有事务，分块写入失败时该分块内的行均标记为失败，之前已提交的分块不会回滚。
+// ClickHouse 的 Upsert 无法可靠区分插入与更新，因此仅支持 importer.ModeInsert。
+func (r *Repository[DTO, ENTITY]) Import(ctx context.Context, src io.Reader, opts *importer.Options, validate importer.Validator[DTO]) (*importer.Report, error) {
+	if r.client == nil {
+		return nil, errClientIsNil
+	}
+	if r.table == "" {
+		return nil, errTableIsEmpty
+	}
+	if opts == nil {
+		opts = &importer.Options{}
+	}
+	if opts.Mode == importer.ModeUpsert {
+		return nil, errUpsertModeUnsupported
+	}
+
+	columns, err := batchColumns(reflect.TypeOf(new(ENTITY)).Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	return importer.Import[DTO](ctx, src, opts, validate, func(ctx context.Context, dtos []*DTO) ([]importer.Status, error) {
+		inserter, err := NewBatchInserter(ctx, r.client.conn, r.table, len(dtos), columns)
+		if err != nil {
+			return nil, err
+		}
+		inserter.SetMetrics(r.metrics)
+
+		for _, dto := range dtos {
+			if err = inserter.Add(r.mapper.ToEntity(dto)); err != nil {
+				_ = inserter.Close()
+				r.log.Errorf("import batch insert failed: %v", err)
+				return nil, err
+			}
+		}
+
+		if err = inserter.Close(); err != nil {
+			r.log.Errorf("import batch insert failed: %v", err)
+			return nil, err
+		}
+
+		return nil, nil
+	})
+}
+
+// batchColumns 按 schema.ColumnName 生成导出字段的列名，与 structValues 的匹配规则一致
+func batchColumns(t reflect.Type) ([]string, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, errEntityNotStruct
+	}
+
+	var cols []string
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		// skip unexported
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if col := schema.ColumnName(sf); col != "-" {
+			cols = append(cols, col)
+		}
+	}
+
+	if len(cols) == 0 {
+		return nil, errNoColumnsToInsert
+	}
+	return cols, nil
+}
@@ -0,0 +1,61 @@
+package clickhouse
+
+import (
+	"github.com/tx7do/go-crud/metrics"
+)
+
+// SetMetrics 设置仓库级指标，默认使用 Client 的 WithMetrics/WithEnableMetrics 配置；传入 nil 关闭指标
+func (r *Repository[DTO, ENTITY]) SetMetrics(m *metrics.Metrics) {
+	r.metrics = m
+}
+
+func init() {
+	metrics.RegisterErrorClass(metrics.ErrorClassInvalidArgument,
+		errClientIsNil,
+		errDTOIsNil,
+		errDedupKeyColumnsNotFound,
+		errEntityHasNoColumns,
+		errEntityIsNil,
+		errEntityNotStruct,
+		errEntityNotStructOrPointer,
+		errEntityNotStructType,
+		errNoColumnsToInsert,
+		errNoColumnsToUpdate,
+		errPagingRequestIsNil,
+		errPrimaryKeyNotFound,
+		errSoftDeleteUnsupported,
+		errTableDefinitionIsNil,
+		errTableIsEmpty,
+		errUpsertKeyColumnsNotFound,
+		errUpsertModeIsNotVersioned,
+		errUpsertModeUnsupported,
+	)
+	metrics.RegisterErrorClass(metrics.ErrorClassStorage,
+		errBatchCreateFailed,
+		errCountQueryFailed,
+		errCreateFailed,
+		errDeleteByFilterFailed,
+		errDeleteFailed,
+		errExistsQueryFailed,
+		errExplainQueryFailed,
+		errGetQueryFailed,
+		errHistogramQueryFailed,
+		errListQueryFailed,
+		errQueryServerTimeFailed,
+		errQuerySystemMutationsFailed,
+		errReadCurrentVersionFailed,
+		errReadUpdatedRecordFailed,
+		errReadUpsertedRecordFailed,
+		errRowsIterationError,
+		errScanCountFailed,
+		errScanExplainRowFailed,
+		errScanHistogramRowFailed,
+		errUnexpectedResultType,
+		errUnexpectedUpsertResultType,
+		errUpdateByFilterFailed,
+		errUpdateFailed,
+		errUpsertInsertFailed,
+		errUpsertPrimaryKeyNotFound,
+		errUpsertUpdateFailed,
+	)
+}
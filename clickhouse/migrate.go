@@ -0,0 +1,88 @@
+package clickhouse
+
+import (
+	"context"
+
+	"github.com/tx7do/go-crud/clickhouse/schema"
+)
+
+// CreateTable 按表定义执行 CREATE TABLE IF NOT EXISTS
+func (c *Client) CreateTable(ctx context.Context, t *schema.Table) error {
+	if t == nil {
+		return errTableDefinitionIsNil
+	}
+	return c.Exec(ctx, t.CreateSQL())
+}
+
+// DiffSchema 查询 system.columns 与 system.data_skipping_indices，对比表定义与现有结构。
+// 表不存在时返回的 Migration 包含全部列与索引
+func (c *Client) DiffSchema(ctx context.Context, t *schema.Table) (*schema.Migration, error) {
+	if t == nil {
+		return nil, errTableDefinitionIsNil
+	}
+
+	var columns []schema.ExistingColumn
+	if err := c.Select(ctx, &columns, schema.ColumnsQuery, t.Database, t.Database, t.Name); err != nil {
+		return nil, err
+	}
+
+	var indexes []schema.ExistingIndex
+	if len(columns) > 0 {
+		if err := c.Select(ctx, &indexes, schema.IndexesQuery, t.Database, t.Database, t.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return schema.Diff(t, columns, indexes), nil
+}
+
+// Migrate 创建不存在的表，并为已存在的表补充新增的列与索引。
+// 列类型变化与多余的列不会自动处理，仅记录警告
+func (c *Client) Migrate(ctx context.Context, tables ...*schema.Table) error {
+	for _, t := range tables {
+		if t == nil {
+			continue
+		}
+
+		m, err := c.DiffSchema(ctx, t)
+		if err != nil {
+			return err
+		}
+
+		// 没有任何现有列说明表不存在
+		if len(m.AddColumns) == len(t.Columns) && len(m.TypeChanges) == 0 && len(m.ExtraColumns) == 0 {
+			if err = c.CreateTable(ctx, t); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, tc := range m.TypeChanges {
+			c.logger.Warnf("column %s.%s type changed from %s to %s, skipped", t.QualifiedName(), tc.Column, tc.From, tc.To)
+		}
+		for _, name := range m.ExtraColumns {
+			c.logger.Warnf("column %s.%s is not defined in schema, skipped", t.QualifiedName(), name)
+		}
+
+		for _, stmt := range m.Statements() {
+			if err = c.Exec(ctx, stmt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Migrate 根据 ENTITY 的结构体标签生成表定义并执行迁移
+func (r *Repository[DTO, ENTITY]) Migrate(ctx context.Context, opts ...schema.Option) error {
+	if r.client == nil {
+		return errClientIsNil
+	}
+
+	t, err := schema.FromStruct[ENTITY](r.table, opts...)
+	if err != nil {
+		r.log.Errorf("build schema of %s failed: %v", r.table, err)
+		return err
+	}
+	return r.client.Migrate(ctx, t)
+}
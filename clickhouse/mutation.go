@@ -0,0 +1,97 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// DefaultMutationTimeout 等待 mutation 完成的默认超时时间
+	DefaultMutationTimeout = 5 * time.Minute
+
+	// MutationPollInterval 轮询 system.mutations 的间隔
+	MutationPollInterval = 200 * time.Millisecond
+)
+
+// WaitForMutations 轮询 system.mutations，等待当前表在 since 之后创建的 mutation（包括轻量级 DELETE）全部完成。
+// since 与 system.mutations.create_time 比较，应为执行 mutation 前读取的服务端时间（见 ServerTime），
+// 使用本地时钟时两端的时钟偏差会导致漏等或多等。
+// 任一 mutation 失败时返回其 latest_fail_reason；timeout 为 0 时使用 DefaultMutationTimeout。
+func (r *Repository[DTO, ENTITY]) WaitForMutations(ctx context.Context, since time.Time, timeout time.Duration) error {
+	if r.client == nil {
+		return errClientIsNil
+	}
+	if r.table == "" {
+		return errTableIsEmpty
+	}
+	if timeout <= 0 {
+		timeout = DefaultMutationTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	database, table := splitTableName(r.table)
+	sqlStr := "SELECT count(), anyIf(latest_fail_reason, latest_fail_reason != '') FROM system.mutations " +
+		"WHERE database = if(? = '', currentDatabase(), ?) AND table = ? AND is_done = 0 AND create_time >= ?"
+
+	ticker := time.NewTicker(MutationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var pending uint64
+		var failReason string
+		row := r.client.conn.QueryRow(ctx, sqlStr, database, database, table, since.Truncate(time.Second))
+		if err := row.Scan(&pending, &failReason); err != nil {
+			r.log.Errorf("query system.mutations failed: %v", err)
+			return errQuerySystemMutationsFailed
+		}
+		if failReason != "" {
+			r.log.Errorf("mutation on %s failed: %s", r.table, failReason)
+			return fmt.Errorf("mutation failed: %s", failReason)
+		}
+		if pending == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			r.log.Errorf("wait for mutations on %s timeout, %d pending", r.table, pending)
+			return fmt.Errorf("wait for mutations timeout: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServerTime 返回 ClickHouse 服务端的当前时间（精确到秒），用作 WaitForMutations 的 since
+func (r *Repository[DTO, ENTITY]) ServerTime(ctx context.Context) (time.Time, error) {
+	if r.client == nil {
+		return time.Time{}, errClientIsNil
+	}
+
+	var now time.Time
+	if err := r.client.conn.QueryRow(ctx, "SELECT now()").Scan(&now); err != nil {
+		r.log.Errorf("query server time failed: %v", err)
+		return time.Time{}, errQueryServerTimeFailed
+	}
+	return now, nil
+}
+
+// mutationStart 在需要等待 mutation 完成时，于执行前读取服务端时间
+func (r *Repository[DTO, ENTITY]) mutationStart(ctx context.Context, opts *FilterOptions) (time.Time, error) {
+	if opts == nil || !opts.WaitMutation {
+		return time.Time{}, nil
+	}
+	return r.ServerTime(ctx)
+}
+
+// splitTableName 将 "db.table" 拆分为库名与表名，未指定库名时库名为空，并去掉反引号
+func splitTableName(name string) (string, string) {
+	name = strings.ReplaceAll(strings.TrimSpace(name), "`", "")
+	if idx := strings.Index(name, "."); idx != -1 {
+		return name[:idx], name[idx+1:]
+	}
+	return "", name
+}
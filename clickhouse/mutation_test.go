@@ -0,0 +1,93 @@
+package clickhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/mapper"
+	"github.com/tx7do/go-utils/trans"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+func TestSplitTableName(t *testing.T) {
+	db, table := splitTableName("candles")
+	assert.Equal(t, "", db)
+	assert.Equal(t, "candles", table)
+
+	db, table = splitTableName(" `market`.`candles` ")
+	assert.Equal(t, "market", db)
+	assert.Equal(t, "candles", table)
+}
+
+func TestRepository_MutationStart(t *testing.T) {
+	repo := NewRepository[NoDeleted, NoDeleted](nil, mapper.NewCopierMapper[NoDeleted, NoDeleted](), "candles", log.NewHelper(log.DefaultLogger))
+
+	// 不等待 mutation 时不查询服务端时间
+	since, err := repo.mutationStart(context.Background(), &FilterOptions{})
+	require.NoError(t, err)
+	assert.True(t, since.IsZero())
+
+	// 等待时以服务端时间为准
+	_, err = repo.mutationStart(context.Background(), &FilterOptions{WaitMutation: true})
+	assert.EqualError(t, err, "clickhouse client is nil")
+}
+
+func TestRepository_BuildWhere(t *testing.T) {
+	repo := NewRepository[NoDeleted, NoDeleted](nil, mapper.NewCopierMapper[NoDeleted, NoDeleted](), "candles", log.NewHelper(log.DefaultLogger))
+
+	where, args, err := repo.buildWhere(nil)
+	require.NoError(t, err)
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+	assert.Equal(t, "candles", repo.fromClause(nil))
+
+	opts := &FilterOptions{
+		FilterExpr: &paginationV1.FilterExpr{
+			Type: paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{
+				{Field: "symbol", Op: paginationV1.Operator_EQ, Value: trans.Ptr("AAPL")},
+			},
+		},
+		Final: true,
+	}
+	where, args, err = repo.buildWhere(opts)
+	require.NoError(t, err)
+	assert.Contains(t, where, "symbol")
+	assert.Equal(t, []any{"AAPL"}, args)
+	assert.Equal(t, "candles FINAL", repo.fromClause(opts))
+
+	// Query 优先于 FilterExpr
+	opts.Query = `{"symbol":"MSFT"}`
+	where, args, err = repo.buildWhere(opts)
+	require.NoError(t, err)
+	assert.Contains(t, where, "symbol")
+	assert.Equal(t, []any{"MSFT"}, args)
+}
+
+func TestRepository_ToQuery(t *testing.T) {
+	repo := NewRepository[NoDeleted, NoDeleted](nil, mapper.NewCopierMapper[NoDeleted, NoDeleted](), "candles", log.NewHelper(log.DefaultLogger))
+
+	q, err := repo.ToQuery(&paginationV1.PagingRequest{
+		FilterExpr: &paginationV1.FilterExpr{
+			Type: paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{
+				{Field: "symbol", Op: paginationV1.Operator_EQ, Value: trans.Ptr("AAPL")},
+			},
+		},
+		Page:     trans.Ptr(uint32(1)),
+		PageSize: trans.Ptr(uint32(20)),
+	})
+	require.NoError(t, err)
+	assert.Contains(t, q.Statement, "FROM candles WHERE")
+	assert.Contains(t, q.Statement, "LIMIT 20")
+	assert.Equal(t, []any{"AAPL"}, q.Args)
+	assert.Contains(t, q.CountStatement, "SELECT COUNT(1) FROM candles WHERE")
+	assert.Equal(t, []any{"AAPL"}, q.CountArgs)
+
+	_, err = repo.ToQuery(nil)
+	assert.Error(t, err)
+}
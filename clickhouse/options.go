@@ -7,6 +7,12 @@ import (
 
 	clickhouseV2 "github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 )
 
 type Creator func() any
@@ -117,13 +123,49 @@ func WithDebugMode(debug bool) Option {
 
 func WithEnableTracing(enableTracing bool) Option {
 	return func(o *Client) {
-		//o.options.EnableTracing = enableTracing
+		o.enableTrace = enableTracing
+	}
+}
+
+func WithTracingOptions(opts ...tracing.Option) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, opts...)
+	}
+}
+
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithTracerProvider(provider))
+	}
+}
+
+func WithTracingAttributes(attrs ...attribute.KeyValue) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithAttributes(attrs...))
+	}
+}
+
+func WithTracingDBSystem(name string) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithDBSystem(name))
 	}
 }
 
 func WithEnableMetrics(enableMetrics bool) Option {
 	return func(o *Client) {
-		//o.options.EnableMetrics = enableMetrics
+		o.enableMetrics = enableMetrics
+	}
+}
+
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *Client) {
+		o.metrics = m
+	}
+}
+
+func WithSlowLog(l *slowlog.Logger) Option {
+	return func(o *Client) {
+		o.slowLog = l
 	}
 }
 
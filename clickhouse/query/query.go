@@ -138,6 +138,22 @@ func (qb *Builder) OrderBy(order string, desc bool) *Builder {
 	return qb
 }
 
+// OrderByExpr 追加完整的排序表达式（含方向），如 "lowerUTF8(name) DESC NULLS LAST"；
+// 表达式由调用方构造，不做字段名转换
+func (qb *Builder) OrderByExpr(expr string) *Builder {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return qb
+	}
+
+	if strings.Contains(expr, ";") || strings.Contains(expr, "--") {
+		panic("Invalid order expression")
+	}
+
+	qb.orderBy = append(qb.orderBy, expr)
+	return qb
+}
+
 // GroupBy 设置分组条件
 func (qb *Builder) GroupBy(columns ...string) *Builder {
 	qb.groupBy = append(qb.groupBy, columns...)
@@ -176,6 +192,11 @@ func (qb *Builder) Limit(limit int) *Builder {
 	return qb
 }
 
+// HasLimit 返回是否已通过 Limit 限制结果行数（LIMIT n BY 不限制总行数）
+func (qb *Builder) HasLimit() bool {
+	return qb.limit > 0 && qb.limitBy == ""
+}
+
 // Offset 设置查询结果的偏移量
 func (qb *Builder) Offset(offset int) *Builder {
 	qb.offset = offset
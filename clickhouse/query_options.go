@@ -0,0 +1,324 @@
+package clickhouse
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	clickhouseV2 "github.com/ClickHouse/clickhouse-go/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultKillTimeout 执行 KILL QUERY 的超时时间
+var DefaultKillTimeout = 5 * time.Second
+
+// QueryOptions 单次请求的查询设置，通过 WithQueryOptions 附加到 context，
+// 由 Client.Query/QueryRow/Select/Exec 以及 Repository 的读取方法使用
+type QueryOptions struct {
+	// Settings ClickHouse 查询级设置，如 max_execution_time、max_rows_to_read
+	Settings map[string]any
+
+	// QueryID 查询 ID；同一请求中的多条语句依次使用 QueryID、QueryID-2、QueryID-3……
+	// 为空时使用 OpenTelemetry 的 trace ID 与 span ID；没有 span 时不设置，由服务端生成，
+	// 仅在 KillOnCancel 需要查询 ID 时随机生成
+	QueryID string
+
+	// KillOnCancel context 取消时在服务端执行 KILL QUERY
+	KillOnCancel bool
+
+	stats []*QueryStats
+	seq   *atomic.Int64
+}
+
+// QueryOption 查询设置选项
+type QueryOption func(o *QueryOptions)
+
+// WithQuerySetting 设置单个 ClickHouse 查询级设置
+func WithQuerySetting(key string, value any) QueryOption {
+	return func(o *QueryOptions) {
+		if o.Settings == nil {
+			o.Settings = map[string]any{}
+		}
+		o.Settings[key] = value
+	}
+}
+
+// WithQuerySettings 批量设置 ClickHouse 查询级设置
+func WithQuerySettings(settings map[string]any) QueryOption {
+	return func(o *QueryOptions) {
+		for k, v := range settings {
+			WithQuerySetting(k, v)(o)
+		}
+	}
+}
+
+// WithMaxExecutionTime 设置 max_execution_time（秒，向上取整）
+func WithMaxExecutionTime(d time.Duration) QueryOption {
+	return WithQuerySetting("max_execution_time", int((d+time.Second-1)/time.Second))
+}
+
+// WithMaxRowsToRead 设置 max_rows_to_read
+func WithMaxRowsToRead(n uint64) QueryOption {
+	return WithQuerySetting("max_rows_to_read", n)
+}
+
+// WithMaxMemoryUsage 设置 max_memory_usage（字节）
+func WithMaxMemoryUsage(n uint64) QueryOption {
+	return WithQuerySetting("max_memory_usage", n)
+}
+
+// WithQueryCache 设置 use_query_cache
+func WithQueryCache(enable bool) QueryOption {
+	v := 0
+	if enable {
+		v = 1
+	}
+	return WithQuerySetting("use_query_cache", v)
+}
+
+// WithQueryID 设置查询 ID
+func WithQueryID(id string) QueryOption {
+	return func(o *QueryOptions) {
+		o.QueryID = id
+	}
+}
+
+// WithKillOnCancel context 取消时在服务端执行 KILL QUERY
+func WithKillOnCancel(enable bool) QueryOption {
+	return func(o *QueryOptions) {
+		o.KillOnCancel = enable
+	}
+}
+
+// WithQueryStats 将查询的进度与 profile 信息累计到 stats
+func WithQueryStats(stats *QueryStats) QueryOption {
+	return func(o *QueryOptions) {
+		if stats != nil {
+			o.stats = append(o.stats, stats)
+		}
+	}
+}
+
+// QueryStats 查询的进度与 profile 信息，同一请求中的多条语句累计在一起
+type QueryStats struct {
+	mu sync.Mutex
+
+	QueryIDs []string `json:"query_ids,omitempty"`
+
+	// 来自 Progress 包
+	ReadRows        uint64        `json:"read_rows"`
+	ReadBytes       uint64        `json:"read_bytes"`
+	TotalRowsToRead uint64        `json:"total_rows_to_read"`
+	WrittenRows     uint64        `json:"written_rows"`
+	WrittenBytes    uint64        `json:"written_bytes"`
+	Elapsed         time.Duration `json:"elapsed"`
+
+	// 来自 ProfileInfo 包
+	ResultRows      uint64 `json:"result_rows"`
+	ResultBytes     uint64 `json:"result_bytes"`
+	Blocks          uint64 `json:"blocks"`
+	RowsBeforeLimit uint64 `json:"rows_before_limit"`
+}
+
+func (s *QueryStats) addQueryID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.QueryIDs = append(s.QueryIDs, id)
+}
+
+func (s *QueryStats) addProgress(p *clickhouseV2.Progress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ReadRows += p.Rows
+	s.ReadBytes += p.Bytes
+	s.TotalRowsToRead += p.TotalRows
+	s.WrittenRows += p.WroteRows
+	s.WrittenBytes += p.WroteBytes
+	s.Elapsed += p.Elapsed
+}
+
+func (s *QueryStats) addProfile(p *clickhouseV2.ProfileInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ResultRows += p.Rows
+	s.ResultBytes += p.Bytes
+	s.Blocks += p.Blocks
+	s.RowsBeforeLimit += p.RowsBeforeLimit
+}
+
+type queryOptionsKey struct{}
+
+// WithQueryOptions 将查询设置附加到 ctx，已有的设置会被合并（Settings 按键覆盖）
+func WithQueryOptions(ctx context.Context, opts ...QueryOption) context.Context {
+	o := queryOptionsFrom(ctx)
+	prevID := o.QueryID
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.seq == nil || o.QueryID != prevID {
+		o.seq = new(atomic.Int64)
+	}
+	return context.WithValue(ctx, queryOptionsKey{}, o)
+}
+
+// queryOptionsFrom 返回 ctx 中查询设置的副本
+func queryOptionsFrom(ctx context.Context) QueryOptions {
+	o, ok := ctx.Value(queryOptionsKey{}).(QueryOptions)
+	if !ok {
+		return QueryOptions{}
+	}
+
+	cp := o
+	cp.Settings = make(map[string]any, len(o.Settings))
+	for k, v := range o.Settings {
+		cp.Settings[k] = v
+	}
+	cp.stats = append([]*QueryStats(nil), o.stats...)
+	return cp
+}
+
+// withDefaultQueryOptions 以 defaults 为基础、ctx 中已有的设置优先，返回新的 ctx
+func withDefaultQueryOptions(ctx context.Context, defaults []QueryOption) context.Context {
+	if len(defaults) == 0 {
+		return ctx
+	}
+
+	o := QueryOptions{}
+	for _, opt := range defaults {
+		opt(&o)
+	}
+	if cur, ok := ctx.Value(queryOptionsKey{}).(QueryOptions); ok {
+		for k, v := range cur.Settings {
+			WithQuerySetting(k, v)(&o)
+		}
+		if cur.QueryID != "" {
+			o.QueryID = cur.QueryID
+		}
+		o.KillOnCancel = o.KillOnCancel || cur.KillOnCancel
+		o.stats = append(o.stats, cur.stats...)
+		o.seq = cur.seq
+	}
+	if o.seq == nil {
+		o.seq = new(atomic.Int64)
+	}
+	return context.WithValue(ctx, queryOptionsKey{}, o)
+}
+
+// prepareQuery 将 ctx 中的查询设置转换为 clickhouse-go 的查询选项；
+// 设置了 KillOnCancel 时启动监听，返回的 done 须在语句结束（包括读取完所有行）后调用
+func (c *Client) prepareQuery(ctx context.Context) (context.Context, func()) {
+	o, ok := ctx.Value(queryOptionsKey{}).(QueryOptions)
+	if !ok {
+		return ctx, func() {}
+	}
+
+	queryID := nextQueryID(ctx, o)
+	if queryID == "" && o.KillOnCancel {
+		queryID = randomQueryID()
+	}
+
+	var chOpts []clickhouseV2.QueryOption
+	if queryID != "" {
+		chOpts = append(chOpts, clickhouseV2.WithQueryID(queryID))
+	}
+	if len(o.Settings) > 0 {
+		settings := make(clickhouseV2.Settings, len(o.Settings))
+		for k, v := range o.Settings {
+			settings[k] = v
+		}
+		chOpts = append(chOpts, clickhouseV2.WithSettings(settings))
+	}
+	if len(o.stats) > 0 {
+		stats := o.stats
+		if queryID != "" {
+			for _, s := range stats {
+				s.addQueryID(queryID)
+			}
+		}
+		chOpts = append(chOpts,
+			clickhouseV2.WithProgress(func(p *clickhouseV2.Progress) {
+				for _, s := range stats {
+					s.addProgress(p)
+				}
+			}),
+			clickhouseV2.WithProfileInfo(func(p *clickhouseV2.ProfileInfo) {
+				for _, s := range stats {
+					s.addProfile(p)
+				}
+			}),
+		)
+	}
+	qctx := clickhouseV2.Context(ctx, chOpts...)
+
+	if !o.KillOnCancel || ctx.Done() == nil {
+		return qctx, func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			// 语句因取消而结束时两个分支可能同时就绪，仍需终止服务端查询
+			if ctx.Err() == nil {
+				return
+			}
+		case <-ctx.Done():
+		}
+		c.killQuery(queryID)
+	}()
+	return qctx, func() { close(done) }
+}
+
+// killQuery 在服务端异步终止查询
+func (c *Client) killQuery(queryID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultKillTimeout)
+	defer cancel()
+
+	if err := c.conn.Exec(ctx, "KILL QUERY WHERE query_id = ? ASYNC", queryID); err != nil {
+		c.logger.Errorf("kill query %s failed: %v", queryID, err)
+		return
+	}
+	c.logger.Infof("killed query %s", queryID)
+}
+
+// nextQueryID 返回本条语句的查询 ID，未设置 QueryID 且没有 span 时返回空字符串
+func nextQueryID(ctx context.Context, o QueryOptions) string {
+	base := o.QueryID
+	if base == "" {
+		sc := trace.SpanContextFromContext(ctx)
+		if !sc.IsValid() {
+			return ""
+		}
+		base = sc.TraceID().String() + "-" + sc.SpanID().String()
+	}
+
+	var n int64 = 1
+	if o.seq != nil {
+		n = o.seq.Add(1)
+	}
+	if n == 1 {
+		return base
+	}
+	return base + "-" + strconv.FormatInt(n, 10)
+}
+
+// randomQueryID 随机生成查询 ID
+func randomQueryID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// SetQueryOptions 设置仓库读取方法（List/Get/Count/Exists）默认的查询设置，ctx 中通过 WithQueryOptions 附加的设置优先
+func (r *Repository[DTO, ENTITY]) SetQueryOptions(opts ...QueryOption) {
+	r.queryOptions = opts
+}
+
+// queryContext 将仓库默认的查询设置附加到 ctx
+func (r *Repository[DTO, ENTITY]) queryContext(ctx context.Context) context.Context {
+	return withDefaultQueryOptions(ctx, r.queryOptions)
+}
@@ -0,0 +1,121 @@
+package clickhouse
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	clickhouseV2 "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueryConn Select 阻塞直到 ctx 取消，Exec 记录执行的语句与参数
+type fakeQueryConn struct {
+	clickhouseV2.Conn
+
+	mu    sync.Mutex
+	execs [][]any
+}
+
+func (c *fakeQueryConn) Select(ctx context.Context, _ any, _ string, _ ...any) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (c *fakeQueryConn) Exec(_ context.Context, query string, args ...any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.execs = append(c.execs, append([]any{query}, args...))
+	return nil
+}
+
+func (c *fakeQueryConn) executed() [][]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([][]any(nil), c.execs...)
+}
+
+func TestQueryOptions_Merge(t *testing.T) {
+	stats := &QueryStats{}
+	ctx := WithQueryOptions(context.Background(), WithMaxExecutionTime(1500*time.Millisecond), WithQueryID("req-1"))
+	ctx = WithQueryOptions(ctx, WithMaxRowsToRead(1000), WithQueryStats(stats))
+
+	o := queryOptionsFrom(ctx)
+	assert.Equal(t, map[string]any{"max_execution_time": 2, "max_rows_to_read": uint64(1000)}, o.Settings)
+	assert.Equal(t, "req-1", o.QueryID)
+	assert.Equal(t, []*QueryStats{stats}, o.stats)
+
+	// ctx 中的设置优先于仓库默认设置
+	ctx = withDefaultQueryOptions(ctx, []QueryOption{WithMaxRowsToRead(10), WithQueryCache(true), WithQueryID("default")})
+	o = queryOptionsFrom(ctx)
+	assert.Equal(t, map[string]any{"max_execution_time": 2, "max_rows_to_read": uint64(1000), "use_query_cache": 1}, o.Settings)
+	assert.Equal(t, "req-1", o.QueryID)
+
+	assert.Equal(t, "req-1", nextQueryID(ctx, o))
+	assert.Equal(t, "req-1-2", nextQueryID(ctx, o))
+
+	// 未设置 QueryID 且没有 span 时不生成查询 ID
+	assert.Empty(t, nextQueryID(context.Background(), QueryOptions{}))
+}
+
+func TestClient_PrepareQuery(t *testing.T) {
+	c := &Client{conn: &fakeQueryConn{}, logger: log.NewHelper(log.DefaultLogger)}
+
+	ctx := context.Background()
+	qctx, done := c.prepareQuery(ctx)
+	done()
+	assert.Equal(t, ctx, qctx)
+
+	stats := &QueryStats{}
+	ctx = WithQueryOptions(ctx, WithQueryID("list"), WithQueryStats(stats))
+	_, done = c.prepareQuery(ctx)
+	done()
+	_, done = c.prepareQuery(ctx)
+	done()
+	assert.Equal(t, []string{"list", "list-2"}, stats.QueryIDs)
+
+	// 列表方法只附加 QueryStats 时不设置查询 ID
+	stats = &QueryStats{}
+	_, done = c.prepareQuery(WithQueryOptions(context.Background(), WithQueryStats(stats)))
+	done()
+	assert.Empty(t, stats.QueryIDs)
+
+	// KillOnCancel 需要查询 ID，此时随机生成
+	_, done = c.prepareQuery(WithQueryOptions(context.Background(), WithKillOnCancel(true), WithQueryStats(stats)))
+	done()
+	require.Len(t, stats.QueryIDs, 1)
+	assert.Len(t, stats.QueryIDs[0], 32)
+
+	stats.addProgress(&clickhouseV2.Progress{Rows: 10, Bytes: 100})
+	stats.addProgress(&clickhouseV2.Progress{Rows: 5, Bytes: 50})
+	stats.addProfile(&clickhouseV2.ProfileInfo{Rows: 3, Blocks: 1})
+	assert.Equal(t, uint64(15), stats.ReadRows)
+	assert.Equal(t, uint64(150), stats.ReadBytes)
+	assert.Equal(t, uint64(3), stats.ResultRows)
+}
+
+func TestClient_KillOnCancel(t *testing.T) {
+	conn := &fakeQueryConn{}
+	c := &Client{conn: conn, logger: log.NewHelper(log.DefaultLogger)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = WithQueryOptions(ctx, WithQueryID("slow-report"), WithKillOnCancel(true))
+
+	errCh := make(chan error, 1)
+	go func() {
+		var dest []struct{}
+		errCh <- c.Select(ctx, &dest, "SELECT sleep(3)")
+	}()
+
+	cancel()
+	require.ErrorIs(t, <-errCh, ErrQueryExecutionFailed)
+	assert.Eventually(t, func() bool {
+		execs := conn.executed()
+		return len(execs) == 1 && execs[0][0] == "KILL QUERY WHERE query_id = ? ASYNC" && execs[0][1] == "slow-report"
+	}, time.Second, 5*time.Millisecond)
+}
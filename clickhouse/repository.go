@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/tx7do/go-utils/mapper"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
@@ -17,13 +19,40 @@ import (
 	"github.com/tx7do/go-crud/clickhouse/filter"
 	paging "github.com/tx7do/go-crud/clickhouse/pagination"
 	"github.com/tx7do/go-crud/clickhouse/query"
+	"github.com/tx7do/go-crud/clickhouse/schema"
 	"github.com/tx7do/go-crud/clickhouse/sorting"
+	"github.com/tx7do/go-crud/guard"
+	"github.com/tx7do/go-crud/limits"
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 )
 
 // PagingResult 是通用的分页返回结构，包含 items 和 total 字段
 type PagingResult[E any] struct {
 	Items []*E   `json:"items"`
 	Total uint64 `json:"total"`
+
+	// Stats 计数与列表查询累计的读取行数、字节数等信息
+	Stats *QueryStats `json:"stats,omitempty"`
+}
+
+// FilterOptions 过滤选项，用于 Get/Count/Exists/UpdateWithFilter/DeleteWithFilter
+type FilterOptions struct {
+	// Query/OrQuery 查询字符串过滤（JSON），优先于 FilterExpr
+	Query   string
+	OrQuery string
+
+	// FilterExpr 结构化过滤
+	FilterExpr *paginationV1.FilterExpr
+
+	// Final 查询时添加 FINAL 修饰符，读取 ReplacingMergeTree 等引擎合并后的结果
+	Final bool
+
+	// WaitMutation 更新/删除后轮询 system.mutations，等待 mutation 完成
+	WaitMutation bool
+	// MutationTimeout 等待 mutation 的超时时间，0 表示使用 DefaultMutationTimeout
+	MutationTimeout time.Duration
 }
 
 // Repository GORM 仓库，包含常用的 CRUD 方法
@@ -46,9 +75,25 @@ type Repository[DTO any, ENTITY any] struct {
 	log    *log.Helper
 
 	table string
+
+	guardOptions guard.Options
+	limitPolicy  *limits.Policy
+	versioning   VersioningOptions
+	queryOptions []QueryOption
+
+	metrics    *metrics.Metrics
+	slowLog    *slowlog.Logger
+	entityName string
 }
 
 func NewRepository[DTO any, ENTITY any](client *Client, mapper *mapper.CopierMapper[DTO, ENTITY], table string, log *log.Helper) *Repository[DTO, ENTITY] {
+	var m *metrics.Metrics
+	var sl *slowlog.Logger
+	if client != nil {
+		m = client.metrics
+		sl = client.slowLog
+	}
+
 	return &Repository[DTO, ENTITY]{
 		client: client,
 		mapper: mapper,
@@ -67,19 +112,33 @@ func NewRepository[DTO any, ENTITY any](client *Client, mapper *mapper.CopierMap
 		structuredFilter:  filter.NewStructuredFilter(),
 
 		fieldSelector: field.NewFieldSelector(),
+
+		metrics:    m,
+		slowLog:    sl,
+		entityName: metrics.EntityName[ENTITY](),
+	}
+}
+
+// Count 使用 FilterOptions（FilterExpr 或查询字符串过滤）计算符合条件的记录数，opts 为 nil 时统计全表。
+// 示例调用： total, err := q.Count(ctx, &FilterOptions{FilterExpr: expr})
+func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, opts *FilterOptions) (ret uint64, err error) {
+	obs := r.observe("Count")
+	defer func() { obs.End(err) }()
+
+	where, args, err := r.buildWhere(opts)
+	if err != nil {
+		return 0, err
 	}
+	return r.countFrom(r.queryContext(ctx), r.fromClause(opts), where, args...)
 }
 
-// Count 使用 ClickHouse client 计算符合 baseWhere 的记录数
-// baseWhere: 可以包含 "WHERE ..." 前缀或只写条件表达式（函数会自动拼接）
-// 示例调用： total, err := q.Count(ctx, "id = ?", id)
-// 支持当只传入一个切片参数时自动展开： q.Count(ctx, "id IN (?)", []int{1,2,3})
-func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, baseWhere string, whereArgs ...any) (uint64, error) {
+// countFrom 在 from（表名，可带 FINAL）上计算符合 baseWhere 的记录数
+func (r *Repository[DTO, ENTITY]) countFrom(ctx context.Context, from string, baseWhere string, whereArgs ...any) (uint64, error) {
 	if r.client == nil {
-		return 0, errors.New("clickhouse client is nil")
+		return 0, errClientIsNil
 	}
 	if r.table == "" {
-		return 0, errors.New("table is empty")
+		return 0, errTableIsEmpty
 	}
 
 	// 展开单个切片参数为独立参数
@@ -94,7 +153,7 @@ func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, baseWhere string, w
 		}
 	}
 
-	aSql := "SELECT COUNT(1) FROM " + r.table
+	aSql := "SELECT COUNT(1) FROM " + from
 	bw := strings.TrimSpace(baseWhere)
 	if bw != "" {
 		// 如果用户传入的不包含 WHERE 前缀，自动添加
@@ -106,10 +165,13 @@ func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, baseWhere string, w
 	}
 
 	// 使用底层连接执行查询
+	ctx, done := r.client.prepareQuery(ctx)
+	defer done()
+
 	rows, err := r.client.conn.Query(ctx, aSql, whereArgs...)
 	if err != nil {
 		r.log.Errorf("clickhouse count query failed: %v", err)
-		return 0, errors.New("count query failed")
+		return 0, errCountQueryFailed
 	}
 	defer func() {
 		if cerr := rows.Close(); cerr != nil {
@@ -121,14 +183,14 @@ func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, baseWhere string, w
 	if rows.Next() {
 		if scanErr := rows.Scan(&cnt); scanErr != nil {
 			r.log.Errorf("scan count failed: %v", scanErr)
-			return 0, errors.New("scan count failed")
+			return 0, errScanCountFailed
 		}
 		return cnt, nil
 	}
 
 	if iterErr := rows.Err(); iterErr != nil {
 		r.log.Errorf("rows iteration error: %v", iterErr)
-		return 0, errors.New("rows iteration error")
+		return 0, errRowsIterationError
 	}
 
 	// 没有行时返回 0
@@ -136,15 +198,77 @@ func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, baseWhere string, w
 }
 
 // ListWithPaging 使用 PagingRequest 查询列表
-func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *paginationV1.PagingRequest) (*PagingResult[DTO], error) {
+func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *paginationV1.PagingRequest) (ret *PagingResult[DTO], err error) {
+	obs := r.observe("ListWithPaging")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Items)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPagingRequest(req)
+
 	if r.client == nil {
-		return nil, errors.New("clickhouse client is nil")
+		return nil, errClientIsNil
 	}
 	if r.table == "" {
-		return nil, errors.New("table is empty")
+		return nil, errTableIsEmpty
+	}
+
+	stats := &QueryStats{}
+	ctx = WithQueryOptions(r.queryContext(ctx), WithQueryStats(stats))
+
+	ctx, span := r.startSpan(ctx, "ListWithPaging", tracing.PagingAttributes(req)...)
+	defer func() { span.End(err) }()
+
+	if req, err = r.limitPolicy.ApplyPaging(r.entityName, req); err != nil {
+		return nil, err
+	}
+
+	queryBuilder := r.buildPagingQuery(req)
+
+	// 计数
+	aSql, args := queryBuilder.BuildWhereParam()
+	total, err := r.countFrom(ctx, r.readSource(false), aSql, args...)
+	if err != nil {
+		r.log.Errorf("count query failed: %v", err)
+		return nil, err
+	}
+
+	// 使用 client.Query（creator + results slice）
+	var rawResults []any
+	creator := func() any {
+		var e ENTITY
+		return &e
+	}
+	aSql, args = queryBuilder.Build()
+	obs.SetStatement(aSql, args...)
+	if err = r.client.Query(ctx, creator, &rawResults, aSql, args...); err != nil {
+		r.log.Errorf("list query failed: %v", err)
+		return nil, errListQueryFailed
+	}
+
+	// 转换为 DTOs
+	dtos := make([]*DTO, 0, len(rawResults))
+	for _, res := range rawResults {
+		if ptr, ok := res.(*ENTITY); ok {
+			dtos = append(dtos, r.mapper.ToDTO(ptr))
+		}
+	}
+
+	span.SetReturnedRows(int64(len(dtos)))
+
+	res := &PagingResult[DTO]{
+		Items: dtos,
+		Total: uint64(total),
+		Stats: stats,
 	}
+	return res, nil
+}
 
-	queryBuilder := query.NewQueryBuilder(r.table, r.log)
+// buildPagingQuery 按 PagingRequest 构建列表查询（过滤、字段、排序与分页）
+func (r *Repository[DTO, ENTITY]) buildPagingQuery(req *paginationV1.PagingRequest) *query.Builder {
+	queryBuilder := query.NewQueryBuilder(r.readSource(false), r.log)
 
 	var err error
 
@@ -161,14 +285,6 @@ func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *pagin
 		}
 	}
 
-	// 计数
-	aSql, args := queryBuilder.BuildWhereParam()
-	total, err := r.Count(ctx, aSql, args...)
-	if err != nil {
-		r.log.Errorf("count query failed: %v", err)
-		return nil, err
-	}
-
 	// select fields
 	if req.FieldMask != nil && len(req.GetFieldMask().Paths) > 0 {
 		_, err = r.fieldSelector.BuildSelector(queryBuilder, req.GetFieldMask().GetPaths())
@@ -195,45 +311,38 @@ func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *pagin
 		}
 	}
 
-	// 使用 client.Query（creator + results slice）
-	var rawResults []any
-	creator := func() any {
-		var e ENTITY
-		return &e
-	}
-	aSql, args = queryBuilder.Build()
-	if err = r.client.Query(ctx, creator, &rawResults, aSql, args...); err != nil {
-		r.log.Errorf("list query failed: %v", err)
-		return nil, errors.New("list query failed")
-	}
-
-	// 转换为 DTOs
-	dtos := make([]*DTO, 0, len(rawResults))
-	for _, res := range rawResults {
-		if ptr, ok := res.(*ENTITY); ok {
-			dtos = append(dtos, r.mapper.ToDTO(ptr))
-		}
-	}
-
-	res := &PagingResult[DTO]{
-		Items: dtos,
-		Total: uint64(total),
-	}
-	return res, nil
+	return queryBuilder
 }
 
 // ListWithPagination 使用 PaginationRequest 查询列表
-func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *paginationV1.PaginationRequest) (*PagingResult[DTO], error) {
+func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *paginationV1.PaginationRequest) (ret *PagingResult[DTO], err error) {
+	obs := r.observe("ListWithPagination")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Items)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPaginationRequest(req)
+
 	if r.client == nil {
-		return nil, errors.New("clickhouse client is nil")
+		return nil, errClientIsNil
 	}
 	if r.table == "" {
-		return nil, errors.New("table is empty")
+		return nil, errTableIsEmpty
 	}
 
-	queryBuilder := query.NewQueryBuilder(r.table, r.log)
+	stats := &QueryStats{}
+	ctx = WithQueryOptions(r.queryContext(ctx), WithQueryStats(stats))
 
-	var err error
+	ctx, span := r.startSpan(ctx, "ListWithPagination", tracing.PaginationAttributes(req)...)
+	defer func() { span.End(err) }()
+
+	if req, err = r.limitPolicy.ApplyPagination(r.entityName, req); err != nil {
+		return nil, err
+	}
+
+	queryBuilder := query.NewQueryBuilder(r.readSource(false), r.log)
 
 	// filters
 	if req.Query != nil || req.OrQuery != nil {
@@ -250,7 +359,7 @@ func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *p
 
 	// 计数
 	aSql, args := queryBuilder.BuildWhereParam()
-	total, err := r.Count(ctx, aSql, args...)
+	total, err := r.countFrom(ctx, r.readSource(false), aSql, args...)
 	if err != nil {
 		r.log.Errorf("count query failed: %v", err)
 		return nil, err
@@ -288,9 +397,10 @@ func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *p
 		return &e
 	}
 	aSql, args = queryBuilder.Build()
+	obs.SetStatement(aSql, args...)
 	if err = r.client.Query(ctx, creator, &rawResults, aSql, args...); err != nil {
 		r.log.Errorf("list query failed: %v", err)
-		return nil, errors.New("list query failed")
+		return nil, errListQueryFailed
 	}
 
 	// 转换为 DTOs
@@ -301,27 +411,44 @@ func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *p
 		}
 	}
 
+	span.SetReturnedRows(int64(len(dtos)))
+
 	res := &PagingResult[DTO]{
 		Items: dtos,
 		Total: uint64(total),
+		Stats: stats,
 	}
 	return res, nil
 }
 
-// Get 根据查询条件获取单条记录
-func (r *Repository[DTO, ENTITY]) Get(ctx context.Context, viewMask *fieldmaskpb.FieldMask) (*DTO, error) {
+// Get 根据 FilterOptions 获取单条记录，未找到时返回 nil, nil
+// 示例调用： dto, err := q.Get(ctx, &FilterOptions{FilterExpr: expr}, viewMask)
+func (r *Repository[DTO, ENTITY]) Get(ctx context.Context, opts *FilterOptions, viewMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("Get")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return nil, errors.New("clickhouse client is nil")
+		return nil, errClientIsNil
 	}
 	if r.table == "" {
-		return nil, errors.New("table is empty")
+		return nil, errTableIsEmpty
 	}
 
+	ctx = r.queryContext(ctx)
+
 	// 规范 viewMask 路径
 	field.NormalizeFieldMaskPaths(viewMask)
 
 	// 构建查询
-	qb := query.NewQueryBuilder(r.table, r.log)
+	qb := query.NewQueryBuilder(r.fromClause(opts), r.log)
+	if err := r.applyFilter(qb, opts); err != nil {
+		return nil, err
+	}
 
 	// 如果提供了 viewMask，则构建 select 子句（日志记录错误但继续）
 	if viewMask != nil && len(viewMask.Paths) > 0 {
@@ -330,52 +457,47 @@ func (r *Repository[DTO, ENTITY]) Get(ctx context.Context, viewMask *fieldmaskpb
 		}
 	}
 
-	// 获取 SQL 与参数，并确保只取一条记录
-	sqlStr, args := qb.Build()
-	sqlStr = strings.TrimSpace(sqlStr)
-	upper := strings.ToUpper(sqlStr)
-	if !strings.Contains(upper, "LIMIT") {
-		sqlStr = sqlStr + " LIMIT 1"
+	// 确保只取一条记录
+	if !qb.HasLimit() {
+		qb.Limit(1)
 	}
+	sqlStr, args := qb.Build()
 
 	// 执行查询并读取首条结果
-	var rawResults []any
-	creator := func() any {
-		var e ENTITY
-		return &e
-	}
-	if err := r.client.Query(ctx, creator, &rawResults, sqlStr, args...); err != nil {
+	ent, err := SelectOne[ENTITY](ctx, r.client, SQL(sqlStr, args...))
+	if err != nil {
 		r.log.Errorf("get query failed: %v", err)
-		return nil, errors.New("get query failed")
+		return nil, errGetQueryFailed
 	}
-
-	if len(rawResults) == 0 {
+	if ent == nil {
 		return nil, nil
 	}
-
-	if ptr, ok := rawResults[0].(*ENTITY); ok {
-		return r.mapper.ToDTO(ptr), nil
-	}
-
-	r.log.Errorf("unexpected result type")
-	return nil, errors.New("unexpected result type")
+	return r.mapper.ToDTO(ent), nil
 }
 
 // Only alias
-func (r *Repository[DTO, ENTITY]) Only(ctx context.Context, viewMask *fieldmaskpb.FieldMask) (*DTO, error) {
-	return r.Get(ctx, viewMask)
+func (r *Repository[DTO, ENTITY]) Only(ctx context.Context, opts *FilterOptions, viewMask *fieldmaskpb.FieldMask) (*DTO, error) {
+	return r.Get(ctx, opts, viewMask)
 }
 
 // Create 在数据库中创建一条记录，返回创建后的 DTO
-func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO, viewMask *fieldmaskpb.FieldMask) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO, viewMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("Create")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return nil, errors.New("clickhouse client is nil")
+		return nil, errClientIsNil
 	}
 	if r.table == "" {
-		return nil, errors.New("table is empty")
+		return nil, errTableIsEmpty
 	}
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
 	// 规范 viewMask 路径
@@ -390,7 +512,7 @@ func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO, viewMask
 		v = v.Elem()
 	}
 	if !v.IsValid() || v.Kind() != reflect.Struct {
-		return nil, errors.New("entity must be a struct or pointer to struct")
+		return nil, errEntityNotStructOrPointer
 	}
 	t := v.Type()
 
@@ -410,19 +532,7 @@ func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO, viewMask
 			continue
 		}
 		// determine column name
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 
 		// apply viewMask if present (支持按字段名或列名匹配)
 		if viewMask != nil && len(mask) > 0 {
@@ -436,7 +546,7 @@ func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO, viewMask
 	}
 
 	if len(cols) == 0 {
-		return nil, errors.New("no columns to insert")
+		return nil, errNoColumnsToInsert
 	}
 
 	placeholders := strings.Repeat("?,", len(cols))
@@ -445,7 +555,7 @@ func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO, viewMask
 
 	if err := r.client.conn.Exec(ctx, aSql, vals...); err != nil {
 		r.log.Errorf("create failed: %v", err)
-		return nil, errors.New("create failed")
+		return nil, errCreateFailed
 	}
 
 	// 返回创建后的 DTO（ClickHouse 不一定会回填自增字段，视表结构而定）
@@ -453,15 +563,21 @@ func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO, viewMask
 }
 
 // CreateX 使用传入的 db 创建记录，支持 viewMask 指定插入字段，返回受影响行数
-func (r *Repository[DTO, ENTITY]) CreateX(ctx context.Context, dto *DTO, viewMask *fieldmaskpb.FieldMask) (int64, error) {
+func (r *Repository[DTO, ENTITY]) CreateX(ctx context.Context, dto *DTO, viewMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("CreateX")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return 0, errors.New("clickhouse client is nil")
+		return 0, errClientIsNil
 	}
 	if r.table == "" {
-		return 0, errors.New("table is empty")
+		return 0, errTableIsEmpty
 	}
 	if dto == nil {
-		return 0, errors.New("dto is nil")
+		return 0, errDTOIsNil
 	}
 
 	// 规范 viewMask 路径
@@ -476,7 +592,7 @@ func (r *Repository[DTO, ENTITY]) CreateX(ctx context.Context, dto *DTO, viewMas
 		v = v.Elem()
 	}
 	if !v.IsValid() || v.Kind() != reflect.Struct {
-		return 0, errors.New("entity must be a struct or pointer to struct")
+		return 0, errEntityNotStructOrPointer
 	}
 	t := v.Type()
 
@@ -496,19 +612,7 @@ func (r *Repository[DTO, ENTITY]) CreateX(ctx context.Context, dto *DTO, viewMas
 			continue
 		}
 		// determine column name
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 
 		// apply viewMask if present (支持按字段名或列名匹配)
 		if viewMask != nil && len(mask) > 0 {
@@ -522,7 +626,7 @@ func (r *Repository[DTO, ENTITY]) CreateX(ctx context.Context, dto *DTO, viewMas
 	}
 
 	if len(cols) == 0 {
-		return 0, errors.New("no columns to insert")
+		return 0, errNoColumnsToInsert
 	}
 
 	placeholders := strings.Repeat("?,", len(cols))
@@ -532,7 +636,7 @@ func (r *Repository[DTO, ENTITY]) CreateX(ctx context.Context, dto *DTO, viewMas
 	// 执行插入（底层 Exec 通常只返回 error）
 	if err := r.client.conn.Exec(ctx, aSql, vals...); err != nil {
 		r.log.Errorf("create failed: %v", err)
-		return 0, errors.New("create failed")
+		return 0, errCreateFailed
 	}
 
 	// ClickHouse Exec 通常不提供 RowsAffected，成功则返回 1（表示已插入一条）
@@ -540,12 +644,18 @@ func (r *Repository[DTO, ENTITY]) CreateX(ctx context.Context, dto *DTO, viewMas
 }
 
 // BatchCreate 批量创建记录，返回创建后的 DTO 列表
-func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO, viewMask *fieldmaskpb.FieldMask) ([]*DTO, error) {
+func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO, viewMask *fieldmaskpb.FieldMask) (ret []*DTO, err error) {
+	obs := r.observe("BatchCreate")
+	defer func() {
+		obs.AffectedRows(int64(len(ret)))
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return nil, errors.New("clickhouse client is nil")
+		return nil, errClientIsNil
 	}
 	if r.table == "" {
-		return nil, errors.New("table is empty")
+		return nil, errTableIsEmpty
 	}
 	if len(dtos) == 0 {
 		return nil, nil
@@ -575,7 +685,7 @@ func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO,
 		firstVal = firstVal.Elem()
 	}
 	if !firstVal.IsValid() || firstVal.Kind() != reflect.Struct {
-		return nil, errors.New("entity must be a struct or pointer to struct")
+		return nil, errEntityNotStructOrPointer
 	}
 	t := firstVal.Type()
 
@@ -593,19 +703,7 @@ func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO,
 			continue
 		}
 		// determine column name
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 
 		// apply viewMask if present (支持按字段名或列名匹配)
 		if viewMask != nil && len(mask) > 0 {
@@ -619,7 +717,7 @@ func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO,
 	}
 
 	if len(cols) == 0 {
-		return nil, errors.New("no columns to insert")
+		return nil, errNoColumnsToInsert
 	}
 
 	// 为每条实体收集值，保证顺序与 cols 对应
@@ -646,7 +744,7 @@ func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO,
 	// 执行插入
 	if err := r.client.conn.Exec(ctx, aSql, vals...); err != nil {
 		r.log.Errorf("batch create failed: %v", err)
-		return nil, errors.New("batch create failed")
+		return nil, errBatchCreateFailed
 	}
 
 	// 将实体映射回 DTO 列表并返回
@@ -660,15 +758,23 @@ func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO,
 }
 
 // Update 使用传入的 db（可包含 Where）更新记录，支持 updateMask 指定更新字段
-func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, dto *DTO, updateMask *fieldmaskpb.FieldMask) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("Update")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return nil, errors.New("clickhouse client is nil")
+		return nil, errClientIsNil
 	}
 	if r.table == "" {
-		return nil, errors.New("table is empty")
+		return nil, errTableIsEmpty
 	}
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
 	// 规范 updateMask 路径
@@ -689,7 +795,7 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, dto *DTO, updateMa
 		v = v.Elem()
 	}
 	if !v.IsValid() || v.Kind() != reflect.Struct {
-		return nil, errors.New("entity must be a struct or pointer to struct")
+		return nil, errEntityNotStructOrPointer
 	}
 	t := v.Type()
 
@@ -706,19 +812,7 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, dto *DTO, updateMa
 			pkIdx = i
 		}
 		// determine column name
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 		// detect id-like column if pk not set
 		if pkIdx == -1 {
 			lc := strings.ToLower(col)
@@ -733,7 +827,7 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, dto *DTO, updateMa
 	}
 
 	if pkIdx == -1 || pkCol == "" {
-		return nil, errors.New("primary key field not found; cannot determine WHERE clause")
+		return nil, errPrimaryKeyNotFound
 	}
 
 	// 构建更新列和值，排除主键
@@ -748,19 +842,7 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, dto *DTO, updateMa
 			continue
 		}
 		// determine column name
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 
 		// 如果提供了 updateMask，则只更新被包含的字段（支持按字段名或列名）
 		if len(mask) > 0 {
@@ -779,7 +861,7 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, dto *DTO, updateMa
 	}
 
 	if len(setExprs) == 0 {
-		return nil, errors.New("no columns to update")
+		return nil, errNoColumnsToUpdate
 	}
 
 	// 主键值
@@ -793,7 +875,7 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, dto *DTO, updateMa
 	args := append(setVals, pkVal)
 	if err := r.client.conn.Exec(ctx, aSql, args...); err != nil {
 		r.log.Errorf("update failed: %v", err)
-		return nil, errors.New("update failed")
+		return nil, errUpdateFailed
 	}
 
 	// 尝试读取更新后的记录并返回（注意：ClickHouse mutation 可能是异步的）
@@ -805,7 +887,7 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, dto *DTO, updateMa
 	selectSQL := fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT 1", r.table, whereClause)
 	if err := r.client.Query(ctx, creator, &rawResults, selectSQL, pkVal); err != nil {
 		r.log.Errorf("read updated record failed: %v", err)
-		return nil, errors.New("read updated record failed")
+		return nil, errReadUpdatedRecordFailed
 	}
 	if len(rawResults) == 0 {
 		return nil, nil
@@ -814,19 +896,25 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, dto *DTO, updateMa
 		return r.mapper.ToDTO(ptr), nil
 	}
 	r.log.Errorf("unexpected result type after update")
-	return nil, errors.New("unexpected result type")
+	return nil, errUnexpectedResultType
 }
 
 // UpdateX 使用传入的 db（可包含 Where）更新记录，支持 updateMask 指定更新字段，返回受影响行数
-func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, dto *DTO, updateMask *fieldmaskpb.FieldMask) (int64, error) {
+func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("UpdateX")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return 0, errors.New("clickhouse client is nil")
+		return 0, errClientIsNil
 	}
 	if r.table == "" {
-		return 0, errors.New("table is empty")
+		return 0, errTableIsEmpty
 	}
 	if dto == nil {
-		return 0, errors.New("dto is nil")
+		return 0, errDTOIsNil
 	}
 
 	// 规范 updateMask 路径
@@ -847,7 +935,7 @@ func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, dto *DTO, updateM
 		v = v.Elem()
 	}
 	if !v.IsValid() || v.Kind() != reflect.Struct {
-		return 0, errors.New("entity must be a struct or pointer to struct")
+		return 0, errEntityNotStructOrPointer
 	}
 	t := v.Type()
 
@@ -862,19 +950,7 @@ func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, dto *DTO, updateM
 		if sf.Tag.Get("pk") == "true" {
 			pkIdx = i
 		}
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 		if pkIdx == -1 {
 			lc := strings.ToLower(col)
 			if lc == "id" || strings.ToLower(sf.Name) == "id" {
@@ -887,7 +963,7 @@ func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, dto *DTO, updateM
 	}
 
 	if pkIdx == -1 || pkCol == "" {
-		return 0, errors.New("primary key field not found; cannot determine WHERE clause")
+		return 0, errPrimaryKeyNotFound
 	}
 
 	// 构建更新列和值，排除主键
@@ -901,19 +977,7 @@ func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, dto *DTO, updateM
 		if sf.PkgPath != "" {
 			continue
 		}
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 
 		// 如果提供了 updateMask，则只更新被包含的字段（支持按字段名或列名）
 		if len(mask) > 0 {
@@ -932,7 +996,7 @@ func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, dto *DTO, updateM
 	}
 
 	if len(setExprs) == 0 {
-		return 0, errors.New("no columns to update")
+		return 0, errNoColumnsToUpdate
 	}
 
 	// 主键值
@@ -946,23 +1010,40 @@ func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, dto *DTO, updateM
 	args := append(setVals, pkVal)
 	if err := r.client.conn.Exec(ctx, aSql, args...); err != nil {
 		r.log.Errorf("update failed: %v", err)
-		return 0, errors.New("update failed")
+		return 0, errUpdateFailed
 	}
 
 	// ClickHouse Exec 通常不提供 RowsAffected，成功则返回 1（表示已提交 mutation）
 	return 1, nil
 }
 
-// Upsert 使用传入的 db（可包含 Where/其他 scope）执行插入或冲突更新，支持 updateMask 指定冲突时更新的字段
-func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMask *fieldmaskpb.FieldMask) (*DTO, error) {
+// Upsert 使用传入的 db（可包含 Where/其他 scope）执行插入或冲突更新，支持 updateMask 指定冲突时更新的字段。
+// 通过 SetVersioning 配置 UpsertReplacing/UpsertCollapsing 时改为插入新版本行，否则使用 mutation
+func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("Upsert")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return nil, errors.New("clickhouse client is nil")
+		return nil, errClientIsNil
 	}
 	if r.table == "" {
-		return nil, errors.New("table is empty")
+		return nil, errTableIsEmpty
 	}
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
+	}
+
+	if r.versioning.Mode != UpsertMutation {
+		ent, err := r.upsertVersioned(ctx, dto, updateMask)
+		if err != nil {
+			return nil, err
+		}
+		return r.mapper.ToDTO(ent), nil
 	}
 
 	// 规范 updateMask 路径
@@ -983,7 +1064,7 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMa
 		v = v.Elem()
 	}
 	if !v.IsValid() || v.Kind() != reflect.Struct {
-		return nil, errors.New("entity must be a struct or pointer to struct")
+		return nil, errEntityNotStructOrPointer
 	}
 	t := v.Type()
 
@@ -998,19 +1079,7 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMa
 		if sf.Tag.Get("pk") == "true" {
 			pkIdx = i
 		}
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 		if pkIdx == -1 {
 			lc := strings.ToLower(col)
 			if lc == "id" || strings.ToLower(sf.Name) == "id" {
@@ -1030,26 +1099,14 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMa
 		if sf.PkgPath != "" {
 			continue
 		}
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 
 		cols = append(cols, col)
 		vals = append(vals, v.Field(i).Interface())
 	}
 
 	if len(cols) == 0 {
-		return nil, errors.New("no columns to insert")
+		return nil, errNoColumnsToInsert
 	}
 
 	placeholders := strings.Repeat("?,", len(cols))
@@ -1066,7 +1123,7 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMa
 	// 插入失败：尝试按主键 UPDATE
 	if pkIdx == -1 || pkCol == "" {
 		r.log.Errorf("upsert insert failed and primary key not found")
-		return nil, errors.New("upsert failed and primary key not found")
+		return nil, errUpsertPrimaryKeyNotFound
 	}
 
 	// 构建 UPDATE 的 set 列与值（排除主键）
@@ -1080,19 +1137,7 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMa
 		if sf.PkgPath != "" {
 			continue
 		}
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 
 		if len(mask) > 0 {
 			if !mask[sf.Name] && !mask[col] {
@@ -1110,7 +1155,7 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMa
 
 	if len(setExprs) == 0 {
 		r.log.Errorf("upsert: no columns to update after insert failure")
-		return nil, errors.New("no columns to update")
+		return nil, errNoColumnsToUpdate
 	}
 
 	pkVal := v.Field(pkIdx).Interface()
@@ -1120,7 +1165,7 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMa
 	args := append(setVals, pkVal)
 	if err := r.client.conn.Exec(ctx, updateSQL, args...); err != nil {
 		r.log.Errorf("upsert update failed: %v", err)
-		return nil, errors.New("upsert update failed")
+		return nil, errUpsertUpdateFailed
 	}
 
 	// 读取更新后的记录并返回（注意 mutation 可能异步）
@@ -1132,7 +1177,7 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMa
 	selectSQL := fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT 1", r.table, whereClause)
 	if err := r.client.Query(ctx, creator, &rawResults, selectSQL, pkVal); err != nil {
 		r.log.Errorf("read upserted record failed: %v", err)
-		return nil, errors.New("read upserted record failed")
+		return nil, errReadUpsertedRecordFailed
 	}
 	if len(rawResults) == 0 {
 		return nil, nil
@@ -1141,19 +1186,32 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO, updateMa
 		return r.mapper.ToDTO(ptr), nil
 	}
 	r.log.Errorf("unexpected result type after upsert")
-	return nil, errors.New("unexpected result type after upsert")
+	return nil, errUnexpectedUpsertResultType
 }
 
 // UpsertX 使用传入的 db（可包含 Where/其他 scope）执行插入或冲突更新，支持 updateMask 指定冲突时更新的字段，返回受影响行数
-func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, dto *DTO, updateMask *fieldmaskpb.FieldMask) (int64, error) {
+func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("UpsertX")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return 0, errors.New("clickhouse client is nil")
+		return 0, errClientIsNil
 	}
 	if r.table == "" {
-		return 0, errors.New("table is empty")
+		return 0, errTableIsEmpty
 	}
 	if dto == nil {
-		return 0, errors.New("dto is nil")
+		return 0, errDTOIsNil
+	}
+
+	if r.versioning.Mode != UpsertMutation {
+		if _, err = r.upsertVersioned(ctx, dto, updateMask); err != nil {
+			return 0, err
+		}
+		return 1, nil
 	}
 
 	// 规范 updateMask 路径
@@ -1174,7 +1232,7 @@ func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, dto *DTO, updateM
 		v = v.Elem()
 	}
 	if !v.IsValid() || v.Kind() != reflect.Struct {
-		return 0, errors.New("entity must be a struct or pointer to struct")
+		return 0, errEntityNotStructOrPointer
 	}
 	t := v.Type()
 
@@ -1189,19 +1247,7 @@ func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, dto *DTO, updateM
 		if sf.Tag.Get("pk") == "true" {
 			pkIdx = i
 		}
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 		if pkIdx == -1 {
 			lc := strings.ToLower(col)
 			if lc == "id" || strings.ToLower(sf.Name) == "id" {
@@ -1221,25 +1267,13 @@ func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, dto *DTO, updateM
 		if sf.PkgPath != "" {
 			continue
 		}
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 		cols = append(cols, col)
 		vals = append(vals, v.Field(i).Interface())
 	}
 
 	if len(cols) == 0 {
-		return 0, errors.New("no columns to insert")
+		return 0, errNoColumnsToInsert
 	}
 
 	placeholders := strings.Repeat("?,", len(cols))
@@ -1255,7 +1289,7 @@ func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, dto *DTO, updateM
 	// INSERT 失败，转为 UPDATE（需要主键）
 	if pkIdx == -1 || pkCol == "" {
 		r.log.Errorf("upsert insert failed and primary key not found")
-		return 0, errors.New("upsert failed and primary key not found")
+		return 0, errUpsertPrimaryKeyNotFound
 	}
 
 	// 构建 UPDATE 的 set 列与值（排除主键），受 updateMask 控制或默认非零字段
@@ -1269,19 +1303,7 @@ func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, dto *DTO, updateM
 		if sf.PkgPath != "" {
 			continue
 		}
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
+		col := schema.ColumnName(sf)
 
 		if len(mask) > 0 {
 			if !mask[sf.Name] && !mask[col] {
@@ -1299,7 +1321,7 @@ func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, dto *DTO, updateM
 
 	if len(setExprs) == 0 {
 		r.log.Errorf("upsert: no columns to update after insert failure")
-		return 0, errors.New("no columns to update")
+		return 0, errNoColumnsToUpdate
 	}
 
 	pkVal := v.Field(pkIdx).Interface()
@@ -1309,7 +1331,7 @@ func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, dto *DTO, updateM
 	args := append(setVals, pkVal)
 	if err := r.client.conn.Exec(ctx, updateSQL, args...); err != nil {
 		r.log.Errorf("upsert update failed: %v", err)
-		return 0, errors.New("upsert update failed")
+		return 0, errUpsertUpdateFailed
 	}
 
 	// ClickHouse Exec 通常不提供 RowsAffected，成功则返回 1（表示已提交 mutation）
@@ -1317,20 +1339,31 @@ func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, dto *DTO, updateM
 }
 
 // Delete 使用传入的 db（可包含 Where）删除记录
-func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, notSoftDelete bool) (int64, error) {
+func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, notSoftDelete bool) (ret int64, err error) {
+	obs := r.observe("Delete")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return 0, errors.New("clickhouse client is nil")
+		return 0, errClientIsNil
 	}
 	if r.table == "" {
-		return 0, errors.New("table is empty")
+		return 0, errTableIsEmpty
 	}
 
 	// 硬删除：清空表
 	if notSoftDelete {
+		// 不带过滤条件，作用于全表
+		if _, err := r.checkGuard(ctx, r.readSource(false), false, ""); err != nil {
+			return 0, err
+		}
+
 		aSql := fmt.Sprintf("TRUNCATE TABLE %s", r.table)
 		if err := r.client.conn.Exec(ctx, aSql); err != nil {
 			r.log.Errorf("TRUNCATE TABLE failed: %v", err)
-			return 0, errors.New("delete failed")
+			return 0, errDeleteFailed
 		}
 		// ClickHouse Exec 通常不提供 RowsAffected，成功则返回 1
 		return 1, nil
@@ -1342,46 +1375,24 @@ func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, notSoftDelete bool
 		t = t.Elem()
 	}
 	if t == nil || t.Kind() != reflect.Struct {
-		return 0, errors.New("entity must be a struct type")
+		return 0, errEntityNotStructType
 	}
 
 	// 查找可能的 deleted_at 字段（支持 tag: db/ch/json 或 字段名）
-	deletedCol := ""
-	for i := 0; i < t.NumField(); i++ {
-		sf := t.Field(i)
-		// skip unexported
-		if sf.PkgPath != "" {
-			continue
-		}
-		col := sf.Tag.Get("db")
-		if col == "" {
-			col = sf.Tag.Get("ch")
-		}
-		if col == "" {
-			col = sf.Tag.Get("json")
-			if idx := strings.Index(col, ","); idx != -1 {
-				col = col[:idx]
-			}
-		}
-		if col == "" {
-			col = strings.ToLower(sf.Name)
-		}
-		lc := strings.ToLower(col)
-		nameLc := strings.ToLower(sf.Name)
-		if lc == "deleted_at" || lc == "deletedat" || nameLc == "deleted_at" || nameLc == "deletedat" {
-			deletedCol = col
-			break
-		}
-	}
+	deletedCol := findDeletedAtColumn(t)
 
 	if deletedCol == "" {
-		return 0, errors.New("soft delete not supported: deleted_at field not found on entity")
+		return 0, errSoftDeleteUnsupported
+	}
+
+	if _, err := r.checkGuard(ctx, r.readSource(false), false, ""); err != nil {
+		return 0, err
 	}
 
 	aSql := fmt.Sprintf("ALTER TABLE %s UPDATE %s = now() WHERE 1", r.table, deletedCol)
 	if err := r.client.conn.Exec(ctx, aSql); err != nil {
 		r.log.Errorf("soft delete (update deleted_at) failed: %v", err)
-		return 0, errors.New("delete failed")
+		return 0, errDeleteFailed
 	}
 
 	// ClickHouse Exec 通常不提供 RowsAffected，成功则返回 1
@@ -1394,13 +1405,26 @@ func (r *Repository[DTO, ENTITY]) SoftDelete(ctx context.Context) (int64, error)
 	return r.Delete(ctx, false)
 }
 
-// Exists 使用传入的 db（可包含 Where）检查是否存在记录
-func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, baseWhere string, whereArgs ...any) (bool, error) {
+// Exists 使用 FilterOptions（FilterExpr 或查询字符串过滤）检查是否存在记录
+// 示例调用： ok, err := q.Exists(ctx, &FilterOptions{FilterExpr: expr})
+func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, opts *FilterOptions) (ret bool, err error) {
+	obs := r.observe("Exists")
+	defer func() { obs.End(err) }()
+
+	where, args, err := r.buildWhere(opts)
+	if err != nil {
+		return false, err
+	}
+	return r.existsFrom(r.queryContext(ctx), r.fromClause(opts), where, args...)
+}
+
+// existsFrom 在 from（表名，可带 FINAL）上检查是否存在符合 baseWhere 的记录
+func (r *Repository[DTO, ENTITY]) existsFrom(ctx context.Context, from string, baseWhere string, whereArgs ...any) (bool, error) {
 	if r.client == nil {
-		return false, errors.New("clickhouse client is nil")
+		return false, errClientIsNil
 	}
 	if r.table == "" {
-		return false, errors.New("table is empty")
+		return false, errTableIsEmpty
 	}
 
 	// 展开单个切片参数为独立参数
@@ -1415,7 +1439,7 @@ func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, baseWhere string,
 		}
 	}
 
-	sqlStr := fmt.Sprintf("SELECT 1 FROM %s", r.table)
+	sqlStr := fmt.Sprintf("SELECT 1 FROM %s", from)
 	bw := strings.TrimSpace(baseWhere)
 	if bw != "" {
 		if !strings.HasPrefix(strings.ToUpper(bw), "WHERE") {
@@ -1426,6 +1450,9 @@ func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, baseWhere string,
 	}
 	sqlStr += " LIMIT 1"
 
+	ctx, done := r.client.prepareQuery(ctx)
+	defer done()
+
 	row := r.client.conn.QueryRow(ctx, sqlStr, whereArgs...)
 	var dummy uint8
 	if err := row.Scan(&dummy); err != nil {
@@ -1438,7 +1465,285 @@ func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, baseWhere string,
 			return false, nil
 		}
 		r.log.Errorf("exists query failed: %v", err)
-		return false, errors.New("exists query failed")
+		return false, errExistsQueryFailed
 	}
 	return true, nil
 }
+
+// UpdateByFilter 根据 FilterExpr 批量更新记录（ALTER TABLE ... UPDATE），返回受影响行数。
+// ClickHouse 的 mutation 不返回影响行数，因此在执行前按相同条件计数。
+func (r *Repository[DTO, ENTITY]) UpdateByFilter(ctx context.Context, filterExpr *paginationV1.FilterExpr, dto *DTO, updateMask *fieldmaskpb.FieldMask) (int64, error) {
+	return r.UpdateWithFilter(ctx, &FilterOptions{FilterExpr: filterExpr}, dto, updateMask)
+}
+
+// UpdateWithFilter 根据 FilterOptions 批量更新记录（ALTER TABLE ... UPDATE ... WHERE），返回执行前按相同条件统计的行数。
+// opts.WaitMutation 为 true 时等待 system.mutations 中的 mutation 完成后返回。
+func (r *Repository[DTO, ENTITY]) UpdateWithFilter(ctx context.Context, opts *FilterOptions, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("UpdateWithFilter")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
+	if r.client == nil {
+		return 0, errClientIsNil
+	}
+	if r.table == "" {
+		return 0, errTableIsEmpty
+	}
+
+	ctx, span := r.startSpan(ctx, "UpdateWithFilter", filterOptionsAttributes(opts)...)
+	defer func() { span.End(err) }()
+	if dto == nil {
+		return 0, errDTOIsNil
+	}
+
+	// 规范 updateMask 路径
+	field.NormalizeFieldMaskPaths(updateMask)
+	mask := map[string]bool{}
+	if updateMask != nil {
+		for _, p := range updateMask.Paths {
+			mask[p] = true
+		}
+	}
+
+	// DTO -> ENTITY
+	ent := r.mapper.ToEntity(dto)
+
+	v := reflect.ValueOf(ent)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return 0, errEntityNotStructOrPointer
+	}
+	t := v.Type()
+
+	// 构建更新列和值，排除主键（ClickHouse 不允许更新主键列）
+	setExprs := make([]string, 0)
+	setVals := make([]any, 0)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		col := schema.ColumnName(sf)
+		if col == "-" || sf.Tag.Get("pk") == "true" || strings.ToLower(col) == "id" {
+			continue
+		}
+
+		if len(mask) > 0 {
+			if !mask[sf.Name] && !mask[col] {
+				continue
+			}
+		} else {
+			if v.Field(i).IsZero() {
+				continue
+			}
+		}
+
+		setExprs = append(setExprs, fmt.Sprintf("%s = ?", col))
+		setVals = append(setVals, v.Field(i).Interface())
+	}
+
+	if len(setExprs) == 0 {
+		return 0, errNoColumnsToUpdate
+	}
+
+	where, whereArgs, err := r.buildWhere(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := r.checkGuard(ctx, r.fromClause(opts), true, where, whereArgs...)
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, nil
+	}
+
+	aSql := fmt.Sprintf("ALTER TABLE %s UPDATE %s WHERE %s", r.table, strings.Join(setExprs, ", "), whereOrTrue(where))
+	args := append(setVals, whereArgs...)
+	startedAt, err := r.mutationStart(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+	obs.SetStatement(aSql, args...)
+	if err = r.client.conn.Exec(ctx, aSql, args...); err != nil {
+		r.log.Errorf("update by filter failed: %v", err)
+		return 0, errUpdateByFilterFailed
+	}
+
+	if opts != nil && opts.WaitMutation {
+		if err = r.WaitForMutations(ctx, startedAt, opts.MutationTimeout); err != nil {
+			return int64(affected), err
+		}
+	}
+
+	return int64(affected), nil
+}
+
+// DeleteByFilter 根据 FilterExpr 删除记录，返回受影响行数。
+// soft 为 true 时将 deleted_at 置为当前时间（ALTER TABLE ... UPDATE），否则使用轻量级 DELETE FROM。
+func (r *Repository[DTO, ENTITY]) DeleteByFilter(ctx context.Context, filterExpr *paginationV1.FilterExpr, soft bool) (int64, error) {
+	return r.DeleteWithFilter(ctx, &FilterOptions{FilterExpr: filterExpr}, soft)
+}
+
+// DeleteWithFilter 根据 FilterOptions 删除记录，返回执行前按相同条件统计的行数。
+// soft 为 true 时将 deleted_at 置为当前时间（ALTER TABLE ... UPDATE），否则使用轻量级 DELETE FROM ... WHERE；
+// opts.WaitMutation 为 true 时等待 system.mutations 中的 mutation 完成后返回。
+func (r *Repository[DTO, ENTITY]) DeleteWithFilter(ctx context.Context, opts *FilterOptions, soft bool) (ret int64, err error) {
+	obs := r.observe("DeleteWithFilter")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
+	if r.client == nil {
+		return 0, errClientIsNil
+	}
+	if r.table == "" {
+		return 0, errTableIsEmpty
+	}
+
+	ctx, span := r.startSpan(ctx, "DeleteWithFilter", filterOptionsAttributes(opts)...)
+	defer func() { span.End(err) }()
+
+	var deletedCol string
+	if soft {
+		deletedCol = findDeletedAtColumn(reflect.TypeOf((*ENTITY)(nil)).Elem())
+		if deletedCol == "" {
+			return 0, errSoftDeleteUnsupported
+		}
+	}
+
+	where, whereArgs, err := r.buildWhere(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := r.checkGuard(ctx, r.fromClause(opts), true, where, whereArgs...)
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, nil
+	}
+
+	var aSql string
+	if soft {
+		aSql = fmt.Sprintf("ALTER TABLE %s UPDATE %s = now() WHERE %s", r.table, deletedCol, whereOrTrue(where))
+	} else {
+		aSql = fmt.Sprintf("DELETE FROM %s WHERE %s", r.table, whereOrTrue(where))
+	}
+	startedAt, err := r.mutationStart(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+	obs.SetStatement(aSql, whereArgs...)
+	if err = r.client.conn.Exec(ctx, aSql, whereArgs...); err != nil {
+		r.log.Errorf("delete by filter failed: %v", err)
+		return 0, errDeleteByFilterFailed
+	}
+
+	if opts != nil && opts.WaitMutation {
+		if err = r.WaitForMutations(ctx, startedAt, opts.MutationTimeout); err != nil {
+			return int64(affected), err
+		}
+	}
+
+	return int64(affected), nil
+}
+
+// startSpan 开始一次仓库操作的 span，语句级的子 span 由 Client 的连接创建
+func (r *Repository[DTO, ENTITY]) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, *tracing.Span) {
+	return r.client.tracer.Start(ctx, operation, r.table, attrs...)
+}
+
+// filterOptionsAttributes 汇总 FilterOptions 的过滤信息，不包含任何过滤值
+func filterOptionsAttributes(opts *FilterOptions) []attribute.KeyValue {
+	if opts == nil {
+		return nil
+	}
+
+	var attrs []attribute.KeyValue
+	if opts.Query != "" || opts.OrQuery != "" {
+		attrs = append(attrs, tracing.FilterQueryKey.Bool(true))
+	}
+	return append(attrs, tracing.FilterAttributes(opts.FilterExpr)...)
+}
+
+// applyFilter 将 FilterOptions 中的过滤条件应用到 qb，FINAL 修饰与去重由 fromClause 决定。
+// 与 ListWithPaging 一致，Query/OrQuery 优先于 FilterExpr。
+func (r *Repository[DTO, ENTITY]) applyFilter(qb *query.Builder, opts *FilterOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	if err := r.limitFilterOptions(opts); err != nil {
+		return err
+	}
+
+	if opts.Query != "" || opts.OrQuery != "" {
+		if _, err := r.queryStringFilter.BuildSelectors(qb, opts.Query, opts.OrQuery); err != nil {
+			r.log.Errorf("build query string filter selectors failed: %s", err.Error())
+			return err
+		}
+	} else if opts.FilterExpr != nil {
+		if _, err := r.structuredFilter.BuildSelectors(qb, opts.FilterExpr); err != nil {
+			r.log.Errorf("build structured filter selectors failed: %s", err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// buildWhere 将 FilterOptions 构建为 WHERE 条件与参数（不含 WHERE 关键字）
+func (r *Repository[DTO, ENTITY]) buildWhere(opts *FilterOptions) (string, []any, error) {
+	qb := query.NewQueryBuilder(r.table, r.log)
+	if err := r.applyFilter(qb, opts); err != nil {
+		return "", nil, err
+	}
+
+	where, args := qb.BuildWhereParam()
+	return where, args, nil
+}
+
+// fromClause 返回 FROM 子句中的数据源，opts.Final 为 true 时附加 FINAL；配置了读取去重时见 readSource
+func (r *Repository[DTO, ENTITY]) fromClause(opts *FilterOptions) string {
+	return r.readSource(opts != nil && opts.Final)
+}
+
+// whereOrTrue 条件为空时返回恒真条件
+func whereOrTrue(where string) string {
+	if strings.TrimSpace(where) == "" {
+		return "1"
+	}
+	return where
+}
+
+// findDeletedAtColumn 查找软删除字段 deleted_at 对应的列名，未找到时返回空字符串
+func findDeletedAtColumn(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		// skip unexported
+		if sf.PkgPath != "" {
+			continue
+		}
+		col := schema.ColumnName(sf)
+		lc := strings.ToLower(col)
+		nameLc := strings.ToLower(sf.Name)
+		if lc == "deleted_at" || lc == "deletedat" || nameLc == "deleted_at" || nameLc == "deletedat" {
+			return col
+		}
+	}
+	return ""
+}
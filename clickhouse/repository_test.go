@@ -3,6 +3,7 @@ package clickhouse
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/guard"
 )
 
 // 为测试定义简单实体类型（没有 deleted_at 字段）
@@ -105,6 +107,20 @@ func TestRepository_ErrorBranches(t *testing.T) {
 			t.Fatalf("unexpected error: %v, want: %s", err, expected)
 		}
 	})
+
+	t.Run("Delete refused without AllowFullTable", func(t *testing.T) {
+		repo := NewRepository[NoDeleted, NoDeleted](client, noDelMapper, "tmp", logger)
+
+		_, err := repo.Delete(ctx, true)
+		if !errors.Is(err, guard.ErrFullTableOperation) {
+			t.Fatalf("expected ErrFullTableOperation, got: %v", err)
+		}
+
+		_, err = repo.DeleteByFilter(ctx, nil, false)
+		if !errors.Is(err, guard.ErrFullTableOperation) {
+			t.Fatalf("expected ErrFullTableOperation, got: %v", err)
+		}
+	})
 }
 
 func TestRepository_Candle_CRUD(t *testing.T) {
@@ -121,6 +137,7 @@ func TestRepository_Candle_CRUD(t *testing.T) {
 
 	repo := NewRepository[Candle, Candle](client, candleMapper, "candles", logger)
 	assert.NotNil(t, repo)
+	repo.SetGuardOptions(guard.Options{AllowFullTable: true})
 
 	// 插入一条
 	now := time.Now().UTC().Truncate(time.Millisecond)
@@ -138,7 +155,13 @@ func TestRepository_Candle_CRUD(t *testing.T) {
 	assert.NotNil(t, created)
 
 	// Exists 应为 true
-	exists, err := repo.Exists(ctx, "symbol = ?", "TEST")
+	bySymbol := &FilterOptions{FilterExpr: &paginationV1.FilterExpr{
+		Type: paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{
+			{Field: "symbol", Op: paginationV1.Operator_EQ, Value: trans.Ptr("TEST")},
+		},
+	}}
+	exists, err := repo.Exists(ctx, bySymbol)
 	assert.NoError(t, err)
 	assert.True(t, exists)
 
@@ -186,7 +209,7 @@ func TestRepository_Candle_CRUD(t *testing.T) {
 	assert.Equal(t, int64(1), delRes)
 
 	// 删除后 Exists 应为 false
-	existsAfter, err := repo.Exists(ctx, "symbol = ?", "TEST")
+	existsAfter, err := repo.Exists(ctx, bySymbol)
 	assert.NoError(t, err)
 	assert.False(t, existsAfter)
 }
@@ -204,6 +227,7 @@ func TestRepository_Candle_ListWithPaging(t *testing.T) {
 	candleMapper := mapper.NewCopierMapper[Candle, Candle]()
 	repo := NewRepository[Candle, Candle](client, candleMapper, "candles", logger)
 	assert.NotNil(t, repo)
+	repo.SetGuardOptions(guard.Options{AllowFullTable: true})
 
 	// 硬删除（truncate）
 	delRes, err := repo.Delete(ctx, true)
@@ -258,3 +282,19 @@ func TestRepository_Candle_ListWithPaging(t *testing.T) {
 	}
 	assert.Equal(t, 10.5, *first.Close)
 }
+
+func TestRepository_Get_LimitOne(t *testing.T) {
+	type account struct {
+		ID          uint64  `ch:"id"`
+		CreditLimit float64 `ch:"credit_limit"`
+	}
+
+	conn := &fakeRowsConn{columns: []string{"id", "credit_limit"}}
+	repo := NewRepository[account, account](newTypedTestClient(conn), mapper.NewCopierMapper[account, account](), "accounts", log.NewHelper(log.DefaultLogger))
+
+	// 过滤列名中含有 limit 时仍只读取一行
+	_, err := repo.Get(context.Background(), &FilterOptions{Query: `{"credit_limit__gt":"100"}`}, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, conn.query, "credit_limit")
+	assert.True(t, strings.HasSuffix(conn.query, " LIMIT 1"), conn.query)
+}
@@ -0,0 +1,110 @@
+package schema
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QualifiedName 返回带库名（如有）的引用表名
+func (t *Table) QualifiedName() string {
+	if t.Database == "" {
+		return quoteIdent(t.Name)
+	}
+	return quoteIdent(t.Database) + "." + quoteIdent(t.Name)
+}
+
+// CreateSQL 生成 CREATE TABLE IF NOT EXISTS 语句；未指定 ORDER BY 时使用 tuple()
+func (t *Table) CreateSQL() string {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(t.QualifiedName())
+	sb.WriteString("\n(\n")
+
+	defs := make([]string, 0, len(t.Columns)+len(t.Indexes))
+	for _, c := range t.Columns {
+		defs = append(defs, "    "+c.Definition())
+	}
+	for _, idx := range t.Indexes {
+		defs = append(defs, "    "+idx.Definition())
+	}
+	sb.WriteString(strings.Join(defs, ",\n"))
+	sb.WriteString("\n)\n")
+
+	engine := t.Engine
+	if engine.Name == "" {
+		engine = MergeTree()
+	}
+	sb.WriteString("ENGINE = " + engine.String())
+
+	if t.PartitionBy != "" {
+		sb.WriteString("\nPARTITION BY " + t.PartitionBy)
+	}
+	if len(t.PrimaryKey) > 0 {
+		sb.WriteString("\nPRIMARY KEY " + tupleExpr(t.PrimaryKey))
+	}
+	sb.WriteString("\nORDER BY " + tupleExpr(t.OrderBy))
+	if t.TTL != "" {
+		sb.WriteString("\nTTL " + t.TTL)
+	}
+	if len(t.Settings) > 0 {
+		keys := make([]string, 0, len(t.Settings))
+		for k := range t.Settings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		settings := make([]string, len(keys))
+		for i, k := range keys {
+			settings[i] = k + " = " + t.Settings[k]
+		}
+		sb.WriteString("\nSETTINGS " + strings.Join(settings, ", "))
+	}
+	if t.Comment != "" {
+		sb.WriteString("\nCOMMENT " + quoteString(t.Comment))
+	}
+	return sb.String()
+}
+
+// Definition 返回列定义：name type [DEFAULT|MATERIALIZED|ALIAS expr] [COMMENT] [CODEC] [TTL]
+func (c Column) Definition() string {
+	parts := []string{quoteIdent(c.Name), c.Type}
+	if c.DefaultKind != "" {
+		parts = append(parts, c.DefaultKind, c.DefaultExpr)
+	}
+	if c.Comment != "" {
+		parts = append(parts, "COMMENT", quoteString(c.Comment))
+	}
+	if c.Codec != "" {
+		parts = append(parts, "CODEC("+c.Codec+")")
+	}
+	if c.TTL != "" {
+		parts = append(parts, "TTL", c.TTL)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Definition 返回索引定义：INDEX name expr TYPE type GRANULARITY n
+func (idx Index) Definition() string {
+	granularity := idx.Granularity
+	if granularity <= 0 {
+		granularity = 1
+	}
+	return "INDEX " + quoteIdent(idx.Name) + " " + idx.Expr + " TYPE " + idx.Type + " GRANULARITY " + strconv.Itoa(granularity)
+}
+
+// tupleExpr 单个表达式原样返回，多个时组成元组，空时为 tuple()
+func tupleExpr(exprs []string) string {
+	switch len(exprs) {
+	case 0:
+		return "tuple()"
+	case 1:
+		return exprs[0]
+	default:
+		return "(" + strings.Join(exprs, ", ") + ")"
+	}
+}
+
+// quoteString 使用单引号引用字符串字面量
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,125 @@
+package schema
+
+import "strings"
+
+// ExistingColumn system.columns 中的列
+type ExistingColumn struct {
+	Name              string `ch:"name"`
+	Type              string `ch:"type"`
+	DefaultKind       string `ch:"default_kind"`
+	DefaultExpression string `ch:"default_expression"`
+}
+
+// ExistingIndex system.data_skipping_indices 中的索引
+type ExistingIndex struct {
+	Name string `ch:"name"`
+	Type string `ch:"type"`
+	Expr string `ch:"expr"`
+}
+
+// ColumnsQuery 查询表的现有列，参数依次为库名（空时为当前库）与表名
+const ColumnsQuery = "SELECT name, type, default_kind, default_expression FROM system.columns " +
+	"WHERE database = if(? = '', currentDatabase(), ?) AND table = ? ORDER BY position"
+
+// IndexesQuery 查询表的现有数据跳过索引，参数同 ColumnsQuery
+const IndexesQuery = "SELECT name, type, expr FROM system.data_skipping_indices " +
+	"WHERE database = if(? = '', currentDatabase(), ?) AND table = ?"
+
+// TypeChange 定义与现有列类型不一致的列
+type TypeChange struct {
+	Column string
+	From   string
+	To     string
+}
+
+// Migration 表定义与现有结构的差异。
+// 只有新增列与新增索引会被执行；类型变化与多余的列可能导致数据丢失或长时间的 mutation，仅用于报告
+type Migration struct {
+	Table *Table
+
+	AddColumns []Column
+	AddIndexes []Index
+
+	TypeChanges  []TypeChange
+	ExtraColumns []string
+}
+
+// Diff 对比表定义与 system.columns、system.data_skipping_indices 的查询结果
+func Diff(t *Table, columns []ExistingColumn, indexes []ExistingIndex) *Migration {
+	m := &Migration{Table: t}
+
+	existing := make(map[string]ExistingColumn, len(columns))
+	for _, c := range columns {
+		existing[c.Name] = c
+	}
+	defined := make(map[string]bool, len(t.Columns))
+	for _, c := range t.Columns {
+		defined[c.Name] = true
+		old, ok := existing[c.Name]
+		if !ok {
+			m.AddColumns = append(m.AddColumns, c)
+			continue
+		}
+		if normalizeType(old.Type) != normalizeType(c.Type) {
+			m.TypeChanges = append(m.TypeChanges, TypeChange{Column: c.Name, From: old.Type, To: c.Type})
+		}
+	}
+	for _, c := range columns {
+		if !defined[c.Name] {
+			m.ExtraColumns = append(m.ExtraColumns, c.Name)
+		}
+	}
+
+	existingIdx := make(map[string]bool, len(indexes))
+	for _, idx := range indexes {
+		existingIdx[idx.Name] = true
+	}
+	for _, idx := range t.Indexes {
+		if !existingIdx[idx.Name] {
+			m.AddIndexes = append(m.AddIndexes, idx)
+		}
+	}
+	return m
+}
+
+// Empty 没有可执行的新增列或索引
+func (m *Migration) Empty() bool {
+	return len(m.AddColumns) == 0 && len(m.AddIndexes) == 0
+}
+
+// Statements 返回新增列与索引的 ALTER TABLE 语句，新增列按定义顺序放在前一列之后
+func (m *Migration) Statements() []string {
+	if m.Empty() {
+		return nil
+	}
+
+	adding := make(map[string]bool, len(m.AddColumns))
+	for _, c := range m.AddColumns {
+		adding[c.Name] = true
+	}
+
+	table := m.Table.QualifiedName()
+	var stmts []string
+	prev := ""
+	for _, c := range m.Table.Columns {
+		if adding[c.Name] {
+			stmt := "ALTER TABLE " + table + " ADD COLUMN IF NOT EXISTS " + c.Definition()
+			if prev == "" {
+				stmt += " FIRST"
+			} else {
+				stmt += " AFTER " + quoteIdent(prev)
+			}
+			stmts = append(stmts, stmt)
+		}
+		prev = c.Name
+	}
+	for _, idx := range m.AddIndexes {
+		stmts = append(stmts, "ALTER TABLE "+table+" ADD INDEX IF NOT EXISTS "+strings.TrimPrefix(idx.Definition(), "INDEX "))
+	}
+	return stmts
+}
+
+// normalizeType 去掉空白后比较类型
+func normalizeType(t string) string {
+	return strings.Join(strings.Fields(t), "")
+}
@@ -0,0 +1,461 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TagName 列定义使用的结构体标签，格式为分号分隔的 key[:value]，例如
+//
+//	Host    string    `ch:"host" schema:"low_cardinality;order_by:1"`
+//	Ts      time.Time `ch:"ts" schema:"precision:3;order_by:2;partition:toYYYYMM"`
+//	Payload string    `ch:"payload" schema:"codec:ZSTD(3);index:tokenbf_v1(512, 3, 0);index_granularity:4"`
+//
+// 支持的键：
+//   - "-"：不生成该列
+//   - type：完整的 ClickHouse 类型，覆盖类型推断
+//   - nullable、low_cardinality：包装为 Nullable(T)、LowCardinality(T)
+//   - precision、timezone：time.Time 推断为 DateTime64(precision, 'timezone')，默认精度 3
+//   - default、materialized、alias：默认值表达式
+//   - codec、comment、ttl：列的压缩编码、注释与 TTL
+//   - order_by[:N]：加入 ORDER BY，N 为位置，缺省按字段顺序排在有位置的列之后
+//   - primary_key：加入 PRIMARY KEY（需为 ORDER BY 的前缀）
+//   - partition[:func]：PARTITION BY 该列，指定 func 时为 func(列)
+//   - version、is_deleted：ReplacingMergeTree 的版本列与删除标记列
+//   - sum：SummingMergeTree 的求和列
+//   - index:TYPE、index_granularity:N：为该列创建数据跳过索引
+const TagName = "schema"
+
+// DefaultDateTimePrecision time.Time 推断为 DateTime64 时的默认精度
+const DefaultDateTimePrecision = 3
+
+var (
+	ErrNotStruct        = errors.New("schema: entity must be a struct")
+	ErrNoColumns        = errors.New("schema: no columns")
+	ErrUnsupportedType  = errors.New("schema: unsupported field type")
+	ErrInvalidTableName = errors.New("schema: invalid table name")
+)
+
+// Column 列定义
+type Column struct {
+	Name string
+	Type string
+
+	// DefaultKind 为 DEFAULT、MATERIALIZED 或 ALIAS，DefaultExpr 为其表达式
+	DefaultKind string
+	DefaultExpr string
+
+	Codec   string
+	Comment string
+	TTL     string
+}
+
+// Index 数据跳过索引
+type Index struct {
+	Name        string
+	Expr        string
+	Type        string
+	Granularity int
+}
+
+// Engine 表引擎
+type Engine struct {
+	Name string
+	Args []string
+}
+
+// MergeTree 返回 MergeTree 引擎
+func MergeTree() Engine {
+	return Engine{Name: "MergeTree"}
+}
+
+// ReplacingMergeTree 返回 ReplacingMergeTree 引擎，version、isDeleted 可为空
+func ReplacingMergeTree(version, isDeleted string) Engine {
+	e := Engine{Name: "ReplacingMergeTree"}
+	if version != "" {
+		e.Args = append(e.Args, quoteIdent(version))
+		if isDeleted != "" {
+			e.Args = append(e.Args, quoteIdent(isDeleted))
+		}
+	}
+	return e
+}
+
+// SummingMergeTree 返回 SummingMergeTree 引擎，未指定列时对所有数值列求和
+func SummingMergeTree(columns ...string) Engine {
+	e := Engine{Name: "SummingMergeTree"}
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, c := range columns {
+			quoted[i] = quoteIdent(c)
+		}
+		e.Args = []string{"(" + strings.Join(quoted, ", ") + ")"}
+	}
+	return e
+}
+
+// String 返回 ENGINE = 之后的引擎表达式
+func (e Engine) String() string {
+	return e.Name + "(" + strings.Join(e.Args, ", ") + ")"
+}
+
+// Table 表定义
+type Table struct {
+	Database string
+	Name     string
+
+	Columns []Column
+	Indexes []Index
+
+	Engine      Engine
+	OrderBy     []string
+	PrimaryKey  []string
+	PartitionBy string
+	TTL         string
+	Settings    map[string]string
+	Comment     string
+}
+
+// Option 表级选项，覆盖由结构体标签推断的设置
+type Option func(t *Table)
+
+// WithEngine 指定表引擎
+func WithEngine(e Engine) Option {
+	return func(t *Table) {
+		t.Engine = e
+	}
+}
+
+// WithOrderBy 指定 ORDER BY 表达式
+func WithOrderBy(exprs ...string) Option {
+	return func(t *Table) {
+		t.OrderBy = exprs
+	}
+}
+
+// WithPrimaryKey 指定 PRIMARY KEY 表达式
+func WithPrimaryKey(exprs ...string) Option {
+	return func(t *Table) {
+		t.PrimaryKey = exprs
+	}
+}
+
+// WithPartitionBy 指定 PARTITION BY 表达式
+func WithPartitionBy(expr string) Option {
+	return func(t *Table) {
+		t.PartitionBy = expr
+	}
+}
+
+// WithTTL 指定表级 TTL 表达式
+func WithTTL(expr string) Option {
+	return func(t *Table) {
+		t.TTL = expr
+	}
+}
+
+// WithIndex 追加数据跳过索引
+func WithIndex(idx Index) Option {
+	return func(t *Table) {
+		t.Indexes = append(t.Indexes, idx)
+	}
+}
+
+// WithSetting 追加表级 SETTINGS
+func WithSetting(key, value string) Option {
+	return func(t *Table) {
+		if t.Settings == nil {
+			t.Settings = map[string]string{}
+		}
+		t.Settings[key] = value
+	}
+}
+
+// WithComment 指定表注释
+func WithComment(comment string) Option {
+	return func(t *Table) {
+		t.Comment = comment
+	}
+}
+
+// FromStruct 依据 ENTITY 的结构体标签生成表定义，table 支持 db.table 形式。
+// 列名规则与 clickhouse.Repository 一致：db -> ch -> json 标签 -> 小写字段名
+func FromStruct[ENTITY any](table string, opts ...Option) (*Table, error) {
+	return FromType(reflect.TypeOf((*ENTITY)(nil)).Elem(), table, opts...)
+}
+
+// orderedColumn ORDER BY 候选列
+type orderedColumn struct {
+	name string
+	pos  int
+}
+
+// FromType 与 FromStruct 相同，接收反射类型
+func FromType(typ reflect.Type, table string, opts ...Option) (*Table, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+
+	db, name := splitTableName(table)
+	if name == "" {
+		return nil, ErrInvalidTableName
+	}
+	t := &Table{Database: db, Name: name}
+
+	var (
+		orderBy            []orderedColumn
+		primaryKey, sums   []string
+		version, isDeleted string
+		partitionBy        string
+	)
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tags := parseTag(sf.Tag.Get(TagName))
+		if _, skip := tags["-"]; skip {
+			continue
+		}
+		col := ColumnName(sf)
+		if col == "-" {
+			continue
+		}
+
+		c, err := buildColumn(sf, col, tags)
+		if err != nil {
+			return nil, err
+		}
+		t.Columns = append(t.Columns, c)
+
+		if v, ok := tags["order_by"]; ok {
+			pos := 1 << 30
+			if n, err := strconv.Atoi(v); err == nil {
+				pos = n
+			}
+			orderBy = append(orderBy, orderedColumn{name: col, pos: pos})
+		}
+		if _, ok := tags["primary_key"]; ok {
+			primaryKey = append(primaryKey, quoteIdent(col))
+		}
+		if fn, ok := tags["partition"]; ok {
+			if fn == "" {
+				partitionBy = quoteIdent(col)
+			} else {
+				partitionBy = fn + "(" + quoteIdent(col) + ")"
+			}
+		}
+		if _, ok := tags["version"]; ok {
+			version = col
+		}
+		if _, ok := tags["is_deleted"]; ok {
+			isDeleted = col
+		}
+		if _, ok := tags["sum"]; ok {
+			sums = append(sums, col)
+		}
+		if idxType, ok := tags["index"]; ok && idxType != "" {
+			idx := Index{Name: "idx_" + col, Expr: quoteIdent(col), Type: idxType, Granularity: 1}
+			if n, err := strconv.Atoi(tags["index_granularity"]); err == nil && n > 0 {
+				idx.Granularity = n
+			}
+			t.Indexes = append(t.Indexes, idx)
+		}
+	}
+	if len(t.Columns) == 0 {
+		return nil, ErrNoColumns
+	}
+
+	sort.SliceStable(orderBy, func(i, j int) bool { return orderBy[i].pos < orderBy[j].pos })
+	for _, o := range orderBy {
+		t.OrderBy = append(t.OrderBy, quoteIdent(o.name))
+	}
+	t.PrimaryKey = primaryKey
+	t.PartitionBy = partitionBy
+
+	switch {
+	case version != "" || isDeleted != "":
+		t.Engine = ReplacingMergeTree(version, isDeleted)
+	case len(sums) > 0:
+		t.Engine = SummingMergeTree(sums...)
+	default:
+		t.Engine = MergeTree()
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
+}
+
+// buildColumn 生成列定义
+func buildColumn(sf reflect.StructField, name string, tags map[string]string) (Column, error) {
+	c := Column{
+		Name:    name,
+		Type:    tags["type"],
+		Codec:   tags["codec"],
+		Comment: tags["comment"],
+		TTL:     tags["ttl"],
+	}
+
+	if c.Type == "" {
+		precision := DefaultDateTimePrecision
+		if n, err := strconv.Atoi(tags["precision"]); err == nil {
+			precision = n
+		}
+		typ, nullable, err := inferType(sf.Type, precision, tags["timezone"])
+		if err != nil {
+			return Column{}, fmt.Errorf("%w: field %s (%s)", err, sf.Name, sf.Type)
+		}
+		_, forceNullable := tags["nullable"]
+		if nullable || forceNullable {
+			typ = "Nullable(" + typ + ")"
+		}
+		if _, ok := tags["low_cardinality"]; ok {
+			typ = "LowCardinality(" + typ + ")"
+		}
+		c.Type = typ
+	}
+
+	for _, kind := range []string{"default", "materialized", "alias"} {
+		if expr, ok := tags[kind]; ok {
+			c.DefaultKind = strings.ToUpper(kind)
+			c.DefaultExpr = expr
+			break
+		}
+	}
+	return c, nil
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	timestampType = reflect.TypeOf(timestamppb.Timestamp{})
+)
+
+// inferType 由 Go 类型推断 ClickHouse 类型，指针与 sql.Null* 返回 nullable
+func inferType(t reflect.Type, precision int, timezone string) (string, bool, error) {
+	nullable := false
+	if t.Kind() == reflect.Ptr {
+		nullable = true
+		t = t.Elem()
+	}
+
+	// sql.NullString 等以 Valid 字段表示空值的类型
+	if t.Kind() == reflect.Struct && t.NumField() == 2 && t.Field(1).Name == "Valid" && t.PkgPath() == "database/sql" {
+		inner, _, err := inferType(t.Field(0).Type, precision, timezone)
+		return inner, true, err
+	}
+
+	switch t {
+	case timeType, timestampType:
+		if timezone != "" {
+			return fmt.Sprintf("DateTime64(%d, '%s')", precision, timezone), nullable, nil
+		}
+		return fmt.Sprintf("DateTime64(%d)", precision), nullable, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "Bool", nullable, nil
+	case reflect.String:
+		return "String", nullable, nil
+	case reflect.Int8:
+		return "Int8", nullable, nil
+	case reflect.Int16:
+		return "Int16", nullable, nil
+	case reflect.Int32:
+		return "Int32", nullable, nil
+	case reflect.Int, reflect.Int64:
+		return "Int64", nullable, nil
+	case reflect.Uint8:
+		return "UInt8", nullable, nil
+	case reflect.Uint16:
+		return "UInt16", nullable, nil
+	case reflect.Uint32:
+		return "UInt32", nullable, nil
+	case reflect.Uint, reflect.Uint64:
+		return "UInt64", nullable, nil
+	case reflect.Float32:
+		return "Float32", nullable, nil
+	case reflect.Float64:
+		return "Float64", nullable, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "String", nullable, nil
+		}
+		elem, elemNullable, err := inferType(t.Elem(), precision, timezone)
+		if err != nil {
+			return "", false, err
+		}
+		if elemNullable {
+			elem = "Nullable(" + elem + ")"
+		}
+		// Array 不能为 Nullable
+		return "Array(" + elem + ")", false, nil
+	case reflect.Map:
+		key, _, err := inferType(t.Key(), precision, timezone)
+		if err != nil {
+			return "", false, err
+		}
+		val, valNullable, err := inferType(t.Elem(), precision, timezone)
+		if err != nil {
+			return "", false, err
+		}
+		if valNullable {
+			val = "Nullable(" + val + ")"
+		}
+		// Map 不能为 Nullable
+		return "Map(" + key + ", " + val + ")", false, nil
+	}
+	return "", false, ErrUnsupportedType
+}
+
+// ColumnName 返回结构体字段对应的列名，优先级：db -> ch -> json 标签 -> 小写字段名，忽略标签中的选项（如 ,omitempty）。
+// DDL 生成与仓库的读写、批量插入共用此规则；标签为 "-" 时返回 "-"，由调用方跳过该字段
+func ColumnName(sf reflect.StructField) string {
+	for _, tag := range []string{"db", "ch", "json"} {
+		if col := strings.TrimSpace(strings.Split(sf.Tag.Get(tag), ",")[0]); col != "" {
+			return col
+		}
+	}
+	return strings.ToLower(sf.Name)
+}
+
+// parseTag 解析 `schema:"k1:v1;k2"`，值中的第一个冒号之后原样保留
+func parseTag(tag string) map[string]string {
+	tags := map[string]string{}
+	for _, part := range strings.Split(tag, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, ":")
+		tags[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return tags
+}
+
+// splitTableName 拆分 db.table，去掉反引号
+func splitTableName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	db, table, found := strings.Cut(name, ".")
+	if !found {
+		return "", strings.Trim(name, "`")
+	}
+	return strings.Trim(db, "`"), strings.Trim(table, "`")
+}
+
+// quoteIdent 使用反引号引用标识符
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
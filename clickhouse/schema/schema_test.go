@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	Host     string            `ch:"host" schema:"low_cardinality;order_by:1"`
+	Ts       time.Time         `ch:"ts" schema:"precision:6;timezone:UTC;order_by:2;partition:toYYYYMM"`
+	UserID   *uint64           `ch:"user_id"`
+	Region   sql.NullString    `ch:"region" schema:"low_cardinality"`
+	Tags     []string          `ch:"tags"`
+	Attrs    map[string]string `ch:"attrs" schema:"codec:ZSTD(3)"`
+	Message  string            `ch:"message" schema:"index:tokenbf_v1(512, 3, 0);index_granularity:4;comment:raw message"`
+	Version  uint64            `ch:"version" schema:"version"`
+	Deleted  uint8             `ch:"deleted" schema:"is_deleted;default:0"`
+	internal string
+	Ignored  string `ch:"ignored" schema:"-"`
+}
+
+type counter struct {
+	Day   time.Time `db:"day" schema:"type:Date;order_by"`
+	Hits  uint64    `db:"hits" schema:"sum"`
+	Bytes uint64    `db:"bytes" schema:"sum;ttl:day + INTERVAL 1 MONTH"`
+}
+
+func TestFromStruct_Types(t *testing.T) {
+	tbl, err := FromStruct[event]("logs.events")
+	require.NoError(t, err)
+
+	assert.Equal(t, "logs", tbl.Database)
+	assert.Equal(t, "events", tbl.Name)
+
+	types := map[string]string{}
+	for _, c := range tbl.Columns {
+		types[c.Name] = c.Type
+	}
+	assert.Equal(t, map[string]string{
+		"host":    "LowCardinality(String)",
+		"ts":      "DateTime64(6, 'UTC')",
+		"user_id": "Nullable(UInt64)",
+		"region":  "LowCardinality(Nullable(String))",
+		"tags":    "Array(String)",
+		"attrs":   "Map(String, String)",
+		"message": "String",
+		"version": "UInt64",
+		"deleted": "UInt8",
+	}, types)
+
+	assert.Equal(t, []string{"`host`", "`ts`"}, tbl.OrderBy)
+	assert.Equal(t, "toYYYYMM(`ts`)", tbl.PartitionBy)
+	assert.Equal(t, "ReplacingMergeTree(`version`, `deleted`)", tbl.Engine.String())
+	require.Len(t, tbl.Indexes, 1)
+	assert.Equal(t, Index{Name: "idx_message", Expr: "`message`", Type: "tokenbf_v1(512, 3, 0)", Granularity: 4}, tbl.Indexes[0])
+}
+
+func TestFromStruct_Errors(t *testing.T) {
+	_, err := FromStruct[int]("t")
+	assert.ErrorIs(t, err, ErrNotStruct)
+
+	_, err = FromStruct[struct{ C chan int }]("t")
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	_, err = FromStruct[event]("")
+	assert.ErrorIs(t, err, ErrInvalidTableName)
+}
+
+func TestTable_CreateSQL(t *testing.T) {
+	tbl, err := FromStruct[counter]("daily_counters",
+		WithTTL("day + INTERVAL 1 YEAR"),
+		WithSetting("index_granularity", "8192"),
+		WithComment("daily counters"),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS `daily_counters`\n"+
+		"(\n"+
+		"    `day` Date,\n"+
+		"    `hits` UInt64,\n"+
+		"    `bytes` UInt64 TTL day + INTERVAL 1 MONTH\n"+
+		")\n"+
+		"ENGINE = SummingMergeTree((`hits`, `bytes`))\n"+
+		"ORDER BY `day`\n"+
+		"TTL day + INTERVAL 1 YEAR\n"+
+		"SETTINGS index_granularity = 8192\n"+
+		"COMMENT 'daily counters'", tbl.CreateSQL())
+
+	tbl, err = FromStruct[event]("events", WithEngine(MergeTree()), WithOrderBy(), WithPrimaryKey())
+	require.NoError(t, err)
+	sqlStr := tbl.CreateSQL()
+	assert.Contains(t, sqlStr, "`message` String COMMENT 'raw message'")
+	assert.Contains(t, sqlStr, "`attrs` Map(String, String) CODEC(ZSTD(3))")
+	assert.Contains(t, sqlStr, "`deleted` UInt8 DEFAULT 0")
+	assert.Contains(t, sqlStr, "INDEX `idx_message` `message` TYPE tokenbf_v1(512, 3, 0) GRANULARITY 4")
+	assert.Contains(t, sqlStr, "ENGINE = MergeTree()\nPARTITION BY toYYYYMM(`ts`)\nORDER BY tuple()")
+}
+
+func TestDiff(t *testing.T) {
+	tbl, err := FromStruct[event]("events")
+	require.NoError(t, err)
+
+	m := Diff(tbl,
+		[]ExistingColumn{
+			{Name: "host", Type: "LowCardinality(String)"},
+			{Name: "ts", Type: "DateTime64(3, 'UTC')"},
+			{Name: "tags", Type: "Array(String)"},
+			{Name: "legacy", Type: "String"},
+		},
+		nil,
+	)
+
+	assert.False(t, m.Empty())
+	assert.Equal(t, []TypeChange{{Column: "ts", From: "DateTime64(3, 'UTC')", To: "DateTime64(6, 'UTC')"}}, m.TypeChanges)
+	assert.Equal(t, []string{"legacy"}, m.ExtraColumns)
+
+	stmts := m.Statements()
+	require.Len(t, stmts, 7)
+	assert.Equal(t, "ALTER TABLE `events` ADD COLUMN IF NOT EXISTS `user_id` Nullable(UInt64) AFTER `ts`", stmts[0])
+	assert.Equal(t, "ALTER TABLE `events` ADD COLUMN IF NOT EXISTS `attrs` Map(String, String) CODEC(ZSTD(3)) AFTER `tags`", stmts[2])
+	assert.Equal(t, "ALTER TABLE `events` ADD INDEX IF NOT EXISTS `idx_message` `message` TYPE tokenbf_v1(512, 3, 0) GRANULARITY 4", stmts[6])
+
+	m = Diff(tbl, []ExistingColumn{
+		{Name: "host", Type: "LowCardinality(String)"},
+		{Name: "ts", Type: "DateTime64(6,'UTC')"},
+		{Name: "user_id", Type: "Nullable(UInt64)"},
+		{Name: "region", Type: "LowCardinality(Nullable(String))"},
+		{Name: "tags", Type: "Array(String)"},
+		{Name: "attrs", Type: "Map(String, String)"},
+		{Name: "message", Type: "String"},
+		{Name: "version", Type: "UInt64"},
+		{Name: "deleted", Type: "UInt8"},
+	}, []ExistingIndex{{Name: "idx_message"}})
+	assert.True(t, m.Empty())
+	assert.Empty(t, m.TypeChanges)
+	assert.Nil(t, m.Statements())
+}
@@ -3,24 +3,40 @@ package sorting
 import (
 	"strings"
 
+	"github.com/tx7do/go-utils/stringcase"
+	"google.golang.org/protobuf/proto"
+
 	pagination "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
 	"github.com/tx7do/go-crud/clickhouse/query"
+	"github.com/tx7do/go-crud/dialect"
+	"github.com/tx7do/go-crud/ordering"
 )
 
 // StructuredSorting 将结构化排序指令转换为 ClickHouse 的 ORDER BY 子句
-type StructuredSorting struct{}
+type StructuredSorting struct {
+	tieBreaker string
+}
 
-// NewStructuredSorting 创建实例
+// NewStructuredSorting 创建实例。
+// ClickHouse 表通常没有唯一主键，默认不追加兜底排序字段，可通过 SetTieBreaker 设置。
 func NewStructuredSorting() *StructuredSorting {
 	return &StructuredSorting{}
 }
 
-// BuildOrderClause 根据传入的排序指令构造 ORDER BY 子句
+// SetTieBreaker 设置兜底排序字段（升序追加在排序指令末尾以保证分页稳定），为空则不追加
+func (ss *StructuredSorting) SetTieBreaker(field string) *StructuredSorting {
+	ss.tieBreaker = strings.TrimSpace(field)
+	return ss
+}
+
+// BuildOrderClause 根据传入的排序指令构造 ORDER BY 子句，
+// 支持空值位置、忽略大小写、排序规则、JSON 路径与日期部分，非法的排序指令将被忽略
 func (ss StructuredSorting) BuildOrderClause(builder *query.Builder, orders []*pagination.Sorting) *query.Builder {
 	if len(orders) == 0 {
 		return builder
 	}
 
+	var applied []*pagination.Sorting
 	for _, o := range orders {
 		if o == nil {
 			continue
@@ -35,12 +51,47 @@ func (ss StructuredSorting) BuildOrderClause(builder *query.Builder, orders []*p
 			continue
 		}
 
-		builder.OrderBy(field, o.GetOrder() == pagination.Sorting_DESC)
+		if ordering.Plain(o) && o.GetNulls() == pagination.Sorting_NULLS_DEFAULT {
+			builder.OrderBy(field, o.GetOrder() == pagination.Sorting_DESC)
+			applied = append(applied, o)
+			continue
+		}
+
+		column, key := splitField(field, o)
+		clauses, err := ordering.Clauses(dialect.ClickHouse, column, key)
+		if err != nil {
+			continue
+		}
+		for _, c := range clauses {
+			builder.OrderByExpr(c)
+		}
+		applied = append(applied, o)
+	}
+
+	if len(applied) > 0 && ss.tieBreaker != "" && fieldNameRegexp.MatchString(ss.tieBreaker) {
+		if len(ordering.WithTieBreaker(applied, ss.tieBreaker)) > len(applied) {
+			builder.OrderBy(ss.tieBreaker, false)
+		}
 	}
 
 	return builder
 }
 
+// splitField 与 Builder.OrderBy 一致：点号前为列名（转为蛇形），点号后为 JSON 路径，并与 json_path 拼接
+func splitField(field string, o *pagination.Sorting) (string, *pagination.Sorting) {
+	col, path, dotted := strings.Cut(field, ".")
+	if !dotted {
+		return stringcase.ToSnakeCase(field), o
+	}
+
+	if p := o.GetJsonPath(); p != "" {
+		path += "." + p
+	}
+	key := proto.Clone(o).(*pagination.Sorting)
+	key.JsonPath = proto.String(path)
+	return stringcase.ToSnakeCase(col), key
+}
+
 // BuildOrderClauseWithDefaultField 当 orders 为空时使用默认排序字段
 func (ss StructuredSorting) BuildOrderClauseWithDefaultField(builder *query.Builder, orders []*pagination.Sorting, defaultOrderField string, defaultDesc bool) *query.Builder {
 	if len(orders) == 0 {
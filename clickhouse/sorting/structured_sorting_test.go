@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 
+	"google.golang.org/protobuf/proto"
+
 	pagination "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
 	"github.com/tx7do/go-crud/clickhouse/query"
 )
@@ -75,3 +77,32 @@ func TestStructuredSorting_BuildOrderClauseWithDefaultField(t *testing.T) {
 		t.Fatalf("expected ORDER BY score DESC, got: %s", sql2)
 	}
 }
+
+func TestStructuredSorting_BuildOrderClause_Rich(t *testing.T) {
+	ss := NewStructuredSorting().SetTieBreaker("id")
+	qb := query.NewQueryBuilder("test_table", nil)
+
+	orders := []*pagination.Sorting{
+		{Field: "userName", Order: pagination.Sorting_DESC, Nulls: pagination.Sorting_NULLS_FIRST, CaseInsensitive: true},
+		{Field: "metadata.owner", JsonPath: proto.String("name"), Collation: proto.String("en")},
+		{Field: "created_at", DatePart: pagination.DatePart_WEEK_DAY},
+		{Field: "name", JsonPath: proto.String("x'); DROP TABLE t")},
+	}
+
+	sql, _ := ss.BuildOrderClause(qb, orders).Build()
+	want := "ORDER BY lowerUTF8(user_name) DESC NULLS FIRST, " +
+		"JSONExtractString(metadata, 'owner', 'name') ASC COLLATE 'en', " +
+		"(toDayOfWeek(created_at) % 7) ASC, id ASC"
+	if !strings.Contains(sql, want) {
+		t.Fatalf("expected %q, got: %s", want, sql)
+	}
+	if strings.Contains(sql, "DROP") {
+		t.Fatalf("invalid json path must be ignored, got: %s", sql)
+	}
+
+	// 已按兜底字段排序时不再追加
+	sql, _ = ss.BuildOrderClause(query.NewQueryBuilder("test_table", nil), []*pagination.Sorting{{Field: "id", Order: pagination.Sorting_DESC}}).Build()
+	if strings.Contains(sql, "id ASC") {
+		t.Fatalf("did not expect tie-breaker, got: %s", sql)
+	}
+}
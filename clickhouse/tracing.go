@@ -0,0 +1,136 @@
+package clickhouse
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/tx7do/go-crud/tracing"
+)
+
+// tracedConn 包装 driver.Conn，为每条语句创建 span。
+// Client 与 Repository 均通过 conn 访问数据库，因此启用追踪后所有操作都会被记录。
+type tracedConn struct {
+	driver.Conn
+	tracer *tracing.Tracer
+}
+
+func (c *tracedConn) startSpan(ctx context.Context, query string) (context.Context, *tracing.Span) {
+	operation, table := tracing.ParseStatement(query)
+	ctx, span := c.tracer.Start(ctx, operation, table)
+	span.SetStatement(query)
+	return ctx, span
+}
+
+func (c *tracedConn) Select(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, span := c.startSpan(ctx, query)
+	err := c.Conn.Select(ctx, dest, query, args...)
+	if err == nil {
+		span.SetReturnedRows(tracing.SliceLen(dest))
+	}
+	span.End(err)
+	return err
+}
+
+func (c *tracedConn) Query(ctx context.Context, query string, args ...any) (driver.Rows, error) {
+	ctx, span := c.startSpan(ctx, query)
+	rows, err := c.Conn.Query(ctx, query, args...)
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (c *tracedConn) QueryRow(ctx context.Context, query string, args ...any) driver.Row {
+	ctx, span := c.startSpan(ctx, query)
+	row := c.Conn.QueryRow(ctx, query, args...)
+	span.End(row.Err())
+	return row
+}
+
+func (c *tracedConn) PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (driver.Batch, error) {
+	ctx, span := c.startSpan(ctx, query)
+	batch, err := c.Conn.PrepareBatch(ctx, query, opts...)
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+	return &tracedBatch{Batch: batch, span: span}, nil
+}
+
+func (c *tracedConn) Exec(ctx context.Context, query string, args ...any) error {
+	ctx, span := c.startSpan(ctx, query)
+	err := c.Conn.Exec(ctx, query, args...)
+	span.End(err)
+	return err
+}
+
+func (c *tracedConn) AsyncInsert(ctx context.Context, query string, wait bool, args ...any) error {
+	ctx, span := c.startSpan(ctx, query)
+	err := c.Conn.AsyncInsert(ctx, query, wait, args...)
+	span.End(err)
+	return err
+}
+
+// tracedRows 在 Close 时结束 span，并记录迭代到的行数
+type tracedRows struct {
+	driver.Rows
+	span *tracing.Span
+
+	count int64
+	once  sync.Once
+}
+
+func (r *tracedRows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	return false
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	r.once.Do(func() {
+		r.span.SetReturnedRows(r.count)
+		r.span.End(r.Rows.Err())
+	})
+	return err
+}
+
+// tracedBatch 在 Send/Abort/Close 时结束 span，并记录写入的行数
+type tracedBatch struct {
+	driver.Batch
+	span *tracing.Span
+
+	once sync.Once
+}
+
+func (b *tracedBatch) end(rows int, err error) {
+	b.once.Do(func() {
+		b.span.SetAffectedRows(int64(rows))
+		b.span.End(err)
+	})
+}
+
+func (b *tracedBatch) Send() error {
+	rows := b.Batch.Rows()
+	err := b.Batch.Send()
+	b.end(rows, err)
+	return err
+}
+
+func (b *tracedBatch) Abort() error {
+	err := b.Batch.Abort()
+	b.end(0, err)
+	return err
+}
+
+func (b *tracedBatch) Close() error {
+	rows := b.Batch.Rows()
+	err := b.Batch.Close()
+	b.end(rows, err)
+	return err
+}
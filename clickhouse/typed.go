@@ -0,0 +1,211 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Statement 可生成 SQL 与参数的查询，*query.Builder 实现了该接口
+type Statement interface {
+	Build() (string, []any)
+}
+
+// rawStatement 原始 SQL 与参数
+type rawStatement struct {
+	query string
+	args  []any
+}
+
+func (s rawStatement) Build() (string, []any) {
+	return s.query, s.args
+}
+
+// SQL 将原始 SQL 与参数包装为 Statement
+func SQL(query string, args ...any) Statement {
+	return rawStatement{query: query, args: args}
+}
+
+// errStopStream 用于在 SelectOne 中提前结束 Stream
+var errStopStream = errors.New("stop stream")
+
+// SelectAll 执行查询并将所有行扫描为 T。
+// 列按 schema.ColumnName（db -> ch -> json 标签 -> 小写字段名）-> 忽略大小写的字段名匹配 T 的字段，匹配结果按 (T, 列) 缓存；
+// Map、Array、Tuple 列由驱动扫描到 map、切片与结构体字段，
+// 展开的 Nested 列（如 items.name）在没有同名字段时写入切片字段 items 中元素的 name 字段
+func SelectAll[T any](ctx context.Context, c *Client, stmt Statement) ([]*T, error) {
+	var results []*T
+	err := Stream[T](ctx, c, stmt, func(v *T) error {
+		results = append(results, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SelectOne 执行查询并返回第一行，没有结果时返回 nil, nil
+func SelectOne[T any](ctx context.Context, c *Client, stmt Statement) (*T, error) {
+	var result *T
+	err := Stream[T](ctx, c, stmt, func(v *T) error {
+		result = v
+		return errStopStream
+	})
+	if err != nil && !errors.Is(err, errStopStream) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Stream 执行查询并逐行扫描为 T 后交给 fn 处理，不在内存中保留结果；fn 返回错误时停止读取并返回该错误
+func Stream[T any](ctx context.Context, c *Client, stmt Statement, fn func(*T) error) error {
+	if c == nil || c.conn == nil {
+		return ErrClientNotInitialized
+	}
+	if stmt == nil || fn == nil {
+		c.logger.Error("statement and callback cannot be nil")
+		return ErrInvalidArgument
+	}
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		c.logger.Errorf("typed query destination must be a struct, got %s", typ)
+		return ErrInvalidArgument
+	}
+
+	query, args := stmt.Build()
+
+	ctx, done := c.prepareQuery(ctx)
+	defer done()
+
+	rows, err := c.conn.Query(ctx, query, args...)
+	if err != nil {
+		c.logger.Errorf("query failed: %v", err)
+		return ErrQueryExecutionFailed
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			c.logger.Errorf("failed to close rows: %v", cerr)
+		}
+	}()
+
+	plan, err := scanPlanFor(typ, rows.Columns())
+	if err != nil {
+		c.logger.Errorf("build scan plan for %s failed: %v", typ, err)
+		return errors.Join(ErrRowScanFailed, err)
+	}
+
+	for rows.Next() {
+		v := new(T)
+		dest, finish := plan.targets(reflect.ValueOf(v).Elem())
+		if err = rows.Scan(dest...); err != nil {
+			c.logger.Errorf("failed to scan row: %v", err)
+			return ErrRowScanFailed
+		}
+		finish()
+
+		if err = fn(v); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		c.logger.Errorf("rows iteration error: %v", err)
+		return ErrRowsIterationError
+	}
+	return nil
+}
+
+// scanColumn 单列的扫描目标
+type scanColumn struct {
+	// field 直接扫描的字段下标；Nested 列为 -1
+	field int
+
+	// nested Nested 列所属的切片字段下标，elemField 为切片元素中的字段下标
+	nested    int
+	elemField int
+	elemType  reflect.Type
+}
+
+// scanPlan 结构体类型与查询列的映射
+type scanPlan struct {
+	columns []scanColumn
+}
+
+var scanPlanCache sync.Map // batchFieldKey -> *scanPlan
+
+// scanPlanFor 返回 t 与 columns 的扫描计划，结果被缓存
+func scanPlanFor(t reflect.Type, columns []string) (*scanPlan, error) {
+	key := batchFieldKey{typ: t, columns: strings.Join(columns, ",")}
+	if v, ok := scanPlanCache.Load(key); ok {
+		return v.(*scanPlan), nil
+	}
+
+	plan := &scanPlan{columns: make([]scanColumn, len(columns))}
+	for i, col := range columns {
+		sc, err := resolveScanColumn(t, col)
+		if err != nil {
+			return nil, err
+		}
+		plan.columns[i] = sc
+	}
+
+	v, _ := scanPlanCache.LoadOrStore(key, plan)
+	return v.(*scanPlan), nil
+}
+
+// resolveScanColumn 查找列对应的字段，找不到时尝试按 Nested 列 parent.child 匹配结构体切片字段
+func resolveScanColumn(t reflect.Type, col string) (scanColumn, error) {
+	if idx := findBatchField(t, col); idx >= 0 {
+		return scanColumn{field: idx, nested: -1}, nil
+	}
+
+	if parent, child, ok := strings.Cut(col, "."); ok {
+		if p := findBatchField(t, parent); p >= 0 {
+			ft := t.Field(p).Type
+			if ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct {
+				if e := findBatchField(ft.Elem(), child); e >= 0 {
+					return scanColumn{field: -1, nested: p, elemField: e, elemType: ft.Elem().Field(e).Type}, nil
+				}
+			}
+		}
+	}
+	return scanColumn{}, fmt.Errorf("未找到列 %s 对应的结构体字段", col)
+}
+
+// targets 返回一行的扫描目标，finish 在 Scan 之后将 Nested 列的数组写回切片字段
+func (p *scanPlan) targets(v reflect.Value) ([]any, func()) {
+	dest := make([]any, len(p.columns))
+	var nested []func()
+	for i, sc := range p.columns {
+		if sc.field >= 0 {
+			dest[i] = v.Field(sc.field).Addr().Interface()
+			continue
+		}
+
+		tmp := reflect.New(reflect.SliceOf(sc.elemType))
+		dest[i] = tmp.Interface()
+		nested = append(nested, func() {
+			values := tmp.Elem()
+			parent := v.Field(sc.nested)
+			if n := values.Len(); parent.Len() < n {
+				grown := reflect.MakeSlice(parent.Type(), n, n)
+				reflect.Copy(grown, parent)
+				parent.Set(grown)
+			}
+			for j := 0; j < values.Len(); j++ {
+				parent.Index(j).Field(sc.elemField).Set(values.Index(j))
+			}
+		})
+	}
+
+	return dest, func() {
+		for _, f := range nested {
+			f()
+		}
+	}
+}
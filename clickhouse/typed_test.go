@@ -0,0 +1,161 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	clickhouseV2 "github.com/ClickHouse/clickhouse-go/v2"
+	driverV2 "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tx7do/go-crud/clickhouse/query"
+)
+
+type orderItem struct {
+	SKU string `ch:"sku"`
+	Qty uint32 `ch:"qty"`
+}
+
+type order struct {
+	ID     uint64            `ch:"id"`
+	Tags   []string          `ch:"tags"`
+	Attrs  map[string]string `ch:"attrs"`
+	Items  []orderItem       `ch:"items"`
+	Remark string
+}
+
+// fakeRowsConn Query 返回预置的列与行
+type fakeRowsConn struct {
+	clickhouseV2.Conn
+
+	columns []string
+	rows    [][]any
+	query   string
+	args    []any
+}
+
+type fakeRows struct {
+	driverV2.Rows
+
+	columns []string
+	rows    [][]any
+	cur     int
+	closed  bool
+}
+
+func (c *fakeRowsConn) Query(_ context.Context, query string, args ...any) (driverV2.Rows, error) {
+	c.query, c.args = query, args
+	return &fakeRows{columns: c.columns, rows: c.rows, cur: -1}, nil
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Next() bool {
+	r.cur++
+	return r.cur < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if len(dest) != len(r.columns) {
+		return fmt.Errorf("expected %d destinations, got %d", len(r.columns), len(dest))
+	}
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.rows[r.cur][i]))
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) Close() error {
+	r.closed = true
+	return nil
+}
+
+func newTypedTestClient(conn clickhouseV2.Conn) *Client {
+	return &Client{conn: conn, logger: log.NewHelper(log.DefaultLogger)}
+}
+
+func TestSelectAll_NestedAndComposite(t *testing.T) {
+	conn := &fakeRowsConn{
+		columns: []string{"id", "tags", "attrs", "items.sku", "items.qty", "remark"},
+		rows: [][]any{
+			{uint64(1), []string{"a"}, map[string]string{"k": "v"}, []string{"s1", "s2"}, []uint32{1, 2}, "first"},
+			{uint64(2), []string{}, map[string]string{}, []string{}, []uint32{}, ""},
+		},
+	}
+	c := newTypedTestClient(conn)
+
+	qb := query.NewQueryBuilder("orders", log.NewHelper(log.DefaultLogger)).Where("id > ?", 0)
+	orders, err := SelectAll[order](context.Background(), c, qb)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+
+	assert.Equal(t, "SELECT * FROM orders WHERE id > ?", conn.query)
+	assert.Equal(t, []any{0}, conn.args)
+	assert.Equal(t, &order{
+		ID:     1,
+		Tags:   []string{"a"},
+		Attrs:  map[string]string{"k": "v"},
+		Items:  []orderItem{{SKU: "s1", Qty: 1}, {SKU: "s2", Qty: 2}},
+		Remark: "first",
+	}, orders[0])
+	assert.Empty(t, orders[1].Items)
+}
+
+func TestSelectOne_And_Stream(t *testing.T) {
+	conn := &fakeRowsConn{
+		columns: []string{"id"},
+		rows:    [][]any{{uint64(7)}, {uint64(8)}},
+	}
+	c := newTypedTestClient(conn)
+
+	o, err := SelectOne[order](context.Background(), c, SQL("SELECT id FROM orders"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), o.ID)
+
+	stop := errors.New("stop")
+	var seen []uint64
+	err = Stream[order](context.Background(), c, SQL("SELECT id FROM orders"), func(o *order) error {
+		seen = append(seen, o.ID)
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, []uint64{7}, seen)
+
+	conn.rows = nil
+	o, err = SelectOne[order](context.Background(), c, SQL("SELECT id FROM orders"))
+	require.NoError(t, err)
+	assert.Nil(t, o)
+}
+
+func TestSelectAll_Errors(t *testing.T) {
+	_, err := SelectAll[order](context.Background(), nil, SQL("SELECT 1"))
+	assert.ErrorIs(t, err, ErrClientNotInitialized)
+
+	c := newTypedTestClient(&fakeRowsConn{columns: []string{"missing"}, rows: [][]any{{1}}})
+	_, err = SelectAll[order](context.Background(), c, SQL("SELECT missing"))
+	assert.ErrorIs(t, err, ErrRowScanFailed)
+
+	_, err = SelectAll[int](context.Background(), c, SQL("SELECT 1"))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
+
+func TestScanPlan_Cache(t *testing.T) {
+	rt := reflect.TypeOf(order{})
+	plan, err := scanPlanFor(rt, []string{"Remark", "items.qty"})
+	require.NoError(t, err)
+	assert.Equal(t, []scanColumn{
+		{field: 4, nested: -1},
+		{field: -1, nested: 3, elemField: 1, elemType: reflect.TypeOf(uint32(0))},
+	}, plan.columns)
+
+	cached, err := scanPlanFor(rt, []string{"Remark", "items.qty"})
+	require.NoError(t, err)
+	assert.Same(t, plan, cached)
+}
@@ -1,10 +1,13 @@
 package clickhouse
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/go-crud/clickhouse/schema"
 )
 
 // Ptr returns a pointer to the provided value.
@@ -81,3 +84,45 @@ func TestStructToValueArrayWithCandlePtr(t *testing.T) {
 		*values[6].(*float64),
 	)
 }
+
+func TestBatchColumns(t *testing.T) {
+	type row struct {
+		ID      uint64 `ch:"id"`
+		Name    string `json:"name,omitempty"`
+		Ignored string `json:"-"`
+		Score   float64
+		hidden  string
+	}
+
+	cols, err := batchColumns(reflect.TypeOf(row{}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "score"}, cols)
+
+	_, err = batchColumns(reflect.TypeOf(0))
+	assert.Error(t, err)
+}
+
+func TestColumnName(t *testing.T) {
+	type row struct {
+		ID      uint64 `ch:"id" db:"row_id"`
+		Name    string `db:"name,omitempty"`
+		Email   string `json:"email,omitempty"`
+		Ignored string `json:"-"`
+		Score   float64
+	}
+
+	typ := reflect.TypeOf(row{})
+	var cols []string
+	for i := 0; i < typ.NumField(); i++ {
+		cols = append(cols, schema.ColumnName(typ.Field(i)))
+	}
+	// db 优先于 ch，无标签时使用小写字段名，与 DDL 生成一致
+	assert.Equal(t, []string{"row_id", "name", "email", "-", "score"}, cols)
+
+	// 批量写入、扫描与更新使用相同的列名
+	assert.Equal(t, 1, findBatchField(typ, "name"))
+	assert.Equal(t, 4, findBatchField(typ, "score"))
+	assert.Equal(t, 0, findBatchField(typ, "row_id"))
+	assert.Equal(t, -1, findBatchField(typ, "missing"))
+	assert.Equal(t, 1, fieldIndexByColumn(typ, "name"))
+}
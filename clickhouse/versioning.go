@@ -0,0 +1,435 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/tx7do/go-crud/clickhouse/field"
+	"github.com/tx7do/go-crud/clickhouse/schema"
+)
+
+// UpsertMode Upsert/UpsertX 的实现方式
+type UpsertMode int
+
+const (
+	// UpsertMutation 默认方式：先 INSERT，失败后按主键执行 ALTER TABLE ... UPDATE mutation
+	UpsertMutation UpsertMode = iota
+	// UpsertReplacing 适用于 ReplacingMergeTree：写入带新版本号的整行，由合并或读取去重保留最新版本
+	UpsertReplacing
+	// UpsertCollapsing 适用于 CollapsingMergeTree：写入旧状态的取消行（sign = -1）与新状态行（sign = 1）
+	UpsertCollapsing
+)
+
+// DedupMode 读取时的去重方式
+type DedupMode int
+
+const (
+	// DedupAuto 按 UpsertMode 选择：UpsertReplacing 且配置了版本列时使用 argMax，其余版本化方式使用 FINAL，UpsertMutation 不去重
+	DedupAuto DedupMode = iota
+	// DedupNone 不去重
+	DedupNone
+	// DedupFinal 使用 FINAL 修饰符
+	DedupFinal
+	// DedupArgMax 按去重键 GROUP BY，并以 argMax(列, 版本列) 取最新版本，需要配置版本列
+	DedupArgMax
+)
+
+// DefaultSignColumn CollapsingMergeTree 默认的标记列
+const DefaultSignColumn = "sign"
+
+// VersioningOptions 基于行版本的 Upsert 与读取去重设置
+type VersioningOptions struct {
+	Mode UpsertMode
+
+	// KeyColumns 去重键，应与表的 ORDER BY 键一致；为空时使用主键列（pk:"true" 或 id）
+	KeyColumns []string
+
+	// VersionColumn ReplacingMergeTree 的版本列，Upsert 时自动写入新版本号
+	VersionColumn string
+	// SignColumn CollapsingMergeTree 的标记列，为空时使用 DefaultSignColumn
+	SignColumn string
+
+	// Dedup Get/List/Count/Exists 读取时的去重方式
+	Dedup DedupMode
+
+	// Version 生成新版本号，默认为当前时间：64 位整数列为纳秒数，32 位整数列为秒数，time.Time 列为当前时间；
+	// 版本号超出版本列的取值范围时 Upsert 返回错误
+	Version func() uint64
+}
+
+// SetVersioning 设置 Upsert 的实现方式与读取去重方式。
+// 默认使用 UpsertMutation，行为与之前一致；ClickHouse 的 mutation 是异步且昂贵的，
+// 对 ReplacingMergeTree/CollapsingMergeTree 表应改用 UpsertReplacing/UpsertCollapsing。
+func (r *Repository[DTO, ENTITY]) SetVersioning(opts VersioningOptions) {
+	if opts.Mode == UpsertCollapsing && opts.SignColumn == "" {
+		opts.SignColumn = DefaultSignColumn
+	}
+	r.versioning = opts
+}
+
+// dedupMode 返回实际使用的去重方式
+func (r *Repository[DTO, ENTITY]) dedupMode() DedupMode {
+	v := r.versioning
+	switch v.Dedup {
+	case DedupAuto:
+		switch v.Mode {
+		case UpsertReplacing:
+			if v.VersionColumn != "" {
+				return DedupArgMax
+			}
+			return DedupFinal
+		case UpsertCollapsing:
+			return DedupFinal
+		}
+		return DedupNone
+	case DedupArgMax:
+		if v.VersionColumn == "" {
+			return DedupFinal
+		}
+	}
+	return v.Dedup
+}
+
+// readSource 返回读取时 FROM 子句中的数据源：表名、表名 FINAL 或 argMax 去重子查询
+func (r *Repository[DTO, ENTITY]) readSource(final bool) string {
+	switch r.dedupMode() {
+	case DedupArgMax:
+		src, err := r.argMaxSource()
+		if err == nil {
+			return src
+		}
+		r.log.Errorf("build argMax source for %s failed, fallback to FINAL: %v", r.table, err)
+		return r.table + " FINAL"
+	case DedupFinal:
+		return r.table + " FINAL"
+	}
+	if final {
+		return r.table + " FINAL"
+	}
+	return r.table
+}
+
+// argMaxSource 构建按去重键分组、以版本列取每列最新值的子查询
+func (r *Repository[DTO, ENTITY]) argMaxSource() (string, error) {
+	t := reflect.TypeOf((*ENTITY)(nil)).Elem()
+	keys := r.keyColumns(t)
+	if len(keys) == 0 {
+		return "", errDedupKeyColumnsNotFound
+	}
+	isKey := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		isKey[k] = true
+	}
+
+	cols := entityColumns(t)
+	if len(cols) == 0 {
+		return "", errEntityHasNoColumns
+	}
+
+	version := r.versioning.VersionColumn
+	selects := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if isKey[col] {
+			selects = append(selects, col)
+			continue
+		}
+		selects = append(selects, fmt.Sprintf("argMax(_src.%s, _src.%s) AS %s", col, version, col))
+	}
+
+	return fmt.Sprintf("(SELECT %s FROM %s AS _src GROUP BY %s)",
+		strings.Join(selects, ", "), r.table, strings.Join(keys, ", ")), nil
+}
+
+// keyColumns 返回去重键，未配置时使用实体的主键列
+func (r *Repository[DTO, ENTITY]) keyColumns(t reflect.Type) []string {
+	if len(r.versioning.KeyColumns) > 0 {
+		return r.versioning.KeyColumns
+	}
+	if pk := findPrimaryKeyColumn(t); pk != "" {
+		return []string{pk}
+	}
+	return nil
+}
+
+// upsertVersioned 以插入新版本行的方式执行 Upsert，返回写入的新状态实体。
+// 指定 updateMask 或使用 UpsertCollapsing 时，先按去重键读取当前状态
+func (r *Repository[DTO, ENTITY]) upsertVersioned(ctx context.Context, dto *DTO, updateMask *fieldmaskpb.FieldMask) (*ENTITY, error) {
+	field.NormalizeFieldMaskPaths(updateMask)
+	var mask []string
+	if updateMask != nil {
+		mask = updateMask.GetPaths()
+	}
+
+	ent := r.mapper.ToEntity(dto)
+	if ent == nil {
+		return nil, errEntityIsNil
+	}
+
+	var current *ENTITY
+	if r.versioning.Mode == UpsertCollapsing || len(mask) > 0 {
+		var err error
+		if current, err = r.loadCurrent(ctx, ent); err != nil {
+			return nil, err
+		}
+	}
+
+	cols, rows, next, err := r.versionedRows(ent, current, mask)
+	if err != nil {
+		return nil, err
+	}
+
+	placeholders := "(" + strings.TrimRight(strings.Repeat("?,", len(cols)), ",") + ")"
+	values := make([]string, len(rows))
+	var args []any
+	for i, row := range rows {
+		values[i] = placeholders
+		args = append(args, row...)
+	}
+	insertSQL := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", r.table, strings.Join(cols, ","), strings.Join(values, ","))
+
+	if err = r.client.conn.Exec(ctx, insertSQL, args...); err != nil {
+		r.log.Errorf("upsert insert failed: %v", err)
+		return nil, errUpsertInsertFailed
+	}
+	return next, nil
+}
+
+// loadCurrent 按 ent 的去重键读取当前（去重后）的状态，不存在时返回 nil
+func (r *Repository[DTO, ENTITY]) loadCurrent(ctx context.Context, ent *ENTITY) (*ENTITY, error) {
+	v := reflect.ValueOf(ent).Elem()
+	keys := r.keyColumns(v.Type())
+	if len(keys) == 0 {
+		return nil, errUpsertKeyColumnsNotFound
+	}
+
+	conds := make([]string, 0, len(keys))
+	args := make([]any, 0, len(keys))
+	for _, k := range keys {
+		idx := fieldIndexByColumn(v.Type(), k)
+		if idx < 0 {
+			return nil, fmt.Errorf("key column %s not found in entity", k)
+		}
+		conds = append(conds, k+" = ?")
+		args = append(args, v.Field(idx).Interface())
+	}
+
+	var rawResults []any
+	creator := func() any {
+		var e ENTITY
+		return &e
+	}
+	sqlStr := "SELECT * FROM " + r.readSource(true) + " WHERE " + strings.Join(conds, " AND ") + " LIMIT 1"
+	if err := r.client.Query(ctx, creator, &rawResults, sqlStr, args...); err != nil {
+		r.log.Errorf("read current version failed: %v", err)
+		return nil, errReadCurrentVersionFailed
+	}
+	if len(rawResults) == 0 {
+		return nil, nil
+	}
+	if ptr, ok := rawResults[0].(*ENTITY); ok {
+		return ptr, nil
+	}
+	return nil, errUnexpectedResultType
+}
+
+// versionedRows 生成要插入的列与行：
+// mask 非空且存在当前状态时，仅将 mask 中的字段覆盖到当前状态上；
+// UpsertReplacing 写入新的版本号，UpsertCollapsing 在新状态行（sign = 1）前写入当前状态的取消行（sign = -1）
+func (r *Repository[DTO, ENTITY]) versionedRows(ent, current *ENTITY, mask []string) ([]string, [][]any, *ENTITY, error) {
+	next := new(ENTITY)
+	*next = *ent
+	nv := reflect.ValueOf(next).Elem()
+	t := nv.Type()
+	if t.Kind() != reflect.Struct {
+		return nil, nil, nil, errEntityNotStructOrPointer
+	}
+
+	if current != nil && len(mask) > 0 {
+		*next = *current
+		masked := make(map[string]bool, len(mask))
+		for _, p := range mask {
+			masked[p] = true
+		}
+		ev := reflect.ValueOf(ent).Elem()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			if masked[sf.Name] || masked[schema.ColumnName(sf)] {
+				nv.Field(i).Set(ev.Field(i))
+			}
+		}
+	}
+
+	var rows [][]any
+	switch r.versioning.Mode {
+	case UpsertReplacing:
+		if r.versioning.VersionColumn != "" {
+			if err := r.stampVersion(nv); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	case UpsertCollapsing:
+		if current != nil {
+			cancel := *current
+			cv := reflect.ValueOf(&cancel).Elem()
+			if err := setSign(cv, r.versioning.SignColumn, -1); err != nil {
+				return nil, nil, nil, err
+			}
+			rows = append(rows, entityValues(cv))
+		}
+		if err := setSign(nv, r.versioning.SignColumn, 1); err != nil {
+			return nil, nil, nil, err
+		}
+	default:
+		return nil, nil, nil, errUpsertModeIsNotVersioned
+	}
+
+	cols := entityColumns(t)
+	if len(cols) == 0 {
+		return nil, nil, nil, errNoColumnsToInsert
+	}
+	rows = append(rows, entityValues(nv))
+	return cols, rows, next, nil
+}
+
+// stampVersion 写入新的版本号
+func (r *Repository[DTO, ENTITY]) stampVersion(v reflect.Value) error {
+	idx := fieldIndexByColumn(v.Type(), r.versioning.VersionColumn)
+	if idx < 0 {
+		return fmt.Errorf("version column %s not found in entity", r.versioning.VersionColumn)
+	}
+
+	f := v.Field(idx)
+	if f.Type() == reflect.TypeOf(time.Time{}) {
+		f.Set(reflect.ValueOf(time.Now()))
+		return nil
+	}
+	if !f.CanUint() && !f.CanInt() {
+		return fmt.Errorf("unsupported version column type %s", f.Type())
+	}
+
+	version, err := r.nextVersion(f.Type())
+	if err != nil {
+		return err
+	}
+
+	// 版本号溢出时会回绕为更小的值，合并后保留的将是旧行
+	if f.CanUint() {
+		if f.OverflowUint(version) {
+			return fmt.Errorf("version %d overflows version column type %s", version, f.Type())
+		}
+		f.SetUint(version)
+		return nil
+	}
+	if version > math.MaxInt64 || f.OverflowInt(int64(version)) {
+		return fmt.Errorf("version %d overflows version column type %s", version, f.Type())
+	}
+	f.SetInt(int64(version))
+	return nil
+}
+
+// nextVersion 生成新的版本号：设置了 Version 时使用其返回值，
+// 否则按版本列的位数取当前时间，64 位为纳秒，32 位为秒，更小的整数列需自行设置 Version
+func (r *Repository[DTO, ENTITY]) nextVersion(t reflect.Type) (uint64, error) {
+	if r.versioning.Version != nil {
+		return r.versioning.Version(), nil
+	}
+
+	now := time.Now()
+	switch {
+	case t.Bits() >= 64:
+		return uint64(now.UnixNano()), nil
+	case t.Bits() >= 32:
+		return uint64(now.Unix()), nil
+	default:
+		return 0, fmt.Errorf("version column type %s is too small for a time based version, set VersioningOptions.Version", t)
+	}
+}
+
+// setSign 写入 CollapsingMergeTree 的标记列
+func setSign(v reflect.Value, column string, sign int64) error {
+	idx := fieldIndexByColumn(v.Type(), column)
+	if idx < 0 {
+		return fmt.Errorf("sign column %s not found in entity", column)
+	}
+	f := v.Field(idx)
+	if !f.CanInt() {
+		return fmt.Errorf("unsupported sign column type %s", f.Type())
+	}
+	f.SetInt(sign)
+	return nil
+}
+
+// entityColumns 返回实体的导出字段对应的列名
+func entityColumns(t reflect.Type) []string {
+	var cols []string
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" || schema.ColumnName(sf) == "-" {
+			continue
+		}
+		cols = append(cols, schema.ColumnName(sf))
+	}
+	return cols
+}
+
+// entityValues 返回与 entityColumns 顺序一致的字段值
+func entityValues(v reflect.Value) []any {
+	t := v.Type()
+	var vals []any
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.PkgPath != "" || schema.ColumnName(sf) == "-" {
+			continue
+		}
+		vals = append(vals, v.Field(i).Interface())
+	}
+	return vals
+}
+
+// fieldIndexByColumn 按列名或字段名查找字段下标，未找到时返回 -1
+func fieldIndexByColumn(t reflect.Type, column string) int {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		if schema.ColumnName(sf) == column || sf.Name == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// findPrimaryKeyColumn 查找主键列：优先 `pk:"true"`，其次列名或字段名为 id
+func findPrimaryKeyColumn(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+
+	pk := ""
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		col := schema.ColumnName(sf)
+		if sf.Tag.Get("pk") == "true" {
+			return col
+		}
+		if pk == "" && (strings.ToLower(col) == "id" || strings.ToLower(sf.Name) == "id") {
+			pk = col
+		}
+	}
+	return pk
+}
@@ -0,0 +1,132 @@
+package clickhouse
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/mapper"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+type versionedAccount struct {
+	ID      uint64 `ch:"id"`
+	Name    string `ch:"name"`
+	Balance int64  `ch:"balance"`
+	Version uint64 `ch:"version"`
+	Sign    int8   `ch:"sign"`
+}
+
+func newVersionedRepo(opts VersioningOptions) *Repository[versionedAccount, versionedAccount] {
+	repo := NewRepository[versionedAccount, versionedAccount](nil, mapper.NewCopierMapper[versionedAccount, versionedAccount](), "accounts", log.NewHelper(log.DefaultLogger))
+	repo.SetVersioning(opts)
+	return repo
+}
+
+func TestRepository_ReadSource(t *testing.T) {
+	repo := newVersionedRepo(VersioningOptions{})
+	assert.Equal(t, "accounts", repo.readSource(false))
+	assert.Equal(t, "accounts FINAL", repo.fromClause(&FilterOptions{Final: true}))
+
+	repo = newVersionedRepo(VersioningOptions{Mode: UpsertReplacing})
+	assert.Equal(t, "accounts FINAL", repo.readSource(false))
+
+	repo = newVersionedRepo(VersioningOptions{Mode: UpsertReplacing, VersionColumn: "version"})
+	assert.Equal(t, "(SELECT id, argMax(_src.name, _src.version) AS name, argMax(_src.balance, _src.version) AS balance, "+
+		"argMax(_src.version, _src.version) AS version, argMax(_src.sign, _src.version) AS sign "+
+		"FROM accounts AS _src GROUP BY id)", repo.readSource(false))
+
+	q, err := repo.ToQuery(&paginationV1.PagingRequest{})
+	require.NoError(t, err)
+	assert.Contains(t, q.Statement, "FROM (SELECT id, argMax(")
+	assert.Contains(t, q.CountStatement, "FROM (SELECT id, argMax(")
+
+	repo = newVersionedRepo(VersioningOptions{Mode: UpsertReplacing, VersionColumn: "version", Dedup: DedupNone})
+	assert.Equal(t, "accounts", repo.readSource(false))
+
+	repo = newVersionedRepo(VersioningOptions{Mode: UpsertCollapsing})
+	assert.Equal(t, "accounts FINAL", repo.readSource(false))
+	assert.Equal(t, DefaultSignColumn, repo.versioning.SignColumn)
+}
+
+func TestRepository_VersionedRows_Replacing(t *testing.T) {
+	repo := newVersionedRepo(VersioningOptions{
+		Mode:          UpsertReplacing,
+		VersionColumn: "version",
+		Version:       func() uint64 { return 42 },
+	})
+
+	cols, rows, next, err := repo.versionedRows(&versionedAccount{ID: 1, Name: "alice", Balance: 10}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "balance", "version", "sign"}, cols)
+	assert.Equal(t, [][]any{{uint64(1), "alice", int64(10), uint64(42), int8(0)}}, rows)
+	assert.Equal(t, uint64(42), next.Version)
+
+	// updateMask 仅覆盖指定字段，其余沿用当前状态
+	current := &versionedAccount{ID: 1, Name: "alice", Balance: 10, Version: 41}
+	_, rows, next, err = repo.versionedRows(&versionedAccount{ID: 1, Balance: 20}, current, []string{"balance"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{uint64(1), "alice", int64(20), uint64(42), int8(0)}}, rows)
+	assert.Equal(t, "alice", next.Name)
+	assert.Equal(t, uint64(41), current.Version)
+
+	repo = newVersionedRepo(VersioningOptions{Mode: UpsertReplacing, VersionColumn: "missing"})
+	_, _, _, err = repo.versionedRows(&versionedAccount{ID: 1}, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestRepository_StampVersion_ColumnWidth(t *testing.T) {
+	type row32 struct {
+		ID      uint64 `ch:"id"`
+		Version uint32 `ch:"version"`
+	}
+	type row16 struct {
+		ID      uint64 `ch:"id"`
+		Version int16  `ch:"version"`
+	}
+
+	repo := newVersionedRepo(VersioningOptions{Mode: UpsertReplacing, VersionColumn: "version"})
+
+	// 32 位版本列默认使用秒数，不会回绕
+	before := uint32(time.Now().Unix())
+	r32 := row32{ID: 1}
+	require.NoError(t, repo.stampVersion(reflect.ValueOf(&r32).Elem()))
+	assert.GreaterOrEqual(t, r32.Version, before)
+
+	r16 := row16{ID: 1}
+	assert.Error(t, repo.stampVersion(reflect.ValueOf(&r16).Elem()))
+
+	// 自定义版本号超出列的取值范围时返回错误
+	repo = newVersionedRepo(VersioningOptions{Mode: UpsertReplacing, VersionColumn: "version", Version: func() uint64 { return 1 << 40 }})
+	assert.Error(t, repo.stampVersion(reflect.ValueOf(&r32).Elem()))
+
+	repo = newVersionedRepo(VersioningOptions{Mode: UpsertReplacing, VersionColumn: "version", Version: func() uint64 { return 7 }})
+	require.NoError(t, repo.stampVersion(reflect.ValueOf(&r16).Elem()))
+	assert.Equal(t, int16(7), r16.Version)
+}
+
+func TestRepository_VersionedRows_Collapsing(t *testing.T) {
+	repo := newVersionedRepo(VersioningOptions{Mode: UpsertCollapsing})
+
+	_, rows, _, err := repo.versionedRows(&versionedAccount{ID: 1, Name: "alice", Balance: 10}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{uint64(1), "alice", int64(10), uint64(0), int8(1)}}, rows)
+
+	// 存在当前状态时先写入取消行
+	current := &versionedAccount{ID: 1, Name: "alice", Balance: 10, Sign: 1}
+	_, rows, next, err := repo.versionedRows(&versionedAccount{ID: 1, Name: "alice", Balance: 15}, current, nil)
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{
+		{uint64(1), "alice", int64(10), uint64(0), int8(-1)},
+		{uint64(1), "alice", int64(15), uint64(0), int8(1)},
+	}, rows)
+	assert.Equal(t, int8(1), next.Sign)
+	assert.Equal(t, int8(1), current.Sign)
+
+	_, _, _, err = newVersionedRepo(VersioningOptions{}).versionedRows(&versionedAccount{}, nil, nil)
+	assert.Error(t, err)
+}
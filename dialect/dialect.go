@@ -0,0 +1,26 @@
+package dialect
+
+import "strings"
+
+// SQL 方言，由排序、全文检索与时间直方图等生成 SQL 片段的包共用
+const (
+	Postgres   = "postgres"
+	MySQL      = "mysql"
+	SQLite     = "sqlite"
+	ClickHouse = "clickhouse"
+)
+
+// Normalize 将驱动或方言名称归一为上面的常量，无法识别的名称返回其小写形式
+func Normalize(name string) string {
+	switch strings.ToLower(name) {
+	case "postgres", "postgresql", "pgx":
+		return Postgres
+	case "mysql", "tidb":
+		return MySQL
+	case "sqlite", "sqlite3":
+		return SQLite
+	case "clickhouse":
+		return ClickHouse
+	}
+	return strings.ToLower(name)
+}
@@ -0,0 +1,19 @@
+package dialect
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"pgx":        Postgres,
+		"PostgreSQL": Postgres,
+		"tidb":       MySQL,
+		"sqlite3":    SQLite,
+		"ClickHouse": ClickHouse,
+		"SQLServer":  "sqlserver",
+	}
+	for name, want := range cases {
+		if got := Normalize(name); got != want {
+			t.Errorf("Normalize(%q): expected %q, got %q", name, want, got)
+		}
+	}
+}
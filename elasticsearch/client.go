@@ -5,19 +5,37 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"net/http"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	elasticsearchV9 "github.com/elastic/go-elasticsearch/v9"
 	esapiV9 "github.com/elastic/go-elasticsearch/v9/esapi"
 
 	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/guard"
+	"github.com/tx7do/go-crud/limits"
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 )
 
 type Client struct {
 	*elasticsearchV9.Client
 	options *elasticsearchV9.Config
 
+	guardOptions guard.Options
+	limitPolicy  *limits.Policy
+
+	enableTrace    bool
+	tracingOptions []tracing.Option
+	tracer         *tracing.Tracer
+
+	metrics *metrics.Metrics
+	slowLog *slowlog.Logger
+
 	log *log.Helper
 }
 
@@ -31,6 +49,16 @@ func NewClient(opts ...Option) (*Client, error) {
 		o(c)
 	}
 
+	if c.enableTrace {
+		c.tracer = tracing.NewTracer("elasticsearch", c.tracingOptions...)
+
+		next := c.options.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		c.options.Transport = &tracedTransport{next: next, tracer: c.tracer}
+	}
+
 	if err := c.createESClient(c.options); err != nil {
 		return nil, err
 	}
@@ -182,8 +210,11 @@ func (c *Client) DeleteIndex(ctx context.Context, indexName string) error {
 }
 
 // DeleteDocument 删除一条数据
-func (c *Client) DeleteDocument(ctx context.Context, indexName, id string) error {
-	_, err := c.Client.Delete(
+func (c *Client) DeleteDocument(ctx context.Context, indexName, id string) (err error) {
+	obs := c.observe("DeleteDocument", indexName)
+	defer func() { obs.End(err) }()
+
+	_, err = c.Client.Delete(
 		indexName, id,
 		c.Client.Delete.WithContext(ctx),
 	)
@@ -195,8 +226,14 @@ func (c *Client) DeleteDocument(ctx context.Context, indexName, id string) error
 }
 
 // InsertDocument 插入一条数据
-func (c *Client) InsertDocument(ctx context.Context, indexName, id string, data interface{}) error {
-	var err error
+func (c *Client) InsertDocument(ctx context.Context, indexName, id string, data interface{}) (err error) {
+	obs := c.observe("InsertDocument", indexName)
+	defer func() {
+		if err == nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
 
 	var dataBytes []byte
 	dataBytes, err = json.Marshal(data)
@@ -225,7 +262,7 @@ func (c *Client) InsertDocument(ctx context.Context, indexName, id string, data
 		return err
 	}
 	defer func(Body io.ReadCloser) {
-		if err = Body.Close(); err != nil {
+		if err := Body.Close(); err != nil {
 			c.log.Errorf("failed to close response body: %v", err)
 		}
 	}(resp.Body)
@@ -246,7 +283,15 @@ func (c *Client) InsertDocument(ctx context.Context, indexName, id string, data
 }
 
 // BatchInsertDocument 批量插入数据
-func (c *Client) BatchInsertDocument(ctx context.Context, indexName string, dataSet []interface{}) error {
+func (c *Client) BatchInsertDocument(ctx context.Context, indexName string, dataSet []interface{}) (err error) {
+	obs := c.observe("BatchInsertDocument", indexName)
+	defer func() {
+		if err == nil {
+			obs.AffectedRows(int64(len(dataSet)))
+		}
+		obs.End(err)
+	}()
+
 	var buf bytes.Buffer
 	for _, data := range dataSet {
 		meta := []byte(`{"index":{}}` + "\n")
@@ -271,7 +316,7 @@ func (c *Client) BatchInsertDocument(ctx context.Context, indexName string, data
 		return err
 	}
 	defer func(Body io.ReadCloser) {
-		if err = Body.Close(); err != nil {
+		if err := Body.Close(); err != nil {
 			c.log.Errorf("failed to close response body: %v", err)
 		}
 	}(resp.Body)
@@ -291,7 +336,15 @@ func (c *Client) BatchInsertDocument(ctx context.Context, indexName string, data
 	return nil
 }
 
-func (c *Client) UpdateDocument(ctx context.Context, indexName string, pk string, doc interface{}) error {
+func (c *Client) UpdateDocument(ctx context.Context, indexName string, pk string, doc interface{}) (err error) {
+	obs := c.observe("UpdateDocument", indexName)
+	defer func() {
+		if err == nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	data, err := json.Marshal(doc)
 	if err != nil {
 		c.log.Errorf("failed to marshal data: %v", err)
@@ -318,7 +371,15 @@ func (c *Client) GetDocument(
 	id string,
 	sourceFields []string,
 	out interface{},
-) error {
+) (err error) {
+	obs := c.observe("GetDocument", indexName)
+	defer func() {
+		if err == nil {
+			obs.ReturnedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	resp, err := c.Client.Get(
 		indexName, id,
 		c.Client.Get.WithContext(ctx),
@@ -358,18 +419,41 @@ func (c *Client) Search(
 	ctx context.Context,
 	indexName string,
 	req *paginationV1.PagingRequest,
-) (*SearchResult, error) {
-	var query string
+) (_ *SearchResult, err error) {
+	ctx, span := c.startSpan(ctx, "Search", indexName, tracing.PagingAttributes(req)...)
+	defer func() { span.End(err) }()
+
+	obs := c.observe("Search", indexName)
+	defer func() { obs.End(err) }()
+	obs.SetPagingRequest(req)
+
+	if req, err = c.limitPolicy.ApplyPaging(indexName, req); err != nil {
+		return nil, err
+	}
+
+	query, sortBy, from, pageSize := searchParams(req)
+	result, err := c.search(ctx, indexName, query, nil, sortBy, from, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetReturnedRows(int64(len(result.Hits.Hits)))
+	obs.ReturnedRows(int64(len(result.Hits.Hits)))
+	return result, nil
+}
+
+// searchParams 将 PagingRequest 转为 search 的查询串、排序与分页参数
+func searchParams(req *paginationV1.PagingRequest) (query string, sortBy map[string]bool, from, pageSize int) {
 	ParseQueryString(req.GetQuery())
 
-	sortBy := make(map[string]bool)
+	sortBy = make(map[string]bool)
 
-	pageSize := req.GetPageSize()
-	if pageSize <= 0 {
-		pageSize = 20 // Default page size
+	size := req.GetPageSize()
+	if size <= 0 {
+		size = 20 // Default page size
 	}
 
-	return c.search(ctx, indexName, query, nil, sortBy, int(req.GetPage()), int(pageSize))
+	return query, sortBy, int(req.GetPage()), int(size)
 }
 
 // search 查询数据
@@ -389,14 +473,7 @@ func (c *Client) search(
 	sortBy map[string]bool,
 	from, pageSize int,
 ) (*SearchResult, error) {
-	var sorts []string
-	for k, v := range sortBy {
-		if v {
-			sorts = append(sorts, k+":asc")
-		} else {
-			sorts = append(sorts, k+":desc")
-		}
-	}
+	sorts := sortClauses(sortBy)
 
 	resp, err := c.Client.Search(
 		c.Client.Search.WithContext(ctx),
@@ -436,3 +513,176 @@ func (c *Client) search(
 
 	return &searchResult, nil
 }
+
+// sortClauses 将排序字段转为 field:asc / field:desc 形式
+func sortClauses(sortBy map[string]bool) []string {
+	var sorts []string
+	for k, v := range sortBy {
+		if v {
+			sorts = append(sorts, k+":asc")
+		} else {
+			sorts = append(sorts, k+":desc")
+		}
+	}
+	return sorts
+}
+
+// SoftDeleteField 软删除使用的字段
+var SoftDeleteField = "deleted_at"
+
+// updateByFilterScript 将 params.doc 中的字段逐一写入文档
+const updateByFilterScript = "for (entry in params.doc.entrySet()) { ctx._source[entry.getKey()] = entry.getValue(); }"
+
+// UpdateByFilter 根据 FilterExpr 批量更新文档（_update_by_query），返回更新的文档数。
+// doc 会被序列化为 JSON 对象；updateMask 不为空时仅更新其中的字段。
+func (c *Client) UpdateByFilter(
+	ctx context.Context,
+	indexName string,
+	filterExpr *paginationV1.FilterExpr,
+	doc interface{},
+	updateMask *fieldmaskpb.FieldMask,
+) (_ int64, err error) {
+	ctx, span := c.startSpan(ctx, "UpdateByFilter", indexName, tracing.FilterAttributes(filterExpr)...)
+	defer func() { span.End(err) }()
+
+	obs := c.observe("UpdateByFilter", indexName)
+	defer func() { obs.End(err) }()
+	obs.SetFilterExpr(filterExpr)
+
+	if err = c.limitPolicy.CheckFilterExpr(indexName, filterExpr); err != nil {
+		return 0, err
+	}
+
+	fields, err := buildUpdateFields(doc, updateMask)
+	if err != nil {
+		c.log.Errorf("failed to build update fields: %v", err)
+		return 0, err
+	}
+	if len(fields) == 0 {
+		return 0, ErrInvalidQuery
+	}
+
+	if err = c.checkGuard(ctx, indexName, filterExpr); err != nil {
+		return 0, err
+	}
+
+	body, err := buildByQueryBody(filterExpr, map[string]any{
+		"source": updateByFilterScript,
+		"lang":   "painless",
+		"params": map[string]any{"doc": fields},
+	})
+	if err != nil {
+		c.log.Errorf("failed to build update by query body: %v", err)
+		return 0, err
+	}
+	obs.SetJSONStatement(body)
+
+	resp, err := c.Client.UpdateByQuery(
+		[]string{indexName},
+		c.Client.UpdateByQuery.WithContext(ctx),
+		c.Client.UpdateByQuery.WithBody(bytes.NewReader(body)),
+		c.Client.UpdateByQuery.WithConflicts("proceed"),
+		c.Client.UpdateByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		c.log.Errorf("failed to update documents by query: %v", err)
+		return 0, err
+	}
+
+	result, err := c.parseByQueryResponse(resp, ErrUpdateByQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	span.SetAffectedRows(result.Updated)
+	obs.AffectedRows(result.Updated)
+	return result.Updated, nil
+}
+
+// DeleteByFilter 根据 FilterExpr 删除文档，返回受影响的文档数。
+// soft 为 true 时通过 _update_by_query 将 deleted_at 置为当前时间，否则使用 _delete_by_query。
+func (c *Client) DeleteByFilter(
+	ctx context.Context,
+	indexName string,
+	filterExpr *paginationV1.FilterExpr,
+	soft bool,
+) (_ int64, err error) {
+	if soft {
+		return c.UpdateByFilter(ctx, indexName, filterExpr,
+			map[string]any{SoftDeleteField: time.Now().UTC().Format(time.RFC3339Nano)}, nil,
+		)
+	}
+
+	ctx, span := c.startSpan(ctx, "DeleteByFilter", indexName, tracing.FilterAttributes(filterExpr)...)
+	defer func() { span.End(err) }()
+
+	obs := c.observe("DeleteByFilter", indexName)
+	defer func() { obs.End(err) }()
+	obs.SetFilterExpr(filterExpr)
+
+	if err = c.limitPolicy.CheckFilterExpr(indexName, filterExpr); err != nil {
+		return 0, err
+	}
+
+	body, err := buildByQueryBody(filterExpr, nil)
+	if err != nil {
+		c.log.Errorf("failed to build delete by query body: %v", err)
+		return 0, err
+	}
+	obs.SetJSONStatement(body)
+
+	if err = c.checkGuard(ctx, indexName, filterExpr); err != nil {
+		return 0, err
+	}
+
+	resp, err := c.Client.DeleteByQuery(
+		[]string{indexName},
+		bytes.NewReader(body),
+		c.Client.DeleteByQuery.WithContext(ctx),
+		c.Client.DeleteByQuery.WithConflicts("proceed"),
+		c.Client.DeleteByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		c.log.Errorf("failed to delete documents by query: %v", err)
+		return 0, err
+	}
+
+	result, err := c.parseByQueryResponse(resp, ErrDeleteByQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	span.SetAffectedRows(result.Deleted)
+	obs.AffectedRows(result.Deleted)
+	return result.Deleted, nil
+}
+
+// parseByQueryResponse 解析 by-query 接口的响应，失败时返回 failedErr
+func (c *Client) parseByQueryResponse(resp *esapiV9.Response, failedErr error) (*ByQueryResult, error) {
+	defer func(Body io.ReadCloser) {
+		if err := Body.Close(); err != nil {
+			c.log.Errorf("failed to close response body: %v", err)
+		}
+	}(resp.Body)
+
+	if resp.IsError() {
+		errResp, err := ParseErrorMessage(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		c.log.Errorf("by query request failed: %s", errResp.Error.Reason)
+		return nil, failedErr
+	}
+
+	var result ByQueryResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		c.log.Errorf("failed to decode by query response: %v", err)
+		return nil, ErrUnmarshalResponse
+	}
+	if len(result.Failures) > 0 {
+		c.log.Errorf("by query request has %d failures: %v", len(result.Failures), result.Failures)
+		return &result, failedErr
+	}
+
+	return &result, nil
+}
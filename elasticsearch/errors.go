@@ -35,4 +35,8 @@ var (
 	ErrGetDocument = errors.InternalServer("GET_DOCUMENT_FAILED", "failed to get document")
 
 	ErrSearchDocument = errors.InternalServer("SEARCH_DOCUMENT_FAILED", "failed to search document")
+
+	ErrUpdateByQuery = errors.InternalServer("UPDATE_BY_QUERY_FAILED", "failed to update documents by query")
+
+	ErrDeleteByQuery = errors.InternalServer("DELETE_BY_QUERY_FAILED", "failed to delete documents by query")
 )
@@ -0,0 +1,91 @@
+package elasticsearch
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	esapiV9 "github.com/elastic/go-elasticsearch/v9/esapi"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/explain"
+)
+
+// ToQuery 返回 Search 针对 req 发送的 _search 请求（不访问 Elasticsearch）。
+// 总数随 _search 响应的 hits.total 一并返回，因此 CountStatement 为空。
+func (c *Client) ToQuery(indexName string, req *paginationV1.PagingRequest) (*explain.Query, error) {
+	if indexName == "" || req == nil {
+		return nil, ErrInvalidQuery
+	}
+
+	req, err := c.limitPolicy.ApplyPaging(indexName, req)
+	if err != nil {
+		return nil, err
+	}
+
+	query, sortBy, from, pageSize := searchParams(req)
+
+	params := url.Values{}
+	params.Set("from", strconv.Itoa(from))
+	params.Set("size", strconv.Itoa(pageSize))
+	if sorts := sortClauses(sortBy); len(sorts) > 0 {
+		params.Set("sort", strings.Join(sorts, ","))
+	}
+	if query != "" {
+		params.Set("q", query)
+	}
+
+	return &explain.Query{
+		Statement: "GET /" + url.PathEscape(indexName) + "/_search?" + params.Encode(),
+	}, nil
+}
+
+// Explain 在 ToQuery 的基础上通过 _validate/query?explain 校验并解释查询，不执行搜索本身
+func (c *Client) Explain(ctx context.Context, indexName string, req *paginationV1.PagingRequest) (*explain.Query, error) {
+	q, err := c.ToQuery(indexName, req)
+	if err != nil {
+		return nil, err
+	}
+
+	query, _, _, _ := searchParams(req)
+
+	opts := []func(*esapiV9.IndicesValidateQueryRequest){
+		c.Client.Indices.ValidateQuery.WithContext(ctx),
+		c.Client.Indices.ValidateQuery.WithIndex(indexName),
+		c.Client.Indices.ValidateQuery.WithExplain(true),
+	}
+	if query != "" {
+		opts = append(opts, c.Client.Indices.ValidateQuery.WithQuery(query))
+	}
+
+	resp, err := c.Client.Indices.ValidateQuery(opts...)
+	if err != nil {
+		c.log.Errorf("failed to validate query: %v", err)
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		if err := Body.Close(); err != nil {
+			c.log.Errorf("failed to close response body: %v", err)
+		}
+	}(resp.Body)
+
+	if resp.IsError() {
+		errResp, err := ParseErrorMessage(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		c.log.Errorf("validate query failed: %s", errResp.Error.Reason)
+		return nil, ErrRequestFailed
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.log.Errorf("failed to read validate query response: %v", err)
+		return nil, err
+	}
+	q.Plan = string(body)
+
+	return q, nil
+}
@@ -0,0 +1,30 @@
+package elasticsearch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/trans"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+func TestClient_ToQuery(t *testing.T) {
+	c := &Client{}
+
+	q, err := c.ToQuery("users", &paginationV1.PagingRequest{
+		Page:     trans.Ptr(uint32(2)),
+		PageSize: trans.Ptr(uint32(15)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "GET /users/_search?from=2&size=15", q.Statement)
+	assert.Empty(t, q.CountStatement)
+
+	q, err = c.ToQuery("users", &paginationV1.PagingRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "GET /users/_search?from=0&size=20", q.Statement)
+
+	_, err = c.ToQuery("", &paginationV1.PagingRequest{})
+	assert.Error(t, err)
+}
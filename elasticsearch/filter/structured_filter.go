@@ -0,0 +1,243 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	"github.com/tx7do/go-utils/stringcase"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+// StructuredFilter 将 FilterExpr 转为 Elasticsearch Query DSL（bool 查询）
+type StructuredFilter struct {
+	codec encoding.Codec
+}
+
+func NewStructuredFilter() *StructuredFilter {
+	return &StructuredFilter{
+		codec: encoding.GetCodec("json"),
+	}
+}
+
+// BuildQuery 将 expr 转为 Query DSL，expr 为空时返回 nil。
+// AND 组转为 bool.filter，OR 组转为 bool.should + minimum_should_match=1。
+func (sf StructuredFilter) BuildQuery(expr *paginationV1.FilterExpr) (map[string]any, error) {
+	if expr == nil {
+		return nil, nil
+	}
+
+	var build func(e *paginationV1.FilterExpr) (map[string]any, error)
+	build = func(e *paginationV1.FilterExpr) (map[string]any, error) {
+		if e == nil || e.GetType() == paginationV1.ExprType_EXPR_TYPE_UNSPECIFIED {
+			return nil, nil
+		}
+
+		var parts []any
+		for _, cond := range e.GetConditions() {
+			q, err := sf.buildCond(cond)
+			if err != nil {
+				return nil, err
+			}
+			if q != nil {
+				parts = append(parts, q)
+			}
+		}
+		for _, g := range e.GetGroups() {
+			q, err := build(g)
+			if err != nil {
+				return nil, err
+			}
+			if q != nil {
+				parts = append(parts, q)
+			}
+		}
+
+		if len(parts) == 0 {
+			return nil, nil
+		}
+		if len(parts) == 1 {
+			return parts[0].(map[string]any), nil
+		}
+
+		switch e.GetType() {
+		case paginationV1.ExprType_AND:
+			return boolQuery("filter", parts), nil
+		case paginationV1.ExprType_OR:
+			q := boolQuery("should", parts)
+			q["bool"].(map[string]any)["minimum_should_match"] = 1
+			return q, nil
+		default:
+			return nil, nil
+		}
+	}
+
+	return build(expr)
+}
+
+// buildCond 将单个 Condition 转为查询子句，字段为空时返回 nil
+func (sf StructuredFilter) buildCond(cond *paginationV1.Condition) (map[string]any, error) {
+	if cond == nil {
+		return nil, nil
+	}
+	field := makeKey(cond.GetField())
+	if field == "" {
+		return nil, nil
+	}
+
+	value := cond.GetValue()
+
+	switch cond.GetOp() {
+	case paginationV1.Operator_EQ, paginationV1.Operator_EXACT:
+		return termQuery(field, value, false), nil
+	case paginationV1.Operator_IEXACT:
+		return termQuery(field, value, true), nil
+	case paginationV1.Operator_NEQ:
+		return mustNot(termQuery(field, value, false)), nil
+
+	case paginationV1.Operator_GT:
+		return rangeQuery(field, map[string]any{"gt": value}), nil
+	case paginationV1.Operator_GTE:
+		return rangeQuery(field, map[string]any{"gte": value}), nil
+	case paginationV1.Operator_LT:
+		return rangeQuery(field, map[string]any{"lt": value}), nil
+	case paginationV1.Operator_LTE:
+		return rangeQuery(field, map[string]any{"lte": value}), nil
+	case paginationV1.Operator_BETWEEN:
+		values := sf.parseValues(value, cond.GetValues())
+		if len(values) != 2 {
+			return nil, fmt.Errorf("between on field %s requires 2 values", field)
+		}
+		return rangeQuery(field, map[string]any{"gte": values[0], "lte": values[1]}), nil
+
+	case paginationV1.Operator_IN:
+		return map[string]any{"terms": map[string]any{field: sf.parseValues(value, cond.GetValues())}}, nil
+	case paginationV1.Operator_NIN:
+		return mustNot(map[string]any{"terms": map[string]any{field: sf.parseValues(value, cond.GetValues())}}), nil
+
+	case paginationV1.Operator_IS_NULL:
+		return mustNot(map[string]any{"exists": map[string]any{"field": field}}), nil
+	case paginationV1.Operator_IS_NOT_NULL, paginationV1.Operator_EXISTS:
+		return map[string]any{"exists": map[string]any{"field": field}}, nil
+
+	case paginationV1.Operator_LIKE:
+		return wildcardQuery(field, likeToWildcard(value), false), nil
+	case paginationV1.Operator_ILIKE:
+		return wildcardQuery(field, likeToWildcard(value), true), nil
+	case paginationV1.Operator_NOT_LIKE:
+		return mustNot(wildcardQuery(field, likeToWildcard(value), false)), nil
+	case paginationV1.Operator_CONTAINS:
+		return wildcardQuery(field, "*"+escapeWildcard(value)+"*", false), nil
+	case paginationV1.Operator_ICONTAINS:
+		return wildcardQuery(field, "*"+escapeWildcard(value)+"*", true), nil
+	case paginationV1.Operator_STARTS_WITH:
+		return wildcardQuery(field, escapeWildcard(value)+"*", false), nil
+	case paginationV1.Operator_ISTARTS_WITH:
+		return wildcardQuery(field, escapeWildcard(value)+"*", true), nil
+	case paginationV1.Operator_ENDS_WITH:
+		return wildcardQuery(field, "*"+escapeWildcard(value), false), nil
+	case paginationV1.Operator_IENDS_WITH:
+		return wildcardQuery(field, "*"+escapeWildcard(value), true), nil
+
+	case paginationV1.Operator_REGEXP:
+		return map[string]any{"regexp": map[string]any{field: map[string]any{"value": value}}}, nil
+	case paginationV1.Operator_IREGEXP:
+		return map[string]any{"regexp": map[string]any{field: map[string]any{"value": value, "case_insensitive": true}}}, nil
+
+	case paginationV1.Operator_ARRAY_CONTAINS:
+		return termQuery(field, value, false), nil
+	case paginationV1.Operator_SEARCH:
+		return map[string]any{"match": map[string]any{field: value}}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported operator %s on field %s", cond.GetOp(), field)
+	}
+}
+
+// parseValues 解析 values 或 value 中的 JSON 数组/逗号分隔列表
+func (sf StructuredFilter) parseValues(value string, values []string) []any {
+	if len(values) > 0 {
+		out := make([]any, 0, len(values))
+		for _, v := range values {
+			out = append(out, v)
+		}
+		return out
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return []any{}
+	}
+
+	var arr []any
+	if err := sf.codec.Unmarshal([]byte(value), &arr); err == nil {
+		return arr
+	}
+
+	parts := strings.Split(value, ",")
+	out := make([]any, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// makeKey 将字段转为 snake_case，支持点号分隔的嵌套字段
+func makeKey(field string) string {
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return ""
+	}
+	parts := strings.Split(field, ".")
+	for i, p := range parts {
+		parts[i] = stringcase.ToSnakeCase(strings.TrimSpace(p))
+	}
+	return strings.Join(parts, ".")
+}
+
+func boolQuery(occur string, clauses []any) map[string]any {
+	return map[string]any{"bool": map[string]any{occur: clauses}}
+}
+
+func mustNot(q map[string]any) map[string]any {
+	return boolQuery("must_not", []any{q})
+}
+
+func termQuery(field, value string, caseInsensitive bool) map[string]any {
+	if caseInsensitive {
+		return map[string]any{"term": map[string]any{field: map[string]any{"value": value, "case_insensitive": true}}}
+	}
+	return map[string]any{"term": map[string]any{field: value}}
+}
+
+func rangeQuery(field string, bounds map[string]any) map[string]any {
+	return map[string]any{"range": map[string]any{field: bounds}}
+}
+
+func wildcardQuery(field, pattern string, caseInsensitive bool) map[string]any {
+	q := map[string]any{"value": pattern}
+	if caseInsensitive {
+		q["case_insensitive"] = true
+	}
+	return map[string]any{"wildcard": map[string]any{field: q}}
+}
+
+// escapeWildcard 转义 wildcard 查询中的特殊字符
+func escapeWildcard(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "*", `\*`)
+	s = strings.ReplaceAll(s, "?", `\?`)
+	return s
+}
+
+// likeToWildcard 将 SQL LIKE 模式（% 与 _）转为 wildcard 模式
+func likeToWildcard(s string) string {
+	s = escapeWildcard(s)
+	s = strings.ReplaceAll(s, "%", "*")
+	s = strings.ReplaceAll(s, "_", "?")
+	return s
+}
@@ -0,0 +1,85 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+func TestStructuredFilter_BuildQuery(t *testing.T) {
+	sf := NewStructuredFilter()
+
+	q, err := sf.BuildQuery(nil)
+	require.NoError(t, err)
+	assert.Nil(t, q)
+
+	q, err = sf.BuildQuery(&paginationV1.FilterExpr{
+		Type: paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{
+			{Field: "userName", Op: paginationV1.Operator_EQ, Value: proto.String("alice")},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"term": map[string]any{"user_name": "alice"}}, q)
+
+	q, err = sf.BuildQuery(&paginationV1.FilterExpr{
+		Type: paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{
+			{Field: "age", Op: paginationV1.Operator_BETWEEN, Values: []string{"18", "30"}},
+			{Field: "status", Op: paginationV1.Operator_NIN, Value: proto.String(`["deleted","banned"]`)},
+		},
+		Groups: []*paginationV1.FilterExpr{
+			{
+				Type: paginationV1.ExprType_OR,
+				Conditions: []*paginationV1.Condition{
+					{Field: "name", Op: paginationV1.Operator_LIKE, Value: proto.String("a%_")},
+					{Field: "deletedAt", Op: paginationV1.Operator_IS_NULL},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"bool": map[string]any{
+			"filter": []any{
+				map[string]any{"range": map[string]any{"age": map[string]any{"gte": "18", "lte": "30"}}},
+				map[string]any{"bool": map[string]any{"must_not": []any{
+					map[string]any{"terms": map[string]any{"status": []any{"deleted", "banned"}}},
+				}}},
+				map[string]any{"bool": map[string]any{
+					"should": []any{
+						map[string]any{"wildcard": map[string]any{"name": map[string]any{"value": "a*?"}}},
+						map[string]any{"bool": map[string]any{"must_not": []any{
+							map[string]any{"exists": map[string]any{"field": "deleted_at"}},
+						}}},
+					},
+					"minimum_should_match": 1,
+				}},
+			},
+		},
+	}, q)
+}
+
+func TestStructuredFilter_Errors(t *testing.T) {
+	sf := NewStructuredFilter()
+
+	_, err := sf.BuildQuery(&paginationV1.FilterExpr{
+		Type: paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{
+			{Field: "age", Op: paginationV1.Operator_BETWEEN, Value: proto.String("18")},
+		},
+	})
+	assert.Error(t, err)
+
+	_, err = sf.BuildQuery(&paginationV1.FilterExpr{
+		Type: paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{
+			{Field: "tags", Op: paginationV1.Operator_JSON_CONTAINS, Value: proto.String("x")},
+		},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/guard"
+)
+
+// checkGuard 校验 UpdateByFilter/DeleteByFilter 的过滤条件；
+// 配置了 MaxAffectedRows 时先通过 _count 计数，超出上限则拒绝执行。
+func (c *Client) checkGuard(ctx context.Context, indexName string, filterExpr *paginationV1.FilterExpr) error {
+	if err := c.guardOptions.CheckFilter(guard.HasFilterExpr(filterExpr)); err != nil {
+		return err
+	}
+	if c.guardOptions.MaxAffectedRows <= 0 {
+		return nil
+	}
+
+	count, err := c.countByFilter(ctx, indexName, filterExpr)
+	if err != nil {
+		return err
+	}
+	if err = c.guardOptions.CheckAffected(count); err != nil {
+		c.log.Errorf("guard rejected by query request on %s: %v", indexName, err)
+		return err
+	}
+	return nil
+}
+
+// countByFilter 统计匹配 FilterExpr 的文档数
+func (c *Client) countByFilter(ctx context.Context, indexName string, filterExpr *paginationV1.FilterExpr) (int64, error) {
+	body, err := buildByQueryBody(filterExpr, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.Client.Count(
+		c.Client.Count.WithContext(ctx),
+		c.Client.Count.WithIndex(indexName),
+		c.Client.Count.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		c.log.Errorf("failed to count documents: %v", err)
+		return 0, err
+	}
+	defer func(Body io.ReadCloser) {
+		if err := Body.Close(); err != nil {
+			c.log.Errorf("failed to close response body: %v", err)
+		}
+	}(resp.Body)
+
+	if resp.IsError() {
+		errResp, err := ParseErrorMessage(resp.Body)
+		if err != nil {
+			return 0, err
+		}
+		c.log.Errorf("count request failed: %s", errResp.Error.Reason)
+		return 0, ErrRequestFailed
+	}
+
+	var result struct {
+		Count int64 `json:"count"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		c.log.Errorf("failed to decode count response: %v", err)
+		return 0, ErrUnmarshalResponse
+	}
+	return result.Count, nil
+}
@@ -0,0 +1,11 @@
+package elasticsearch
+
+import (
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+)
+
+// observe 开始观测一次文档操作，entity 为索引名
+func (c *Client) observe(method, indexName string) *slowlog.Observation {
+	return slowlog.Observe(c.metrics, c.slowLog, metrics.BackendElasticsearch, indexName, method)
+}
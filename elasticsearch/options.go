@@ -5,6 +5,14 @@ import (
 	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tx7do/go-crud/guard"
+	"github.com/tx7do/go-crud/limits"
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 )
 
 type Option func(o *Client)
@@ -39,6 +47,18 @@ func WithEnableMetrics(enable bool) Option {
 	}
 }
 
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *Client) {
+		o.metrics = m
+	}
+}
+
+func WithSlowLog(l *slowlog.Logger) Option {
+	return func(o *Client) {
+		o.slowLog = l
+	}
+}
+
 func WithEnableDebugLogger(enable bool) Option {
 	return func(o *Client) {
 		o.options.EnableDebugLogger = enable
@@ -118,3 +138,47 @@ func WithLogger(logger log.Logger) Option {
 		o.log = log.NewHelper(log.With(logger, "module", "elasticsearch-client"))
 	}
 }
+
+// WithGuardOptions 设置 UpdateByFilter/DeleteByFilter 的安全保护
+func WithGuardOptions(opts guard.Options) Option {
+	return func(o *Client) {
+		o.guardOptions = opts
+	}
+}
+
+// WithLimitPolicy 设置 Search/UpdateByFilter/DeleteByFilter 的请求复杂度限制，Overrides 的键为索引名
+func WithLimitPolicy(p *limits.Policy) Option {
+	return func(o *Client) {
+		o.limitPolicy = p
+	}
+}
+
+func WithEnableTrace(enable bool) Option {
+	return func(o *Client) {
+		o.enableTrace = enable
+	}
+}
+
+func WithTracingOptions(opts ...tracing.Option) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, opts...)
+	}
+}
+
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithTracerProvider(provider))
+	}
+}
+
+func WithTracingAttributes(attrs ...attribute.KeyValue) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithAttributes(attrs...))
+	}
+}
+
+func WithTracingDBSystem(name string) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithDBSystem(name))
+	}
+}
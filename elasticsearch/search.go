@@ -0,0 +1,191 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/elasticsearch/filter"
+	"github.com/tx7do/go-crud/search"
+	"github.com/tx7do/go-crud/tracing"
+)
+
+// FullTextSearch 全文检索：使用 multi_match 按字段权重匹配 sreq.Query，req 的 FilterExpr 作为过滤条件，
+// 支持 page/page_size、offset/limit 分页与 order_by 排序；开启高亮时片段随命中项的 Highlight 返回。
+func (c *Client) FullTextSearch(
+	ctx context.Context,
+	indexName string,
+	req *paginationV1.PagingRequest,
+	sreq *search.Request,
+) (_ *SearchResult, err error) {
+	ctx, span := c.startSpan(ctx, "FullTextSearch", indexName, tracing.PagingAttributes(req)...)
+	defer func() { span.End(err) }()
+
+	obs := c.observe("FullTextSearch", indexName)
+	defer func() { obs.End(err) }()
+	obs.SetPagingRequest(req)
+
+	if indexName == "" || req == nil {
+		return nil, ErrInvalidQuery
+	}
+	if req, err = c.limitPolicy.ApplyPaging(indexName, req); err != nil {
+		return nil, err
+	}
+
+	body, err := buildFullTextSearchBody(req, sreq)
+	if err != nil {
+		c.log.Errorf("failed to build full-text search body: %v", err)
+		return nil, err
+	}
+	obs.SetJSONStatement(body)
+
+	resp, err := c.Client.Search(
+		c.Client.Search.WithContext(ctx),
+		c.Client.Search.WithIndex(indexName),
+		c.Client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		c.log.Errorf("failed to search documents: %v", err)
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		if err := Body.Close(); err != nil {
+			c.log.Errorf("failed to close response body: %v", err)
+		}
+	}(resp.Body)
+
+	if resp.IsError() {
+		var errResp *ErrorResponse
+		if errResp, err = ParseErrorMessage(resp.Body); err != nil {
+			return nil, err
+		}
+
+		c.log.Errorf("full-text search failed: %s", errResp.Error.Reason)
+
+		return nil, ErrSearchDocument
+	}
+
+	var result SearchResult
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		c.log.Errorf("failed to decode search result: %v", err)
+		return nil, err
+	}
+
+	span.SetReturnedRows(int64(len(result.Hits.Hits)))
+	obs.ReturnedRows(int64(len(result.Hits.Hits)))
+	return &result, nil
+}
+
+// DecodeSearchHits 将 SearchResult 的 _source 解码为 T，并附带相关度分数与高亮片段
+func DecodeSearchHits[T any](result *SearchResult) (*search.Result[T], error) {
+	if result == nil {
+		return &search.Result[T]{}, nil
+	}
+
+	hits := make([]*search.Hit[T], 0, len(result.Hits.Hits))
+	for _, h := range result.Hits.Hits {
+		item := new(T)
+		if len(h.Source) > 0 {
+			if err := json.Unmarshal(h.Source, item); err != nil {
+				return nil, err
+			}
+		}
+		hits = append(hits, &search.Hit[T]{
+			Item:       item,
+			Score:      h.Score,
+			Highlights: h.Highlight,
+		})
+	}
+
+	return &search.Result[T]{Hits: hits, Total: uint64(result.Hits.Total.Value)}, nil
+}
+
+// buildFullTextSearchBody 构建 FullTextSearch 的 _search 请求体
+func buildFullTextSearchBody(req *paginationV1.PagingRequest, sreq *search.Request) ([]byte, error) {
+	if err := sreq.Validate(); err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(sreq.Fields))
+	for _, f := range sreq.Fields {
+		if f.Weight > 0 && f.Weight != 1 {
+			fields = append(fields, f.Name+"^"+strconv.FormatFloat(f.Weight, 'g', -1, 64))
+		} else {
+			fields = append(fields, f.Name)
+		}
+	}
+	match := map[string]any{
+		"query":  sreq.Query,
+		"fields": fields,
+		"type":   "best_fields",
+	}
+	if sreq.Language != "" {
+		match["analyzer"] = sreq.Language
+	}
+
+	boolQuery := map[string]any{"must": []any{map[string]any{"multi_match": match}}}
+	filterQuery, err := filter.NewStructuredFilter().BuildQuery(req.GetFilterExpr())
+	if err != nil {
+		return nil, err
+	}
+	if filterQuery != nil {
+		boolQuery["filter"] = []any{filterQuery}
+	}
+
+	body := map[string]any{
+		"query":            map[string]any{"bool": boolQuery},
+		"track_total_hits": true,
+	}
+
+	if !req.GetNoPaging() {
+		switch {
+		case req.Page != nil && req.PageSize != nil:
+			page := max(req.GetPage(), 1)
+			body["from"] = int(page-1) * int(req.GetPageSize())
+			body["size"] = req.GetPageSize()
+		case req.Offset != nil && req.Limit != nil:
+			body["from"] = req.GetOffset()
+			body["size"] = req.GetLimit()
+		}
+	}
+
+	var sorts []any
+	if sreq.SortByRelevance {
+		sorts = append(sorts, "_score")
+	}
+	for _, o := range req.GetOrderBy() {
+		if field, desc := strings.CutPrefix(o, "-"); desc {
+			sorts = append(sorts, map[string]any{field: "desc"})
+		} else {
+			sorts = append(sorts, map[string]any{o: "asc"})
+		}
+	}
+	if len(sorts) > 0 {
+		body["sort"] = sorts
+	}
+
+	if paths := req.GetFieldMask().GetPaths(); len(paths) > 0 {
+		body["_source"] = paths
+	}
+
+	if h := sreq.HighlightOptions(); h != nil {
+		highlightFields := make(map[string]any, len(sreq.Fields))
+		for _, f := range sreq.Fields {
+			highlightFields[f.Name] = map[string]any{
+				"fragment_size":       h.FragmentSize,
+				"number_of_fragments": h.MaxFragments,
+			}
+		}
+		body["highlight"] = map[string]any{
+			"pre_tags":  []string{h.PreTag},
+			"post_tags": []string{h.PostTag},
+			"fields":    highlightFields,
+		}
+	}
+
+	return json.Marshal(body)
+}
@@ -0,0 +1,74 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/trans"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/search"
+)
+
+func TestBuildFullTextSearchBody(t *testing.T) {
+	body, err := buildFullTextSearchBody(&paginationV1.PagingRequest{
+		Page:     trans.Ptr(uint32(3)),
+		PageSize: trans.Ptr(uint32(10)),
+		OrderBy:  []string{"-created_at"},
+		FilterExpr: &paginationV1.FilterExpr{
+			Type: paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{
+				{Field: "status", Op: paginationV1.Operator_EQ, Value: trans.Ptr("published")},
+			},
+		},
+	}, &search.Request{
+		Query:           "go crud",
+		Fields:          []search.Field{{Name: "title", Weight: 3}, {Name: "body"}},
+		Language:        "ik_smart",
+		SortByRelevance: true,
+		Highlight:       &search.Highlight{MaxFragments: 2},
+	})
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(body, &got))
+
+	match := got["query"].(map[string]any)["bool"].(map[string]any)["must"].([]any)[0].(map[string]any)["multi_match"].(map[string]any)
+	assert.Equal(t, []any{"title^3", "body"}, match["fields"])
+	assert.Equal(t, "ik_smart", match["analyzer"])
+	assert.NotNil(t, got["query"].(map[string]any)["bool"].(map[string]any)["filter"])
+
+	assert.EqualValues(t, 20, got["from"])
+	assert.EqualValues(t, 10, got["size"])
+	assert.Equal(t, []any{"_score", map[string]any{"created_at": "desc"}}, got["sort"])
+
+	highlight := got["highlight"].(map[string]any)
+	assert.Equal(t, []any{search.DefaultPreTag}, highlight["pre_tags"])
+	assert.EqualValues(t, 2, highlight["fields"].(map[string]any)["title"].(map[string]any)["number_of_fragments"])
+
+	_, err = buildFullTextSearchBody(&paginationV1.PagingRequest{}, &search.Request{Query: "go"})
+	assert.ErrorIs(t, err, search.ErrNoFields)
+}
+
+func TestDecodeSearchHits(t *testing.T) {
+	var result SearchResult
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"hits": {
+			"total": {"value": 7},
+			"hits": [{"_id": "1", "_score": 1.5, "_source": {"title": "go-crud"}, "highlight": {"title": ["go-<em>crud</em>"]}}]
+		}
+	}`), &result))
+
+	type doc struct {
+		Title string `json:"title"`
+	}
+	got, err := DecodeSearchHits[doc](&result)
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, got.Total)
+	require.Len(t, got.Hits, 1)
+	assert.Equal(t, "go-crud", got.Hits[0].Item.Title)
+	assert.Equal(t, 1.5, got.Hits[0].Score)
+	assert.Equal(t, []string{"go-<em>crud</em>"}, got.Hits[0].Highlights["title"])
+}
@@ -0,0 +1,78 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/tx7do/go-crud/tracing"
+)
+
+// tracedTransport 包装 http.RoundTripper，为每个发往 Elasticsearch 的请求创建 span。
+// 请求体为 JSON 时脱敏后记录为 db.query.text（_bulk 等 NDJSON 请求体不记录）。
+type tracedTransport struct {
+	next   http.RoundTripper
+	tracer *tracing.Tracer
+}
+
+func (t *tracedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation, index := parseRequestPath(req.Method, req.URL.Path)
+
+	ctx, span := t.tracer.Start(req.Context(), operation, index,
+		attribute.String("http.request.method", req.Method),
+	)
+
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			if data, err := io.ReadAll(body); err == nil {
+				span.SetJSONStatement(data)
+			}
+			_ = body.Close()
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		// HEAD 请求（如 IndexExists）的 404 属于正常结果
+		if !(req.Method == http.MethodHead && resp.StatusCode == http.StatusNotFound) {
+			span.End(fmt.Errorf("elasticsearch responded with status %d", resp.StatusCode))
+			return resp, err
+		}
+	}
+	span.End(err)
+
+	return resp, err
+}
+
+// parseRequestPath 从请求路径解析操作与索引名：
+// 以 "_" 开头的首个路径段作为操作（如 _search -> search），否则使用小写的 HTTP 方法；
+// 首个不以 "_" 开头的路径段作为索引名。
+func parseRequestPath(method, path string) (operation, index string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range segments {
+		if seg == "" {
+			continue
+		}
+		if strings.HasPrefix(seg, "_") {
+			operation = strings.TrimPrefix(seg, "_")
+			break
+		}
+		if i == 0 {
+			index = seg
+		}
+	}
+	if operation == "" {
+		operation = strings.ToLower(method)
+	}
+	return operation, index
+}
+
+// startSpan 开始一次客户端操作的 span，HTTP 请求级的子 span 由 tracedTransport 创建
+func (c *Client) startSpan(ctx context.Context, operation, indexName string, attrs ...attribute.KeyValue) (context.Context, *tracing.Span) {
+	return c.tracer.Start(ctx, operation, indexName, attrs...)
+}
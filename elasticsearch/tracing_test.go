@@ -0,0 +1,28 @@
+package elasticsearch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRequestPath(t *testing.T) {
+	cases := []struct {
+		method    string
+		path      string
+		operation string
+		index     string
+	}{
+		{"POST", "/users/_search", "search", "users"},
+		{"POST", "/users/_update_by_query", "update_by_query", "users"},
+		{"PUT", "/users/_create/1", "create", "users"},
+		{"POST", "/_bulk", "bulk", ""},
+		{"HEAD", "/users", "head", "users"},
+		{"GET", "/", "get", ""},
+	}
+	for _, c := range cases {
+		op, index := parseRequestPath(c.method, c.path)
+		assert.Equal(t, c.operation, op, c.path)
+		assert.Equal(t, c.index, index, c.path)
+	}
+}
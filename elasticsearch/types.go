@@ -33,6 +33,21 @@ type SearchResult struct {
 			ID     string          `json:"_id"`
 			Score  float64         `json:"_score"`
 			Source json.RawMessage `json:"_source"`
+
+			// Highlight 请求开启高亮时返回的字段片段
+			Highlight map[string][]string `json:"highlight,omitempty"`
 		} `json:"hits"`
 	} `json:"hits"`
 }
+
+// ByQueryResult 表示 _update_by_query / _delete_by_query 的响应
+type ByQueryResult struct {
+	Took             int   `json:"took"`
+	TimedOut         bool  `json:"timed_out"`
+	Total            int64 `json:"total"`
+	Updated          int64 `json:"updated"`
+	Deleted          int64 `json:"deleted"`
+	VersionConflicts int64 `json:"version_conflicts"`
+	Noops            int64 `json:"noops"`
+	Failures         []any `json:"failures"`
+}
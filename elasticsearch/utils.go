@@ -8,6 +8,12 @@ import (
 	"github.com/go-kratos/kratos/v2/encoding"
 	_ "github.com/go-kratos/kratos/v2/encoding/json"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/tx7do/go-utils/stringcase"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+
+	"github.com/tx7do/go-crud/elasticsearch/filter"
 )
 
 // ParseErrorMessage 解析 Elasticsearch 错误消息
@@ -109,3 +115,64 @@ func MakeQueryString(andQuery, orQuery string) string {
 		return strings.Join(a, " AND ") + " AND " + strings.Join(o, " OR ")
 	}
 }
+
+// buildByQueryBody 构建 _update_by_query / _delete_by_query 的请求体，filterExpr 为空时匹配全部文档
+func buildByQueryBody(filterExpr *paginationV1.FilterExpr, script map[string]any) ([]byte, error) {
+	q, err := filter.NewStructuredFilter().BuildQuery(filterExpr)
+	if err != nil {
+		return nil, err
+	}
+	if q == nil {
+		q = map[string]any{"match_all": map[string]any{}}
+	}
+
+	body := map[string]any{"query": q}
+	if script != nil {
+		body["script"] = script
+	}
+	return json.Marshal(body)
+}
+
+// buildUpdateFields 将 doc 序列化为字段映射；updateMask 不为空时仅保留其中的字段，否则去掉空值字段
+func buildUpdateFields(doc interface{}, updateMask *fieldmaskpb.FieldMask) (map[string]any, error) {
+	if doc == nil {
+		return nil, nil
+	}
+
+	var fields map[string]any
+	if m, ok := doc.(map[string]any); ok {
+		fields = m
+	} else {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+	}
+
+	if updateMask == nil || len(updateMask.GetPaths()) == 0 {
+		for k, v := range fields {
+			if v == nil {
+				delete(fields, k)
+			}
+		}
+		return fields, nil
+	}
+
+	res := make(map[string]any, len(updateMask.GetPaths()))
+	for _, path := range updateMask.GetPaths() {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key := stringcase.ToSnakeCase(path)
+		if v, ok := fields[path]; ok {
+			res[path] = v
+		} else {
+			res[key] = fields[key]
+		}
+	}
+	return res, nil
+}
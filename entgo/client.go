@@ -199,7 +199,7 @@ func QueryAllChildrenIds[T EntClientInterface](ctx context.Context, entClient *E
 
 		if err := rows.Scan(&id); err != nil {
 			log.Errorf("scan child node failed: %s", err.Error())
-			return nil, errors.New("scan child node failed")
+			return nil, errScanChildNodeFailed
 		}
 
 		childIDs = append(childIDs, id)
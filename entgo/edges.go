@@ -0,0 +1,433 @@
+package entgo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"entgo.io/ent/dialect/sql"
+	"github.com/jinzhu/copier"
+	"github.com/tx7do/go-utils/stringcase"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+// DefaultMaxEdgeDepth 默认允许预加载的边嵌套层数
+const DefaultMaxEdgeDepth = 3
+
+var (
+	// ErrEdgeDepthExceeded 边路径的嵌套层数超出限制
+	ErrEdgeDepthExceeded = errors.New("edge path exceeds max depth")
+
+	// ErrInvalidEdgePath 边路径不合法，如在边的字段后继续使用点号
+	ErrInvalidEdgePath = errors.New("invalid edge path")
+)
+
+// edgeLoad 一条边的预加载计划
+type edgeLoad struct {
+	method string       // ent 生成的预加载方法，如 "WithRoles"
+	query  reflect.Type // 边的查询类型，如 *ent.RoleQuery
+
+	all    bool     // 选择全部列
+	fields []string // 选择的列（蛇形）
+
+	conditions []*paginationV1.Condition // 作用于边的过滤条件
+	selectors  []func(s *sql.Selector)
+
+	edges []*edgeLoad
+}
+
+// edgePlan 从 field_mask 与 FilterExpr 中解析出的预加载计划
+type edgePlan struct {
+	maxDepth int
+	columns  []string // 根实体的列
+	edges    []*edgeLoad
+}
+
+// SetMaxEdgeDepth 设置 field_mask 中边路径允许的最大嵌套层数，<= 0 表示不识别边路径。
+//
+// 启用时，field_mask 中以边名开头的路径会被转换为 ent 的 WithXxx 预加载：
+// "roles" 或 "department.*" 加载边的全部列，"roles.name" 仅选择边的 name 列，"roles.permissions.code" 继续嵌套预加载；
+// 顶层 AND 表达式中以边名开头的条件（如 "roles.name"）作为过滤条件作用于被加载的边，而不是过滤主实体。
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) SetMaxEdgeDepth(depth int) {
+	r.maxEdgeDepth = depth
+}
+
+// applyEdges 识别 mask 与 expr 中的边路径并在 builder 上预加载。
+// 返回剔除边路径后的 mask 与 expr；存在边时根实体的列已通过 ent 的 Select 设置（以便 ent 补齐主键与外键），返回的 mask 为 nil。
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) applyEdges(
+	builder any,
+	mask *fieldmaskpb.FieldMask,
+	expr *paginationV1.FilterExpr,
+) (*fieldmaskpb.FieldMask, *paginationV1.FilterExpr, error) {
+	if r.maxEdgeDepth <= 0 || builder == nil {
+		return mask, expr, nil
+	}
+
+	plan := &edgePlan{maxDepth: r.maxEdgeDepth}
+	root := reflect.TypeOf(builder)
+
+	for _, path := range mask.GetPaths() {
+		if err := plan.addPath(root, path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	expr, err := plan.addFilter(root, expr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(plan.edges) == 0 {
+		return mask, expr, nil
+	}
+
+	if err = plan.buildSelectors(r.structuredFilter.BuildSelectors); err != nil {
+		return nil, nil, err
+	}
+
+	q := reflect.ValueOf(builder)
+	if len(plan.columns) > 0 {
+		if m := q.MethodByName("Select"); m.IsValid() {
+			m.Call(stringValues(plan.columns))
+		}
+	}
+	applyEdgeLoads(q, plan.edges)
+
+	return nil, expr, nil
+}
+
+// applyPagingEdges 对 PagingRequest 应用边预加载，返回剔除边路径后的请求（必要时复制，不修改调用方的请求）
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) applyPagingEdges(builder any, req *paginationV1.PagingRequest) (*paginationV1.PagingRequest, error) {
+	mask, expr, err := r.applyEdges(builder, req.GetFieldMask(), req.GetFilterExpr())
+	if err != nil {
+		return nil, err
+	}
+	if mask == req.GetFieldMask() && expr == req.GetFilterExpr() {
+		return req, nil
+	}
+
+	// 移到边上的条件同样计入过滤复杂度
+	if expr != req.GetFilterExpr() {
+		if err = r.limitPolicy.CheckFilterExpr(r.entityName, req.GetFilterExpr()); err != nil {
+			return nil, err
+		}
+	}
+
+	req = proto.Clone(req).(*paginationV1.PagingRequest)
+	req.FieldMask, req.FilterExpr = mask, expr
+	return req, nil
+}
+
+// applyPaginationEdges 对 PaginationRequest 应用边预加载，返回剔除边路径后的请求（必要时复制，不修改调用方的请求）
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) applyPaginationEdges(builder any, req *paginationV1.PaginationRequest) (*paginationV1.PaginationRequest, error) {
+	mask, expr, err := r.applyEdges(builder, req.GetFieldMask(), req.GetFilterExpr())
+	if err != nil {
+		return nil, err
+	}
+	if mask == req.GetFieldMask() && expr == req.GetFilterExpr() {
+		return req, nil
+	}
+
+	req = proto.Clone(req).(*paginationV1.PaginationRequest)
+	req.FieldMask, req.FilterExpr = mask, expr
+	return req, nil
+}
+
+// addPath 解析一条 field_mask 路径
+func (p *edgePlan) addPath(root reflect.Type, path string) error {
+	segments := strings.Split(path, ".")
+
+	edge := p.edge(nil, root, segments[0])
+	if edge == nil {
+		p.columns = append(p.columns, normalizeColumn(path))
+		return nil
+	}
+
+	depth := 1
+	for i := 1; ; i++ {
+		if depth > p.maxDepth {
+			return fmt.Errorf("%w: %s", ErrEdgeDepthExceeded, path)
+		}
+
+		rest := segments[i:]
+		if len(rest) == 0 || (len(rest) == 1 && rest[0] == "*") {
+			edge.all = true
+			return nil
+		}
+
+		if next := p.edge(edge, edge.query, rest[0]); next != nil {
+			edge = next
+			depth++
+			continue
+		}
+
+		if len(rest) > 1 {
+			return fmt.Errorf("%w: %s", ErrInvalidEdgePath, path)
+		}
+		edge.fields = appendOnce(edge.fields, normalizeColumn(rest[0]))
+		return nil
+	}
+}
+
+// addFilter 将顶层 AND 表达式中以边名开头的条件移到对应的边，返回剩余的表达式
+func (p *edgePlan) addFilter(root reflect.Type, expr *paginationV1.FilterExpr) (*paginationV1.FilterExpr, error) {
+	if expr == nil || expr.GetType() != paginationV1.ExprType_AND {
+		return expr, nil
+	}
+
+	var rest []*paginationV1.Condition
+	for _, cond := range expr.GetConditions() {
+		segments := strings.Split(cond.GetField(), ".")
+		if len(segments) < 2 || p.lookup(root, segments[0]) == nil {
+			rest = append(rest, cond)
+			continue
+		}
+
+		edge := p.edge(nil, root, segments[0])
+		for depth := 1; ; depth++ {
+			if depth > p.maxDepth {
+				return nil, fmt.Errorf("%w: %s", ErrEdgeDepthExceeded, cond.GetField())
+			}
+			segments = segments[1:]
+			if len(segments) == 1 {
+				break
+			}
+			next := p.edge(edge, edge.query, segments[0])
+			if next == nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidEdgePath, cond.GetField())
+			}
+			edge = next
+		}
+
+		// 仅出现在过滤条件中的边加载全部列
+		if len(edge.fields) == 0 {
+			edge.all = true
+		}
+
+		c := proto.Clone(cond).(*paginationV1.Condition)
+		c.Field = normalizeColumn(segments[0])
+		edge.conditions = append(edge.conditions, c)
+	}
+
+	if len(rest) == len(expr.GetConditions()) {
+		return expr, nil
+	}
+
+	out := proto.Clone(expr).(*paginationV1.FilterExpr)
+	out.Conditions = rest
+	return out, nil
+}
+
+// lookup 查找 query 类型上名为 name 的边，返回其预加载方法
+func (p *edgePlan) lookup(query reflect.Type, name string) *reflect.Method {
+	if query == nil || name == "" || name == "*" {
+		return nil
+	}
+
+	m, ok := query.MethodByName("With" + stringcase.ToPascalCase(name))
+	if !ok {
+		return nil
+	}
+	// 形如 func(q *XQuery, opts ...func(*YQuery)) *XQuery
+	t := m.Type
+	if !t.IsVariadic() || t.NumIn() != 2 || t.In(1).Elem().Kind() != reflect.Func || t.In(1).Elem().NumIn() != 1 {
+		return nil
+	}
+	return &m
+}
+
+// edge 返回 parent（nil 表示根实体）下名为 name 的边，不存在时按需创建
+func (p *edgePlan) edge(parent *edgeLoad, query reflect.Type, name string) *edgeLoad {
+	m := p.lookup(query, name)
+	if m == nil {
+		return nil
+	}
+
+	edges := &p.edges
+	if parent != nil {
+		edges = &parent.edges
+	}
+	for _, e := range *edges {
+		if e.method == m.Name {
+			return e
+		}
+	}
+
+	e := &edgeLoad{
+		method: m.Name,
+		query:  m.Type.In(1).Elem().In(0),
+	}
+	*edges = append(*edges, e)
+	return e
+}
+
+// buildSelectors 为各条边构建过滤选择器
+func (p *edgePlan) buildSelectors(build func(*paginationV1.FilterExpr) ([]func(s *sql.Selector), error)) error {
+	var walk func(edges []*edgeLoad) error
+	walk = func(edges []*edgeLoad) error {
+		for _, e := range edges {
+			if len(e.conditions) > 0 {
+				selectors, err := build(&paginationV1.FilterExpr{
+					Type:       paginationV1.ExprType_AND,
+					Conditions: e.conditions,
+				})
+				if err != nil {
+					return err
+				}
+				e.selectors = selectors
+			}
+			if err := walk(e.edges); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(p.edges)
+}
+
+// applyEdgeLoads 在 query 上调用 WithXxx 预加载各条边
+func applyEdgeLoads(query reflect.Value, edges []*edgeLoad) {
+	for _, e := range edges {
+		m := query.MethodByName(e.method)
+		if !m.IsValid() {
+			continue
+		}
+
+		opt := reflect.MakeFunc(m.Type().In(0).Elem(), func(args []reflect.Value) []reflect.Value {
+			q := args[0]
+			if !e.all && len(e.fields) > 0 {
+				if sm := q.MethodByName("Select"); sm.IsValid() {
+					sm.Call(stringValues(e.fields))
+				}
+			}
+			if len(e.selectors) > 0 {
+				if mm := q.MethodByName("Modify"); mm.IsValid() {
+					mm.CallSlice([]reflect.Value{reflect.ValueOf(e.selectors)})
+				}
+			}
+			applyEdgeLoads(q, e.edges)
+			return nil
+		})
+		m.Call([]reflect.Value{opt})
+	}
+}
+
+// mapEdges 将实体 Edges 中已加载的边复制到 DTO 的同名字段，并递归处理嵌套的边
+func mapEdges(dst, src reflect.Value) {
+	for dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			return
+		}
+		dst = dst.Elem()
+	}
+	for src.Kind() == reflect.Pointer {
+		if src.IsNil() {
+			return
+		}
+		src = src.Elem()
+	}
+	if dst.Kind() != reflect.Struct || src.Kind() != reflect.Struct {
+		return
+	}
+
+	edges := src.FieldByName("Edges")
+	if !edges.IsValid() || edges.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < edges.NumField(); i++ {
+		f := edges.Type().Field(i)
+		ev := edges.Field(i)
+		if !f.IsExported() || (ev.Kind() != reflect.Pointer && ev.Kind() != reflect.Slice) || ev.IsNil() {
+			continue
+		}
+
+		df := dst.FieldByName(f.Name)
+		if !df.IsValid() || !df.CanSet() {
+			continue
+		}
+		if err := copier.CopyWithOption(df.Addr().Interface(), ev.Interface(), copier.Option{DeepCopy: true}); err != nil {
+			continue
+		}
+
+		switch ev.Kind() {
+		case reflect.Slice:
+			if df.Kind() != reflect.Slice {
+				continue
+			}
+			for j := 0; j < ev.Len() && j < df.Len(); j++ {
+				mapEdges(df.Index(j), ev.Index(j))
+			}
+		default:
+			mapEdges(df, ev)
+		}
+	}
+}
+
+// toDTO 将实体转换为 DTO，并映射已预加载的边
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) toDTO(entity *ENTITY) *DTO {
+	dto := r.mapper.ToDTO(entity)
+	if dto != nil && r.maxEdgeDepth > 0 {
+		mapEdges(reflect.ValueOf(dto), reflect.ValueOf(entity))
+	}
+	return dto
+}
+
+// normalizeColumn 将 field_mask 中的字段名转为蛇形列名
+func normalizeColumn(name string) string {
+	if name == "id_" || name == "_id" {
+		return "id"
+	}
+	return stringcase.ToSnakeCase(name)
+}
+
+func appendOnce(items []string, item string) []string {
+	for _, it := range items {
+		if it == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
+
+func stringValues(items []string) []reflect.Value {
+	values := make([]reflect.Value, 0, len(items))
+	for _, it := range items {
+		values = append(values, reflect.ValueOf(it))
+	}
+	return values
+}
@@ -0,0 +1,230 @@
+package entgo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"entgo.io/ent/dialect/sql"
+	"github.com/tx7do/go-utils/mapper"
+	"github.com/tx7do/go-utils/trans"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/entgo/ent"
+	"github.com/tx7do/go-crud/entgo/ent/predicate"
+)
+
+// 模拟 ent 生成的查询：UserQuery -> roles -> permissions，UserQuery -> department
+type fakeUserQuery struct {
+	fields     []string
+	roles      *fakeRoleQuery
+	department *fakeDepartmentQuery
+}
+
+func (q *fakeUserQuery) Select(fields ...string) *fakeUserQuery {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+func (q *fakeUserQuery) WithRoles(opts ...func(*fakeRoleQuery)) *fakeUserQuery {
+	q.roles = &fakeRoleQuery{}
+	for _, opt := range opts {
+		opt(q.roles)
+	}
+	return q
+}
+
+func (q *fakeUserQuery) WithDepartment(opts ...func(*fakeDepartmentQuery)) *fakeUserQuery {
+	q.department = &fakeDepartmentQuery{}
+	for _, opt := range opts {
+		opt(q.department)
+	}
+	return q
+}
+
+type fakeRoleQuery struct {
+	fields      []string
+	modifiers   []func(*sql.Selector)
+	permissions *fakePermissionQuery
+}
+
+func (q *fakeRoleQuery) Select(fields ...string) *fakeRoleQuery {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+func (q *fakeRoleQuery) Modify(modifiers ...func(*sql.Selector)) *fakeRoleQuery {
+	q.modifiers = append(q.modifiers, modifiers...)
+	return q
+}
+
+func (q *fakeRoleQuery) WithPermissions(opts ...func(*fakePermissionQuery)) *fakeRoleQuery {
+	q.permissions = &fakePermissionQuery{}
+	for _, opt := range opts {
+		opt(q.permissions)
+	}
+	return q
+}
+
+type fakePermissionQuery struct {
+	fields []string
+}
+
+func (q *fakePermissionQuery) Select(fields ...string) *fakePermissionQuery {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+type fakeDepartmentQuery struct {
+	fields []string
+}
+
+func (q *fakeDepartmentQuery) Select(fields ...string) *fakeDepartmentQuery {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+func newEdgeTestRepository() *Repository[
+	ent.UserQuery, ent.UserSelect,
+	ent.UserCreate, ent.UserCreateBulk,
+	ent.UserUpdate, ent.UserUpdateOne,
+	ent.UserDelete,
+	predicate.User, ent.User, ent.User,
+] {
+	return NewRepository[
+		ent.UserQuery, ent.UserSelect,
+		ent.UserCreate, ent.UserCreateBulk,
+		ent.UserUpdate, ent.UserUpdateOne,
+		ent.UserDelete,
+		predicate.User, ent.User, ent.User,
+	](mapper.NewCopierMapper[ent.User, ent.User]())
+}
+
+func TestApplyEdges(t *testing.T) {
+	r := newEdgeTestRepository()
+	q := &fakeUserQuery{}
+
+	mask := &fieldmaskpb.FieldMask{Paths: []string{"userName", "roles.name", "roles.permissions.code", "department.*"}}
+	expr := &paginationV1.FilterExpr{
+		Type: paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{
+			{Field: "status", Op: paginationV1.Operator_EQ, Value: trans.Ptr("ON")},
+			{Field: "roles.code", Op: paginationV1.Operator_EQ, Value: trans.Ptr("admin")},
+			{Field: "preferences.theme", Op: paginationV1.Operator_EQ, Value: trans.Ptr("dark")},
+		},
+	}
+
+	gotMask, gotExpr, err := r.applyEdges(q, mask, expr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotMask != nil {
+		t.Errorf("root columns should be applied via Select, got mask %v", gotMask)
+	}
+	if !reflect.DeepEqual(q.fields, []string{"user_name"}) {
+		t.Errorf("root fields: got %v", q.fields)
+	}
+
+	if q.roles == nil || !reflect.DeepEqual(q.roles.fields, []string{"name"}) {
+		t.Fatalf("roles not loaded with selected fields: %+v", q.roles)
+	}
+	if len(q.roles.modifiers) != 1 {
+		t.Errorf("expected roles filter modifier, got %d", len(q.roles.modifiers))
+	}
+	if q.roles.permissions == nil || !reflect.DeepEqual(q.roles.permissions.fields, []string{"code"}) {
+		t.Errorf("permissions not loaded with selected fields: %+v", q.roles.permissions)
+	}
+	if q.department == nil || len(q.department.fields) != 0 {
+		t.Errorf("department should be loaded with all fields: %+v", q.department)
+	}
+
+	// 边上的条件被移出，JSON 字段条件保留
+	if len(gotExpr.GetConditions()) != 2 || gotExpr.GetConditions()[1].GetField() != "preferences.theme" {
+		t.Errorf("unexpected remaining conditions: %v", gotExpr.GetConditions())
+	}
+	if len(expr.GetConditions()) != 3 {
+		t.Error("caller's FilterExpr must not be modified")
+	}
+}
+
+func TestApplyEdges_NoEdges(t *testing.T) {
+	r := newEdgeTestRepository()
+	q := &fakeUserQuery{}
+
+	mask := &fieldmaskpb.FieldMask{Paths: []string{"name", "preferences.theme"}}
+	gotMask, _, err := r.applyEdges(q, mask, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotMask != mask || q.fields != nil {
+		t.Errorf("mask without edges must be left untouched, got %v / %v", gotMask, q.fields)
+	}
+}
+
+func TestApplyEdges_Depth(t *testing.T) {
+	r := newEdgeTestRepository()
+	r.SetMaxEdgeDepth(1)
+
+	_, _, err := r.applyEdges(&fakeUserQuery{}, &fieldmaskpb.FieldMask{Paths: []string{"roles.permissions.code"}}, nil)
+	if !errors.Is(err, ErrEdgeDepthExceeded) {
+		t.Errorf("expected ErrEdgeDepthExceeded, got %v", err)
+	}
+
+	_, _, err = r.applyEdges(&fakeUserQuery{}, &fieldmaskpb.FieldMask{Paths: []string{"roles.meta.key"}}, nil)
+	if !errors.Is(err, ErrInvalidEdgePath) {
+		t.Errorf("expected ErrInvalidEdgePath, got %v", err)
+	}
+
+	r.SetMaxEdgeDepth(0)
+	q := &fakeUserQuery{}
+	if _, _, err = r.applyEdges(q, &fieldmaskpb.FieldMask{Paths: []string{"roles.name"}}, nil); err != nil || q.roles != nil {
+		t.Errorf("edge loading should be disabled, got %v / %+v", err, q.roles)
+	}
+}
+
+type edgeTestRole struct {
+	Name  string
+	Edges struct {
+		Permissions []*edgeTestPermission
+	}
+}
+
+type edgeTestPermission struct {
+	Code string
+}
+
+type edgeTestUser struct {
+	Name  string
+	Edges struct {
+		Roles      []*edgeTestRole
+		Department *edgeTestPermission
+	}
+}
+
+type edgeTestRoleDTO struct {
+	Name        string
+	Permissions []*edgeTestPermission
+}
+
+type edgeTestUserDTO struct {
+	Name  string
+	Roles []*edgeTestRoleDTO
+}
+
+func TestMapEdges(t *testing.T) {
+	entity := &edgeTestUser{Name: "alice"}
+	entity.Edges.Roles = []*edgeTestRole{{Name: "admin"}}
+	entity.Edges.Roles[0].Edges.Permissions = []*edgeTestPermission{{Code: "user:read"}}
+	entity.Edges.Department = &edgeTestPermission{Code: "rd"}
+
+	dto := &edgeTestUserDTO{Name: "alice"}
+	mapEdges(reflect.ValueOf(dto), reflect.ValueOf(entity))
+
+	if len(dto.Roles) != 1 || dto.Roles[0].Name != "admin" {
+		t.Fatalf("roles not mapped: %+v", dto.Roles)
+	}
+	if len(dto.Roles[0].Permissions) != 1 || dto.Roles[0].Permissions[0].Code != "user:read" {
+		t.Errorf("nested permissions not mapped: %+v", dto.Roles[0].Permissions)
+	}
+}
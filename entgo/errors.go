@@ -0,0 +1,31 @@
+package entgo
+
+import "errors"
+
+// 仓库自身返回的错误，在 init 中按分类注册到 metrics，作为 class 标签
+var (
+	// 参数缺失或不合法
+	errBulkBuilderIsNil        = errors.New("bulk builder is nil")
+	errDTOIsNil                = errors.New("dto is nil")
+	errDTOProtoMessageIsNil    = errors.New("dto proto message is nil")
+	errDTOsIsEmpty             = errors.New("dtos is empty")
+	errDriverIsNil             = errors.New("driver is nil")
+	errGuardCallbacksRequired  = errors.New("beginTx and fn are required")
+	errImportCallbacksRequired = errors.New("beginTx and newBulkBuilder are required")
+	errPaginationRequestIsNil  = errors.New("paginationV1 request is nil")
+	errPagingRequestIsNil      = errors.New("paging request is nil")
+	errQueryBuilderIsNil       = errors.New("query builder is nil")
+	errTableIsEmpty            = errors.New("table is empty")
+	errUpsertModeUnsupported   = errors.New("upsert mode is not supported by ent repository")
+
+	// 底层存储执行失败，原始错误已记录日志
+	errDeleteFailed             = errors.New("delete failed")
+	errExistsCheckFailed        = errors.New("exists check failed")
+	errExplainQueryFailed       = errors.New("explain query failed")
+	errQueryCountFailed         = errors.New("query count failed")
+	errQueryListFailed          = errors.New("query list failed")
+	errScanChildNodeFailed      = errors.New("scan child node failed")
+	errSearchQueryFailed        = errors.New("search query failed")
+	errSoftDeleteByFilterFailed = errors.New("soft delete by filter failed")
+	errUpdateByFilterFailed     = errors.New("update by filter failed")
+)
@@ -0,0 +1,117 @@
+package entgo
+
+import (
+	"context"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/go-kratos/kratos/v2/log"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/explain"
+)
+
+// ToQuery 返回 ListWithPaging 针对 req 生成的列表与计数 SQL 及参数（不访问数据库）。
+// ent 生成的查询构造器不暴露最终 SQL，这里基于 table 构造 SELECT * 的 sql.Selector 并应用与 ListWithPaging 相同的 selectors；
+// dialectName 为 dialect.MySQL / dialect.Postgres / dialect.SQLite 等。
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) ToQuery(dialectName, table string, req *paginationV1.PagingRequest) (*explain.Query, error) {
+	if table == "" {
+		return nil, errTableIsEmpty
+	}
+
+	whereSelectors, querySelectors, err := r.buildListSelectors(req)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := sql.Dialect(dialectName)
+
+	listSelector := builder.Select("*").From(builder.Table(table))
+	for _, s := range querySelectors {
+		if s != nil {
+			s(listSelector)
+		}
+	}
+	listSQL, listArgs := listSelector.Query()
+
+	countSelector := builder.Select(sql.Count("*")).From(builder.Table(table))
+	for _, s := range whereSelectors {
+		if s != nil {
+			s(countSelector)
+		}
+	}
+	countSQL, countArgs := countSelector.Query()
+
+	if err = listSelector.Err(); err != nil {
+		return nil, err
+	}
+	if err = countSelector.Err(); err != nil {
+		return nil, err
+	}
+
+	return &explain.Query{
+		Statement:      listSQL,
+		Args:           listArgs,
+		CountStatement: countSQL,
+		CountArgs:      countArgs,
+	}, nil
+}
+
+// Explain 在 ToQuery 的基础上通过 drv 对列表 SQL 执行 EXPLAIN，返回数据库的执行计划（不执行列表查询本身）
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) Explain(ctx context.Context, drv dialect.Driver, table string, req *paginationV1.PagingRequest) (*explain.Query, error) {
+	if drv == nil {
+		return nil, errDriverIsNil
+	}
+
+	q, err := r.ToQuery(drv.Dialect(), table, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows sql.Rows
+	if err = drv.Query(ctx, "EXPLAIN "+q.Statement, q.Args, &rows); err != nil {
+		log.Errorf("explain query failed: %s", err.Error())
+		return nil, errExplainQueryFailed
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			log.Errorf("failed to close rows: %s", cerr.Error())
+		}
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][]any
+	for rows.Next() {
+		row := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err = rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		values = append(values, row)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	q.Plan = explain.FormatTable(columns, values)
+	return q, nil
+}
@@ -0,0 +1,61 @@
+package entgo
+
+import (
+	"strings"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"github.com/tx7do/go-utils/mapper"
+	"github.com/tx7do/go-utils/trans"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/entgo/ent"
+	"github.com/tx7do/go-crud/entgo/ent/predicate"
+)
+
+func TestRepository_ToQuery(t *testing.T) {
+	r := NewRepository[
+		ent.UserQuery, ent.UserSelect,
+		ent.UserCreate, ent.UserCreateBulk,
+		ent.UserUpdate, ent.UserUpdateOne,
+		ent.UserDelete,
+		predicate.User, ent.User, ent.User,
+	](mapper.NewCopierMapper[ent.User, ent.User]())
+
+	q, err := r.ToQuery(dialect.MySQL, "users", &paginationV1.PagingRequest{
+		FilterExpr: &paginationV1.FilterExpr{
+			Type: paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{
+				{Field: "name", Op: paginationV1.Operator_EQ, Value: trans.Ptr("alice")},
+			},
+		},
+		Page:     trans.Ptr(uint32(2)),
+		PageSize: trans.Ptr(uint32(10)),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{"FROM `users`", "`name` = ?", "LIMIT 10", "OFFSET 10"} {
+		if !strings.Contains(q.Statement, want) {
+			t.Errorf("Statement: expected to contain %q, got %q", want, q.Statement)
+		}
+	}
+	if len(q.Args) != 1 || q.Args[0] != "alice" {
+		t.Errorf("Args: expected [alice], got %v", q.Args)
+	}
+
+	if !strings.HasPrefix(q.CountStatement, "SELECT COUNT(*) FROM `users`") {
+		t.Errorf("CountStatement: got %q", q.CountStatement)
+	}
+	if strings.Contains(q.CountStatement, "LIMIT") {
+		t.Errorf("CountStatement: expected no LIMIT, got %q", q.CountStatement)
+	}
+	if len(q.CountArgs) != 1 {
+		t.Errorf("CountArgs: expected 1 arg, got %v", q.CountArgs)
+	}
+
+	if _, err = r.ToQuery(dialect.MySQL, "", &paginationV1.PagingRequest{}); err == nil {
+		t.Errorf("expected error for empty table")
+	}
+}
@@ -83,10 +83,14 @@ func (sf StructuredFilter) buildFilterSelector(expr *pagination.FilterExpr) (fun
 			return
 		}
 
+		if len(ps) == 0 {
+			return
+		}
+
 		// Combine predicates based on expression type
 		switch expr.GetType() {
 		case pagination.ExprType_AND:
-			s.Where(sql.Or(ps...))
+			s.Where(sql.And(ps...))
 		case pagination.ExprType_OR:
 			s.Where(sql.Or(ps...))
 		}
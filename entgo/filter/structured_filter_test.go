@@ -3,6 +3,8 @@ package filter
 import (
 	"testing"
 
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
 	"github.com/tx7do/go-utils/trans"
 	"google.golang.org/protobuf/encoding/protojson"
 
@@ -286,3 +288,35 @@ func TestStructuredFilter_VariousConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildFilterSelectors_AndCombinesWithAnd(t *testing.T) {
+	sf := NewStructuredFilter()
+
+	expr := &pagination.FilterExpr{
+		Type: pagination.ExprType_AND,
+		Conditions: []*pagination.Condition{
+			{Field: "a", Op: pagination.Operator_EQ, Value: trans.Ptr("1")},
+			{Field: "b", Op: pagination.Operator_EQ, Value: trans.Ptr("2")},
+		},
+		Groups: []*pagination.FilterExpr{{Type: pagination.ExprType_OR}},
+	}
+
+	sels, err := sf.BuildSelectors(expr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+	for _, sel := range sels {
+		sel(s)
+	}
+	query, args := s.Query()
+
+	want := `SELECT * FROM "users" WHERE "users"."a" = $1 AND "users"."b" = $2`
+	if query != want {
+		t.Errorf("expected %s, got %s", want, query)
+	}
+	if len(args) != 2 {
+		t.Errorf("expected 2 args, got %v", args)
+	}
+}
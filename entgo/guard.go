@@ -0,0 +1,163 @@
+package entgo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/tx7do/go-crud/guard"
+)
+
+var (
+	// ErrGuardTxRequired 配置了 MaxAffectedRows 的写操作未在 ExecWithGuard 开启的事务中执行
+	ErrGuardTxRequired = errors.New("max affected rows requires the write to run inside ExecWithGuard")
+
+	// ErrUninspectableBuilder 无法从 builder 判断是否设置了过滤条件，需通过 predicates 参数显式传入
+	ErrUninspectableBuilder = errors.New("cannot inspect the filters of the query builder, pass predicates explicitly")
+)
+
+// guardTxKey 标记当前写操作由 ExecWithGuard 开启的事务承载
+type guardTxKey struct{}
+
+// SetGuardOptions 设置批量更新/删除的安全保护。
+// 默认拒绝不带谓词的更新与删除；MaxAffectedRows 大于 0 时写操作须通过 ExecWithGuard 在事务中执行，
+// 受影响行数超出上限则回滚并返回 guard.ErrTooManyRowsAffected。
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) SetGuardOptions(opts guard.Options) {
+	r.guardOptions = opts
+}
+
+// ExecWithGuard 在 beginTx 开启的事务中执行 fn，受影响行数超出 MaxAffectedRows 或 fn 返回错误时回滚，否则提交。
+// fn 需基于传入的事务构造 builder 再调用 Delete、UpdateX、UpdateByFilter 或 DeleteByFilter，并返回受影响行数。
+//
+// 示例：
+//
+//	affected, err := repo.ExecWithGuard(ctx,
+//		func(ctx context.Context) (entgo.Committer, error) { return client.Tx(ctx) },
+//		func(ctx context.Context, tx entgo.Committer) (int, error) {
+//			return repo.Delete(ctx, tx.(*ent.Tx).User.Delete(), user.AgeGT(60))
+//		},
+//	)
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) ExecWithGuard(
+	ctx context.Context,
+	beginTx func(ctx context.Context) (Committer, error),
+	fn func(ctx context.Context, tx Committer) (int, error),
+) (int, error) {
+	if beginTx == nil || fn == nil {
+		return 0, errGuardCallbacksRequired
+	}
+
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := fn(context.WithValue(ctx, guardTxKey{}, true), tx)
+	if err == nil {
+		err = r.guardOptions.CheckAffected(int64(affected))
+	}
+	if err != nil {
+		return 0, Rollback(tx, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// checkGuardFilter 校验 builder 或本次传入的谓词中至少存在一个过滤条件。
+// 未传入谓词且无法识别 builder 结构时返回 ErrUninspectableBuilder。
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) checkGuardFilter(builder any, predicates int) error {
+	if predicates > 0 || r.guardOptions.CheckFilter(false) == nil {
+		return nil
+	}
+
+	has, ok := builderHasPredicates(builder)
+	if !ok {
+		return ErrUninspectableBuilder
+	}
+	return r.guardOptions.CheckFilter(has)
+}
+
+// execWithGuard 执行写操作；配置了 MaxAffectedRows 时要求处于 ExecWithGuard 的事务中，
+// 受影响行数超出上限时返回 guard 错误，由 ExecWithGuard 回滚事务。exec 的错误原样返回。
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) execWithGuard(ctx context.Context, exec func(ctx context.Context) (int, error)) (int, error) {
+	if r.guardOptions.MaxAffectedRows <= 0 {
+		return exec(ctx)
+	}
+	if inGuardTx, _ := ctx.Value(guardTxKey{}).(bool); !inGuardTx {
+		return 0, ErrGuardTxRequired
+	}
+
+	affected, err := exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err = r.guardOptions.CheckAffected(int64(affected)); err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// builderHasPredicates 判断 ent 生成的 Update/Delete builder 上是否已设置谓词（mutation.predicates）。
+// ok 为 false 表示无法识别 builder 结构。
+func builderHasPredicates(builder any) (has bool, ok bool) {
+	v := reflect.ValueOf(builder)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false, true
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return false, false
+	}
+
+	m := v.FieldByName("mutation")
+	if !m.IsValid() {
+		return false, false
+	}
+	for m.Kind() == reflect.Ptr {
+		if m.IsNil() {
+			return false, true
+		}
+		m = m.Elem()
+	}
+	if m.Kind() != reflect.Struct {
+		return false, false
+	}
+
+	ps := m.FieldByName("predicates")
+	if !ps.IsValid() || ps.Kind() != reflect.Slice {
+		return false, false
+	}
+	return ps.Len() > 0, true
+}
+
+// isGuardError 判断是否为安全保护返回的错误，此类错误原样返回给调用方
+func isGuardError(err error) bool {
+	return errors.Is(err, guard.ErrTooManyRowsAffected) || errors.Is(err, ErrGuardTxRequired)
+}
@@ -0,0 +1,124 @@
+package entgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tx7do/go-utils/mapper"
+
+	"github.com/tx7do/go-crud/entgo/ent"
+	"github.com/tx7do/go-crud/entgo/ent/predicate"
+	"github.com/tx7do/go-crud/entgo/ent/user"
+	"github.com/tx7do/go-crud/guard"
+)
+
+func TestBuilderHasPredicates(t *testing.T) {
+	cli := ent.NewClient()
+
+	cases := []struct {
+		name    string
+		builder any
+		has, ok bool
+	}{
+		{"delete without where", cli.User.Delete(), false, true},
+		{"delete with where", cli.User.Delete().Where(user.IDEQ(1)), true, true},
+		{"update without where", cli.User.Update(), false, true},
+		{"update with where", cli.User.Update().Where(user.IDEQ(1)), true, true},
+		{"nil builder", (*ent.UserDelete)(nil), false, true},
+		{"unknown builder", struct{}{}, false, false},
+	}
+	for _, c := range cases {
+		if has, ok := builderHasPredicates(c.builder); has != c.has || ok != c.ok {
+			t.Errorf("%s: expected (%v, %v), got (%v, %v)", c.name, c.has, c.ok, has, ok)
+		}
+	}
+}
+
+func TestCheckGuardFilter_UnknownBuilder(t *testing.T) {
+	r := newGuardTestRepository()
+
+	if err := r.checkGuardFilter(struct{}{}, 0); !errors.Is(err, ErrUninspectableBuilder) {
+		t.Errorf("expected ErrUninspectableBuilder, got %v", err)
+	}
+	if err := r.checkGuardFilter(struct{}{}, 1); err != nil {
+		t.Errorf("explicit predicates: unexpected error %v", err)
+	}
+
+	r.SetGuardOptions(guard.Options{AllowFullTable: true})
+	if err := r.checkGuardFilter(struct{}{}, 0); err != nil {
+		t.Errorf("AllowFullTable: unexpected error %v", err)
+	}
+}
+
+func TestExecWithGuard_RollsBack(t *testing.T) {
+	ctx := context.Background()
+	cli := createTestEntClient(t)
+	defer cli.Close()
+
+	cli.User.Delete().ExecX(ctx)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		cli.User.Create().SetName(name).SetAge(20).ExecX(ctx)
+	}
+
+	r := newGuardTestRepository()
+	r.SetGuardOptions(guard.Options{MaxAffectedRows: 2})
+	beginTx := func(ctx context.Context) (Committer, error) { return cli.Tx(ctx) }
+
+	// 未通过 ExecWithGuard 执行时拒绝写入
+	if _, err := r.Delete(ctx, cli.User.Delete(), user.AgeEQ(20)); !errors.Is(err, ErrGuardTxRequired) {
+		t.Fatalf("expected ErrGuardTxRequired, got %v", err)
+	}
+
+	_, err := r.ExecWithGuard(ctx, beginTx, func(ctx context.Context, tx Committer) (int, error) {
+		return r.Delete(ctx, tx.(*ent.Tx).User.Delete(), user.AgeEQ(20))
+	})
+	if !errors.Is(err, guard.ErrTooManyRowsAffected) {
+		t.Fatalf("expected ErrTooManyRowsAffected, got %v", err)
+	}
+	if n := cli.User.Query().CountX(ctx); n != 3 {
+		t.Fatalf("expected delete to be rolled back, %d rows left", n)
+	}
+
+	// fn 内多次写操作的总行数同样受限
+	_, err = r.ExecWithGuard(ctx, beginTx, func(ctx context.Context, tx Committer) (int, error) {
+		var total int
+		for _, name := range []string{"alice", "bob", "carol"} {
+			n, err := r.Delete(ctx, tx.(*ent.Tx).User.Delete(), user.NameEQ(name))
+			if err != nil {
+				return 0, err
+			}
+			total += n
+		}
+		return total, nil
+	})
+	if !errors.Is(err, guard.ErrTooManyRowsAffected) {
+		t.Fatalf("expected ErrTooManyRowsAffected for the total, got %v", err)
+	}
+
+	affected, err := r.ExecWithGuard(ctx, beginTx, func(ctx context.Context, tx Committer) (int, error) {
+		return r.Delete(ctx, tx.(*ent.Tx).User.Delete(), user.NameEQ("alice"))
+	})
+	if err != nil || affected != 1 {
+		t.Fatalf("expected 1 deleted row, got %d, %v", affected, err)
+	}
+	if n := cli.User.Query().CountX(ctx); n != 2 {
+		t.Fatalf("expected 2 rows after commit, got %d", n)
+	}
+}
+
+func newGuardTestRepository() *Repository[
+	ent.UserQuery, ent.UserSelect,
+	ent.UserCreate, ent.UserCreateBulk,
+	ent.UserUpdate, ent.UserUpdateOne,
+	ent.UserDelete,
+	predicate.User, ent.User, ent.User,
+] {
+	return NewRepository[
+		ent.UserQuery, ent.UserSelect,
+		ent.UserCreate, ent.UserCreateBulk,
+		ent.UserUpdate, ent.UserUpdateOne,
+		ent.UserDelete,
+		predicate.User, ent.User, ent.User,
+	](mapper.NewCopierMapper[ent.User, ent.User]())
+}
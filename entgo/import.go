@@ -0,0 +1,78 @@
+package entgo
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/tx7do/go-crud/importer"
+)
+
+// Committer 可提交与回滚的事务，ent 生成的 *ent.Tx 满足该接口
+type Committer interface {
+	Rollbacker
+	Commit() error
+}
+
+// Import 从 CSV/JSONL 批量导入数据。
+// 每个分块通过 beginTx 开启独立事务，由 newBulkBuilder 基于该事务构造 CreateBulk，再经 BatchCreate 写入；
+// 分块写入失败时回滚该事务。ent 仓库没有通用的 Upsert，因此仅支持 importer.ModeInsert。
+//
+// 示例：
+//
+//	report, err := repo.Import(ctx, f, &importer.Options{Format: importer.FormatCSV}, nil,
+//		func(ctx context.Context) (entgo.Committer, error) { return client.Tx(ctx) },
+//		func(tx entgo.Committer, dtos []*userV1.User) entgo.CreateBulkBuilder[ent.UserCreateBulk, ent.User] {
+//			builders := make([]*ent.UserCreate, 0, len(dtos))
+//			for _, dto := range dtos {
+//				builders = append(builders, tx.(*ent.Tx).User.Create().SetName(dto.GetName()))
+//			}
+//			return tx.(*ent.Tx).User.CreateBulk(builders...)
+//		},
+//	)
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) Import(
+	ctx context.Context,
+	src io.Reader,
+	opts *importer.Options,
+	validate importer.Validator[DTO],
+	beginTx func(ctx context.Context) (Committer, error),
+	newBulkBuilder func(tx Committer, dtos []*DTO) CreateBulkBuilder[ENT_CREATE_BULK, ENTITY],
+) (*importer.Report, error) {
+	if opts == nil {
+		opts = &importer.Options{}
+	}
+	if opts.Mode == importer.ModeUpsert {
+		return nil, errUpsertModeUnsupported
+	}
+	if !opts.DryRun && (beginTx == nil || newBulkBuilder == nil) {
+		return nil, errImportCallbacksRequired
+	}
+
+	return importer.Import[DTO](ctx, src, opts, validate, func(ctx context.Context, dtos []*DTO) ([]importer.Status, error) {
+		tx, err := beginTx(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("begin transaction failed: %w", err)
+		}
+
+		builder := newBulkBuilder(tx, dtos)
+		if builder == nil {
+			return nil, Rollback(tx, errBulkBuilderIsNil)
+		}
+
+		if _, err = r.BatchCreate(ctx, builder, dtos, nil, nil); err != nil {
+			return nil, Rollback(tx, err)
+		}
+
+		if err = tx.Commit(); err != nil {
+			return nil, fmt.Errorf("commit transaction failed: %w", err)
+		}
+
+		return nil, nil
+	})
+}
@@ -0,0 +1,50 @@
+package entgo
+
+import (
+	"github.com/tx7do/go-crud/metrics"
+)
+
+// SetMetrics 设置仓库级指标，多个仓库可共享同一个 metrics.Metrics；传入 nil 关闭指标
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) SetMetrics(m *metrics.Metrics) {
+	r.metrics = m
+}
+
+func init() {
+	metrics.RegisterErrorClass(metrics.ErrorClassInvalidArgument,
+		errBulkBuilderIsNil,
+		errDTOIsNil,
+		errDTOProtoMessageIsNil,
+		errDTOsIsEmpty,
+		errDriverIsNil,
+		errGuardCallbacksRequired,
+		errImportCallbacksRequired,
+		errPaginationRequestIsNil,
+		errPagingRequestIsNil,
+		errQueryBuilderIsNil,
+		errTableIsEmpty,
+		errUpsertModeUnsupported,
+		ErrEdgeDepthExceeded,
+		ErrInvalidEdgePath,
+		ErrUninspectableBuilder,
+	)
+	metrics.RegisterErrorClass(metrics.ErrorClassStorage,
+		errDeleteFailed,
+		errExistsCheckFailed,
+		errExplainQueryFailed,
+		errQueryCountFailed,
+		errQueryListFailed,
+		errScanChildNodeFailed,
+		errSearchQueryFailed,
+		errSoftDeleteByFilterFailed,
+		errUpdateByFilterFailed,
+	)
+	metrics.RegisterErrorClass(metrics.ErrorClassRejected,
+		ErrGuardTxRequired,
+	)
+}
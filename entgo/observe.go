@@ -0,0 +1,28 @@
+package entgo
+
+import (
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+)
+
+// SetSlowLog 设置慢查询日志，多个仓库可共享同一个 slowlog.Logger；传入 nil 关闭
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) SetSlowLog(l *slowlog.Logger) {
+	r.slowLog = l
+}
+
+// observe 开始观测一次仓库操作
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) observe(method string) *slowlog.Observation {
+	return slowlog.Observe(r.metrics, r.slowLog, metrics.BackendEnt, r.entityName, method)
+}
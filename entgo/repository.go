@@ -2,7 +2,9 @@ package entgo
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"reflect"
+	"time"
 
 	"entgo.io/ent/dialect/sql"
 	"github.com/go-kratos/kratos/v2/log"
@@ -19,8 +21,15 @@ import (
 	paging "github.com/tx7do/go-crud/entgo/pagination"
 	"github.com/tx7do/go-crud/entgo/sorting"
 	"github.com/tx7do/go-crud/entgo/update"
+	"github.com/tx7do/go-crud/guard"
+	"github.com/tx7do/go-crud/limits"
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
 )
 
+// SoftDeleteField 软删除使用的字段，与 mixin.DeletedAt 保持一致
+var SoftDeleteField = "deleted_at"
+
 type QueryBuilder[ENT_QUERY any, ENT_SELECT any, ENTITY any] interface {
 	Modify(modifiers ...func(s *sql.Selector)) *ENT_SELECT
 
@@ -141,6 +150,14 @@ type Repository[
 	structuredFilter  *filter.StructuredFilter
 
 	fieldSelector *field.Selector
+
+	guardOptions guard.Options
+	limitPolicy  *limits.Policy
+	maxEdgeDepth int
+
+	metrics    *metrics.Metrics
+	slowLog    *slowlog.Logger
+	entityName string
 }
 
 func NewRepository[
@@ -176,6 +193,10 @@ func NewRepository[
 		structuredFilter:  filter.NewStructuredFilter(),
 
 		fieldSelector: field.NewFieldSelector(),
+
+		maxEdgeDepth: DefaultMaxEdgeDepth,
+
+		entityName: metrics.EntityName[ENTITY](),
 	}
 }
 
@@ -196,9 +217,12 @@ func (r *Repository[
 	ctx context.Context,
 	builder QueryBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	predicates ...func(s *sql.Selector),
-) (int, error) {
+) (ret int, err error) {
+	obs := r.observe("Count")
+	defer func() { obs.End(err) }()
+
 	if builder == nil {
-		return 0, errors.New("query builder is nil")
+		return 0, errQueryBuilderIsNil
 	}
 
 	if len(predicates) > 0 {
@@ -208,7 +232,7 @@ func (r *Repository[
 	count, err := builder.Count(ctx)
 	if err != nil {
 		log.Errorf("query count failed: %s", err.Error())
-		return 0, errors.New("query count failed")
+		return 0, errQueryCountFailed
 	}
 
 	return count, nil
@@ -225,9 +249,12 @@ func (r *Repository[
 	ctx context.Context,
 	builder QueryBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	predicates ...func(s *sql.Selector),
-) (bool, error) {
+) (ret bool, err error) {
+	obs := r.observe("Exists")
+	defer func() { obs.End(err) }()
+
 	if builder == nil {
-		return false, errors.New("query builder is nil")
+		return false, errQueryBuilderIsNil
 	}
 
 	if len(predicates) > 0 {
@@ -237,7 +264,7 @@ func (r *Repository[
 	exists, err := builder.Exist(ctx)
 	if err != nil {
 		log.Errorf("exists check failed: %s", err.Error())
-		return false, errors.New("exists check failed")
+		return false, errExistsCheckFailed
 	}
 
 	return exists, nil
@@ -255,13 +282,22 @@ func (r *Repository[
 	builder ListBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	countBuilder ListBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	req *paginationV1.PagingRequest,
-) (*PagingResult[DTO], error) {
+) (ret *PagingResult[DTO], err error) {
+	obs := r.observe("ListWithPaging")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Items)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPagingRequest(req)
+
 	if req == nil {
-		return nil, errors.New("paging request is nil")
+		return nil, errPagingRequestIsNil
 	}
 
 	if builder == nil {
-		return nil, errors.New("query builder is nil")
+		return nil, errQueryBuilderIsNil
 	}
 
 	whereSelectors, _, err := r.BuildListSelectorWithPaging(builder, req)
@@ -272,12 +308,12 @@ func (r *Repository[
 	entities, err := builder.All(ctx)
 	if err != nil {
 		log.Errorf("query list failed: %s", err.Error())
-		return nil, errors.New("query list failed")
+		return nil, errQueryListFailed
 	}
 
 	dtos := make([]*DTO, 0, len(entities))
 	for _, entity := range entities {
-		dto := r.mapper.ToDTO(entity)
+		dto := r.toDTO(entity)
 		dtos = append(dtos, dto)
 	}
 
@@ -289,7 +325,7 @@ func (r *Repository[
 		count, err = countBuilder.Count(ctx)
 		if err != nil {
 			log.Errorf("query count failed: %s", err.Error())
-			return nil, errors.New("query count failed")
+			return nil, errQueryCountFailed
 		}
 	}
 
@@ -313,13 +349,22 @@ func (r *Repository[
 	builder ListBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	countBuilder ListBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	req *paginationV1.PagingRequest,
-) (*PagingResult[DTO], error) {
+) (ret *PagingResult[DTO], err error) {
+	obs := r.observe("ListTreeWithPaging")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Items)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPagingRequest(req)
+
 	if req == nil {
-		return nil, errors.New("paging request is nil")
+		return nil, errPagingRequestIsNil
 	}
 
 	if builder == nil {
-		return nil, errors.New("query builder is nil")
+		return nil, errQueryBuilderIsNil
 	}
 
 	whereSelectors, _, err := r.BuildListSelectorWithPaging(builder, req)
@@ -330,13 +375,13 @@ func (r *Repository[
 	entities, err := builder.All(ctx)
 	if err != nil {
 		log.Errorf("query list failed: %s", err.Error())
-		return nil, errors.New("query list failed")
+		return nil, errQueryListFailed
 	}
 
 	// 先把所有 ENTITY 映射为 DTO 列表
 	allDTOs := make([]*DTO, 0, len(entities))
 	for _, entity := range entities {
-		dto := r.mapper.ToDTO(entity)
+		dto := r.toDTO(entity)
 		allDTOs = append(allDTOs, dto)
 	}
 
@@ -376,7 +421,7 @@ func (r *Repository[
 		count, err = countBuilder.Count(ctx)
 		if err != nil {
 			log.Errorf("query count failed: %s", err.Error())
-			return nil, errors.New("query count failed")
+			return nil, errQueryCountFailed
 		}
 	}
 
@@ -400,11 +445,45 @@ func (r *Repository[
 	req *paginationV1.PagingRequest,
 ) (whereSelectors []func(s *sql.Selector), querySelectors []func(s *sql.Selector), err error) {
 	if req == nil {
-		return nil, nil, errors.New("paging request is nil")
+		return nil, nil, errPagingRequestIsNil
 	}
 
 	if builder == nil {
-		return nil, nil, errors.New("query builder is nil")
+		return nil, nil, errQueryBuilderIsNil
+	}
+
+	if req, err = r.applyPagingEdges(builder, req); err != nil {
+		return nil, nil, err
+	}
+
+	whereSelectors, querySelectors, err = r.buildListSelectors(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if querySelectors != nil {
+		builder.Modify(querySelectors...)
+	}
+
+	return whereSelectors, querySelectors, nil
+}
+
+// buildListSelectors 按分页请求构建过滤、字段、排序与分页 selectors，whereSelectors 仅包含过滤条件
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) buildListSelectors(
+	req *paginationV1.PagingRequest,
+) (whereSelectors []func(s *sql.Selector), querySelectors []func(s *sql.Selector), err error) {
+	if req == nil {
+		return nil, nil, errPagingRequestIsNil
+	}
+
+	if req, err = r.limitPolicy.ApplyPaging(r.entityName, req); err != nil {
+		return nil, nil, err
 	}
 
 	var sortingSelector func(s *sql.Selector)
@@ -468,10 +547,6 @@ func (r *Repository[
 		querySelectors = append(querySelectors, pagingSelector)
 	}
 
-	if querySelectors != nil {
-		builder.Modify(querySelectors...)
-	}
-
 	return whereSelectors, querySelectors, nil
 }
 
@@ -487,13 +562,22 @@ func (r *Repository[
 	builder ListBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	countBuilder ListBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	req *paginationV1.PaginationRequest,
-) (*PagingResult[DTO], error) {
+) (ret *PagingResult[DTO], err error) {
+	obs := r.observe("ListWithPagination")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Items)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPaginationRequest(req)
+
 	if req == nil {
-		return nil, errors.New("paginationV1 request is nil")
+		return nil, errPaginationRequestIsNil
 	}
 
 	if builder == nil {
-		return nil, errors.New("query builder is nil")
+		return nil, errQueryBuilderIsNil
 	}
 
 	whereSelectors, _, err := r.BuildListSelectorWithPagination(builder, req)
@@ -504,12 +588,12 @@ func (r *Repository[
 	entities, err := builder.All(ctx)
 	if err != nil {
 		log.Errorf("query list failed: %s", err.Error())
-		return nil, errors.New("query list failed")
+		return nil, errQueryListFailed
 	}
 
 	dtos := make([]*DTO, 0, len(entities))
 	for _, entity := range entities {
-		dto := r.mapper.ToDTO(entity)
+		dto := r.toDTO(entity)
 		dtos = append(dtos, dto)
 	}
 
@@ -521,7 +605,7 @@ func (r *Repository[
 		count, err = countBuilder.Count(ctx)
 		if err != nil {
 			log.Errorf("query count failed: %s", err.Error())
-			return nil, errors.New("query count failed")
+			return nil, errQueryCountFailed
 		}
 	}
 
@@ -545,13 +629,22 @@ func (r *Repository[
 	builder ListBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	countBuilder ListBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	req *paginationV1.PaginationRequest,
-) (*PagingResult[DTO], error) {
+) (ret *PagingResult[DTO], err error) {
+	obs := r.observe("ListTreeWithPagination")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Items)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPaginationRequest(req)
+
 	if req == nil {
-		return nil, errors.New("paging request is nil")
+		return nil, errPagingRequestIsNil
 	}
 
 	if builder == nil {
-		return nil, errors.New("query builder is nil")
+		return nil, errQueryBuilderIsNil
 	}
 
 	whereSelectors, _, err := r.BuildListSelectorWithPagination(builder, req)
@@ -562,13 +655,13 @@ func (r *Repository[
 	entities, err := builder.All(ctx)
 	if err != nil {
 		log.Errorf("query list failed: %s", err.Error())
-		return nil, errors.New("query list failed")
+		return nil, errQueryListFailed
 	}
 
 	// 先把所有 ENTITY 映射为 DTO 列表
 	allDTOs := make([]*DTO, 0, len(entities))
 	for _, entity := range entities {
-		dto := r.mapper.ToDTO(entity)
+		dto := r.toDTO(entity)
 		allDTOs = append(allDTOs, dto)
 	}
 
@@ -608,7 +701,7 @@ func (r *Repository[
 		count, err = countBuilder.Count(ctx)
 		if err != nil {
 			log.Errorf("query count failed: %s", err.Error())
-			return nil, errors.New("query count failed")
+			return nil, errQueryCountFailed
 		}
 	}
 
@@ -632,11 +725,19 @@ func (r *Repository[
 	req *paginationV1.PaginationRequest,
 ) (whereSelectors []func(s *sql.Selector), querySelectors []func(s *sql.Selector), err error) {
 	if req == nil {
-		return nil, nil, errors.New("paginationV1 request is nil")
+		return nil, nil, errPaginationRequestIsNil
 	}
 
 	if builder == nil {
-		return nil, nil, errors.New("query builder is nil")
+		return nil, nil, errQueryBuilderIsNil
+	}
+
+	if req, err = r.limitPolicy.ApplyPagination(r.entityName, req); err != nil {
+		return nil, nil, err
+	}
+
+	if req, err = r.applyPaginationEdges(builder, req); err != nil {
+		return nil, nil, err
 	}
 
 	var sortingSelector func(s *sql.Selector)
@@ -715,18 +816,30 @@ func (r *Repository[
 	builder QueryBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
 	viewMask *fieldmaskpb.FieldMask,
 	predicates ...func(s *sql.Selector),
-) (*DTO, error) {
+) (ret *DTO, err error) {
+	obs := r.observe("Get")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if builder == nil {
-		return nil, errors.New("query builder is nil")
+		return nil, errQueryBuilderIsNil
 	}
 
 	if len(predicates) > 0 {
 		builder.Modify(predicates...)
 	}
 
+	if viewMask, _, err = r.applyEdges(builder, viewMask, nil); err != nil {
+		return nil, err
+	}
+
 	field.NormalizeFieldMaskPaths(viewMask)
 
-	if viewMask != nil && len(viewMask.Paths) == 0 {
+	if viewMask != nil && len(viewMask.Paths) > 0 {
 		builder.Select(viewMask.GetPaths()...)
 	}
 
@@ -735,7 +848,7 @@ func (r *Repository[
 		return nil, err
 	}
 
-	return r.mapper.ToDTO(entity), nil
+	return r.toDTO(entity), nil
 }
 
 // Only 根据查询条件获取单条记录
@@ -767,13 +880,21 @@ func (r *Repository[
 	dto *DTO,
 	createMask *fieldmaskpb.FieldMask,
 	doCreateFieldFunc func(dto *DTO),
-) (*DTO, error) {
+) (ret *DTO, err error) {
+	obs := r.observe("Create")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if builder == nil {
-		return nil, errors.New("query builder is nil")
+		return nil, errQueryBuilderIsNil
 	}
 
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
 	field.NormalizeFieldMaskPaths(createMask)
@@ -781,7 +902,7 @@ func (r *Repository[
 	var dtoAny any = dto
 	var dtoProto = dtoAny.(proto.Message)
 	if dtoProto == nil {
-		return nil, errors.New("dto proto message is nil")
+		return nil, errDTOProtoMessageIsNil
 	}
 	if err := fieldmaskutil.FilterByFieldMask(trans.Ptr(dtoProto), createMask); err != nil {
 		log.Errorf("invalid field mask [%v], error: %s", createMask, err.Error())
@@ -814,13 +935,16 @@ func (r *Repository[
 	dto *DTO,
 	createMask *fieldmaskpb.FieldMask,
 	doCreateFieldFunc func(dto *DTO),
-) error {
+) (err error) {
+	obs := r.observe("CreateX")
+	defer func() { obs.End(err) }()
+
 	if builder == nil {
-		return errors.New("query builder is nil")
+		return errQueryBuilderIsNil
 	}
 
 	if dto == nil {
-		return errors.New("dto is nil")
+		return errDTOIsNil
 	}
 
 	field.NormalizeFieldMaskPaths(createMask)
@@ -828,7 +952,7 @@ func (r *Repository[
 	var dtoAny any = dto
 	var dtoProto = dtoAny.(proto.Message)
 	if dtoProto == nil {
-		return errors.New("dto proto message is nil")
+		return errDTOProtoMessageIsNil
 	}
 	if err := fieldmaskutil.FilterByFieldMask(trans.Ptr(dtoProto), createMask); err != nil {
 		log.Errorf("invalid field mask [%v], error: %s", createMask, err.Error())
@@ -860,12 +984,18 @@ func (r *Repository[
 	dtos []*DTO,
 	createMask *fieldmaskpb.FieldMask,
 	doCreateFieldFunc func(dto *DTO),
-) ([]*DTO, error) {
+) (ret []*DTO, err error) {
+	obs := r.observe("BatchCreate")
+	defer func() {
+		obs.AffectedRows(int64(len(ret)))
+		obs.End(err)
+	}()
+
 	if builder == nil {
-		return nil, errors.New("query builder is nil")
+		return nil, errQueryBuilderIsNil
 	}
 	if len(dtos) == 0 {
-		return nil, errors.New("dtos is empty")
+		return nil, errDTOsIsEmpty
 	}
 
 	field.NormalizeFieldMaskPaths(createMask)
@@ -917,13 +1047,21 @@ func (r *Repository[
 	updateMask *fieldmaskpb.FieldMask,
 	doUpdateFieldFunc func(dto *DTO),
 	predicates ...PREDICATE,
-) (*DTO, error) {
+) (ret *DTO, err error) {
+	obs := r.observe("UpdateOne")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if builder == nil {
-		return nil, errors.New("query builder is nil")
+		return nil, errQueryBuilderIsNil
 	}
 
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
 	if len(predicates) > 0 {
@@ -935,7 +1073,7 @@ func (r *Repository[
 	var dtoAny any = dto
 	var dtoProto = dtoAny.(proto.Message)
 	if dtoProto == nil {
-		return nil, errors.New("dto proto message is nil")
+		return nil, errDTOProtoMessageIsNil
 	}
 	if err := fieldmaskutil.FilterByFieldMask(trans.Ptr(dtoProto), updateMask); err != nil {
 		log.Errorf("invalid field mask [%v], error: %s", updateMask, err.Error())
@@ -948,7 +1086,6 @@ func (r *Repository[
 
 	r.applyUpdateOneNilFieldMask(dtoProto, updateMask, builder)
 
-	var err error
 	var entity *ENTITY
 	if entity, err = builder.Save(ctx); err != nil {
 		log.Errorf("update one data failed: %s", err.Error())
@@ -1028,13 +1165,20 @@ func (r *Repository[
 	updateMask *fieldmaskpb.FieldMask,
 	doUpdateFieldFunc func(dto *DTO),
 	predicates ...PREDICATE,
-) error {
+) (err error) {
+	obs := r.observe("UpdateX")
+	defer func() { obs.End(err) }()
+
 	if builder == nil {
-		return errors.New("query builder is nil")
+		return errQueryBuilderIsNil
 	}
 
 	if dto == nil {
-		return errors.New("dto is nil")
+		return errDTOIsNil
+	}
+
+	if err := r.checkGuardFilter(builder, len(predicates)); err != nil {
+		return err
 	}
 
 	if len(predicates) > 0 {
@@ -1046,7 +1190,7 @@ func (r *Repository[
 	var dtoAny any = dto
 	var dtoProto = dtoAny.(proto.Message)
 	if dtoProto == nil {
-		return errors.New("dto proto message is nil")
+		return errDTOProtoMessageIsNil
 	}
 	if err := fieldmaskutil.FilterByFieldMask(trans.Ptr(dtoProto), updateMask); err != nil {
 		log.Errorf("invalid field mask [%v], error: %s", updateMask, err.Error())
@@ -1059,8 +1203,10 @@ func (r *Repository[
 
 	r.applyUpdateNilFieldMask(dtoProto, updateMask, builder)
 
-	if err := builder.Exec(ctx); err != nil {
-		log.Errorf("update one data failed: %s", err.Error())
+	if _, err = r.execWithGuard(ctx, builder.Save); err != nil {
+		if !isGuardError(err) {
+			log.Errorf("update one data failed: %s", err.Error())
+		}
 		return err
 	}
 
@@ -1078,9 +1224,19 @@ func (r *Repository[
 	ctx context.Context,
 	builder DeleteBuilder[ENT_DELETE, PREDICATE],
 	predicates ...PREDICATE,
-) (int, error) {
+) (ret int, err error) {
+	obs := r.observe("Delete")
+	defer func() {
+		obs.AffectedRows(int64(ret))
+		obs.End(err)
+	}()
+
 	if builder == nil {
-		return 0, errors.New("query builder is nil")
+		return 0, errQueryBuilderIsNil
+	}
+
+	if err := r.checkGuardFilter(builder, len(predicates)); err != nil {
+		return 0, err
 	}
 
 	if len(predicates) > 0 {
@@ -1088,11 +1244,205 @@ func (r *Repository[
 	}
 
 	var affected int
-	var err error
-	if affected, err = builder.Exec(ctx); err != nil {
+	if affected, err = r.execWithGuard(ctx, builder.Exec); err != nil {
+		if isGuardError(err) {
+			return 0, err
+		}
 		log.Errorf("delete failed: %s", err.Error())
-		return 0, errors.New("delete failed")
+		return 0, errDeleteFailed
 	}
 
 	return affected, nil
 }
+
+// UpdateByFilter 根据 FilterExpr 批量更新记录，返回受影响行数。
+// builder 需由调用方预先设置好要更新的字段（ent 的 Set 方法为强类型），此处负责应用过滤条件与 updateMask 置空字段。
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) UpdateByFilter(
+	ctx context.Context,
+	builder UpdateBuilder[ENT_UPDATE, PREDICATE],
+	filterExpr *paginationV1.FilterExpr,
+	dto *DTO,
+	updateMask *fieldmaskpb.FieldMask,
+	doUpdateFieldFunc func(dto *DTO),
+) (ret int, err error) {
+	obs := r.observe("UpdateByFilter")
+	defer func() {
+		obs.AffectedRows(int64(ret))
+		obs.End(err)
+	}()
+	obs.SetFilterExpr(filterExpr)
+
+	if builder == nil {
+		return 0, errQueryBuilderIsNil
+	}
+
+	if dto == nil {
+		return 0, errDTOIsNil
+	}
+
+	predicates, err := r.buildFilterPredicates(filterExpr)
+	if err != nil {
+		return 0, err
+	}
+	if err = r.checkGuardFilter(builder, len(predicates)); err != nil {
+		return 0, err
+	}
+	if len(predicates) > 0 {
+		builder.Where(predicates...)
+	}
+
+	field.NormalizeFieldMaskPaths(updateMask)
+
+	var dtoAny any = dto
+	var dtoProto = dtoAny.(proto.Message)
+	if dtoProto == nil {
+		return 0, errDTOProtoMessageIsNil
+	}
+	if err = fieldmaskutil.FilterByFieldMask(trans.Ptr(dtoProto), updateMask); err != nil {
+		log.Errorf("invalid field mask [%v], error: %s", updateMask, err.Error())
+		return 0, err
+	}
+
+	if doUpdateFieldFunc != nil {
+		doUpdateFieldFunc(dto)
+	}
+
+	r.applyUpdateNilFieldMask(dtoProto, updateMask, builder)
+
+	var affected int
+	if affected, err = r.execWithGuard(ctx, builder.Save); err != nil {
+		if isGuardError(err) {
+			return 0, err
+		}
+		log.Errorf("update by filter failed: %s", err.Error())
+		return 0, errUpdateByFilterFailed
+	}
+
+	return affected, nil
+}
+
+// DeleteByFilter 根据 FilterExpr 删除记录，返回受影响行数。
+// soft 为 true 时使用 softDeleteBuilder 将 deleted_at 置为当前时间，否则使用 deleteBuilder 物理删除；未使用的 builder 可为 nil
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) DeleteByFilter(
+	ctx context.Context,
+	deleteBuilder DeleteBuilder[ENT_DELETE, PREDICATE],
+	softDeleteBuilder UpdateBuilder[ENT_UPDATE, PREDICATE],
+	filterExpr *paginationV1.FilterExpr,
+	soft bool,
+) (ret int, err error) {
+	obs := r.observe("DeleteByFilter")
+	defer func() {
+		obs.AffectedRows(int64(ret))
+		obs.End(err)
+	}()
+	obs.SetFilterExpr(filterExpr)
+
+	if soft {
+		return r.softDeleteByFilter(ctx, softDeleteBuilder, filterExpr)
+	}
+
+	if deleteBuilder == nil {
+		return 0, errQueryBuilderIsNil
+	}
+
+	predicates, err := r.buildFilterPredicates(filterExpr)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.Delete(ctx, deleteBuilder, predicates...)
+}
+
+// softDeleteByFilter 根据 FilterExpr 软删除记录（将 deleted_at 置为当前时间），返回受影响行数
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) softDeleteByFilter(
+	ctx context.Context,
+	builder UpdateBuilder[ENT_UPDATE, PREDICATE],
+	filterExpr *paginationV1.FilterExpr,
+) (int, error) {
+	if builder == nil {
+		return 0, errQueryBuilderIsNil
+	}
+
+	predicates, err := r.buildFilterPredicates(filterExpr)
+	if err != nil {
+		return 0, err
+	}
+	if err = r.checkGuardFilter(builder, len(predicates)); err != nil {
+		return 0, err
+	}
+	if len(predicates) > 0 {
+		builder.Where(predicates...)
+	}
+
+	builder.Modify(func(u *sql.UpdateBuilder) {
+		u.Set(SoftDeleteField, time.Now())
+	})
+
+	var affected int
+	if affected, err = r.execWithGuard(ctx, builder.Save); err != nil {
+		if isGuardError(err) {
+			return 0, err
+		}
+		log.Errorf("soft delete by filter failed: %s", err.Error())
+		return 0, errSoftDeleteByFilterFailed
+	}
+
+	return affected, nil
+}
+
+// buildFilterPredicates 将 FilterExpr 构建为 ent 生成的谓词（如 predicate.User）
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) buildFilterPredicates(filterExpr *paginationV1.FilterExpr) ([]PREDICATE, error) {
+	if err := r.limitPolicy.CheckFilterExpr(r.entityName, filterExpr); err != nil {
+		return nil, err
+	}
+
+	selectors, err := r.structuredFilter.BuildSelectors(filterExpr)
+	if err != nil {
+		log.Errorf("build structured filter selectors failed: %s", err.Error())
+		return nil, err
+	}
+
+	return ToPredicates[PREDICATE](selectors)
+}
+
+// ToPredicates 将 func(*sql.Selector) 转换为 ent 生成的谓词类型，二者底层类型一致
+func ToPredicates[PREDICATE any](selectors []func(s *sql.Selector)) ([]PREDICATE, error) {
+	predicateType := reflect.TypeOf((*PREDICATE)(nil)).Elem()
+
+	predicates := make([]PREDICATE, 0, len(selectors))
+	for _, s := range selectors {
+		if s == nil {
+			continue
+		}
+		v := reflect.ValueOf(s)
+		if !v.Type().ConvertibleTo(predicateType) {
+			return nil, fmt.Errorf("selector can not convert to predicate type %s", predicateType)
+		}
+		predicates = append(predicates, v.Convert(predicateType).Interface().(PREDICATE))
+	}
+	return predicates, nil
+}
@@ -0,0 +1,188 @@
+package entgo
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"github.com/go-kratos/kratos/v2/log"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/search"
+)
+
+// selectValuer ent 生成的实体通过 Value 读取经 Modify 附加选择的列
+type selectValuer interface {
+	Value(name string) (ent.Value, error)
+}
+
+// Search 全文检索：在 PagingRequest 的过滤、排序与分页之上按 sreq 匹配，返回相关度分数与高亮片段。
+// Postgres 使用 tsvector，MySQL 使用 FULLTEXT 索引（高亮在内存中生成），SQLite 使用 FTS5 虚拟表。
+func (r *Repository[
+	ENT_QUERY, ENT_SELECT,
+	ENT_CREATE, ENT_CREATE_BULK,
+	ENT_UPDATE, ENT_UPDATE_ONE,
+	ENT_DELETE,
+	PREDICATE, DTO, ENTITY,
+]) Search(
+	ctx context.Context,
+	builder ListBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
+	countBuilder ListBuilder[ENT_QUERY, ENT_SELECT, ENTITY],
+	req *paginationV1.PagingRequest,
+	sreq *search.Request,
+) (ret *search.Result[DTO], err error) {
+	obs := r.observe("Search")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Hits)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPagingRequest(req)
+
+	if req == nil {
+		return nil, errPagingRequestIsNil
+	}
+	if builder == nil {
+		return nil, errQueryBuilderIsNil
+	}
+	if err = sreq.Validate(); err != nil {
+		return nil, err
+	}
+
+	matchSelector, orderSelector, columnSelector := SearchSelectors(sreq)
+
+	// 相关度排序需在 PagingRequest 的排序之前加入，附加列需在字段选择之后加入
+	builder.Modify(matchSelector, orderSelector)
+	whereSelectors, _, err := r.BuildListSelectorWithPaging(builder, req)
+	if err != nil {
+		return nil, err
+	}
+	builder.Modify(columnSelector)
+
+	entities, err := builder.All(ctx)
+	if err != nil {
+		log.Errorf("search query failed: %s", err.Error())
+		return nil, errSearchQueryFailed
+	}
+
+	hits := make([]*search.Hit[DTO], 0, len(entities))
+	for _, entity := range entities {
+		dto := r.toDTO(entity)
+		hit := &search.Hit[DTO]{Item: dto}
+
+		var highlighted bool
+		if v, ok := any(entity).(selectValuer); ok {
+			if score, verr := v.Value(search.ScoreColumn); verr == nil {
+				hit.Score = toFloat(score)
+			}
+			if h, verr := v.Value(search.HighlightColumn); verr == nil {
+				hit.Highlights = search.DecodeHighlights(toString(h))
+				highlighted = true
+			}
+		}
+		if !highlighted {
+			hit.Highlights = search.HighlightItem(dto, sreq)
+		}
+
+		hits = append(hits, hit)
+	}
+
+	var count int
+	if countBuilder != nil {
+		countBuilder.Modify(append([]func(s *sql.Selector){matchSelector}, whereSelectors...)...)
+		count, err = countBuilder.Count(ctx)
+		if err != nil {
+			log.Errorf("query count failed: %s", err.Error())
+			return nil, errQueryCountFailed
+		}
+	}
+
+	return &search.Result[DTO]{Hits: hits, Total: uint64(count)}, nil
+}
+
+// SearchSelectors 返回全文检索的匹配条件、相关度排序与附加列（分数、高亮）selectors，方言与表名取自 Selector
+func SearchSelectors(sreq *search.Request) (match, order, columns func(s *sql.Selector)) {
+	build := func(s *sql.Selector) *search.SQL {
+		ss, err := search.BuildSQL(s.Dialect(), s.TableName(), sreq)
+		if err != nil {
+			s.AddError(err)
+			return nil
+		}
+		return ss
+	}
+
+	match = func(s *sql.Selector) {
+		if ss := build(s); ss != nil {
+			s.Where(sql.P(func(b *sql.Builder) {
+				writeExpr(b, ss.Where, ss.WhereArgs)
+			}))
+		}
+	}
+
+	order = func(s *sql.Selector) {
+		if sreq.SortByRelevance {
+			s.OrderExprFunc(func(b *sql.Builder) {
+				b.Ident(search.ScoreColumn).WriteString(" DESC")
+			})
+		}
+	}
+
+	columns = func(s *sql.Selector) {
+		ss := build(s)
+		if ss == nil {
+			return
+		}
+		s.AppendSelectExprAs(sql.ExprFunc(func(b *sql.Builder) {
+			writeExpr(b, ss.Score, ss.ScoreArgs)
+		}), search.ScoreColumn)
+		if ss.Highlight != "" {
+			s.AppendSelectExprAs(sql.ExprFunc(func(b *sql.Builder) {
+				writeExpr(b, ss.Highlight, ss.HighlightArgs)
+			}), search.HighlightColumn)
+		}
+	}
+
+	return match, order, columns
+}
+
+// writeExpr 将以 ? 占位的表达式写入 Builder，参数按方言生成占位符（Postgres 为 $n）
+func writeExpr(b *sql.Builder, expr string, args []any) {
+	parts := strings.Split(expr, "?")
+	for i, p := range parts {
+		b.WriteString(p)
+		if i < len(parts)-1 && i < len(args) {
+			b.Arg(args[i])
+		}
+	}
+}
+
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case []byte:
+		f, _ := strconv.ParseFloat(string(n), 64)
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+func toString(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package entgo
+
+import (
+	"strings"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+
+	"github.com/tx7do/go-crud/search"
+)
+
+func TestSearchSelectors(t *testing.T) {
+	sreq := &search.Request{
+		Query:           "go crud",
+		Fields:          []search.Field{{Name: "name", Weight: 2}, {Name: "bio"}},
+		Language:        "english",
+		SortByRelevance: true,
+		Highlight:       &search.Highlight{},
+	}
+	match, order, columns := SearchSelectors(sreq)
+
+	b := sql.Dialect(dialect.Postgres)
+	s := b.Select(b.Table("users").C("id")).From(b.Table("users"))
+	match(s)
+	order(s)
+	columns(s)
+	query, args := s.Query()
+	if err := s.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		`@@ websearch_to_tsquery($16::regconfig, $17)`,
+		`AS "_search_score"`,
+		`AS "_search_highlights"`,
+		`ORDER BY "_search_score" DESC`,
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("expected to contain %q, got %q", want, query)
+		}
+	}
+	if strings.Contains(query, "?") {
+		t.Errorf("placeholders must be rewritten for postgres, got %q", query)
+	}
+	if n := strings.Count(query, "$"); n != len(args) {
+		t.Errorf("expected %d placeholders, got %d", len(args), n)
+	}
+
+	bad := sql.Dialect(dialect.Gremlin).Select("*").From(sql.Table("users"))
+	match(bad)
+	if bad.Err() == nil {
+		t.Error("expected error for unsupported dialect")
+	}
+}
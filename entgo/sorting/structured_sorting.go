@@ -2,28 +2,65 @@ package sorting
 
 import (
 	"entgo.io/ent/dialect/sql"
+
 	pagination "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/ordering"
 )
 
+// DefaultTieBreaker 默认的兜底排序字段
+const DefaultTieBreaker = "id"
+
 type StructuredSorting struct {
+	tieBreaker string
 }
 
 func NewStructuredSorting() *StructuredSorting {
-	return &StructuredSorting{}
+	return &StructuredSorting{tieBreaker: DefaultTieBreaker}
+}
+
+// SetTieBreaker 设置兜底排序字段（升序追加在排序指令末尾以保证分页稳定），为空则不追加
+func (ss *StructuredSorting) SetTieBreaker(field string) *StructuredSorting {
+	ss.tieBreaker = field
+	return ss
 }
 
+// BuildSelector 构建排序选择器，支持空值位置、忽略大小写、排序规则、JSON 路径与日期部分；
+// 排序指令非法时返回错误。
 func (ss StructuredSorting) BuildSelector(orders []*pagination.Sorting) (func(s *sql.Selector), error) {
 	if len(orders) == 0 {
 		return nil, nil
 	}
 
+	for _, order := range orders {
+		if order == nil || order.GetField() == "" {
+			continue
+		}
+		if _, err := ordering.Key("", order.GetField(), order); err != nil {
+			return nil, err
+		}
+	}
+
+	orders = ordering.WithTieBreaker(orders, ss.tieBreaker)
+
 	return func(s *sql.Selector) {
 		for _, order := range orders {
 			if order == nil || order.GetField() == "" {
 				continue
 			}
 
-			buildOrderBySelector(s, order.Field, order.GetOrder() == pagination.Sorting_DESC)
+			if ordering.Plain(order) && order.GetNulls() == pagination.Sorting_NULLS_DEFAULT {
+				buildOrderBySelector(s, order.Field, order.GetOrder() == pagination.Sorting_DESC)
+				continue
+			}
+
+			clauses, err := ordering.Clauses(s.Dialect(), s.C(order.GetField()), order)
+			if err != nil {
+				s.AddError(err)
+				return
+			}
+			for _, c := range clauses {
+				s.OrderExpr(sql.Expr(c))
+			}
 		}
 	}, nil
 }
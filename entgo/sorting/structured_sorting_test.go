@@ -4,7 +4,9 @@ import (
 	"strings"
 	"testing"
 
+	"entgo.io/ent/dialect"
 	"entgo.io/ent/dialect/sql"
+	"google.golang.org/protobuf/proto"
 
 	pagination "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
 )
@@ -94,3 +96,56 @@ func TestStructuredSorting_BuildSelectorWithDefaultField(t *testing.T) {
 		t.Fatalf("expected ORDER BY score DESC, got: %s", sqlStr2)
 	}
 }
+
+func TestStructuredSorting_BuildSelector_Rich(t *testing.T) {
+	ss := NewStructuredSorting()
+
+	selFunc, err := ss.BuildSelector([]*pagination.Sorting{
+		{Field: "name", Order: pagination.Sorting_DESC, Nulls: pagination.Sorting_NULLS_LAST, CaseInsensitive: true},
+		{Field: "metadata", JsonPath: proto.String("priority")},
+		{Field: "created_at", DatePart: pagination.DatePart_MONTH},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("t"))
+	selFunc(s)
+	sqlStr, _ := s.Query()
+	want := `ORDER BY LOWER("t"."name") DESC NULLS LAST, ("t"."metadata" #>> '{priority}') ASC, EXTRACT(MONTH FROM "t"."created_at") ASC, "t"."id" ASC`
+	if !strings.HasSuffix(sqlStr, want) {
+		t.Fatalf("expected suffix %q, got: %s", want, sqlStr)
+	}
+
+	s = sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("t"))
+	selFunc(s)
+	sqlStr, _ = s.Query()
+	if !strings.Contains(sqlStr, "ORDER BY CASE WHEN LOWER(`t`.`name`) IS NULL THEN 1 ELSE 0 END ASC, LOWER(`t`.`name`) DESC") {
+		t.Fatalf("expected emulated NULLS LAST, got: %s", sqlStr)
+	}
+}
+
+func TestStructuredSorting_BuildSelector_TieBreaker(t *testing.T) {
+	selFunc, _ := NewStructuredSorting().BuildSelector([]*pagination.Sorting{{Field: "id", Order: pagination.Sorting_DESC}})
+	s := sql.Select("*").From(sql.Table("t"))
+	selFunc(s)
+	if sqlStr, _ := s.Query(); strings.Count(sqlStr, "`id`") != 1 {
+		t.Fatalf("did not expect duplicated id ordering, got: %s", sqlStr)
+	}
+
+	selFunc, _ = NewStructuredSorting().SetTieBreaker("").BuildSelector([]*pagination.Sorting{{Field: "name"}})
+	s = sql.Select("*").From(sql.Table("t"))
+	selFunc(s)
+	if sqlStr, _ := s.Query(); strings.Contains(sqlStr, "`id`") {
+		t.Fatalf("did not expect tie-breaker, got: %s", sqlStr)
+	}
+}
+
+func TestStructuredSorting_BuildSelector_Invalid(t *testing.T) {
+	_, err := NewStructuredSorting().BuildSelector([]*pagination.Sorting{
+		{Field: "metadata", JsonPath: proto.String("a'); DROP TABLE t; --")},
+	})
+	if err == nil {
+		t.Fatal("expected error for invalid json path")
+	}
+}
@@ -0,0 +1,87 @@
+package explain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Query 描述一次列表请求生成的查询，用于排查慢查询或结果不符合预期的问题。
+// ToQuery 只构建不执行；Explain 额外填充数据库返回的执行计划，同样不会执行读取。
+type Query struct {
+	// Statement 列表查询语句：SQL/InfluxQL；MongoDB 为 find 命令的 Extended JSON、Elasticsearch 为 _search 请求行
+	Statement string
+	// Args 语句参数（按占位符顺序）
+	Args []any
+
+	// CountStatement 计数语句
+	CountStatement string
+	// CountArgs 计数语句参数
+	CountArgs []any
+
+	// Plan 数据库返回的执行计划，仅 Explain 填充
+	Plan string
+}
+
+// String 以便于日志输出的格式返回查询内容
+func (q *Query) String() string {
+	if q == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(q.Statement)
+	if len(q.Args) > 0 {
+		sb.WriteString(fmt.Sprintf("\n-- args: %v", q.Args))
+	}
+	if q.CountStatement != "" {
+		sb.WriteString("\n")
+		sb.WriteString(q.CountStatement)
+		if len(q.CountArgs) > 0 {
+			sb.WriteString(fmt.Sprintf("\n-- args: %v", q.CountArgs))
+		}
+	}
+	if q.Plan != "" {
+		sb.WriteString("\n-- plan:\n")
+		sb.WriteString(q.Plan)
+	}
+	return sb.String()
+}
+
+// FormatTable 将 EXPLAIN 返回的行格式化为文本：单列结果逐行输出，多列结果首行为列名、列之间以制表符分隔
+func FormatTable(columns []string, rows [][]any) string {
+	var sb strings.Builder
+
+	if len(columns) > 1 {
+		sb.WriteString(strings.Join(columns, "\t"))
+		sb.WriteString("\n")
+	}
+
+	for i, row := range rows {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		for j, v := range row {
+			if j > 0 {
+				sb.WriteString("\t")
+			}
+			sb.WriteString(formatValue(v))
+		}
+	}
+	return sb.String()
+}
+
+func formatValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(t)
+	case *string:
+		if t == nil {
+			return "NULL"
+		}
+		return *t
+	default:
+		return fmt.Sprint(t)
+	}
+}
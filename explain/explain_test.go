@@ -0,0 +1,39 @@
+package explain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatTable(t *testing.T) {
+	single := FormatTable([]string{"explain"}, [][]any{{"Expression"}, {"  ReadFromMergeTree"}})
+	if single != "Expression\n  ReadFromMergeTree" {
+		t.Errorf("single column: got %q", single)
+	}
+
+	multi := FormatTable([]string{"id", "detail"}, [][]any{{int64(2), []byte("SCAN users")}, {3, nil}})
+	if multi != "id\tdetail\n2\tSCAN users\n3\tNULL" {
+		t.Errorf("multi column: got %q", multi)
+	}
+}
+
+func TestQuery_String(t *testing.T) {
+	var nilQuery *Query
+	if nilQuery.String() != "" {
+		t.Errorf("nil query: expected empty string")
+	}
+
+	q := &Query{
+		Statement:      "SELECT * FROM users WHERE id = ?",
+		Args:           []any{1},
+		CountStatement: "SELECT COUNT(*) FROM users WHERE id = ?",
+		CountArgs:      []any{1},
+		Plan:           "SCAN users",
+	}
+	s := q.String()
+	for _, want := range []string{q.Statement, q.CountStatement, "-- args: [1]", "-- plan:\nSCAN users"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("String(): expected to contain %q, got %q", want, s)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package gorm
+
+import "errors"
+
+// 仓库自身返回的错误，在 init 中按分类注册到 metrics，作为 class 标签
+var (
+	// 参数缺失或不合法
+	errDBIsNil                = errors.New("db is nil")
+	errDTOIsNil               = errors.New("dto is nil")
+	errPaginationRequestIsNil = errors.New("pagination request is nil")
+	errPagingRequestIsNil     = errors.New("paging request is nil")
+
+	// 底层存储执行失败，原始错误已记录日志
+	errBatchCreateFailed       = errors.New("batch create failed")
+	errCreateFailed            = errors.New("create failed")
+	errExistsQueryFailed       = errors.New("exists query failed")
+	errExplainQueryFailed      = errors.New("explain query failed")
+	errHistogramQueryFailed    = errors.New("histogram query failed")
+	errParseEntitySchemaFailed = errors.New("parse entity schema failed")
+	errQueryCountFailed        = errors.New("query count failed")
+	errQueryListFailed         = errors.New("query list failed")
+	errScanHistogramRowFailed  = errors.New("scan histogram row failed")
+	errSearchQueryFailed       = errors.New("search query failed")
+	errUpsertFailed            = errors.New("upsert failed")
+)
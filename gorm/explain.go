@@ -0,0 +1,88 @@
+package gorm
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/explain"
+)
+
+// ToQuery 返回 ListWithPaging 针对 req 生成的列表与计数 SQL 及参数（DryRun，不访问数据库）
+func (r *Repository[DTO, ENTITY]) ToQuery(ctx context.Context, db *gorm.DB, req *paginationV1.PagingRequest) (*explain.Query, error) {
+	listDB, whereSelectors, err := r.buildPagingDB(ctx, db, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var entities []*ENTITY
+	listStmt := listDB.Session(&gorm.Session{DryRun: true}).Find(&entities).Statement
+
+	countDB := db.WithContext(ctx).Model(new(ENTITY))
+	for _, s := range whereSelectors {
+		if s != nil {
+			countDB = s(countDB)
+		}
+	}
+	var cnt int64
+	countStmt := countDB.Session(&gorm.Session{DryRun: true}).Count(&cnt).Statement
+
+	if listStmt.Error != nil {
+		return nil, listStmt.Error
+	}
+	if countStmt.Error != nil {
+		return nil, countStmt.Error
+	}
+
+	return &explain.Query{
+		Statement:      listStmt.SQL.String(),
+		Args:           listStmt.Vars,
+		CountStatement: countStmt.SQL.String(),
+		CountArgs:      countStmt.Vars,
+	}, nil
+}
+
+// Explain 在 ToQuery 的基础上对列表 SQL 执行 EXPLAIN，返回数据库的执行计划（不执行列表查询本身）
+func (r *Repository[DTO, ENTITY]) Explain(ctx context.Context, db *gorm.DB, req *paginationV1.PagingRequest) (*explain.Query, error) {
+	q, err := r.ToQuery(ctx, db, req)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.WithContext(ctx).Raw("EXPLAIN "+q.Statement, q.Args...).Rows()
+	if err != nil {
+		log.Errorf("explain query failed: %s", err.Error())
+		return nil, errExplainQueryFailed
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			log.Errorf("failed to close rows: %s", cerr.Error())
+		}
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][]any
+	for rows.Next() {
+		row := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err = rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		values = append(values, row)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	q.Plan = explain.FormatTable(columns, values)
+	return q, nil
+}
@@ -36,7 +36,14 @@ func (poc Processor) Process(db *gorm.DB, op pagination.Operator, field, value s
 		return db
 	}
 	// 将 field 转为 snake_case（与 DB 列风格一致）
-	field = stringcase.ToSnakeCase(field)
+	return poc.ProcessColumn(db, op, stringcase.ToSnakeCase(field), value, values)
+}
+
+// ProcessColumn 与 Process 相同，但 field 按原样使用（适用于已带表别名或引号的列表达式）
+func (poc Processor) ProcessColumn(db *gorm.DB, op pagination.Operator, field, value string, values []string) *gorm.DB {
+	if db == nil {
+		return db
+	}
 
 	switch op {
 	case pagination.Operator_EQ:
@@ -21,10 +21,15 @@ const (
 	JsonFieldDelimiter = "."  // JSONB字段分隔符
 )
 
+// ColumnResolver 将字段解析为完整的列表达式（如关联字段 "department.name" -> "Department"."name"）；
+// ok 为 false 时按原有规则（snake_case 列或 JSON 字段）处理
+type ColumnResolver func(field string) (column string, ok bool)
+
 // QueryStringFilter 字符串过滤器 (GORM 版)
 type QueryStringFilter struct {
 	codec     encoding.Codec
 	processor *Processor
+	resolver  ColumnResolver
 }
 
 func NewQueryStringFilter() *QueryStringFilter {
@@ -34,6 +39,12 @@ func NewQueryStringFilter() *QueryStringFilter {
 	}
 }
 
+// WithColumnResolver 返回使用 resolver 解析字段的副本，原实例不受影响
+func (sf QueryStringFilter) WithColumnResolver(resolver ColumnResolver) *QueryStringFilter {
+	sf.resolver = resolver
+	return &sf
+}
+
 // BuildSelectors 构建可应用于 *gorm.DB 的过滤闭包 slice
 func (sf QueryStringFilter) BuildSelectors(andFilterJsonString, orFilterJsonString string) ([]func(*gorm.DB) *gorm.DB, error) {
 	var selectors []func(*gorm.DB) *gorm.DB
@@ -206,8 +217,25 @@ func (sf QueryStringFilter) MakeFieldFilter(keys []string, value string) func(*g
 		}
 	}
 
+	// 由 resolver 解析的字段（如关联字段）
+	resolved, isResolved := "", false
+	if sf.resolver != nil {
+		resolved, isResolved = sf.resolver(field)
+	}
+	handleColumn := func(op pagination.Operator) func(*gorm.DB) *gorm.DB {
+		return func(db *gorm.DB) *gorm.DB {
+			if db == nil {
+				return db
+			}
+			return sf.processor.ProcessColumn(db, op, resolved, value, nil)
+		}
+	}
+
 	// 单字段（默认等于）
 	if len(keys) == 1 {
+		if isResolved {
+			return handleColumn(pagination.Operator_EQ)
+		}
 		if sf.isJsonFieldKey(field) {
 			parts := sf.splitJsonFieldKey(field)
 			col := stringcase.ToSnakeCase(parts[0])
@@ -225,6 +253,9 @@ func (sf QueryStringFilter) MakeFieldFilter(keys []string, value string) func(*g
 		if !ok {
 			return nil
 		}
+		if isResolved {
+			return handleColumn(op)
+		}
 		if sf.isJsonFieldKey(field) {
 			parts := sf.splitJsonFieldKey(field)
 			col := stringcase.ToSnakeCase(parts[0])
@@ -248,6 +279,15 @@ func (sf QueryStringFilter) MakeFieldFilter(keys []string, value string) func(*g
 			if !ok {
 				return nil
 			}
+			if isResolved {
+				return func(db *gorm.DB) *gorm.DB {
+					if db == nil {
+						return db
+					}
+					db = sf.processor.DatePart(db, datePart, resolved)
+					return sf.processor.ProcessColumn(db, op, resolved, value, nil)
+				}
+			}
 			// 对 json 字段先提取 json expr 再按 date 部分处理
 			if sf.isJsonFieldKey(field) {
 				parts := sf.splitJsonFieldKey(field)
@@ -287,6 +327,16 @@ func (sf QueryStringFilter) MakeFieldFilter(keys []string, value string) func(*g
 			if !ok {
 				return nil
 			}
+			if isResolved {
+				return func(db *gorm.DB) *gorm.DB {
+					if db == nil {
+						return db
+					}
+					return db.Not(func(tx *gorm.DB) *gorm.DB {
+						return handleColumn(op)(tx)
+					})
+				}
+			}
 			// 使用 Processor.Process 然后 wrap 为 NOT 通过 gorm.Not
 			if sf.isJsonFieldKey(field) {
 				parts := sf.splitJsonFieldKey(field)
@@ -18,6 +18,7 @@ import (
 type StructuredFilter struct {
 	codec     encoding.Codec
 	processor *Processor
+	resolver  ColumnResolver
 }
 
 func NewStructuredFilter() *StructuredFilter {
@@ -27,6 +28,12 @@ func NewStructuredFilter() *StructuredFilter {
 	}
 }
 
+// WithColumnResolver 返回使用 resolver 解析字段的副本，原实例不受影响
+func (sf StructuredFilter) WithColumnResolver(resolver ColumnResolver) *StructuredFilter {
+	sf.resolver = resolver
+	return &sf
+}
+
 // BuildSelectors 将 FilterExpr 转为一组可应用于 *gorm.DB 的闭包
 func (sf StructuredFilter) BuildSelectors(expr *pagination.FilterExpr) ([]func(*gorm.DB) *gorm.DB, error) {
 	var sels []func(*gorm.DB) *gorm.DB
@@ -73,6 +80,13 @@ func (sf StructuredFilter) buildFilterSelector(expr *pagination.FilterExpr) (fun
 			val = *cond.Value
 		}
 
+		// 由 resolver 解析的字段（如关联字段）按原样使用
+		if sf.resolver != nil {
+			if col, ok := sf.resolver(cond.GetField()); ok {
+				return sf.processor.ProcessColumn(db, cond.GetOp(), col, val, cond.GetValues())
+			}
+		}
+
 		// 支持 JSON 字段 (e.g. preferences.daily_email)
 		if strings.Contains(cond.GetField(), ".") {
 			parts := strings.SplitN(cond.GetField(), ".", 2)
@@ -0,0 +1,125 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/tx7do/go-crud/guard"
+)
+
+// SetGuardOptions 设置批量更新/删除的安全保护。
+// 默认拒绝不带 WHERE 条件（且实体无主键值）的更新与删除；
+// MaxAffectedRows 大于 0 时写操作在事务中执行，受影响行数超出上限则回滚并返回 guard.ErrTooManyRowsAffected。
+func (r *Repository[DTO, ENTITY]) SetGuardOptions(opts guard.Options) {
+	r.guardOptions = opts
+}
+
+// applyGuardFilter 校验查询是否带有 WHERE 条件。
+// 未带条件但实体主键非零时，显式追加主键条件：Model 与 Updates 的目标不是同一对象时，GORM 不会自动按主键过滤。
+// 既无条件也无主键时由 guardOptions 决定是否允许全表操作，出错时仍返回原查询，调用方无需判空。
+func (r *Repository[DTO, ENTITY]) applyGuardFilter(ctx context.Context, qdb *gorm.DB, ent *ENTITY) (*gorm.DB, error) {
+	if hasWhereClause(qdb) {
+		return qdb, nil
+	}
+	if ent != nil {
+		conds, err := primaryKeyConds(ctx, qdb, ent)
+		if err != nil {
+			return nil, err
+		}
+		if len(conds) > 0 {
+			return qdb.Where(conds), nil
+		}
+	}
+	if err := r.guardOptions.CheckFilter(false); err != nil {
+		return qdb, err
+	}
+	// 已允许全表操作，同时放开 GORM 自身的全表更新/删除检查
+	return qdb.Session(&gorm.Session{AllowGlobalUpdate: true}), nil
+}
+
+// execWithGuard 执行写操作；配置了 MaxAffectedRows 时在事务中执行，超出上限则回滚。
+// 执行失败时记录日志并返回 failedMsg，超出上限时返回 guard 错误。
+func (r *Repository[DTO, ENTITY]) execWithGuard(qdb *gorm.DB, failedMsg string, exec func(tx *gorm.DB) *gorm.DB) (int64, error) {
+	if r.guardOptions.MaxAffectedRows <= 0 {
+		res := exec(qdb)
+		if res.Error != nil {
+			log.Errorf("%s: %s", failedMsg, res.Error.Error())
+			return 0, errors.New(failedMsg)
+		}
+		return res.RowsAffected, nil
+	}
+
+	var affected int64
+	err := qdb.Transaction(func(tx *gorm.DB) error {
+		res := exec(tx)
+		if res.Error != nil {
+			return res.Error
+		}
+		affected = res.RowsAffected
+		return r.guardOptions.CheckAffected(affected)
+	})
+	if err != nil {
+		if errors.Is(err, guard.ErrTooManyRowsAffected) {
+			log.Errorf("%s: %s, rolled back", failedMsg, err.Error())
+			return 0, err
+		}
+		log.Errorf("%s: %s", failedMsg, err.Error())
+		return 0, errors.New(failedMsg)
+	}
+	return affected, nil
+}
+
+// hasWhereClause 判断查询是否已包含 WHERE 条件。
+// Scopes 添加的条件在执行时才会应用，因此未找到时以 DryRun 方式构建一次计数查询后再判断；
+// 构建时使用 Unscoped，避免软删除自动添加的 deleted_at 条件被视为过滤条件
+func hasWhereClause(db *gorm.DB) bool {
+	if db == nil || db.Statement == nil {
+		return false
+	}
+	if statementHasWhere(db.Statement) {
+		return true
+	}
+
+	var count int64
+	tx := db.Session(&gorm.Session{DryRun: true}).Unscoped().Count(&count)
+	return tx.Statement != nil && statementHasWhere(tx.Statement)
+}
+
+// statementHasWhere 判断语句的 WHERE 子句是否包含条件
+func statementHasWhere(stmt *gorm.Statement) bool {
+	c, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return false
+	}
+	if w, ok := c.Expression.(clause.Where); ok {
+		return len(w.Exprs) > 0
+	}
+	return c.Expression != nil
+}
+
+// primaryKeyConds 返回实体主键列与值，任一主键为零值时返回 nil
+func primaryKeyConds(ctx context.Context, db *gorm.DB, ent any) (map[string]any, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(ent); err != nil {
+		return nil, err
+	}
+	if stmt.Schema == nil || len(stmt.Schema.PrimaryFields) == 0 {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(ent)
+	conds := make(map[string]any, len(stmt.Schema.PrimaryFields))
+	for _, pf := range stmt.Schema.PrimaryFields {
+		v, zero := pf.ValueOf(ctx, rv)
+		if zero {
+			return nil, nil
+		}
+		conds[pf.DBName] = v
+	}
+	return conds, nil
+}
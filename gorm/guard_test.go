@@ -0,0 +1,105 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tx7do/go-utils/mapper"
+	"gorm.io/gorm"
+
+	"github.com/tx7do/go-crud/guard"
+)
+
+type guardUser struct {
+	ID        uint
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+func TestHasWhereClause(t *testing.T) {
+	base := openRelationTestDB(t)
+	query := func() *gorm.DB { return base.Model(&guardUser{}) }
+
+	if hasWhereClause(query()) {
+		t.Errorf("plain query: expected no WHERE clause")
+	}
+	if !hasWhereClause(query().Where("name = ?", "alice")) {
+		t.Errorf("Where: expected WHERE clause")
+	}
+
+	// Scopes 中的条件在执行时才会应用
+	byName := func(tx *gorm.DB) *gorm.DB { return tx.Where("name = ?", "alice") }
+	if !hasWhereClause(query().Scopes(byName)) {
+		t.Errorf("Scopes: expected WHERE clause")
+	}
+	noop := func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }
+	if hasWhereClause(query().Scopes(noop)) {
+		t.Errorf("Scopes without conditions: expected no WHERE clause")
+	}
+
+	// 检查不修改原查询
+	scoped := query().Scopes(byName)
+	hasWhereClause(scoped)
+	if _, ok := scoped.Statement.Clauses["WHERE"]; ok {
+		t.Errorf("hasWhereClause must not apply scopes to the original statement")
+	}
+}
+
+func TestUpdate_PrimaryKeyBecomesWhereClause(t *testing.T) {
+	db := openRelationTestDB(t)
+	var updates []string
+	if err := db.Callback().Update().After("gorm:update").Register("test:capture", func(tx *gorm.DB) {
+		updates = append(updates, tx.Statement.SQL.String())
+	}); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	r := NewRepository[guardUser, guardUser](mapper.NewCopierMapper[guardUser, guardUser]())
+	if _, err := r.UpdateX(context.Background(), db, &guardUser{ID: 7, Name: "alice"}, nil); err != nil {
+		t.Fatalf("UpdateX: %v", err)
+	}
+	if len(updates) != 1 {
+		t.Fatalf("expected 1 update statement, got %d", len(updates))
+	}
+	if !strings.Contains(updates[0], "WHERE") || !strings.Contains(updates[0], "`id` = ?") {
+		t.Errorf("expected primary key predicate, got %s", updates[0])
+	}
+
+	// 主键为零值且无 WHERE 条件时被拒绝
+	_, err := r.UpdateX(context.Background(), db, &guardUser{Name: "bob"}, nil)
+	if !errors.Is(err, guard.ErrFullTableOperation) {
+		t.Errorf("expected ErrFullTableOperation, got %v", err)
+	}
+}
+
+func TestGuard_AllowFullTable(t *testing.T) {
+	db := openRelationTestDB(t)
+	var statements []string
+	capture := func(tx *gorm.DB) { statements = append(statements, tx.Statement.SQL.String()) }
+	if err := db.Callback().Update().After("gorm:update").Register("test:capture", capture); err != nil {
+		t.Fatalf("register update callback: %v", err)
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("test:capture", capture); err != nil {
+		t.Fatalf("register delete callback: %v", err)
+	}
+
+	r := NewRepository[guardUser, guardUser](mapper.NewCopierMapper[guardUser, guardUser]())
+	r.SetGuardOptions(guard.Options{AllowFullTable: true})
+
+	if _, err := r.UpdateX(context.Background(), db, &guardUser{Name: "bob"}, nil); err != nil {
+		t.Fatalf("UpdateX: %v", err)
+	}
+	if _, err := r.Delete(context.Background(), db, true); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(statements))
+	}
+	for _, sql := range statements {
+		if strings.Contains(sql, "`id`") {
+			t.Errorf("expected full table statement, got %s", sql)
+		}
+	}
+}
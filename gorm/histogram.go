@@ -0,0 +1,95 @@
+package gorm
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+
+	"github.com/tx7do/go-crud/histogram"
+)
+
+// Histogram 时间直方图：按 hreq.Interval 在 hreq.Timezone 下对时间字段分桶，返回补零且有序的桶。
+// Postgres 使用 date_trunc，MySQL 使用 DATE_FORMAT（时区转换需加载时区表），SQLite 使用 strftime
+func (r *Repository[DTO, ENTITY]) Histogram(ctx context.Context, db *gorm.DB, hreq *histogram.Request) (ret *histogram.Result, err error) {
+	obs := r.observe("Histogram")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Buckets)))
+		}
+		obs.End(err)
+	}()
+
+	if db == nil {
+		return nil, errDBIsNil
+	}
+
+	histDB, err := r.buildHistogramDB(ctx, db, hreq)
+	if err != nil {
+		return nil, err
+	}
+
+	collector, err := histogram.NewCollector(hreq)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := histDB.Rows()
+	if err != nil {
+		log.Errorf("histogram query failed: %s", err.Error())
+		return nil, errHistogramQueryFailed
+	}
+	defer rows.Close()
+
+	values := make([]any, 2+len(hreq.Aggregates))
+	dest := make([]any, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			log.Errorf("scan histogram row failed: %s", err.Error())
+			return nil, errScanHistogramRowFailed
+		}
+		if err = collector.Add(values[0], values[1], values[2:]...); err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		log.Errorf("histogram query failed: %s", err.Error())
+		return nil, errHistogramQueryFailed
+	}
+
+	return collector.Result()
+}
+
+// buildHistogramDB 构造按分桶键分组的查询，列顺序为分桶键、文档数与各聚合
+func (r *Repository[DTO, ENTITY]) buildHistogramDB(ctx context.Context, db *gorm.DB, hreq *histogram.Request) (*gorm.DB, error) {
+	s, err := histogram.BuildSQL(db.Dialector.Name(), hreq)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.limitPolicy.CheckFilter(r.entityName, hreq.Query, hreq.OrQuery, hreq.FilterExpr); err != nil {
+		return nil, err
+	}
+
+	_, whereSelectors, err := r.buildListDB(ctx, db, relationQuery{
+		query:      hreq.Query,
+		orQuery:    hreq.OrQuery,
+		filterExpr: hreq.FilterExpr,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	histDB := db.WithContext(ctx).Model(new(ENTITY))
+	for _, sel := range whereSelectors {
+		if sel != nil {
+			histDB = sel(histDB)
+		}
+	}
+	if s.Where != "" {
+		histDB = histDB.Where(s.Where, s.WhereArgs...)
+	}
+	return histDB.Select(s.Select()).Group(s.GroupBy).Order(s.OrderBy), nil
+}
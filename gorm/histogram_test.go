@@ -0,0 +1,67 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+
+	"github.com/tx7do/go-crud/histogram"
+)
+
+// histogramDialector 以 mysql 方言名生成 SQL 的空方言
+type histogramDialector struct {
+	tests.DummyDialector
+}
+
+func (histogramDialector) Name() string { return "mysql" }
+
+func TestHistogram_BuildDB(t *testing.T) {
+	db, err := gorm.Open(histogramDialector{}, &gorm.Config{
+		DryRun: true,
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	r := newRelationTestRepository()
+
+	histDB, err := r.buildHistogramDB(context.Background(), db, &histogram.Request{
+		Field:      "created_at",
+		Interval:   "1d",
+		Timezone:   "Asia/Shanghai",
+		Start:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Query:      `{"department.name":"eng"}`,
+		Aggregates: []histogram.Aggregate{{Func: histogram.FuncMax, Field: "id"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var rows []map[string]any
+	stmt := histDB.Find(&rows).Statement.SQL.String()
+	for _, want := range []string{
+		"DATE_FORMAT(CONVERT_TZ(created_at, '+00:00', 'Asia/Shanghai'), '%Y-%m-%d 00:00:00') AS _bucket",
+		"COUNT(*) AS _count",
+		"MAX(id) AS max_id",
+		"LEFT JOIN `rel_departments` `Department`",
+		"`Department`.`name` = ?",
+		"created_at >= ?",
+		"GROUP BY `_bucket`",
+		"ORDER BY _bucket",
+	} {
+		if !strings.Contains(stmt, want) {
+			t.Errorf("histogram statement missing %q:\n%s", want, stmt)
+		}
+	}
+
+	_, err = r.buildHistogramDB(context.Background(), db, &histogram.Request{Field: "created_at", Interval: "5h"})
+	if !errors.Is(err, histogram.ErrInvalidInterval) {
+		t.Errorf("expected ErrInvalidInterval, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package gorm
+
+import (
+	"context"
+	"io"
+
+	"gorm.io/gorm"
+
+	"github.com/tx7do/go-crud/importer"
+)
+
+// Import 从 CSV/JSONL 批量导入数据。
+// 每个分块在独立事务中写入：ModeInsert 使用 BatchCreate，ModeUpsert 逐行使用 Upsert，
+// 并根据主键是否已存在区分插入与更新。任一行写入失败时整个分块回滚。
+func (r *Repository[DTO, ENTITY]) Import(ctx context.Context, db *gorm.DB, src io.Reader, opts *importer.Options, validate importer.Validator[DTO]) (*importer.Report, error) {
+	if db == nil {
+		return nil, errDBIsNil
+	}
+	if opts == nil {
+		opts = &importer.Options{}
+	}
+
+	return importer.Import[DTO](ctx, src, opts, validate, func(ctx context.Context, dtos []*DTO) ([]importer.Status, error) {
+		statuses := make([]importer.Status, len(dtos))
+
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if opts.Mode != importer.ModeUpsert {
+				_, err := r.BatchCreate(ctx, tx, dtos, nil)
+				return err
+			}
+
+			for i, dto := range dtos {
+				exists, err := r.existsByPrimaryKey(ctx, tx, r.mapper.ToEntity(dto))
+				if err != nil {
+					return err
+				}
+				if _, err = r.Upsert(ctx, tx, dto, opts.UpdateMask); err != nil {
+					return err
+				}
+				if exists {
+					statuses[i] = importer.StatusUpdated
+				} else {
+					statuses[i] = importer.StatusInserted
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return statuses, nil
+	})
+}
+
+// existsByPrimaryKey 根据实体的主键值判断记录是否已存在，主键为零值时视为不存在
+func (r *Repository[DTO, ENTITY]) existsByPrimaryKey(ctx context.Context, db *gorm.DB, ent *ENTITY) (bool, error) {
+	if ent == nil {
+		return false, nil
+	}
+
+	conds, err := primaryKeyConds(ctx, db, ent)
+	if err != nil || len(conds) == 0 {
+		return false, err
+	}
+
+	var count int64
+	if err := db.WithContext(ctx).Model(new(ENTITY)).Where(conds).Limit(1).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
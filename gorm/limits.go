@@ -0,0 +1,11 @@
+package gorm
+
+import (
+	"github.com/tx7do/go-crud/limits"
+)
+
+// SetLimitPolicy 设置请求复杂度限制（分页大小、偏移量、过滤复杂度与排序字段数），nil 表示不限制。
+// 超出限制时返回 Reason 为 limits.Reason* 的 BadRequest 错误。
+func (r *Repository[DTO, ENTITY]) SetLimitPolicy(p *limits.Policy) {
+	r.limitPolicy = p
+}
@@ -0,0 +1,34 @@
+package gorm
+
+import (
+	"github.com/tx7do/go-crud/metrics"
+)
+
+// SetMetrics 设置仓库级指标，多个仓库可共享同一个 metrics.Metrics；传入 nil 关闭指标
+func (r *Repository[DTO, ENTITY]) SetMetrics(m *metrics.Metrics) {
+	r.metrics = m
+}
+
+func init() {
+	metrics.RegisterErrorClass(metrics.ErrorClassInvalidArgument,
+		errDBIsNil,
+		errDTOIsNil,
+		errPaginationRequestIsNil,
+		errPagingRequestIsNil,
+		ErrRelationDepthExceeded,
+		ErrInvalidRelationPath,
+	)
+	metrics.RegisterErrorClass(metrics.ErrorClassStorage,
+		errBatchCreateFailed,
+		errCreateFailed,
+		errExistsQueryFailed,
+		errExplainQueryFailed,
+		errHistogramQueryFailed,
+		errParseEntitySchemaFailed,
+		errQueryCountFailed,
+		errQueryListFailed,
+		errScanHistogramRowFailed,
+		errSearchQueryFailed,
+		errUpsertFailed,
+	)
+}
@@ -0,0 +1,22 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tx7do/go-utils/mapper"
+
+	"github.com/tx7do/go-crud/metrics"
+)
+
+func TestRepository_ErrorClass(t *testing.T) {
+	r := NewRepository[guardUser, guardUser](mapper.NewCopierMapper[guardUser, guardUser]())
+
+	_, err := r.Delete(context.Background(), nil, false)
+	if got := (*metrics.Metrics)(nil).ErrorClass(err); got != metrics.ErrorClassInvalidArgument {
+		t.Errorf("nil db: expected %q, got %q", metrics.ErrorClassInvalidArgument, got)
+	}
+	if got := (*metrics.Metrics)(nil).ErrorClass(errQueryListFailed); got != metrics.ErrorClassStorage {
+		t.Errorf("query list failed: expected %q, got %q", metrics.ErrorClassStorage, got)
+	}
+}
@@ -0,0 +1,16 @@
+package gorm
+
+import (
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+)
+
+// SetSlowLog 设置慢查询日志，多个仓库可共享同一个 slowlog.Logger；传入 nil 关闭
+func (r *Repository[DTO, ENTITY]) SetSlowLog(l *slowlog.Logger) {
+	r.slowLog = l
+}
+
+// observe 开始观测一次仓库操作
+func (r *Repository[DTO, ENTITY]) observe(method string) *slowlog.Observation {
+	return slowlog.Observe(r.metrics, r.slowLog, metrics.BackendGorm, r.entityName, method)
+}
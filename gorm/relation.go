@@ -0,0 +1,570 @@
+package gorm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tx7do/go-utils/stringcase"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"gorm.io/gorm/utils"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/gorm/filter"
+)
+
+// DefaultMaxRelationDepth EnableRelations 允许的关联路径嵌套层数
+const DefaultMaxRelationDepth = 3
+
+var (
+	// ErrRelationDepthExceeded 关联路径的嵌套层数超出限制
+	ErrRelationDepthExceeded = errors.New("relation path exceeds max depth")
+
+	// ErrInvalidRelationPath 关联路径不合法，如关联上不存在的字段、按一对多关联排序
+	ErrInvalidRelationPath = errors.New("invalid relation path")
+)
+
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// relationQuery 请求中可能引用关联路径的部分
+type relationQuery struct {
+	query      string
+	orQuery    string
+	filterExpr *paginationV1.FilterExpr
+	fieldMask  *fieldmaskpb.FieldMask
+	sorting    []*paginationV1.Sorting
+	orderBy    []string
+}
+
+// relationJoin 一条 JOIN：命名关联（如 "Department.Company"）或原始 JOIN 语句
+type relationJoin struct {
+	query string
+	args  []any
+}
+
+// relationPreload 一条 Preload；all 或 columns 为空时加载全部列，否则只选择 columns 与 keys
+type relationPreload struct {
+	path    string   // 关联名路径，如 "Roles.Permissions"
+	all     bool     // field_mask 请求了整个关联
+	columns []string // field_mask 请求的列
+	keys    []string // 回填关联所需的主键与外键
+}
+
+// relationPlan 单次请求的关联计划：过滤与排序用到的关联转为 JOIN，field_mask 中的关联转为 Preload
+type relationPlan struct {
+	stmt     *gorm.Statement
+	schema   *schema.Schema
+	maxDepth int
+
+	joins  []relationJoin
+	joined map[string]bool // 已 JOIN 的别名
+	named  map[string]bool // 通过命名关联 JOIN 的别名
+	toMany bool            // 是否 JOIN 了一对多或多对多关联，需要 DISTINCT
+
+	columns  []string // 根表的列，nil 表示全部列
+	rootKeys []string // 根表上回填关联所需的外键
+
+	preloads   []*relationPreload
+	preloadIdx map[string]*relationPreload
+}
+
+// EnableRelations 以 DefaultMaxRelationDepth 启用关联路径解析，参见 SetMaxRelationDepth
+func (r *Repository[DTO, ENTITY]) EnableRelations() {
+	r.SetMaxRelationDepth(DefaultMaxRelationDepth)
+}
+
+// SetMaxRelationDepth 设置关联路径允许的最大嵌套层数，默认为 0，即不识别关联路径，点分路径按原有方式处理。
+//
+// 启用时，过滤、排序与 field_mask 中以关联名开头的路径（如 "department.name"、"roles.permissions.code"）按 GORM 的 schema 关联解析：
+// 过滤与排序用到的一对一、多对一关联使用命名 Joins（别名与 GORM 一致，如 "Department"、"Department__Company"），
+// 一对多、多对多关联使用 LEFT JOIN，此时列表查询使用 DISTINCT，计数使用 COUNT(DISTINCT 主键)；
+// field_mask 中的关联转为 Preload（"roles" 或 "roles.*" 加载全部列），并自动补齐回填关联所需的主键与外键列。
+func (r *Repository[DTO, ENTITY]) SetMaxRelationDepth(depth int) {
+	r.maxRelationDepth = depth
+}
+
+// planRelations 解析 q 中的关联路径；未涉及关联时返回 nil，查询保持原有行为
+func (r *Repository[DTO, ENTITY]) planRelations(db *gorm.DB, q relationQuery) (*relationPlan, error) {
+	if r.maxRelationDepth <= 0 || db == nil {
+		return nil, nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(ENTITY)); err != nil || len(stmt.Schema.Relationships.Relations) == 0 {
+		return nil, nil
+	}
+
+	p := &relationPlan{
+		stmt:       stmt,
+		schema:     stmt.Schema,
+		maxDepth:   r.maxRelationDepth,
+		joined:     map[string]bool{},
+		named:      map[string]bool{},
+		preloadIdx: map[string]*relationPreload{},
+	}
+
+	for _, field := range filterFields(q) {
+		rels, f, err := p.lookup(field)
+		if err != nil {
+			return nil, err
+		}
+		if len(rels) == 0 {
+			continue
+		}
+		if f == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRelationPath, field)
+		}
+		p.join(rels)
+	}
+
+	for _, field := range sortingFields(q) {
+		rels, f, err := p.lookup(field)
+		if err != nil {
+			return nil, err
+		}
+		if len(rels) == 0 {
+			continue
+		}
+		if f == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRelationPath, field)
+		}
+		for _, rel := range rels {
+			if !toOne(rel) {
+				return nil, fmt.Errorf("%w: cannot sort by to-many relation %s", ErrInvalidRelationPath, field)
+			}
+		}
+		// 命名关联的列总会以 "别名__列" 出现在选择列中，DISTINCT 时同样可以用于排序
+		p.join(rels)
+	}
+
+	if err := p.addMask(q.fieldMask); err != nil {
+		return nil, err
+	}
+
+	if len(p.joins) == 0 && len(p.preloads) == 0 {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// addMask 将 field_mask 拆分为根表的列与关联的 Preload；既无关联路径也无 JOIN 时不做处理
+func (p *relationPlan) addMask(mask *fieldmaskpb.FieldMask) error {
+	var hasRelation bool
+	for _, path := range mask.GetPaths() {
+		rels, _, err := p.lookup(path)
+		if err != nil {
+			return err
+		}
+		if len(rels) > 0 {
+			hasRelation = true
+		}
+	}
+	if !hasRelation && len(p.joins) == 0 {
+		return nil
+	}
+
+	var all bool
+	var columns []string
+	for _, path := range mask.GetPaths() {
+		rels, f, _ := p.lookup(path)
+		switch {
+		case len(rels) > 0:
+			p.load(rels, f)
+		case f != nil:
+			columns = appendOnce(columns, f.DBName)
+		case strings.TrimSpace(path) == "*":
+			all = true
+		default:
+			// 非关联的点号路径（如 JSON 字段）选择其所在的列
+			columns = appendOnce(columns, stringcase.ToSnakeCase(strings.SplitN(strings.TrimSpace(path), ".", 2)[0]))
+		}
+	}
+	if all || len(columns) == 0 {
+		return nil
+	}
+
+	for _, f := range p.schema.PrimaryFields {
+		columns = appendOnce(columns, f.DBName)
+	}
+	for _, key := range p.rootKeys {
+		columns = appendOnce(columns, key)
+	}
+	p.columns = columns
+	return nil
+}
+
+// load 预加载关联链 rels。
+// 已通过命名关联 JOIN 的部分由 GORM 选择其全部列并回填，不再 Preload
+func (p *relationPlan) load(rels []*schema.Relationship, f *schema.Field) {
+	parent, parentNamed := "", false
+	for i, rel := range rels {
+		parentKeys, childKeys := relationKeys(rel)
+		for _, pf := range rel.FieldSchema.PrimaryFields {
+			childKeys = append(childKeys, pf.DBName)
+		}
+		for _, key := range parentKeys {
+			switch {
+			case parent == "":
+				p.rootKeys = appendOnce(p.rootKeys, key)
+			case !parentNamed:
+				p.preloadIdx[parent].keys = appendOnce(p.preloadIdx[parent].keys, key)
+			}
+		}
+		last := i == len(rels)-1
+
+		if alias := relationAlias(rels[:i+1]); p.named[alias] {
+			parent, parentNamed = alias, true
+			continue
+		}
+
+		path := relationPath(rels[:i+1])
+		pl := p.preloadIdx[path]
+		if pl == nil {
+			pl = &relationPreload{path: path}
+			p.preloadIdx[path] = pl
+			p.preloads = append(p.preloads, pl)
+		}
+		for _, key := range childKeys {
+			pl.keys = appendOnce(pl.keys, key)
+		}
+		if last {
+			if f == nil {
+				pl.all = true
+			} else {
+				pl.columns = appendOnce(pl.columns, f.DBName)
+			}
+		}
+		parent, parentNamed = path, false
+	}
+}
+
+// join 为关联链 rels 添加 JOIN 并返回末端的别名。
+// 链首的一对一关联使用 GORM 的命名 Joins，自第一个一对多关联起使用原始 LEFT JOIN，别名规则与 GORM 相同
+func (p *relationPlan) join(rels []*schema.Relationship) string {
+	var named []string
+	parent := p.schema.Table
+	many := false
+	for i, rel := range rels {
+		alias := relationAlias(rels[:i+1])
+		if toOne(rel) && !many {
+			named = append(named, rel.Name)
+			if !p.joined[alias] {
+				p.joined[alias], p.named[alias] = true, true
+				p.joins = append(p.joins, relationJoin{query: strings.Join(named, ".")})
+			}
+		} else {
+			many = true
+			if !toOne(rel) {
+				p.toMany = true
+			}
+			if !p.joined[alias] {
+				p.joined[alias] = true
+				p.joins = append(p.joins, p.rawJoins(rel, parent, alias)...)
+			}
+		}
+		parent = alias
+	}
+	return parent
+}
+
+// rawJoins 生成从 parent 到关联 rel（别名 alias）的 LEFT JOIN；多对多关联经由中间表
+func (p *relationPlan) rawJoins(rel *schema.Relationship, parent, alias string) []relationJoin {
+	target := "LEFT JOIN " + p.stmt.Quote(rel.FieldSchema.Table) + " " + p.stmt.Quote(alias) + " ON "
+
+	var on []string
+	var args []any
+	if rel.JoinTable == nil {
+		for _, ref := range rel.References {
+			switch {
+			case ref.PrimaryValue != "":
+				on = append(on, p.column(alias, ref.ForeignKey.DBName)+" = ?")
+				args = append(args, ref.PrimaryValue)
+			case ref.OwnPrimaryKey:
+				on = append(on, p.column(parent, ref.PrimaryKey.DBName)+" = "+p.column(alias, ref.ForeignKey.DBName))
+			default:
+				on = append(on, p.column(parent, ref.ForeignKey.DBName)+" = "+p.column(alias, ref.PrimaryKey.DBName))
+			}
+		}
+		on = append(on, p.softDelete(rel.FieldSchema, alias)...)
+		return []relationJoin{{query: target + strings.Join(on, " AND "), args: args}}
+	}
+
+	mid := utils.NestedRelationName(alias, rel.JoinTable.Table)
+	var midOn []string
+	for _, ref := range rel.References {
+		fk := p.column(mid, ref.ForeignKey.DBName)
+		switch {
+		case ref.PrimaryValue != "":
+			midOn = append(midOn, fk+" = ?")
+			args = append(args, ref.PrimaryValue)
+		case ref.OwnPrimaryKey:
+			midOn = append(midOn, fk+" = "+p.column(parent, ref.PrimaryKey.DBName))
+		default:
+			on = append(on, p.column(alias, ref.PrimaryKey.DBName)+" = "+fk)
+		}
+	}
+	on = append(on, p.softDelete(rel.FieldSchema, alias)...)
+	return []relationJoin{
+		{query: "LEFT JOIN " + p.stmt.Quote(rel.JoinTable.Table) + " " + p.stmt.Quote(mid) + " ON " + strings.Join(midOn, " AND "), args: args},
+		{query: target + strings.Join(on, " AND ")},
+	}
+}
+
+// softDelete 关联模型启用软删除时，排除已删除的记录
+func (p *relationPlan) softDelete(s *schema.Schema, alias string) []string {
+	for _, f := range s.Fields {
+		if f.FieldType == deletedAtType && f.DBName != "" {
+			return []string{p.column(alias, f.DBName) + " IS NULL"}
+		}
+	}
+	return nil
+}
+
+// lookup 将点号路径解析为关联链与末端字段。
+// 不以关联名开头的路径返回空的关联链（单段路径同时返回根表字段）；路径以关联名或 "*" 结尾时字段为 nil
+func (p *relationPlan) lookup(path string) ([]*schema.Relationship, *schema.Field, error) {
+	path = strings.TrimSpace(path)
+	parts := strings.Split(path, ".")
+
+	var rels []*schema.Relationship
+	var f *schema.Field
+	cur := p.schema
+	for i, part := range parts {
+		if rel := findRelation(cur, part); rel != nil {
+			rels = append(rels, rel)
+			cur = rel.FieldSchema
+			continue
+		}
+		if len(rels) == 0 {
+			if len(parts) == 1 {
+				return nil, findField(cur, part), nil
+			}
+			return nil, nil, nil
+		}
+		if i != len(parts)-1 {
+			return nil, nil, fmt.Errorf("%w: %s", ErrInvalidRelationPath, path)
+		}
+		if part != "*" {
+			if f = findField(cur, part); f == nil {
+				return nil, nil, fmt.Errorf("%w: %s", ErrInvalidRelationPath, path)
+			}
+		}
+	}
+
+	if len(rels) > p.maxDepth {
+		return nil, nil, fmt.Errorf("%w: %s", ErrRelationDepthExceeded, path)
+	}
+	return rels, f, nil
+}
+
+// resolve 将字段解析为带表名或关联别名的列，供过滤与排序使用
+func (p *relationPlan) resolve(field string) (string, bool) {
+	rels, f, err := p.lookup(field)
+	if err != nil || f == nil {
+		return "", false
+	}
+	if len(rels) == 0 {
+		return p.column(p.schema.Table, f.DBName), true
+	}
+	return p.column(relationAlias(rels), f.DBName), true
+}
+
+// joinScope 添加过滤与排序所需的 JOIN，列表与计数共用
+func (p *relationPlan) joinScope(db *gorm.DB) *gorm.DB {
+	for _, j := range p.joins {
+		db = db.Joins(j.query, j.args...)
+	}
+	return db
+}
+
+// countScope 存在一对多 JOIN 时按主键去重计数
+func (p *relationPlan) countScope(db *gorm.DB) *gorm.DB {
+	if !p.toMany || p.schema.PrioritizedPrimaryField == nil {
+		return db
+	}
+	return db.Distinct(p.schema.Table + "." + p.schema.PrioritizedPrimaryField.DBName)
+}
+
+// selectScope 设置列表查询的选择列、DISTINCT 与 Preload
+func (p *relationPlan) selectScope(db *gorm.DB) *gorm.DB {
+	if p.columns != nil {
+		columns := make([]string, 0, len(p.columns))
+		for _, c := range p.columns {
+			columns = append(columns, p.column(p.schema.Table, c))
+		}
+		db = db.Select(columns)
+	}
+	if p.toMany {
+		db = db.Distinct()
+	}
+
+	for _, pl := range p.preloads {
+		if pl.all || len(pl.columns) == 0 {
+			db = db.Preload(pl.path)
+			continue
+		}
+		columns := append([]string{}, pl.columns...)
+		for _, key := range pl.keys {
+			columns = appendOnce(columns, key)
+		}
+		db = db.Preload(pl.path, func(tx *gorm.DB) *gorm.DB {
+			return tx.Select(columns)
+		})
+	}
+	return db
+}
+
+// column 返回带引号的 表.列
+func (p *relationPlan) column(table, name string) string {
+	return p.stmt.Quote(clause.Column{Table: table, Name: name})
+}
+
+// relationKeys 返回回填关联 rel 时父、子两侧各自需要选择的键列
+func relationKeys(rel *schema.Relationship) (parent, child []string) {
+	for _, ref := range rel.References {
+		switch {
+		case ref.PrimaryValue != "":
+			if rel.JoinTable == nil {
+				child = append(child, ref.ForeignKey.DBName)
+			}
+		case rel.JoinTable != nil:
+			// 多对多的外键位于中间表
+			if ref.OwnPrimaryKey {
+				parent = append(parent, ref.PrimaryKey.DBName)
+			} else {
+				child = append(child, ref.PrimaryKey.DBName)
+			}
+		case ref.OwnPrimaryKey:
+			parent = append(parent, ref.PrimaryKey.DBName)
+			child = append(child, ref.ForeignKey.DBName)
+		default:
+			parent = append(parent, ref.ForeignKey.DBName)
+			child = append(child, ref.PrimaryKey.DBName)
+		}
+	}
+	return parent, child
+}
+
+// findRelation 按关联名查找，忽略大小写与下划线（"department"、"user_roles" 分别匹配 Department、UserRoles）
+func findRelation(s *schema.Schema, name string) *schema.Relationship {
+	if rel, ok := s.Relationships.Relations[name]; ok {
+		return rel
+	}
+	key := strings.ReplaceAll(name, "_", "")
+	for relName, rel := range s.Relationships.Relations {
+		if strings.EqualFold(relName, key) {
+			return rel
+		}
+	}
+	return nil
+}
+
+// findField 按列名、字段名或其蛇形形式查找数据库字段
+func findField(s *schema.Schema, name string) *schema.Field {
+	for _, n := range []string{name, stringcase.ToSnakeCase(name)} {
+		if f := s.LookUpField(n); f != nil && f.DBName != "" {
+			return f
+		}
+	}
+	return nil
+}
+
+func toOne(rel *schema.Relationship) bool {
+	return rel.Type == schema.HasOne || rel.Type == schema.BelongsTo
+}
+
+// relationAlias 返回关联链的表别名，与 GORM 命名 Joins 一致，如 "Department__Company"
+func relationAlias(rels []*schema.Relationship) string {
+	alias := rels[0].Name
+	for _, rel := range rels[1:] {
+		alias = utils.NestedRelationName(alias, rel.Name)
+	}
+	return alias
+}
+
+// relationPath 返回关联链的 Preload 路径，如 "Roles.Permissions"
+func relationPath(rels []*schema.Relationship) string {
+	names := make([]string, 0, len(rels))
+	for _, rel := range rels {
+		names = append(names, rel.Name)
+	}
+	return strings.Join(names, ".")
+}
+
+// filterFields 返回过滤条件中引用的字段
+func filterFields(q relationQuery) []string {
+	var fields []string
+	for _, s := range []string{q.query, q.orQuery} {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		var maps []map[string]any
+		var single map[string]any
+		if err := json.Unmarshal([]byte(s), &single); err == nil {
+			maps = append(maps, single)
+		} else if err = json.Unmarshal([]byte(s), &maps); err != nil {
+			continue
+		}
+		for _, m := range maps {
+			// 按键排序，使生成的 JOIN 顺序稳定
+			keys := make([]string, 0, len(m))
+			for key := range m {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fields = appendOnce(fields, strings.Split(key, filter.QueryDelimiter)[0])
+			}
+		}
+	}
+
+	var walk func(expr *paginationV1.FilterExpr)
+	walk = func(expr *paginationV1.FilterExpr) {
+		for _, cond := range expr.GetConditions() {
+			fields = appendOnce(fields, cond.GetField())
+		}
+		for _, g := range expr.GetGroups() {
+			walk(g)
+		}
+	}
+	walk(q.filterExpr)
+	return fields
+}
+
+// sortingFields 返回排序中引用的字段；order_by 字符串去掉 "-" 前缀与 ":desc"、".asc" 等方向后缀
+func sortingFields(q relationQuery) []string {
+	var fields []string
+	for _, o := range q.sorting {
+		if f := strings.TrimSpace(o.GetField()); f != "" {
+			fields = appendOnce(fields, f)
+		}
+	}
+	for _, expr := range q.orderBy {
+		expr = strings.TrimPrefix(strings.TrimSpace(expr), "-")
+		if i := strings.LastIndexAny(expr, ":."); i > 0 {
+			switch strings.ToLower(expr[i+1:]) {
+			case "asc", "desc":
+				expr = expr[:i]
+			}
+		}
+		if expr != "" {
+			fields = appendOnce(fields, expr)
+		}
+	}
+	return fields
+}
+
+func appendOnce(items []string, item string) []string {
+	for _, it := range items {
+		if it == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
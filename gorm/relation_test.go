@@ -0,0 +1,213 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tx7do/go-utils/mapper"
+	"github.com/tx7do/go-utils/trans"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+type relCompany struct {
+	ID   uint
+	Name string
+}
+
+type relDepartment struct {
+	ID        uint
+	Name      string
+	CompanyID uint
+	Company   *relCompany
+}
+
+type relPermission struct {
+	ID   uint
+	Code string
+}
+
+type relRole struct {
+	ID          uint
+	Name        string
+	Permissions []*relPermission `gorm:"many2many:rel_role_permissions"`
+}
+
+type relUser struct {
+	ID           uint
+	UserName     string
+	DepartmentID uint
+	Department   *relDepartment
+	Roles        []*relRole `gorm:"many2many:rel_user_roles"`
+}
+
+func openRelationTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{
+		DryRun: true,
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	return db
+}
+
+func newRelationTestRepository() *Repository[relUser, relUser] {
+	r := NewRepository[relUser, relUser](mapper.NewCopierMapper[relUser, relUser]())
+	r.EnableRelations()
+	return r
+}
+
+func TestRelation_ToOneFilterAndSort(t *testing.T) {
+	r := newRelationTestRepository()
+	db := openRelationTestDB(t)
+
+	q, err := r.ToQuery(context.Background(), db, &paginationV1.PagingRequest{
+		Query:   trans.Ptr(`{"department.name__icontains":"eng","userName":"alice"}`),
+		OrderBy: []string{"-department.company.name"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"LEFT JOIN `rel_departments` `Department` ON `rel_users`.`department_id` = `Department`.`id`",
+		"LEFT JOIN `rel_companies` `Department__Company` ON `Department`.`company_id` = `Department__Company`.`id`",
+		"`rel_users`.`user_name` = ?",
+		"ORDER BY `Department__Company`.`name` DESC",
+	} {
+		if !strings.Contains(q.Statement, want) {
+			t.Errorf("list statement missing %q:\n%s", want, q.Statement)
+		}
+	}
+	if !strings.Contains(strings.ToLower(q.Statement), "lower(`department`.`name`)") && !strings.Contains(q.Statement, "`Department`.`name` LIKE") {
+		t.Errorf("department filter not applied on joined alias:\n%s", q.Statement)
+	}
+	if strings.Contains(q.Statement, "DISTINCT") {
+		t.Errorf("to-one joins must not use DISTINCT:\n%s", q.Statement)
+	}
+	if !strings.Contains(q.CountStatement, "LEFT JOIN `rel_departments` `Department`") || !strings.Contains(q.CountStatement, "count(*)") {
+		t.Errorf("count must keep the joins:\n%s", q.CountStatement)
+	}
+}
+
+func TestRelation_ToManyFilter(t *testing.T) {
+	r := newRelationTestRepository()
+	db := openRelationTestDB(t)
+
+	q, err := r.ToQuery(context.Background(), db, &paginationV1.PagingRequest{
+		FilterExpr: &paginationV1.FilterExpr{
+			Type: paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{
+				{Field: "roles.permissions.code", Op: paginationV1.Operator_EQ, Value: trans.Ptr("user:read")},
+				{Field: "id", Op: paginationV1.Operator_EQ, Value: trans.Ptr("1")},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"SELECT DISTINCT",
+		"LEFT JOIN `rel_user_roles` `Roles__rel_user_roles` ON `Roles__rel_user_roles`.`rel_user_id` = `rel_users`.`id`",
+		"LEFT JOIN `rel_roles` `Roles` ON `Roles`.`id` = `Roles__rel_user_roles`.`rel_role_id`",
+		"LEFT JOIN `rel_permissions` `Roles__Permissions` ON `Roles__Permissions`.`id` = `Roles__Permissions__rel_role_permissions`.`rel_permission_id`",
+		"`Roles__Permissions`.`code` = ?",
+		"`rel_users`.`id` = ?",
+	} {
+		if !strings.Contains(q.Statement, want) {
+			t.Errorf("list statement missing %q:\n%s", want, q.Statement)
+		}
+	}
+	if !strings.Contains(q.CountStatement, "COUNT(DISTINCT(`rel_users`.`id`))") {
+		t.Errorf("count must be distinct on the primary key:\n%s", q.CountStatement)
+	}
+}
+
+func TestRelation_FieldMaskPreload(t *testing.T) {
+	r := newRelationTestRepository()
+	db := openRelationTestDB(t)
+
+	listDB, _, err := r.buildPagingDB(context.Background(), db, &paginationV1.PagingRequest{
+		FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"userName", "roles.name", "roles.permissions.code", "department"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	selects := strings.Join(listDB.Statement.Selects, ", ")
+	if selects != "`rel_users`.`user_name`, `rel_users`.`id`, `rel_users`.`department_id`" {
+		t.Errorf("unexpected root columns: %s", selects)
+	}
+	for _, name := range []string{"Roles", "Roles.Permissions", "Department"} {
+		if _, ok := listDB.Statement.Preloads[name]; !ok {
+			t.Errorf("missing preload %s, got %v", name, listDB.Statement.Preloads)
+		}
+	}
+	if len(listDB.Statement.Joins) != 0 {
+		t.Errorf("field mask alone must not join, got %d joins", len(listDB.Statement.Joins))
+	}
+}
+
+func TestRelation_InvalidPath(t *testing.T) {
+	r := newRelationTestRepository()
+	db := openRelationTestDB(t)
+	ctx := context.Background()
+
+	_, _, err := r.buildPagingDB(ctx, db, &paginationV1.PagingRequest{OrderBy: []string{"roles.name"}})
+	if !errors.Is(err, ErrInvalidRelationPath) {
+		t.Errorf("sorting by a to-many relation: expected ErrInvalidRelationPath, got %v", err)
+	}
+
+	_, _, err = r.buildPagingDB(ctx, db, &paginationV1.PagingRequest{Query: trans.Ptr(`{"department.missing":"x"}`)})
+	if !errors.Is(err, ErrInvalidRelationPath) {
+		t.Errorf("unknown relation field: expected ErrInvalidRelationPath, got %v", err)
+	}
+
+	r.SetMaxRelationDepth(1)
+	_, _, err = r.buildPagingDB(ctx, db, &paginationV1.PagingRequest{
+		FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"roles.permissions.code"}},
+	})
+	if !errors.Is(err, ErrRelationDepthExceeded) {
+		t.Errorf("expected ErrRelationDepthExceeded, got %v", err)
+	}
+
+	r.SetMaxRelationDepth(0)
+	listDB, _, err := r.buildPagingDB(ctx, db, &paginationV1.PagingRequest{
+		FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"roles.name"}},
+	})
+	if err != nil || len(listDB.Statement.Preloads) != 0 {
+		t.Errorf("relation paths should be disabled, got %v / %v", err, listDB.Statement.Preloads)
+	}
+}
+
+func TestRelation_DisabledByDefault(t *testing.T) {
+	r := NewRepository[relUser, relUser](mapper.NewCopierMapper[relUser, relUser]())
+	db := openRelationTestDB(t)
+
+	// 未启用关联路径时，点分路径按原有方式处理，不产生 JOIN、Preload 与 DISTINCT
+	q, err := r.ToQuery(context.Background(), db, &paginationV1.PagingRequest{
+		Query:     trans.Ptr(`{"department.name":"eng"}`),
+		OrderBy:   []string{"-department.name"},
+		FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"id", "department.name"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantList := "SELECT `id`, `department`.`name` FROM `rel_users` WHERE department_name = ? ORDER BY department DESC"
+	if q.Statement != wantList {
+		t.Errorf("list statement changed:\nwant %s\ngot  %s", wantList, q.Statement)
+	}
+	wantCount := "SELECT count(*) FROM `rel_users` WHERE department_name = ?"
+	if q.CountStatement != wantCount {
+		t.Errorf("count statement changed:\nwant %s\ngot  %s", wantCount, q.CountStatement)
+	}
+}
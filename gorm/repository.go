@@ -18,6 +18,10 @@ import (
 	"github.com/tx7do/go-crud/gorm/filter"
 	paging "github.com/tx7do/go-crud/gorm/pagination"
 	"github.com/tx7do/go-crud/gorm/sorting"
+	"github.com/tx7do/go-crud/guard"
+	"github.com/tx7do/go-crud/limits"
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
 )
 
 // PagingResult 通用分页返回
@@ -51,6 +55,15 @@ type Repository[DTO any, ENTITY any] struct {
 	structuredFilter  *filter.StructuredFilter
 
 	fieldSelector *field.Selector
+
+	maxRelationDepth int
+
+	guardOptions guard.Options
+	limitPolicy  *limits.Policy
+
+	metrics    *metrics.Metrics
+	slowLog    *slowlog.Logger
+	entityName string
 }
 
 func NewRepository[DTO any, ENTITY any](mapper *mapper.CopierMapper[DTO, ENTITY]) *Repository[DTO, ENTITY] {
@@ -68,13 +81,18 @@ func NewRepository[DTO any, ENTITY any](mapper *mapper.CopierMapper[DTO, ENTITY]
 		structuredFilter:  filter.NewStructuredFilter(),
 
 		fieldSelector: field.NewFieldSelector(),
+
+		entityName: metrics.EntityName[ENTITY](),
 	}
 }
 
 // Count 使用 whereSelectors 计算符合条件的记录数
-func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB) (int64, error) {
+func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB) (ret int64, err error) {
+	obs := r.observe("Count")
+	defer func() { obs.End(err) }()
+
 	if db == nil {
-		return 0, errors.New("db is nil")
+		return 0, errDBIsNil
 	}
 
 	countDB := db.WithContext(ctx).Model(new(ENTITY))
@@ -87,16 +105,19 @@ func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, db *gorm.DB, whereS
 	var cnt int64
 	if err := countDB.Count(&cnt).Error; err != nil {
 		log.Errorf("query count failed: %s", err.Error())
-		return 0, errors.New("query count failed")
+		return 0, errQueryCountFailed
 	}
 	return cnt, nil
 }
 
 // CountWithOptions 使用可选参数执行计数，返回 int64（更通用）
 // 保持原有 whereSelectors 参数风格，额外行为由 opts 控制
-func (r *Repository[DTO, ENTITY]) CountWithOptions(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, opts *CountOptions) (int64, error) {
+func (r *Repository[DTO, ENTITY]) CountWithOptions(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, opts *CountOptions) (ret int64, err error) {
+	obs := r.observe("CountWithOptions")
+	defer func() { obs.End(err) }()
+
 	if db == nil {
-		return 0, errors.New("db is nil")
+		return 0, errDBIsNil
 	}
 	if opts == nil {
 		opts = &CountOptions{}
@@ -133,63 +154,100 @@ func (r *Repository[DTO, ENTITY]) CountWithOptions(ctx context.Context, db *gorm
 	var cnt int64
 	if err := countDB.Count(&cnt).Error; err != nil {
 		log.Errorf("query count failed: %s", err.Error())
-		return 0, errors.New("query count failed")
+		return 0, errQueryCountFailed
 	}
 	return cnt, nil
 }
 
-// ListWithPaging 使用 PagingRequest 查询列表（接收 *gorm.DB）
-func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, db *gorm.DB, req *paginationV1.PagingRequest) (*PagingResult[DTO], error) {
+// buildPagingDB 按 PagingRequest 构造列表查询 DB，同时返回用于计数的 whereSelectors
+func (r *Repository[DTO, ENTITY]) buildPagingDB(ctx context.Context, db *gorm.DB, req *paginationV1.PagingRequest) (*gorm.DB, []func(*gorm.DB) *gorm.DB, error) {
 	if req == nil {
-		return nil, errors.New("paging request is nil")
+		return nil, nil, errPagingRequestIsNil
 	}
 	if db == nil {
-		return nil, errors.New("db is nil")
+		return nil, nil, errDBIsNil
+	}
+
+	req, err := r.limitPolicy.ApplyPaging(r.entityName, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var pagingSelector func(*gorm.DB) *gorm.DB
+
+	// pagination
+	if !req.GetNoPaging() {
+		if req.Page != nil && req.PageSize != nil {
+			pagingSelector = r.pagePaginator.BuildDB(int(req.GetPage()), int(req.GetPageSize()))
+		} else if req.Offset != nil && req.Limit != nil {
+			pagingSelector = r.offsetPaginator.BuildDB(int(req.GetOffset()), int(req.GetLimit()))
+		} else if req.Token != nil && req.Offset != nil {
+			pagingSelector = r.tokenPaginator.BuildDB(req.GetToken(), int(req.GetOffset()))
+		}
+	}
+
+	return r.buildListDB(ctx, db, relationQuery{
+		query:      req.GetQuery(),
+		orQuery:    req.GetOrQuery(),
+		filterExpr: req.GetFilterExpr(),
+		fieldMask:  req.GetFieldMask(),
+		sorting:    req.GetSorting(),
+		orderBy:    req.GetOrderBy(),
+	}, pagingSelector)
+}
+
+// buildListDB 按过滤、字段选择、排序与分页构造列表查询 DB，同时返回用于计数的 whereSelectors。
+// 涉及关联路径时，关联 JOIN 与去重计数一并包含在 whereSelectors 中
+func (r *Repository[DTO, ENTITY]) buildListDB(ctx context.Context, db *gorm.DB, q relationQuery, pagingSelector func(*gorm.DB) *gorm.DB) (*gorm.DB, []func(*gorm.DB) *gorm.DB, error) {
+	plan, err := r.planRelations(db, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	queryStringFilter, structuredFilter := r.queryStringFilter, r.structuredFilter
+	queryStringSorting, structuredSorting := r.queryStringSorting, r.structuredSorting
+	if plan != nil {
+		queryStringFilter = queryStringFilter.WithColumnResolver(plan.resolve)
+		structuredFilter = structuredFilter.WithColumnResolver(plan.resolve)
+		queryStringSorting = queryStringSorting.WithColumnResolver(plan.resolve)
+		structuredSorting = structuredSorting.WithColumnResolver(plan.resolve)
 	}
 
-	var err error
 	var whereSelectors []func(*gorm.DB) *gorm.DB
 	var selectSelector func(*gorm.DB) *gorm.DB
 	var sortingSelector func(*gorm.DB) *gorm.DB
-	var pagingSelector func(*gorm.DB) *gorm.DB
 
 	// filters
-	if req.Query != nil || req.OrQuery != nil {
-		whereSelectors, err = r.queryStringFilter.BuildSelectors(req.GetQuery(), req.GetOrQuery())
+	if q.query != "" || q.orQuery != "" {
+		whereSelectors, err = queryStringFilter.BuildSelectors(q.query, q.orQuery)
 		if err != nil {
 			log.Errorf("build query string filter selectors failed: %s", err.Error())
 		}
-	} else if req.FilterExpr != nil {
-		whereSelectors, err = r.structuredFilter.BuildSelectors(req.GetFilterExpr())
+	} else if q.filterExpr != nil {
+		whereSelectors, err = structuredFilter.BuildSelectors(q.filterExpr)
 		if err != nil {
 			log.Errorf("build structured filter selectors failed: %s", err.Error())
 		}
 	}
+	if plan != nil {
+		whereSelectors = append([]func(*gorm.DB) *gorm.DB{plan.joinScope}, whereSelectors...)
+	}
 
 	// select fields
-	if req.GetFieldMask() != nil && len(req.GetFieldMask().Paths) > 0 {
-		selectSelector, err = r.fieldSelector.BuildSelector(req.GetFieldMask().GetPaths())
+	if plan != nil {
+		selectSelector = plan.selectScope
+	} else if len(q.fieldMask.GetPaths()) > 0 {
+		selectSelector, err = r.fieldSelector.BuildSelector(q.fieldMask.GetPaths())
 		if err != nil {
 			log.Errorf("build field select selector failed: %s", err.Error())
 		}
 	}
 
 	// order by
-	if len(req.GetSorting()) > 0 {
-		sortingSelector = r.structuredSorting.BuildScope(req.GetSorting())
-	} else if len(req.GetOrderBy()) > 0 {
-		sortingSelector = r.queryStringSorting.BuildScope(req.GetOrderBy())
-	}
-
-	// pagination
-	if !req.GetNoPaging() {
-		if req.Page != nil && req.PageSize != nil {
-			pagingSelector = r.pagePaginator.BuildDB(int(req.GetPage()), int(req.GetPageSize()))
-		} else if req.Offset != nil && req.Limit != nil {
-			pagingSelector = r.offsetPaginator.BuildDB(int(req.GetOffset()), int(req.GetLimit()))
-		} else if req.Token != nil && req.Offset != nil {
-			pagingSelector = r.tokenPaginator.BuildDB(req.GetToken(), int(req.GetOffset()))
-		}
+	if len(q.sorting) > 0 {
+		sortingSelector = structuredSorting.BuildScope(q.sorting)
+	} else if len(q.orderBy) > 0 {
+		sortingSelector = queryStringSorting.BuildScope(q.orderBy)
 	}
 
 	// 构造查询 DB 并应用 selectors
@@ -209,11 +267,44 @@ func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, db *gorm.D
 		listDB = pagingSelector(listDB)
 	}
 
+	// 一对多 JOIN 时按主键去重计数
+	if plan != nil && plan.toMany {
+		whereSelectors = append(whereSelectors, plan.countScope)
+	}
+
+	return listDB, whereSelectors, nil
+}
+
+// ListWithPaging 使用 PagingRequest 查询列表（接收 *gorm.DB）
+func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, db *gorm.DB, req *paginationV1.PagingRequest) (ret *PagingResult[DTO], err error) {
+	obs := r.observe("ListWithPaging")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Items)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPagingRequest(req)
+
+	if req == nil {
+		return nil, errPagingRequestIsNil
+	}
+	if db == nil {
+		return nil, errDBIsNil
+	}
+
+	listDB, whereSelectors, err := r.buildPagingDB(ctx, db, req)
+	if err != nil {
+		return nil, err
+	}
+
 	// 执行查询
 	var entities []*ENTITY
-	if err = listDB.Find(&entities).Error; err != nil {
+	findDB := listDB.Find(&entities)
+	obs.SetStatement(findDB.Statement.SQL.String(), findDB.Statement.Vars...)
+	if err = findDB.Error; err != nil {
 		log.Errorf("query list failed: %s", err.Error())
-		return nil, errors.New("query list failed")
+		return nil, errQueryListFailed
 	}
 
 	// map to DTOs
@@ -237,47 +328,28 @@ func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, db *gorm.D
 }
 
 // ListWithPagination 使用 PaginationRequest 查询列表（接收 *gorm.DB）
-func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, db *gorm.DB, req *paginationV1.PaginationRequest) (*PagingResult[DTO], error) {
+func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, db *gorm.DB, req *paginationV1.PaginationRequest) (ret *PagingResult[DTO], err error) {
+	obs := r.observe("ListWithPagination")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Items)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPaginationRequest(req)
+
 	if req == nil {
-		return nil, errors.New("pagination request is nil")
+		return nil, errPaginationRequestIsNil
 	}
 	if db == nil {
-		return nil, errors.New("db is nil")
+		return nil, errDBIsNil
 	}
 
-	var err error
-	var whereSelectors []func(*gorm.DB) *gorm.DB
-	var selectSelector func(*gorm.DB) *gorm.DB
-	var sortingSelector func(*gorm.DB) *gorm.DB
-	var pagingSelector func(*gorm.DB) *gorm.DB
-
-	// filters
-	if req.Query != nil || req.OrQuery != nil {
-		whereSelectors, err = r.queryStringFilter.BuildSelectors(req.GetQuery(), req.GetOrQuery())
-		if err != nil {
-			log.Errorf("build query string filter selectors failed: %s", err.Error())
-		}
-	} else if req.FilterExpr != nil {
-		whereSelectors, err = r.structuredFilter.BuildSelectors(req.GetFilterExpr())
-		if err != nil {
-			log.Errorf("build structured filter selectors failed: %s", err.Error())
-		}
-	}
-
-	// select fields
-	if req.GetFieldMask() != nil && len(req.GetFieldMask().Paths) > 0 {
-		selectSelector, err = r.fieldSelector.BuildSelector(req.GetFieldMask().GetPaths())
-		if err != nil {
-			log.Errorf("build field select selector failed: %s", err.Error())
-		}
+	if req, err = r.limitPolicy.ApplyPagination(r.entityName, req); err != nil {
+		return nil, err
 	}
 
-	// order by
-	if len(req.GetSorting()) > 0 {
-		sortingSelector = r.structuredSorting.BuildScope(req.GetSorting())
-	} else if len(req.GetOrderBy()) > 0 {
-		sortingSelector = r.queryStringSorting.BuildScope(req.GetOrderBy())
-	}
+	var pagingSelector func(*gorm.DB) *gorm.DB
 
 	// pagination types
 	switch req.GetPaginationType().(type) {
@@ -289,28 +361,25 @@ func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, db *go
 		pagingSelector = r.tokenPaginator.BuildDB(req.GetTokenBased().GetToken(), int(req.GetTokenBased().GetPageSize()))
 	}
 
-	// 构造查询 DB 并应用 selectors
-	listDB := db.WithContext(ctx).Model(new(ENTITY))
-	for _, s := range whereSelectors {
-		if s != nil {
-			listDB = s(listDB)
-		}
-	}
-	if selectSelector != nil {
-		listDB = selectSelector(listDB)
-	}
-	if sortingSelector != nil {
-		listDB = sortingSelector(listDB)
-	}
-	if pagingSelector != nil {
-		listDB = pagingSelector(listDB)
+	listDB, whereSelectors, err := r.buildListDB(ctx, db, relationQuery{
+		query:      req.GetQuery(),
+		orQuery:    req.GetOrQuery(),
+		filterExpr: req.GetFilterExpr(),
+		fieldMask:  req.GetFieldMask(),
+		sorting:    req.GetSorting(),
+		orderBy:    req.GetOrderBy(),
+	}, pagingSelector)
+	if err != nil {
+		return nil, err
 	}
 
 	// 执行查询
 	var entities []*ENTITY
-	if err = listDB.Find(&entities).Error; err != nil {
+	findDB := listDB.Find(&entities)
+	obs.SetStatement(findDB.Statement.SQL.String(), findDB.Statement.Vars...)
+	if err = findDB.Error; err != nil {
 		log.Errorf("query list failed: %s", err.Error())
-		return nil, errors.New("query list failed")
+		return nil, errQueryListFailed
 	}
 
 	// map to DTOs
@@ -335,16 +404,32 @@ func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, db *go
 
 // Get 根据查询条件获取单条记录
 // 示例调用： `dto, err := q.Get(ctx, db.Where("id = ?", id), nil)`
-func (r *Repository[DTO, ENTITY]) Get(ctx context.Context, db *gorm.DB, viewMask *fieldmaskpb.FieldMask) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) Get(ctx context.Context, db *gorm.DB, viewMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("Get")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return nil, errors.New("db is nil")
+		return nil, errDBIsNil
 	}
 
-	field.NormalizeFieldMaskPaths(viewMask)
+	plan, err := r.planRelations(db, relationQuery{fieldMask: viewMask})
+	if err != nil {
+		return nil, err
+	}
 
 	qdb := db.WithContext(ctx).Model(new(ENTITY))
-	if viewMask != nil && len(viewMask.Paths) > 0 {
-		qdb = qdb.Select(viewMask.GetPaths())
+	if plan != nil {
+		qdb = plan.selectScope(qdb)
+	} else {
+		field.NormalizeFieldMaskPaths(viewMask)
+		if viewMask != nil && len(viewMask.Paths) > 0 {
+			qdb = qdb.Select(viewMask.GetPaths())
+		}
 	}
 
 	var ent ENTITY
@@ -360,13 +445,24 @@ func (r *Repository[DTO, ENTITY]) Get(ctx context.Context, db *gorm.DB, viewMask
 // 示例调用：使用 q.queryStringFilter 等构造 selectors 后调用新方法
 // whereSelectors, _ := q.queryStringFilter.BuildSelectors(req.GetQuery(), req.GetOrQuery())
 // dto, err := q.GetWithFilters(ctx, db, whereSelectors, viewMask)
-func (r *Repository[DTO, ENTITY]) GetWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, viewMask *fieldmaskpb.FieldMask) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) GetWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, viewMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("GetWithFilters")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return nil, errors.New("db is nil")
+		return nil, errDBIsNil
 	}
 
-	// 规范 viewMask 路径（复用已有 helper）
-	field.NormalizeFieldMaskPaths(viewMask)
+	// field_mask 中的关联路径转为 Preload
+	plan, err := r.planRelations(db, relationQuery{fieldMask: viewMask})
+	if err != nil {
+		return nil, err
+	}
 
 	// 构造查询 DB 并应用 where selectors
 	qdb := db.WithContext(ctx).Model(new(ENTITY))
@@ -377,8 +473,14 @@ func (r *Repository[DTO, ENTITY]) GetWithFilters(ctx context.Context, db *gorm.D
 	}
 
 	// 应用字段选择
-	if viewMask != nil && len(viewMask.Paths) > 0 {
-		qdb = qdb.Select(viewMask.GetPaths())
+	if plan != nil {
+		qdb = plan.selectScope(qdb)
+	} else {
+		// 规范 viewMask 路径（复用已有 helper）
+		field.NormalizeFieldMaskPaths(viewMask)
+		if viewMask != nil && len(viewMask.Paths) > 0 {
+			qdb = qdb.Select(viewMask.GetPaths())
+		}
 	}
 
 	// 执行查询
@@ -398,12 +500,20 @@ func (r *Repository[DTO, ENTITY]) Only(ctx context.Context, db *gorm.DB, viewMas
 
 // Create 在数据库中创建一条记录，返回创建后的 DTO
 // 示例调用： `dto, err := q.Create(ctx, db, dto, viewMask)`
-func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, db *gorm.DB, dto *DTO, viewMask *fieldmaskpb.FieldMask) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, db *gorm.DB, dto *DTO, viewMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("Create")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return nil, errors.New("db is nil")
+		return nil, errDBIsNil
 	}
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
 	// 规范 viewMask 路径（目前仅规范，返回时直接使用 mapper 的结果）
@@ -417,7 +527,7 @@ func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, db *gorm.DB, dto *
 	res := qdb.Create(&ent)
 	if res.Error != nil {
 		log.Errorf("create failed: %s", res.Error.Error())
-		return nil, errors.New("create failed")
+		return nil, errCreateFailed
 	}
 
 	// 返回创建后的 DTO（ent 已由 GORM 填充自增等字段）
@@ -426,12 +536,18 @@ func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, db *gorm.DB, dto *
 
 // CreateX 使用传入的 db 创建记录，支持 viewMask 指定插入字段，返回受影响行数
 // 示例调用： `rows, err := q.CreateX(ctx, db, dto, viewMask)`
-func (r *Repository[DTO, ENTITY]) CreateX(ctx context.Context, db *gorm.DB, dto *DTO, viewMask *fieldmaskpb.FieldMask) (int64, error) {
+func (r *Repository[DTO, ENTITY]) CreateX(ctx context.Context, db *gorm.DB, dto *DTO, viewMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("CreateX")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return 0, errors.New("db is nil")
+		return 0, errDBIsNil
 	}
 	if dto == nil {
-		return 0, errors.New("dto is nil")
+		return 0, errDTOIsNil
 	}
 
 	// 规范 viewMask 路径（目前仅规范）
@@ -452,19 +568,25 @@ func (r *Repository[DTO, ENTITY]) CreateX(ctx context.Context, db *gorm.DB, dto
 	res := qdb.Create(&ent)
 	if res.Error != nil {
 		log.Errorf("create failed: %s", res.Error.Error())
-		return 0, errors.New("create failed")
+		return 0, errCreateFailed
 	}
 	return res.RowsAffected, nil
 }
 
 // CreateXWithFilters 接受 whereSelectors 并在内部应用到查询 DB，然后执行创建，返回受影响行数
 // 示例调用：构造 selectors 后调用： `rows, err := q.CreateXWithFilters(ctx, db, whereSelectors, dto, viewMask)`
-func (r *Repository[DTO, ENTITY]) CreateXWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, dto *DTO, viewMask *fieldmaskpb.FieldMask) (int64, error) {
+func (r *Repository[DTO, ENTITY]) CreateXWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, dto *DTO, viewMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("CreateXWithFilters")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return 0, errors.New("db is nil")
+		return 0, errDBIsNil
 	}
 	if dto == nil {
-		return 0, errors.New("dto is nil")
+		return 0, errDTOIsNil
 	}
 
 	// 规范 viewMask 路径
@@ -490,16 +612,22 @@ func (r *Repository[DTO, ENTITY]) CreateXWithFilters(ctx context.Context, db *go
 	res := qdb.Create(&ent)
 	if res.Error != nil {
 		log.Errorf("create failed: %s", res.Error.Error())
-		return 0, errors.New("create failed")
+		return 0, errCreateFailed
 	}
 	return res.RowsAffected, nil
 }
 
 // BatchCreate 批量创建记录，返回创建后的 DTO 列表
 // 将此方法添加到 `gorm/repository.go` 中的 Repository 定义下
-func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, db *gorm.DB, dtos []*DTO, viewMask *fieldmaskpb.FieldMask) ([]*DTO, error) {
+func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, db *gorm.DB, dtos []*DTO, viewMask *fieldmaskpb.FieldMask) (ret []*DTO, err error) {
+	obs := r.observe("BatchCreate")
+	defer func() {
+		obs.AffectedRows(int64(len(ret)))
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return nil, errors.New("db is nil")
+		return nil, errDBIsNil
 	}
 	if len(dtos) == 0 {
 		return nil, nil
@@ -526,7 +654,7 @@ func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, db *gorm.DB,
 		createResult := qdb.Create(&ent)
 		if createResult.Error != nil {
 			log.Errorf("batch create failed: %s", createResult.Error.Error())
-			return nil, errors.New("batch create failed")
+			return nil, errBatchCreateFailed
 		}
 
 		res = append(res, r.mapper.ToDTO(ent))
@@ -537,12 +665,20 @@ func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, db *gorm.DB,
 
 // Update 使用传入的 db（可包含 Where）更新记录，支持 updateMask 指定更新字段
 // 示例调用： `dto, err := q.Update(ctx, db.Where("id = ?", id), dto, updateMask)`
-func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, db *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, db *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("Update")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return nil, errors.New("db is nil")
+		return nil, errDBIsNil
 	}
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
 	// 规范 updateMask 路径
@@ -559,11 +695,13 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, db *gorm.DB, dto *
 		qdb = qdb.Select(updateMask.GetPaths())
 	}
 
+	if qdb, err = r.applyGuardFilter(ctx, qdb, ent); err != nil {
+		return nil, err
+	}
+
 	// 执行更新
-	res := qdb.Updates(ent)
-	if res.Error != nil {
-		log.Errorf("update failed: %s", res.Error.Error())
-		return nil, errors.New("update failed")
+	if _, err := r.execWithGuard(qdb, "update failed", func(tx *gorm.DB) *gorm.DB { return tx.Updates(ent) }); err != nil {
+		return nil, err
 	}
 
 	// 读取并返回更新后的实体
@@ -577,12 +715,20 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, db *gorm.DB, dto *
 
 // UpdateWithFilters 接受 whereSelectors 并在内部应用到查询 DB，然后执行更新
 // 示例调用：构造 selectors 后调用： `dto, err := q.UpdateWithFilters(ctx, db, whereSelectors, dto, updateMask)`
-func (r *Repository[DTO, ENTITY]) UpdateWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) UpdateWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("UpdateWithFilters")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return nil, errors.New("db is nil")
+		return nil, errDBIsNil
 	}
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
 	// 规范 updateMask 路径
@@ -604,11 +750,13 @@ func (r *Repository[DTO, ENTITY]) UpdateWithFilters(ctx context.Context, db *gor
 		qdb = qdb.Select(updateMask.GetPaths())
 	}
 
+	if qdb, err = r.applyGuardFilter(ctx, qdb, ent); err != nil {
+		return nil, err
+	}
+
 	// 执行更新
-	res := qdb.Updates(ent)
-	if res.Error != nil {
-		log.Errorf("update failed: %s", res.Error.Error())
-		return nil, errors.New("update failed")
+	if _, err := r.execWithGuard(qdb, "update failed", func(tx *gorm.DB) *gorm.DB { return tx.Updates(ent) }); err != nil {
+		return nil, err
 	}
 
 	// 读取并返回更新后的实体
@@ -622,12 +770,18 @@ func (r *Repository[DTO, ENTITY]) UpdateWithFilters(ctx context.Context, db *gor
 
 // UpdateX 使用传入的 db（可包含 Where）更新记录，支持 updateMask 指定更新字段，返回受影响行数
 // 示例调用： `rows, err := q.UpdateX(ctx, db.Where("id = ?", id), dto, updateMask)`
-func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, db *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (int64, error) {
+func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, db *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("UpdateX")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return 0, errors.New("db is nil")
+		return 0, errDBIsNil
 	}
 	if dto == nil {
-		return 0, errors.New("dto is nil")
+		return 0, errDTOIsNil
 	}
 
 	// 规范 updateMask 路径
@@ -644,23 +798,28 @@ func (r *Repository[DTO, ENTITY]) UpdateX(ctx context.Context, db *gorm.DB, dto
 		qdb = qdb.Select(updateMask.GetPaths())
 	}
 
-	// 执行更新
-	res := qdb.Updates(ent)
-	if res.Error != nil {
-		log.Errorf("update failed: %s", res.Error.Error())
-		return 0, errors.New("update failed")
+	if qdb, err = r.applyGuardFilter(ctx, qdb, ent); err != nil {
+		return 0, err
 	}
-	return res.RowsAffected, nil
+
+	// 执行更新
+	return r.execWithGuard(qdb, "update failed", func(tx *gorm.DB) *gorm.DB { return tx.Updates(ent) })
 }
 
 // UpdateXWithFilters 接受 whereSelectors 并在内部应用到查询 DB，然后执行更新，返回受影响行数
 // 示例调用：构造 selectors 后调用： `rows, err := q.UpdateXWithFilters(ctx, db, whereSelectors, dto, updateMask)`
-func (r *Repository[DTO, ENTITY]) UpdateXWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (int64, error) {
+func (r *Repository[DTO, ENTITY]) UpdateXWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("UpdateXWithFilters")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return 0, errors.New("db is nil")
+		return 0, errDBIsNil
 	}
 	if dto == nil {
-		return 0, errors.New("dto is nil")
+		return 0, errDTOIsNil
 	}
 
 	// 规范 updateMask 路径
@@ -682,23 +841,30 @@ func (r *Repository[DTO, ENTITY]) UpdateXWithFilters(ctx context.Context, db *go
 		qdb = qdb.Select(updateMask.GetPaths())
 	}
 
-	// 执行更新
-	res := qdb.Updates(ent)
-	if res.Error != nil {
-		log.Errorf("update failed: %s", res.Error.Error())
-		return 0, errors.New("update failed")
+	if qdb, err = r.applyGuardFilter(ctx, qdb, ent); err != nil {
+		return 0, err
 	}
-	return res.RowsAffected, nil
+
+	// 执行更新
+	return r.execWithGuard(qdb, "update failed", func(tx *gorm.DB) *gorm.DB { return tx.Updates(ent) })
 }
 
 // Upsert 使用传入的 db（可包含 Where/其他 scope）执行插入或冲突更新，支持 updateMask 指定冲突时更新的字段
 // 示例调用： `dto, err := q.Upsert(ctx, db, dto, updateMask)`
-func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, db *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, db *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("Upsert")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return nil, errors.New("db is nil")
+		return nil, errDBIsNil
 	}
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
 	// 规范 updateMask 路径
@@ -726,7 +892,7 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, db *gorm.DB, dto *
 	res := qdb.Clauses(onConflict).Create(&ent)
 	if res.Error != nil {
 		log.Errorf("upsert failed: %s", res.Error.Error())
-		return nil, errors.New("upsert failed")
+		return nil, errUpsertFailed
 	}
 
 	// 返回 upsert 后的 DTO（ent 已由 GORM 填充）
@@ -735,12 +901,20 @@ func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, db *gorm.DB, dto *
 
 // UpsertWithFilters 接受 whereSelectors 并在内部应用到查询 DB，然后执行 upsert，支持 updateMask 指定冲突时更新的字段
 // 示例调用：构造 selectors 后调用： `dto, err := q.UpsertWithFilters(ctx, db, whereSelectors, dto, updateMask)`
-func (r *Repository[DTO, ENTITY]) UpsertWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) UpsertWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret *DTO, err error) {
+	obs := r.observe("UpsertWithFilters")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return nil, errors.New("db is nil")
+		return nil, errDBIsNil
 	}
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
 	// 规范 updateMask 路径
@@ -773,7 +947,7 @@ func (r *Repository[DTO, ENTITY]) UpsertWithFilters(ctx context.Context, db *gor
 	res := qdb.Clauses(onConflict).Create(&ent)
 	if res.Error != nil {
 		log.Errorf("upsert failed: %s", res.Error.Error())
-		return nil, errors.New("upsert failed")
+		return nil, errUpsertFailed
 	}
 
 	return r.mapper.ToDTO(ent), nil
@@ -781,12 +955,18 @@ func (r *Repository[DTO, ENTITY]) UpsertWithFilters(ctx context.Context, db *gor
 
 // UpsertX 使用传入的 db（可包含 Where/其他 scope）执行插入或冲突更新，支持 updateMask 指定冲突时更新的字段，返回受影响行数
 // 示例调用： `rows, err := q.UpsertX(ctx, db, dto, updateMask)`
-func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, db *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (int64, error) {
+func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, db *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("UpsertX")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return 0, errors.New("db is nil")
+		return 0, errDBIsNil
 	}
 	if dto == nil {
-		return 0, errors.New("dto is nil")
+		return 0, errDTOIsNil
 	}
 
 	// 规范 updateMask 路径
@@ -814,7 +994,7 @@ func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, db *gorm.DB, dto
 	res := qdb.Clauses(onConflict).Create(&ent)
 	if res.Error != nil {
 		log.Errorf("upsert failed: %s", res.Error.Error())
-		return 0, errors.New("upsert failed")
+		return 0, errUpsertFailed
 	}
 
 	return res.RowsAffected, nil
@@ -822,12 +1002,18 @@ func (r *Repository[DTO, ENTITY]) UpsertX(ctx context.Context, db *gorm.DB, dto
 
 // UpsertXWithFilters 接受 whereSelectors 并在内部应用到查询 DB，然后执行 upsert，支持 updateMask 指定冲突时更新的字段，返回受影响行数
 // 示例调用：构造 selectors 后调用： `rows, err := q.UpsertXWithFilters(ctx, db, whereSelectors, dto, updateMask)`
-func (r *Repository[DTO, ENTITY]) UpsertXWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (int64, error) {
+func (r *Repository[DTO, ENTITY]) UpsertXWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("UpsertXWithFilters")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return 0, errors.New("db is nil")
+		return 0, errDBIsNil
 	}
 	if dto == nil {
-		return 0, errors.New("dto is nil")
+		return 0, errDTOIsNil
 	}
 
 	// 规范 updateMask 路径
@@ -860,7 +1046,7 @@ func (r *Repository[DTO, ENTITY]) UpsertXWithFilters(ctx context.Context, db *go
 	res := qdb.Clauses(onConflict).Create(&ent)
 	if res.Error != nil {
 		log.Errorf("upsert failed: %s", res.Error.Error())
-		return 0, errors.New("upsert failed")
+		return 0, errUpsertFailed
 	}
 
 	return res.RowsAffected, nil
@@ -868,9 +1054,15 @@ func (r *Repository[DTO, ENTITY]) UpsertXWithFilters(ctx context.Context, db *go
 
 // Delete 使用传入的 db（可包含 Where）删除记录
 // 示例调用： `rows, err := q.Delete(ctx, db.Where("id = ?", id))`
-func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, db *gorm.DB, notSoftDelete bool) (int64, error) {
+func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, db *gorm.DB, notSoftDelete bool) (ret int64, err error) {
+	obs := r.observe("Delete")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return 0, errors.New("db is nil")
+		return 0, errDBIsNil
 	}
 
 	qdb := db.WithContext(ctx).Model(new(ENTITY))
@@ -879,19 +1071,24 @@ func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, db *gorm.DB, notSo
 		qdb = qdb.Unscoped()
 	}
 
-	res := qdb.Delete(new(ENTITY))
-	if res.Error != nil {
-		log.Errorf("delete failed: %s", res.Error.Error())
-		return 0, errors.New("delete failed")
+	if qdb, err = r.applyGuardFilter(ctx, qdb, nil); err != nil {
+		return 0, err
 	}
-	return res.RowsAffected, nil
+
+	return r.execWithGuard(qdb, "delete failed", func(tx *gorm.DB) *gorm.DB { return tx.Delete(new(ENTITY)) })
 }
 
 // DeleteWithFilters 接受 whereSelectors 并在内部应用到查询 DB，然后执行删除
 // 示例调用：构造 selectors 后调用： `rows, err := q.DeleteWithFilters(ctx, db, whereSelectors)`
-func (r *Repository[DTO, ENTITY]) DeleteWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB) (int64, error) {
+func (r *Repository[DTO, ENTITY]) DeleteWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB) (ret int64, err error) {
+	obs := r.observe("DeleteWithFilters")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if db == nil {
-		return 0, errors.New("db is nil")
+		return 0, errDBIsNil
 	}
 
 	qdb := db.WithContext(ctx).Model(new(ENTITY))
@@ -901,12 +1098,69 @@ func (r *Repository[DTO, ENTITY]) DeleteWithFilters(ctx context.Context, db *gor
 		}
 	}
 
-	res := qdb.Delete(new(ENTITY))
-	if res.Error != nil {
-		log.Errorf("delete failed: %s", res.Error.Error())
-		return 0, errors.New("delete failed")
+	if qdb, err = r.applyGuardFilter(ctx, qdb, nil); err != nil {
+		return 0, err
 	}
-	return res.RowsAffected, nil
+
+	return r.execWithGuard(qdb, "delete failed", func(tx *gorm.DB) *gorm.DB { return tx.Delete(new(ENTITY)) })
+}
+
+// UpdateByFilter 根据 FilterExpr 批量更新记录，返回受影响行数
+// 示例调用： `rows, err := q.UpdateByFilter(ctx, db, filterExpr, dto, updateMask)`
+func (r *Repository[DTO, ENTITY]) UpdateByFilter(ctx context.Context, db *gorm.DB, filterExpr *paginationV1.FilterExpr, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("UpdateByFilter")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+	obs.SetFilterExpr(filterExpr)
+
+	if db == nil {
+		return 0, errDBIsNil
+	}
+
+	if err = r.limitPolicy.CheckFilterExpr(r.entityName, filterExpr); err != nil {
+		return 0, err
+	}
+
+	whereSelectors, err := r.structuredFilter.BuildSelectors(filterExpr)
+	if err != nil {
+		log.Errorf("build structured filter selectors failed: %s", err.Error())
+		return 0, err
+	}
+
+	return r.UpdateXWithFilters(ctx, db, whereSelectors, dto, updateMask)
+}
+
+// DeleteByFilter 根据 FilterExpr 删除记录，返回受影响行数
+// soft 为 true 时执行软删除（需实体包含 gorm.DeletedAt 字段），否则物理删除
+func (r *Repository[DTO, ENTITY]) DeleteByFilter(ctx context.Context, db *gorm.DB, filterExpr *paginationV1.FilterExpr, soft bool) (ret int64, err error) {
+	obs := r.observe("DeleteByFilter")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+	obs.SetFilterExpr(filterExpr)
+
+	if db == nil {
+		return 0, errDBIsNil
+	}
+
+	if err = r.limitPolicy.CheckFilterExpr(r.entityName, filterExpr); err != nil {
+		return 0, err
+	}
+
+	whereSelectors, err := r.structuredFilter.BuildSelectors(filterExpr)
+	if err != nil {
+		log.Errorf("build structured filter selectors failed: %s", err.Error())
+		return 0, err
+	}
+
+	if !soft {
+		db = db.Unscoped()
+	}
+
+	return r.DeleteWithFilters(ctx, db, whereSelectors)
 }
 
 // SoftDelete 对符合 whereSelectors 的记录执行软删除
@@ -919,9 +1173,12 @@ func (r *Repository[DTO, ENTITY]) SoftDelete(ctx context.Context, db *gorm.DB) (
 
 // Exists 使用传入的 db（可包含 Where）检查是否存在记录
 // 示例调用： `exists, err := q.Exists(ctx, db.Where("id = ?", id))`
-func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, db *gorm.DB) (bool, error) {
+func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, db *gorm.DB) (ret bool, err error) {
+	obs := r.observe("Exists")
+	defer func() { obs.End(err) }()
+
 	if db == nil {
-		return false, errors.New("db is nil")
+		return false, errDBIsNil
 	}
 
 	qdb := db.WithContext(ctx).Model(new(ENTITY)).Limit(1)
@@ -932,16 +1189,19 @@ func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, db *gorm.DB) (bool
 			return false, nil
 		}
 		log.Errorf("exists query failed: %s", err.Error())
-		return false, errors.New("exists query failed")
+		return false, errExistsQueryFailed
 	}
 	return true, nil
 }
 
 // ExistsWithFilters 接受 whereSelectors 并在内部应用到查询 DB，然后检查是否存在记录
 // 示例调用：构造 selectors 后调用： `exists, err := q.ExistsWithFilters(ctx, db, whereSelectors)`
-func (r *Repository[DTO, ENTITY]) ExistsWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB) (bool, error) {
+func (r *Repository[DTO, ENTITY]) ExistsWithFilters(ctx context.Context, db *gorm.DB, whereSelectors []func(*gorm.DB) *gorm.DB) (ret bool, err error) {
+	obs := r.observe("ExistsWithFilters")
+	defer func() { obs.End(err) }()
+
 	if db == nil {
-		return false, errors.New("db is nil")
+		return false, errDBIsNil
 	}
 
 	qdb := db.WithContext(ctx).Model(new(ENTITY)).Limit(1)
@@ -957,7 +1217,7 @@ func (r *Repository[DTO, ENTITY]) ExistsWithFilters(ctx context.Context, db *gor
 			return false, nil
 		}
 		log.Errorf("exists query failed: %s", err.Error())
-		return false, errors.New("exists query failed")
+		return false, errExistsQueryFailed
 	}
 	return true, nil
 }
@@ -0,0 +1,106 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/search"
+)
+
+// searchRow 检索结果行：实体字段之外附带相关度分数与高亮片段
+type searchRow[ENTITY any] struct {
+	Entity     ENTITY         `gorm:"embedded"`
+	Score      float64        `gorm:"column:_search_score"`
+	Highlights sql.NullString `gorm:"column:_search_highlights"`
+}
+
+// Search 全文检索：在 PagingRequest 的过滤、排序与分页之上按 sreq 匹配，返回相关度分数与高亮片段。
+// Postgres 使用 tsvector，MySQL 使用 FULLTEXT 索引（高亮在内存中生成），SQLite 使用 FTS5 虚拟表。
+func (r *Repository[DTO, ENTITY]) Search(ctx context.Context, db *gorm.DB, req *paginationV1.PagingRequest, sreq *search.Request) (ret *search.Result[DTO], err error) {
+	obs := r.observe("Search")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Hits)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPagingRequest(req)
+
+	if req == nil {
+		return nil, errPagingRequestIsNil
+	}
+	if db == nil {
+		return nil, errDBIsNil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err = stmt.Parse(new(ENTITY)); err != nil {
+		log.Errorf("parse entity schema failed: %s", err.Error())
+		return nil, errParseEntitySchemaFailed
+	}
+	table := stmt.Schema.Table
+
+	s, err := search.BuildSQL(db.Dialector.Name(), table, sreq)
+	if err != nil {
+		return nil, err
+	}
+	matchSelector := func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(s.Where, s.WhereArgs...)
+	}
+
+	// 相关度排序需在 PagingRequest 的排序之前加入
+	searchDB := matchSelector(db)
+	if sreq.SortByRelevance {
+		searchDB = searchDB.Order(search.ScoreColumn + " DESC")
+	}
+
+	listDB, whereSelectors, err := r.buildPagingDB(ctx, searchDB, req)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := db.Statement.Quote(table) + ".*"
+	if len(listDB.Statement.Selects) > 0 {
+		columns = strings.Join(listDB.Statement.Selects, ", ")
+	}
+	selectSQL := columns + ", " + s.Score + " AS " + search.ScoreColumn
+	selectArgs := append([]any{}, s.ScoreArgs...)
+	if s.Highlight != "" {
+		selectSQL += ", " + s.Highlight + " AS " + search.HighlightColumn
+		selectArgs = append(selectArgs, s.HighlightArgs...)
+	}
+	listDB = listDB.Select(selectSQL, selectArgs...)
+
+	var rows []*searchRow[ENTITY]
+	findDB := listDB.Find(&rows)
+	obs.SetStatement(findDB.Statement.SQL.String(), findDB.Statement.Vars...)
+	if err = findDB.Error; err != nil {
+		log.Errorf("search query failed: %s", err.Error())
+		return nil, errSearchQueryFailed
+	}
+
+	hits := make([]*search.Hit[DTO], 0, len(rows))
+	for _, row := range rows {
+		dto := r.mapper.ToDTO(&row.Entity)
+		hit := &search.Hit[DTO]{Item: dto, Score: row.Score}
+		if s.Highlight != "" {
+			hit.Highlights = search.DecodeHighlights(row.Highlights.String)
+		} else {
+			hit.Highlights = search.HighlightItem(dto, sreq)
+		}
+		hits = append(hits, hit)
+	}
+
+	total, err := r.Count(ctx, db, append([]func(*gorm.DB) *gorm.DB{matchSelector}, whereSelectors...))
+	if err != nil {
+		log.Errorf("count query failed: %s", err.Error())
+		return nil, err
+	}
+
+	return &search.Result[DTO]{Hits: hits, Total: uint64(total)}, nil
+}
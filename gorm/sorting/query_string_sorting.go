@@ -8,7 +8,9 @@ import (
 )
 
 // QueryStringSorting 用于把查询字符串转换为 GORM 的 order scope
-type QueryStringSorting struct{}
+type QueryStringSorting struct {
+	resolver ColumnResolver
+}
 
 // NewQueryStringSorting 创建实例
 func NewQueryStringSorting() *QueryStringSorting {
@@ -62,6 +64,39 @@ func parseOrder(expr string) (string, string, bool) {
 	return expr, dir, true
 }
 
+// WithColumnResolver 返回使用 resolver 解析排序字段的副本，原实例不受影响
+func (qss QueryStringSorting) WithColumnResolver(resolver ColumnResolver) *QueryStringSorting {
+	qss.resolver = resolver
+	return &qss
+}
+
+// resolveOrder 使用 resolver 解析 order 表达式，先于 parseOrder 执行，
+// 使 "department.name" 这类关联字段不会被当作 "field.desc" 格式
+func (qss QueryStringSorting) resolveOrder(expr string) (string, string, bool) {
+	if qss.resolver == nil {
+		return "", "", false
+	}
+
+	expr = strings.TrimSpace(expr)
+	desc := strings.HasPrefix(expr, "-")
+	expr = strings.TrimPrefix(expr, "-")
+	if i := strings.LastIndexAny(expr, ":."); i > 0 {
+		switch strings.ToLower(expr[i+1:]) {
+		case "desc":
+			desc = true
+			expr = expr[:i]
+		case "asc":
+			expr = expr[:i]
+		}
+	}
+
+	column, ok := qss.resolver(expr)
+	if !ok {
+		return "", "", false
+	}
+	return column, toDirection(desc), true
+}
+
 // BuildScope 根据 orderBys 构建 GORM scope（可与 db.Scopes 一起使用）
 // orderBys 示例: []string{"-created_at", "name:asc", "user.id.desc"}
 func (qss QueryStringSorting) BuildScope(orderBys []string) func(*gorm.DB) *gorm.DB {
@@ -70,6 +105,10 @@ func (qss QueryStringSorting) BuildScope(orderBys []string) func(*gorm.DB) *gorm
 			return db
 		}
 		for _, ob := range orderBys {
+			if column, dir, ok := qss.resolveOrder(ob); ok {
+				db = db.Order(column + " " + dir)
+				continue
+			}
 			field, dir, ok := parseOrder(ob)
 			if !ok {
 				// 跳过不合法的字段表达式
@@ -5,24 +5,51 @@ import (
 	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	pagination "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/ordering"
 )
 
 // StructuredSorting 用于把结构化的排序指令转换为 GORM 的 order scope
-type StructuredSorting struct{}
+type StructuredSorting struct {
+	tieBreaker     string
+	autoTieBreaker bool
+	resolver       ColumnResolver
+}
 
-// NewStructuredSorting 创建实例
+// NewStructuredSorting 创建实例，默认以模型主键作为排序的兜底字段
 func NewStructuredSorting() *StructuredSorting {
-	return &StructuredSorting{}
+	return &StructuredSorting{autoTieBreaker: true}
+}
+
+// SetTieBreaker 设置兜底排序字段（升序追加在排序指令末尾以保证分页稳定），为空则不追加
+func (ss *StructuredSorting) SetTieBreaker(field string) *StructuredSorting {
+	ss.tieBreaker = strings.TrimSpace(field)
+	ss.autoTieBreaker = false
+	return ss
 }
 
-// BuildScope 根据 orders 构建 GORM scope（可与 db.Scopes 一起使用）
+// WithColumnResolver 返回使用 resolver 解析排序字段的副本，原实例不受影响
+func (ss StructuredSorting) WithColumnResolver(resolver ColumnResolver) *StructuredSorting {
+	ss.resolver = resolver
+	return &ss
+}
+
+// BuildScope 根据 orders 构建 GORM scope（可与 db.Scopes 一起使用）。
+// 支持空值位置、忽略大小写、排序规则、JSON 路径与日期部分，非法的排序指令将被忽略。
 func (ss StructuredSorting) BuildScope(orders []*pagination.Sorting) func(*gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		if len(orders) == 0 {
 			return db
 		}
+
+		var dialect string
+		if db.Dialector != nil {
+			dialect = db.Dialector.Name()
+		}
+
+		var applied bool
 		for _, o := range orders {
 			if o == nil {
 				continue
@@ -31,20 +58,57 @@ func (ss StructuredSorting) BuildScope(orders []*pagination.Sorting) func(*gorm.
 			if field == "" {
 				continue
 			}
+			column := field
+			if ss.resolver != nil {
+				if col, ok := ss.resolver(field); ok {
+					column = col
+				}
+			}
 			// 校验字段名，允许类似 "t.field"
-			if !fieldNameRegexp.MatchString(field) {
+			if column == field && !fieldNameRegexp.MatchString(field) {
 				continue
 			}
-			dir := "ASC"
-			if o.GetOrder() == pagination.Sorting_DESC {
-				dir = "DESC"
+			clauses, err := ordering.Clauses(dialect, column, o)
+			if err != nil {
+				continue
+			}
+			for _, c := range clauses {
+				db = db.Order(c)
 			}
-			db = db.Order(fmt.Sprintf("%s %s", field, dir))
+			applied = true
 		}
+
+		if applied {
+			if tb := ss.tieBreakerOf(db); tb != "" && len(ordering.WithTieBreaker(orders, tb)) > len(orders) {
+				db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: tb}})
+			}
+		}
+
 		return db
 	}
 }
 
+// tieBreakerOf 返回兜底排序字段；自动模式下取模型的主键列
+func (ss StructuredSorting) tieBreakerOf(db *gorm.DB) string {
+	if !ss.autoTieBreaker {
+		return ss.tieBreaker
+	}
+
+	stmt := db.Statement
+	if stmt == nil || stmt.Model == nil {
+		return ""
+	}
+	if stmt.Schema == nil {
+		if err := stmt.Parse(stmt.Model); err != nil {
+			return ""
+		}
+	}
+	if stmt.Schema == nil || stmt.Schema.PrioritizedPrimaryField == nil {
+		return ""
+	}
+	return stmt.Schema.PrioritizedPrimaryField.DBName
+}
+
 // BuildScopeWithDefaultField 当 orders 为空时使用默认排序字段
 // defaultOrderField 为空则不应用默认排序
 func (ss StructuredSorting) BuildScopeWithDefaultField(orders []*pagination.Sorting, defaultOrderField string, defaultDesc bool) func(*gorm.DB) *gorm.DB {
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 
+	"google.golang.org/protobuf/proto"
+
 	pagination "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
 )
 
@@ -68,3 +70,40 @@ func TestStructuredSorting_BuildScopeWithDefaultField(t *testing.T) {
 		t.Fatalf("expected ORDER BY score DESC, got: %s", sql2)
 	}
 }
+
+func TestStructuredSorting_BuildScope_Rich(t *testing.T) {
+	ss := NewStructuredSorting()
+
+	orders := []*pagination.Sorting{
+		{Field: "name", Order: pagination.Sorting_DESC, Nulls: pagination.Sorting_NULLS_LAST, CaseInsensitive: true},
+		{Field: "created_at", DatePart: pagination.DatePart_YEAR},
+	}
+	sql := sqlOfScope(t, ss.BuildScope(orders))
+	for _, want := range []string{
+		"LOWER(name) DESC NULLS LAST",
+		"CAST(strftime('%Y', created_at) AS INTEGER) ASC",
+		"`users`.`id`",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %q in SQL, got: %s", want, sql)
+		}
+	}
+
+	// 已按主键排序时不再追加
+	sql = sqlOfScope(t, ss.BuildScope([]*pagination.Sorting{{Field: "id", Order: pagination.Sorting_DESC}}))
+	if strings.Contains(sql, "`users`.`id`") {
+		t.Fatalf("did not expect tie-breaker when sorting by id, got: %s", sql)
+	}
+
+	// 关闭兜底排序
+	sql = sqlOfScope(t, NewStructuredSorting().SetTieBreaker("").BuildScope([]*pagination.Sorting{{Field: "name"}}))
+	if strings.Contains(sql, "`users`.`id`") {
+		t.Fatalf("did not expect tie-breaker, got: %s", sql)
+	}
+
+	// 非法 JSON 路径被忽略
+	sql = sqlOfScope(t, ss.BuildScope([]*pagination.Sorting{{Field: "name", JsonPath: proto.String("a'); --")}}))
+	if strings.Contains(sql, "--") {
+		t.Fatalf("unexpected injected sql: %s", sql)
+	}
+}
@@ -4,6 +4,10 @@ import "regexp"
 
 var fieldNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_\.]+$`)
 
+// ColumnResolver 将排序字段解析为完整的列表达式（如关联字段 "department.name" -> "Department"."name"）；
+// ok 为 false 时按原有规则处理该字段
+type ColumnResolver func(field string) (column string, ok bool)
+
 func toDirection(desc bool) string {
 	if desc {
 		return "DESC"
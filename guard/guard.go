@@ -0,0 +1,60 @@
+package guard
+
+import (
+	"errors"
+	"fmt"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+var (
+	// ErrFullTableOperation 未带过滤条件的更新/删除被拒绝
+	ErrFullTableOperation = errors.New("refusing to update or delete without a filter, set AllowFullTable to override")
+
+	// ErrTooManyRowsAffected 受影响行数超过 MaxAffectedRows
+	ErrTooManyRowsAffected = errors.New("affected rows exceed the configured limit")
+)
+
+// Options 破坏性操作（批量更新/删除）的安全保护配置，零值表示拒绝全表操作且不限制受影响行数
+type Options struct {
+	// AllowFullTable 允许不带过滤条件的更新/删除
+	AllowFullTable bool
+
+	// MaxAffectedRows 单次操作允许的最大受影响行数，0 表示不限制。
+	// 超出时操作被中止：支持事务的后端会回滚，其余后端在执行前按相同条件计数并拒绝执行。
+	MaxAffectedRows int64
+}
+
+// CheckFilter 校验是否带有过滤条件
+func (o *Options) CheckFilter(hasFilter bool) error {
+	if hasFilter || (o != nil && o.AllowFullTable) {
+		return nil
+	}
+	return ErrFullTableOperation
+}
+
+// CheckAffected 校验受影响行数是否超过上限
+func (o *Options) CheckAffected(affected int64) error {
+	if o == nil || o.MaxAffectedRows <= 0 || affected <= o.MaxAffectedRows {
+		return nil
+	}
+	return fmt.Errorf("%w: %d > %d", ErrTooManyRowsAffected, affected, o.MaxAffectedRows)
+}
+
+// HasFilterExpr 判断 FilterExpr 是否包含至少一个有效条件
+func HasFilterExpr(expr *paginationV1.FilterExpr) bool {
+	if expr == nil || expr.GetType() == paginationV1.ExprType_EXPR_TYPE_UNSPECIFIED {
+		return false
+	}
+	for _, c := range expr.GetConditions() {
+		if c != nil && c.GetField() != "" {
+			return true
+		}
+	}
+	for _, g := range expr.GetGroups() {
+		if HasFilterExpr(g) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package guard
+
+import (
+	"errors"
+	"testing"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+func TestOptions_CheckFilter(t *testing.T) {
+	var nilOpts *Options
+	if err := nilOpts.CheckFilter(false); !errors.Is(err, ErrFullTableOperation) {
+		t.Errorf("nil options: expected ErrFullTableOperation, got %v", err)
+	}
+	if err := nilOpts.CheckFilter(true); err != nil {
+		t.Errorf("nil options with filter: unexpected error %v", err)
+	}
+	if err := (&Options{}).CheckFilter(false); !errors.Is(err, ErrFullTableOperation) {
+		t.Errorf("zero options: expected ErrFullTableOperation, got %v", err)
+	}
+	if err := (&Options{AllowFullTable: true}).CheckFilter(false); err != nil {
+		t.Errorf("AllowFullTable: unexpected error %v", err)
+	}
+}
+
+func TestOptions_CheckAffected(t *testing.T) {
+	var nilOpts *Options
+	if err := nilOpts.CheckAffected(1 << 20); err != nil {
+		t.Errorf("nil options: unexpected error %v", err)
+	}
+
+	opts := &Options{MaxAffectedRows: 10}
+	if err := opts.CheckAffected(10); err != nil {
+		t.Errorf("at limit: unexpected error %v", err)
+	}
+	if err := opts.CheckAffected(11); !errors.Is(err, ErrTooManyRowsAffected) {
+		t.Errorf("over limit: expected ErrTooManyRowsAffected, got %v", err)
+	}
+}
+
+func TestHasFilterExpr(t *testing.T) {
+	cases := []struct {
+		name string
+		expr *paginationV1.FilterExpr
+		want bool
+	}{
+		{"nil", nil, false},
+		{"unspecified", &paginationV1.FilterExpr{
+			Conditions: []*paginationV1.Condition{{Field: "id"}},
+		}, false},
+		{"empty and", &paginationV1.FilterExpr{Type: paginationV1.ExprType_AND}, false},
+		{"empty field", &paginationV1.FilterExpr{
+			Type:       paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{{Field: ""}},
+		}, false},
+		{"condition", &paginationV1.FilterExpr{
+			Type:       paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{{Field: "id"}},
+		}, true},
+		{"nested group", &paginationV1.FilterExpr{
+			Type: paginationV1.ExprType_AND,
+			Groups: []*paginationV1.FilterExpr{{
+				Type:       paginationV1.ExprType_OR,
+				Conditions: []*paginationV1.Condition{{Field: "name"}},
+			}},
+		}, true},
+	}
+
+	for _, c := range cases {
+		if got := HasFilterExpr(c.expr); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
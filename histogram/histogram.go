@@ -0,0 +1,589 @@
+package histogram
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+// 结果集中分桶键与文档数的列别名
+const (
+	KeyColumn   = "_bucket"
+	CountColumn = "_count"
+)
+
+// KeyLayout SQL 与 ClickHouse 返回的分桶键格式（所选时区下的本地时间）
+const KeyLayout = "2006-01-02 15:04:05"
+
+// DefaultMaxBuckets 单次查询默认允许的最大分桶数
+const DefaultMaxBuckets = 10000
+
+var (
+	// ErrInvalidField 时间字段或聚合字段名不合法
+	ErrInvalidField = errors.New("invalid histogram field name")
+
+	// ErrInvalidInterval 分桶间隔不合法
+	ErrInvalidInterval = errors.New("invalid histogram interval")
+
+	// ErrInvalidTimezone 时区不合法
+	ErrInvalidTimezone = errors.New("invalid histogram timezone")
+
+	// ErrInvalidAggregate 聚合函数不合法
+	ErrInvalidAggregate = errors.New("invalid histogram aggregate")
+
+	// ErrInvalidRange 时间范围不合法
+	ErrInvalidRange = errors.New("invalid histogram time range")
+
+	// ErrTooManyBuckets 分桶数超过上限
+	ErrTooManyBuckets = errors.New("too many histogram buckets")
+)
+
+var (
+	fieldNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	timezoneRegexp  = regexp.MustCompile(`^[A-Za-z0-9_/+\-]+$`)
+	intervalRegexp  = regexp.MustCompile(`^(\d*)([A-Za-z]+)$`)
+)
+
+// Unit 分桶间隔的单位
+type Unit string
+
+const (
+	UnitSecond  Unit = "second"
+	UnitMinute  Unit = "minute"
+	UnitHour    Unit = "hour"
+	UnitDay     Unit = "day"
+	UnitWeek    Unit = "week"
+	UnitMonth   Unit = "month"
+	UnitQuarter Unit = "quarter"
+	UnitYear    Unit = "year"
+)
+
+// unitSuffixes 间隔后缀到单位的映射，M 为月、m 为分钟
+var unitSuffixes = map[string]Unit{
+	"s": UnitSecond, "second": UnitSecond,
+	"m": UnitMinute, "minute": UnitMinute,
+	"h": UnitHour, "hour": UnitHour,
+	"d": UnitDay, "day": UnitDay,
+	"w": UnitWeek, "week": UnitWeek,
+	"M": UnitMonth, "month": UnitMonth,
+	"q": UnitQuarter, "quarter": UnitQuarter,
+	"y": UnitYear, "year": UnitYear,
+}
+
+var unitSeconds = map[Unit]int64{
+	UnitSecond: 1,
+	UnitMinute: 60,
+	UnitHour:   3600,
+}
+
+// Interval 分桶间隔：一天以内的间隔须能整除一天，以便桶边界与零点对齐；日及以上的间隔只支持 1 个单位
+type Interval struct {
+	N    int
+	Unit Unit
+}
+
+// ParseInterval 解析分桶间隔，支持 "15m"、"1h"、"1d"、"1w"、"1M"、"1q"、"1y" 及 "hour"、"day" 等单位名
+func ParseInterval(s string) (Interval, error) {
+	m := intervalRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Interval{}, errors.Join(ErrInvalidInterval, errors.New(s))
+	}
+
+	unit, ok := unitSuffixes[m[2]]
+	if !ok {
+		unit, ok = unitSuffixes[strings.ToLower(m[2])]
+	}
+	if !ok {
+		return Interval{}, errors.Join(ErrInvalidInterval, errors.New(s))
+	}
+
+	n := 1
+	if m[1] != "" {
+		var err error
+		if n, err = strconv.Atoi(m[1]); err != nil {
+			return Interval{}, errors.Join(ErrInvalidInterval, errors.New(s))
+		}
+	}
+
+	iv := Interval{N: n, Unit: unit}
+	if !iv.valid() {
+		return Interval{}, errors.Join(ErrInvalidInterval, errors.New(s))
+	}
+	return iv, nil
+}
+
+func (iv Interval) valid() bool {
+	if iv.N <= 0 {
+		return false
+	}
+	if secs, ok := unitSeconds[iv.Unit]; ok {
+		total := secs * int64(iv.N)
+		return total <= 86400 && 86400%total == 0
+	}
+	return iv.N == 1
+}
+
+// Fixed 是否为一天以内的固定长度间隔
+func (iv Interval) Fixed() bool {
+	_, ok := unitSeconds[iv.Unit]
+	return ok
+}
+
+// Seconds 固定长度间隔的秒数，日及以上的间隔返回 0
+func (iv Interval) Seconds() int64 {
+	return unitSeconds[iv.Unit] * int64(iv.N)
+}
+
+// String 返回间隔的简写形式
+func (iv Interval) String() string {
+	for _, suffix := range []string{"s", "m", "h", "d", "w", "M", "q", "y"} {
+		if unitSuffixes[suffix] == iv.Unit {
+			return strconv.Itoa(iv.N) + suffix
+		}
+	}
+	return ""
+}
+
+// Truncate 返回 t 所在桶的起始时间（loc 时区下的本地时间），周以周一为起点
+func (iv Interval) Truncate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	y, mo, d := t.Date()
+
+	switch iv.Unit {
+	case UnitDay:
+		return time.Date(y, mo, d, 0, 0, 0, 0, loc)
+	case UnitWeek:
+		return time.Date(y, mo, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	case UnitMonth:
+		return time.Date(y, mo, 1, 0, 0, 0, 0, loc)
+	case UnitQuarter:
+		return time.Date(y, (mo-1)/3*3+1, 1, 0, 0, 0, 0, loc)
+	case UnitYear:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, loc)
+	}
+
+	secs := int64(t.Hour()*3600 + t.Minute()*60 + t.Second())
+	secs -= secs % iv.Seconds()
+	return time.Date(y, mo, d, 0, 0, int(secs), 0, loc)
+}
+
+// Next 返回下一个桶的起始时间
+func (iv Interval) Next(t time.Time) time.Time {
+	y, mo, d := t.Date()
+	h, mi, s := t.Clock()
+	loc := t.Location()
+
+	switch iv.Unit {
+	case UnitDay:
+		return time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+	case UnitWeek:
+		return time.Date(y, mo, d+7, 0, 0, 0, 0, loc)
+	case UnitMonth:
+		return time.Date(y, mo+1, 1, 0, 0, 0, 0, loc)
+	case UnitQuarter:
+		return time.Date(y, mo+3, 1, 0, 0, 0, 0, loc)
+	case UnitYear:
+		return time.Date(y+1, 1, 1, 0, 0, 0, 0, loc)
+	}
+
+	next := time.Date(y, mo, d, h, mi, s+int(iv.Seconds()), 0, loc)
+	if !next.After(t) {
+		// 夏令时回拨时本地时间重复，按绝对时长前进
+		next = t.Add(time.Duration(iv.Seconds()) * time.Second)
+	}
+	return next
+}
+
+// Func 聚合函数
+type Func string
+
+const (
+	FuncCount Func = "count"
+	FuncSum   Func = "sum"
+	FuncAvg   Func = "avg"
+	FuncMin   Func = "min"
+	FuncMax   Func = "max"
+)
+
+// Aggregate 每个桶内的聚合
+type Aggregate struct {
+	Func Func
+
+	// Field 聚合字段，count 为空时统计文档数
+	Field string
+
+	// Name 结果中的名称，默认为 <func>_<field>
+	Name string
+}
+
+// Alias 返回聚合结果的名称
+func (a Aggregate) Alias() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	if a.Field == "" {
+		return string(a.Func)
+	}
+	return string(a.Func) + "_" + a.Field
+}
+
+func (a Aggregate) validate() error {
+	switch a.Func {
+	case FuncCount:
+		if a.Field == "" {
+			break
+		}
+		fallthrough
+	case FuncSum, FuncAvg, FuncMin, FuncMax:
+		if !fieldNameRegexp.MatchString(a.Field) {
+			return errors.Join(ErrInvalidField, errors.New(a.Field))
+		}
+	default:
+		return errors.Join(ErrInvalidAggregate, errors.New(string(a.Func)))
+	}
+
+	if alias := a.Alias(); !fieldNameRegexp.MatchString(alias) || alias == KeyColumn || alias == CountColumn {
+		return errors.Join(ErrInvalidAggregate, errors.New(alias))
+	}
+	return nil
+}
+
+// Request 时间直方图请求：按 Interval 将 Field 分桶，统计每个桶的文档数与 Aggregates
+type Request struct {
+	// Field 时间字段名，InfluxDB 固定使用 time
+	Field string
+
+	// Interval 分桶间隔，见 ParseInterval
+	Interval string
+
+	// Timezone IANA 时区名，决定桶边界，默认 UTC
+	Timezone string
+
+	// Start、End 时间范围 [Start, End)，可选；设置后空桶会补齐到整个范围
+	Start time.Time
+	End   time.Time
+
+	// Aggregates 每个桶内附加的聚合
+	Aggregates []Aggregate
+
+	// 过滤条件，语义与 PagingRequest 中的同名字段一致
+	Query      string
+	OrQuery    string
+	FilterExpr *paginationV1.FilterExpr
+
+	// MaxBuckets 最大分桶数，小于等于 0 时使用 DefaultMaxBuckets
+	MaxBuckets int
+}
+
+// Validate 校验直方图请求
+func (r *Request) Validate() error {
+	if r == nil || !fieldNameRegexp.MatchString(r.Field) {
+		field := ""
+		if r != nil {
+			field = r.Field
+		}
+		return errors.Join(ErrInvalidField, errors.New(field))
+	}
+
+	iv, err := ParseInterval(r.Interval)
+	if err != nil {
+		return err
+	}
+	loc, err := r.Location()
+	if err != nil {
+		return err
+	}
+
+	for _, a := range r.Aggregates {
+		if err = a.validate(); err != nil {
+			return err
+		}
+	}
+
+	if !r.Start.IsZero() && !r.End.IsZero() {
+		if !r.Start.Before(r.End) {
+			return ErrInvalidRange
+		}
+		if n := estimateBuckets(iv, r.Start.In(loc), r.End.In(loc)); n > int64(r.maxBuckets()) {
+			return fmt.Errorf("%w: %d > %d", ErrTooManyBuckets, n, r.maxBuckets())
+		}
+	}
+	return nil
+}
+
+// ParsedInterval 返回解析后的分桶间隔
+func (r *Request) ParsedInterval() (Interval, error) {
+	return ParseInterval(r.Interval)
+}
+
+// Location 返回时区，未设置时为 UTC
+func (r *Request) Location() (*time.Location, error) {
+	if r.Timezone == "" {
+		return time.UTC, nil
+	}
+	if !timezoneRegexp.MatchString(r.Timezone) {
+		return nil, errors.Join(ErrInvalidTimezone, errors.New(r.Timezone))
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidTimezone, err)
+	}
+	return loc, nil
+}
+
+// TimezoneName 返回时区名，未设置时为 UTC
+func (r *Request) TimezoneName() string {
+	if r.Timezone == "" {
+		return "UTC"
+	}
+	return r.Timezone
+}
+
+// PagingRequest 返回只包含过滤条件的 PagingRequest，供各后端复用已有的过滤实现
+func (r *Request) PagingRequest() *paginationV1.PagingRequest {
+	noPaging := true
+	req := &paginationV1.PagingRequest{
+		NoPaging:   &noPaging,
+		FilterExpr: r.FilterExpr,
+	}
+	if r.Query != "" {
+		query := r.Query
+		req.Query = &query
+	}
+	if r.OrQuery != "" {
+		orQuery := r.OrQuery
+		req.OrQuery = &orQuery
+	}
+	return req
+}
+
+func (r *Request) maxBuckets() int {
+	if r.MaxBuckets <= 0 {
+		return DefaultMaxBuckets
+	}
+	return r.MaxBuckets
+}
+
+// estimateBuckets 估算 [start, end) 覆盖的桶数
+func estimateBuckets(iv Interval, start, end time.Time) int64 {
+	if iv.Fixed() {
+		return int64(end.Sub(start).Seconds())/iv.Seconds() + 2
+	}
+	days := int64(end.Sub(start).Hours()/24) + 1
+	switch iv.Unit {
+	case UnitWeek:
+		return days/7 + 2
+	case UnitMonth:
+		return days/28 + 2
+	case UnitQuarter:
+		return days/90 + 2
+	case UnitYear:
+		return days/365 + 2
+	}
+	return days + 1
+}
+
+// Bucket 直方图的一个桶
+type Bucket struct {
+	// Key 桶的起始时间，位于请求的时区
+	Key time.Time
+
+	// Count 桶内文档数
+	Count int64
+
+	// Values 聚合名到聚合值的映射，空桶的值为 0
+	Values map[string]float64
+}
+
+// Result 直方图结果，Buckets 按 Key 升序排列且已补齐空桶
+type Result struct {
+	Interval Interval
+	Location *time.Location
+	Buckets  []*Bucket
+}
+
+// Collector 收集各后端返回的分桶行，生成补零且有序的结果
+type Collector struct {
+	req      *Request
+	interval Interval
+	loc      *time.Location
+	buckets  map[int64]*Bucket
+}
+
+// NewCollector 创建收集器，req 须已通过校验
+func NewCollector(req *Request) (*Collector, error) {
+	iv, err := req.ParsedInterval()
+	if err != nil {
+		return nil, err
+	}
+	loc, err := req.Location()
+	if err != nil {
+		return nil, err
+	}
+	return &Collector{req: req, interval: iv, loc: loc, buckets: make(map[int64]*Bucket)}, nil
+}
+
+// Add 加入一行：key 为 time.Time 或 KeyLayout 格式的本地时间字符串，count 与 values 为数值（values 按 Aggregates 顺序）
+func (c *Collector) Add(key any, count any, values ...any) error {
+	var t time.Time
+	switch k := key.(type) {
+	case time.Time:
+		t = k
+	case string:
+		parsed, err := parseKey(k, c.loc)
+		if err != nil {
+			return err
+		}
+		t = parsed
+	case []byte:
+		parsed, err := parseKey(string(k), c.loc)
+		if err != nil {
+			return err
+		}
+		t = parsed
+	default:
+		return fmt.Errorf("unsupported histogram bucket key type %T", key)
+	}
+	t = c.interval.Truncate(t, c.loc)
+
+	b, ok := c.buckets[t.Unix()]
+	if !ok {
+		b = &Bucket{Key: t, Values: make(map[string]float64, len(c.req.Aggregates))}
+		c.buckets[t.Unix()] = b
+	}
+	b.Count += int64(ToFloat(count))
+
+	for i, a := range c.req.Aggregates {
+		if i >= len(values) {
+			break
+		}
+		v := ToFloat(values[i])
+		old, exists := b.Values[a.Alias()]
+		switch {
+		case !exists:
+			b.Values[a.Alias()] = v
+		case a.Func == FuncCount || a.Func == FuncSum:
+			b.Values[a.Alias()] = old + v
+		case a.Func == FuncMin:
+			b.Values[a.Alias()] = math.Min(old, v)
+		case a.Func == FuncMax:
+			b.Values[a.Alias()] = math.Max(old, v)
+		}
+	}
+	return nil
+}
+
+// Result 返回补齐空桶后的结果：设置了 Start/End 时覆盖整个范围，否则覆盖最早到最晚的非空桶
+func (c *Collector) Result() (*Result, error) {
+	res := &Result{Interval: c.interval, Location: c.loc}
+
+	var start, end time.Time
+	if !c.req.Start.IsZero() {
+		start = c.interval.Truncate(c.req.Start, c.loc)
+	}
+	if !c.req.End.IsZero() {
+		end = c.req.End.In(c.loc)
+	}
+
+	if len(c.buckets) > 0 {
+		keys := make([]int64, 0, len(c.buckets))
+		for k := range c.buckets {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+		if first := c.buckets[keys[0]].Key; start.IsZero() || first.Before(start) {
+			start = first
+		}
+		if last := c.buckets[keys[len(keys)-1]].Key; end.IsZero() || !last.Before(end) {
+			end = c.interval.Next(last)
+		}
+	}
+	if start.IsZero() || end.IsZero() {
+		return res, nil
+	}
+
+	for t := start; t.Before(end); t = c.interval.Next(t) {
+		if len(res.Buckets) >= c.req.maxBuckets() {
+			return nil, fmt.Errorf("%w: > %d", ErrTooManyBuckets, c.req.maxBuckets())
+		}
+		b, ok := c.buckets[t.Unix()]
+		if !ok {
+			b = &Bucket{Key: t, Values: make(map[string]float64, len(c.req.Aggregates))}
+		}
+		for _, a := range c.req.Aggregates {
+			if _, exists := b.Values[a.Alias()]; !exists {
+				b.Values[a.Alias()] = 0
+			}
+		}
+		res.Buckets = append(res.Buckets, b)
+	}
+	return res, nil
+}
+
+// parseKey 解析 KeyLayout 格式的本地时间，兼容带小数秒或 T 分隔的形式
+func parseKey(s string, loc *time.Location) (time.Time, error) {
+	s = strings.Replace(strings.TrimSpace(s), "T", " ", 1)
+	if len(s) > len(KeyLayout) {
+		s = s[:len(KeyLayout)]
+	}
+	t, err := time.ParseInLocation(KeyLayout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid histogram bucket key %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// ToFloat 将数据库驱动返回的数值转换为 float64，NULL、NaN 与无法识别的值视为 0
+func ToFloat(v any) float64 {
+	var f float64
+	switch n := v.(type) {
+	case nil:
+		return 0
+	case float64:
+		f = n
+	case float32:
+		f = float64(n)
+	case int:
+		f = float64(n)
+	case int8:
+		f = float64(n)
+	case int16:
+		f = float64(n)
+	case int32:
+		f = float64(n)
+	case int64:
+		f = float64(n)
+	case uint:
+		f = float64(n)
+	case uint8:
+		f = float64(n)
+	case uint16:
+		f = float64(n)
+	case uint32:
+		f = float64(n)
+	case uint64:
+		f = float64(n)
+	case []byte:
+		f, _ = strconv.ParseFloat(string(n), 64)
+	case string:
+		f, _ = strconv.ParseFloat(n, 64)
+	case *float64:
+		if n != nil {
+			f = *n
+		}
+	default:
+		return 0
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	return f
+}
@@ -0,0 +1,173 @@
+package histogram
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tx7do/go-crud/dialect"
+)
+
+func TestParseInterval(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Interval
+		err  bool
+	}{
+		{"15m", Interval{15, UnitMinute}, false},
+		{"1h", Interval{1, UnitHour}, false},
+		{"hour", Interval{1, UnitHour}, false},
+		{"6h", Interval{6, UnitHour}, false},
+		{"1M", Interval{1, UnitMonth}, false},
+		{"1q", Interval{1, UnitQuarter}, false},
+		{"w", Interval{1, UnitWeek}, false},
+		{"7m", Interval{}, true},
+		{"2d", Interval{}, true},
+		{"0h", Interval{}, true},
+		{"1x", Interval{}, true},
+		{"", Interval{}, true},
+	}
+	for _, c := range cases {
+		got, err := ParseInterval(c.in)
+		if c.err {
+			if !errors.Is(err, ErrInvalidInterval) {
+				t.Errorf("%q: expected ErrInvalidInterval, got %v", c.in, err)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%q: expected %v, got %v (%v)", c.in, c.want, got, err)
+		}
+	}
+}
+
+func TestInterval_Truncate(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	// 2024-05-15 (周三) 23:47:30 +08:00
+	ts := time.Date(2024, 5, 15, 15, 47, 30, 0, time.UTC)
+
+	cases := []struct {
+		interval string
+		want     string
+	}{
+		{"15m", "2024-05-15 23:45:00"},
+		{"6h", "2024-05-15 18:00:00"},
+		{"1d", "2024-05-15 00:00:00"},
+		{"1w", "2024-05-13 00:00:00"},
+		{"1M", "2024-05-01 00:00:00"},
+		{"1q", "2024-04-01 00:00:00"},
+		{"1y", "2024-01-01 00:00:00"},
+	}
+	for _, c := range cases {
+		iv, _ := ParseInterval(c.interval)
+		if got := iv.Truncate(ts, loc).Format(KeyLayout); got != c.want {
+			t.Errorf("%s: expected %s, got %s", c.interval, c.want, got)
+		}
+	}
+}
+
+func TestRequest_Validate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		req  *Request
+		want error
+	}{
+		{nil, ErrInvalidField},
+		{&Request{Field: "created_at; DROP TABLE x", Interval: "1h"}, ErrInvalidField},
+		{&Request{Field: "created_at", Interval: "5h"}, ErrInvalidInterval},
+		{&Request{Field: "created_at", Interval: "1h", Timezone: "Asia/Shanghai'"}, ErrInvalidTimezone},
+		{&Request{Field: "created_at", Interval: "1h", Aggregates: []Aggregate{{Func: "median", Field: "v"}}}, ErrInvalidAggregate},
+		{&Request{Field: "created_at", Interval: "1h", Aggregates: []Aggregate{{Func: FuncSum}}}, ErrInvalidField},
+		{&Request{Field: "created_at", Interval: "1h", Start: start, End: start}, ErrInvalidRange},
+		{&Request{Field: "created_at", Interval: "1s", Start: start, End: start.AddDate(1, 0, 0)}, ErrTooManyBuckets},
+		{&Request{Field: "created_at", Interval: "1h", Start: start, End: start.AddDate(0, 0, 7),
+			Aggregates: []Aggregate{{Func: FuncCount}, {Func: FuncAvg, Field: "amount"}}}, nil},
+	}
+	for i, c := range cases {
+		if err := c.req.Validate(); !errors.Is(err, c.want) {
+			t.Errorf("case %d: expected %v, got %v", i, c.want, err)
+		}
+	}
+}
+
+func TestCollector_Fill(t *testing.T) {
+	req := &Request{
+		Field:      "created_at",
+		Interval:   "1d",
+		Start:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:        time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		Aggregates: []Aggregate{{Func: FuncSum, Field: "amount"}},
+	}
+	c, err := NewCollector(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Add("2024-01-03 00:00:00", int64(2), []byte("7.5")); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Add(time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC), uint64(1), 3.0); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Buckets) != 4 {
+		t.Fatalf("expected 4 buckets, got %d", len(res.Buckets))
+	}
+	wantCounts := []int64{1, 0, 2, 0}
+	wantSums := []float64{3, 0, 7.5, 0}
+	for i, b := range res.Buckets {
+		if day := b.Key.Day(); day != i+1 {
+			t.Errorf("bucket %d: unexpected key %v", i, b.Key)
+		}
+		if b.Count != wantCounts[i] || b.Values["sum_amount"] != wantSums[i] {
+			t.Errorf("bucket %d: expected %d/%v, got %d/%v", i, wantCounts[i], wantSums[i], b.Count, b.Values)
+		}
+	}
+
+	if err = c.Add("not a time", 1); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
+
+func TestBuildSQL(t *testing.T) {
+	req := &Request{
+		Field:      "created_at",
+		Interval:   "15m",
+		Timezone:   "UTC",
+		Start:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Aggregates: []Aggregate{{Func: FuncAvg, Field: "latency", Name: "avg_latency"}},
+	}
+
+	cases := map[string][]string{
+		dialect.Postgres:   {"date_trunc('day', (created_at AT TIME ZONE 'UTC'))", "/ 900) * interval '900 seconds'", "AVG(latency) AS avg_latency"},
+		dialect.MySQL:      {"FLOOR(TIME_TO_SEC(created_at) / 900) * 900 SECOND", "COUNT(*) AS _count"},
+		dialect.SQLite:     {"strftime('%Y-%m-%d %H:%M:%S', created_at, 'start of day'", "% 86400 / 900 * 900"},
+		dialect.ClickHouse: {"toStartOfInterval(created_at, INTERVAL 15 MINUTE, 'UTC')", "toFloat64(avg(latency)) AS avg_latency"},
+	}
+	for dialect, wants := range cases {
+		s, err := BuildSQL(dialect, req)
+		if err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		sel := s.Select()
+		for _, want := range wants {
+			if !strings.Contains(sel, want) {
+				t.Errorf("%s: select missing %q:\n%s", dialect, want, sel)
+			}
+		}
+		if s.Where != "created_at >= ?" || len(s.WhereArgs) != 1 || s.GroupBy != KeyColumn {
+			t.Errorf("%s: unexpected where/group: %q %v %q", dialect, s.Where, s.WhereArgs, s.GroupBy)
+		}
+	}
+
+	if _, err := BuildSQL("oracle", req); !errors.Is(err, ErrUnsupportedDialect) {
+		t.Errorf("expected ErrUnsupportedDialect, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package histogram
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tx7do/go-crud/dialect"
+)
+
+// ErrUnsupportedDialect 方言不支持时间直方图
+var ErrUnsupportedDialect = errors.New("histogram is not supported for dialect")
+
+// SQL 时间直方图的 SQL 片段，参数使用 ? 占位
+type SQL struct {
+	// Columns SELECT 列：分桶键（KeyColumn，KeyLayout 格式的本地时间字符串）、文档数（CountColumn）与各聚合（按 Aggregates 顺序，以 Alias 命名）
+	Columns []string
+
+	// Where 时间范围条件，未设置 Start/End 时为空
+	Where     string
+	WhereArgs []any
+
+	// GroupBy、OrderBy 按分桶键分组并升序排列
+	GroupBy string
+	OrderBy string
+}
+
+// Select 返回逗号连接的 SELECT 列
+func (s *SQL) Select() string {
+	return strings.Join(s.Columns, ", ")
+}
+
+// BuildSQL 生成指定方言的时间直方图 SQL 片段：
+//   - Postgres：date_trunc 作用于 AT TIME ZONE 转换后的本地时间，多单位的间隔按秒数向下取整；
+//   - MySQL：CONVERT_TZ 后用 DATE_FORMAT 截断（需加载时区表），周按 WEEKDAY 回退到周一；
+//   - SQLite：按时区在 Start（未设置时为当前时间）的偏移量换算，strftime 截断；
+//   - ClickHouse：toStartOfInterval、toMonday、toStartOfMonth 等带时区的函数。
+func BuildSQL(sqlDialect string, req *Request) (*SQL, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	iv, _ := req.ParsedInterval()
+	loc, _ := req.Location()
+
+	var key string
+	sqlDialect = dialect.Normalize(sqlDialect)
+	switch sqlDialect {
+	case dialect.Postgres:
+		key = postgresKey(req.Field, req.TimezoneName(), iv)
+	case dialect.MySQL:
+		key = mysqlKey(req.Field, req.TimezoneName(), iv)
+	case dialect.SQLite:
+		ref := req.Start
+		if ref.IsZero() {
+			ref = time.Now()
+		}
+		_, offset := ref.In(loc).Zone()
+		key = sqliteKey(req.Field, offset, iv)
+	case dialect.ClickHouse:
+		key = clickhouseKey(req.Field, req.TimezoneName(), iv)
+	default:
+		return nil, errors.Join(ErrUnsupportedDialect, errors.New(sqlDialect))
+	}
+
+	s := &SQL{
+		Columns: []string{key + " AS " + KeyColumn, countExpr(sqlDialect, "") + " AS " + CountColumn},
+		GroupBy: KeyColumn,
+		OrderBy: KeyColumn,
+	}
+	for _, a := range req.Aggregates {
+		s.Columns = append(s.Columns, aggregateExpr(sqlDialect, a)+" AS "+a.Alias())
+	}
+
+	var conds []string
+	if !req.Start.IsZero() {
+		conds = append(conds, req.Field+" >= ?")
+		s.WhereArgs = append(s.WhereArgs, req.Start)
+	}
+	if !req.End.IsZero() {
+		conds = append(conds, req.Field+" < ?")
+		s.WhereArgs = append(s.WhereArgs, req.End)
+	}
+	s.Where = strings.Join(conds, " AND ")
+
+	return s, nil
+}
+
+func countExpr(sqlDialect, field string) string {
+	if sqlDialect == dialect.ClickHouse {
+		return "toFloat64(count(" + field + "))"
+	}
+	if field == "" {
+		field = "*"
+	}
+	return "COUNT(" + field + ")"
+}
+
+func aggregateExpr(sqlDialect string, a Aggregate) string {
+	if a.Func == FuncCount {
+		return countExpr(sqlDialect, a.Field)
+	}
+	if sqlDialect == dialect.ClickHouse {
+		return "toFloat64(" + string(a.Func) + "(" + a.Field + "))"
+	}
+	return strings.ToUpper(string(a.Func)) + "(" + a.Field + ")"
+}
+
+func postgresKey(field, tz string, iv Interval) string {
+	local := fmt.Sprintf("(%s AT TIME ZONE '%s')", field, tz)
+
+	var key string
+	switch {
+	case !iv.Fixed() || iv.N == 1:
+		key = fmt.Sprintf("date_trunc('%s', %s)", iv.Unit, local)
+	default:
+		day := fmt.Sprintf("date_trunc('day', %s)", local)
+		key = fmt.Sprintf("%s + floor(extract(epoch from %s - %s) / %d) * interval '%d seconds'",
+			day, local, day, iv.Seconds(), iv.Seconds())
+	}
+	return fmt.Sprintf("to_char(%s, 'YYYY-MM-DD HH24:MI:SS')", key)
+}
+
+func mysqlKey(field, tz string, iv Interval) string {
+	local := field
+	if tz != "UTC" {
+		local = fmt.Sprintf("CONVERT_TZ(%s, '+00:00', '%s')", field, tz)
+	}
+
+	switch iv.Unit {
+	case UnitDay:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d 00:00:00')", local)
+	case UnitWeek:
+		return fmt.Sprintf("DATE_FORMAT(DATE_SUB(DATE(%s), INTERVAL WEEKDAY(%s) DAY), '%%Y-%%m-%%d 00:00:00')", local, local)
+	case UnitMonth:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-01 00:00:00')", local)
+	case UnitQuarter:
+		return fmt.Sprintf("CONCAT(YEAR(%s), '-', LPAD(QUARTER(%s) * 3 - 2, 2, '0'), '-01 00:00:00')", local, local)
+	case UnitYear:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-01-01 00:00:00')", local)
+	}
+
+	if iv.N == 1 {
+		switch iv.Unit {
+		case UnitSecond:
+			return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:%%i:%%s')", local)
+		case UnitMinute:
+			return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:%%i:00')", local)
+		case UnitHour:
+			return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:00:00')", local)
+		}
+	}
+	return fmt.Sprintf("DATE_FORMAT(DATE_ADD(DATE(%s), INTERVAL FLOOR(TIME_TO_SEC(%s) / %d) * %d SECOND), '%%Y-%%m-%%d %%H:%%i:%%s')",
+		local, local, iv.Seconds(), iv.Seconds())
+}
+
+func sqliteKey(field string, offset int, iv Interval) string {
+	local := field
+	if offset != 0 {
+		local = fmt.Sprintf("datetime(%s, '%+d seconds')", field, offset)
+	}
+
+	switch iv.Unit {
+	case UnitDay:
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00', %s)", local)
+	case UnitWeek:
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00', %s, 'weekday 0', '-6 days')", local)
+	case UnitMonth:
+		return fmt.Sprintf("strftime('%%Y-%%m-01 00:00:00', %s)", local)
+	case UnitQuarter:
+		return fmt.Sprintf("printf('%%s-%%02d-01 00:00:00', strftime('%%Y', %s), (CAST(strftime('%%m', %s) AS INTEGER) - 1) / 3 * 3 + 1)", local, local)
+	case UnitYear:
+		return fmt.Sprintf("strftime('%%Y-01-01 00:00:00', %s)", local)
+	}
+
+	if iv.N == 1 {
+		switch iv.Unit {
+		case UnitSecond:
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:%%S', %s)", local)
+		case UnitMinute:
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:00', %s)", local)
+		case UnitHour:
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:00:00', %s)", local)
+		}
+	}
+	return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:%%S', %s, 'start of day', '+' || (CAST(strftime('%%s', %s) AS INTEGER) %% 86400 / %d * %d) || ' seconds')",
+		local, local, iv.Seconds(), iv.Seconds())
+}
+
+func clickhouseKey(field, tz string, iv Interval) string {
+	var key string
+	switch iv.Unit {
+	case UnitDay:
+		key = fmt.Sprintf("toStartOfDay(%s, '%s')", field, tz)
+	case UnitWeek:
+		key = fmt.Sprintf("toDateTime(toMonday(%s, '%s'), '%s')", field, tz, tz)
+	case UnitMonth:
+		key = fmt.Sprintf("toDateTime(toStartOfMonth(%s, '%s'), '%s')", field, tz, tz)
+	case UnitQuarter:
+		key = fmt.Sprintf("toDateTime(toStartOfQuarter(%s, '%s'), '%s')", field, tz, tz)
+	case UnitYear:
+		key = fmt.Sprintf("toDateTime(toStartOfYear(%s, '%s'), '%s')", field, tz, tz)
+	default:
+		key = fmt.Sprintf("toStartOfInterval(%s, INTERVAL %d %s, '%s')", field, iv.N, strings.ToUpper(string(iv.Unit)), tz)
+	}
+	return fmt.Sprintf("formatDateTime(%s, '%%Y-%%m-%%d %%H:%%i:%%S', '%s')", key, tz)
+}
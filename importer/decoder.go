@@ -0,0 +1,411 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// timeLayouts CSV 中时间字段支持的格式
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.DateTime,
+	time.DateOnly,
+	"2006-01-02T15:04:05",
+}
+
+// parseError 单行解析失败，不影响后续行的读取
+type parseError struct {
+	err error
+}
+
+func (e *parseError) Error() string { return e.err.Error() }
+func (e *parseError) Unwrap() error { return e.err }
+
+// decoder 逐行读取源数据并转换为 DTO
+type decoder[DTO any] struct {
+	opts    *Options
+	isProto bool
+
+	// CSV
+	csvReader   *csv.Reader
+	protoFields []protoreflect.FieldDescriptor
+	structIdx   [][]int
+
+	// JSONL
+	lineReader *bufio.Reader
+	line       int
+}
+
+func newDecoder[DTO any](src io.Reader, opts *Options) (*decoder[DTO], error) {
+	d := &decoder[DTO]{opts: opts}
+	_, d.isProto = any(new(DTO)).(proto.Message)
+
+	switch opts.Format {
+	case FormatCSV:
+		if err := d.initCSV(src); err != nil {
+			return nil, err
+		}
+	case FormatJSONL:
+		d.lineReader = bufio.NewReader(src)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %d", opts.Format)
+	}
+
+	return d, nil
+}
+
+// mapColumn 按 ColumnMapping 将源列名转换为 DTO 字段名
+func (d *decoder[DTO]) mapColumn(col string) string {
+	if mapped, ok := d.opts.ColumnMapping[col]; ok && mapped != "" {
+		return mapped
+	}
+	return col
+}
+
+func (d *decoder[DTO]) initCSV(src io.Reader) error {
+	d.csvReader = csv.NewReader(src)
+	if d.opts.Comma != 0 {
+		d.csvReader.Comma = d.opts.Comma
+	}
+	d.csvReader.TrimLeadingSpace = true
+
+	header, err := d.csvReader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return errors.New("csv header is missing")
+		}
+		return err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\uFEFF")
+	}
+
+	if d.isProto {
+		desc := any(new(DTO)).(proto.Message).ProtoReflect().Descriptor()
+		d.protoFields = make([]protoreflect.FieldDescriptor, len(header))
+		for i, col := range header {
+			d.protoFields[i] = lookupProtoField(desc, d.mapColumn(strings.TrimSpace(col)))
+		}
+	} else {
+		t := reflect.TypeOf(new(DTO)).Elem()
+		if t.Kind() != reflect.Struct {
+			return errors.New("dto must be a struct or proto message")
+		}
+		d.structIdx = make([][]int, len(header))
+		for i, col := range header {
+			d.structIdx[i] = lookupStructField(t, d.mapColumn(strings.TrimSpace(col)))
+		}
+	}
+
+	return nil
+}
+
+// next 读取下一行，返回行号与解析后的 DTO；读取结束时返回 io.EOF
+func (d *decoder[DTO]) next() (int, *DTO, error) {
+	if d.csvReader != nil {
+		return d.nextCSV()
+	}
+	return d.nextJSONL()
+}
+
+func (d *decoder[DTO]) nextCSV() (int, *DTO, error) {
+	record, err := d.csvReader.Read()
+	if err == io.EOF {
+		return 0, nil, io.EOF
+	}
+	if err != nil {
+		var pErr *csv.ParseError
+		if errors.As(err, &pErr) {
+			return pErr.StartLine, nil, &parseError{err: err}
+		}
+		return 0, nil, err
+	}
+
+	line, _ := d.csvReader.FieldPos(0)
+
+	dto := new(DTO)
+	if d.isProto {
+		err = d.fillProto(any(dto).(proto.Message), record)
+	} else {
+		err = d.fillStruct(reflect.ValueOf(dto).Elem(), record)
+	}
+	if err != nil {
+		return line, nil, &parseError{err: err}
+	}
+
+	return line, dto, nil
+}
+
+func (d *decoder[DTO]) nextJSONL() (int, *DTO, error) {
+	for {
+		raw, err := d.lineReader.ReadBytes('\n')
+		if len(raw) == 0 && err != nil {
+			return 0, nil, err
+		}
+		d.line++
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+		if d.line == 1 {
+			raw = bytes.TrimPrefix(raw, []byte("\uFEFF"))
+		}
+
+		dto, dErr := d.decodeJSON(raw)
+		if dErr != nil {
+			return d.line, nil, &parseError{err: dErr}
+		}
+		return d.line, dto, nil
+	}
+}
+
+func (d *decoder[DTO]) decodeJSON(raw []byte) (*DTO, error) {
+	if len(d.opts.ColumnMapping) > 0 {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, err
+		}
+		mapped := make(map[string]json.RawMessage, len(obj))
+		for k, v := range obj {
+			mapped[d.mapColumn(k)] = v
+		}
+		var err error
+		if raw, err = json.Marshal(mapped); err != nil {
+			return nil, err
+		}
+	}
+
+	dto := new(DTO)
+	if d.isProto {
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, any(dto).(proto.Message)); err != nil {
+			return nil, err
+		}
+		return dto, nil
+	}
+
+	if err := json.Unmarshal(raw, dto); err != nil {
+		return nil, err
+	}
+	return dto, nil
+}
+
+// fillProto 将 CSV 行转为 JSON 后交给 protojson 解析，以复用其类型转换与错误信息
+func (d *decoder[DTO]) fillProto(msg proto.Message, record []string) error {
+	obj := make(map[string]json.RawMessage, len(record))
+	for i, val := range record {
+		if i >= len(d.protoFields) || d.protoFields[i] == nil || val == "" {
+			continue
+		}
+		fd := d.protoFields[i]
+		raw, err := csvValueToJSON(fd, val)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", fd.Name(), err)
+		}
+		obj[fd.JSONName()] = raw
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return protojson.Unmarshal(data, msg)
+}
+
+func (d *decoder[DTO]) fillStruct(v reflect.Value, record []string) error {
+	for i, val := range record {
+		if i >= len(d.structIdx) || d.structIdx[i] == nil || val == "" {
+			continue
+		}
+		fv := v.FieldByIndex(d.structIdx[i])
+		if err := setReflectValue(fv, val); err != nil {
+			return fmt.Errorf("column %s: %w", v.Type().FieldByIndex(d.structIdx[i]).Name, err)
+		}
+	}
+	return nil
+}
+
+// lookupProtoField 依次按 proto 字段名、JSON 名、忽略大小写匹配字段
+func lookupProtoField(desc protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := desc.Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	if fd := fields.ByJSONName(name); fd != nil {
+		return fd
+	}
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if strings.EqualFold(string(fd.Name()), name) || strings.EqualFold(fd.JSONName(), name) {
+			return fd
+		}
+	}
+	return nil
+}
+
+// lookupStructField 按 json tag 或字段名（忽略大小写）匹配导出字段
+func lookupStructField(t reflect.Type, name string) []int {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if idx := strings.Index(tag, ","); idx != -1 {
+			tag = tag[:idx]
+		}
+		if tag == "-" {
+			continue
+		}
+		if strings.EqualFold(tag, name) || strings.EqualFold(sf.Name, name) {
+			return sf.Index
+		}
+	}
+	return nil
+}
+
+// csvValueToJSON 将 CSV 单元格按字段类型转换为 JSON 值
+func csvValueToJSON(fd protoreflect.FieldDescriptor, val string) (json.RawMessage, error) {
+	if fd.IsMap() {
+		return json.RawMessage(val), nil
+	}
+	if fd.IsList() {
+		if strings.HasPrefix(strings.TrimSpace(val), "[") {
+			return json.RawMessage(val), nil
+		}
+		parts := strings.Split(val, ",")
+		items := make([]json.RawMessage, 0, len(parts))
+		for _, p := range parts {
+			item, err := scalarToJSON(fd, strings.TrimSpace(p))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return json.Marshal(items)
+	}
+	return scalarToJSON(fd, val)
+}
+
+func scalarToJSON(fd protoreflect.FieldDescriptor, val string) (json.RawMessage, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(b)
+
+	case protoreflect.EnumKind:
+		if n, err := strconv.ParseInt(val, 10, 32); err == nil {
+			return json.Marshal(n)
+		}
+		return json.Marshal(val)
+
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		switch fd.Message().FullName() {
+		case "google.protobuf.BoolValue":
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(b)
+		case "google.protobuf.Timestamp":
+			t, err := parseTime(val)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(t.UTC().Format(time.RFC3339Nano))
+		}
+		trimmed := strings.TrimSpace(val)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			return json.RawMessage(trimmed), nil
+		}
+		return json.Marshal(val)
+
+	default:
+		// 数值、字符串、bytes：protojson 均接受字符串形式
+		return json.Marshal(val)
+	}
+}
+
+func parseTime(val string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, val); err == nil {
+			return t, nil
+		}
+	}
+	if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time value %q", val)
+}
+
+// setReflectValue 将字符串按目标字段类型赋值
+func setReflectValue(v reflect.Value, val string) error {
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setReflectValue(elem.Elem(), val); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		t, err := parseTime(val)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return json.Unmarshal([]byte(val), v.Addr().Interface())
+	}
+	return nil
+}
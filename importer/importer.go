@@ -0,0 +1,256 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// DefaultBatchSize 默认分块大小
+var DefaultBatchSize = 500
+
+// Format 导入数据的格式
+type Format int
+
+const (
+	FormatCSV   Format = iota // CSV，首行为表头
+	FormatJSONL               // JSON Lines，每行一个 JSON 对象
+)
+
+// Mode 写入方式
+type Mode int
+
+const (
+	ModeInsert Mode = iota // 仅插入（BatchCreate）
+	ModeUpsert             // 插入或更新（Upsert）
+)
+
+// Status 单行的处理结果
+type Status int
+
+const (
+	StatusInserted Status = iota // 已插入
+	StatusUpdated                // 已更新
+	StatusSkipped                // 已跳过
+	StatusFailed                 // 失败
+	StatusValid                  // 校验通过（dry-run 时未写入）
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusInserted:
+		return "inserted"
+	case StatusUpdated:
+		return "updated"
+	case StatusSkipped:
+		return "skipped"
+	case StatusFailed:
+		return "failed"
+	case StatusValid:
+		return "valid"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrSkipRow 校验函数返回该错误时，该行被标记为跳过而不是失败
+var ErrSkipRow = errors.New("skip row")
+
+// Options 导入选项
+type Options struct {
+	// Format 源数据格式
+	Format Format
+	// Mode 写入方式，默认仅插入
+	Mode Mode
+	// BatchSize 每个分块的行数，<=0 时使用 DefaultBatchSize
+	BatchSize int
+	// DryRun 为 true 时仅解析与校验，不写入数据库
+	DryRun bool
+	// StopOnError 为 true 时遇到第一个失败行即停止导入
+	StopOnError bool
+	// Comma CSV 分隔符，0 表示使用 ','
+	Comma rune
+	// ColumnMapping 源列名 -> DTO 字段名（支持 proto 字段名或 JSON 名），未映射的列按原名匹配，无法匹配的列被忽略
+	ColumnMapping map[string]string
+	// UpdateMask Upsert 时冲突需要更新的字段，为空表示更新全部字段
+	UpdateMask *fieldmaskpb.FieldMask
+}
+
+func (o *Options) batchSize() int {
+	if o == nil || o.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return o.BatchSize
+}
+
+// Validator 行校验函数，line 为源数据中的行号（从 1 开始）
+type Validator[DTO any] func(line int, dto *DTO) error
+
+// WriteFunc 写入一个分块，返回与 dtos 一一对应的状态；返回 nil 状态表示全部为插入。
+// 返回错误时整个分块视为失败（调用方应保证分块在同一事务中写入）。
+type WriteFunc[DTO any] func(ctx context.Context, dtos []*DTO) ([]Status, error)
+
+// RowResult 单行的导入结果
+type RowResult struct {
+	Line   int    `json:"line"`
+	Status Status `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Report 导入报告
+type Report struct {
+	DryRun bool `json:"dry_run"`
+
+	Total    int `json:"total"`
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+	Failed   int `json:"failed"`
+	Valid    int `json:"valid"`
+
+	Rows []RowResult `json:"rows"`
+}
+
+func (r *Report) add(line int, status Status, reason string) {
+	r.Total++
+	switch status {
+	case StatusInserted:
+		r.Inserted++
+	case StatusUpdated:
+		r.Updated++
+	case StatusSkipped:
+		r.Skipped++
+	case StatusFailed:
+		r.Failed++
+	case StatusValid:
+		r.Valid++
+	}
+	r.Rows = append(r.Rows, RowResult{Line: line, Status: status, Reason: reason})
+}
+
+// Failures 返回所有失败的行
+func (r *Report) Failures() []RowResult {
+	var res []RowResult
+	for _, row := range r.Rows {
+		if row.Status == StatusFailed {
+			res = append(res, row)
+		}
+	}
+	return res
+}
+
+// Import 从 src 读取 CSV/JSONL 数据，解析为 DTO 并校验，然后按分块调用 write 写入。
+// 解析或校验失败的行不会写入，并在报告中记录原因；返回的 error 仅表示导入被中止。
+func Import[DTO any](ctx context.Context, src io.Reader, opts *Options, validate Validator[DTO], write WriteFunc[DTO]) (*Report, error) {
+	if src == nil {
+		return nil, errors.New("source reader is nil")
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	if write == nil && !opts.DryRun {
+		return nil, errors.New("write func is nil")
+	}
+
+	dec, err := newDecoder[DTO](src, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: opts.DryRun}
+	batchSize := opts.batchSize()
+
+	lines := make([]int, 0, batchSize)
+	dtos := make([]*DTO, 0, batchSize)
+
+	flush := func() error {
+		if len(dtos) == 0 {
+			return nil
+		}
+		defer func() {
+			lines = lines[:0]
+			dtos = dtos[:0]
+		}()
+
+		if opts.DryRun {
+			for _, line := range lines {
+				report.add(line, StatusValid, "")
+			}
+			return nil
+		}
+
+		statuses, wErr := write(ctx, dtos)
+		if wErr != nil {
+			for _, line := range lines {
+				report.add(line, StatusFailed, wErr.Error())
+			}
+			if opts.StopOnError {
+				return wErr
+			}
+			return nil
+		}
+
+		for i, line := range lines {
+			status := StatusInserted
+			if i < len(statuses) {
+				status = statuses[i]
+			}
+			report.add(line, status, "")
+		}
+		return nil
+	}
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return report, err
+		}
+
+		line, dto, rErr := dec.next()
+		if rErr == io.EOF {
+			break
+		}
+		if rErr != nil {
+			var pErr *parseError
+			if !errors.As(rErr, &pErr) {
+				// 读取源数据失败，无法继续
+				return report, rErr
+			}
+			report.add(line, StatusFailed, pErr.Error())
+			if opts.StopOnError {
+				return report, rErr
+			}
+			continue
+		}
+
+		if validate != nil {
+			if vErr := validate(line, dto); vErr != nil {
+				if errors.Is(vErr, ErrSkipRow) {
+					report.add(line, StatusSkipped, vErr.Error())
+					continue
+				}
+				report.add(line, StatusFailed, vErr.Error())
+				if opts.StopOnError {
+					return report, vErr
+				}
+				continue
+			}
+		}
+
+		lines = append(lines, line)
+		dtos = append(dtos, dto)
+
+		if len(dtos) >= batchSize {
+			if err = flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if err = flush(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,270 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	pagination "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+type testUser struct {
+	ID        uint32     `json:"id"`
+	Name      string     `json:"name"`
+	Age       *int       `json:"age"`
+	Active    bool       `json:"active"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+func TestImport_CSVStruct(t *testing.T) {
+	src := "id,user_name,age,active,created_at\n" +
+		"1,alice,30,true,2024-01-02 03:04:05\n" +
+		"2,bob,abc,false,\n" +
+		"3,carol,,false,2024-01-02\n"
+
+	var written []*testUser
+	report, err := Import[testUser](context.Background(), strings.NewReader(src), &Options{
+		Format:        FormatCSV,
+		ColumnMapping: map[string]string{"user_name": "name"},
+	}, nil, func(ctx context.Context, dtos []*testUser) ([]Status, error) {
+		written = append(written, dtos...)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := report.Total; got != 3 {
+		t.Errorf("report.Total: expected %v, got %v", 3, got)
+	}
+	if got := report.Inserted; got != 2 {
+		t.Errorf("report.Inserted: expected %v, got %v", 2, got)
+	}
+	if got := report.Failed; got != 1 {
+		t.Errorf("report.Failed: expected %v, got %v", 1, got)
+	}
+
+	failures := report.Failures()
+	if got := len(failures); got != 1 {
+		t.Fatalf("len(failures): expected 1, got %d", got)
+	}
+	if got := failures[0].Line; got != 3 {
+		t.Errorf("failures[0].Line: expected %v, got %v", 3, got)
+	}
+	if !strings.Contains(failures[0].Reason, "Age") {
+		t.Errorf("failures[0].Reason: expected to contain %q, got %q", "Age", failures[0].Reason)
+	}
+
+	if got := len(written); got != 2 {
+		t.Fatalf("len(written): expected 2, got %d", got)
+	}
+	if got := written[0].Name; got != "alice" {
+		t.Errorf("written[0].Name: expected %v, got %v", "alice", got)
+	}
+	if written[0].Age == nil {
+		t.Fatalf("written[0].Age: expected non-nil")
+	}
+	if got := *written[0].Age; got != 30 {
+		t.Errorf("*written[0].Age: expected %v, got %v", 30, got)
+	}
+	if !written[0].Active {
+		t.Errorf("written[0].Active: expected true")
+	}
+	if written[0].CreatedAt == nil {
+		t.Fatalf("written[0].CreatedAt: expected non-nil")
+	}
+	if got := written[0].CreatedAt.Year(); got != 2024 {
+		t.Errorf("written[0].CreatedAt.Year(): expected %v, got %v", 2024, got)
+	}
+	if written[1].Age != nil {
+		t.Errorf("written[1].Age: expected nil")
+	}
+}
+
+func TestImport_CSVProto(t *testing.T) {
+	src := "field,order\n" +
+		"id,DESC\n" +
+		"name,1\n" +
+		"age,UNKNOWN\n"
+
+	var written []*pagination.Sorting
+	report, err := Import[pagination.Sorting](context.Background(), strings.NewReader(src), &Options{
+		Format: FormatCSV,
+	}, nil, func(ctx context.Context, dtos []*pagination.Sorting) ([]Status, error) {
+		written = append(written, dtos...)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := report.Inserted; got != 2 {
+		t.Errorf("report.Inserted: expected %v, got %v", 2, got)
+	}
+	if got := report.Failed; got != 1 {
+		t.Errorf("report.Failed: expected %v, got %v", 1, got)
+	}
+	if got := len(written); got != 2 {
+		t.Fatalf("len(written): expected 2, got %d", got)
+	}
+	if got := written[0].GetOrder(); got != pagination.Sorting_DESC {
+		t.Errorf("written[0].GetOrder(): expected %v, got %v", pagination.Sorting_DESC, got)
+	}
+	if got := written[1].GetOrder(); got != pagination.Sorting_DESC {
+		t.Errorf("written[1].GetOrder(): expected %v, got %v", pagination.Sorting_DESC, got)
+	}
+}
+
+func TestImport_JSONLProto(t *testing.T) {
+	src := `{"page": 1, "size": 20, "noPaging": false}` + "\n" +
+		"\n" +
+		`{"page": "x"}` + "\n" +
+		`{"page": 3, "no_paging": true}`
+
+	var written []*pagination.PagingRequest
+	report, err := Import[pagination.PagingRequest](context.Background(), strings.NewReader(src), &Options{
+		Format:        FormatJSONL,
+		ColumnMapping: map[string]string{"size": "pageSize"},
+	}, nil, func(ctx context.Context, dtos []*pagination.PagingRequest) ([]Status, error) {
+		written = append(written, dtos...)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := report.Total; got != 3 {
+		t.Errorf("report.Total: expected %v, got %v", 3, got)
+	}
+	if got := report.Inserted; got != 2 {
+		t.Errorf("report.Inserted: expected %v, got %v", 2, got)
+	}
+	if got := len(report.Failures()); got != 1 {
+		t.Fatalf("len(report.Failures()): expected 1, got %d", got)
+	}
+	if got := report.Failures()[0].Line; got != 3 {
+		t.Errorf("report.Failures()[0].Line: expected %v, got %v", 3, got)
+	}
+
+	if got := len(written); got != 2 {
+		t.Fatalf("len(written): expected 2, got %d", got)
+	}
+	if got := written[0].GetPageSize(); got != uint32(20) {
+		t.Errorf("written[0].GetPageSize(): expected %v, got %v", uint32(20), got)
+	}
+	if !written[1].GetNoPaging() {
+		t.Errorf("written[1].GetNoPaging(): expected true")
+	}
+}
+
+func TestImport_ValidatorAndBatching(t *testing.T) {
+	src := "id,name\n1,a\n2,\n3,c\n4,d\n5,e\n"
+
+	var chunks [][]*testUser
+	report, err := Import[testUser](context.Background(), strings.NewReader(src), &Options{
+		Format:    FormatCSV,
+		BatchSize: 2,
+	}, func(line int, dto *testUser) error {
+		if dto.Name == "" {
+			return ErrSkipRow
+		}
+		if dto.ID == 4 {
+			return errors.New("id 4 is reserved")
+		}
+		return nil
+	}, func(ctx context.Context, dtos []*testUser) ([]Status, error) {
+		chunk := make([]*testUser, len(dtos))
+		copy(chunk, dtos)
+		chunks = append(chunks, chunk)
+
+		statuses := make([]Status, len(dtos))
+		for i, dto := range dtos {
+			if dto.ID == 5 {
+				statuses[i] = StatusUpdated
+			}
+		}
+		return statuses, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := report.Total; got != 5 {
+		t.Errorf("report.Total: expected %v, got %v", 5, got)
+	}
+	if got := report.Inserted; got != 2 {
+		t.Errorf("report.Inserted: expected %v, got %v", 2, got)
+	}
+	if got := report.Updated; got != 1 {
+		t.Errorf("report.Updated: expected %v, got %v", 1, got)
+	}
+	if got := report.Skipped; got != 1 {
+		t.Errorf("report.Skipped: expected %v, got %v", 1, got)
+	}
+	if got := report.Failed; got != 1 {
+		t.Errorf("report.Failed: expected %v, got %v", 1, got)
+	}
+	if got := len(chunks); got != 2 {
+		t.Fatalf("len(chunks): expected 2, got %d", got)
+	}
+	if got := len(chunks[0]); got != 2 {
+		t.Errorf("len(chunks[0]): expected 2, got %d", got)
+	}
+	if got := len(chunks[1]); got != 1 {
+		t.Errorf("len(chunks[1]): expected 1, got %d", got)
+	}
+}
+
+func TestImport_WriteFailure(t *testing.T) {
+	src := "id,name\n1,a\n2,b\n3,c\n"
+
+	calls := 0
+	report, err := Import[testUser](context.Background(), strings.NewReader(src), &Options{
+		Format:      FormatCSV,
+		BatchSize:   2,
+		StopOnError: true,
+	}, nil, func(ctx context.Context, dtos []*testUser) ([]Status, error) {
+		calls++
+		return nil, errors.New("duplicate key")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if got := calls; got != 1 {
+		t.Errorf("calls: expected %v, got %v", 1, got)
+	}
+	if got := report.Failed; got != 2 {
+		t.Errorf("report.Failed: expected %v, got %v", 2, got)
+	}
+	if got := report.Rows[0].Reason; got != "duplicate key" {
+		t.Errorf("report.Rows[0].Reason: expected %v, got %v", "duplicate key", got)
+	}
+}
+
+func TestImport_DryRun(t *testing.T) {
+	src := "id,name\n1,a\nx,b\n"
+
+	report, err := Import[testUser](context.Background(), strings.NewReader(src), &Options{
+		Format: FormatCSV,
+		DryRun: true,
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !report.DryRun {
+		t.Errorf("report.DryRun: expected true")
+	}
+	if got := report.Valid; got != 1 {
+		t.Errorf("report.Valid: expected %v, got %v", 1, got)
+	}
+	if got := report.Failed; got != 1 {
+		t.Errorf("report.Failed: expected %v, got %v", 1, got)
+	}
+	if got := report.Inserted; got != 0 {
+		t.Errorf("report.Inserted: expected %v, got %v", 0, got)
+	}
+}
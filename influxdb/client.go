@@ -5,6 +5,11 @@ import (
 
 	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
 	"github.com/go-kratos/kratos/v2/log"
+
+	influxQuery "github.com/tx7do/go-crud/influxdb/query"
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 )
 
 type Client struct {
@@ -13,6 +18,13 @@ type Client struct {
 	log *log.Helper
 
 	options *influxdb3.ClientConfig
+
+	enableTrace    bool
+	tracingOptions []tracing.Option
+	tracer         *tracing.Tracer
+
+	metrics *metrics.Metrics
+	slowLog *slowlog.Logger
 }
 
 func NewClient(opts ...Option) (*Client, error) {
@@ -28,6 +40,11 @@ func NewClient(opts ...Option) (*Client, error) {
 		c.log = log.NewHelper(log.DefaultLogger)
 	}
 
+	if c.enableTrace {
+		opts := append([]tracing.Option{tracing.WithNamespace(c.options.Database)}, c.tracingOptions...)
+		c.tracer = tracing.NewTracer("influxdb", opts...)
+	}
+
 	if err := c.createInfluxdbClient(c.options); err != nil {
 		return nil, err
 	}
@@ -92,7 +109,7 @@ func (c *Client) Query(ctx context.Context, query string) (*influxdb3.QueryItera
 	return result, nil
 }
 
-// QueryWithParams 使用参数化方式查询数据
+// QueryWithParams 使用参数化方式查询数据：过滤值以查询参数绑定，不拼接进语句
 func (c *Client) QueryWithParams(
 	ctx context.Context,
 	table string,
@@ -104,9 +121,9 @@ func (c *Client) QueryWithParams(
 		return nil, ErrInfluxDBClientNotInitialized
 	}
 
-	query := BuildQueryWithParams(table, filters, operators, fields)
+	stmt, params := influxQuery.BuildBoundQuery(table, filters, operators, fields)
 
-	result, err := c.ExecInfluxQLQuery(ctx, query)
+	result, err := c.ExecInfluxQLQueryWithParams(ctx, stmt, params)
 	if err != nil {
 		c.log.Errorf("failed to query data: %v", err)
 		return nil, ErrInfluxDBQueryFailed
@@ -206,11 +223,14 @@ func (c *Client) Exist(ctx context.Context, query string) (bool, error) {
 }
 
 // ExecInfluxQLQuery 执行 Flux/InfluxQL 查询并返回原始迭代器
-func (c *Client) ExecInfluxQLQuery(ctx context.Context, query string, opts ...influxdb3.QueryOption) (*influxdb3.QueryIterator, error) {
+func (c *Client) ExecInfluxQLQuery(ctx context.Context, query string, opts ...influxdb3.QueryOption) (_ *influxdb3.QueryIterator, err error) {
 	if c.cli == nil {
 		return nil, ErrInfluxDBClientNotInitialized
 	}
 
+	ctx, span := c.startSpan(ctx, query)
+	defer func() { span.End(err) }()
+
 	finalOpts := append([]influxdb3.QueryOption{influxdb3.WithQueryType(influxdb3.InfluxQL)}, opts...)
 	it, err := c.cli.Query(ctx, query, finalOpts...)
 	if err != nil {
@@ -221,12 +241,37 @@ func (c *Client) ExecInfluxQLQuery(ctx context.Context, query string, opts ...in
 	return it, nil
 }
 
+// ExecInfluxQLQueryWithParams 执行带 $name 参数的 InfluxQL 查询并返回原始迭代器
+func (c *Client) ExecInfluxQLQueryWithParams(ctx context.Context, query string, params influxdb3.QueryParameters, opts ...influxdb3.QueryOption) (_ *influxdb3.QueryIterator, err error) {
+	if c.cli == nil {
+		return nil, ErrInfluxDBClientNotInitialized
+	}
+	if len(params) == 0 {
+		return c.ExecInfluxQLQuery(ctx, query, opts...)
+	}
+
+	ctx, span := c.startSpan(ctx, query)
+	defer func() { span.End(err) }()
+
+	finalOpts := append([]influxdb3.QueryOption{influxdb3.WithQueryType(influxdb3.InfluxQL)}, opts...)
+	it, err := c.cli.QueryWithParameters(ctx, query, params, finalOpts...)
+	if err != nil {
+		c.log.Errorf("failed to exec InfluxQL query: %v, query: %s", err, query)
+		return nil, ErrInfluxDBQueryFailed
+	}
+
+	return it, nil
+}
+
 // ExecSQLQuery 执行 SQL 查询并返回原始迭代器
-func (c *Client) ExecSQLQuery(ctx context.Context, query string, opts ...influxdb3.QueryOption) (*influxdb3.QueryIterator, error) {
+func (c *Client) ExecSQLQuery(ctx context.Context, query string, opts ...influxdb3.QueryOption) (_ *influxdb3.QueryIterator, err error) {
 	if c.cli == nil {
 		return nil, ErrInfluxDBClientNotInitialized
 	}
 
+	ctx, span := c.startSpan(ctx, query)
+	defer func() { span.End(err) }()
+
 	finalOpts := append([]influxdb3.QueryOption{influxdb3.WithQueryType(influxdb3.SQL)}, opts...)
 	it, err := c.cli.Query(ctx, query, finalOpts...)
 	if err != nil {
@@ -238,17 +283,23 @@ func (c *Client) ExecSQLQuery(ctx context.Context, query string, opts ...influxd
 }
 
 // WritePointsStrict 接受严格类型 []*influxdb3.Point 并写入
-func (c *Client) WritePointsStrict(ctx context.Context, points []*influxdb3.Point) error {
+func (c *Client) WritePointsStrict(ctx context.Context, points []*influxdb3.Point) (err error) {
 	if c.cli == nil {
 		return ErrInfluxDBClientNotInitialized
 	}
 	if len(points) == 0 {
 		return nil
 	}
-	if err := c.cli.WritePoints(ctx, points); err != nil {
+
+	ctx, span := c.tracer.Start(ctx, "WRITE", "")
+	defer func() { span.End(err) }()
+
+	if err = c.cli.WritePoints(ctx, points); err != nil {
 		c.log.Errorf("failed to write points: %v", err)
 		return ErrBatchInsertFailed
 	}
+
+	span.SetAffectedRows(int64(len(points)))
 	return nil
 }
 
@@ -260,3 +311,11 @@ func (c *Client) WritePoints(ctx context.Context, pts []any) error {
 	}
 	return c.WritePointsStrict(ctx, points)
 }
+
+// startSpan 开始一次查询的 span，操作与 measurement 从语句中解析；未启用追踪时返回 nil
+func (c *Client) startSpan(ctx context.Context, query string) (context.Context, *tracing.Span) {
+	operation, measurement := tracing.ParseStatement(query)
+	ctx, span := c.tracer.Start(ctx, operation, measurement)
+	span.SetStatement(query)
+	return ctx, span
+}
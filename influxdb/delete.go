@@ -0,0 +1,107 @@
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DeletePoints 调用 /api/v2/delete 删除 [start, stop) 范围内满足 predicate 的数据。
+// predicate 使用 delete 谓词语法（如 _measurement="cpu" AND host="a"），只支持标签等值与 AND；
+// 该接口由 InfluxDB 2.x 与兼容 v2 API 的版本提供，不支持删除的服务端会返回错误
+func (c *Client) DeletePoints(ctx context.Context, start, stop time.Time, predicate string) (err error) {
+	if c.cli == nil {
+		return ErrInfluxDBClientNotInitialized
+	}
+	if start.IsZero() || stop.IsZero() || !start.Before(stop) {
+		return ErrTimeRangeRequired
+	}
+
+	ctx, span := c.tracer.Start(ctx, "DELETE", "")
+	defer func() { span.End(err) }()
+
+	body, err := json.Marshal(map[string]string{
+		"start":     start.UTC().Format(time.RFC3339Nano),
+		"stop":      stop.UTC().Format(time.RFC3339Nano),
+		"predicate": predicate,
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(c.options.Host, "/") + "/api/v2/delete")
+	if err != nil {
+		c.log.Errorf("invalid influxdb host: %v", err)
+		return ErrInfluxDBDeleteFailed
+	}
+	q := endpoint.Query()
+	q.Set("bucket", c.options.Database)
+	if c.options.Organization != "" {
+		q.Set("org", c.options.Organization)
+	}
+	endpoint.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.options.Token != "" {
+		scheme := c.options.AuthScheme
+		if scheme == "" {
+			scheme = "Token"
+		}
+		req.Header.Set("Authorization", scheme+" "+c.options.Token)
+	}
+
+	httpClient := c.options.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		c.log.Errorf("failed to delete points: %v", err)
+		return ErrInfluxDBDeleteFailed
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		c.log.Errorf("failed to delete points: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+		return ErrInfluxDBDeleteFailed
+	}
+	return nil
+}
+
+// deletePredicate 生成 measurement 与标签等值条件的 delete 谓词，标签按名称排序
+func deletePredicate(measurement string, tags map[string]string) string {
+	parts := []string{`_measurement="` + escapePredicateValue(measurement) + `"`}
+	for _, k := range sortedKeys(tags) {
+		parts = append(parts, quotePredicateKey(k)+`="`+escapePredicateValue(tags[k])+`"`)
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// quotePredicateKey 含空格等特殊字符的标签名使用双引号包裹
+func quotePredicateKey(k string) string {
+	for _, r := range k {
+		if !(r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return `"` + escapePredicateValue(k) + `"`
+		}
+	}
+	return k
+}
+
+// quoteIdentifier 将 InfluxQL 标识符（如来自请求的标签名）用双引号包裹并转义其中的 \ 与 "
+func quoteIdentifier(k string) string {
+	return `"` + escapePredicateValue(k) + `"`
+}
+
+func escapePredicateValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v)
+}
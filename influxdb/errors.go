@@ -1,6 +1,10 @@
 package influxdb
 
-import "github.com/go-kratos/kratos/v2/errors"
+import (
+	stderrors "errors"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
 
 var (
 	ErrInfluxDBClientNotInitialized = errors.InternalServer("INFLUXDB_CLIENT_NOT_INITIALIZED", "client not initialized")
@@ -23,3 +27,28 @@ var (
 
 	ErrInsertFailed = errors.InternalServer("INFLUXDB_INSERT_FAILED", "insert failed")
 )
+
+var (
+	ErrInfluxDBDeleteFailed = errors.InternalServer("INFLUXDB_DELETE_FAILED", "delete failed")
+
+	ErrMapperNotSet = errors.InternalServer("INFLUXDB_MAPPER_NOT_SET", "point mapper not set")
+
+	ErrTimeRangeRequired = errors.BadRequest("INFLUXDB_TIME_RANGE_REQUIRED", "destructive operation requires both start and end time")
+
+	ErrUnsupportedDeletePredicate = errors.BadRequest("INFLUXDB_UNSUPPORTED_DELETE_PREDICATE", "delete predicate only supports tag equality")
+)
+
+var (
+	ErrWriterClosed = errors.InternalServer("INFLUXDB_WRITER_CLOSED", "writer closed")
+
+	ErrWriterQueueFull = errors.ServiceUnavailable("INFLUXDB_WRITER_QUEUE_FULL", "write queue full")
+)
+
+// 仓库自身返回的错误，在 init 中按分类注册到 metrics，作为 class 标签
+var (
+	// 参数缺失或不合法
+	errCollectionIsEmpty  = stderrors.New("collection is empty")
+	errDTOIsNil           = stderrors.New("dto is nil")
+	errDatabaseIsNil      = stderrors.New("influxdb database is nil")
+	errPagingRequestIsNil = stderrors.New("paging request is nil")
+)
@@ -0,0 +1,76 @@
+package influxdb
+
+import (
+	"context"
+	"sort"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/explain"
+)
+
+// ToQuery 返回 ListWithPaging 针对 req 生成的 InfluxQL（不访问数据库）。
+// ListWithPaging 直接以该语句计数，因此 Statement 与 CountStatement 相同。
+func (r *Repository[DTO, ENTITY]) ToQuery(req *paginationV1.PagingRequest) (*explain.Query, error) {
+	if r.collection == "" {
+		return nil, errCollectionIsEmpty
+	}
+	if req == nil {
+		return nil, errPagingRequestIsNil
+	}
+
+	qb, err := r.buildPagingQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := qb.Build()
+	return &explain.Query{
+		Statement:      stmt,
+		CountStatement: stmt,
+	}, nil
+}
+
+// Explain 在 ToQuery 的基础上执行 EXPLAIN，返回 InfluxDB 的查询计划（不执行查询本身）
+func (r *Repository[DTO, ENTITY]) Explain(ctx context.Context, req *paginationV1.PagingRequest) (*explain.Query, error) {
+	if r.client == nil {
+		return nil, errDatabaseIsNil
+	}
+
+	q, err := r.ToQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := r.client.ExecInfluxQLQuery(ctx, "EXPLAIN "+q.Statement, influxdb3.WithQueryType(influxdb3.InfluxQL))
+	if err != nil {
+		r.log.Errorf("explain query failed: %v", err)
+		return nil, ErrInfluxDBQueryFailed
+	}
+
+	var columns []string
+	var values [][]any
+	for it.Next() {
+		record := it.Value()
+		if columns == nil {
+			for k := range record {
+				columns = append(columns, k)
+			}
+			sort.Strings(columns)
+		}
+
+		row := make([]any, len(columns))
+		for i, col := range columns {
+			row[i] = record[col]
+		}
+		values = append(values, row)
+	}
+	if err = it.Err(); err != nil {
+		r.log.Errorf("query iterator error: %v", err)
+		return nil, ErrInfluxDBQueryFailed
+	}
+
+	q.Plan = explain.FormatTable(columns, values)
+	return q, nil
+}
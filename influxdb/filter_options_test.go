@@ -0,0 +1,75 @@
+package influxdb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+func TestRepository_BuildFilterQuery(t *testing.T) {
+	repo := NewRepository[histogramPoint, histogramPoint](nil, "cpu", log.NewHelper(log.DefaultLogger))
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	qb, err := repo.buildFilterQuery(&FilterOptions{
+		Query: `{"usage__gt":"0.5"}`,
+		Tags:  map[string]string{"region": "eu", "host": "a"},
+		Start: start,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `SELECT * FROM cpu WHERE usage > '0.5' AND "host" = $p1 AND "region" = $p2 AND time >= $p3`
+	if got := qb.Build(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	params := qb.Params()
+	if params["p1"] != "a" || params["p2"] != "eu" || params["p3"] != "2024-01-01T00:00:00Z" {
+		t.Errorf("unexpected params: %v", params)
+	}
+
+	qb, err = repo.buildFilterQuery(&FilterOptions{Tags: map[string]string{`host" OR 1=1 --`: "a"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = `SELECT * FROM cpu WHERE "host\" OR 1=1 --" = $p1`
+	if got := qb.Build(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRepository_BuildDeletePredicate(t *testing.T) {
+	repo := NewRepository[histogramPoint, histogramPoint](nil, "cpu", log.NewHelper(log.DefaultLogger))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		opts *FilterOptions
+		want error
+	}{
+		{nil, ErrTimeRangeRequired},
+		{&FilterOptions{Start: start}, ErrTimeRangeRequired},
+		{&FilterOptions{Start: start, End: start}, ErrTimeRangeRequired},
+		{&FilterOptions{Start: start, End: start.Add(time.Hour), FilterExpr: &paginationV1.FilterExpr{}}, ErrUnsupportedDeletePredicate},
+	}
+	for i, c := range cases {
+		if _, err := repo.buildDeletePredicate(c.opts); !errors.Is(err, c.want) {
+			t.Errorf("case %d: expected %v, got %v", i, c.want, err)
+		}
+	}
+
+	predicate, err := repo.buildDeletePredicate(&FilterOptions{
+		Start: start,
+		End:   start.Add(time.Hour),
+		Tags:  map[string]string{"host": `a"b`, "data center": "x"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `_measurement="cpu" AND "data center"="x" AND host="a\"b"`
+	if predicate != want {
+		t.Errorf("got %s, want %s", predicate, want)
+	}
+}
@@ -0,0 +1,156 @@
+package influxdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tx7do/go-crud/histogram"
+	"github.com/tx7do/go-crud/influxdb/query"
+	"github.com/tx7do/go-crud/tracing"
+)
+
+// ErrUnsupportedHistogramInterval GROUP BY time() 只支持固定长度的间隔，不支持月、季度与年
+var ErrUnsupportedHistogramInterval = errors.New("influxdb histogram does not support calendar intervals")
+
+// Histogram 时间直方图：使用 InfluxQL 的 GROUP BY time() 与 tz() 按 hreq.Interval 分桶，返回补零且有序的桶。
+// 分桶固定使用 time 列，hreq.Field 仅用于校验；未指定聚合字段的 count 使用 count(*)，取各字段计数的最大值作为文档数
+func (r *Repository[DTO, ENTITY]) Histogram(ctx context.Context, hreq *histogram.Request) (ret *histogram.Result, err error) {
+	obs := r.observe("Histogram")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Buckets)))
+		}
+		obs.End(err)
+	}()
+
+	if r.client == nil {
+		return nil, errDatabaseIsNil
+	}
+	if r.collection == "" {
+		return nil, errCollectionIsEmpty
+	}
+
+	qb, err := r.buildHistogramQuery(hreq)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := r.client.tracer.Start(ctx, "Histogram", r.collection, tracing.FilterAttributes(hreq.FilterExpr)...)
+	defer func() { span.End(err) }()
+
+	aSql := qb.Build()
+	obs.SetStatement(aSql)
+	it, err := r.client.ExecInfluxQLQuery(ctx, aSql)
+	if err != nil {
+		return nil, err
+	}
+
+	collector, err := histogram.NewCollector(hreq)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := make(map[string]bool, len(hreq.Aggregates))
+	for _, a := range hreq.Aggregates {
+		aliases[a.Alias()] = true
+	}
+
+	for it.Next() {
+		row := it.Value()
+
+		var key time.Time
+		switch t := row["time"].(type) {
+		case time.Time:
+			key = t
+		case int64:
+			key = time.Unix(0, t)
+		default:
+			continue
+		}
+
+		// count(*) 为每个字段返回一列 count_<field>，取最大值作为文档数
+		count := maxCountColumn(row, aliases)
+
+		values := make([]any, 0, len(hreq.Aggregates))
+		for _, a := range hreq.Aggregates {
+			if a.Func == histogram.FuncCount && a.Field == "" {
+				values = append(values, count)
+				continue
+			}
+			values = append(values, row[a.Alias()])
+		}
+		if err = collector.Add(key, count, values...); err != nil {
+			return nil, err
+		}
+	}
+	if err = it.Err(); err != nil {
+		r.log.Errorf("histogram query iterator error: %v", err)
+		return nil, ErrInfluxDBQueryFailed
+	}
+
+	ret, err = collector.Result()
+	if ret != nil {
+		span.SetReturnedRows(int64(len(ret.Buckets)))
+	}
+	return ret, err
+}
+
+// buildHistogramQuery 构造 GROUP BY time() 查询；空桶由 histogram.Collector 补齐，因此使用 fill(none)
+func (r *Repository[DTO, ENTITY]) buildHistogramQuery(hreq *histogram.Request) (*query.Builder, error) {
+	if err := hreq.Validate(); err != nil {
+		return nil, err
+	}
+	if err := r.limitPolicy.CheckFilter(r.entityName, hreq.Query, hreq.OrQuery, hreq.FilterExpr); err != nil {
+		return nil, err
+	}
+
+	iv, _ := hreq.ParsedInterval()
+	var groupBy string
+	switch iv.Unit {
+	case histogram.UnitMonth, histogram.UnitQuarter, histogram.UnitYear:
+		return nil, errors.Join(ErrUnsupportedHistogramInterval, errors.New(hreq.Interval))
+	case histogram.UnitWeek:
+		// 时间纪元为周四，偏移 4 天使桶从周一开始
+		groupBy = "time(1w, 4d)"
+	default:
+		groupBy = "time(" + iv.String() + ")"
+	}
+
+	qb := query.NewQueryBuilder(r.collection)
+	if hreq.Query != "" || hreq.OrQuery != "" {
+		if _, err := r.queryStringFilter.BuildSelectors(qb, hreq.Query, hreq.OrQuery); err != nil {
+			return nil, err
+		}
+	} else if hreq.FilterExpr != nil {
+		if _, err := r.structuredFilter.BuildSelectors(qb, hreq.FilterExpr); err != nil {
+			return nil, err
+		}
+	}
+
+	if !hreq.Start.IsZero() {
+		qb.WhereFromRaw("time >= '" + hreq.Start.UTC().Format(time.RFC3339Nano) + "'")
+	}
+	if !hreq.End.IsZero() {
+		qb.WhereFromRaw("time < '" + hreq.End.UTC().Format(time.RFC3339Nano) + "'")
+	}
+
+	fields := []string{"count(*)"}
+	for _, a := range hreq.Aggregates {
+		fn := string(a.Func)
+		if a.Func == histogram.FuncAvg {
+			fn = "mean"
+		}
+		if a.Func == histogram.FuncCount && a.Field == "" {
+			// 与文档数相同，不重复查询
+			continue
+		}
+		fields = append(fields, fn+"("+a.Field+") AS "+a.Alias())
+	}
+
+	qb.Select(fields).GroupBy(groupBy).Fill("none")
+	if tz := hreq.TimezoneName(); tz != "UTC" {
+		qb.TimeZone(tz)
+	}
+	return qb, nil
+}
@@ -0,0 +1,43 @@
+package influxdb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/go-crud/histogram"
+)
+
+type histogramPoint struct {
+	Host  string
+	Usage float64
+}
+
+func TestRepository_BuildHistogramQuery(t *testing.T) {
+	repo := NewRepository[histogramPoint, histogramPoint](nil, "cpu", log.NewHelper(log.DefaultLogger))
+
+	q, err := repo.buildHistogramQuery(&histogram.Request{
+		Field:    "time",
+		Interval: "1w",
+		Timezone: "Asia/Shanghai",
+		Start:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Aggregates: []histogram.Aggregate{
+			{Func: histogram.FuncCount},
+			{Func: histogram.FuncAvg, Field: "usage"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "SELECT count(*), mean(usage) AS avg_usage FROM cpu WHERE time >= '2024-01-01T00:00:00Z' GROUP BY time(1w, 4d) fill(none) tz('Asia/Shanghai')"
+	if got := q.Build(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	_, err = repo.buildHistogramQuery(&histogram.Request{Field: "time", Interval: "1M"})
+	if !errors.Is(err, ErrUnsupportedHistogramInterval) {
+		t.Errorf("expected ErrUnsupportedHistogramInterval, got %v", err)
+	}
+}
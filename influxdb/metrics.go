@@ -0,0 +1,20 @@
+package influxdb
+
+import (
+	"github.com/tx7do/go-crud/metrics"
+)
+
+// SetMetrics 设置仓库级指标，默认使用 Client 的 WithMetrics 配置；传入 nil 关闭指标
+func (r *Repository[DTO, ENTITY]) SetMetrics(m *metrics.Metrics) {
+	r.metrics = m
+}
+
+func init() {
+	metrics.RegisterErrorClass(metrics.ErrorClassInvalidArgument,
+		errCollectionIsEmpty,
+		errDTOIsNil,
+		errDatabaseIsNil,
+		errPagingRequestIsNil,
+		ErrUnsupportedHistogramInterval,
+	)
+}
@@ -0,0 +1,16 @@
+package influxdb
+
+import (
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+)
+
+// SetSlowLog 设置慢查询日志，默认使用 Client 的 WithSlowLog 配置；传入 nil 关闭
+func (r *Repository[DTO, ENTITY]) SetSlowLog(l *slowlog.Logger) {
+	r.slowLog = l
+}
+
+// observe 开始观测一次仓库操作
+func (r *Repository[DTO, ENTITY]) observe(method string) *slowlog.Observation {
+	return slowlog.Observe(r.metrics, r.slowLog, metrics.BackendInfluxDB, r.entityName, method)
+}
@@ -6,6 +6,12 @@ import (
 
 	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
 	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 )
 
 type Option func(o *Client)
@@ -83,3 +89,45 @@ func WithAuthScheme(authScheme string) Option {
 		o.options.AuthScheme = authScheme
 	}
 }
+
+func WithEnableTrace(enable bool) Option {
+	return func(o *Client) {
+		o.enableTrace = enable
+	}
+}
+
+func WithTracingOptions(opts ...tracing.Option) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, opts...)
+	}
+}
+
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithTracerProvider(provider))
+	}
+}
+
+func WithTracingAttributes(attrs ...attribute.KeyValue) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithAttributes(attrs...))
+	}
+}
+
+func WithTracingDBSystem(name string) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithDBSystem(name))
+	}
+}
+
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *Client) {
+		o.metrics = m
+	}
+}
+
+func WithSlowLog(l *slowlog.Logger) Option {
+	return func(o *Client) {
+		o.slowLog = l
+	}
+}
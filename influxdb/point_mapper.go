@@ -0,0 +1,620 @@
+package influxdb
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/tx7do/go-utils/stringcase"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Point 各组成部分的角色，对应结构体标签 `influx:"measurement|tag|field|time"`
+const (
+	RoleMeasurement = "measurement"
+	RoleTag         = "tag"
+	RoleField       = "field"
+	RoleTime        = "time"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	timestampType = reflect.TypeOf((*timestamppb.Timestamp)(nil))
+	protoMsgType  = reflect.TypeOf((*proto.Message)(nil)).Elem()
+)
+
+// structField 结构体字段的映射元数据
+type structField struct {
+	index []int
+	key   string
+	role  string
+}
+
+// structFieldCache 结构体类型 -> []structField
+var structFieldCache sync.Map
+
+// structFields 解析并缓存结构体的 influx 标签。
+// 标签格式为 `influx:"role[,key]"`，key 缺省为字段名的 snake_case；无标签的匿名结构体字段会展开
+func structFields(typ reflect.Type) []structField {
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil
+	}
+	if v, ok := structFieldCache.Load(typ); ok {
+		return v.([]structField)
+	}
+
+	var fields []structField
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag := sf.Tag.Get("influx")
+		if tag == "" || tag == "-" {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Type != timeType {
+				for _, sub := range structFields(sf.Type) {
+					sub.index = append([]int{i}, sub.index...)
+					fields = append(fields, sub)
+				}
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		role, key, _ := strings.Cut(tag, ",")
+		switch role {
+		case RoleMeasurement, RoleTag, RoleField, RoleTime:
+		default:
+			continue
+		}
+		if key == "" {
+			key = stringcase.ToSnakeCase(sf.Name)
+		}
+		fields = append(fields, structField{index: []int{i}, key: key, role: role})
+	}
+
+	v, _ := structFieldCache.LoadOrStore(typ, fields)
+	return v.([]structField)
+}
+
+// StructRoles 返回结构体 T 的 influx 标签声明的 key -> 角色，T 不是结构体时返回空
+func StructRoles[T any]() map[string]string {
+	fields := structFields(reflect.TypeOf((*T)(nil)).Elem())
+	roles := make(map[string]string, len(fields))
+	for _, f := range fields {
+		roles[f.key] = f.role
+	}
+	return roles
+}
+
+// StructMapper 依据结构体的 influx 标签在 T 与 Point 之间双向转换，字段元数据按类型缓存。
+// tag 支持字符串、布尔与数值；field 支持布尔、数值、字符串、[]byte 与 time.Time（纳秒时间戳）；
+// time 支持 time.Time、*timestamppb.Timestamp 与纳秒时间戳；指针字段为 nil 时跳过
+type StructMapper[T any] struct {
+	fields []structField
+}
+
+// NewStructMapper 创建结构体转换器
+func NewStructMapper[T any]() *StructMapper[T] {
+	return &StructMapper[T]{fields: structFields(reflect.TypeOf((*T)(nil)).Elem())}
+}
+
+// ToPoint 将结构体转换为 Point；未声明 measurement 时 measurement 为空，由调用方设置。
+// 没有任何 field 时返回 nil
+func (m *StructMapper[T]) ToPoint(data *T) *influxdb3.Point {
+	if data == nil || len(m.fields) == 0 {
+		return nil
+	}
+
+	v := reflect.ValueOf(data).Elem()
+	point := influxdb3.NewPointWithMeasurement("")
+	for _, f := range m.fields {
+		fv := v.FieldByIndex(f.index)
+		switch f.role {
+		case RoleMeasurement:
+			if s, ok := reflectString(fv); ok {
+				point.SetMeasurement(s)
+			}
+		case RoleTime:
+			if t, ok := reflectTime(fv); ok && !t.IsZero() {
+				point.SetTimestamp(t)
+			}
+		case RoleTag:
+			// 空标签值在行协议中无效
+			if s, ok := reflectString(fv); ok && s != "" {
+				point.SetTag(f.key, s)
+			}
+		case RoleField:
+			if val, ok := reflectFieldValue(fv); ok {
+				point.SetField(f.key, val)
+			}
+		}
+	}
+
+	if !point.HasFields() {
+		return nil
+	}
+	return point
+}
+
+// ToData 将 Point 转换为结构体。
+// InfluxQL 结果可能不带列类型元数据，标签会以 field 返回，因此 tag、field 均先查标签再查字段
+func (m *StructMapper[T]) ToData(point *influxdb3.Point) *T {
+	if point == nil {
+		return nil
+	}
+
+	out := new(T)
+	v := reflect.ValueOf(out).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	for _, f := range m.fields {
+		fv := v.FieldByIndex(f.index)
+		switch f.role {
+		case RoleMeasurement:
+			_ = setReflectValue(fv, point.GetMeasurement())
+		case RoleTime:
+			if !point.Values.Timestamp.IsZero() {
+				_ = setReflectValue(fv, point.Values.Timestamp)
+			}
+		default:
+			if raw := pointValue(point, f.key); raw != nil {
+				_ = setReflectValue(fv, raw)
+			}
+		}
+	}
+	return out
+}
+
+// protoField proto 字段的映射元数据
+type protoField struct {
+	fd   protoreflect.FieldDescriptor
+	key  string
+	role string
+}
+
+// ProtoMapper 基于 protobuf 反射在消息 T（*T 实现 proto.Message）与 Point 之间双向转换，字段元数据在创建时缓存。
+// 字段角色优先取 roles（key 为 snake_case 字段名），否则按 ProtoMessageToPoint 的约定识别；
+// 仅支持标量、枚举与 google.protobuf.Timestamp，repeated、map 与其它嵌套消息会被跳过
+type ProtoMapper[T any] struct {
+	fields []protoField
+}
+
+// NewProtoMapper 创建 proto 消息转换器，*T 未实现 proto.Message 时返回 nil
+func NewProtoMapper[T any](roles map[string]string) *ProtoMapper[T] {
+	msg, ok := any(new(T)).(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	desc := msg.ProtoReflect().Descriptor().Fields()
+	fields := make([]protoField, 0, desc.Len())
+	for i := 0; i < desc.Len(); i++ {
+		fd := desc.Get(i)
+		if fd.IsList() || fd.IsMap() {
+			continue
+		}
+		if fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() != "google.protobuf.Timestamp" {
+			continue
+		}
+		key := stringcase.ToSnakeCase(fd.JSONName())
+		role := roles[key]
+		if role == "" {
+			role = protoFieldRole(fd)
+		}
+		fields = append(fields, protoField{fd: fd, key: key, role: role})
+	}
+	return &ProtoMapper[T]{fields: fields}
+}
+
+// ToPoint 将消息转换为 Point；没有显式存在性（proto3 普通标量）的字段零值也会写入。没有任何 field 时返回 nil
+func (m *ProtoMapper[T]) ToPoint(data *T) *influxdb3.Point {
+	if data == nil {
+		return nil
+	}
+	msg := any(data).(proto.Message).ProtoReflect()
+
+	point := influxdb3.NewPointWithMeasurement("")
+	for _, f := range m.fields {
+		if f.fd.HasPresence() && !msg.Has(f.fd) {
+			continue
+		}
+		gv, ok := protoValueToGo(f.fd, msg.Get(f.fd))
+		if !ok {
+			continue
+		}
+		switch f.role {
+		case RoleMeasurement:
+			if s, ok := gv.(string); ok {
+				point.SetMeasurement(s)
+			}
+		case RoleTime:
+			if t, ok := gv.(time.Time); ok && !t.IsZero() {
+				point.SetTimestamp(t)
+			}
+		case RoleTag:
+			if s := fmt.Sprint(gv); s != "" {
+				point.SetTag(f.key, s)
+			}
+		case RoleField:
+			if t, ok := gv.(time.Time); ok {
+				gv = t.UnixNano()
+			}
+			point.SetField(f.key, gv)
+		}
+	}
+
+	if !point.HasFields() {
+		return nil
+	}
+	return point
+}
+
+// ToData 将 Point 转换为消息，无法转换的值会被忽略
+func (m *ProtoMapper[T]) ToData(point *influxdb3.Point) *T {
+	if point == nil {
+		return nil
+	}
+
+	out := new(T)
+	msg := any(out).(proto.Message).ProtoReflect()
+	for _, f := range m.fields {
+		var raw any
+		switch f.role {
+		case RoleMeasurement:
+			raw = point.GetMeasurement()
+		case RoleTime:
+			if !point.Values.Timestamp.IsZero() {
+				raw = point.Values.Timestamp
+			}
+		default:
+			raw = pointValue(point, f.key)
+		}
+		if raw == nil {
+			continue
+		}
+		if pv, ok := goToProtoValue(f.fd, raw); ok {
+			msg.Set(f.fd, pv)
+		}
+	}
+	return out
+}
+
+// NewDefaultMapper 按类型选择转换器：*DTO 实现 proto.Message 时使用 ProtoMapper，
+// 字段角色取自 ENTITY 的 influx 标签；否则使用 DTO 自身标签的 StructMapper
+func NewDefaultMapper[DTO any, ENTITY any]() Mapper[DTO] {
+	if reflect.PointerTo(reflect.TypeOf((*DTO)(nil)).Elem()).Implements(protoMsgType) {
+		return NewProtoMapper[DTO](StructRoles[ENTITY]())
+	}
+	return NewStructMapper[DTO]()
+}
+
+// protoFieldRole 按 ProtoMessageToPoint 的约定识别字段角色
+func protoFieldRole(fd protoreflect.FieldDescriptor) string {
+	jsonName := fd.JSONName()
+	switch {
+	case jsonName == "measurement":
+		return RoleMeasurement
+	case hasTagHint(jsonName) || hasTagHint(string(fd.Name())):
+		return RoleTag
+	case isTimestampField(fd, jsonName):
+		return RoleTime
+	default:
+		return RoleField
+	}
+}
+
+// pointValue 按 key 先查标签再查字段
+func pointValue(point *influxdb3.Point, key string) any {
+	if s, ok := point.GetTag(key); ok {
+		return s
+	}
+	return point.GetField(key)
+}
+
+// reflectString 将字符串、布尔与数值格式化为字符串
+func reflectString(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// reflectTime 读取 time.Time、*timestamppb.Timestamp 或纳秒时间戳
+func reflectTime(v reflect.Value) (time.Time, bool) {
+	if v.Type() == timestampType {
+		if v.IsNil() {
+			return time.Time{}, false
+		}
+		return v.Interface().(*timestamppb.Timestamp).AsTime(), true
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return time.Time{}, false
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == timeType:
+		return v.Interface().(time.Time), true
+	case v.Kind() == reflect.Int64:
+		return time.Unix(0, v.Int()), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// reflectFieldValue 将字段值转换为行协议支持的类型
+func reflectFieldValue(v reflect.Value) (any, bool) {
+	if v.Type() == timestampType {
+		if v.IsNil() {
+			return nil, false
+		}
+		return v.Interface().(*timestamppb.Timestamp).AsTime().UnixNano(), true
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return v.String(), true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), true
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).UnixNano(), true
+		}
+	}
+	return nil, false
+}
+
+// setReflectValue 将查询结果中的值写入结构体字段，必要时进行类型转换；指针字段会被分配
+func setReflectValue(dst reflect.Value, src any) bool {
+	if !dst.CanSet() || src == nil {
+		return false
+	}
+
+	if dst.Type() == timestampType {
+		t, ok := toTime(src)
+		if ok {
+			dst.Set(reflect.ValueOf(timestamppb.New(t)))
+		}
+		return ok
+	}
+	if dst.Kind() == reflect.Ptr {
+		elem := reflect.New(dst.Type().Elem())
+		if !setReflectValue(elem.Elem(), src) {
+			return false
+		}
+		dst.Set(elem)
+		return true
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(fmt.Sprint(src))
+	case reflect.Bool:
+		b, ok := toBool(src)
+		if !ok {
+			return false
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t, ok := src.(time.Time); ok {
+			dst.SetInt(t.UnixNano())
+			return true
+		}
+		n, ok := toInt64(src)
+		if !ok || dst.OverflowInt(n) {
+			return false
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u, ok := src.(uint64); ok {
+			if dst.OverflowUint(u) {
+				return false
+			}
+			dst.SetUint(u)
+			return true
+		}
+		n, ok := toInt64(src)
+		if !ok || n < 0 || dst.OverflowUint(uint64(n)) {
+			return false
+		}
+		dst.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat64(src)
+		if !ok {
+			return false
+		}
+		dst.SetFloat(f)
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() != reflect.Uint8 {
+			return false
+		}
+		dst.SetBytes([]byte(fmt.Sprint(src)))
+	case reflect.Struct:
+		if dst.Type() != timeType {
+			return false
+		}
+		t, ok := toTime(src)
+		if !ok {
+			return false
+		}
+		dst.Set(reflect.ValueOf(t))
+	default:
+		return false
+	}
+	return true
+}
+
+// protoValueToGo 将 proto 标量、枚举与 Timestamp 转换为 Go 原生值
+func protoValueToGo(fd protoreflect.FieldDescriptor, v protoreflect.Value) (any, bool) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool(), true
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return v.Int(), true
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return int64(v.Uint()), true
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return v.Uint(), true
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float(), true
+	case protoreflect.StringKind:
+		return v.String(), true
+	case protoreflect.BytesKind:
+		return string(v.Bytes()), true
+	case protoreflect.EnumKind:
+		return int32(v.Enum()), true
+	case protoreflect.MessageKind:
+		if fd.Message().FullName() == "google.protobuf.Timestamp" {
+			if ts, ok := v.Message().Interface().(*timestamppb.Timestamp); ok && ts != nil {
+				return ts.AsTime(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// goToProtoValue 将查询结果中的值转换为字段对应的 protoreflect.Value；枚举支持数值与名称
+func goToProtoValue(fd protoreflect.FieldDescriptor, src any) (protoreflect.Value, bool) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if b, ok := toBool(src); ok {
+			return protoreflect.ValueOfBool(b), true
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if n, ok := toInt64(src); ok {
+			return protoreflect.ValueOfInt32(int32(n)), true
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if n, ok := toInt64(src); ok {
+			return protoreflect.ValueOfInt64(n), true
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if n, ok := toInt64(src); ok && n >= 0 {
+			return protoreflect.ValueOfUint32(uint32(n)), true
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if u, ok := src.(uint64); ok {
+			return protoreflect.ValueOfUint64(u), true
+		}
+		if n, ok := toInt64(src); ok && n >= 0 {
+			return protoreflect.ValueOfUint64(uint64(n)), true
+		}
+	case protoreflect.FloatKind:
+		if f, ok := toFloat64(src); ok {
+			return protoreflect.ValueOfFloat32(float32(f)), true
+		}
+	case protoreflect.DoubleKind:
+		if f, ok := toFloat64(src); ok {
+			return protoreflect.ValueOfFloat64(f), true
+		}
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(fmt.Sprint(src)), true
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(fmt.Sprint(src))), true
+	case protoreflect.EnumKind:
+		if s, ok := src.(string); ok {
+			if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+				return protoreflect.ValueOfEnum(ev.Number()), true
+			}
+		}
+		if n, ok := toInt64(src); ok {
+			return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), true
+		}
+	case protoreflect.MessageKind:
+		if t, ok := toTime(src); ok {
+			return protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect()), true
+		}
+	}
+	return protoreflect.Value{}, false
+}
+
+// toInt64 在 numericToInt64 基础上支持数字字符串
+func toInt64(v any) (int64, bool) {
+	if s, ok := v.(string); ok {
+		n, err := strconv.ParseInt(s, 10, 64)
+		return n, err == nil
+	}
+	return numericToInt64(v)
+}
+
+func toFloat64(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	if n, ok := numericToInt64(v); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func toBool(v any) (bool, bool) {
+	switch t := v.(type) {
+	case bool:
+		return t, true
+	case string:
+		b, err := strconv.ParseBool(t)
+		return b, err == nil
+	}
+	return false, false
+}
+
+// toTime 支持 time.Time、纳秒时间戳与 RFC3339 字符串
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		ts, err := time.Parse(time.RFC3339Nano, t)
+		return ts, err == nil
+	}
+	if n, ok := numericToInt64(v); ok {
+		return time.Unix(0, n).UTC(), true
+	}
+	return time.Time{}, false
+}
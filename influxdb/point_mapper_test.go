@@ -0,0 +1,130 @@
+package influxdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+type mapperBase struct {
+	Host string `influx:"tag,host_name"`
+}
+
+type mapperEntity struct {
+	mapperBase
+	Region  *string                `influx:"tag"`
+	Usage   float64                `influx:"field"`
+	Cores   uint32                 `influx:"field"`
+	Healthy bool                   `influx:"field"`
+	Ts      *timestamppb.Timestamp `influx:"time"`
+	Ignored string
+}
+
+func TestStructMapper_RoundTrip(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 123, time.UTC)
+	region := "eu"
+	m := NewStructMapper[mapperEntity]()
+
+	pt := m.ToPoint(&mapperEntity{
+		mapperBase: mapperBase{Host: "srv-1"},
+		Region:     &region,
+		Usage:      0.75,
+		Cores:      8,
+		Healthy:    true,
+		Ts:         timestamppb.New(now),
+		Ignored:    "x",
+	})
+	if pt == nil {
+		t.Fatal("expected point")
+	}
+	if v, ok := pt.GetTag("host_name"); !ok || v != "srv-1" {
+		t.Errorf("expected tag host_name=srv-1, got %q", v)
+	}
+	if pt.GetField("cores") != uint64(8) || pt.GetField("ignored") != nil {
+		t.Errorf("unexpected fields: %v", pt.Values.Fields)
+	}
+	if !pt.Values.Timestamp.Equal(now) {
+		t.Errorf("unexpected timestamp %v", pt.Values.Timestamp)
+	}
+
+	// 模拟不带列类型元数据的查询结果：标签以 field 返回，整数为 int64
+	row := influxdb3.NewPoint("cpu", nil, map[string]any{
+		"host_name": "srv-2",
+		"region":    "us",
+		"usage":     0.5,
+		"cores":     int64(4),
+		"healthy":   true,
+	}, now)
+	got := m.ToData(row)
+	if got.Host != "srv-2" || got.Region == nil || *got.Region != "us" ||
+		got.Usage != 0.5 || got.Cores != 4 || !got.Healthy || !got.Ts.AsTime().Equal(now) {
+		t.Errorf("unexpected entity: %+v", got)
+	}
+
+	if m.ToPoint(&mapperEntity{}) == nil {
+		t.Error("expected zero-valued fields to be written")
+	}
+	if NewStructMapper[struct{ A int }]().ToPoint(&struct{ A int }{1}) != nil {
+		t.Error("expected nil point for struct without influx tags")
+	}
+}
+
+func TestProtoMapper_RoundTrip(t *testing.T) {
+	m := NewProtoMapper[paginationV1.Sorting](map[string]string{"field": RoleTag})
+	if m == nil {
+		t.Fatal("expected proto mapper")
+	}
+
+	collation := "C"
+	pt := m.ToPoint(&paginationV1.Sorting{
+		Field:           "name",
+		Order:           paginationV1.Sorting_DESC,
+		CaseInsensitive: true,
+		Collation:       &collation,
+	})
+	if pt == nil {
+		t.Fatal("expected point")
+	}
+	if v, ok := pt.GetTag("field"); !ok || v != "name" {
+		t.Errorf("expected tag field=name, got %q", v)
+	}
+	if pt.GetField("order") != int32(1) || pt.GetField("case_insensitive") != true || pt.GetField("collation") != "C" {
+		t.Errorf("unexpected fields: %v", pt.Values.Fields)
+	}
+	// 未设置的 optional 字段不写入
+	if pt.GetField("json_path") != nil {
+		t.Error("unexpected json_path field")
+	}
+
+	row := influxdb3.NewPoint("sorting", map[string]string{"field": "age"}, map[string]any{
+		"order":            int64(1),
+		"nulls":            "NULLS_LAST",
+		"case_insensitive": true,
+		"json_path":        "a.b",
+	}, time.Now())
+	got := m.ToData(row)
+	if got.GetField() != "age" || got.GetOrder() != paginationV1.Sorting_DESC ||
+		got.GetNulls() != paginationV1.Sorting_NULLS_LAST || !got.GetCaseInsensitive() || got.GetJsonPath() != "a.b" {
+		t.Errorf("unexpected message: %v", got)
+	}
+
+	if NewProtoMapper[mapperEntity](nil) != nil {
+		t.Error("expected nil mapper for non-proto type")
+	}
+}
+
+func TestNewDefaultMapper(t *testing.T) {
+	if _, ok := NewDefaultMapper[paginationV1.Sorting, mapperEntity]().(*ProtoMapper[paginationV1.Sorting]); !ok {
+		t.Error("expected ProtoMapper for proto DTO")
+	}
+	if _, ok := NewDefaultMapper[mapperEntity, mapperEntity]().(*StructMapper[mapperEntity]); !ok {
+		t.Error("expected StructMapper for struct DTO")
+	}
+	if roles := StructRoles[mapperEntity](); roles["host_name"] != RoleTag || roles["ts"] != RoleTime || len(roles) != 6 {
+		t.Errorf("unexpected roles: %v", roles)
+	}
+}
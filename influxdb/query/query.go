@@ -7,6 +7,18 @@ import (
 	"strings"
 )
 
+// Fill 设置 GROUP BY time() 时空桶的填充方式，如 null、none、0、previous、linear
+func (qb *Builder) Fill(option string) *Builder {
+	qb.fill = option
+	return qb
+}
+
+// TimeZone 设置 tz() 子句，GROUP BY time() 按该时区对齐桶边界
+func (qb *Builder) TimeZone(tz string) *Builder {
+	qb.timezone = tz
+	return qb
+}
+
 // Builder 用于构造 InfluxQL 查询
 type Builder struct {
 	table     string
@@ -17,6 +29,9 @@ type Builder struct {
 	limit     int
 	offset    int
 	precision string
+	fill      string
+	timezone  string
+	params    map[string]any
 }
 
 // NewQueryBuilder 创建新的 QueryBuilder
@@ -244,6 +259,44 @@ func (qb *Builder) WhereFromRaw(raws ...string) *Builder {
 	return qb
 }
 
+// WhereParam 追加 "field op $pN" 条件，value 作为查询参数绑定而不拼接进语句；
+// op 支持 =、!=、>、>=、<、<= 及 eq、ne、gt、gte、lt、lte，其它取值按 = 处理
+func (qb *Builder) WhereParam(field, op string, value any) *Builder {
+	switch strings.ToLower(op) {
+	case "!=", "<>", "ne":
+		op = "!="
+	case ">", "gt":
+		op = ">"
+	case ">=", "gte":
+		op = ">="
+	case "<", "lt":
+		op = "<"
+	case "<=", "lte":
+		op = "<="
+	default:
+		op = "="
+	}
+	if qb.params == nil {
+		qb.params = make(map[string]any)
+	}
+	name := fmt.Sprintf("p%d", len(qb.params)+1)
+	qb.params[name] = value
+	qb.where = append(qb.where, fmt.Sprintf("%s %s $%s", field, op, name))
+	return qb
+}
+
+// Params 返回 WhereParam 绑定的查询参数，没有参数时返回 nil
+func (qb *Builder) Params() map[string]any {
+	if len(qb.params) == 0 {
+		return nil
+	}
+	params := make(map[string]any, len(qb.params))
+	for k, v := range qb.params {
+		params[k] = v
+	}
+	return params
+}
+
 // GroupBy 设置 group by 字段
 func (qb *Builder) GroupBy(fields ...string) *Builder {
 	qb.groupBy = append(qb.groupBy, fields...)
@@ -301,6 +354,11 @@ func (qb *Builder) Build() string {
 		sb.WriteString(" GROUP BY ")
 		sb.WriteString(strings.Join(qb.groupBy, ", "))
 	}
+	if qb.fill != "" {
+		sb.WriteString(" fill(")
+		sb.WriteString(qb.fill)
+		sb.WriteString(")")
+	}
 
 	// order by
 	if len(qb.orderBy) > 0 {
@@ -316,6 +374,13 @@ func (qb *Builder) Build() string {
 		sb.WriteString(fmt.Sprintf(" OFFSET %d", qb.offset))
 	}
 
+	// tz 子句位于语句末尾
+	if qb.timezone != "" {
+		sb.WriteString(" tz(")
+		sb.WriteString(formatValue(qb.timezone))
+		sb.WriteString(")")
+	}
+
 	return sb.String()
 }
 
@@ -331,3 +396,23 @@ func BuildQueryWithParams(
 		WhereFromMaps(filters, operators)
 	return qb.Build()
 }
+
+// BuildBoundQuery 与 BuildQueryWithParams 相同，但过滤值以 $pN 参数绑定，返回语句与参数
+func BuildBoundQuery(
+	table string,
+	filters map[string]interface{},
+	operators map[string]string,
+	fields []string,
+) (string, map[string]any) {
+	keys := make([]string, 0, len(filters))
+	for k := range filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	qb := NewQueryBuilder(table).Select(fields)
+	for _, k := range keys {
+		qb.WhereParam(k, strings.TrimSpace(operators[k]), filters[k])
+	}
+	return qb.Build(), qb.Params()
+}
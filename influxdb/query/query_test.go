@@ -57,6 +57,20 @@ func TestBuilder_GroupOrderLimitOffset(t *testing.T) {
 	}
 }
 
+func TestBuilder_FillTimeZone(t *testing.T) {
+	q := NewQueryBuilder("cpu").
+		Select([]string{"mean(usage) AS mean_usage"}).
+		WhereFromRaw("time >= '2024-01-01T00:00:00Z'").
+		GroupBy("time(1h)").
+		Fill("none").
+		TimeZone("Asia/Shanghai").
+		Build()
+	want := "SELECT mean(usage) AS mean_usage FROM cpu WHERE time >= '2024-01-01T00:00:00Z' GROUP BY time(1h) fill(none) tz('Asia/Shanghai')"
+	if q != want {
+		t.Fatalf("got %q, want %q", q, want)
+	}
+}
+
 func TestBuildQueryWithParams_Helper(t *testing.T) {
 	filters := map[string]interface{}{
 		"a": 1,
@@ -70,3 +84,22 @@ func TestBuildQueryWithParams_Helper(t *testing.T) {
 		t.Fatalf("got %q, want %q", q, want)
 	}
 }
+
+func TestBuilder_WhereParam(t *testing.T) {
+	qb := NewQueryBuilder("cpu").
+		WhereParam("host", "eq", "a' OR 1=1").
+		WhereParam("time", ">=", "2024-01-01T00:00:00Z")
+	want := "SELECT * FROM cpu WHERE host = $p1 AND time >= $p2"
+	if q := qb.Build(); q != want {
+		t.Fatalf("got %q, want %q", q, want)
+	}
+	params := qb.Params()
+	if params["p1"] != "a' OR 1=1" || params["p2"] != "2024-01-01T00:00:00Z" {
+		t.Fatalf("unexpected params: %v", params)
+	}
+
+	q, params := BuildBoundQuery("t", map[string]interface{}{"b": 2, "a": "x"}, map[string]string{"b": ">"}, nil)
+	if q != "SELECT * FROM t WHERE a = $p1 AND b > $p2" || params["p1"] != "x" || params["p2"] != 2 {
+		t.Fatalf("unexpected bound query: %q %v", q, params)
+	}
+}
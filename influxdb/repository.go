@@ -2,9 +2,9 @@ package influxdb
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"time"
 
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
 	"github.com/go-kratos/kratos/v2/log"
 
 	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
@@ -13,6 +13,10 @@ import (
 	paging "github.com/tx7do/go-crud/influxdb/pagination"
 	"github.com/tx7do/go-crud/influxdb/query"
 	"github.com/tx7do/go-crud/influxdb/sorting"
+	"github.com/tx7do/go-crud/limits"
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 )
 
 // Repository MongoDB 版仓库（泛型）
@@ -32,9 +36,41 @@ type Repository[DTO any, ENTITY any] struct {
 	client     *Client
 	collection string
 	log        *log.Helper
+
+	mapper Mapper[DTO]
+
+	limitPolicy *limits.Policy
+
+	metrics    *metrics.Metrics
+	slowLog    *slowlog.Logger
+	entityName string
+}
+
+// FilterOptions 过滤选项，用于 Get/LatestPerSeries/Count/Exists/Delete
+type FilterOptions struct {
+	// Query/OrQuery 查询字符串过滤（JSON），优先于 FilterExpr
+	Query   string
+	OrQuery string
+
+	// FilterExpr 结构化过滤
+	FilterExpr *paginationV1.FilterExpr
+
+	// Tags 序列标签的等值条件
+	Tags map[string]string
+
+	// Start、End 时间范围 [Start, End)，Delete 时必须设置
+	Start time.Time
+	End   time.Time
 }
 
 func NewRepository[DTO any, ENTITY any](client *Client, collection string, logger *log.Helper) *Repository[DTO, ENTITY] {
+	var m *metrics.Metrics
+	var sl *slowlog.Logger
+	if client != nil {
+		m = client.metrics
+		sl = client.slowLog
+	}
+
 	return &Repository[DTO, ENTITY]{
 		client:     client,
 		collection: collection,
@@ -52,16 +88,63 @@ func NewRepository[DTO any, ENTITY any](client *Client, collection string, logge
 		structuredFilter:  filter.NewStructuredFilter(),
 
 		fieldSelector: field.NewFieldSelector(),
+
+		mapper: NewDefaultMapper[DTO, ENTITY](),
+
+		metrics:    m,
+		slowLog:    sl,
+		entityName: metrics.EntityName[ENTITY](),
 	}
 }
 
+// SetMapper 设置 DTO 与 Point 之间的转换器，默认为 NewDefaultMapper 的结果
+func (r *Repository[DTO, ENTITY]) SetMapper(m Mapper[DTO]) {
+	r.mapper = m
+}
+
 // ListWithPaging 针对 paginationV1.PagingRequest 的列表查询（兼容 Query/OrQuery/FilterExpr）
-func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *paginationV1.PagingRequest) ([]*DTO, int64, error) {
+func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *paginationV1.PagingRequest) (ret []*DTO, _ int64, err error) {
+	obs := r.observe("ListWithPaging")
+	defer func() {
+		obs.ReturnedRows(int64(len(ret)))
+		obs.End(err)
+	}()
+	obs.SetPagingRequest(req)
+
 	if r.client == nil {
-		return nil, 0, errors.New("influxdb database is nil")
+		return nil, 0, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return nil, 0, errors.New("collection is empty")
+		return nil, 0, errCollectionIsEmpty
+	}
+
+	ctx, span := r.client.tracer.Start(ctx, "ListWithPaging", r.collection, tracing.PagingAttributes(req)...)
+	defer func() { span.End(err) }()
+
+	qb, err := r.buildPagingQuery(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ret, err = r.queryDTOs(ctx, obs, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+	span.SetReturnedRows(int64(len(ret)))
+
+	total, err := r.Count(ctx, &FilterOptions{Query: req.GetQuery(), OrQuery: req.GetOrQuery(), FilterExpr: req.GetFilterExpr()})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return ret, total, nil
+}
+
+// buildPagingQuery 按 PagingRequest 构建查询（过滤、字段、排序与分页）
+func (r *Repository[DTO, ENTITY]) buildPagingQuery(req *paginationV1.PagingRequest) (*query.Builder, error) {
+	req, err := r.limitPolicy.ApplyPaging(r.entityName, req)
+	if err != nil {
+		return nil, err
 	}
 
 	qb := query.NewQueryBuilder(r.collection)
@@ -69,11 +152,11 @@ func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *pagin
 	// apply filters
 	if req.GetQuery() != "" || req.GetOrQuery() != "" {
 		if _, err := r.queryStringFilter.BuildSelectors(qb, req.GetQuery(), req.GetOrQuery()); err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 	} else if req.FilterExpr != nil {
 		if _, err := r.structuredFilter.BuildSelectors(qb, req.FilterExpr); err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 	}
 
@@ -102,22 +185,30 @@ func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *pagin
 		}
 	}
 
-	// 计数
-	total, err := r.client.Count(ctx, qb.Build())
-	if err != nil {
-		return nil, 0, err
-	}
-
-	return nil, total, nil
+	return qb, nil
 }
 
 // ListWithPagination 针对 paginationV1.PaginationRequest 的列表查询
-func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *paginationV1.PaginationRequest) ([]*DTO, int64, error) {
+func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *paginationV1.PaginationRequest) (ret []*DTO, _ int64, err error) {
+	obs := r.observe("ListWithPagination")
+	defer func() {
+		obs.ReturnedRows(int64(len(ret)))
+		obs.End(err)
+	}()
+	obs.SetPaginationRequest(req)
+
 	if r.client == nil {
-		return nil, 0, errors.New("influxdb database is nil")
+		return nil, 0, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return nil, 0, errors.New("collection is empty")
+		return nil, 0, errCollectionIsEmpty
+	}
+
+	ctx, span := r.client.tracer.Start(ctx, "ListWithPagination", r.collection, tracing.PaginationAttributes(req)...)
+	defer func() { span.End(err) }()
+
+	if req, err = r.limitPolicy.ApplyPagination(r.entityName, req); err != nil {
+		return nil, 0, err
 	}
 
 	qb := query.NewQueryBuilder(r.collection)
@@ -157,103 +248,339 @@ func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *p
 		_ = r.tokenPaginator.BuildClause(qb, req.GetTokenBased().GetToken(), int(req.GetTokenBased().GetPageSize()))
 	}
 
-	// 计数
-	total, err := r.client.Count(ctx, qb.Build())
+	ret, err = r.queryDTOs(ctx, obs, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+	span.SetReturnedRows(int64(len(ret)))
+
+	total, err := r.Count(ctx, &FilterOptions{Query: req.GetQuery(), OrQuery: req.GetOrQuery(), FilterExpr: req.GetFilterExpr()})
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return nil, total, nil
+	return ret, total, nil
 }
 
-// Create 插入一条记录
-func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO) (*DTO, error) {
+// Create 插入一条记录，未指定时间戳时由服务端使用写入时间
+func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO) (ret *DTO, err error) {
+	obs := r.observe("Create")
+	defer func() { obs.End(err) }()
+
 	if r.client == nil {
-		return nil, errors.New("influxdb database is nil")
+		return nil, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return nil, errors.New("collection is empty")
+		return nil, errCollectionIsEmpty
 	}
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
-	return nil, nil
+	points, err := r.toPoints([]*DTO{dto})
+	if err != nil {
+		return nil, err
+	}
+	if err = r.client.WritePointsStrict(ctx, points); err != nil {
+		return nil, err
+	}
+	obs.AffectedRows(1)
+	return dto, nil
 }
 
-// BatchCreate 批量插入
-func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO) ([]*DTO, error) {
+// BatchCreate 批量插入，任一 DTO 无法转换时不写入任何数据
+func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO) (ret []*DTO, err error) {
+	obs := r.observe("BatchCreate")
+	defer func() { obs.End(err) }()
+
 	if r.client == nil {
-		return nil, errors.New("influxdb database is nil")
+		return nil, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return nil, errors.New("collection is empty")
+		return nil, errCollectionIsEmpty
 	}
 	if len(dtos) == 0 {
 		return nil, nil
 	}
 
-	return nil, nil
+	points, err := r.toPoints(dtos)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.client.WritePointsStrict(ctx, points); err != nil {
+		return nil, err
+	}
+	obs.AffectedRows(int64(len(points)))
+	return dtos, nil
+}
+
+// toPoints 通过 mapper 将 DTO 转换为 Point，measurement 统一为 collection
+func (r *Repository[DTO, ENTITY]) toPoints(dtos []*DTO) ([]*influxdb3.Point, error) {
+	if r.mapper == nil {
+		return nil, ErrMapperNotSet
+	}
+
+	points := make([]*influxdb3.Point, 0, len(dtos))
+	for _, dto := range dtos {
+		if dto == nil {
+			return nil, ErrEmptyData
+		}
+		point := r.mapper.ToPoint(dto)
+		if point == nil {
+			return nil, ErrInvalidPoint
+		}
+		point.SetMeasurement(r.collection)
+		points = append(points, point)
+	}
+	return points, nil
 }
 
-// Count 按给定 builder 中的 filter 统计数量
-func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, baseWhere string, whereArgs ...any) (int64, error) {
+// Count 统计符合 opts 的记录数（count(*) 各字段计数的最大值）
+func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, opts *FilterOptions) (ret int64, err error) {
+	obs := r.observe("Count")
+	defer func() { obs.End(err) }()
+
 	if r.client == nil {
-		return 0, errors.New("influxdb database is nil")
+		return 0, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return 0, errors.New("collection is empty")
+		return 0, errCollectionIsEmpty
 	}
 
-	qb := query.NewQueryBuilder(r.collection)
+	qb, err := r.buildFilterQuery(opts)
+	if err != nil {
+		return 0, err
+	}
+	qb.Select([]string{"count(*)"})
 
-	if baseWhere != "" {
-		if len(whereArgs) > 0 {
-			baseWhere = fmt.Sprintf(baseWhere, whereArgs...)
-		}
-		// 使用 WhereFromRaw 添加 where 片段（函数会去掉可能的 "WHERE" 前缀）
-		qb.WhereFromRaw(baseWhere)
+	aSql := qb.Build()
+	obs.SetStatement(aSql)
+	it, err := r.client.ExecInfluxQLQueryWithParams(ctx, aSql, qb.Params())
+	if err != nil {
+		return 0, err
+	}
+
+	for it.Next() {
+		ret += maxCountColumn(it.Value(), nil)
+	}
+	if err = it.Err(); err != nil {
+		r.log.Errorf("count query iterator error: %v", err)
+		return 0, ErrInfluxDBQueryFailed
 	}
+	return ret, nil
+}
+
+// Exists 检查是否存在符合 opts 的记录
+func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, opts *FilterOptions) (ret bool, err error) {
+	obs := r.observe("Exists")
+	defer func() { obs.End(err) }()
+
+	if r.client == nil {
+		return false, errDatabaseIsNil
+	}
+	if r.collection == "" {
+		return false, errCollectionIsEmpty
+	}
+
+	qb, err := r.buildFilterQuery(opts)
+	if err != nil {
+		return false, err
+	}
+	qb.Limit(1)
 
 	aSql := qb.Build()
-	if aSql == "" {
-		return 0, errors.New("query builder produced empty query")
+	obs.SetStatement(aSql)
+	it, err := r.client.ExecInfluxQLQueryWithParams(ctx, aSql, qb.Params())
+	if err != nil {
+		return false, err
+	}
+
+	exists := it.Next()
+	if err = it.Err(); err != nil {
+		r.log.Errorf("exists query iterator error: %v", err)
+		return false, ErrInfluxDBQueryFailed
+	}
+	return exists, nil
+}
+
+// Get 返回符合 opts 的最新一条记录，不存在时返回 nil
+func (r *Repository[DTO, ENTITY]) Get(ctx context.Context, opts *FilterOptions) (ret *DTO, err error) {
+	obs := r.observe("Get")
+	defer func() { obs.End(err) }()
+
+	if r.client == nil {
+		return nil, errDatabaseIsNil
+	}
+	if r.collection == "" {
+		return nil, errCollectionIsEmpty
 	}
 
-	count, err := r.client.Count(ctx, aSql)
+	qb, err := r.buildFilterQuery(opts)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	qb.OrderBy("time", true).Limit(1)
+
+	dtos, err := r.queryDTOs(ctx, obs, qb)
+	if err != nil {
+		return nil, err
+	}
+	if len(dtos) == 0 {
+		return nil, nil
+	}
+	return dtos[0], nil
+}
+
+// LatestPerSeries 返回符合 opts 的每个序列（measurement + 标签集）的最新一条记录
+func (r *Repository[DTO, ENTITY]) LatestPerSeries(ctx context.Context, opts *FilterOptions) (ret []*DTO, err error) {
+	obs := r.observe("LatestPerSeries")
+	defer func() {
+		obs.ReturnedRows(int64(len(ret)))
+		obs.End(err)
+	}()
+
+	if r.client == nil {
+		return nil, errDatabaseIsNil
 	}
-	return count, nil
+	if r.collection == "" {
+		return nil, errCollectionIsEmpty
+	}
+
+	qb, err := r.buildFilterQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+	// GROUP BY * 时 LIMIT 作用于每个序列
+	qb.GroupBy("*").OrderBy("time", true).Limit(1)
+
+	return r.queryDTOs(ctx, obs, qb)
 }
 
-// Exists 检查是否存在符合条件的记录
-func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, baseWhere string, whereArgs ...any) (bool, error) {
+// Upsert 写入一条记录：measurement、标签集与时间戳都相同的点会覆盖已有的字段值
+func (r *Repository[DTO, ENTITY]) Upsert(ctx context.Context, dto *DTO) (ret *DTO, err error) {
+	obs := r.observe("Upsert")
+	defer func() { obs.End(err) }()
+
 	if r.client == nil {
-		return false, errors.New("influxdb database is nil")
+		return nil, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return false, errors.New("collection is empty")
+		return nil, errCollectionIsEmpty
+	}
+	if dto == nil {
+		return nil, errDTOIsNil
 	}
 
+	points, err := r.toPoints([]*DTO{dto})
+	if err != nil {
+		return nil, err
+	}
+	if points[0].Values.Timestamp.IsZero() {
+		// 未指定时间戳时服务端使用写入时间，无法覆盖已有的点
+		return nil, ErrInvalidPoint
+	}
+
+	if err = r.client.WritePointsStrict(ctx, points); err != nil {
+		return nil, err
+	}
+	obs.AffectedRows(1)
+	return dto, nil
+}
+
+// Delete 删除 [opts.Start, opts.End) 范围内标签满足 opts.Tags 的数据，时间范围必须设置；
+// delete 谓词只支持标签等值，opts 中的 Query/OrQuery/FilterExpr 不可用
+func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, opts *FilterOptions) (err error) {
+	obs := r.observe("Delete")
+	defer func() { obs.End(err) }()
+
+	if r.client == nil {
+		return errDatabaseIsNil
+	}
+	if r.collection == "" {
+		return errCollectionIsEmpty
+	}
+
+	predicate, err := r.buildDeletePredicate(opts)
+	if err != nil {
+		return err
+	}
+	obs.SetStatement(predicate)
+
+	return r.client.DeletePoints(ctx, opts.Start, opts.End, predicate)
+}
+
+// buildDeletePredicate 校验时间范围并生成 delete 谓词
+func (r *Repository[DTO, ENTITY]) buildDeletePredicate(opts *FilterOptions) (string, error) {
+	if opts == nil || opts.Start.IsZero() || opts.End.IsZero() || !opts.Start.Before(opts.End) {
+		return "", ErrTimeRangeRequired
+	}
+	if opts.Query != "" || opts.OrQuery != "" || opts.FilterExpr != nil {
+		return "", ErrUnsupportedDeletePredicate
+	}
+	return deletePredicate(r.collection, opts.Tags), nil
+}
+
+// buildFilterQuery 按 FilterOptions 构建查询：Query/OrQuery 优先于 FilterExpr，标签与时间范围以查询参数绑定，
+// 标签名作为标识符加双引号
+func (r *Repository[DTO, ENTITY]) buildFilterQuery(opts *FilterOptions) (*query.Builder, error) {
 	qb := query.NewQueryBuilder(r.collection)
+	if opts == nil {
+		return qb, nil
+	}
 
-	if baseWhere != "" {
-		if len(whereArgs) > 0 {
-			baseWhere = fmt.Sprintf(baseWhere, whereArgs...)
+	if err := r.limitPolicy.CheckFilter(r.entityName, opts.Query, opts.OrQuery, opts.FilterExpr); err != nil {
+		return nil, err
+	}
+
+	if opts.Query != "" || opts.OrQuery != "" {
+		if _, err := r.queryStringFilter.BuildSelectors(qb, opts.Query, opts.OrQuery); err != nil {
+			return nil, err
+		}
+	} else if opts.FilterExpr != nil {
+		if _, err := r.structuredFilter.BuildSelectors(qb, opts.FilterExpr); err != nil {
+			return nil, err
 		}
-		// 使用 WhereFromRaw 添加 where 片段（函数会去掉可能的 "WHERE" 前缀）
-		qb.WhereFromRaw(baseWhere)
 	}
 
-	aSql := qb.Build()
-	if aSql == "" {
-		return false, errors.New("query builder produced empty query")
+	for _, k := range sortedKeys(opts.Tags) {
+		qb.WhereParam(quoteIdentifier(k), "=", opts.Tags[k])
+	}
+	if !opts.Start.IsZero() {
+		qb.WhereParam("time", ">=", opts.Start.UTC().Format(time.RFC3339Nano))
+	}
+	if !opts.End.IsZero() {
+		qb.WhereParam("time", "<", opts.End.UTC().Format(time.RFC3339Nano))
 	}
+	return qb, nil
+}
 
-	exists, err := r.client.Exist(ctx, aSql)
+// queryDTOs 执行查询并通过 mapper 将结果点转换为 DTO
+func (r *Repository[DTO, ENTITY]) queryDTOs(ctx context.Context, obs *slowlog.Observation, qb *query.Builder) ([]*DTO, error) {
+	if r.mapper == nil {
+		return nil, ErrMapperNotSet
+	}
+
+	aSql := qb.Build()
+	obs.SetStatement(aSql)
+	it, err := r.client.ExecInfluxQLQueryWithParams(ctx, aSql, qb.Params())
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return exists, nil
+
+	var dtos []*DTO
+	for it.Next() {
+		// InfluxQL 结果不一定带 measurement 列
+		point, err := it.AsPoints().AsPointWithMeasurement(r.collection)
+		if err != nil || point == nil {
+			return nil, ErrInvalidPoint
+		}
+		if dto := r.mapper.ToData(point); dto != nil {
+			dtos = append(dtos, dto)
+		}
+	}
+	if err = it.Err(); err != nil {
+		r.log.Errorf("query iterator error: %v", err)
+		return nil, ErrInfluxDBQueryFailed
+	}
+	return dtos, nil
 }
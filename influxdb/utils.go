@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -563,3 +564,27 @@ func isTimestampField(fd protoreflect.FieldDescriptor, jsonName string) bool {
 	}
 	return false
 }
+
+// sortedKeys 返回按字典序排列的 map 键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// maxCountColumn 返回 count(*) 结果行中各 count_<field> 列的最大值，skip 中的列不参与
+func maxCountColumn(row map[string]any, skip map[string]bool) int64 {
+	var n int64
+	for k, v := range row {
+		if !strings.HasPrefix(k, "count_") || skip[k] {
+			continue
+		}
+		if c, ok := numericToInt64(v); ok && c > n {
+			n = c
+		}
+	}
+	return n
+}
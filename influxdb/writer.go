@@ -0,0 +1,428 @@
+package influxdb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/go-crud/metrics"
+)
+
+// DropPolicy 写入队列已满时的处理策略
+type DropPolicy int
+
+const (
+	// DropPolicyBlock 阻塞等待队列空间（背压），直到 ctx 结束
+	DropPolicyBlock DropPolicy = iota
+	// DropPolicyDropNewest 丢弃正在写入的点，Write 返回 ErrWriterQueueFull
+	DropPolicyDropNewest
+	// DropPolicyDropOldest 丢弃队列中最早的点，为新点腾出空间
+	DropPolicyDropOldest
+)
+
+// 丢弃原因，用于指标标签
+const (
+	dropReasonQueueFull   = "queue_full"
+	dropReasonWriteFailed = "write_failed"
+)
+
+const (
+	defaultWriterBatchSize     = 5000
+	defaultWriterFlushInterval = time.Second
+	defaultWriterQueueSize     = 100000
+	defaultWriterMaxRetries    = 3
+	defaultWriterMinBackoff    = 100 * time.Millisecond
+	defaultWriterMaxBackoff    = 10 * time.Second
+)
+
+// DeadLetterFunc 接收未能写入的点及原因；在写入协程或 Write 调用方中同步执行，不应阻塞
+type DeadLetterFunc func(points []*influxdb3.Point, err error)
+
+type WriterOption func(w *Writer)
+
+// WithWriterBatchSize 单次写入的最大点数，达到后立即提交
+func WithWriterBatchSize(size int) WriterOption {
+	return func(w *Writer) {
+		if size > 0 {
+			w.batchSize = size
+		}
+	}
+}
+
+// WithWriterFlushInterval 缓冲中的点最长等待时间
+func WithWriterFlushInterval(interval time.Duration) WriterOption {
+	return func(w *Writer) {
+		if interval > 0 {
+			w.flushInterval = interval
+		}
+	}
+}
+
+// WithWriterQueueSize 队列容量，超过后按 DropPolicy 处理
+func WithWriterQueueSize(size int) WriterOption {
+	return func(w *Writer) {
+		if size > 0 {
+			w.queueSize = size
+		}
+	}
+}
+
+// WithWriterDropPolicy 队列已满时的处理策略，默认 DropPolicyBlock
+func WithWriterDropPolicy(policy DropPolicy) WriterOption {
+	return func(w *Writer) {
+		w.dropPolicy = policy
+	}
+}
+
+// WithWriterRetry 设置 429/5xx 与网络错误的最大重试次数及指数退避的初始、最大间隔；maxRetries 为 0 时不重试
+func WithWriterRetry(maxRetries int, minBackoff, maxBackoff time.Duration) WriterOption {
+	return func(w *Writer) {
+		if maxRetries >= 0 {
+			w.maxRetries = maxRetries
+		}
+		if minBackoff > 0 {
+			w.minBackoff = minBackoff
+		}
+		if maxBackoff >= w.minBackoff {
+			w.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithWriterDeadLetter 设置死信回调，接收被丢弃或重试后仍写入失败的点
+func WithWriterDeadLetter(fn DeadLetterFunc) WriterOption {
+	return func(w *Writer) {
+		w.deadLetter = fn
+	}
+}
+
+// WithWriterMetrics 设置指标，默认使用 Client 的 WithMetrics 配置；传入 nil 关闭指标
+func WithWriterMetrics(m *metrics.Metrics) WriterOption {
+	return func(w *Writer) {
+		w.metrics = m
+	}
+}
+
+// Writer 异步批量写入器：点先进入有界队列，由后台协程按数量或时间间隔批量提交，
+// 429/5xx 与网络错误按指数退避重试，最终失败的点交给死信回调。可被多个协程并发使用
+type Writer struct {
+	write func(ctx context.Context, points []*influxdb3.Point) error
+	name  string
+	log   *log.Helper
+
+	batchSize     int
+	flushInterval time.Duration
+	queueSize     int
+	dropPolicy    DropPolicy
+	maxRetries    int
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	deadLetter    DeadLetterFunc
+	metrics       *metrics.Metrics
+
+	queue    chan *influxdb3.Point
+	flushReq chan chan error
+
+	mu     sync.RWMutex
+	closed bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWriter 创建异步写入器并启动后台协程，使用完毕后必须调用 Close
+func NewWriter(client *Client, opts ...WriterOption) (*Writer, error) {
+	if client == nil || client.cli == nil {
+		return nil, ErrInfluxDBClientNotInitialized
+	}
+
+	write := func(ctx context.Context, points []*influxdb3.Point) (err error) {
+		ctx, span := client.tracer.Start(ctx, "WRITE", "")
+		defer func() { span.End(err) }()
+
+		if err = client.cli.WritePoints(ctx, points); err != nil {
+			return err
+		}
+		span.SetAffectedRows(int64(len(points)))
+		return nil
+	}
+
+	var name string
+	if client.options != nil {
+		name = client.options.Database
+	}
+	return newWriter(write, name, client.log, append([]WriterOption{WithWriterMetrics(client.metrics)}, opts...)...), nil
+}
+
+func newWriter(write func(ctx context.Context, points []*influxdb3.Point) error, name string, logger *log.Helper, opts ...WriterOption) *Writer {
+	w := &Writer{
+		write: write,
+		name:  name,
+		log:   logger,
+
+		batchSize:     defaultWriterBatchSize,
+		flushInterval: defaultWriterFlushInterval,
+		queueSize:     defaultWriterQueueSize,
+		maxRetries:    defaultWriterMaxRetries,
+		minBackoff:    defaultWriterMinBackoff,
+		maxBackoff:    defaultWriterMaxBackoff,
+
+		flushReq: make(chan chan error),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.log == nil {
+		w.log = log.NewHelper(log.With(log.DefaultLogger, "module", "influxdb-writer"))
+	}
+
+	w.queue = make(chan *influxdb3.Point, w.queueSize)
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+
+	go w.run()
+	return w
+}
+
+// Write 将点放入队列后立即返回，nil 点会被忽略。
+// 队列已满时按 DropPolicy 阻塞或丢弃；DropPolicyDropNewest 丢弃任一点时返回 ErrWriterQueueFull
+func (w *Writer) Write(ctx context.Context, points ...*influxdb3.Point) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	var err error
+	for _, p := range points {
+		if p == nil {
+			continue
+		}
+		if e := w.enqueue(ctx, p); e != nil && err == nil {
+			err = e
+			if w.dropPolicy == DropPolicyBlock {
+				break
+			}
+		}
+	}
+	w.metrics.SetBatchQueueDepth(metrics.BackendInfluxDB, w.name, len(w.queue))
+	return err
+}
+
+// enqueue 按 DropPolicy 将点放入队列
+func (w *Writer) enqueue(ctx context.Context, p *influxdb3.Point) error {
+	select {
+	case w.queue <- p:
+		return nil
+	default:
+	}
+
+	switch w.dropPolicy {
+	case DropPolicyDropNewest:
+		w.drop([]*influxdb3.Point{p}, ErrWriterQueueFull, dropReasonQueueFull)
+		return ErrWriterQueueFull
+
+	case DropPolicyDropOldest:
+		for {
+			select {
+			case w.queue <- p:
+				return nil
+			default:
+			}
+			select {
+			case old := <-w.queue:
+				w.drop([]*influxdb3.Point{old}, ErrWriterQueueFull, dropReasonQueueFull)
+			default:
+			}
+		}
+
+	default:
+		select {
+		case w.queue <- p:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Flush 提交调用前已入队的所有点，返回其中写入失败的第一个错误
+func (w *Writer) Flush(ctx context.Context) error {
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return ErrWriterClosed
+	}
+
+	reply := make(chan error, 1)
+	select {
+	case w.flushReq <- reply:
+		w.mu.RUnlock()
+	case <-ctx.Done():
+		w.mu.RUnlock()
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 停止接收新点并提交队列中剩余的点；ctx 结束时放弃重试，未写入的点交给死信回调
+func (w *Writer) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		<-w.done
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+		return ctx.Err()
+	}
+}
+
+// run 后台协程：按数量、时间间隔或 Flush 请求提交缓冲中的点，队列关闭后提交剩余的点并退出
+func (w *Writer) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*influxdb3.Point, 0, w.batchSize)
+	for {
+		select {
+		case p, ok := <-w.queue:
+			if !ok {
+				_ = w.flush(batch)
+				return
+			}
+			batch = append(batch, p)
+			if len(batch) >= w.batchSize {
+				_ = w.flush(batch)
+				batch = make([]*influxdb3.Point, 0, w.batchSize)
+			}
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				_ = w.flush(batch)
+				batch = make([]*influxdb3.Point, 0, w.batchSize)
+			}
+
+		case reply := <-w.flushReq:
+			var err error
+			for n := len(w.queue); n > 0; n-- {
+				p, ok := <-w.queue
+				if !ok {
+					break
+				}
+				batch = append(batch, p)
+				if len(batch) >= w.batchSize {
+					if e := w.flush(batch); e != nil && err == nil {
+						err = e
+					}
+					batch = make([]*influxdb3.Point, 0, w.batchSize)
+				}
+			}
+			if e := w.flush(batch); e != nil && err == nil {
+				err = e
+			}
+			batch = make([]*influxdb3.Point, 0, w.batchSize)
+			reply <- err
+		}
+	}
+}
+
+// flush 带重试地写入一批点，失败时交给死信回调
+func (w *Writer) flush(points []*influxdb3.Point) (err error) {
+	if len(points) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() {
+		w.metrics.ObserveBatchFlush(metrics.BackendInfluxDB, w.name, time.Since(start), err)
+		w.metrics.SetBatchQueueDepth(metrics.BackendInfluxDB, w.name, len(w.queue))
+	}()
+
+	if err = w.writeWithRetry(points); err != nil {
+		w.drop(points, err, dropReasonWriteFailed)
+	}
+	return err
+}
+
+// writeWithRetry 对可重试的错误按指数退避重试，服务端返回 Retry-After 时以其为准
+func (w *Writer) writeWithRetry(points []*influxdb3.Point) error {
+	backoff := w.minBackoff
+	for attempt := 0; ; attempt++ {
+		err := w.write(w.ctx, points)
+		if err == nil {
+			return nil
+		}
+		if attempt >= w.maxRetries || !isRetryableWriteError(err) || w.ctx.Err() != nil {
+			return err
+		}
+
+		delay := backoff
+		var se *influxdb3.ServerError
+		if errors.As(err, &se) && se.RetryAfter > 0 {
+			delay = time.Duration(se.RetryAfter) * time.Second
+		}
+		if delay > w.maxBackoff {
+			delay = w.maxBackoff
+		}
+		w.log.Warnf("influxdb write of %d points failed, retrying in %s (%d/%d): %v", len(points), delay, attempt+1, w.maxRetries, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-w.ctx.Done():
+			timer.Stop()
+			return err
+		}
+
+		if backoff *= 2; backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+}
+
+// drop 记录丢弃的点并交给死信回调，未设置回调时记录日志
+func (w *Writer) drop(points []*influxdb3.Point, err error, reason string) {
+	w.metrics.AddBatchDropped(metrics.BackendInfluxDB, w.name, reason, len(points))
+	if w.deadLetter != nil {
+		w.deadLetter(points, err)
+		return
+	}
+	w.log.Errorf("influxdb writer dropped %d points (%s): %v", len(points), reason, err)
+}
+
+// isRetryableWriteError 429、5xx 与网络错误可以重试
+func isRetryableWriteError(err error) bool {
+	var se *influxdb3.ServerError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= http.StatusInternalServerError
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
@@ -0,0 +1,178 @@
+package influxdb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+)
+
+type fakeWriteSink struct {
+	mu      sync.Mutex
+	batches [][]*influxdb3.Point
+	errs    []error
+}
+
+func (s *fakeWriteSink) write(_ context.Context, points []*influxdb3.Point) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	s.batches = append(s.batches, points)
+	return nil
+}
+
+func (s *fakeWriteSink) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizes := make([]int, len(s.batches))
+	for i, b := range s.batches {
+		sizes[i] = len(b)
+	}
+	return sizes
+}
+
+func writerPoint(i int) *influxdb3.Point {
+	return influxdb3.NewPoint("cpu", nil, map[string]any{"v": int64(i)}, time.Unix(int64(i), 0))
+}
+
+func TestWriter_SizeFlushAndClose(t *testing.T) {
+	sink := &fakeWriteSink{}
+	w := newWriter(sink.write, "test", nil, WithWriterBatchSize(3), WithWriterFlushInterval(time.Hour))
+
+	for i := 0; i < 7; i++ {
+		if err := w.Write(context.Background(), writerPoint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sink.sizes(); len(got) != 3 || got[0] != 3 || got[1] != 3 || got[2] != 1 {
+		t.Errorf("unexpected batch sizes %v", got)
+	}
+	if err := w.Write(context.Background(), writerPoint(8)); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("expected ErrWriterClosed, got %v", err)
+	}
+}
+
+func TestWriter_IntervalAndFlush(t *testing.T) {
+	sink := &fakeWriteSink{}
+	w := newWriter(sink.write, "test", nil, WithWriterFlushInterval(10*time.Millisecond))
+	defer func() { _ = w.Close(context.Background()) }()
+
+	_ = w.Write(context.Background(), writerPoint(1), writerPoint(2))
+	deadline := time.Now().Add(time.Second)
+	for len(sink.sizes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := sink.sizes(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("expected one interval flush of 2 points, got %v", got)
+	}
+
+	_ = w.Write(context.Background(), writerPoint(3))
+	if err := w.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.sizes(); len(got) != 2 || got[1] != 1 {
+		t.Errorf("expected explicit flush of 1 point, got %v", got)
+	}
+}
+
+func TestWriter_RetryAndDeadLetter(t *testing.T) {
+	var dead []*influxdb3.Point
+	var deadErr error
+	sink := &fakeWriteSink{errs: []error{
+		&influxdb3.ServerError{StatusCode: http.StatusServiceUnavailable},
+		&influxdb3.ServerError{StatusCode: http.StatusTooManyRequests},
+		nil,
+		&influxdb3.ServerError{StatusCode: http.StatusBadRequest},
+	}}
+	w := newWriter(sink.write, "test", nil,
+		WithWriterRetry(2, time.Millisecond, 2*time.Millisecond),
+		WithWriterDeadLetter(func(points []*influxdb3.Point, err error) {
+			dead, deadErr = points, err
+		}),
+	)
+
+	// 两次可重试错误后成功
+	_ = w.Write(context.Background(), writerPoint(1))
+	if err := w.Flush(context.Background()); err != nil {
+		t.Fatalf("expected retries to succeed, got %v", err)
+	}
+
+	// 400 不重试，直接进入死信
+	_ = w.Write(context.Background(), writerPoint(2))
+	if err := w.Flush(context.Background()); err == nil {
+		t.Fatal("expected flush error")
+	}
+	_ = w.Close(context.Background())
+
+	if got := sink.sizes(); len(got) != 1 {
+		t.Errorf("expected one successful batch, got %v", got)
+	}
+	var se *influxdb3.ServerError
+	if len(dead) != 1 || !errors.As(deadErr, &se) || se.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected dead letter %v: %v", dead, deadErr)
+	}
+}
+
+func TestWriter_DropPolicies(t *testing.T) {
+	block := make(chan struct{})
+	write := func(ctx context.Context, points []*influxdb3.Point) error {
+		<-block
+		return nil
+	}
+
+	var dropped int
+	var mu sync.Mutex
+	deadLetter := WithWriterDeadLetter(func(points []*influxdb3.Point, err error) {
+		mu.Lock()
+		dropped += len(points)
+		mu.Unlock()
+	})
+
+	w := newWriter(write, "test", nil, WithWriterBatchSize(1), WithWriterQueueSize(2),
+		WithWriterDropPolicy(DropPolicyDropNewest), deadLetter)
+
+	// 第一个点被后台协程取出并阻塞在写入中，随后队列可容纳 2 个点
+	_ = w.Write(context.Background(), writerPoint(0))
+	time.Sleep(20 * time.Millisecond)
+	err := w.Write(context.Background(), writerPoint(1), writerPoint(2), writerPoint(3))
+	if !errors.Is(err, ErrWriterQueueFull) {
+		t.Errorf("expected ErrWriterQueueFull, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	w.dropPolicy = DropPolicyBlock
+	if err = w.Write(ctx, writerPoint(4)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected backpressure to time out, got %v", err)
+	}
+
+	w.dropPolicy = DropPolicyDropOldest
+	if err = w.Write(context.Background(), writerPoint(5)); err != nil {
+		t.Errorf("expected drop-oldest to accept point, got %v", err)
+	}
+
+	close(block)
+	_ = w.Close(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if dropped != 2 {
+		t.Errorf("expected 2 dropped points, got %d", dropped)
+	}
+}
@@ -0,0 +1,233 @@
+package limits
+
+import (
+	"encoding/json"
+	"regexp/syntax"
+	"strings"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+// queryDelimiter query/or_query 中字段与操作符的分隔符
+const queryDelimiter = "__"
+
+// CheckFilterExpr 校验 FilterExpr 的嵌套层数、条件数、IN 列表大小与正则
+func (l Limits) CheckFilterExpr(expr *paginationV1.FilterExpr) error {
+	return l.CheckFilter("", "", expr)
+}
+
+// CheckFilter 校验 query/or_query 过滤字符串与 FilterExpr，条件数合并计算
+func (l Limits) CheckFilter(query, orQuery string, expr *paginationV1.FilterExpr) error {
+	var conditions int
+	if err := l.checkQueryString(query, &conditions); err != nil {
+		return err
+	}
+	if err := l.checkQueryString(orQuery, &conditions); err != nil {
+		return err
+	}
+	return l.checkExpr(expr, 1, &conditions)
+}
+
+func (l Limits) checkExpr(expr *paginationV1.FilterExpr, depth int, conditions *int) error {
+	if expr == nil {
+		return nil
+	}
+	if l.MaxFilterDepth > 0 && depth > l.MaxFilterDepth {
+		return &Error{
+			Reason:  ReasonFilterTooDeep,
+			Message: "filter expression nested too deep",
+			Field:   "filter_expr",
+			Limit:   int64(l.MaxFilterDepth),
+			Actual:  int64(depth),
+		}
+	}
+
+	for _, c := range expr.GetConditions() {
+		if c == nil {
+			continue
+		}
+		if err := l.countCondition(conditions); err != nil {
+			return err
+		}
+
+		switch c.GetOp() {
+		case paginationV1.Operator_IN, paginationV1.Operator_NIN:
+			n := len(c.GetValues())
+			if c.GetValue() != "" {
+				n = max(n, jsonArrayLen(c.GetValue()))
+			}
+			if err := l.checkInList(c.GetField(), n); err != nil {
+				return err
+			}
+		case paginationV1.Operator_REGEXP, paginationV1.Operator_IREGEXP:
+			if err := l.checkRegex(c.GetField(), c.GetValue()); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, g := range expr.GetGroups() {
+		if err := l.checkExpr(g, depth+1, conditions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkQueryString 校验 query/or_query 过滤字符串，格式为 {"field__op":"value"} 或其数组。
+// 无法解析的字符串交由过滤器处理，此处不报错。
+func (l Limits) checkQueryString(query string, conditions *int) error {
+	if strings.TrimSpace(query) == "" {
+		return nil
+	}
+
+	var raw any
+	if err := json.Unmarshal([]byte(query), &raw); err != nil {
+		return nil
+	}
+
+	var maps []map[string]any
+	switch v := raw.(type) {
+	case map[string]any:
+		maps = append(maps, v)
+	case []any:
+		for _, item := range v {
+			if m, ok := item.(map[string]any); ok {
+				maps = append(maps, m)
+			}
+		}
+	}
+
+	for _, m := range maps {
+		for key, value := range m {
+			if err := l.countCondition(conditions); err != nil {
+				return err
+			}
+
+			parts := strings.Split(key, queryDelimiter)
+			for _, op := range parts[1:] {
+				switch strings.ToLower(op) {
+				case "in", "nin", "not_in":
+					if err := l.checkInList(parts[0], queryValueLen(value)); err != nil {
+						return err
+					}
+				case "regexp", "iregexp":
+					s, _ := value.(string)
+					if err := l.checkRegex(parts[0], s); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (l Limits) countCondition(conditions *int) error {
+	*conditions++
+	if l.MaxConditions <= 0 || *conditions <= l.MaxConditions {
+		return nil
+	}
+	return &Error{
+		Reason:  ReasonTooManyConditions,
+		Message: "too many filter conditions",
+		Limit:   int64(l.MaxConditions),
+		Actual:  int64(*conditions),
+	}
+}
+
+func (l Limits) checkInList(field string, n int) error {
+	if l.MaxInListSize <= 0 || n <= l.MaxInListSize {
+		return nil
+	}
+	return &Error{
+		Reason:  ReasonInListTooLarge,
+		Message: "IN list too large",
+		Field:   field,
+		Limit:   int64(l.MaxInListSize),
+		Actual:  int64(n),
+	}
+}
+
+func (l Limits) checkRegex(field, pattern string) error {
+	if l.DisallowRegex {
+		return &Error{
+			Reason:  ReasonRegexNotAllowed,
+			Message: "regular expression filters are not allowed",
+			Field:   field,
+		}
+	}
+	if l.MaxRegexLength > 0 && len(pattern) > l.MaxRegexLength {
+		return &Error{
+			Reason:  ReasonRegexTooComplex,
+			Message: "regular expression too long",
+			Field:   field,
+			Limit:   int64(l.MaxRegexLength),
+			Actual:  int64(len(pattern)),
+		}
+	}
+	if !l.RejectComplexRegex {
+		return nil
+	}
+
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return &Error{
+			Reason:  ReasonRegexTooComplex,
+			Message: "invalid regular expression: " + err.Error(),
+			Field:   field,
+		}
+	}
+	if hasNestedQuantifier(re, false) {
+		return &Error{
+			Reason:  ReasonRegexTooComplex,
+			Message: "regular expression contains nested quantifiers",
+			Field:   field,
+		}
+	}
+	return nil
+}
+
+// hasNestedQuantifier 判断正则是否在可重复的子表达式内再次使用可重复量词，如 (a+)+、(a*b)*
+func hasNestedQuantifier(re *syntax.Regexp, inRepeat bool) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		if inRepeat {
+			return true
+		}
+		inRepeat = true
+	case syntax.OpRepeat:
+		if re.Max == -1 || re.Max > 1 {
+			if inRepeat {
+				return true
+			}
+			inRepeat = true
+		}
+	}
+	for _, sub := range re.Sub {
+		if hasNestedQuantifier(sub, inRepeat) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryValueLen 返回 query 中 IN 取值的个数，取值可以是 JSON 数组或 JSON 数组字符串
+func queryValueLen(value any) int {
+	switch v := value.(type) {
+	case []any:
+		return len(v)
+	case string:
+		return jsonArrayLen(v)
+	}
+	return 1
+}
+
+// jsonArrayLen 返回 JSON 数组字符串的元素个数，非数组时按单个取值计算
+func jsonArrayLen(s string) int {
+	var arr []json.RawMessage
+	if err := json.Unmarshal([]byte(s), &arr); err != nil {
+		return 1
+	}
+	return len(arr)
+}
@@ -0,0 +1,413 @@
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	kratosErrors "github.com/go-kratos/kratos/v2/errors"
+	"google.golang.org/protobuf/proto"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+// ErrInvalidArgument 请求超出复杂度限制，所有 *Error 均可通过 errors.Is 匹配
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// 错误原因，对应 *Error.Reason
+const (
+	ReasonPageSizeTooLarge   = "PAGE_SIZE_TOO_LARGE"
+	ReasonOffsetTooLarge     = "OFFSET_TOO_LARGE"
+	ReasonNoPagingNotAllowed = "NO_PAGING_NOT_ALLOWED"
+	ReasonFilterTooDeep      = "FILTER_TOO_DEEP"
+	ReasonTooManyConditions  = "TOO_MANY_CONDITIONS"
+	ReasonInListTooLarge     = "IN_LIST_TOO_LARGE"
+	ReasonRegexNotAllowed    = "REGEX_NOT_ALLOWED"
+	ReasonRegexTooComplex    = "REGEX_TOO_COMPLEX"
+	ReasonTooManySortFields  = "TOO_MANY_SORT_FIELDS"
+)
+
+// Error 请求超出复杂度限制，Reason 为上面的常量之一
+type Error struct {
+	Reason  string
+	Message string
+
+	// Field 触发限制的请求字段或过滤字段
+	Field string
+
+	// Limit/Actual 配置的上限与请求的实际值，不适用时为 0
+	Limit  int64
+	Actual int64
+}
+
+func (e *Error) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Message)
+	if e.Field != "" {
+		fmt.Fprintf(&sb, " (%s)", e.Field)
+	}
+	if e.Limit > 0 {
+		fmt.Fprintf(&sb, ": %d > %d", e.Actual, e.Limit)
+	}
+	return sb.String()
+}
+
+func (e *Error) Unwrap() error {
+	return ErrInvalidArgument
+}
+
+// GetReason 返回错误原因，与 kratos 错误的 Reason 语义一致
+func (e *Error) GetReason() string {
+	return e.Reason
+}
+
+// ToKratos 转为 Reason 相同的 BadRequest 错误，原错误作为 cause 保留
+func (e *Error) ToKratos() *kratosErrors.Error {
+	return kratosErrors.BadRequest(e.Reason, e.Error()).WithCause(e)
+}
+
+// toKratos 将 *Error 转为 BadRequest 错误，其他错误原样返回
+func toKratos(err error) error {
+	var le *Error
+	if errors.As(err, &le) {
+		return le.ToKratos()
+	}
+	return err
+}
+
+// NoPagingMode 无界查询（no_paging 或未携带任何分页参数）的处理方式
+type NoPagingMode int
+
+const (
+	// NoPagingAllow 允许无界查询
+	NoPagingAllow NoPagingMode = iota
+	// NoPagingClamp 改写为第一页、每页 MaxPageSize 条；MaxPageSize 为 0 时等同于 NoPagingAllow
+	NoPagingClamp
+	// NoPagingReject 拒绝无界查询
+	NoPagingReject
+)
+
+// Limits 单个实体的请求复杂度限制，零值表示不做任何限制
+type Limits struct {
+	// MaxPageSize 每页最大条数（page_size/limit），0 表示不限制
+	MaxPageSize uint32
+	// RejectOversize 超出 MaxPageSize 时返回错误，默认截断为 MaxPageSize
+	RejectOversize bool
+
+	// MaxOffset 最大偏移量（offset 或 (page-1)*page_size），0 表示不限制
+	MaxOffset uint64
+
+	// NoPaging 无界查询的处理方式
+	NoPaging NoPagingMode
+
+	// MaxFilterDepth FilterExpr 最大嵌套层数（根表达式为第 1 层），0 表示不限制
+	MaxFilterDepth int
+	// MaxConditions 过滤条件总数（FilterExpr 与 query/or_query 合计），0 表示不限制
+	MaxConditions int
+	// MaxInListSize IN/NIN 的最大取值个数，0 表示不限制
+	MaxInListSize int
+
+	// DisallowRegex 禁止 REGEXP/IREGEXP 过滤
+	DisallowRegex bool
+	// MaxRegexLength 正则表达式最大长度，0 表示不限制
+	MaxRegexLength int
+	// RejectComplexRegex 拒绝无法解析或含嵌套量词（如 (a+)+）的正则，避免回溯引擎的灾难性回溯
+	RejectComplexRegex bool
+
+	// MaxSortFields 排序字段最大个数（sorting 与 order_by 分别计算），0 表示不限制
+	MaxSortFields int
+}
+
+// Recommended 返回建议的限制配置
+func Recommended() Limits {
+	return Limits{
+		MaxPageSize:        100,
+		MaxOffset:          10000,
+		NoPaging:           NoPagingReject,
+		MaxFilterDepth:     5,
+		MaxConditions:      50,
+		MaxInListSize:      1000,
+		MaxRegexLength:     256,
+		RejectComplexRegex: true,
+		MaxSortFields:      5,
+	}
+}
+
+// Policy 请求复杂度策略，由各仓库在执行列表查询与按条件更新/删除前应用。
+// nil 表示不限制；超出限制时返回 Reason 为 Reason* 的 BadRequest 错误，可通过 errors.As 取得 *Error。
+type Policy struct {
+	// Limits 默认限制
+	Limits
+
+	// Overrides 按实体覆盖默认限制（整体替换），键为 ENTITY 的类型名，Elasticsearch 为索引名
+	Overrides map[string]Limits
+}
+
+// For 返回实体生效的限制
+func (p *Policy) For(entity string) Limits {
+	if p == nil {
+		return Limits{}
+	}
+	if l, ok := p.Overrides[entity]; ok {
+		return l
+	}
+	return p.Limits
+}
+
+// ApplyPaging 按实体限制校验并改写 PagingRequest
+func (p *Policy) ApplyPaging(entity string, req *paginationV1.PagingRequest) (*paginationV1.PagingRequest, error) {
+	if p == nil {
+		return req, nil
+	}
+	req, err := p.For(entity).ApplyPaging(req)
+	return req, toKratos(err)
+}
+
+// ApplyPagination 按实体限制校验并改写 PaginationRequest
+func (p *Policy) ApplyPagination(entity string, req *paginationV1.PaginationRequest) (*paginationV1.PaginationRequest, error) {
+	if p == nil {
+		return req, nil
+	}
+	req, err := p.For(entity).ApplyPagination(req)
+	return req, toKratos(err)
+}
+
+// CheckFilterExpr 按实体限制校验 FilterExpr
+func (p *Policy) CheckFilterExpr(entity string, expr *paginationV1.FilterExpr) error {
+	if p == nil {
+		return nil
+	}
+	return toKratos(p.For(entity).CheckFilterExpr(expr))
+}
+
+// CheckFilter 按实体限制校验 query/or_query 过滤字符串与 FilterExpr
+func (p *Policy) CheckFilter(entity, query, orQuery string, expr *paginationV1.FilterExpr) error {
+	if p == nil {
+		return nil
+	}
+	return toKratos(p.For(entity).CheckFilter(query, orQuery, expr))
+}
+
+// ApplyPaging 校验过滤、排序与分页参数；需要截断时返回修改后的副本，不修改原请求
+func (l Limits) ApplyPaging(req *paginationV1.PagingRequest) (*paginationV1.PagingRequest, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	if err := l.checkRequest(req.GetQuery(), req.GetOrQuery(), req.GetFilterExpr(), len(req.GetSorting()), len(req.GetOrderBy())); err != nil {
+		return nil, err
+	}
+
+	// 与各仓库一致：page+page_size 优先，其次 offset+limit，token 分页时 offset 作为每页条数
+	switch {
+	case req.GetNoPaging():
+		if l.NoPaging == NoPagingReject {
+			return nil, l.noPagingError()
+		}
+		if l.NoPaging == NoPagingClamp && l.MaxPageSize > 0 {
+			return l.firstPage(req), nil
+		}
+		return req, nil
+
+	case req.Page != nil && req.PageSize != nil:
+		size, err := l.pageSize("page_size", req.GetPageSize())
+		if err != nil {
+			return nil, err
+		}
+		if err = l.checkOffset("page", pageOffset(req.GetPage(), size)); err != nil {
+			return nil, err
+		}
+		if size != req.GetPageSize() {
+			req = proto.Clone(req).(*paginationV1.PagingRequest)
+			req.PageSize = proto.Uint32(size)
+		}
+		return req, nil
+
+	case req.Offset != nil && req.Limit != nil:
+		size, err := l.pageSize("limit", req.GetLimit())
+		if err != nil {
+			return nil, err
+		}
+		if err = l.checkOffset("offset", req.GetOffset()); err != nil {
+			return nil, err
+		}
+		if size != req.GetLimit() {
+			req = proto.Clone(req).(*paginationV1.PagingRequest)
+			req.Limit = proto.Uint32(size)
+		}
+		return req, nil
+
+	case req.Token != nil && req.Offset != nil:
+		if l.MaxPageSize == 0 || req.GetOffset() <= uint64(l.MaxPageSize) {
+			return req, nil
+		}
+		if l.RejectOversize {
+			return nil, l.pageSizeError("offset", req.GetOffset())
+		}
+		req = proto.Clone(req).(*paginationV1.PagingRequest)
+		req.Offset = proto.Uint64(uint64(l.MaxPageSize))
+		return req, nil
+
+	default:
+		// 未携带分页参数时各仓库返回全部记录，按无界查询处理
+		if l.NoPaging == NoPagingReject {
+			return nil, l.noPagingError()
+		}
+		if l.NoPaging == NoPagingClamp && l.MaxPageSize > 0 {
+			return l.firstPage(req), nil
+		}
+		return req, nil
+	}
+}
+
+// ApplyPagination 校验过滤、排序与分页参数；需要截断时返回修改后的副本，不修改原请求
+func (l Limits) ApplyPagination(req *paginationV1.PaginationRequest) (*paginationV1.PaginationRequest, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	if err := l.checkRequest(req.GetQuery(), req.GetOrQuery(), req.GetFilterExpr(), len(req.GetSorting()), len(req.GetOrderBy())); err != nil {
+		return nil, err
+	}
+
+	switch req.GetPaginationType().(type) {
+	case *paginationV1.PaginationRequest_PageBased:
+		pb := req.GetPageBased()
+		size, err := l.pageSize("page_size", pb.GetPageSize())
+		if err != nil {
+			return nil, err
+		}
+		if err = l.checkOffset("page", pageOffset(pb.GetPage(), size)); err != nil {
+			return nil, err
+		}
+		if size != pb.GetPageSize() {
+			req = proto.Clone(req).(*paginationV1.PaginationRequest)
+			req.GetPageBased().PageSize = size
+		}
+		return req, nil
+
+	case *paginationV1.PaginationRequest_OffsetBased:
+		ob := req.GetOffsetBased()
+		size, err := l.pageSize("limit", ob.GetLimit())
+		if err != nil {
+			return nil, err
+		}
+		if err = l.checkOffset("offset", ob.GetOffset()); err != nil {
+			return nil, err
+		}
+		if size != ob.GetLimit() {
+			req = proto.Clone(req).(*paginationV1.PaginationRequest)
+			req.GetOffsetBased().Limit = size
+		}
+		return req, nil
+
+	case *paginationV1.PaginationRequest_TokenBased:
+		tb := req.GetTokenBased()
+		size, err := l.pageSize("page_size", tb.GetPageSize())
+		if err != nil {
+			return nil, err
+		}
+		if size != tb.GetPageSize() {
+			req = proto.Clone(req).(*paginationV1.PaginationRequest)
+			req.GetTokenBased().PageSize = size
+		}
+		return req, nil
+
+	default:
+		// no_paging 或未设置分页方式
+		if l.NoPaging == NoPagingReject {
+			return nil, l.noPagingError()
+		}
+		if l.NoPaging == NoPagingClamp && l.MaxPageSize > 0 {
+			req = proto.Clone(req).(*paginationV1.PaginationRequest)
+			req.PaginationType = &paginationV1.PaginationRequest_PageBased{
+				PageBased: &paginationV1.PageBasedPagination{Page: 1, PageSize: l.MaxPageSize},
+			}
+		}
+		return req, nil
+	}
+}
+
+// checkRequest 校验请求中的过滤条件与排序字段数
+func (l Limits) checkRequest(query, orQuery string, expr *paginationV1.FilterExpr, sorting, orderBy int) error {
+	if err := l.checkSortFields("sorting", sorting); err != nil {
+		return err
+	}
+	if err := l.checkSortFields("order_by", orderBy); err != nil {
+		return err
+	}
+
+	return l.CheckFilter(query, orQuery, expr)
+}
+
+func (l Limits) checkSortFields(field string, n int) error {
+	if l.MaxSortFields <= 0 || n <= l.MaxSortFields {
+		return nil
+	}
+	return &Error{
+		Reason:  ReasonTooManySortFields,
+		Message: "too many sort fields",
+		Field:   field,
+		Limit:   int64(l.MaxSortFields),
+		Actual:  int64(n),
+	}
+}
+
+// pageSize 返回截断后的每页条数，RejectOversize 时超出上限返回错误
+func (l Limits) pageSize(field string, size uint32) (uint32, error) {
+	if l.MaxPageSize == 0 || size <= l.MaxPageSize {
+		return size, nil
+	}
+	if l.RejectOversize {
+		return 0, l.pageSizeError(field, uint64(size))
+	}
+	return l.MaxPageSize, nil
+}
+
+func (l Limits) pageSizeError(field string, size uint64) error {
+	return &Error{
+		Reason:  ReasonPageSizeTooLarge,
+		Message: "page size too large",
+		Field:   field,
+		Limit:   int64(l.MaxPageSize),
+		Actual:  int64(size),
+	}
+}
+
+func (l Limits) checkOffset(field string, offset uint64) error {
+	if l.MaxOffset == 0 || offset <= l.MaxOffset {
+		return nil
+	}
+	return &Error{
+		Reason:  ReasonOffsetTooLarge,
+		Message: "offset too large",
+		Field:   field,
+		Limit:   int64(l.MaxOffset),
+		Actual:  int64(offset),
+	}
+}
+
+func (l Limits) noPagingError() error {
+	return &Error{
+		Reason:  ReasonNoPagingNotAllowed,
+		Message: "unpaged queries are not allowed",
+		Field:   "no_paging",
+	}
+}
+
+// firstPage 将无界的 PagingRequest 改写为第一页、每页 MaxPageSize 条
+func (l Limits) firstPage(req *paginationV1.PagingRequest) *paginationV1.PagingRequest {
+	req = proto.Clone(req).(*paginationV1.PagingRequest)
+	req.NoPaging = nil
+	req.Page = proto.Uint32(1)
+	req.PageSize = proto.Uint32(l.MaxPageSize)
+	return req
+}
+
+// pageOffset 计算页码分页的偏移量，页码从 1 开始
+func pageOffset(page, size uint32) uint64 {
+	if page <= 1 {
+		return 0
+	}
+	return uint64(page-1) * uint64(size)
+}
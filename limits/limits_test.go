@@ -0,0 +1,255 @@
+package limits
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	kratosErrors "github.com/go-kratos/kratos/v2/errors"
+	"google.golang.org/protobuf/proto"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+func reasonOf(t *testing.T, err error) string {
+	t.Helper()
+	var le *Error
+	if !errors.As(err, &le) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("expected errors.Is ErrInvalidArgument for %v", err)
+	}
+	return le.Reason
+}
+
+func TestPolicy_Nil(t *testing.T) {
+	var p *Policy
+	req := &paginationV1.PagingRequest{NoPaging: proto.Bool(true)}
+	got, err := p.ApplyPaging("User", req)
+	if err != nil || got != req {
+		t.Fatalf("nil policy: got %v, %v", got, err)
+	}
+	if err = p.CheckFilterExpr("User", &paginationV1.FilterExpr{}); err != nil {
+		t.Fatalf("nil policy: unexpected error %v", err)
+	}
+}
+
+func TestLimits_ApplyPaging_PageSize(t *testing.T) {
+	l := Limits{MaxPageSize: 100}
+
+	req := &paginationV1.PagingRequest{Page: proto.Uint32(1), PageSize: proto.Uint32(1000000)}
+	got, err := l.ApplyPaging(req)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got.GetPageSize() != 100 {
+		t.Errorf("expected page size clamped to 100, got %d", got.GetPageSize())
+	}
+	if req.GetPageSize() != 1000000 {
+		t.Errorf("original request must not be modified")
+	}
+
+	l.RejectOversize = true
+	if _, err = l.ApplyPaging(req); reasonOf(t, err) != ReasonPageSizeTooLarge {
+		t.Errorf("expected %s, got %v", ReasonPageSizeTooLarge, err)
+	}
+
+	ok := &paginationV1.PagingRequest{Offset: proto.Uint64(0), Limit: proto.Uint32(50)}
+	if got, err = l.ApplyPaging(ok); err != nil || got != ok {
+		t.Errorf("within limits: got %v, %v", got, err)
+	}
+}
+
+func TestLimits_ApplyPaging_Offset(t *testing.T) {
+	l := Limits{MaxOffset: 1000}
+
+	if _, err := l.ApplyPaging(&paginationV1.PagingRequest{Offset: proto.Uint64(1001), Limit: proto.Uint32(10)}); reasonOf(t, err) != ReasonOffsetTooLarge {
+		t.Errorf("expected %s, got %v", ReasonOffsetTooLarge, err)
+	}
+	if _, err := l.ApplyPaging(&paginationV1.PagingRequest{Page: proto.Uint32(102), PageSize: proto.Uint32(10)}); reasonOf(t, err) != ReasonOffsetTooLarge {
+		t.Errorf("expected %s for deep page, got %v", ReasonOffsetTooLarge, err)
+	}
+	if _, err := l.ApplyPaging(&paginationV1.PagingRequest{Page: proto.Uint32(101), PageSize: proto.Uint32(10)}); err != nil {
+		t.Errorf("at limit: unexpected error %v", err)
+	}
+}
+
+func TestLimits_NoPaging(t *testing.T) {
+	unpaged := []*paginationV1.PagingRequest{
+		{NoPaging: proto.Bool(true)},
+		{},
+	}
+
+	for _, req := range unpaged {
+		if _, err := (Limits{NoPaging: NoPagingReject}).ApplyPaging(req); reasonOf(t, err) != ReasonNoPagingNotAllowed {
+			t.Errorf("expected %s, got %v", ReasonNoPagingNotAllowed, err)
+		}
+
+		got, err := (Limits{NoPaging: NoPagingClamp, MaxPageSize: 50}).ApplyPaging(req)
+		if err != nil {
+			t.Fatalf("clamp: unexpected error %v", err)
+		}
+		if got.GetNoPaging() || got.GetPage() != 1 || got.GetPageSize() != 50 {
+			t.Errorf("clamp: expected first page of 50, got %v", got)
+		}
+
+		if got, err = (Limits{}).ApplyPaging(req); err != nil || got != req {
+			t.Errorf("allow: got %v, %v", got, err)
+		}
+	}
+
+	got, err := (Limits{NoPaging: NoPagingClamp, MaxPageSize: 20}).ApplyPagination(&paginationV1.PaginationRequest{
+		PaginationType: &paginationV1.PaginationRequest_NoPaging{NoPaging: &paginationV1.NoPaging{}},
+	})
+	if err != nil {
+		t.Fatalf("pagination clamp: unexpected error %v", err)
+	}
+	if got.GetPageBased().GetPage() != 1 || got.GetPageBased().GetPageSize() != 20 {
+		t.Errorf("pagination clamp: expected first page of 20, got %v", got)
+	}
+}
+
+func TestLimits_ApplyPagination(t *testing.T) {
+	l := Limits{MaxPageSize: 100, MaxOffset: 500}
+
+	req := &paginationV1.PaginationRequest{
+		PaginationType: &paginationV1.PaginationRequest_TokenBased{
+			TokenBased: &paginationV1.TokenBasedPagination{Token: "abc", PageSize: 1000},
+		},
+	}
+	got, err := l.ApplyPagination(req)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got.GetTokenBased().GetPageSize() != 100 || req.GetTokenBased().GetPageSize() != 1000 {
+		t.Errorf("expected cloned request clamped to 100, got %v", got)
+	}
+
+	_, err = l.ApplyPagination(&paginationV1.PaginationRequest{
+		PaginationType: &paginationV1.PaginationRequest_OffsetBased{
+			OffsetBased: &paginationV1.OffsetBasedPagination{Offset: 501, Limit: 10},
+		},
+	})
+	if reasonOf(t, err) != ReasonOffsetTooLarge {
+		t.Errorf("expected %s, got %v", ReasonOffsetTooLarge, err)
+	}
+}
+
+func nestedExpr(depth int) *paginationV1.FilterExpr {
+	expr := &paginationV1.FilterExpr{
+		Type:       paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{{Field: "id", Op: paginationV1.Operator_EQ, Value: proto.String("1")}},
+	}
+	for i := 1; i < depth; i++ {
+		expr = &paginationV1.FilterExpr{Type: paginationV1.ExprType_OR, Groups: []*paginationV1.FilterExpr{expr}}
+	}
+	return expr
+}
+
+func TestLimits_CheckFilterExpr(t *testing.T) {
+	l := Limits{MaxFilterDepth: 5, MaxConditions: 3, MaxInListSize: 3}
+
+	if err := l.CheckFilterExpr(nestedExpr(5)); err != nil {
+		t.Errorf("depth 5: unexpected error %v", err)
+	}
+	if err := l.CheckFilterExpr(nestedExpr(50)); reasonOf(t, err) != ReasonFilterTooDeep {
+		t.Errorf("expected %s, got %v", ReasonFilterTooDeep, err)
+	}
+
+	many := &paginationV1.FilterExpr{Type: paginationV1.ExprType_AND}
+	for i := 0; i < 4; i++ {
+		many.Conditions = append(many.Conditions, &paginationV1.Condition{Field: "id", Op: paginationV1.Operator_EQ, Value: proto.String("1")})
+	}
+	if err := l.CheckFilterExpr(many); reasonOf(t, err) != ReasonTooManyConditions {
+		t.Errorf("expected %s, got %v", ReasonTooManyConditions, err)
+	}
+
+	in := &paginationV1.FilterExpr{
+		Type:       paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{{Field: "id", Op: paginationV1.Operator_IN, Values: []string{"1", "2", "3", "4"}}},
+	}
+	if err := l.CheckFilterExpr(in); reasonOf(t, err) != ReasonInListTooLarge {
+		t.Errorf("values: expected %s, got %v", ReasonInListTooLarge, err)
+	}
+	in.Conditions[0] = &paginationV1.Condition{Field: "id", Op: paginationV1.Operator_NIN, Value: proto.String(`[1,2,3,4]`)}
+	if err := l.CheckFilterExpr(in); reasonOf(t, err) != ReasonInListTooLarge {
+		t.Errorf("json value: expected %s, got %v", ReasonInListTooLarge, err)
+	}
+}
+
+func TestLimits_Regex(t *testing.T) {
+	expr := func(pattern string) *paginationV1.FilterExpr {
+		return &paginationV1.FilterExpr{
+			Type:       paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{{Field: "name", Op: paginationV1.Operator_REGEXP, Value: proto.String(pattern)}},
+		}
+	}
+
+	l := Limits{RejectComplexRegex: true, MaxRegexLength: 32}
+	for _, pattern := range []string{`^abc`, `a+b*c?`, `(foo|bar)\d{1,3}`} {
+		if err := l.CheckFilterExpr(expr(pattern)); err != nil {
+			t.Errorf("%q: unexpected error %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{`(a+)+$`, `(a*b)*`, `(\w{2,})*x`, `([`, strings.Repeat("a", 33)} {
+		if err := l.CheckFilterExpr(expr(pattern)); reasonOf(t, err) != ReasonRegexTooComplex {
+			t.Errorf("%q: expected %s, got %v", pattern, ReasonRegexTooComplex, err)
+		}
+	}
+
+	if err := (Limits{DisallowRegex: true}).CheckFilterExpr(expr(`^a`)); reasonOf(t, err) != ReasonRegexNotAllowed {
+		t.Errorf("expected %s, got %v", ReasonRegexNotAllowed, err)
+	}
+}
+
+func TestLimits_QueryStringAndSorting(t *testing.T) {
+	l := Limits{MaxInListSize: 2, MaxConditions: 2, DisallowRegex: true, MaxSortFields: 1}
+
+	cases := map[string]*paginationV1.PagingRequest{
+		ReasonInListTooLarge:    {Query: proto.String(`{"id__in":"[\"1\",\"2\",\"3\"]"}`)},
+		ReasonTooManyConditions: {Query: proto.String(`{"a":"1","b":"2"}`), OrQuery: proto.String(`[{"c":"3"}]`)},
+		ReasonRegexNotAllowed:   {OrQuery: proto.String(`{"name__iregexp":"^a"}`)},
+		ReasonTooManySortFields: {OrderBy: []string{"id", "-name"}},
+	}
+	for reason, req := range cases {
+		if _, err := l.ApplyPaging(req); reasonOf(t, err) != reason {
+			t.Errorf("expected %s, got %v", reason, err)
+		}
+	}
+
+	if _, err := l.ApplyPaging(&paginationV1.PagingRequest{Query: proto.String(`{"id__in":"[\"1\",\"2\"]"}`)}); err != nil {
+		t.Errorf("within limits: unexpected error %v", err)
+	}
+}
+
+func TestPolicy_Overrides(t *testing.T) {
+	p := &Policy{
+		Limits:    Limits{MaxPageSize: 10, RejectOversize: true},
+		Overrides: map[string]Limits{"AuditLog": {MaxPageSize: 1000, RejectOversize: true}},
+	}
+	req := &paginationV1.PagingRequest{Page: proto.Uint32(1), PageSize: proto.Uint32(500)}
+
+	if _, err := p.ApplyPaging("User", req); reasonOf(t, err) != ReasonPageSizeTooLarge {
+		t.Errorf("default limits: expected %s, got %v", ReasonPageSizeTooLarge, err)
+	}
+	if _, err := p.ApplyPaging("AuditLog", req); err != nil {
+		t.Errorf("override: unexpected error %v", err)
+	}
+}
+
+func TestPolicy_KratosError(t *testing.T) {
+	p := &Policy{Limits: Limits{MaxPageSize: 10, RejectOversize: true}}
+	_, err := p.ApplyPaging("User", &paginationV1.PagingRequest{Page: proto.Uint32(1), PageSize: proto.Uint32(500)})
+	if !kratosErrors.IsBadRequest(err) || kratosErrors.Reason(err) != ReasonPageSizeTooLarge {
+		t.Fatalf("expected BadRequest %s, got %v", ReasonPageSizeTooLarge, err)
+	}
+	if reasonOf(t, err) != ReasonPageSizeTooLarge {
+		t.Errorf("expected *Error cause, got %v", err)
+	}
+
+	err = p.CheckFilterExpr("User", &paginationV1.FilterExpr{})
+	if err != nil {
+		t.Errorf("within limits: unexpected error %v", err)
+	}
+}
@@ -0,0 +1,408 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/tx7do/go-crud/guard"
+	"github.com/tx7do/go-crud/histogram"
+	"github.com/tx7do/go-crud/ordering"
+	"github.com/tx7do/go-crud/search"
+)
+
+// 后端名称，作为 backend 标签的取值
+const (
+	BackendGorm          = "gorm"
+	BackendEnt           = "ent"
+	BackendMongoDB       = "mongodb"
+	BackendClickHouse    = "clickhouse"
+	BackendElasticsearch = "elasticsearch"
+	BackendInfluxDB      = "influxdb"
+)
+
+// 错误分类，作为 class 标签的取值；带 Reason 的错误（如 kratos errors）使用其 Reason 作为分类
+const (
+	ErrorClassCanceled = "canceled"
+	ErrorClassTimeout  = "timeout"
+	ErrorClassUnknown  = "unknown"
+
+	// ErrorClassInvalidArgument 参数缺失或不合法
+	ErrorClassInvalidArgument = "invalid_argument"
+	// ErrorClassRejected 被安全保护拒绝的破坏性操作
+	ErrorClassRejected = "rejected"
+	// ErrorClassStorage 底层存储执行失败
+	ErrorClassStorage = "storage"
+)
+
+// errorClasses 通过 RegisterErrorClass 注册的错误值与分类
+var errorClasses struct {
+	sync.RWMutex
+	entries []errorClassEntry
+}
+
+type errorClassEntry struct {
+	err   error
+	class string
+}
+
+func init() {
+	RegisterErrorClass(ErrorClassRejected, guard.ErrFullTableOperation, guard.ErrTooManyRowsAffected)
+	RegisterErrorClass(ErrorClassInvalidArgument,
+		histogram.ErrInvalidField, histogram.ErrInvalidInterval, histogram.ErrInvalidTimezone,
+		histogram.ErrInvalidAggregate, histogram.ErrInvalidRange, histogram.ErrTooManyBuckets,
+		search.ErrEmptyQuery, search.ErrNoFields, search.ErrInvalidField,
+		ordering.ErrInvalidJSONPath, ordering.ErrInvalidCollation, ordering.ErrInvalidDatePart,
+	)
+}
+
+// RegisterErrorClass 将错误值映射到分类，ErrorClass 通过 errors.Is 匹配。
+// 各仓库包在 init 中注册自身返回的错误值，使 class 标签不再是 unknown
+func RegisterErrorClass(class string, errs ...error) {
+	errorClasses.Lock()
+	defer errorClasses.Unlock()
+
+	for _, err := range errs {
+		if err != nil {
+			errorClasses.entries = append(errorClasses.entries, errorClassEntry{err: err, class: class})
+		}
+	}
+}
+
+// registeredClass 返回 err 匹配的已注册分类，未匹配时返回空字符串
+func registeredClass(err error) string {
+	errorClasses.RLock()
+	defer errorClasses.RUnlock()
+
+	for _, e := range errorClasses.entries {
+		if errors.Is(err, e.err) {
+			return e.class
+		}
+	}
+	return ""
+}
+
+// DefaultNamespace 指标名称的默认前缀
+const DefaultNamespace = "go_crud"
+
+// Option 配置 Metrics
+type Option func(o *options)
+
+type options struct {
+	namespace     string
+	subsystem     string
+	constLabels   prometheus.Labels
+	buckets       []float64
+	errorClassify func(err error) string
+}
+
+// WithNamespace 设置指标名称前缀，默认 go_crud
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithSubsystem 设置指标名称的子系统段
+func WithSubsystem(subsystem string) Option {
+	return func(o *options) {
+		o.subsystem = subsystem
+	}
+}
+
+// WithConstLabels 为所有指标附加固定标签
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(o *options) {
+		o.constLabels = labels
+	}
+}
+
+// WithBuckets 设置耗时直方图的桶（单位：秒），默认 prometheus.DefBuckets
+func WithBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// WithErrorClassifier 自定义错误分类，返回空字符串时回退到默认分类
+func WithErrorClassifier(classify func(err error) string) Option {
+	return func(o *options) {
+		o.errorClassify = classify
+	}
+}
+
+// Metrics 所有仓库共享的 Prometheus 指标。
+// nil Metrics 表示未启用指标，其方法均可安全调用。
+type Metrics struct {
+	duration   *prometheus.HistogramVec
+	rows       *prometheus.CounterVec
+	errors     *prometheus.CounterVec
+	cache      *prometheus.CounterVec
+	batchQueue *prometheus.GaugeVec
+	batchFlush *prometheus.HistogramVec
+	batchDrop  *prometheus.CounterVec
+
+	errorClassify func(err error) string
+}
+
+// New 创建指标并注册到 reg，reg 为 nil 时使用 prometheus.DefaultRegisterer。
+// 同一 Registerer 上重复创建时复用已注册的指标，因此多个客户端可以安全地各自调用 New。
+func New(reg prometheus.Registerer, opts ...Option) (*Metrics, error) {
+	o := options{
+		namespace: DefaultNamespace,
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	m := &Metrics{
+		errorClassify: o.errorClassify,
+	}
+
+	m.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   o.namespace,
+		Subsystem:   o.subsystem,
+		Name:        "operation_duration_seconds",
+		Help:        "Duration of repository operations.",
+		ConstLabels: o.constLabels,
+		Buckets:     o.buckets,
+	}, []string{"backend", "entity", "method"})
+
+	m.rows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   o.namespace,
+		Subsystem:   o.subsystem,
+		Name:        "rows_total",
+		Help:        "Rows returned or affected by repository operations.",
+		ConstLabels: o.constLabels,
+	}, []string{"backend", "entity", "method", "kind"})
+
+	m.errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   o.namespace,
+		Subsystem:   o.subsystem,
+		Name:        "operation_errors_total",
+		Help:        "Failed repository operations by error class.",
+		ConstLabels: o.constLabels,
+	}, []string{"backend", "entity", "method", "class"})
+
+	m.cache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   o.namespace,
+		Subsystem:   o.subsystem,
+		Name:        "cache_requests_total",
+		Help:        "Cache lookups by result, recorded by callers that cache repository results.",
+		ConstLabels: o.constLabels,
+	}, []string{"backend", "entity", "result"})
+
+	m.batchQueue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   o.namespace,
+		Subsystem:   o.subsystem,
+		Name:        "batch_queue_depth",
+		Help:        "Rows buffered in batch inserters and not yet flushed.",
+		ConstLabels: o.constLabels,
+	}, []string{"backend", "table"})
+
+	m.batchFlush = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   o.namespace,
+		Subsystem:   o.subsystem,
+		Name:        "batch_flush_duration_seconds",
+		Help:        "Duration of batch inserter flushes.",
+		ConstLabels: o.constLabels,
+		Buckets:     o.buckets,
+	}, []string{"backend", "table", "status"})
+
+	m.batchDrop = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   o.namespace,
+		Subsystem:   o.subsystem,
+		Name:        "batch_dropped_rows_total",
+		Help:        "Rows discarded by batch writers without being written.",
+		ConstLabels: o.constLabels,
+	}, []string{"backend", "table", "reason"})
+
+	var err error
+	if m.duration, err = register(reg, m.duration); err != nil {
+		return nil, err
+	}
+	if m.rows, err = register(reg, m.rows); err != nil {
+		return nil, err
+	}
+	if m.errors, err = register(reg, m.errors); err != nil {
+		return nil, err
+	}
+	if m.cache, err = register(reg, m.cache); err != nil {
+		return nil, err
+	}
+	if m.batchQueue, err = register(reg, m.batchQueue); err != nil {
+		return nil, err
+	}
+	if m.batchFlush, err = register(reg, m.batchFlush); err != nil {
+		return nil, err
+	}
+	if m.batchDrop, err = register(reg, m.batchDrop); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// register 注册 collector，已注册时返回已存在的同名 collector
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+// EntityName 返回实体类型名，用作 entity 标签
+func EntityName[T any]() string {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// reasoner 带分类原因的错误，kratos 的 *errors.Error 满足该接口
+type reasoner interface {
+	GetReason() string
+}
+
+// ErrorClass 返回错误的分类：上下文取消与超时单独分类，其次是通过 RegisterErrorClass 注册的错误值，
+// 带 Reason 的错误使用其 Reason，其余为 unknown
+func (m *Metrics) ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	if m != nil && m.errorClassify != nil {
+		if class := m.errorClassify(err); class != "" {
+			return class
+		}
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	}
+
+	if class := registeredClass(err); class != "" {
+		return class
+	}
+
+	var re reasoner
+	if errors.As(err, &re) && re.GetReason() != "" {
+		return re.GetReason()
+	}
+	return ErrorClassUnknown
+}
+
+// Start 开始一次仓库操作的计时，结束时调用 Observation.End
+func (m *Metrics) Start(backend, entity, method string) *Observation {
+	if m == nil {
+		return nil
+	}
+	return &Observation{
+		metrics: m,
+		backend: backend,
+		entity:  entity,
+		method:  method,
+		start:   time.Now(),
+	}
+}
+
+// CacheHit 记录一次缓存命中。
+// 仓库本身不带缓存层，不会调用该方法；在仓库外自行缓存查询结果的调用方在命中时调用，
+// 与仓库操作指标共用 backend/entity 标签，计入 cache_requests_total{result="hit"}。
+func (m *Metrics) CacheHit(backend, entity string) {
+	if m == nil {
+		return
+	}
+	m.cache.WithLabelValues(backend, entity, "hit").Inc()
+}
+
+// CacheMiss 记录一次缓存未命中，用法同 CacheHit，计入 cache_requests_total{result="miss"}
+func (m *Metrics) CacheMiss(backend, entity string) {
+	if m == nil {
+		return
+	}
+	m.cache.WithLabelValues(backend, entity, "miss").Inc()
+}
+
+// SetBatchQueueDepth 设置批量插入器当前缓冲的行数
+func (m *Metrics) SetBatchQueueDepth(backend, table string, depth int) {
+	if m == nil {
+		return
+	}
+	m.batchQueue.WithLabelValues(backend, table).Set(float64(depth))
+}
+
+// ObserveBatchFlush 记录一次批量提交的耗时与结果
+func (m *Metrics) ObserveBatchFlush(backend, table string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.batchFlush.WithLabelValues(backend, table, status).Observe(d.Seconds())
+}
+
+// AddBatchDropped 记录批量写入器丢弃的行数，reason 如 queue_full、write_failed
+func (m *Metrics) AddBatchDropped(backend, table, reason string, n int) {
+	if m == nil || n <= 0 {
+		return
+	}
+	m.batchDrop.WithLabelValues(backend, table, reason).Add(float64(n))
+}
+
+// Observation 一次仓库操作的指标记录，nil Observation 的方法均为空操作
+type Observation struct {
+	metrics *Metrics
+
+	backend string
+	entity  string
+	method  string
+	start   time.Time
+}
+
+// ReturnedRows 记录返回的行数
+func (o *Observation) ReturnedRows(n int64) {
+	o.addRows("returned", n)
+}
+
+// AffectedRows 记录写入/更新/删除影响的行数
+func (o *Observation) AffectedRows(n int64) {
+	o.addRows("affected", n)
+}
+
+func (o *Observation) addRows(kind string, n int64) {
+	if o == nil || n <= 0 {
+		return
+	}
+	o.metrics.rows.WithLabelValues(o.backend, o.entity, o.method, kind).Add(float64(n))
+}
+
+// End 记录耗时，err 不为空时按分类计入错误数
+func (o *Observation) End(err error) {
+	if o == nil {
+		return
+	}
+	o.metrics.duration.WithLabelValues(o.backend, o.entity, o.method).Observe(time.Since(o.start).Seconds())
+	if err != nil {
+		o.metrics.errors.WithLabelValues(o.backend, o.entity, o.method, o.metrics.ErrorClass(err)).Inc()
+	}
+}
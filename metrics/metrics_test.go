@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/tx7do/go-crud/guard"
+)
+
+type reasonError struct{ reason string }
+
+func (e *reasonError) Error() string     { return e.reason }
+func (e *reasonError) GetReason() string { return e.reason }
+
+type testUser struct{}
+
+func gather(t *testing.T, reg *prometheus.Registry) map[string]*dto.MetricFamily {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	out := make(map[string]*dto.MetricFamily, len(families))
+	for _, f := range families {
+		out[f.GetName()] = f
+	}
+	return out
+}
+
+func labelsOf(m *dto.Metric) map[string]string {
+	out := make(map[string]string, len(m.GetLabel()))
+	for _, l := range m.GetLabel() {
+		out[l.GetName()] = l.GetValue()
+	}
+	return out
+}
+
+func TestMetrics_Observation(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := New(reg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	obs := m.Start(BackendGorm, "User", "ListWithPaging")
+	obs.ReturnedRows(3)
+	obs.End(nil)
+
+	obs = m.Start(BackendGorm, "User", "DeleteByFilter")
+	obs.AffectedRows(2)
+	obs.End(&reasonError{reason: "DELETE_FAILED"})
+
+	families := gather(t, reg)
+
+	duration := families["go_crud_operation_duration_seconds"]
+	if duration == nil {
+		t.Fatal("go_crud_operation_duration_seconds not registered")
+	}
+	if got := len(duration.GetMetric()); got != 2 {
+		t.Errorf("len(duration): expected 2, got %d", got)
+	}
+
+	rows := map[string]float64{}
+	for _, metric := range families["go_crud_rows_total"].GetMetric() {
+		l := labelsOf(metric)
+		rows[l["method"]+"/"+l["kind"]] = metric.GetCounter().GetValue()
+	}
+	if got := rows["ListWithPaging/returned"]; got != 3 {
+		t.Errorf("ListWithPaging/returned: expected 3, got %v", got)
+	}
+	if got := rows["DeleteByFilter/affected"]; got != 2 {
+		t.Errorf("DeleteByFilter/affected: expected 2, got %v", got)
+	}
+
+	errs := families["go_crud_operation_errors_total"].GetMetric()
+	if got := len(errs); got != 1 {
+		t.Fatalf("len(errors): expected 1, got %d", got)
+	}
+	if got := labelsOf(errs[0])["class"]; got != "DELETE_FAILED" {
+		t.Errorf("class: expected %q, got %q", "DELETE_FAILED", got)
+	}
+}
+
+func TestMetrics_SharedRegisterer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m1, err := New(reg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m2, err := New(reg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m1.CacheHit(BackendMongoDB, "User")
+	m2.CacheMiss(BackendMongoDB, "User")
+	m2.SetBatchQueueDepth(BackendClickHouse, "events", 42)
+	m1.ObserveBatchFlush(BackendClickHouse, "events", time.Millisecond, nil)
+	m2.AddBatchDropped(BackendInfluxDB, "cpu", "queue_full", 3)
+
+	families := gather(t, reg)
+	if got := len(families["go_crud_cache_requests_total"].GetMetric()); got != 2 {
+		t.Errorf("len(cache): expected 2, got %d", got)
+	}
+	queue := families["go_crud_batch_queue_depth"].GetMetric()
+	if got := len(queue); got != 1 {
+		t.Fatalf("len(queue): expected 1, got %d", got)
+	}
+	if got := queue[0].GetGauge().GetValue(); got != 42 {
+		t.Errorf("queue depth: expected 42, got %v", got)
+	}
+	if got := len(families["go_crud_batch_flush_duration_seconds"].GetMetric()); got != 1 {
+		t.Errorf("len(flush): expected 1, got %d", got)
+	}
+	dropped := families["go_crud_batch_dropped_rows_total"].GetMetric()
+	if len(dropped) != 1 || dropped[0].GetCounter().GetValue() != 3 {
+		t.Errorf("dropped: expected one series with 3, got %v", dropped)
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *Metrics
+	obs := m.Start(BackendGorm, "User", "Get")
+	obs.ReturnedRows(1)
+	obs.End(errors.New("boom"))
+	m.CacheHit(BackendGorm, "User")
+	m.SetBatchQueueDepth(BackendClickHouse, "events", 1)
+	m.ObserveBatchFlush(BackendClickHouse, "events", time.Second, nil)
+	m.AddBatchDropped(BackendInfluxDB, "cpu", "queue_full", 1)
+}
+
+func TestMetrics_ErrorClass(t *testing.T) {
+	errRegistered := errors.New("query list failed")
+	RegisterErrorClass(ErrorClassStorage, errRegistered)
+
+	m, err := New(prometheus.NewRegistry(), WithErrorClassifier(func(err error) string {
+		if err.Error() == "custom" {
+			return "custom_class"
+		}
+		return ""
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{context.Canceled, ErrorClassCanceled},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{fmt.Errorf("wrapped: %w", &reasonError{reason: "ROW_NOT_FOUND"}), "ROW_NOT_FOUND"},
+		{errors.New("custom"), "custom_class"},
+		{fmt.Errorf("wrapped: %w", guard.ErrTooManyRowsAffected), ErrorClassRejected},
+		{errRegistered, ErrorClassStorage},
+		{errors.New("boom"), ErrorClassUnknown},
+	}
+	for _, c := range cases {
+		if got := m.ErrorClass(c.err); got != c.want {
+			t.Errorf("ErrorClass(%v): expected %q, got %q", c.err, c.want, got)
+		}
+	}
+}
+
+func TestEntityName(t *testing.T) {
+	if got := EntityName[testUser](); got != "testUser" {
+		t.Errorf("EntityName[testUser]: expected %q, got %q", "testUser", got)
+	}
+	if got := EntityName[*testUser](); got != "testUser" {
+		t.Errorf("EntityName[*testUser]: expected %q, got %q", "testUser", got)
+	}
+}
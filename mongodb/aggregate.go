@@ -0,0 +1,62 @@
+package mongodb
+
+import (
+	"context"
+
+	optionsV2 "go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/mongodb/query"
+)
+
+// BuildQuery 按 PagingRequest 构建与 ListWithPaging 相同的查询（过滤、投影、排序与分页），
+// 可在其上继续 Lookup/Unwind/AddStage 后交给 Aggregate 执行
+func (r *Repository[DTO, ENTITY]) BuildQuery(req *paginationV1.PagingRequest) (*query.Builder, error) {
+	if req == nil {
+		req = &paginationV1.PagingRequest{}
+	}
+	return r.buildPagingQuery(req)
+}
+
+// Aggregate 将 qb 的过滤条件（$match）、自定义阶段、排序、分页与投影组合为一条聚合管道在仓库集合上执行，
+// 结果解码为 T；opts 追加在 qb 的聚合选项（allowDiskUse、collation）之后
+func Aggregate[T any, DTO any, ENTITY any](ctx context.Context, r *Repository[DTO, ENTITY], qb *query.Builder, opts ...optionsV2.Lister[optionsV2.AggregateOptions]) (ret []*T, err error) {
+	if r == nil {
+		return nil, errRepositoryIsNil
+	}
+
+	obs := r.observe("Aggregate")
+	defer func() {
+		obs.ReturnedRows(int64(len(ret)))
+		obs.End(err)
+	}()
+
+	if r.client == nil {
+		return nil, errDatabaseIsNil
+	}
+	if r.collection == "" {
+		return nil, errCollectionIsEmpty
+	}
+	if qb == nil {
+		qb = query.NewQueryBuilder()
+	}
+
+	ctx, span := r.startSpan(ctx, "Aggregate")
+	defer func() { span.End(err) }()
+
+	pipeline, aggOpts := qb.BuildAggregate()
+	if len(pipeline) > 0 {
+		observeFilter(obs, pipeline[0])
+	}
+
+	listers := append([]optionsV2.Lister[optionsV2.AggregateOptions]{aggOpts}, opts...)
+
+	var results []*T
+	if err = r.client.Aggregate(ctx, r.collection, pipeline, &results, listers...); err != nil {
+		r.log.Errorf("aggregate failed: %v", err)
+		return nil, err
+	}
+
+	span.SetReturnedRows(int64(len(results)))
+	return results, nil
+}
@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/mapper"
+	"github.com/tx7do/go-utils/trans"
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/mongodb/query"
+)
+
+func TestRepository_BuildQueryForAggregate(t *testing.T) {
+	logger := log.NewHelper(log.DefaultLogger)
+	repo := NewRepository[NoDeleted, NoDeleted](&Client{}, "orders", mapper.NewCopierMapper[NoDeleted, NoDeleted](), logger)
+
+	qb, err := repo.BuildQuery(&paginationV1.PagingRequest{
+		FilterExpr: &paginationV1.FilterExpr{
+			Type: paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{
+				{Field: "name", Op: paginationV1.Operator_EQ, Value: trans.Ptr("alice")},
+			},
+		},
+		Page:     trans.Ptr(uint32(2)),
+		PageSize: trans.Ptr(uint32(10)),
+	})
+	require.NoError(t, err)
+
+	qb.Lookup("users", "user_id", "_id", "user").Unwind("user", false)
+	pipeline, _ := qb.BuildAggregate()
+
+	var stages []string
+	for _, stage := range pipeline {
+		stages = append(stages, stage[0].Key)
+	}
+	assert.Equal(t, []string{query.OperatorMatch, query.OperatorLookup, query.OperatorUnwind, query.OperatorSkip, query.OperatorLimit}, stages)
+
+	match, err := bsonV2.MarshalExtJSON(pipeline[0], false, false)
+	require.NoError(t, err)
+	assert.Contains(t, string(match), `"alice"`)
+}
+
+func TestAggregate_ErrorBranches(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewHelper(log.DefaultLogger)
+	m := mapper.NewCopierMapper[NoDeleted, NoDeleted]()
+
+	_, err := Aggregate[bsonV2.M](ctx, NewRepository[NoDeleted, NoDeleted](nil, "orders", m, logger), nil)
+	assert.EqualError(t, err, "mongodb database is nil")
+
+	_, err = Aggregate[bsonV2.M](ctx, NewRepository[NoDeleted, NoDeleted](&Client{}, "", m, logger), nil)
+	assert.EqualError(t, err, "collection is empty")
+}
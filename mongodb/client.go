@@ -7,8 +7,13 @@ import (
 
 	"github.com/go-kratos/kratos/v2/log"
 
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
 	mongoV2 "go.mongodb.org/mongo-driver/v2/mongo"
 	optionsV2 "go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 )
 
 type Client struct {
@@ -19,6 +24,15 @@ type Client struct {
 
 	database string
 	timeout  time.Duration // 默认超时时间
+
+	enableTrace    bool
+	tracingOptions []tracing.Option
+	tracer         *tracing.Tracer
+
+	metrics *metrics.Metrics
+	slowLog *slowlog.Logger
+
+	txOptions []TxOption // 默认事务选项
 }
 
 func NewClient(opts ...Option) (*Client, error) {
@@ -35,6 +49,11 @@ func NewClient(opts ...Option) (*Client, error) {
 		c.log = log.NewHelper(log.NewStdLogger(os.Stderr))
 	}
 
+	if c.enableTrace {
+		opts := append([]tracing.Option{tracing.WithNamespace(c.database)}, c.tracingOptions...)
+		c.tracer = tracing.NewTracer("mongodb", opts...)
+	}
+
 	if err := c.createMongodbClient(c.options); err != nil {
 		return nil, err
 	}
@@ -89,13 +108,27 @@ func (c *Client) CheckConnect() bool {
 	return true
 }
 
+// startSpan 开始一次 MongoDB 操作的 span，filter 脱敏后记录为 db.query.text；未启用追踪时返回 nil
+func (c *Client) startSpan(ctx context.Context, operation, collection string, filter interface{}) (context.Context, *tracing.Span) {
+	ctx, span := c.tracer.Start(ctx, operation, collection)
+	if span != nil && filter != nil {
+		if doc, err := bsonV2.MarshalExtJSON(filter, false, false); err == nil {
+			span.SetJSONStatement(doc)
+		}
+	}
+	return ctx, span
+}
+
 // FindOne 查询单个文档
-func (c *Client) FindOne(ctx context.Context, collection string, filter interface{}, result interface{}) error {
+func (c *Client) FindOne(ctx context.Context, collection string, filter interface{}, result interface{}) (err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "findOne", collection, filter)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -103,49 +136,117 @@ func (c *Client) FindOne(ctx context.Context, collection string, filter interfac
 }
 
 // Find 查询多个文档
-func (c *Client) Find(ctx context.Context, collection string, filter interface{}, results interface{}) error {
+func (c *Client) Find(ctx context.Context, collection string, filter interface{}, results interface{}, opts ...optionsV2.Lister[optionsV2.FindOptions]) (err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "find", collection, filter)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
-	cursor, err := c.cli.Database(c.database).Collection(collection).Find(ctx, filter)
+	cursor, err := c.cli.Database(c.database).Collection(collection).Find(ctx, filter, opts...)
 	if err != nil {
 		c.log.Errorf("failed to find documents in collection %s: %v", collection, err)
 		return err
 	}
 	defer func(cursor *mongoV2.Cursor, ctx context.Context) {
-		if err = cursor.Close(ctx); err != nil {
-			c.log.Errorf("failed to close cursor: %v", err)
+		if cerr := cursor.Close(ctx); cerr != nil {
+			c.log.Errorf("failed to close cursor: %v", cerr)
+		}
+	}(cursor, ctx)
+
+	if err = cursor.All(ctx, results); err != nil {
+		return err
+	}
+
+	span.SetReturnedRows(tracing.SliceLen(results))
+	return nil
+}
+
+// Aggregate 执行聚合管道，并将结果解码到 results
+func (c *Client) Aggregate(ctx context.Context, collection string, pipeline interface{}, results interface{}, opts ...optionsV2.Lister[optionsV2.AggregateOptions]) (err error) {
+	if c.cli == nil {
+		c.log.Errorf("mongodb client is not initialized")
+		return mongoV2.ErrClientDisconnected
+	}
+
+	ctx, span := c.startSpan(ctx, "aggregate", collection, nil)
+	defer func() { span.End(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	cursor, err := c.cli.Database(c.database).Collection(collection).Aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		c.log.Errorf("failed to aggregate collection %s: %v", collection, err)
+		return err
+	}
+	defer func(cursor *mongoV2.Cursor, ctx context.Context) {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			c.log.Errorf("failed to close cursor: %v", cerr)
 		}
 	}(cursor, ctx)
 
-	return cursor.All(ctx, results)
+	if err = cursor.All(ctx, results); err != nil {
+		return err
+	}
+
+	span.SetReturnedRows(tracing.SliceLen(results))
+	return nil
+}
+
+// RunCommand 在当前数据库上执行命令，并将结果解码到 result
+func (c *Client) RunCommand(ctx context.Context, command interface{}, result interface{}) (err error) {
+	if c.cli == nil {
+		c.log.Errorf("mongodb client is not initialized")
+		return mongoV2.ErrClientDisconnected
+	}
+
+	ctx, span := c.startSpan(ctx, "runCommand", "", command)
+	defer func() { span.End(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.cli.Database(c.database).RunCommand(ctx, command).Decode(result)
 }
 
 // InsertOne 插入单个文档
-func (c *Client) InsertOne(ctx context.Context, collection string, document interface{}) (*mongoV2.InsertOneResult, error) {
+func (c *Client) InsertOne(ctx context.Context, collection string, document interface{}) (_ *mongoV2.InsertOneResult, err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return nil, mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "insertOne", collection, nil)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
-	return c.cli.Database(c.database).Collection(collection).InsertOne(ctx, document)
+	res, err := c.cli.Database(c.database).Collection(collection).InsertOne(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAffectedRows(1)
+	return res, nil
 }
 
 // InsertMany 插入多个文档
-func (c *Client) InsertMany(ctx context.Context, collection string, documents []interface{}) (*mongoV2.InsertManyResult, error) {
+func (c *Client) InsertMany(ctx context.Context, collection string, documents []interface{}) (_ *mongoV2.InsertManyResult, err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return nil, mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "insertMany", collection, nil)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -155,16 +256,20 @@ func (c *Client) InsertMany(ctx context.Context, collection string, documents []
 		return nil, err
 	}
 
+	span.SetAffectedRows(int64(len(res.InsertedIDs)))
 	return res, nil
 }
 
 // UpdateOne 更新单个文档
-func (c *Client) UpdateOne(ctx context.Context, collection string, filter, update interface{}) (*mongoV2.UpdateResult, error) {
+func (c *Client) UpdateOne(ctx context.Context, collection string, filter, update interface{}) (_ *mongoV2.UpdateResult, err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return nil, mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "updateOne", collection, filter)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -174,16 +279,20 @@ func (c *Client) UpdateOne(ctx context.Context, collection string, filter, updat
 		return nil, err
 	}
 
+	span.SetAffectedRows(res.ModifiedCount)
 	return res, nil
 }
 
 // UpdateMany 更新多个文档
-func (c *Client) UpdateMany(ctx context.Context, collection string, filter, update interface{}) (*mongoV2.UpdateResult, error) {
+func (c *Client) UpdateMany(ctx context.Context, collection string, filter, update interface{}) (_ *mongoV2.UpdateResult, err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return nil, mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "updateMany", collection, filter)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -193,22 +302,26 @@ func (c *Client) UpdateMany(ctx context.Context, collection string, filter, upda
 		return nil, err
 	}
 
+	span.SetAffectedRows(res.ModifiedCount)
 	return res, nil
 }
 
 // FindOneAndUpdate 在集合中查找并更新单个文档，结果 Decode 到 result 参数。
 // 可传入可选的 *optionsV2.FindOneAndUpdateOptions。
-func (c *Client) FindOneAndUpdate(ctx context.Context, collection string, filter, update interface{}, result interface{}, opts ...optionsV2.Lister[optionsV2.FindOneAndUpdateOptions]) error {
+func (c *Client) FindOneAndUpdate(ctx context.Context, collection string, filter, update interface{}, result interface{}, opts ...optionsV2.Lister[optionsV2.FindOneAndUpdateOptions]) (err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "findOneAndUpdate", collection, filter)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
 	sr := c.cli.Database(c.database).Collection(collection).FindOneAndUpdate(ctx, filter, update, opts...)
-	if err := sr.Decode(result); err != nil {
+	if err = sr.Decode(result); err != nil {
 		c.log.Errorf("failed to FindOneAndUpdate in collection %s: %v", collection, err)
 		return err
 	}
@@ -217,25 +330,37 @@ func (c *Client) FindOneAndUpdate(ctx context.Context, collection string, filter
 }
 
 // DeleteOne 删除单个文档
-func (c *Client) DeleteOne(ctx context.Context, collection string, filter interface{}) (*mongoV2.DeleteResult, error) {
+func (c *Client) DeleteOne(ctx context.Context, collection string, filter interface{}) (_ *mongoV2.DeleteResult, err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return nil, mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "deleteOne", collection, filter)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
-	return c.cli.Database(c.database).Collection(collection).DeleteOne(ctx, filter)
+	res, err := c.cli.Database(c.database).Collection(collection).DeleteOne(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAffectedRows(res.DeletedCount)
+	return res, nil
 }
 
 // DeleteMany 删除多个文档
-func (c *Client) DeleteMany(ctx context.Context, collection string, filter interface{}) (*mongoV2.DeleteResult, error) {
+func (c *Client) DeleteMany(ctx context.Context, collection string, filter interface{}) (_ *mongoV2.DeleteResult, err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return nil, mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "deleteMany", collection, filter)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -245,16 +370,20 @@ func (c *Client) DeleteMany(ctx context.Context, collection string, filter inter
 		return nil, err
 	}
 
+	span.SetAffectedRows(res.DeletedCount)
 	return res, nil
 }
 
 // Count 统计集合中文档数量，使用 Client 配置的超时和日志方式
-func (c *Client) Count(ctx context.Context, collection string, filter interface{}) (int64, error) {
+func (c *Client) Count(ctx context.Context, collection string, filter interface{}) (_ int64, err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return 0, mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "countDocuments", collection, filter)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -269,12 +398,15 @@ func (c *Client) Count(ctx context.Context, collection string, filter interface{
 
 // Exist 检查集合中是否存在满足 filter 的文档，返回布尔值和可能的错误。
 // 使用 Client 的超时配置，客户端未初始化时返回 mongoV2.ErrClientDisconnected。
-func (c *Client) Exist(ctx context.Context, collection string, filter interface{}) (bool, error) {
+func (c *Client) Exist(ctx context.Context, collection string, filter interface{}) (_ bool, err error) {
 	if c.cli == nil {
 		c.log.Errorf("mongodb client is not initialized")
 		return false, mongoV2.ErrClientDisconnected
 	}
 
+	ctx, span := c.startSpan(ctx, "countDocuments", collection, filter)
+	defer func() { span.End(err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -1 +1,18 @@
 package mongodb
+
+import "errors"
+
+// 仓库自身返回的错误，在 init 中按分类注册到 metrics，作为 class 标签
+var (
+	// 参数缺失或不合法
+	errCollectionIsEmpty          = errors.New("collection is empty")
+	errDTOIsNil                   = errors.New("dto is nil")
+	errDatabaseIsNil              = errors.New("mongodb database is nil")
+	errEmptyFilterForUpdate       = errors.New("empty filter for update")
+	errNoFieldsToUpdate           = errors.New("no fields to update")
+	errPagingRequestIsNil         = errors.New("paging request is nil")
+	errQueryBuilderIsNilForDelete = errors.New("query builder is nil for delete")
+	errQueryBuilderIsNilForUpdate = errors.New("query builder is nil for update")
+	errRepositoryIsNil            = errors.New("repository is nil")
+	errTransactionCallbackIsNil   = errors.New("transaction callback is nil")
+)
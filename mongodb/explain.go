@@ -0,0 +1,107 @@
+package mongodb
+
+import (
+	"context"
+
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/explain"
+)
+
+// ToQuery 返回 ListWithPaging 针对 req 生成的 find 与 count 命令（Extended JSON，不访问数据库）
+func (r *Repository[DTO, ENTITY]) ToQuery(req *paginationV1.PagingRequest) (*explain.Query, error) {
+	findCmd, countCmd, err := r.buildCommands(req)
+	if err != nil {
+		return nil, err
+	}
+
+	findJSON, err := bsonV2.MarshalExtJSON(findCmd, false, false)
+	if err != nil {
+		return nil, err
+	}
+	countJSON, err := bsonV2.MarshalExtJSON(countCmd, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &explain.Query{
+		Statement:      string(findJSON),
+		CountStatement: string(countJSON),
+	}, nil
+}
+
+// Explain 在 ToQuery 的基础上以 queryPlanner 模式执行 explain 命令，返回 MongoDB 的查询计划（不执行 find 本身）
+func (r *Repository[DTO, ENTITY]) Explain(ctx context.Context, req *paginationV1.PagingRequest) (*explain.Query, error) {
+	if r.client == nil {
+		return nil, errDatabaseIsNil
+	}
+
+	q, err := r.ToQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	findCmd, _, err := r.buildCommands(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result bsonV2.M
+	if err = r.client.RunCommand(ctx, bsonV2.D{
+		{Key: "explain", Value: findCmd},
+		{Key: "verbosity", Value: "queryPlanner"},
+	}, &result); err != nil {
+		r.log.Errorf("explain command failed: %v", err)
+		return nil, err
+	}
+
+	plan, err := bsonV2.MarshalExtJSON(result, false, false)
+	if err != nil {
+		return nil, err
+	}
+	q.Plan = string(plan)
+
+	return q, nil
+}
+
+// buildCommands 将 PagingRequest 转为与 ListWithPaging 等价的 find 与 count 命令文档
+func (r *Repository[DTO, ENTITY]) buildCommands(req *paginationV1.PagingRequest) (bsonV2.D, bsonV2.D, error) {
+	if r.collection == "" {
+		return nil, nil, errCollectionIsEmpty
+	}
+	if req == nil {
+		return nil, nil, errPagingRequestIsNil
+	}
+
+	qb, err := r.buildPagingQuery(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filterDoc, opts := qb.Build()
+
+	findCmd := bsonV2.D{
+		{Key: "find", Value: r.collection},
+		{Key: "filter", Value: filterDoc},
+	}
+	if opts.Sort != nil {
+		findCmd = append(findCmd, bsonV2.E{Key: "sort", Value: opts.Sort})
+	}
+	if opts.Projection != nil {
+		findCmd = append(findCmd, bsonV2.E{Key: "projection", Value: opts.Projection})
+	}
+	if opts.Skip != nil {
+		findCmd = append(findCmd, bsonV2.E{Key: "skip", Value: *opts.Skip})
+	}
+	if opts.Limit != nil {
+		findCmd = append(findCmd, bsonV2.E{Key: "limit", Value: *opts.Limit})
+	}
+
+	countCmd := bsonV2.D{
+		{Key: "count", Value: r.collection},
+		{Key: "query", Value: filterDoc},
+	}
+
+	return findCmd, countCmd, nil
+}
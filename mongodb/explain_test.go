@@ -0,0 +1,39 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/mapper"
+	"github.com/tx7do/go-utils/trans"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+func TestRepository_ToQuery(t *testing.T) {
+	logger := log.NewHelper(log.DefaultLogger)
+	repo := NewRepository[NoDeleted, NoDeleted](&Client{}, "test_explain", mapper.NewCopierMapper[NoDeleted, NoDeleted](), logger)
+
+	q, err := repo.ToQuery(&paginationV1.PagingRequest{
+		FilterExpr: &paginationV1.FilterExpr{
+			Type: paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{
+				{Field: "name", Op: paginationV1.Operator_EQ, Value: trans.Ptr("alice")},
+			},
+		},
+		Page:     trans.Ptr(uint32(2)),
+		PageSize: trans.Ptr(uint32(10)),
+	})
+	require.NoError(t, err)
+	assert.Contains(t, q.Statement, `"find":"test_explain"`)
+	assert.Contains(t, q.Statement, `"alice"`)
+	assert.Contains(t, q.Statement, `"skip":10`)
+	assert.Contains(t, q.Statement, `"limit":10`)
+	assert.Contains(t, q.CountStatement, `"count":"test_explain"`)
+	assert.NotContains(t, q.CountStatement, "limit")
+
+	_, err = repo.ToQuery(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package mongodb
+
+import (
+	"context"
+
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/tx7do/go-crud/guard"
+)
+
+// SetGuardOptions 设置批量更新/删除的安全保护。
+// 默认拒绝空过滤文档的更新与删除；MaxAffectedRows 大于 0 时在执行前按相同条件计数，超出上限则拒绝执行。
+func (r *Repository[DTO, ENTITY]) SetGuardOptions(opts guard.Options) {
+	r.guardOptions = opts
+}
+
+// checkGuard 校验过滤文档，并在配置了 MaxAffectedRows 时计数校验上限
+func (r *Repository[DTO, ENTITY]) checkGuard(ctx context.Context, filterDoc interface{}) error {
+	if err := r.guardOptions.CheckFilter(!isEmptyFilter(filterDoc)); err != nil {
+		return err
+	}
+	if r.guardOptions.MaxAffectedRows <= 0 {
+		return nil
+	}
+
+	count, err := r.client.Count(ctx, r.collection, filterDoc)
+	if err != nil {
+		return err
+	}
+	if err = r.guardOptions.CheckAffected(count); err != nil {
+		r.log.Errorf("guard rejected write on %s: %v", r.collection, err)
+		return err
+	}
+	return nil
+}
+
+// isEmptyFilter 判断过滤文档是否为空（匹配全部文档）
+func isEmptyFilter(filterDoc interface{}) bool {
+	switch f := filterDoc.(type) {
+	case nil:
+		return true
+	case bsonV2.M:
+		return len(f) == 0
+	case map[string]interface{}:
+		return len(f) == 0
+	case bsonV2.D:
+		return len(f) == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,38 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/tx7do/go-utils/mapper"
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/tx7do/go-crud/guard"
+	"github.com/tx7do/go-crud/mongodb/query"
+)
+
+func TestIsEmptyFilter(t *testing.T) {
+	assert.True(t, isEmptyFilter(nil))
+	assert.True(t, isEmptyFilter(bsonV2.M{}))
+	assert.True(t, isEmptyFilter(bsonV2.D{}))
+	assert.True(t, isEmptyFilter(map[string]interface{}{}))
+	assert.False(t, isEmptyFilter(bsonV2.M{"id": 1}))
+	assert.False(t, isEmptyFilter(bsonV2.D{{Key: "id", Value: 1}}))
+}
+
+func TestRepository_GuardRejectsFullCollection(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewHelper(log.DefaultLogger)
+	repo := NewRepository[NoDeleted, NoDeleted](&Client{}, "test_guard", mapper.NewCopierMapper[NoDeleted, NoDeleted](), logger)
+
+	_, err := repo.Delete(ctx, query.NewQueryBuilder())
+	assert.ErrorIs(t, err, guard.ErrFullTableOperation)
+
+	_, err = repo.DeleteByFilter(ctx, nil, true)
+	assert.ErrorIs(t, err, guard.ErrFullTableOperation)
+
+	_, err = repo.UpdateByFilter(ctx, nil, &NoDeleted{Name: "x"}, nil)
+	assert.ErrorIs(t, err, guard.ErrFullTableOperation)
+}
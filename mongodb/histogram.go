@@ -0,0 +1,147 @@
+package mongodb
+
+import (
+	"context"
+
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/tx7do/go-crud/histogram"
+	"github.com/tx7do/go-crud/mongodb/query"
+	"github.com/tx7do/go-crud/tracing"
+)
+
+// Histogram 时间直方图：使用 $dateTrunc（需 MongoDB 5.0+）按 hreq.Interval 在 hreq.Timezone 下分桶，周以周一为起点，返回补零且有序的桶
+func (r *Repository[DTO, ENTITY]) Histogram(ctx context.Context, hreq *histogram.Request) (ret *histogram.Result, err error) {
+	obs := r.observe("Histogram")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Buckets)))
+		}
+		obs.End(err)
+	}()
+
+	if r.client == nil {
+		return nil, errDatabaseIsNil
+	}
+	if r.collection == "" {
+		return nil, errCollectionIsEmpty
+	}
+
+	match, pipeline, err := r.buildHistogramPipeline(hreq)
+	if err != nil {
+		return nil, err
+	}
+	observeFilter(obs, match)
+
+	ctx, span := r.startSpan(ctx, "Histogram", tracing.FilterAttributes(hreq.FilterExpr)...)
+	defer func() { span.End(err) }()
+
+	var rows []bsonV2.M
+	if err = r.client.Aggregate(ctx, r.collection, pipeline, &rows); err != nil {
+		r.log.Errorf("histogram aggregate failed: %v", err)
+		return nil, err
+	}
+
+	collector, err := histogram.NewCollector(hreq)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		key, ok := row["_id"].(bsonV2.DateTime)
+		if !ok {
+			// 时间字段缺失或不是日期类型的文档归入 null 桶，不参与直方图
+			continue
+		}
+		values := make([]any, 0, len(hreq.Aggregates))
+		for _, a := range hreq.Aggregates {
+			values = append(values, row[a.Alias()])
+		}
+		if err = collector.Add(key.Time(), row[histogram.CountColumn], values...); err != nil {
+			return nil, err
+		}
+	}
+
+	ret, err = collector.Result()
+	if ret != nil {
+		span.SetReturnedRows(int64(len(ret.Buckets)))
+	}
+	return ret, err
+}
+
+// buildHistogramPipeline 构造 $match、$group（$dateTrunc 分桶）与 $sort 阶段，同时返回 $match 的过滤文档
+func (r *Repository[DTO, ENTITY]) buildHistogramPipeline(hreq *histogram.Request) (bsonV2.M, []bsonV2.D, error) {
+	if err := hreq.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := r.limitPolicy.CheckFilter(r.entityName, hreq.Query, hreq.OrQuery, hreq.FilterExpr); err != nil {
+		return nil, nil, err
+	}
+	iv, _ := hreq.ParsedInterval()
+
+	qb := query.NewQueryBuilder()
+	if hreq.Query != "" || hreq.OrQuery != "" {
+		if _, err := r.queryStringFilter.BuildSelectors(qb, hreq.Query, hreq.OrQuery); err != nil {
+			return nil, nil, err
+		}
+	} else if hreq.FilterExpr != nil {
+		if _, err := r.structuredFilter.BuildSelectors(qb, hreq.FilterExpr); err != nil {
+			return nil, nil, err
+		}
+	}
+	match, _ := qb.Build()
+
+	// 时间范围与已有过滤条件以 $and 组合，避免覆盖同名字段上的条件
+	rangeCond := bsonV2.M{}
+	if !hreq.Start.IsZero() {
+		rangeCond["$gte"] = hreq.Start
+	}
+	if !hreq.End.IsZero() {
+		rangeCond["$lt"] = hreq.End
+	}
+	if len(rangeCond) > 0 {
+		timeCond := bsonV2.M{hreq.Field: rangeCond}
+		if len(match) == 0 {
+			match = timeCond
+		} else {
+			match = bsonV2.M{"$and": bsonV2.A{match, timeCond}}
+		}
+	}
+
+	group := bsonV2.D{
+		{Key: "_id", Value: bsonV2.M{"$dateTrunc": bsonV2.M{
+			"date":        "$" + hreq.Field,
+			"unit":        string(iv.Unit),
+			"binSize":     iv.N,
+			"timezone":    hreq.TimezoneName(),
+			"startOfWeek": "monday",
+		}}},
+		{Key: histogram.CountColumn, Value: bsonV2.M{"$sum": 1}},
+	}
+	for _, a := range hreq.Aggregates {
+		group = append(group, bsonV2.E{Key: a.Alias(), Value: histogramAccumulator(a)})
+	}
+
+	var pipeline []bsonV2.D
+	if len(match) > 0 {
+		pipeline = append(pipeline, bsonV2.D{{Key: "$match", Value: match}})
+	}
+	pipeline = append(pipeline,
+		bsonV2.D{{Key: "$group", Value: group}},
+		bsonV2.D{{Key: "$sort", Value: bsonV2.D{{Key: "_id", Value: 1}}}},
+	)
+	return match, pipeline, nil
+}
+
+// histogramAccumulator 返回聚合对应的 $group 累加器
+func histogramAccumulator(a histogram.Aggregate) bsonV2.M {
+	if a.Func == histogram.FuncCount {
+		if a.Field == "" {
+			return bsonV2.M{"$sum": 1}
+		}
+		// 只统计字段存在且不为 null 的文档
+		return bsonV2.M{"$sum": bsonV2.M{"$cond": bsonV2.A{
+			bsonV2.M{"$eq": bsonV2.A{bsonV2.M{"$ifNull": bsonV2.A{"$" + a.Field, nil}}, nil}}, 0, 1,
+		}}}
+	}
+	return bsonV2.M{"$" + string(a.Func): "$" + a.Field}
+}
@@ -0,0 +1,49 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/mapper"
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/tx7do/go-crud/histogram"
+)
+
+func TestRepository_BuildHistogramPipeline(t *testing.T) {
+	logger := log.NewHelper(log.DefaultLogger)
+	repo := NewRepository[NoDeleted, NoDeleted](&Client{}, "orders", mapper.NewCopierMapper[NoDeleted, NoDeleted](), logger)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	match, pipeline, err := repo.buildHistogramPipeline(&histogram.Request{
+		Field:      "created_at",
+		Interval:   "15m",
+		Timezone:   "Asia/Shanghai",
+		Start:      start,
+		Query:      `{"status":"paid"}`,
+		Aggregates: []histogram.Aggregate{{Func: histogram.FuncSum, Field: "amount"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, pipeline, 3)
+
+	assert.Equal(t, bsonV2.M{"$and": bsonV2.A{
+		bsonV2.M{"status": "paid"},
+		bsonV2.M{"created_at": bsonV2.M{"$gte": start}},
+	}}, match)
+
+	group := pipeline[1][0].Value.(bsonV2.D)
+	assert.Equal(t, bsonV2.M{"$dateTrunc": bsonV2.M{
+		"date":        "$created_at",
+		"unit":        "minute",
+		"binSize":     15,
+		"timezone":    "Asia/Shanghai",
+		"startOfWeek": "monday",
+	}}, group[0].Value)
+	assert.Equal(t, bsonV2.E{Key: "sum_amount", Value: bsonV2.M{"$sum": "$amount"}}, group[2])
+
+	_, _, err = repo.buildHistogramPipeline(&histogram.Request{Field: "created_at", Interval: "1h", Timezone: "Mars/Base"})
+	assert.ErrorIs(t, err, histogram.ErrInvalidTimezone)
+}
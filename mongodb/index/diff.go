@@ -0,0 +1,252 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// IDIndexName _id 上的默认索引，不参与比较与删除
+const IDIndexName = "_id_"
+
+// Existing listIndexes 返回的现有索引
+type Existing struct {
+	Name string   `bson:"name"`
+	Keys bsonV2.D `bson:"key"`
+
+	Unique             bool     `bson:"unique,omitempty"`
+	Sparse             bool     `bson:"sparse,omitempty"`
+	ExpireAfterSeconds *int64   `bson:"expireAfterSeconds,omitempty"`
+	PartialFilter      bsonV2.D `bson:"partialFilterExpression,omitempty"`
+	Weights            bsonV2.D `bson:"weights,omitempty"`
+}
+
+// Drift 名称相同（或索引键相同）但定义不一致的索引
+type Drift struct {
+	Name     string
+	Existing string
+	Reasons  []string
+}
+
+func (d Drift) String() string {
+	if d.Existing != d.Name {
+		return fmt.Sprintf("%s (exists as %s): %s", d.Name, d.Existing, strings.Join(d.Reasons, "; "))
+	}
+	return d.Name + ": " + strings.Join(d.Reasons, "; ")
+}
+
+// Report 索引定义与现有索引的差异，以及 EnsureIndexes 的执行结果。
+// 不一致的索引只用于报告，重建索引可能长时间占用资源，需要人工处理
+type Report struct {
+	Collection string
+
+	Missing   []Index
+	Drift     []Drift
+	Unmanaged []string
+
+	Created []string
+	Dropped []string
+}
+
+// InSync 索引定义与现有索引完全一致
+func (r *Report) InSync() bool {
+	return len(r.Missing) == 0 && len(r.Drift) == 0 && len(r.Unmanaged) == 0
+}
+
+// Diff 对比索引定义与现有索引。索引按名称匹配；名称不同但索引键相同的现有索引视为同一索引，
+// 因为 MongoDB 不允许以不同名称重复创建相同的索引键
+func Diff(defined []Index, existing []Existing) *Report {
+	r := &Report{}
+
+	byName := make(map[string]Existing, len(existing))
+	for _, e := range existing {
+		byName[e.Name] = e
+	}
+
+	managed := map[string]bool{IDIndexName: true}
+	for _, idx := range defined {
+		name := idx.IndexName()
+
+		e, ok := byName[name]
+		if !ok {
+			for _, candidate := range existing {
+				if !managed[candidate.Name] && sameKeys(idx, candidate) {
+					e, ok = candidate, true
+					break
+				}
+			}
+		}
+		if !ok {
+			r.Missing = append(r.Missing, idx)
+			continue
+		}
+
+		managed[e.Name] = true
+		reasons := compare(idx, e)
+		if e.Name != name {
+			reasons = append([]string{"name differs"}, reasons...)
+		}
+		if len(reasons) > 0 {
+			r.Drift = append(r.Drift, Drift{Name: name, Existing: e.Name, Reasons: reasons})
+		}
+	}
+
+	for _, e := range existing {
+		if !managed[e.Name] {
+			r.Unmanaged = append(r.Unmanaged, e.Name)
+		}
+	}
+	return r
+}
+
+// compare 返回索引定义与现有索引不一致之处
+func compare(idx Index, e Existing) []string {
+	var reasons []string
+	if !sameKeySpec(idx, e) {
+		reasons = append(reasons, fmt.Sprintf("keys %s, want %s", docString(e.Keys), docString(idx.Keys)))
+	}
+	if idx.Unique != e.Unique {
+		reasons = append(reasons, fmt.Sprintf("unique %t, want %t", e.Unique, idx.Unique))
+	}
+	if idx.Sparse != e.Sparse {
+		reasons = append(reasons, fmt.Sprintf("sparse %t, want %t", e.Sparse, idx.Sparse))
+	}
+
+	var want, got int64 = -1, -1
+	if idx.ExpireAfterSeconds != nil {
+		want = int64(*idx.ExpireAfterSeconds)
+	}
+	if e.ExpireAfterSeconds != nil {
+		got = *e.ExpireAfterSeconds
+	}
+	if want != got {
+		reasons = append(reasons, fmt.Sprintf("expireAfterSeconds %d, want %d", got, want))
+	}
+
+	if docString(idx.PartialFilter) != docString(e.PartialFilter) {
+		reasons = append(reasons, fmt.Sprintf("partialFilterExpression %s, want %s", docString(e.PartialFilter), docString(idx.PartialFilter)))
+	}
+	if idx.IsText() && !sameWeights(idx, e) {
+		reasons = append(reasons, fmt.Sprintf("weights %s, want %s", docString(e.Weights), docString(textWeights(idx))))
+	}
+	return reasons
+}
+
+// sameKeys 比较索引键，文本索引还需比较文本字段的权重
+func sameKeys(idx Index, e Existing) bool {
+	return sameKeySpec(idx, e) && (!idx.IsText() || sameWeights(idx, e))
+}
+
+// sameKeySpec 比较 key 文档；文本索引在服务端存储为 _fts/_ftsx，文本字段体现在 weights 中
+func sameKeySpec(idx Index, e Existing) bool {
+	want := idx.Keys
+	if idx.IsText() {
+		want = nil
+		fts := false
+		for _, k := range idx.Keys {
+			if k.Value != TypeText {
+				want = append(want, k)
+			} else if !fts {
+				want = append(want, bsonV2.E{Key: "_fts", Value: TypeText}, bsonV2.E{Key: "_ftsx", Value: int32(1)})
+				fts = true
+			}
+		}
+	}
+
+	if len(want) != len(e.Keys) {
+		return false
+	}
+	for i := range want {
+		if want[i].Key != e.Keys[i].Key || normalizeValue(want[i].Value) != normalizeValue(e.Keys[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// textWeights 返回文本索引各字段的权重，未指定的字段为 1
+func textWeights(idx Index) bsonV2.D {
+	weights := map[string]any{}
+	for _, w := range idx.Weights {
+		weights[w.Key] = w.Value
+	}
+	var d bsonV2.D
+	for _, k := range idx.Keys {
+		if k.Value != TypeText {
+			continue
+		}
+		w, ok := weights[k.Key]
+		if !ok {
+			w = int32(1)
+		}
+		d = append(d, bsonV2.E{Key: k.Key, Value: w})
+	}
+	return d
+}
+
+// sameWeights 忽略顺序比较文本索引的权重
+func sameWeights(idx Index, e Existing) bool {
+	return weightsString(textWeights(idx)) == weightsString(e.Weights)
+}
+
+func weightsString(d bsonV2.D) string {
+	parts := make([]string, 0, len(d))
+	for _, w := range d {
+		parts = append(parts, w.Key+"="+normalizeValue(w.Value))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// normalizeValue 将数值统一格式化，避免 int32、int64 与 double 的差异
+func normalizeValue(v any) string {
+	switch n := v.(type) {
+	case int32:
+		return fmt.Sprint(float64(n))
+	case int64:
+		return fmt.Sprint(float64(n))
+	case int:
+		return fmt.Sprint(float64(n))
+	case float64:
+		return fmt.Sprint(n)
+	}
+	return fmt.Sprint(v)
+}
+
+// docString 以 relaxed Extended JSON 输出文档，空文档为 {}
+func docString(d bsonV2.D) string {
+	if len(d) == 0 {
+		return "{}"
+	}
+	b, err := bsonV2.MarshalExtJSON(d, false, false)
+	if err != nil {
+		return fmt.Sprint(d)
+	}
+	return string(b)
+}
+
+// EnsureOptions EnsureIndexes 的选项
+type EnsureOptions struct {
+	// DropUnmanaged 删除未在定义中声明的索引（_id_ 除外）
+	DropUnmanaged bool
+	// DryRun 只对比并返回报告，不创建或删除索引
+	DryRun bool
+}
+
+type EnsureOption func(o *EnsureOptions)
+
+// DropUnmanaged 删除未在定义中声明的索引
+func DropUnmanaged() EnsureOption {
+	return func(o *EnsureOptions) {
+		o.DropUnmanaged = true
+	}
+}
+
+// DryRun 只报告差异，不修改索引
+func DryRun() EnsureOption {
+	return func(o *EnsureOptions) {
+		o.DryRun = true
+	}
+}
@@ -0,0 +1,407 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+	mongoV2 "go.mongodb.org/mongo-driver/v2/mongo"
+	optionsV2 "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// TagName 索引声明使用的结构体标签，多个声明以 | 分隔，每个声明为分号分隔的 key[:value]，例如
+//
+//	Email     string    `bson:"email" index:"unique"`
+//	TenantID  string    `bson:"tenant_id" index:"name:tenant_created;order:1"`
+//	CreatedAt time.Time `bson:"created_at" index:"name:tenant_created;order:2;desc | ttl:720h"`
+//	Title     string    `bson:"title" index:"text;name:search;weight:10"`
+//	Body      string    `bson:"body" index:"text;name:search"`
+//	Location  GeoPoint  `bson:"location" index:"2dsphere"`
+//	Status    string    `bson:"status" index:"partial:{\"status\": \"active\"}"`
+//
+// 支持的键：
+//   - "-"：忽略该字段
+//   - name：索引名，同名的声明组成复合索引；缺省按 MongoDB 规则生成，如 tenant_id_1_created_at_-1
+//   - order：字段在复合索引中的位置，缺省按字段顺序排在有位置的字段之后
+//   - desc：降序
+//   - text、2dsphere、hashed：索引类型，weight 为文本索引的权重
+//   - unique、sparse：唯一索引与稀疏索引
+//   - ttl：TTL 索引的过期时间，Go duration 或秒数，只能用于单字段索引
+//   - partial：Extended JSON 格式的 partialFilterExpression
+//
+// 字段名与驱动的编码规则一致：bson 标签，缺省为小写的字段名；
+// 带 inline 的内嵌结构体展开到当前文档，未带 inline 的匿名结构体字段为子文档，索引键带上子文档名前缀
+const TagName = "index"
+
+const (
+	TypeText     = "text"
+	Type2DSphere = "2dsphere"
+	TypeHashed   = "hashed"
+)
+
+var (
+	ErrNotStruct    = errors.New("index: entity must be a struct")
+	ErrInvalidTag   = errors.New("index: invalid tag")
+	ErrInvalidIndex = errors.New("index: invalid index")
+)
+
+// Index 索引定义
+type Index struct {
+	Name string
+	// Keys 有序的索引键，值为 1、-1 或 text、2dsphere、hashed
+	Keys bsonV2.D
+
+	Unique bool
+	Sparse bool
+
+	// ExpireAfterSeconds TTL 索引的过期秒数
+	ExpireAfterSeconds *int32
+	// PartialFilter 部分索引的过滤条件
+	PartialFilter bsonV2.D
+	// Weights 文本索引字段的权重，未列出的文本字段权重为 1
+	Weights bsonV2.D
+}
+
+// Keys 以字段列表创建索引定义，"-field" 为降序，"field:text" 等指定索引类型
+func Keys(fields ...string) Index {
+	var idx Index
+	for _, f := range fields {
+		if name, typ, ok := strings.Cut(f, ":"); ok {
+			idx.Keys = append(idx.Keys, bsonV2.E{Key: name, Value: typ})
+		} else if strings.HasPrefix(f, "-") {
+			idx.Keys = append(idx.Keys, bsonV2.E{Key: f[1:], Value: int32(-1)})
+		} else {
+			idx.Keys = append(idx.Keys, bsonV2.E{Key: f, Value: int32(1)})
+		}
+	}
+	return idx
+}
+
+// Named 指定索引名
+func (i Index) Named(name string) Index {
+	i.Name = name
+	return i
+}
+
+// AsUnique 设为唯一索引
+func (i Index) AsUnique() Index {
+	i.Unique = true
+	return i
+}
+
+// AsSparse 设为稀疏索引
+func (i Index) AsSparse() Index {
+	i.Sparse = true
+	return i
+}
+
+// TTL 设为 TTL 索引，过期时间向上取整到秒
+func (i Index) TTL(d time.Duration) Index {
+	secs := int32((d + time.Second - 1) / time.Second)
+	i.ExpireAfterSeconds = &secs
+	return i
+}
+
+// Partial 设为部分索引
+func (i Index) Partial(filter bsonV2.D) Index {
+	i.PartialFilter = filter
+	return i
+}
+
+// Weight 设置文本索引字段的权重
+func (i Index) Weight(field string, weight int32) Index {
+	i.Weights = append(i.Weights, bsonV2.E{Key: field, Value: weight})
+	return i
+}
+
+// IndexName 返回索引名，未指定时按 MongoDB 的默认规则由索引键生成
+func (i Index) IndexName() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	parts := make([]string, 0, len(i.Keys)*2)
+	for _, k := range i.Keys {
+		parts = append(parts, k.Key, fmt.Sprint(k.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// IsText 是否为文本索引
+func (i Index) IsText() bool {
+	for _, k := range i.Keys {
+		if k.Value == TypeText {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 检查索引定义
+func (i Index) Validate() error {
+	if len(i.Keys) == 0 {
+		return fmt.Errorf("%w: %s has no keys", ErrInvalidIndex, i.IndexName())
+	}
+	if i.ExpireAfterSeconds != nil && len(i.Keys) > 1 {
+		return fmt.Errorf("%w: ttl index %s must have a single key", ErrInvalidIndex, i.IndexName())
+	}
+	if len(i.Weights) > 0 && !i.IsText() {
+		return fmt.Errorf("%w: weights require a text index on %s", ErrInvalidIndex, i.IndexName())
+	}
+	return nil
+}
+
+// Model 转换为驱动的 IndexModel
+func (i Index) Model() mongoV2.IndexModel {
+	opts := optionsV2.Index().SetName(i.IndexName())
+	if i.Unique {
+		opts.SetUnique(true)
+	}
+	if i.Sparse {
+		opts.SetSparse(true)
+	}
+	if i.ExpireAfterSeconds != nil {
+		opts.SetExpireAfterSeconds(*i.ExpireAfterSeconds)
+	}
+	if len(i.PartialFilter) > 0 {
+		opts.SetPartialFilterExpression(i.PartialFilter)
+	}
+	if len(i.Weights) > 0 {
+		opts.SetWeights(i.Weights)
+	}
+	return mongoV2.IndexModel{Keys: i.Keys, Options: opts}
+}
+
+// FromStruct 依据 ENTITY 的结构体标签生成索引定义，extra 为额外注册的索引，与标签中同名的索引会被覆盖
+func FromStruct[ENTITY any](extra ...Index) ([]Index, error) {
+	return FromType(reflect.TypeOf((*ENTITY)(nil)).Elem(), extra...)
+}
+
+// declaration 单个字段上的一条索引声明
+type declaration struct {
+	field string
+	value any
+	order int
+	seq   int
+
+	name          string
+	unique        bool
+	sparse        bool
+	ttl           *int32
+	partialFilter bsonV2.D
+	weight        *int32
+}
+
+// FromType 与 FromStruct 相同，接收反射类型
+func FromType(t reflect.Type, extra ...Index) ([]Index, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+
+	var decls []declaration
+	if err := collect(t, "", &decls); err != nil {
+		return nil, err
+	}
+
+	// 按索引名分组，保持首次出现的顺序
+	var names []string
+	groups := map[string][]declaration{}
+	for _, d := range decls {
+		key := d.name
+		if key == "" {
+			key = "\x00" + strconv.Itoa(d.seq)
+		}
+		if _, ok := groups[key]; !ok {
+			names = append(names, key)
+		}
+		groups[key] = append(groups[key], d)
+	}
+
+	var indexes []Index
+	registered := make(map[string]bool, len(extra))
+	for _, idx := range extra {
+		registered[idx.IndexName()] = true
+	}
+	for _, key := range names {
+		idx := build(groups[key])
+		if registered[idx.IndexName()] {
+			continue
+		}
+		if err := idx.Validate(); err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, idx)
+	}
+	for _, idx := range extra {
+		if err := idx.Validate(); err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes, nil
+}
+
+// collect 收集结构体字段上的索引声明，prefix 为子文档的路径前缀。
+// 与驱动的编码规则一致：未导出字段被忽略，带 inline 的结构体字段展开到当前文档，
+// 其余匿名结构体字段编码为子文档，其中的索引键为 "子文档名.字段名"
+func collect(t reflect.Type, prefix string, decls *[]declaration) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		bsonName, inline := bsonFieldName(f)
+		if bsonName == "-" {
+			continue
+		}
+		if f.Anonymous || inline {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				sub := prefix
+				if !inline {
+					sub = prefix + bsonName + "."
+				}
+				if err := collect(ft, sub, decls); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		tag, ok := f.Tag.Lookup(TagName)
+		if !ok || strings.TrimSpace(tag) == "-" {
+			continue
+		}
+		for _, part := range strings.Split(tag, "|") {
+			d, err := parseDeclaration(prefix+bsonName, part)
+			if err != nil {
+				return fmt.Errorf("%w: field %s: %v", ErrInvalidTag, f.Name, err)
+			}
+			d.seq = len(*decls)
+			*decls = append(*decls, d)
+		}
+	}
+	return nil
+}
+
+// bsonFieldName 返回字段的 bson 名称以及是否为 inline 字段
+func bsonFieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("bson")
+	name, opts, _ := strings.Cut(tag, ",")
+	inline := false
+	for _, o := range strings.Split(opts, ",") {
+		if o == "inline" {
+			inline = true
+		}
+	}
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	return name, inline
+}
+
+// parseDeclaration 解析一条索引声明
+func parseDeclaration(field, decl string) (declaration, error) {
+	d := declaration{field: field, value: int32(1), order: -1}
+	for _, item := range strings.Split(decl, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, val, _ := strings.Cut(item, ":")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+
+		switch key {
+		case "name":
+			d.name = val
+		case "order":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return d, fmt.Errorf("invalid order %q", val)
+			}
+			d.order = n
+		case "desc":
+			d.value = int32(-1)
+		case TypeText, Type2DSphere, TypeHashed:
+			d.value = key
+		case "unique":
+			d.unique = true
+		case "sparse":
+			d.sparse = true
+		case "ttl":
+			secs, err := parseSeconds(val)
+			if err != nil {
+				return d, fmt.Errorf("invalid ttl %q", val)
+			}
+			d.ttl = &secs
+		case "weight":
+			n, err := strconv.ParseInt(val, 10, 32)
+			if err != nil {
+				return d, fmt.Errorf("invalid weight %q", val)
+			}
+			w := int32(n)
+			d.weight = &w
+		case "partial":
+			var filter bsonV2.D
+			if err := bsonV2.UnmarshalExtJSON([]byte(val), false, &filter); err != nil {
+				return d, fmt.Errorf("invalid partial filter %q: %v", val, err)
+			}
+			d.partialFilter = filter
+		default:
+			return d, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	return d, nil
+}
+
+// parseSeconds 解析秒数或 Go duration，向上取整到秒
+func parseSeconds(s string) (int32, error) {
+	if n, err := strconv.ParseInt(s, 10, 32); err == nil {
+		return int32(n), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return int32((d + time.Second - 1) / time.Second), nil
+}
+
+// build 将同一索引的声明合并为索引定义
+func build(decls []declaration) Index {
+	sort.SliceStable(decls, func(a, b int) bool {
+		oa, ob := decls[a].order, decls[b].order
+		if oa < 0 || ob < 0 {
+			return oa >= 0 && ob < 0
+		}
+		return oa < ob
+	})
+
+	var idx Index
+	for _, d := range decls {
+		idx.Name = d.name
+		idx.Keys = append(idx.Keys, bsonV2.E{Key: d.field, Value: d.value})
+		idx.Unique = idx.Unique || d.unique
+		idx.Sparse = idx.Sparse || d.sparse
+		if d.ttl != nil {
+			idx.ExpireAfterSeconds = d.ttl
+		}
+		if d.partialFilter != nil {
+			idx.PartialFilter = d.partialFilter
+		}
+		if d.weight != nil {
+			idx.Weights = append(idx.Weights, bsonV2.E{Key: d.field, Value: *d.weight})
+		}
+	}
+	return idx
+}
@@ -0,0 +1,134 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type Base struct {
+	TenantID  string    `bson:"tenant_id" index:"name:tenant_created;order:1"`
+	CreatedAt time.Time `bson:"created_at" index:"name:tenant_created;order:2;desc | ttl:720h"`
+}
+
+// Audit 未带 inline，编码为子文档 audit
+type Audit struct {
+	UpdatedBy string `bson:"updated_by" index:""`
+}
+
+type article struct {
+	Base `bson:",inline"`
+	Audit
+
+	ID       bsonV2.ObjectID `bson:"_id,omitempty"`
+	Email    string          `bson:"email" index:"unique;sparse"`
+	Title    string          `bson:"title" index:"text;name:search;weight:10"`
+	Body     string          `bson:"body" index:"text;name:search"`
+	Location bsonV2.D        `bson:"location" index:"2dsphere"`
+	Status   string          `index:"partial:{\"status\": \"active\"}"`
+	Ignored  string          `bson:"-" index:""`
+	Skipped  string          `bson:"skipped" index:"-"`
+}
+
+func TestFromStruct(t *testing.T) {
+	indexes, err := FromStruct[article]()
+	require.NoError(t, err)
+
+	byName := map[string]Index{}
+	var names []string
+	for _, idx := range indexes {
+		byName[idx.IndexName()] = idx
+		names = append(names, idx.IndexName())
+	}
+	assert.Equal(t, []string{"tenant_created", "created_at_1", "audit.updated_by_1", "email_1", "search", "location_2dsphere", "status_1"}, names)
+
+	// inline 的内嵌结构体展开到顶层，未带 inline 的匿名结构体为子文档
+	assert.Equal(t, bsonV2.D{{Key: "tenant_id", Value: int32(1)}, {Key: "created_at", Value: int32(-1)}}, byName["tenant_created"].Keys)
+	assert.Equal(t, bsonV2.D{{Key: "audit.updated_by", Value: int32(1)}}, byName["audit.updated_by_1"].Keys)
+	assert.Equal(t, int32(720*3600), *byName["created_at_1"].ExpireAfterSeconds)
+	assert.True(t, byName["email_1"].Unique)
+	assert.True(t, byName["email_1"].Sparse)
+	assert.Equal(t, bsonV2.D{{Key: "title", Value: TypeText}, {Key: "body", Value: TypeText}}, byName["search"].Keys)
+	assert.Equal(t, bsonV2.D{{Key: "title", Value: int32(10)}}, byName["search"].Weights)
+	assert.Equal(t, bsonV2.D{{Key: "status", Value: "active"}}, byName["status_1"].PartialFilter)
+
+	// 复合索引不能设置 TTL
+	_, err = FromStruct[article](Keys("tenant_id", "-score").TTL(90 * time.Second).Named("bad"))
+	assert.ErrorIs(t, err, ErrInvalidIndex)
+
+	// 注册的索引覆盖标签中的同名索引
+	indexes, err = FromStruct[article](Keys("email").Named("email_1").AsUnique())
+	require.NoError(t, err)
+	require.Len(t, indexes, 7)
+	assert.Equal(t, Keys("email").Named("email_1").AsUnique(), indexes[6])
+}
+
+func TestFromStruct_Errors(t *testing.T) {
+	_, err := FromStruct[int]()
+	assert.ErrorIs(t, err, ErrNotStruct)
+
+	type badOrder struct {
+		A string `index:"order:x"`
+	}
+	_, err = FromStruct[badOrder]()
+	assert.ErrorIs(t, err, ErrInvalidTag)
+
+	type badKey struct {
+		A string `index:"clustered"`
+	}
+	_, err = FromStruct[badKey]()
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestDiff(t *testing.T) {
+	defined := []Index{
+		Keys("tenant_id", "-created_at").Named("tenant_created"),
+		Keys("email").AsUnique(),
+		Keys("title:text", "body:text").Named("search").Weight("title", 10),
+		Keys("expire_at").TTL(time.Hour),
+		Keys("sku"),
+	}
+	ttl := int64(60)
+	existing := []Existing{
+		{Name: "_id_", Keys: bsonV2.D{{Key: "_id", Value: int32(1)}}},
+		{Name: "tenant_created", Keys: bsonV2.D{{Key: "tenant_id", Value: 1.0}, {Key: "created_at", Value: int64(-1)}}},
+		{Name: "email_1", Keys: bsonV2.D{{Key: "email", Value: int32(1)}}},
+		{Name: "search", Keys: bsonV2.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}},
+			Weights: bsonV2.D{{Key: "body", Value: int32(1)}, {Key: "title", Value: int32(10)}}},
+		{Name: "expire", Keys: bsonV2.D{{Key: "expire_at", Value: int32(1)}}, ExpireAfterSeconds: &ttl},
+		{Name: "legacy_1", Keys: bsonV2.D{{Key: "legacy", Value: int32(1)}}},
+	}
+
+	r := Diff(defined, existing)
+	require.Len(t, r.Missing, 1)
+	assert.Equal(t, "sku_1", r.Missing[0].IndexName())
+	assert.Equal(t, []string{"legacy_1"}, r.Unmanaged)
+
+	require.Len(t, r.Drift, 2)
+	assert.Equal(t, Drift{Name: "email_1", Existing: "email_1", Reasons: []string{"unique false, want true"}}, r.Drift[0])
+	assert.Equal(t, Drift{Name: "expire_at_1", Existing: "expire", Reasons: []string{"name differs", "expireAfterSeconds 60, want 3600"}}, r.Drift[1])
+	assert.Equal(t, "expire_at_1 (exists as expire): name differs; expireAfterSeconds 60, want 3600", r.Drift[1].String())
+	assert.False(t, r.InSync())
+
+	// 文本索引权重变化
+	existing[3].Weights = bsonV2.D{{Key: "body", Value: int32(1)}, {Key: "title", Value: int32(1)}}
+	r = Diff(defined[2:3], existing[3:4])
+	require.Len(t, r.Drift, 1)
+	assert.Equal(t, []string{`weights {"body":1,"title":1}, want {"title":10,"body":1}`}, r.Drift[0].Reasons)
+}
+
+func TestIndex_Model(t *testing.T) {
+	idx := Keys("status", "-created_at").Named("active_recent").Partial(bsonV2.D{{Key: "status", Value: "active"}})
+	assert.Equal(t, "active_recent", idx.IndexName())
+	assert.Equal(t, "status_1_created_at_-1", Keys("status", "-created_at").IndexName())
+
+	m := idx.Model()
+	assert.Equal(t, idx.Keys, m.Keys)
+	require.NotNil(t, m.Options)
+
+	assert.ErrorIs(t, Index{}.Validate(), ErrInvalidIndex)
+	assert.ErrorIs(t, Keys("a").Weight("a", 2).Validate(), ErrInvalidIndex)
+}
@@ -0,0 +1,167 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	mongoV2 "go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/tx7do/go-crud/mongodb/index"
+)
+
+// ListIndexes 查询集合的现有索引，集合不存在时返回空
+func (c *Client) ListIndexes(ctx context.Context, collection string) (_ []index.Existing, err error) {
+	if c.cli == nil {
+		c.log.Errorf("mongodb client is not initialized")
+		return nil, mongoV2.ErrClientDisconnected
+	}
+
+	ctx, span := c.startSpan(ctx, "listIndexes", collection, nil)
+	defer func() { span.End(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	cursor, err := c.cli.Database(c.database).Collection(collection).Indexes().List(ctx)
+	if err != nil {
+		// NamespaceNotFound
+		var ce mongoV2.CommandError
+		if errors.As(err, &ce) && ce.Code == 26 {
+			return nil, nil
+		}
+		c.log.Errorf("failed to list indexes of collection %s: %v", collection, err)
+		return nil, err
+	}
+	defer func(cursor *mongoV2.Cursor, ctx context.Context) {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			c.log.Errorf("failed to close cursor: %v", cerr)
+		}
+	}(cursor, ctx)
+
+	var existing []index.Existing
+	if err = cursor.All(ctx, &existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// CreateIndexes 在集合上创建索引，返回创建的索引名
+func (c *Client) CreateIndexes(ctx context.Context, collection string, indexes ...index.Index) (_ []string, err error) {
+	if c.cli == nil {
+		c.log.Errorf("mongodb client is not initialized")
+		return nil, mongoV2.ErrClientDisconnected
+	}
+	if len(indexes) == 0 {
+		return nil, nil
+	}
+
+	models := make([]mongoV2.IndexModel, 0, len(indexes))
+	for _, idx := range indexes {
+		if err = idx.Validate(); err != nil {
+			return nil, err
+		}
+		models = append(models, idx.Model())
+	}
+
+	ctx, span := c.startSpan(ctx, "createIndexes", collection, nil)
+	defer func() { span.End(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	names, err := c.cli.Database(c.database).Collection(collection).Indexes().CreateMany(ctx, models)
+	if err != nil {
+		c.log.Errorf("failed to create indexes on collection %s: %v", collection, err)
+		return nil, err
+	}
+	return names, nil
+}
+
+// DropIndex 删除集合上指定名称的索引
+func (c *Client) DropIndex(ctx context.Context, collection, name string) (err error) {
+	if c.cli == nil {
+		c.log.Errorf("mongodb client is not initialized")
+		return mongoV2.ErrClientDisconnected
+	}
+
+	ctx, span := c.startSpan(ctx, "dropIndexes", collection, nil)
+	defer func() { span.End(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	if err = c.cli.Database(c.database).Collection(collection).Indexes().DropOne(ctx, name); err != nil {
+		c.log.Errorf("failed to drop index %s on collection %s: %v", name, collection, err)
+		return err
+	}
+	return nil
+}
+
+// EnsureIndexes 对比索引定义与集合的现有索引，创建缺失的索引。
+// 定义不一致的索引只记录警告并在报告中返回；未声明的索引默认保留，指定 index.DropUnmanaged() 时删除
+func (c *Client) EnsureIndexes(ctx context.Context, collection string, indexes []index.Index, opts ...index.EnsureOption) (*index.Report, error) {
+	var o index.EnsureOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	existing, err := c.ListIndexes(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	report := index.Diff(indexes, existing)
+	report.Collection = collection
+
+	for _, d := range report.Drift {
+		c.log.Warnf("index drift on collection %s, skipped: %s", collection, d.String())
+	}
+	if o.DryRun {
+		return report, nil
+	}
+
+	if report.Created, err = c.CreateIndexes(ctx, collection, report.Missing...); err != nil {
+		return report, err
+	}
+
+	if !o.DropUnmanaged {
+		for _, name := range report.Unmanaged {
+			c.log.Warnf("index %s.%s is not declared, kept", collection, name)
+		}
+		return report, nil
+	}
+	for _, name := range report.Unmanaged {
+		if err = c.DropIndex(ctx, collection, name); err != nil {
+			return report, err
+		}
+		report.Dropped = append(report.Dropped, name)
+	}
+	return report, nil
+}
+
+// RegisterIndexes 注册额外的索引定义，与结构体标签中同名的索引以注册的为准
+func (r *Repository[DTO, ENTITY]) RegisterIndexes(indexes ...index.Index) {
+	r.indexes = append(r.indexes, indexes...)
+}
+
+// Indexes 返回由 ENTITY 结构体标签与 RegisterIndexes 注册的索引定义
+func (r *Repository[DTO, ENTITY]) Indexes() ([]index.Index, error) {
+	return index.FromStruct[ENTITY](r.indexes...)
+}
+
+// EnsureIndexes 按仓库的索引定义同步集合索引，参见 Client.EnsureIndexes
+func (r *Repository[DTO, ENTITY]) EnsureIndexes(ctx context.Context, opts ...index.EnsureOption) (*index.Report, error) {
+	if r.client == nil {
+		return nil, errDatabaseIsNil
+	}
+	if r.collection == "" {
+		return nil, errCollectionIsEmpty
+	}
+
+	indexes, err := r.Indexes()
+	if err != nil {
+		r.log.Errorf("build indexes of %s failed: %v", r.collection, err)
+		return nil, err
+	}
+	return r.client.EnsureIndexes(ctx, r.collection, indexes, opts...)
+}
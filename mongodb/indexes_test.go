@@ -0,0 +1,41 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/mapper"
+
+	"github.com/tx7do/go-crud/mongodb/index"
+)
+
+type indexedUser struct {
+	Email    string `bson:"email" index:"unique"`
+	TenantID string `bson:"tenant_id" index:"name:tenant_email;order:1"`
+	Nickname string `bson:"nickname"`
+}
+
+func TestRepository_Indexes(t *testing.T) {
+	logger := log.NewHelper(log.DefaultLogger)
+	m := mapper.NewCopierMapper[indexedUser, indexedUser]()
+
+	repo := NewRepository[indexedUser, indexedUser](&Client{}, "users", m, logger)
+	repo.RegisterIndexes(index.Keys("nickname").AsSparse())
+
+	indexes, err := repo.Indexes()
+	require.NoError(t, err)
+	var names []string
+	for _, idx := range indexes {
+		names = append(names, idx.IndexName())
+	}
+	assert.Equal(t, []string{"email_1", "tenant_email", "nickname_1"}, names)
+
+	_, err = NewRepository[indexedUser, indexedUser](nil, "users", m, logger).EnsureIndexes(context.Background())
+	assert.EqualError(t, err, "mongodb database is nil")
+
+	_, err = NewRepository[indexedUser, indexedUser](&Client{}, "", m, logger).EnsureIndexes(context.Background())
+	assert.EqualError(t, err, "collection is empty")
+}
@@ -0,0 +1,52 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/mapper"
+	"github.com/tx7do/go-utils/trans"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/limits"
+)
+
+func TestRepository_LimitPolicy(t *testing.T) {
+	logger := log.NewHelper(log.DefaultLogger)
+	repo := NewRepository[NoDeleted, NoDeleted](&Client{}, "test_limits", mapper.NewCopierMapper[NoDeleted, NoDeleted](), logger)
+	repo.SetLimitPolicy(&limits.Policy{
+		Limits: limits.Limits{MaxPageSize: 50, MaxInListSize: 2},
+	})
+
+	q, err := repo.ToQuery(&paginationV1.PagingRequest{
+		Page:     trans.Ptr(uint32(1)),
+		PageSize: trans.Ptr(uint32(1000000)),
+	})
+	require.NoError(t, err)
+	assert.Contains(t, q.Statement, `"limit":50`)
+
+	_, err = repo.ToQuery(&paginationV1.PagingRequest{
+		FilterExpr: &paginationV1.FilterExpr{
+			Type: paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{
+				{Field: "name", Op: paginationV1.Operator_IN, Values: []string{"a", "b", "c"}},
+			},
+		},
+	})
+	require.Error(t, err)
+	assert.True(t, errors.IsBadRequest(err))
+	assert.Equal(t, limits.ReasonInListTooLarge, errors.Reason(err))
+	assert.ErrorIs(t, err, limits.ErrInvalidArgument)
+
+	_, err = repo.DeleteByFilter(context.Background(), &paginationV1.FilterExpr{
+		Type: paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{
+			{Field: "name", Op: paginationV1.Operator_NIN, Value: trans.Ptr(`["a","b","c"]`)},
+		},
+	}, false)
+	assert.ErrorIs(t, err, limits.ErrInvalidArgument)
+}
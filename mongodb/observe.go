@@ -0,0 +1,28 @@
+package mongodb
+
+import (
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+)
+
+// SetSlowLog 设置慢查询日志，默认使用 Client 的 WithSlowLog 配置；传入 nil 关闭
+func (r *Repository[DTO, ENTITY]) SetSlowLog(l *slowlog.Logger) {
+	r.slowLog = l
+}
+
+// observe 开始观测一次仓库操作
+func (r *Repository[DTO, ENTITY]) observe(method string) *slowlog.Observation {
+	return slowlog.Observe(r.metrics, r.slowLog, metrics.BackendMongoDB, r.entityName, method)
+}
+
+// observeFilter 以扩展 JSON 记录过滤文档，用于慢查询指纹
+func observeFilter(obs *slowlog.Observation, filter interface{}) {
+	if obs.Call == nil || filter == nil {
+		return
+	}
+	if doc, err := bsonV2.MarshalExtJSON(filter, false, false); err == nil {
+		obs.SetJSONStatement(doc)
+	}
+}
@@ -5,7 +5,14 @@ import (
 	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	optionsV2 "go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/tx7do/go-crud/metrics"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 )
 
 type Option func(o *Client)
@@ -92,3 +99,52 @@ func WithBSONOptions(opt *optionsV2.BSONOptions) Option {
 		o.options = append(o.options, optionsV2.Client().SetBSONOptions(opt))
 	}
 }
+
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *Client) {
+		o.metrics = m
+	}
+}
+
+func WithSlowLog(l *slowlog.Logger) Option {
+	return func(o *Client) {
+		o.slowLog = l
+	}
+}
+
+func WithEnableTrace(enable bool) Option {
+	return func(o *Client) {
+		o.enableTrace = enable
+	}
+}
+
+func WithTracingOptions(opts ...tracing.Option) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, opts...)
+	}
+}
+
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithTracerProvider(provider))
+	}
+}
+
+func WithTracingAttributes(attrs ...attribute.KeyValue) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithAttributes(attrs...))
+	}
+}
+
+func WithTracingDBSystem(name string) Option {
+	return func(o *Client) {
+		o.tracingOptions = append(o.tracingOptions, tracing.WithDBSystem(name))
+	}
+}
+
+// WithTransactionOptions 设置 WithTransaction 的默认事务选项，调用时传入的选项优先
+func WithTransactionOptions(opts ...TxOption) Option {
+	return func(o *Client) {
+		o.txOptions = append(o.txOptions, opts...)
+	}
+}
@@ -31,6 +31,8 @@ const (
 	OperatorText       = "$text"       // 文本搜索
 	OperatorWhere      = "$where"      // JavaScript 表达式
 	OperatorSearch     = "$search"     // 文本搜索
+	OperatorLanguage   = "$language"   // 文本搜索语言
+	OperatorMeta       = "$meta"       // 元数据（如 textScore）
 
 	// 数组操作符
 
@@ -13,7 +13,8 @@ type Builder struct {
 	findOpts    *optionsV2.FindOptions
 	findOneOpts *optionsV2.FindOneOptions
 
-	pipeline []bsonV2.D
+	pipeline     []bsonV2.D
+	allowDiskUse *bool
 
 	skip     *int64
 	limit    *int64
@@ -139,6 +140,40 @@ func (qb *Builder) SetTextSearch(search string) *Builder {
 	return qb
 }
 
+// SetTextSearchWithLanguage 设置文本搜索条件并指定分词语言，language 为空时使用文本索引的默认语言
+func (qb *Builder) SetTextSearchWithLanguage(search, language string) *Builder {
+	text := bsonV2.M{OperatorSearch: search}
+	if language != "" {
+		text[OperatorLanguage] = language
+	}
+	qb.filter[OperatorText] = text
+	return qb
+}
+
+// SetTextScore 将文本搜索的相关度分数投影到 field，sortByScore 为 true 时按分数降序排在已有排序之前
+func (qb *Builder) SetTextScore(field string, sortByScore bool) *Builder {
+	if qb.findOpts == nil {
+		qb.findOpts = &optionsV2.FindOptions{}
+	}
+	meta := bsonV2.M{OperatorMeta: "textScore"}
+
+	projection, ok := qb.findOpts.Projection.(bsonV2.M)
+	if !ok || projection == nil {
+		projection = bsonV2.M{}
+	}
+	projection[field] = meta
+	qb.findOpts.Projection = projection
+
+	if sortByScore {
+		sort := bsonV2.D{{Key: field, Value: meta}}
+		if existing, ok := qb.findOpts.Sort.(bsonV2.D); ok {
+			sort = append(sort, existing...)
+		}
+		qb.findOpts.Sort = sort
+	}
+	return qb
+}
+
 // SetMod 设置字段的模运算条件
 func (qb *Builder) SetMod(field string, divisor, remainder int) *Builder {
 	qb.filter[field] = bsonV2.M{OperatorMod: bsonV2.A{divisor, remainder}}
@@ -222,6 +257,19 @@ func (qb *Builder) SetSortWithPriority(sortFields []bsonV2.E) *Builder {
 	return qb
 }
 
+// SetCollation 设置查询使用的排序规则（同时作用于过滤条件的字符串比较与排序）
+func (qb *Builder) SetCollation(collation *optionsV2.Collation) *Builder {
+	if qb.findOpts == nil {
+		qb.findOpts = &optionsV2.FindOptions{}
+	}
+	if qb.findOneOpts == nil {
+		qb.findOneOpts = &optionsV2.FindOneOptions{}
+	}
+	qb.findOpts.Collation = collation
+	qb.findOneOpts.Collation = collation
+	return qb
+}
+
 // SetProjection 设置查询结果的字段投影
 func (qb *Builder) SetProjection(projection bsonV2.M) *Builder {
 	if qb.findOpts == nil {
@@ -291,6 +339,30 @@ func (qb *Builder) AddStage(stage bsonV2.D) *Builder {
 	return qb
 }
 
+// Lookup 添加 $lookup 阶段，将 from 集合中 foreignField 等于 localField 的文档关联到数组字段 as
+func (qb *Builder) Lookup(from, localField, foreignField, as string) *Builder {
+	return qb.AddStage(bsonV2.D{{Key: OperatorLookup, Value: bsonV2.D{
+		{Key: "from", Value: from},
+		{Key: "localField", Value: localField},
+		{Key: "foreignField", Value: foreignField},
+		{Key: "as", Value: as},
+	}}})
+}
+
+// Unwind 添加 $unwind 阶段拆分数组字段 path（不带 $ 前缀），preserveEmpty 为 true 时保留数组为空或不存在的文档
+func (qb *Builder) Unwind(path string, preserveEmpty bool) *Builder {
+	return qb.AddStage(bsonV2.D{{Key: OperatorUnwind, Value: bsonV2.D{
+		{Key: "path", Value: "$" + path},
+		{Key: "preserveNullAndEmptyArrays", Value: preserveEmpty},
+	}}})
+}
+
+// SetAllowDiskUse 设置聚合是否允许使用磁盘临时文件
+func (qb *Builder) SetAllowDiskUse(allow bool) *Builder {
+	qb.allowDiskUse = &allow
+	return qb
+}
+
 // BuildPipeline 返回最终的聚合管道
 func (qb *Builder) BuildPipeline() []bsonV2.D {
 	if qb.pipeline == nil {
@@ -360,3 +432,55 @@ func (qb *Builder) BuildFindOne() (interface{}, optionsV2.Lister[optionsV2.FindO
 	}
 	return qb.filter, &findOneOptsLister{opts: qb.findOneOpts}, nil
 }
+
+// BuildAggregate 将过滤条件、自定义阶段、排序、分页与投影组合为一条聚合管道，并返回聚合选项。
+// 阶段顺序为 $match -> AddStage 添加的阶段 -> $sort -> $skip -> $limit -> $project，
+// 因此排序与投影可以使用 $lookup/$group 等阶段产生的字段
+func (qb *Builder) BuildAggregate() ([]bsonV2.D, *optionsV2.AggregateOptionsBuilder) {
+	var pipeline []bsonV2.D
+
+	if len(qb.filter) > 0 {
+		filterCopy := make(bsonV2.M, len(qb.filter))
+		for k, v := range qb.filter {
+			filterCopy[k] = v
+		}
+		pipeline = append(pipeline, bsonV2.D{{Key: OperatorMatch, Value: filterCopy}})
+	}
+
+	pipeline = append(pipeline, qb.pipeline...)
+
+	opts := optionsV2.Aggregate()
+	if qb.allowDiskUse != nil {
+		opts.SetAllowDiskUse(*qb.allowDiskUse)
+	}
+
+	skip, limit := qb.skip, qb.limit
+	if qb.findOpts != nil {
+		if skip == nil {
+			skip = qb.findOpts.Skip
+		}
+		if limit == nil {
+			limit = qb.findOpts.Limit
+		}
+		if qb.findOpts.Sort != nil {
+			pipeline = append(pipeline, bsonV2.D{{Key: OperatorSortAgg, Value: qb.findOpts.Sort}})
+		}
+		if qb.findOpts.Collation != nil {
+			opts.SetCollation(qb.findOpts.Collation)
+		}
+	}
+	if skip != nil && *skip > 0 {
+		pipeline = append(pipeline, bsonV2.D{{Key: OperatorSkip, Value: *skip}})
+	}
+	if limit != nil && *limit > 0 {
+		pipeline = append(pipeline, bsonV2.D{{Key: OperatorLimit, Value: *limit}})
+	}
+	if qb.findOpts != nil && qb.findOpts.Projection != nil {
+		pipeline = append(pipeline, bsonV2.D{{Key: OperatorProject, Value: qb.findOpts.Projection}})
+	}
+
+	if pipeline == nil {
+		pipeline = []bsonV2.D{}
+	}
+	return pipeline, opts
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+	optionsV2 "go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 func TestQueryBuilder(t *testing.T) {
@@ -293,3 +294,61 @@ func TestBuildImmutability(t *testing.T) {
 		assert.Equal(t, int64(10), *beforeOpts.Skip)
 	}
 }
+
+func TestTextSearchWithScore(t *testing.T) {
+	qb := NewQueryBuilder()
+	qb.SetTextSearchWithLanguage("go crud", "english")
+	assert.Equal(t, bsonV2.M{OperatorSearch: "go crud", OperatorLanguage: "english"}, qb.filter[OperatorText])
+
+	qb.SetProjection(bsonV2.M{"title": 1})
+	qb.SetSortWithPriority([]bsonV2.E{{Key: "created_at", Value: -1}})
+	qb.SetTextScore("score", true)
+
+	meta := bsonV2.M{OperatorMeta: "textScore"}
+	_, opts := qb.Build()
+	assert.Equal(t, bsonV2.M{"title": 1, "score": meta}, opts.Projection)
+	assert.Equal(t, bsonV2.D{{Key: "score", Value: meta}, {Key: "created_at", Value: -1}}, opts.Sort)
+}
+
+func TestBuildAggregate(t *testing.T) {
+	qb := NewQueryBuilder()
+	qb.Where(bsonV2.M{"status": "paid"}).
+		Lookup("users", "user_id", "_id", "user").
+		Unwind("user", true).
+		SetSort(bsonV2.D{{Key: "user.name", Value: 1}}).
+		SetSkipLimit(20, 10).
+		SetProjection(bsonV2.M{"user.name": 1, "amount": 1}).
+		SetAllowDiskUse(true)
+
+	pipeline, opts := qb.BuildAggregate()
+	assert.Equal(t, []bsonV2.D{
+		{{Key: OperatorMatch, Value: bsonV2.M{"status": "paid"}}},
+		{{Key: OperatorLookup, Value: bsonV2.D{
+			{Key: "from", Value: "users"},
+			{Key: "localField", Value: "user_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "user"},
+		}}},
+		{{Key: OperatorUnwind, Value: bsonV2.D{
+			{Key: "path", Value: "$user"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		{{Key: OperatorSortAgg, Value: bsonV2.D{{Key: "user.name", Value: 1}}}},
+		{{Key: OperatorSkip, Value: int64(20)}},
+		{{Key: OperatorLimit, Value: int64(10)}},
+		{{Key: OperatorProject, Value: bsonV2.M{"user.name": 1, "amount": 1}}},
+	}, pipeline)
+
+	var applied optionsV2.AggregateOptions
+	for _, fn := range opts.List() {
+		assert.NoError(t, fn(&applied))
+	}
+	if assert.NotNil(t, applied.AllowDiskUse) {
+		assert.True(t, *applied.AllowDiskUse)
+	}
+
+	// 空查询生成空管道
+	pipeline, _ = NewQueryBuilder().BuildAggregate()
+	assert.Empty(t, pipeline)
+	assert.NotNil(t, pipeline)
+}
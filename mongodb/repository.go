@@ -2,22 +2,35 @@ package mongodb
 
 import (
 	"context"
-	"errors"
+	"strings"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/tx7do/go-utils/mapper"
+	"github.com/tx7do/go-utils/stringcase"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/guard"
+	"github.com/tx7do/go-crud/limits"
+	"github.com/tx7do/go-crud/metrics"
 	"github.com/tx7do/go-crud/mongodb/field"
 	"github.com/tx7do/go-crud/mongodb/filter"
+	"github.com/tx7do/go-crud/mongodb/index"
 	paging "github.com/tx7do/go-crud/mongodb/pagination"
 	"github.com/tx7do/go-crud/mongodb/query"
 	"github.com/tx7do/go-crud/mongodb/sorting"
+	"github.com/tx7do/go-crud/slowlog"
+	"github.com/tx7do/go-crud/tracing"
 
 	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
 	optionsV2 "go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// SoftDeleteField 软删除使用的字段
+var SoftDeleteField = "deleted_at"
+
 // Repository MongoDB 版仓库（泛型）
 type Repository[DTO any, ENTITY any] struct {
 	mapper *mapper.CopierMapper[DTO, ENTITY]
@@ -34,12 +47,28 @@ type Repository[DTO any, ENTITY any] struct {
 
 	fieldSelector *field.Selector
 
+	guardOptions guard.Options
+	limitPolicy  *limits.Policy
+
+	indexes []index.Index // RegisterIndexes 注册的索引
+
 	client     *Client
 	collection string
 	log        *log.Helper
+
+	metrics    *metrics.Metrics
+	slowLog    *slowlog.Logger
+	entityName string
 }
 
 func NewRepository[DTO any, ENTITY any](client *Client, collection string, mapper *mapper.CopierMapper[DTO, ENTITY], logger *log.Helper) *Repository[DTO, ENTITY] {
+	var m *metrics.Metrics
+	var sl *slowlog.Logger
+	if client != nil {
+		m = client.metrics
+		sl = client.slowLog
+	}
+
 	return &Repository[DTO, ENTITY]{
 		client:     client,
 		collection: collection,
@@ -58,16 +87,79 @@ func NewRepository[DTO any, ENTITY any](client *Client, collection string, mappe
 		structuredFilter:  filter.NewStructuredFilter(),
 
 		fieldSelector: field.NewFieldSelector(),
+
+		metrics:    m,
+		slowLog:    sl,
+		entityName: metrics.EntityName[ENTITY](),
 	}
 }
 
 // ListWithPaging 针对 paginationV1.PagingRequest 的列表查询（兼容 Query/OrQuery/FilterExpr）
-func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *paginationV1.PagingRequest) ([]*DTO, int64, error) {
+func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *paginationV1.PagingRequest) (ret []*DTO, _ int64, err error) {
+	obs := r.observe("ListWithPaging")
+	defer func() {
+		obs.ReturnedRows(int64(len(ret)))
+		obs.End(err)
+	}()
+	obs.SetPagingRequest(req)
+
 	if r.client == nil {
-		return nil, 0, errors.New("mongodb database is nil")
+		return nil, 0, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return nil, 0, errors.New("collection is empty")
+		return nil, 0, errCollectionIsEmpty
+	}
+
+	ctx, span := r.startSpan(ctx, "ListWithPaging", tracing.PagingAttributes(req)...)
+	defer func() { span.End(err) }()
+
+	qb, err := r.buildPagingQuery(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 计数
+	total, err := r.Count(ctx, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 执行查询
+	filterDoc, findOpts, err := qb.BuildFind()
+	if err != nil {
+		return nil, 0, err
+	}
+	if filterDoc == nil {
+		filterDoc = bsonV2.M{}
+	}
+	observeFilter(obs, filterDoc)
+
+	var results []*ENTITY
+	if err = r.client.Find(ctx, r.collection, filterDoc, &results, findOpts); err != nil {
+		r.log.Errorf("find failed: %v", err)
+		return nil, 0, err
+	}
+
+	// 转换为 DTO
+	dtos := make([]*DTO, 0, len(results))
+	for _, ent := range results {
+		dtos = append(dtos, r.mapper.ToDTO(ent))
+	}
+
+	span.SetReturnedRows(int64(len(dtos)))
+	return dtos, total, nil
+}
+
+// startSpan 开始一次仓库操作的 span，子 span 由 Client 的各操作创建
+func (r *Repository[DTO, ENTITY]) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, *tracing.Span) {
+	return r.client.tracer.Start(ctx, operation, r.collection, attrs...)
+}
+
+// buildPagingQuery 按 PagingRequest 构建查询（过滤、投影、排序与分页）
+func (r *Repository[DTO, ENTITY]) buildPagingQuery(req *paginationV1.PagingRequest) (*query.Builder, error) {
+	req, err := r.limitPolicy.ApplyPaging(r.entityName, req)
+	if err != nil {
+		return nil, err
 	}
 
 	qb := query.NewQueryBuilder()
@@ -75,11 +167,11 @@ func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *pagin
 	// apply filters
 	if req.GetQuery() != "" || req.GetOrQuery() != "" {
 		if _, err := r.queryStringFilter.BuildSelectors(qb, req.GetQuery(), req.GetOrQuery()); err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 	} else if req.FilterExpr != nil {
 		if _, err := r.structuredFilter.BuildSelectors(qb, req.FilterExpr); err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 	}
 
@@ -108,42 +200,30 @@ func (r *Repository[DTO, ENTITY]) ListWithPaging(ctx context.Context, req *pagin
 		}
 	}
 
-	// 计数
-	total, err := r.Count(ctx, qb)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	// 执行查询
-	filterDoc, _, err := qb.BuildFind()
-	if err != nil {
-		return nil, 0, err
-	}
-	if filterDoc == nil {
-		filterDoc = bsonV2.M{}
-	}
-
-	var results []*ENTITY
-	if err = r.client.Find(ctx, r.collection, filterDoc, &results); err != nil {
-		r.log.Errorf("find failed: %v", err)
-		return nil, 0, err
-	}
-
-	// 转换为 DTO
-	dtos := make([]*DTO, 0, len(results))
-	for _, ent := range results {
-		dtos = append(dtos, r.mapper.ToDTO(ent))
-	}
-	return dtos, total, nil
+	return qb, nil
 }
 
 // ListWithPagination 针对 paginationV1.PaginationRequest 的列表查询
-func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *paginationV1.PaginationRequest) ([]*DTO, int64, error) {
+func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *paginationV1.PaginationRequest) (ret []*DTO, _ int64, err error) {
+	obs := r.observe("ListWithPagination")
+	defer func() {
+		obs.ReturnedRows(int64(len(ret)))
+		obs.End(err)
+	}()
+	obs.SetPaginationRequest(req)
+
 	if r.client == nil {
-		return nil, 0, errors.New("mongodb database is nil")
+		return nil, 0, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return nil, 0, errors.New("collection is empty")
+		return nil, 0, errCollectionIsEmpty
+	}
+
+	ctx, span := r.startSpan(ctx, "ListWithPagination", tracing.PaginationAttributes(req)...)
+	defer func() { span.End(err) }()
+
+	if req, err = r.limitPolicy.ApplyPagination(r.entityName, req); err != nil {
+		return nil, 0, err
 	}
 
 	qb := query.NewQueryBuilder()
@@ -190,16 +270,17 @@ func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *p
 	}
 
 	// 执行查询
-	filterDoc, _, err := qb.BuildFind()
+	filterDoc, findOpts, err := qb.BuildFind()
 	if err != nil {
 		return nil, 0, err
 	}
 	if filterDoc == nil {
 		filterDoc = bsonV2.M{}
 	}
+	observeFilter(obs, filterDoc)
 
 	var results []*ENTITY
-	if err = r.client.Find(ctx, r.collection, filterDoc, &results); err != nil {
+	if err = r.client.Find(ctx, r.collection, filterDoc, &results, findOpts); err != nil {
 		r.log.Errorf("find failed: %v", err)
 		return nil, 0, err
 	}
@@ -209,16 +290,26 @@ func (r *Repository[DTO, ENTITY]) ListWithPagination(ctx context.Context, req *p
 	for _, ent := range results {
 		dtos = append(dtos, r.mapper.ToDTO(ent))
 	}
+
+	span.SetReturnedRows(int64(len(dtos)))
 	return dtos, total, nil
 }
 
 // Get 根据过滤条件返回单条记录（使用 FilterExpr 或 Query/OrQuery 前置构建 qb）
-func (r *Repository[DTO, ENTITY]) Get(ctx context.Context, qb *query.Builder) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) Get(ctx context.Context, qb *query.Builder) (ret *DTO, err error) {
+	obs := r.observe("Get")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return nil, errors.New("mongodb database is nil")
+		return nil, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return nil, errors.New("collection is empty")
+		return nil, errCollectionIsEmpty
 	}
 	if qb == nil {
 		qb = query.NewQueryBuilder()
@@ -243,15 +334,23 @@ func (r *Repository[DTO, ENTITY]) Get(ctx context.Context, qb *query.Builder) (*
 }
 
 // Create 插入一条记录
-func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO) (ret *DTO, err error) {
+	obs := r.observe("Create")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return nil, errors.New("mongodb database is nil")
+		return nil, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return nil, errors.New("collection is empty")
+		return nil, errCollectionIsEmpty
 	}
 	if dto == nil {
-		return nil, errors.New("dto is nil")
+		return nil, errDTOIsNil
 	}
 
 	ent := r.mapper.ToEntity(dto)
@@ -265,12 +364,18 @@ func (r *Repository[DTO, ENTITY]) Create(ctx context.Context, dto *DTO) (*DTO, e
 }
 
 // BatchCreate 批量插入
-func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO) ([]*DTO, error) {
+func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO) (ret []*DTO, err error) {
+	obs := r.observe("BatchCreate")
+	defer func() {
+		obs.AffectedRows(int64(len(ret)))
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return nil, errors.New("mongodb database is nil")
+		return nil, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return nil, errors.New("collection is empty")
+		return nil, errCollectionIsEmpty
 	}
 	if len(dtos) == 0 {
 		return nil, nil
@@ -297,15 +402,23 @@ func (r *Repository[DTO, ENTITY]) BatchCreate(ctx context.Context, dtos []*DTO)
 }
 
 // Update 根据 filter 在 qb 中定位并更新（qb 应包含 where/selector info）
-func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, qb *query.Builder, updateDoc interface{}) (*DTO, error) {
+func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, qb *query.Builder, updateDoc interface{}) (ret *DTO, err error) {
+	obs := r.observe("Update")
+	defer func() {
+		if ret != nil {
+			obs.AffectedRows(1)
+		}
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return nil, errors.New("mongodb database is nil")
+		return nil, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return nil, errors.New("collection is empty")
+		return nil, errCollectionIsEmpty
 	}
 	if qb == nil {
-		return nil, errors.New("query builder is nil for update")
+		return nil, errQueryBuilderIsNilForUpdate
 	}
 
 	filterDoc, _, err := qb.BuildFindOne()
@@ -313,7 +426,7 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, qb *query.Builder,
 		return nil, err
 	}
 	if filterDoc == nil {
-		return nil, errors.New("empty filter for update")
+		return nil, errEmptyFilterForUpdate
 	}
 
 	var ent ENTITY
@@ -331,15 +444,21 @@ func (r *Repository[DTO, ENTITY]) Update(ctx context.Context, qb *query.Builder,
 }
 
 // Delete 根据 qb 中的 filter 删除（硬删除）
-func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, qb *query.Builder) (int64, error) {
+func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, qb *query.Builder) (ret int64, err error) {
+	obs := r.observe("Delete")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+
 	if r.client == nil {
-		return 0, errors.New("mongodb database is nil")
+		return 0, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return 0, errors.New("collection is empty")
+		return 0, errCollectionIsEmpty
 	}
 	if qb == nil {
-		return 0, errors.New("query builder is nil for delete")
+		return 0, errQueryBuilderIsNilForDelete
 	}
 
 	filterDoc, _, err := qb.BuildFind()
@@ -350,6 +469,10 @@ func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, qb *query.Builder)
 		filterDoc = bsonV2.M{}
 	}
 
+	if err = r.checkGuard(ctx, filterDoc); err != nil {
+		return 0, err
+	}
+
 	res, err := r.client.DeleteMany(ctx, r.collection, filterDoc)
 	if err != nil {
 		r.log.Errorf("delete documents failed: %v", err)
@@ -360,12 +483,15 @@ func (r *Repository[DTO, ENTITY]) Delete(ctx context.Context, qb *query.Builder)
 }
 
 // Count 按给定 builder 中的 filter 统计数量
-func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, qb *query.Builder) (int64, error) {
+func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, qb *query.Builder) (ret int64, err error) {
+	obs := r.observe("Count")
+	defer func() { obs.End(err) }()
+
 	if r.client == nil {
-		return 0, errors.New("mongodb database is nil")
+		return 0, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return 0, errors.New("collection is empty")
+		return 0, errCollectionIsEmpty
 	}
 	if qb == nil {
 		qb = query.NewQueryBuilder()
@@ -386,12 +512,15 @@ func (r *Repository[DTO, ENTITY]) Count(ctx context.Context, qb *query.Builder)
 }
 
 // Exists 判断是否存在符合 qb 的记录
-func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, qb *query.Builder) (bool, error) {
+func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, qb *query.Builder) (ret bool, err error) {
+	obs := r.observe("Exists")
+	defer func() { obs.End(err) }()
+
 	if r.client == nil {
-		return false, errors.New("mongodb database is nil")
+		return false, errDatabaseIsNil
 	}
 	if r.collection == "" {
-		return false, errors.New("collection is empty")
+		return false, errCollectionIsEmpty
 	}
 	if qb == nil {
 		qb = query.NewQueryBuilder()
@@ -410,3 +539,155 @@ func (r *Repository[DTO, ENTITY]) Exists(ctx context.Context, qb *query.Builder)
 
 	return exist, nil
 }
+
+// UpdateByFilter 根据 FilterExpr 批量更新文档（UpdateMany + $set），返回修改的文档数。
+// updateMask 为空时仅更新非空字段，否则仅更新 updateMask 中的字段。
+func (r *Repository[DTO, ENTITY]) UpdateByFilter(ctx context.Context, filterExpr *paginationV1.FilterExpr, dto *DTO, updateMask *fieldmaskpb.FieldMask) (ret int64, err error) {
+	obs := r.observe("UpdateByFilter")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+	obs.SetFilterExpr(filterExpr)
+
+	if r.client == nil {
+		return 0, errDatabaseIsNil
+	}
+	if r.collection == "" {
+		return 0, errCollectionIsEmpty
+	}
+
+	ctx, span := r.startSpan(ctx, "UpdateByFilter", tracing.FilterAttributes(filterExpr)...)
+	defer func() { span.End(err) }()
+	if dto == nil {
+		return 0, errDTOIsNil
+	}
+
+	setDoc, err := r.buildSetDocument(dto, updateMask)
+	if err != nil {
+		return 0, err
+	}
+	if len(setDoc) == 0 {
+		return 0, errNoFieldsToUpdate
+	}
+
+	filterDoc, err := r.buildFilterDocument(filterExpr)
+	if err != nil {
+		return 0, err
+	}
+	if err = r.checkGuard(ctx, filterDoc); err != nil {
+		return 0, err
+	}
+
+	res, err := r.client.UpdateMany(ctx, r.collection, filterDoc, bsonV2.M{query.OperatorSet: setDoc})
+	if err != nil {
+		r.log.Errorf("update by filter failed: %v", err)
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
+
+// DeleteByFilter 根据 FilterExpr 删除文档，返回受影响的文档数。
+// soft 为 true 时将 deleted_at 置为当前时间（UpdateMany），否则使用 DeleteMany。
+func (r *Repository[DTO, ENTITY]) DeleteByFilter(ctx context.Context, filterExpr *paginationV1.FilterExpr, soft bool) (ret int64, err error) {
+	obs := r.observe("DeleteByFilter")
+	defer func() {
+		obs.AffectedRows(ret)
+		obs.End(err)
+	}()
+	obs.SetFilterExpr(filterExpr)
+
+	if r.client == nil {
+		return 0, errDatabaseIsNil
+	}
+	if r.collection == "" {
+		return 0, errCollectionIsEmpty
+	}
+
+	ctx, span := r.startSpan(ctx, "DeleteByFilter", tracing.FilterAttributes(filterExpr)...)
+	defer func() { span.End(err) }()
+
+	filterDoc, err := r.buildFilterDocument(filterExpr)
+	if err != nil {
+		return 0, err
+	}
+	if err = r.checkGuard(ctx, filterDoc); err != nil {
+		return 0, err
+	}
+
+	if soft {
+		res, err := r.client.UpdateMany(ctx, r.collection, filterDoc, bsonV2.M{query.OperatorSet: bsonV2.M{SoftDeleteField: time.Now()}})
+		if err != nil {
+			r.log.Errorf("soft delete by filter failed: %v", err)
+			return 0, err
+		}
+		return res.ModifiedCount, nil
+	}
+
+	res, err := r.client.DeleteMany(ctx, r.collection, filterDoc)
+	if err != nil {
+		r.log.Errorf("delete by filter failed: %v", err)
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+// buildFilterDocument 使用 StructuredFilter 将 FilterExpr 构建为过滤文档
+func (r *Repository[DTO, ENTITY]) buildFilterDocument(filterExpr *paginationV1.FilterExpr) (bsonV2.M, error) {
+	if err := r.limitPolicy.CheckFilterExpr(r.entityName, filterExpr); err != nil {
+		return nil, err
+	}
+
+	qb := query.NewQueryBuilder()
+	if filterExpr != nil {
+		if _, err := r.structuredFilter.BuildSelectors(qb, filterExpr); err != nil {
+			return nil, err
+		}
+	}
+
+	filterDoc, _ := qb.Build()
+	return filterDoc, nil
+}
+
+// buildSetDocument 将 DTO 转换为 $set 文档，始终排除 _id
+func (r *Repository[DTO, ENTITY]) buildSetDocument(dto *DTO, updateMask *fieldmaskpb.FieldMask) (bsonV2.M, error) {
+	ent := r.mapper.ToEntity(dto)
+
+	raw, err := bsonV2.Marshal(ent)
+	if err != nil {
+		return nil, err
+	}
+	var doc bsonV2.M
+	if err = bsonV2.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	delete(doc, "_id")
+
+	if updateMask == nil || len(updateMask.GetPaths()) == 0 {
+		for k, v := range doc {
+			if v == nil {
+				delete(doc, k)
+			}
+		}
+		return doc, nil
+	}
+
+	setDoc := bsonV2.M{}
+	for _, path := range updateMask.GetPaths() {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key := stringcase.ToSnakeCase(path)
+		if v, ok := doc[key]; ok {
+			setDoc[key] = v
+		} else if v, ok = doc[path]; ok {
+			setDoc[path] = v
+		} else {
+			setDoc[key] = nil
+		}
+	}
+	return setDoc, nil
+}
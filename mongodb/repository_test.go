@@ -12,6 +12,7 @@ import (
 	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
 
 	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/guard"
 )
 
 // 简单实体类型用于测试
@@ -97,6 +98,7 @@ func TestRepository_CRUDAndList(t *testing.T) {
 	repo := NewRepository[NoDeleted, NoDeleted](client, "test_crud_list", noDelMapper, logger)
 
 	// 清空集合
+	repo.SetGuardOptions(guard.Options{AllowFullTable: true})
 	_, _ = repo.Delete(ctx, query.NewQueryBuilder())
 
 	// Create
@@ -161,6 +163,7 @@ func TestRepository_ListWithPaging_ConditionQuery(t *testing.T) {
 	repo := NewRepository[NoDeleted, NoDeleted](client, "test_list_query", noDelMapper, logger)
 
 	// 清空集合
+	repo.SetGuardOptions(guard.Options{AllowFullTable: true})
 	_, _ = repo.Delete(ctx, query.NewQueryBuilder())
 
 	// 插入数据
@@ -0,0 +1,107 @@
+package mongodb
+
+import (
+	"context"
+
+	mongoV2 "go.mongodb.org/mongo-driver/v2/mongo"
+	optionsV2 "go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/search"
+	"github.com/tx7do/go-crud/tracing"
+)
+
+// Search 全文检索：在 PagingRequest 的过滤、排序与分页之上使用 $text 匹配，textScore 作为相关度分数。
+// 集合须建立文本索引（见 TextIndexModel），字段权重由索引决定；高亮片段在内存中按 sreq.Fields 生成。
+func (r *Repository[DTO, ENTITY]) Search(ctx context.Context, req *paginationV1.PagingRequest, sreq *search.Request) (ret *search.Result[DTO], err error) {
+	obs := r.observe("Search")
+	defer func() {
+		if ret != nil {
+			obs.ReturnedRows(int64(len(ret.Hits)))
+		}
+		obs.End(err)
+	}()
+	obs.SetPagingRequest(req)
+
+	if r.client == nil {
+		return nil, errDatabaseIsNil
+	}
+	if r.collection == "" {
+		return nil, errCollectionIsEmpty
+	}
+	if err = sreq.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, span := r.startSpan(ctx, "Search", tracing.PagingAttributes(req)...)
+	defer func() { span.End(err) }()
+
+	qb, err := r.buildPagingQuery(req)
+	if err != nil {
+		return nil, err
+	}
+	qb.SetTextSearchWithLanguage(sreq.Query, sreq.Language)
+
+	total, err := r.Count(ctx, qb)
+	if err != nil {
+		return nil, err
+	}
+
+	// 投影与排序须在分页查询构建之后设置，以免被字段选择与排序覆盖
+	qb.SetTextScore(search.ScoreColumn, sreq.SortByRelevance)
+
+	filterDoc, findOpts, err := qb.BuildFind()
+	if err != nil {
+		return nil, err
+	}
+	observeFilter(obs, filterDoc)
+
+	var docs []bsonV2.Raw
+	if err = r.client.Find(ctx, r.collection, filterDoc, &docs, findOpts); err != nil {
+		r.log.Errorf("search failed: %v", err)
+		return nil, err
+	}
+
+	hits := make([]*search.Hit[DTO], 0, len(docs))
+	for _, doc := range docs {
+		var entity ENTITY
+		if err = bsonV2.Unmarshal(doc, &entity); err != nil {
+			r.log.Errorf("decode search result failed: %v", err)
+			return nil, err
+		}
+
+		dto := r.mapper.ToDTO(&entity)
+		score, _ := doc.Lookup(search.ScoreColumn).DoubleOK()
+		hits = append(hits, &search.Hit[DTO]{
+			Item:       dto,
+			Score:      score,
+			Highlights: search.HighlightItem(dto, sreq),
+		})
+	}
+
+	span.SetReturnedRows(int64(len(hits)))
+	return &search.Result[DTO]{Hits: hits, Total: uint64(total)}, nil
+}
+
+// TextIndexModel 返回按字段权重建立的文本索引，language 为默认分词语言（为空时使用 MongoDB 默认的 english）
+func TextIndexModel(fields []search.Field, language string) mongoV2.IndexModel {
+	keys := make(bsonV2.D, 0, len(fields))
+	weights := make(bsonV2.D, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, bsonV2.E{Key: f.Name, Value: "text"})
+
+		w := int32(f.Weight)
+		if w <= 0 {
+			w = 1
+		}
+		weights = append(weights, bsonV2.E{Key: f.Name, Value: w})
+	}
+
+	opts := optionsV2.Index().SetWeights(weights)
+	if language != "" {
+		opts.SetDefaultLanguage(language)
+	}
+	return mongoV2.IndexModel{Keys: keys, Options: opts}
+}
@@ -5,25 +5,52 @@ import (
 
 	pagination "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
 	"github.com/tx7do/go-crud/mongodb/query"
+	"github.com/tx7do/go-crud/ordering"
 	"github.com/tx7do/go-utils/stringcase"
 	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+	optionsV2 "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// DefaultTieBreaker 默认的兜底排序字段
+	DefaultTieBreaker = "_id"
+
+	// defaultCollationLocale 仅要求忽略大小写、未指定排序规则时使用的 locale
+	defaultCollationLocale = "en"
 )
 
 // StructuredSorting 将结构化排序指令转换为 MongoDB 的 ORDER BY 子句
-type StructuredSorting struct{}
+type StructuredSorting struct {
+	tieBreaker string
+}
 
 // NewStructuredSorting 创建实例
 func NewStructuredSorting() *StructuredSorting {
-	return &StructuredSorting{}
+	return &StructuredSorting{tieBreaker: DefaultTieBreaker}
+}
+
+// SetTieBreaker 设置兜底排序字段（升序追加在排序指令末尾以保证分页稳定），为空则不追加
+func (ss *StructuredSorting) SetTieBreaker(field string) *StructuredSorting {
+	ss.tieBreaker = strings.TrimSpace(field)
+	return ss
 }
 
-// BuildOrderClause 根据传入的排序指令构造 ORDER BY 子句
+// BuildOrderClause 根据传入的排序指令构造 ORDER BY 子句。
+// json_path 拼接为点路径；case_insensitive 与 collation 转换为查询级的 Collation（取第一个指定者），
+// 它同样作用于过滤条件的字符串比较。
+// $sort 无法表达空值位置与日期部分：空值在升序时总是排在最前、降序时排在最后，date_part 被忽略。
 func (ss StructuredSorting) BuildOrderClause(builder *query.Builder, orders []*pagination.Sorting) *query.Builder {
+	return buildOrderClause(builder, orders, ss.tieBreaker)
+}
+
+func buildOrderClause(builder *query.Builder, orders []*pagination.Sorting, tieBreaker string) *query.Builder {
 	if builder == nil || len(orders) == 0 {
 		return builder
 	}
 
 	var sortFields []bsonV2.E
+	var collation *optionsV2.Collation
+	var hasTieBreaker bool
 	for _, o := range orders {
 		if o == nil {
 			continue
@@ -36,13 +63,32 @@ func (ss StructuredSorting) BuildOrderClause(builder *query.Builder, orders []*p
 		if !fieldNameRegexp.MatchString(field) {
 			continue
 		}
+		if _, err := ordering.Key("", field, o); err != nil {
+			continue
+		}
 
 		var col string
 		if strings.Contains(field, ".") {
 			parts := strings.SplitN(field, ".", 2)
-			col = stringcase.ToSnakeCase(parts[0]) + "." + parts[1]
+			col = toSnakeCase(parts[0]) + "." + parts[1]
 		} else {
-			col = stringcase.ToSnakeCase(field)
+			col = toSnakeCase(field)
+		}
+		if path := o.GetJsonPath(); path != "" {
+			col += "." + path
+		}
+		if col == tieBreaker {
+			hasTieBreaker = true
+		}
+
+		if collation == nil && (o.GetCaseInsensitive() || o.GetCollation() != "") {
+			collation = &optionsV2.Collation{Locale: o.GetCollation()}
+			if collation.Locale == "" {
+				collation.Locale = defaultCollationLocale
+			}
+			if o.GetCaseInsensitive() {
+				collation.Strength = 2
+			}
 		}
 
 		dir := int32(1)
@@ -52,13 +98,26 @@ func (ss StructuredSorting) BuildOrderClause(builder *query.Builder, orders []*p
 		sortFields = append(sortFields, bsonV2.E{Key: col, Value: dir})
 	}
 
+	if len(sortFields) > 0 && tieBreaker != "" && !hasTieBreaker {
+		sortFields = append(sortFields, bsonV2.E{Key: tieBreaker, Value: int32(1)})
+	}
+
 	if len(sortFields) > 0 {
 		builder.SetSortWithPriority(sortFields)
 	}
+	if collation != nil {
+		builder.SetCollation(collation)
+	}
 
 	return builder
 }
 
+// toSnakeCase 转为蛇形命名，并保留 "_id" 等字段的前导下划线
+func toSnakeCase(field string) string {
+	name := strings.TrimLeft(field, "_")
+	return field[:len(field)-len(name)] + stringcase.ToSnakeCase(name)
+}
+
 // BuildOrderClauseWithDefaultField 当 orders 为空时使用默认排序字段
 func (ss StructuredSorting) BuildOrderClauseWithDefaultField(builder *query.Builder, orders []*pagination.Sorting, defaultOrderField string, defaultDesc bool) *query.Builder {
 	if builder == nil {
@@ -72,12 +131,12 @@ func (ss StructuredSorting) BuildOrderClauseWithDefaultField(builder *query.Buil
 		if defaultDesc {
 			order = pagination.Sorting_DESC
 		}
-		orders = []*pagination.Sorting{
+		return buildOrderClause(builder, []*pagination.Sorting{
 			{
 				Field: defaultOrderField,
 				Order: order,
 			},
-		}
+		}, "")
 	}
 	return ss.BuildOrderClause(builder, orders)
 }
@@ -6,6 +6,7 @@ import (
 	pagination "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
 	"github.com/tx7do/go-crud/mongodb/query"
 	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestStructuredSorting_BuildOrderClause_NoOrders_NoSort(t *testing.T) {
@@ -48,9 +49,9 @@ func TestStructuredSorting_BuildOrderClause_Orderings(t *testing.T) {
 		t.Fatalf("unexpected sort type: %#v", opts.Sort)
 	}
 
-	// valid entries: name, age, user_profile.name, created_at => 4 entries
-	if len(sortD) != 4 {
-		t.Fatalf("expected 4 sort entries, got %d: %#v", len(sortD), sortD)
+	// valid entries: name, age, user_profile.name, created_at + _id tie-breaker => 5 entries
+	if len(sortD) != 5 {
+		t.Fatalf("expected 5 sort entries, got %d: %#v", len(sortD), sortD)
 	}
 
 	if sortD[0].Key != "name" || sortD[0].Value != int32(1) {
@@ -65,6 +66,9 @@ func TestStructuredSorting_BuildOrderClause_Orderings(t *testing.T) {
 	if sortD[3].Key != "created_at" || sortD[3].Value != int32(1) {
 		t.Fatalf("expected fourth entry {created_at, 1}, got %#v", sortD[3])
 	}
+	if sortD[4].Key != "_id" || sortD[4].Value != int32(1) {
+		t.Fatalf("expected tie-breaker entry {_id, 1}, got %#v", sortD[4])
+	}
 }
 
 func TestStructuredSorting_BuildOrderClauseWithDefaultField(t *testing.T) {
@@ -105,7 +109,51 @@ func TestStructuredSorting_BuildOrderClauseWithDefaultField(t *testing.T) {
 	default:
 		t.Fatalf("unexpected sort type: %#v", opts2.Sort)
 	}
-	if len(sortD) != 1 || sortD[0].Key != "score" || sortD[0].Value != int32(-1) {
+	if len(sortD) != 2 || sortD[0].Key != "score" || sortD[0].Value != int32(-1) || sortD[1].Key != "_id" {
 		t.Fatalf("expected ORDER BY score DESC, got: %#v", sortD)
 	}
 }
+
+func TestStructuredSorting_BuildOrderClause_Rich(t *testing.T) {
+	ss := NewStructuredSorting()
+	qb := query.NewQueryBuilder()
+
+	orders := []*pagination.Sorting{
+		{Field: "name", Order: pagination.Sorting_DESC, CaseInsensitive: true, Collation: proto.String("de")},
+		{Field: "metadata", JsonPath: proto.String("owner.name")},
+		{Field: "title", JsonPath: proto.String("x'); --")},
+		{Field: "_id", Order: pagination.Sorting_DESC},
+	}
+
+	_, opts := ss.BuildOrderClause(qb, orders).Build()
+	sortD, ok := opts.Sort.(bsonV2.D)
+	if !ok {
+		t.Fatalf("unexpected sort type: %#v", opts.Sort)
+	}
+
+	want := bsonV2.D{{Key: "name", Value: int32(-1)}, {Key: "metadata.owner.name", Value: int32(1)}, {Key: "_id", Value: int32(-1)}}
+	if len(sortD) != len(want) {
+		t.Fatalf("expected %#v, got %#v", want, sortD)
+	}
+	for i := range want {
+		if sortD[i] != want[i] {
+			t.Fatalf("expected %#v, got %#v", want, sortD)
+		}
+	}
+
+	if opts.Collation == nil || opts.Collation.Locale != "de" || opts.Collation.Strength != 2 {
+		t.Fatalf("expected case-insensitive collation, got %#v", opts.Collation)
+	}
+}
+
+func TestStructuredSorting_BuildOrderClause_NoTieBreaker(t *testing.T) {
+	ss := NewStructuredSorting().SetTieBreaker("")
+
+	_, opts := ss.BuildOrderClause(query.NewQueryBuilder(), []*pagination.Sorting{{Field: "name"}}).Build()
+	if sortD := opts.Sort.(bsonV2.D); len(sortD) != 1 {
+		t.Fatalf("did not expect tie-breaker, got %#v", sortD)
+	}
+	if opts.Collation != nil {
+		t.Fatalf("did not expect collation, got %#v", opts.Collation)
+	}
+}
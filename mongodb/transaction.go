@@ -0,0 +1,215 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	mongoV2 "go.mongodb.org/mongo-driver/v2/mongo"
+	optionsV2 "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+)
+
+const (
+	// LabelTransientTransactionError 事务整体可重试的错误标签
+	LabelTransientTransactionError = "TransientTransactionError"
+	// LabelUnknownTransactionCommitResult 提交结果未知、可重试提交的错误标签
+	LabelUnknownTransactionCommitResult = "UnknownTransactionCommitResult"
+
+	// DefaultTxMaxRetries 默认的事务重试次数
+	DefaultTxMaxRetries = 3
+	// DefaultTxRetryTimeout 默认的事务重试总时长，与驱动 Session.WithTransaction 一致
+	DefaultTxRetryTimeout = 120 * time.Second
+)
+
+// TxOptions 事务选项
+type TxOptions struct {
+	ReadConcern    *readconcern.ReadConcern
+	WriteConcern   *writeconcern.WriteConcern
+	ReadPreference *readpref.ReadPref
+
+	// MaxRetries TransientTransactionError 时整个事务的重试次数，UnknownTransactionCommitResult 时提交的重试次数；小于 0 表示不重试
+	MaxRetries int
+	// RetryTimeout 从第一次开始事务起允许重试的总时长，超过后不再重试
+	RetryTimeout time.Duration
+}
+
+type TxOption func(o *TxOptions)
+
+// WithTxReadConcern 设置事务的读关注，如 readconcern.Snapshot()
+func WithTxReadConcern(rc *readconcern.ReadConcern) TxOption {
+	return func(o *TxOptions) {
+		o.ReadConcern = rc
+	}
+}
+
+// WithTxWriteConcern 设置事务的写关注，如 writeconcern.Majority()
+func WithTxWriteConcern(wc *writeconcern.WriteConcern) TxOption {
+	return func(o *TxOptions) {
+		o.WriteConcern = wc
+	}
+}
+
+// WithTxReadPreference 设置事务的读偏好，事务中只能为 primary
+func WithTxReadPreference(rp *readpref.ReadPref) TxOption {
+	return func(o *TxOptions) {
+		o.ReadPreference = rp
+	}
+}
+
+// WithTxMaxRetries 设置事务与提交的重试次数
+func WithTxMaxRetries(n int) TxOption {
+	return func(o *TxOptions) {
+		o.MaxRetries = n
+	}
+}
+
+// WithTxRetryTimeout 设置事务重试的总时长
+func WithTxRetryTimeout(d time.Duration) TxOption {
+	return func(o *TxOptions) {
+		o.RetryTimeout = d
+	}
+}
+
+// newTxOptions 依次应用客户端默认选项与本次调用的选项
+func newTxOptions(defaults []TxOption, opts []TxOption) TxOptions {
+	o := TxOptions{
+		MaxRetries:   DefaultTxMaxRetries,
+		RetryTimeout: DefaultTxRetryTimeout,
+	}
+	for _, opt := range defaults {
+		opt(&o)
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// transactionOptions 转换为驱动的事务选项，未设置的读写关注沿用客户端配置
+func (o TxOptions) transactionOptions() *optionsV2.TransactionOptionsBuilder {
+	txOpts := optionsV2.Transaction()
+	if o.ReadConcern != nil {
+		txOpts.SetReadConcern(o.ReadConcern)
+	}
+	if o.WriteConcern != nil {
+		txOpts.SetWriteConcern(o.WriteConcern)
+	}
+	if o.ReadPreference != nil {
+		txOpts.SetReadPreference(o.ReadPreference)
+	}
+	return txOpts
+}
+
+// txSession 事务所需的会话操作，*mongoV2.Session 实现了该接口
+type txSession interface {
+	StartTransaction(opts ...optionsV2.Lister[optionsV2.TransactionOptions]) error
+	AbortTransaction(ctx context.Context) error
+	CommitTransaction(ctx context.Context) error
+}
+
+// HasErrorLabel 判断错误（含包装的错误）是否带有指定的服务端错误标签
+func HasErrorLabel(err error, label string) bool {
+	var le mongoV2.LabeledError
+	return errors.As(err, &le) && le.HasErrorLabel(label)
+}
+
+// InTransaction 判断 ctx 是否已处于进行中的事务
+func InTransaction(ctx context.Context) bool {
+	sess := mongoV2.SessionFromContext(ctx)
+	return sess != nil && sess.ClientSession() != nil && sess.ClientSession().TransactionRunning()
+}
+
+// WithTransaction 在一个新会话中以事务执行 fn。
+// 会话通过传给 fn 的 ctx 传播，使用该 ctx 调用的 Client 与 Repository 方法都会自动加入事务；
+// fn 返回错误时回滚事务，带 TransientTransactionError 标签的错误会重试整个事务，
+// 提交时带 UnknownTransactionCommitResult 标签的错误只重试提交，因此 fn 可能被执行多次，应保证幂等。
+// ctx 已处于事务中时直接执行 fn 并加入外层事务
+func (c *Client) WithTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) (err error) {
+	if c.cli == nil {
+		c.log.Errorf("mongodb client is not initialized")
+		return mongoV2.ErrClientDisconnected
+	}
+	if fn == nil {
+		return errTransactionCallbackIsNil
+	}
+
+	if InTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	ctx, span := c.tracer.Start(ctx, "transaction", "")
+	defer func() { span.End(err) }()
+
+	sess, err := c.cli.StartSession()
+	if err != nil {
+		c.log.Errorf("failed to start session: %v", err)
+		return err
+	}
+	defer sess.EndSession(context.WithoutCancel(ctx))
+
+	return c.runTransaction(mongoV2.NewSessionContext(ctx, sess), sess, fn, newTxOptions(c.txOptions, opts))
+}
+
+// runTransaction 执行事务并按错误标签重试，ctx 中应已携带 sess
+func (c *Client) runTransaction(ctx context.Context, sess txSession, fn func(ctx context.Context) error, o TxOptions) error {
+	deadline := time.Now().Add(o.RetryTimeout)
+	canRetry := func(attempt int) bool {
+		return attempt < o.MaxRetries && time.Now().Before(deadline) && ctx.Err() == nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := sess.StartTransaction(o.transactionOptions()); err != nil {
+			c.log.Errorf("failed to start transaction: %v", err)
+			return err
+		}
+
+		if err := fn(ctx); err != nil {
+			// ctx 被取消时仍需回滚，避免事务在服务端持有锁直到超时
+			if aerr := sess.AbortTransaction(context.WithoutCancel(ctx)); aerr != nil {
+				c.log.Warnf("failed to abort transaction: %v", aerr)
+			}
+			if HasErrorLabel(err, LabelTransientTransactionError) && canRetry(attempt) {
+				c.log.Warnf("transient transaction error, retrying (%d/%d): %v", attempt+1, o.MaxRetries, err)
+				continue
+			}
+			return err
+		}
+
+		err := c.commitTransaction(ctx, sess, canRetry)
+		if err == nil {
+			return nil
+		}
+		if HasErrorLabel(err, LabelTransientTransactionError) && canRetry(attempt) {
+			c.log.Warnf("transient transaction error on commit, retrying (%d/%d): %v", attempt+1, o.MaxRetries, err)
+			continue
+		}
+		c.log.Errorf("failed to commit transaction: %v", err)
+		return err
+	}
+}
+
+// commitTransaction 提交事务，提交结果未知时重试提交
+func (c *Client) commitTransaction(ctx context.Context, sess txSession, canRetry func(attempt int) bool) error {
+	for attempt := 0; ; attempt++ {
+		err := sess.CommitTransaction(ctx)
+		if err == nil {
+			return nil
+		}
+		if HasErrorLabel(err, LabelUnknownTransactionCommitResult) && canRetry(attempt) {
+			c.log.Warnf("unknown transaction commit result, retrying commit (%d): %v", attempt+1, err)
+			continue
+		}
+		return err
+	}
+}
+
+// WithTransaction 在仓库所属客户端上以事务执行 fn，参见 Client.WithTransaction
+func (r *Repository[DTO, ENTITY]) WithTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if r.client == nil {
+		return errDatabaseIsNil
+	}
+	return r.client.WithTransaction(ctx, fn, opts...)
+}
@@ -0,0 +1,169 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tx7do/go-utils/mapper"
+	bsonV2 "go.mongodb.org/mongo-driver/v2/bson"
+	mongoV2 "go.mongodb.org/mongo-driver/v2/mongo"
+	optionsV2 "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+
+	"github.com/tx7do/go-crud/guard"
+	"github.com/tx7do/go-crud/mongodb/query"
+)
+
+// fakeTxSession 按顺序返回预置的提交错误，并记录调用
+type fakeTxSession struct {
+	commitErrs []error
+	calls      []string
+}
+
+func (s *fakeTxSession) StartTransaction(...optionsV2.Lister[optionsV2.TransactionOptions]) error {
+	s.calls = append(s.calls, "start")
+	return nil
+}
+
+func (s *fakeTxSession) AbortTransaction(context.Context) error {
+	s.calls = append(s.calls, "abort")
+	return nil
+}
+
+func (s *fakeTxSession) CommitTransaction(context.Context) error {
+	s.calls = append(s.calls, "commit")
+	if len(s.commitErrs) == 0 {
+		return nil
+	}
+	err := s.commitErrs[0]
+	s.commitErrs = s.commitErrs[1:]
+	return err
+}
+
+func labeledError(label string) error {
+	return fmt.Errorf("wrapped: %w", mongoV2.CommandError{Code: 112, Message: label, Labels: []string{label}})
+}
+
+func TestClient_RunTransaction_Retry(t *testing.T) {
+	c := &Client{log: log.NewHelper(log.DefaultLogger)}
+	ctx := context.Background()
+
+	// fn 返回 TransientTransactionError 时重试整个事务
+	sess := &fakeTxSession{}
+	runs := 0
+	err := c.runTransaction(ctx, sess, func(context.Context) error {
+		runs++
+		if runs == 1 {
+			return labeledError(LabelTransientTransactionError)
+		}
+		return nil
+	}, newTxOptions(nil, nil))
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, []string{"start", "abort", "start", "commit"}, sess.calls)
+
+	// 提交结果未知时只重试提交
+	sess = &fakeTxSession{commitErrs: []error{labeledError(LabelUnknownTransactionCommitResult), labeledError(LabelUnknownTransactionCommitResult)}}
+	runs = 0
+	err = c.runTransaction(ctx, sess, func(context.Context) error { runs++; return nil }, newTxOptions(nil, nil))
+	require.NoError(t, err)
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, []string{"start", "commit", "commit", "commit"}, sess.calls)
+
+	// 超过重试次数后返回错误
+	sess = &fakeTxSession{}
+	err = c.runTransaction(ctx, sess, func(context.Context) error {
+		return labeledError(LabelTransientTransactionError)
+	}, newTxOptions(nil, []TxOption{WithTxMaxRetries(1)}))
+	assert.True(t, HasErrorLabel(err, LabelTransientTransactionError))
+	assert.Equal(t, []string{"start", "abort", "start", "abort"}, sess.calls)
+
+	// 普通错误回滚且不重试
+	boom := errors.New("boom")
+	sess = &fakeTxSession{}
+	err = c.runTransaction(ctx, sess, func(context.Context) error { return boom }, newTxOptions(nil, nil))
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, []string{"start", "abort"}, sess.calls)
+}
+
+func TestTxOptions(t *testing.T) {
+	o := newTxOptions(
+		[]TxOption{WithTxWriteConcern(writeconcern.Majority()), WithTxMaxRetries(5)},
+		[]TxOption{WithTxReadConcern(readconcern.Snapshot()), WithTxMaxRetries(1)},
+	)
+	assert.Equal(t, 1, o.MaxRetries)
+	assert.Equal(t, DefaultTxRetryTimeout, o.RetryTimeout)
+	assert.Equal(t, "snapshot", o.ReadConcern.Level)
+	assert.Equal(t, "majority", o.WriteConcern.W)
+
+	assert.False(t, InTransaction(context.Background()))
+	assert.Error(t, (&Client{log: log.NewHelper(log.DefaultLogger)}).WithTransaction(context.Background(), func(context.Context) error { return nil }))
+}
+
+// TestRepository_WithTransaction 需要单节点副本集，例如：
+// docker run -d -p 27017:27017 mongo:7 --replSet rs0 && docker exec <id> mongosh --eval "rs.initiate()"
+// MONGODB_REPLICA_SET_URI="mongodb://127.0.0.1:27017/?replicaSet=rs0&directConnection=true" go test -run TestRepository_WithTransaction
+func TestRepository_WithTransaction(t *testing.T) {
+	uri := os.Getenv("MONGODB_REPLICA_SET_URI")
+	if uri == "" {
+		t.Skip("MONGODB_REPLICA_SET_URI not set")
+	}
+
+	client, err := NewClient(WithLogger(log.DefaultLogger), WithURI(uri), WithDatabase("go_crud_test"))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := context.Background()
+	logger := log.NewHelper(log.DefaultLogger)
+	repo := NewRepository[NoDeleted, NoDeleted](client, "test_transaction", mapper.NewCopierMapper[NoDeleted, NoDeleted](), logger)
+	repo.SetGuardOptions(guard.Options{AllowFullTable: true})
+
+	// 事务中不能隐式创建集合，先写入再清空以确保集合存在
+	_, err = repo.Create(ctx, &NoDeleted{ID: 0})
+	require.NoError(t, err)
+	_, err = repo.Delete(ctx, query.NewQueryBuilder())
+	require.NoError(t, err)
+
+	// 回滚
+	boom := errors.New("boom")
+	err = repo.WithTransaction(ctx, func(ctx context.Context) error {
+		assert.True(t, InTransaction(ctx))
+		if _, err := repo.Create(ctx, &NoDeleted{ID: 1, Name: "rollback"}); err != nil {
+			return err
+		}
+		n, err := repo.Count(ctx, query.NewQueryBuilder())
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, int64(1), n)
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+
+	n, err := repo.Count(ctx, query.NewQueryBuilder())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	// 提交，嵌套调用加入外层事务
+	err = client.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.Create(ctx, &NoDeleted{ID: 2, Name: "commit"}); err != nil {
+			return err
+		}
+		return repo.WithTransaction(ctx, func(ctx context.Context) error {
+			_, err := repo.Update(ctx, query.NewQueryBuilder().Where(bsonV2.M{"id": 2}), bsonV2.M{"$set": bsonV2.M{"name": "nested"}})
+			return err
+		})
+	}, WithTxWriteConcern(writeconcern.Majority()), WithTxReadConcern(readconcern.Snapshot()))
+	require.NoError(t, err)
+
+	got, err := repo.Get(ctx, query.NewQueryBuilder().Where(bsonV2.M{"id": 2}))
+	require.NoError(t, err)
+	assert.Equal(t, "nested", got.Name)
+}
@@ -0,0 +1,268 @@
+package ordering
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/dialect"
+)
+
+var (
+	// ErrInvalidJSONPath JSON 路径不合法
+	ErrInvalidJSONPath = errors.New("invalid sorting json path")
+
+	// ErrInvalidCollation 排序规则名不合法
+	ErrInvalidCollation = errors.New("invalid sorting collation")
+
+	// ErrInvalidDatePart 日期部分不合法
+	ErrInvalidDatePart = errors.New("invalid sorting date part")
+)
+
+var (
+	// fieldNameRegexp 字段名，允许 "t.field" 形式的表前缀
+	fieldNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+	// jsonPathRegexp 以点分隔的 JSON 路径，每段为字母数字下划线
+	jsonPathRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*$`)
+	// collationRegexp 排序规则名，如 "C"、"en_US.utf8"、"utf8mb4_unicode_ci"
+	collationRegexp = regexp.MustCompile(`^[A-Za-z0-9_\-.@]+$`)
+)
+
+// ValidField 校验排序字段名
+func ValidField(field string) bool {
+	return fieldNameRegexp.MatchString(field)
+}
+
+// Plain 判断排序指令是否只按字段本身排序（未使用 JSON 路径、日期部分、大小写与排序规则）
+func Plain(o *paginationV1.Sorting) bool {
+	return o.GetJsonPath() == "" &&
+		o.GetDatePart() == paginationV1.DatePart_DATE_PART_UNSPECIFIED &&
+		!o.GetCaseInsensitive() &&
+		o.GetCollation() == ""
+}
+
+// WithTieBreaker 在排序指令末尾追加 field 升序，保证分页结果稳定；
+// orders 为空、field 为空或 orders 中已按 field 本身排序时原样返回
+func WithTieBreaker(orders []*paginationV1.Sorting, field string) []*paginationV1.Sorting {
+	if field == "" {
+		return orders
+	}
+
+	var n int
+	for _, o := range orders {
+		if o == nil || o.GetField() == "" {
+			continue
+		}
+		n++
+		if o.GetField() == field && Plain(o) {
+			return orders
+		}
+	}
+	if n == 0 {
+		return orders
+	}
+
+	out := make([]*paginationV1.Sorting, 0, len(orders)+1)
+	out = append(out, orders...)
+	return append(out, &paginationV1.Sorting{Field: field, Order: paginationV1.Sorting_ASC})
+}
+
+// Key 返回排序键表达式：column 为调用方已校验（或已加引号）的列表达式，
+// 依次应用 json_path、date_part、case_insensitive 与 collation。
+// ClickHouse 的 COLLATE 是 ORDER BY 元素的修饰符而非表达式的一部分，此处只校验，由 Clauses 追加在方向与 NULLS 之后
+func Key(sqlDialect, column string, o *paginationV1.Sorting) (string, error) {
+	sqlDialect = dialect.Normalize(sqlDialect)
+	expr := column
+
+	if path := o.GetJsonPath(); path != "" {
+		if !jsonPathRegexp.MatchString(path) {
+			return "", fmt.Errorf("%w: %s", ErrInvalidJSONPath, path)
+		}
+		expr = jsonExtract(sqlDialect, expr, strings.Split(path, "."))
+	}
+
+	if part := o.GetDatePart(); part != paginationV1.DatePart_DATE_PART_UNSPECIFIED {
+		extracted, ok := datePart(sqlDialect, expr, part)
+		if !ok {
+			return "", fmt.Errorf("%w: %s for %s", ErrInvalidDatePart, part, sqlDialect)
+		}
+		expr = extracted
+	}
+
+	if o.GetCaseInsensitive() {
+		if sqlDialect == dialect.ClickHouse {
+			expr = "lowerUTF8(" + expr + ")"
+		} else {
+			expr = "LOWER(" + expr + ")"
+		}
+	}
+
+	if c := o.GetCollation(); c != "" {
+		if !collationRegexp.MatchString(c) {
+			return "", fmt.Errorf("%w: %s", ErrInvalidCollation, c)
+		}
+		switch sqlDialect {
+		case dialect.Postgres:
+			expr += ` COLLATE "` + c + `"`
+		case dialect.ClickHouse:
+			// 由 Clauses 追加
+		default:
+			expr += " COLLATE " + c
+		}
+	}
+
+	return expr, nil
+}
+
+// Clauses 返回排序指令的 ORDER BY 片段（不含 ORDER BY 关键字）。
+// Postgres、SQLite 与 ClickHouse 使用 NULLS FIRST/LAST，其它方言先按 CASE WHEN ... IS NULL 排序以获得一致的空值位置。
+func Clauses(sqlDialect, column string, o *paginationV1.Sorting) ([]string, error) {
+	key, err := Key(sqlDialect, column, o)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := "ASC"
+	if o.GetOrder() == paginationV1.Sorting_DESC {
+		dir = "DESC"
+	}
+
+	nulls := o.GetNulls()
+
+	// ClickHouse: expr [ASC|DESC] [NULLS FIRST|LAST] [COLLATE 'x']
+	if dialect.Normalize(sqlDialect) == dialect.ClickHouse {
+		clause := key + " " + dir
+		switch nulls {
+		case paginationV1.Sorting_NULLS_FIRST:
+			clause += " NULLS FIRST"
+		case paginationV1.Sorting_NULLS_LAST:
+			clause += " NULLS LAST"
+		}
+		if c := o.GetCollation(); c != "" {
+			clause += " COLLATE '" + c + "'"
+		}
+		return []string{clause}, nil
+	}
+
+	if nulls == paginationV1.Sorting_NULLS_DEFAULT {
+		return []string{key + " " + dir}, nil
+	}
+
+	switch dialect.Normalize(sqlDialect) {
+	case dialect.Postgres, dialect.SQLite:
+		if nulls == paginationV1.Sorting_NULLS_FIRST {
+			return []string{key + " " + dir + " NULLS FIRST"}, nil
+		}
+		return []string{key + " " + dir + " NULLS LAST"}, nil
+	}
+
+	nullDir := "ASC"
+	if nulls == paginationV1.Sorting_NULLS_FIRST {
+		nullDir = "DESC"
+	}
+	return []string{
+		"CASE WHEN " + key + " IS NULL THEN 1 ELSE 0 END " + nullDir,
+		key + " " + dir,
+	}, nil
+}
+
+// jsonExtract 取出 JSON 路径的值；除 SQLite 按原始类型比较外，其余方言按文本比较
+func jsonExtract(sqlDialect, expr string, path []string) string {
+	switch sqlDialect {
+	case dialect.Postgres:
+		return "(" + expr + " #>> '{" + strings.Join(path, ",") + "}')"
+	case dialect.MySQL:
+		return "JSON_UNQUOTE(JSON_EXTRACT(" + expr + ", '$." + strings.Join(path, ".") + "'))"
+	case dialect.SQLite:
+		return "json_extract(" + expr + ", '$." + strings.Join(path, ".") + "')"
+	case dialect.ClickHouse:
+		return "JSONExtractString(" + expr + ", '" + strings.Join(path, "', '") + "')"
+	}
+	return "JSON_VALUE(" + expr + ", '$." + strings.Join(path, ".") + "')"
+}
+
+// datePart 取日期/时间表达式的某一部分；DATE、TIME 为日期与时间值，其余为整数。
+// WEEK_DAY 为 0-6（周日为 0），ISO_WEEK_DAY 为 1-7（周一为 1）。方言不支持该部分时返回 false
+func datePart(sqlDialect, expr string, part paginationV1.DatePart) (string, bool) {
+	var tmpl string
+	switch sqlDialect {
+	case dialect.SQLite:
+		tmpl = map[paginationV1.DatePart]string{
+			paginationV1.DatePart_DATE:         "date(%s)",
+			paginationV1.DatePart_YEAR:         "CAST(strftime('%%Y', %s) AS INTEGER)",
+			paginationV1.DatePart_QUARTER:      "((CAST(strftime('%%m', %s) AS INTEGER) + 2) / 3)",
+			paginationV1.DatePart_MONTH:        "CAST(strftime('%%m', %s) AS INTEGER)",
+			paginationV1.DatePart_WEEK:         "CAST(strftime('%%W', %s) AS INTEGER)",
+			paginationV1.DatePart_WEEK_DAY:     "CAST(strftime('%%w', %s) AS INTEGER)",
+			paginationV1.DatePart_ISO_WEEK_DAY: "((CAST(strftime('%%w', %s) AS INTEGER) + 6) %% 7 + 1)",
+			paginationV1.DatePart_DAY:          "CAST(strftime('%%d', %s) AS INTEGER)",
+			paginationV1.DatePart_TIME:         "time(%s)",
+			paginationV1.DatePart_HOUR:         "CAST(strftime('%%H', %s) AS INTEGER)",
+			paginationV1.DatePart_MINUTE:       "CAST(strftime('%%M', %s) AS INTEGER)",
+			paginationV1.DatePart_SECOND:       "CAST(strftime('%%S', %s) AS INTEGER)",
+			paginationV1.DatePart_MICROSECOND:  "(CAST(strftime('%%f', %s) * 1000000 AS INTEGER) %% 1000000)",
+		}[part]
+
+	case dialect.ClickHouse:
+		tmpl = map[paginationV1.DatePart]string{
+			paginationV1.DatePart_DATE:         "toDate(%s)",
+			paginationV1.DatePart_YEAR:         "toYear(%s)",
+			paginationV1.DatePart_ISO_YEAR:     "toISOYear(%s)",
+			paginationV1.DatePart_QUARTER:      "toQuarter(%s)",
+			paginationV1.DatePart_MONTH:        "toMonth(%s)",
+			paginationV1.DatePart_WEEK:         "toISOWeek(%s)",
+			paginationV1.DatePart_WEEK_DAY:     "(toDayOfWeek(%s) %% 7)",
+			paginationV1.DatePart_ISO_WEEK_DAY: "toDayOfWeek(%s)",
+			paginationV1.DatePart_DAY:          "toDayOfMonth(%s)",
+			paginationV1.DatePart_TIME:         "toTime(%s)",
+			paginationV1.DatePart_HOUR:         "toHour(%s)",
+			paginationV1.DatePart_MINUTE:       "toMinute(%s)",
+			paginationV1.DatePart_SECOND:       "toSecond(%s)",
+			paginationV1.DatePart_MICROSECOND:  "(toUnixTimestamp64Micro(%s) %% 1000000)",
+		}[part]
+
+	case dialect.MySQL:
+		tmpl = map[paginationV1.DatePart]string{
+			paginationV1.DatePart_DATE:         "DATE(%s)",
+			paginationV1.DatePart_YEAR:         "YEAR(%s)",
+			paginationV1.DatePart_ISO_YEAR:     "(YEARWEEK(%s, 3) DIV 100)",
+			paginationV1.DatePart_QUARTER:      "QUARTER(%s)",
+			paginationV1.DatePart_MONTH:        "MONTH(%s)",
+			paginationV1.DatePart_WEEK:         "WEEK(%s, 3)",
+			paginationV1.DatePart_WEEK_DAY:     "(DAYOFWEEK(%s) - 1)",
+			paginationV1.DatePart_ISO_WEEK_DAY: "(WEEKDAY(%s) + 1)",
+			paginationV1.DatePart_DAY:          "DAY(%s)",
+			paginationV1.DatePart_TIME:         "TIME(%s)",
+			paginationV1.DatePart_HOUR:         "HOUR(%s)",
+			paginationV1.DatePart_MINUTE:       "MINUTE(%s)",
+			paginationV1.DatePart_SECOND:       "SECOND(%s)",
+			paginationV1.DatePart_MICROSECOND:  "MICROSECOND(%s)",
+		}[part]
+
+	default:
+		// Postgres 与标准 SQL
+		tmpl = map[paginationV1.DatePart]string{
+			paginationV1.DatePart_DATE:         "CAST(%s AS DATE)",
+			paginationV1.DatePart_YEAR:         "EXTRACT(YEAR FROM %s)",
+			paginationV1.DatePart_ISO_YEAR:     "EXTRACT(ISOYEAR FROM %s)",
+			paginationV1.DatePart_QUARTER:      "EXTRACT(QUARTER FROM %s)",
+			paginationV1.DatePart_MONTH:        "EXTRACT(MONTH FROM %s)",
+			paginationV1.DatePart_WEEK:         "EXTRACT(WEEK FROM %s)",
+			paginationV1.DatePart_WEEK_DAY:     "EXTRACT(DOW FROM %s)",
+			paginationV1.DatePart_ISO_WEEK_DAY: "EXTRACT(ISODOW FROM %s)",
+			paginationV1.DatePart_DAY:          "EXTRACT(DAY FROM %s)",
+			paginationV1.DatePart_TIME:         "CAST(%s AS TIME)",
+			paginationV1.DatePart_HOUR:         "EXTRACT(HOUR FROM %s)",
+			paginationV1.DatePart_MINUTE:       "EXTRACT(MINUTE FROM %s)",
+			paginationV1.DatePart_SECOND:       "EXTRACT(SECOND FROM %s)",
+			paginationV1.DatePart_MICROSECOND:  "(EXTRACT(MICROSECONDS FROM %s) %% 1000000)",
+		}[part]
+	}
+
+	if tmpl == "" {
+		return "", false
+	}
+	return fmt.Sprintf(tmpl, expr), true
+}
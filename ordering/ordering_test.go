@@ -0,0 +1,99 @@
+package ordering
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+func TestClauses(t *testing.T) {
+	cases := []struct {
+		dialect string
+		sorting *paginationV1.Sorting
+		want    []string
+	}{
+		{"postgres", &paginationV1.Sorting{Field: "name", Order: paginationV1.Sorting_DESC, Nulls: paginationV1.Sorting_NULLS_LAST}, []string{"name DESC NULLS LAST"}},
+		{"mysql", &paginationV1.Sorting{Field: "name", Nulls: paginationV1.Sorting_NULLS_LAST}, []string{"CASE WHEN name IS NULL THEN 1 ELSE 0 END ASC", "name ASC"}},
+		{"mysql", &paginationV1.Sorting{Field: "name", Nulls: paginationV1.Sorting_NULLS_FIRST}, []string{"CASE WHEN name IS NULL THEN 1 ELSE 0 END DESC", "name ASC"}},
+		{"sqlite3", &paginationV1.Sorting{Field: "name", CaseInsensitive: true, Nulls: paginationV1.Sorting_NULLS_FIRST}, []string{"LOWER(name) ASC NULLS FIRST"}},
+		{"postgres", &paginationV1.Sorting{Field: "metadata", JsonPath: proto.String("a.priority")}, []string{"(metadata #>> '{a,priority}') ASC"}},
+		{"mysql", &paginationV1.Sorting{Field: "metadata", JsonPath: proto.String("priority"), Order: paginationV1.Sorting_DESC}, []string{"JSON_UNQUOTE(JSON_EXTRACT(metadata, '$.priority')) DESC"}},
+		{"sqlite", &paginationV1.Sorting{Field: "metadata", JsonPath: proto.String("priority")}, []string{"json_extract(metadata, '$.priority') ASC"}},
+		{"postgres", &paginationV1.Sorting{Field: "create_time", DatePart: paginationV1.DatePart_MONTH}, []string{"EXTRACT(MONTH FROM create_time) ASC"}},
+		{"mysql", &paginationV1.Sorting{Field: "create_time", DatePart: paginationV1.DatePart_WEEK_DAY}, []string{"(DAYOFWEEK(create_time) - 1) ASC"}},
+		{"sqlite", &paginationV1.Sorting{Field: "create_time", DatePart: paginationV1.DatePart_MONTH}, []string{"CAST(strftime('%m', create_time) AS INTEGER) ASC"}},
+		{"sqlite", &paginationV1.Sorting{Field: "create_time", DatePart: paginationV1.DatePart_ISO_WEEK_DAY}, []string{"((CAST(strftime('%w', create_time) AS INTEGER) + 6) % 7 + 1) ASC"}},
+		{"postgres", &paginationV1.Sorting{Field: "create_time", DatePart: paginationV1.DatePart_DATE}, []string{"CAST(create_time AS DATE) ASC"}},
+		{"clickhouse", &paginationV1.Sorting{Field: "create_time", DatePart: paginationV1.DatePart_MONTH, Nulls: paginationV1.Sorting_NULLS_LAST}, []string{"toMonth(create_time) ASC NULLS LAST"}},
+		{"postgres", &paginationV1.Sorting{Field: "name", Collation: proto.String("C")}, []string{`name COLLATE "C" ASC`}},
+		{"mysql", &paginationV1.Sorting{Field: "name", CaseInsensitive: true, Collation: proto.String("utf8mb4_unicode_ci")}, []string{"LOWER(name) COLLATE utf8mb4_unicode_ci ASC"}},
+		{"clickhouse", &paginationV1.Sorting{Field: "name", Order: paginationV1.Sorting_DESC, Nulls: paginationV1.Sorting_NULLS_FIRST, Collation: proto.String("en")}, []string{"name DESC NULLS FIRST COLLATE 'en'"}},
+	}
+	for _, c := range cases {
+		got, err := Clauses(c.dialect, c.sorting.GetField(), c.sorting)
+		if err != nil {
+			t.Fatalf("%s %v: unexpected error %v", c.dialect, c.sorting, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s %v: expected %v, got %v", c.dialect, c.sorting, c.want, got)
+		}
+	}
+}
+
+func TestClauses_Invalid(t *testing.T) {
+	cases := map[error]*paginationV1.Sorting{
+		ErrInvalidJSONPath:  {Field: "metadata", JsonPath: proto.String("a'); DROP TABLE users; --")},
+		ErrInvalidCollation: {Field: "name", Collation: proto.String(`C" DESC, (SELECT 1)`)},
+		ErrInvalidDatePart:  {Field: "create_time", DatePart: paginationV1.DatePart(99)},
+	}
+	for want, s := range cases {
+		if _, err := Clauses("postgres", s.GetField(), s); !errors.Is(err, want) {
+			t.Errorf("expected %v, got %v", want, err)
+		}
+	}
+
+	// SQLite 没有 ISO 年
+	if _, err := Clauses("sqlite", "create_time", &paginationV1.Sorting{Field: "create_time", DatePart: paginationV1.DatePart_ISO_YEAR}); !errors.Is(err, ErrInvalidDatePart) {
+		t.Errorf("expected %v, got %v", ErrInvalidDatePart, err)
+	}
+
+	for _, field := range []string{"id", "t.name", "_x"} {
+		if !ValidField(field) {
+			t.Errorf("%q should be valid", field)
+		}
+	}
+	for _, field := range []string{"", "1a", "a.b.c", "name DESC", "a;b"} {
+		if ValidField(field) {
+			t.Errorf("%q should be invalid", field)
+		}
+	}
+}
+
+func TestWithTieBreaker(t *testing.T) {
+	orders := []*paginationV1.Sorting{{Field: "name"}}
+	got := WithTieBreaker(orders, "id")
+	if len(got) != 2 || got[1].GetField() != "id" || len(orders) != 1 {
+		t.Errorf("expected id appended without modifying input, got %v", got)
+	}
+
+	withID := []*paginationV1.Sorting{{Field: "id", Order: paginationV1.Sorting_DESC}, {Field: "name"}}
+	if got = WithTieBreaker(withID, "id"); len(got) != 2 {
+		t.Errorf("id already sorted, got %v", got)
+	}
+
+	lowerID := []*paginationV1.Sorting{{Field: "id", CaseInsensitive: true}}
+	if got = WithTieBreaker(lowerID, "id"); len(got) != 2 {
+		t.Errorf("computed key on id is not a tie-breaker, got %v", got)
+	}
+
+	if got = WithTieBreaker(nil, "id"); got != nil {
+		t.Errorf("empty orders must stay empty, got %v", got)
+	}
+	if got = WithTieBreaker(orders, ""); len(got) != 1 {
+		t.Errorf("empty tie-breaker must not append, got %v", got)
+	}
+}
@@ -0,0 +1,229 @@
+package search
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Terms 从检索文本中提取用于高亮的词项：保留引号内的短语，去掉 +/- 前缀、* 后缀与 AND/OR/NOT，排除词（以 - 开头）不参与高亮
+func Terms(query string) []string {
+	var (
+		terms []string
+		seen  = make(map[string]bool)
+	)
+	add := func(t string, exclude bool) {
+		t = strings.TrimRight(strings.TrimSpace(t), "*")
+		if t == "" || exclude {
+			return
+		}
+		switch strings.ToUpper(t) {
+		case "AND", "OR", "NOT":
+			return
+		}
+		if k := strings.ToLower(t); !seen[k] {
+			seen[k] = true
+			terms = append(terms, t)
+		}
+	}
+
+	rest := query
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+
+		exclude := false
+		switch rest[0] {
+		case '-':
+			exclude = true
+			rest = rest[1:]
+		case '+', '~', '>', '<':
+			rest = rest[1:]
+		}
+
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				add(rest[1:], exclude)
+				break
+			}
+			add(rest[1:end+1], exclude)
+			rest = rest[end+2:]
+			continue
+		}
+
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		add(strings.Trim(rest[:end], `()"`), exclude)
+		rest = rest[end:]
+	}
+	return terms
+}
+
+// HighlightText 在文本中查找词项（不区分大小写，匹配词首），返回带高亮标签的片段；未命中时返回 nil
+func HighlightText(text string, terms []string, h *Highlight) []string {
+	if text == "" || len(terms) == 0 || h == nil {
+		return nil
+	}
+
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	type span struct{ start, end int }
+	var matches []span
+	for _, t := range terms {
+		tr := []rune(strings.ToLower(t))
+		for i := 0; i+len(tr) <= len(lower); i++ {
+			if i > 0 && isWordRune(lower[i-1]) {
+				continue
+			}
+			if string(lower[i:i+len(tr)]) == string(tr) {
+				matches = append(matches, span{i, i + len(tr)})
+				i += len(tr) - 1
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].start != matches[j].start {
+			return matches[i].start < matches[j].start
+		}
+		return matches[i].end > matches[j].end
+	})
+	merged := matches[:1]
+	for _, m := range matches[1:] {
+		last := &merged[len(merged)-1]
+		if m.start < last.end {
+			last.end = max(last.end, m.end)
+			continue
+		}
+		merged = append(merged, m)
+	}
+
+	// 以命中位置为中心截取片段，相邻或重叠的片段合并
+	var windows []span
+	for _, m := range merged {
+		pad := max(h.FragmentSize-(m.end-m.start), 0) / 2
+		w := span{max(m.start-pad, 0), min(m.end+pad, len(runes))}
+		if n := len(windows); n > 0 && w.start <= windows[n-1].end {
+			windows[n-1].end = max(windows[n-1].end, w.end)
+			continue
+		}
+		if len(windows) == h.MaxFragments {
+			break
+		}
+		windows = append(windows, w)
+	}
+
+	fragments := make([]string, 0, len(windows))
+	for _, w := range windows {
+		var sb strings.Builder
+		pos := w.start
+		for _, m := range merged {
+			if m.start < w.start || m.end > w.end {
+				continue
+			}
+			sb.WriteString(string(runes[pos:m.start]))
+			sb.WriteString(h.PreTag)
+			sb.WriteString(string(runes[m.start:m.end]))
+			sb.WriteString(h.PostTag)
+			pos = m.end
+		}
+		sb.WriteString(string(runes[pos:w.end]))
+		fragments = append(fragments, strings.TrimSpace(sb.String()))
+	}
+	return fragments
+}
+
+// HighlightItem 在内存中为结果项的检索字段生成高亮片段，用于数据库不支持高亮的场景；未开启高亮时返回 nil
+func HighlightItem(item any, req *Request) map[string][]string {
+	h := req.HighlightOptions()
+	if h == nil || item == nil {
+		return nil
+	}
+
+	terms := Terms(req.Query)
+	out := make(map[string][]string)
+	for _, f := range req.Fields {
+		text, ok := FieldText(item, f.Name)
+		if !ok {
+			continue
+		}
+		if fragments := HighlightText(text, terms, h); len(fragments) > 0 {
+			out[f.Name] = fragments
+		}
+	}
+	return out
+}
+
+// FieldText 读取结果项中字符串字段的值，支持 proto 消息（按字段名或 JSON 名）与普通结构体（忽略大小写与下划线）
+func FieldText(item any, field string) (string, bool) {
+	if m, ok := item.(proto.Message); ok {
+		msg := m.ProtoReflect()
+		if !msg.IsValid() {
+			return "", false
+		}
+		fields := msg.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(field))
+		if fd == nil {
+			fd = fields.ByJSONName(field)
+		}
+		if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() || fd.IsMap() {
+			return "", false
+		}
+		return msg.Get(fd).String(), true
+	}
+
+	v := reflect.ValueOf(item)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+
+	want := normalizeName(field)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() || normalizeName(t.Field(i).Name) != want {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				return "", false
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() != reflect.String {
+			return "", false
+		}
+		return fv.String(), true
+	}
+	return "", false
+}
+
+func normalizeName(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, "_", ""))
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
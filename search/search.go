@@ -0,0 +1,219 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+)
+
+// 结果集中相关度分数与高亮片段的列别名
+const (
+	ScoreColumn     = "_search_score"
+	HighlightColumn = "_search_highlights"
+)
+
+const (
+	DefaultPreTag       = "<em>"
+	DefaultPostTag      = "</em>"
+	DefaultFragmentSize = 150
+	DefaultMaxFragments = 3
+)
+
+var (
+	// ErrEmptyQuery 检索文本为空
+	ErrEmptyQuery = errors.New("search query is empty")
+
+	// ErrNoFields 未指定检索字段
+	ErrNoFields = errors.New("search fields are empty")
+
+	// ErrInvalidField 检索字段名不合法
+	ErrInvalidField = errors.New("invalid search field name")
+)
+
+var fieldNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Field 参与检索的字段及其权重
+type Field struct {
+	Name string
+
+	// Weight 相对权重，小于等于 0 时视为 1
+	Weight float64
+}
+
+// Highlight 高亮配置，零值字段使用默认值
+type Highlight struct {
+	PreTag  string
+	PostTag string
+
+	// FragmentSize 每个片段的大致字符数
+	FragmentSize int
+	// MaxFragments 每个字段最多返回的片段数
+	MaxFragments int
+}
+
+// Request 全文检索请求，与 PagingRequest 一起使用：后者负责过滤、排序与分页
+type Request struct {
+	// Query 检索文本，语法由后端决定：
+	// Postgres 为 websearch_to_tsquery，MySQL 为 BOOLEAN MODE，SQLite 将各词项按短语匹配，MongoDB 为 $text，Elasticsearch 为 multi_match
+	Query string
+
+	// Fields 检索字段及权重
+	Fields []Field
+
+	// Language 分词配置：Postgres 的 regconfig（默认 simple）、MongoDB 的 $language、Elasticsearch 的 analyzer
+	Language string
+
+	// SortByRelevance 按相关度降序排列，优先于 PagingRequest 中的排序
+	SortByRelevance bool
+
+	// Highlight 不为 nil 时返回高亮片段
+	Highlight *Highlight
+
+	// FTSTable SQLite FTS5 虚拟表名，默认 <表名>_fts，其列顺序须与 Fields 一致
+	FTSTable string
+}
+
+// Validate 校验检索请求
+func (r *Request) Validate() error {
+	if r == nil || r.Query == "" {
+		return ErrEmptyQuery
+	}
+	if len(r.Fields) == 0 {
+		return ErrNoFields
+	}
+	for _, f := range r.Fields {
+		if !fieldNameRegexp.MatchString(f.Name) {
+			return errors.Join(ErrInvalidField, errors.New(f.Name))
+		}
+	}
+	return nil
+}
+
+// FieldNames 返回检索字段名
+func (r *Request) FieldNames() []string {
+	names := make([]string, 0, len(r.Fields))
+	for _, f := range r.Fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// HighlightOptions 返回补全默认值后的高亮配置，未开启高亮时返回 nil
+func (r *Request) HighlightOptions() *Highlight {
+	if r == nil || r.Highlight == nil {
+		return nil
+	}
+	h := *r.Highlight
+	if h.PreTag == "" && h.PostTag == "" {
+		h.PreTag, h.PostTag = DefaultPreTag, DefaultPostTag
+	}
+	if h.FragmentSize <= 0 {
+		h.FragmentSize = DefaultFragmentSize
+	}
+	if h.MaxFragments <= 0 {
+		h.MaxFragments = DefaultMaxFragments
+	}
+	return &h
+}
+
+// weight 返回字段权重
+func (f Field) weight() float64 {
+	if f.Weight <= 0 {
+		return 1
+	}
+	return f.Weight
+}
+
+// uniformWeights 判断所有字段权重是否相同
+func (r *Request) uniformWeights() bool {
+	for _, f := range r.Fields[1:] {
+		if f.weight() != r.Fields[0].weight() {
+			return false
+		}
+	}
+	return true
+}
+
+// weightLabels 按权重从高到低为字段分配 Postgres 的权重标签 A-D，超过 4 档的归入 D
+func (r *Request) weightLabels() []byte {
+	distinct := make([]float64, 0, len(r.Fields))
+	seen := make(map[float64]bool)
+	for _, f := range r.Fields {
+		if w := f.weight(); !seen[w] {
+			seen[w] = true
+			distinct = append(distinct, w)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(distinct)))
+
+	rank := make(map[float64]int, len(distinct))
+	for i, w := range distinct {
+		rank[w] = min(i, 3)
+	}
+
+	labels := make([]byte, len(r.Fields))
+	for i, f := range r.Fields {
+		labels[i] = "ABCD"[rank[f.weight()]]
+	}
+	return labels
+}
+
+// Hit 检索命中项
+type Hit[T any] struct {
+	Item *T
+
+	// Score 相关度分数，越大越相关；各后端的取值范围不同，仅用于同一次检索内比较
+	Score float64
+
+	// Highlights 字段名到高亮片段的映射
+	Highlights map[string][]string
+}
+
+// Result 检索结果
+type Result[T any] struct {
+	Hits  []*Hit[T]
+	Total uint64
+}
+
+// Items 返回按命中顺序排列的结果项
+func (r *Result[T]) Items() []*T {
+	if r == nil {
+		return nil
+	}
+	items := make([]*T, 0, len(r.Hits))
+	for _, h := range r.Hits {
+		items = append(items, h.Item)
+	}
+	return items
+}
+
+// DecodeHighlights 解析 SQL 高亮表达式返回的 JSON 对象，字段值可以是字符串或字符串数组，空片段会被忽略
+func DecodeHighlights(s string) map[string][]string {
+	if s == "" {
+		return nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil
+	}
+
+	out := make(map[string][]string, len(raw))
+	for k, v := range raw {
+		var fragments []string
+		if err := json.Unmarshal(v, &fragments); err != nil {
+			var one string
+			if err = json.Unmarshal(v, &one); err != nil {
+				continue
+			}
+			fragments = []string{one}
+		}
+		for _, f := range fragments {
+			if f != "" {
+				out[k] = append(out[k], f)
+			}
+		}
+	}
+	return out
+}
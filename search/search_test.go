@@ -0,0 +1,157 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestRequest_Validate(t *testing.T) {
+	cases := []struct {
+		req  *Request
+		want error
+	}{
+		{nil, ErrEmptyQuery},
+		{&Request{Fields: []Field{{Name: "title"}}}, ErrEmptyQuery},
+		{&Request{Query: "go"}, ErrNoFields},
+		{&Request{Query: "go", Fields: []Field{{Name: "title; DROP TABLE users"}}}, ErrInvalidField},
+		{&Request{Query: "go", Fields: []Field{{Name: "title"}}}, nil},
+	}
+	for i, c := range cases {
+		if err := c.req.Validate(); !errors.Is(err, c.want) {
+			t.Errorf("case %d: expected %v, got %v", i, c.want, err)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := Terms(`+golang "full text" -java data* OR Golang`)
+	want := []string{"golang", "full text", "data"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestHighlightText(t *testing.T) {
+	h := (&Request{Highlight: &Highlight{FragmentSize: 20}}).HighlightOptions()
+
+	got := HighlightText("Learning Go is fun, and the rest of this sentence is only padding; going further with GO.", []string{"go"}, h)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 fragments, got %v", got)
+	}
+	if !strings.Contains(got[0], "<em>Go</em> is") {
+		t.Errorf("unexpected first fragment %q", got[0])
+	}
+	if !strings.Contains(got[1], "<em>go</em>ing") || !strings.Contains(got[1], "<em>GO</em>") {
+		t.Errorf("unexpected second fragment %q", got[1])
+	}
+
+	if got = HighlightText("cargo", []string{"go"}, h); got != nil {
+		t.Errorf("match must start at a word boundary, got %v", got)
+	}
+}
+
+func TestHighlightItem(t *testing.T) {
+	req := &Request{
+		Query:     "crud",
+		Fields:    []Field{{Name: "title"}, {Name: "user_name"}, {Name: "missing"}},
+		Highlight: &Highlight{},
+	}
+
+	type item struct {
+		Title    string
+		UserName *string
+	}
+	name := "crud bot"
+	got := HighlightItem(&item{Title: "go-crud", UserName: &name}, req)
+	want := map[string][]string{"title": {"go-<em>crud</em>"}, "user_name": {"<em>crud</em> bot"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("struct: expected %v, got %v", want, got)
+	}
+
+	msg, _ := structpb.NewValue("crud")
+	if text, ok := FieldText(msg, "string_value"); !ok || text != "crud" {
+		t.Errorf("proto: expected crud, got %q %v", text, ok)
+	}
+}
+
+func TestBuildSQL_Postgres(t *testing.T) {
+	req := &Request{
+		Query:     "go crud",
+		Fields:    []Field{{Name: "title", Weight: 2}, {Name: "body"}},
+		Language:  "english",
+		Highlight: &Highlight{},
+	}
+	s, err := BuildSQL("postgres", "posts", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(s.Where, `setweight(to_tsvector(?::regconfig, coalesce("posts"."title"::text, '')), 'A')`) ||
+		!strings.Contains(s.Where, `'B')) @@ websearch_to_tsquery(?::regconfig, ?)`) {
+		t.Errorf("unexpected where %s", s.Where)
+	}
+	if want := []any{"english", "english", "english", "go crud"}; !reflect.DeepEqual(s.WhereArgs, want) {
+		t.Errorf("unexpected where args %v", s.WhereArgs)
+	}
+	if s.ScoreArgs[0] != "{0.1,0.2,0.5,1}" || strings.Count(s.Score, "?") != len(s.ScoreArgs) {
+		t.Errorf("unexpected score %s %v", s.Score, s.ScoreArgs)
+	}
+	if !strings.HasPrefix(s.Highlight, "json_build_object('title', ts_headline(") || strings.Count(s.Highlight, "?") != len(s.HighlightArgs) {
+		t.Errorf("unexpected highlight %s %v", s.Highlight, s.HighlightArgs)
+	}
+}
+
+func TestBuildSQL_MySQL(t *testing.T) {
+	req := &Request{Query: "+go -java", Fields: []Field{{Name: "title"}, {Name: "body"}}}
+	s, err := BuildSQL("mysql", "posts", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Where != "MATCH(`posts`.`title`, `posts`.`body`) AGAINST(? IN BOOLEAN MODE)" || s.Score != s.Where {
+		t.Errorf("unexpected where %s / score %s", s.Where, s.Score)
+	}
+	if s.Highlight != "" {
+		t.Errorf("mysql has no highlight expression, got %s", s.Highlight)
+	}
+
+	req.Fields[0].Weight = 3
+	if s, _ = BuildSQL("mysql", "posts", req); s.Score != "(3 * MATCH(`posts`.`title`) AGAINST(? IN BOOLEAN MODE) + 1 * MATCH(`posts`.`body`) AGAINST(? IN BOOLEAN MODE))" {
+		t.Errorf("unexpected weighted score %s", s.Score)
+	}
+}
+
+func TestBuildSQL_SQLite(t *testing.T) {
+	req := &Request{Query: `go "full text"`, Fields: []Field{{Name: "title", Weight: 2}, {Name: "body"}}, Highlight: &Highlight{}}
+	s, err := BuildSQL("sqlite3", "posts", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Where != `"posts".rowid IN (SELECT rowid FROM "posts_fts" WHERE "posts_fts" MATCH ?)` {
+		t.Errorf("unexpected where %s", s.Where)
+	}
+	if s.WhereArgs[0] != `{title body} : ("go" "full text")` {
+		t.Errorf("unexpected match %v", s.WhereArgs[0])
+	}
+	if !strings.HasPrefix(s.Score, `(SELECT -bm25("posts_fts", 2, 1) FROM`) {
+		t.Errorf("unexpected score %s", s.Score)
+	}
+	if strings.Count(s.Highlight, "?") != len(s.HighlightArgs) {
+		t.Errorf("unexpected highlight %s %v", s.Highlight, s.HighlightArgs)
+	}
+
+	if _, err = BuildSQL("sqlserver", "posts", req); !errors.Is(err, ErrUnsupportedDialect) {
+		t.Errorf("expected ErrUnsupportedDialect, got %v", err)
+	}
+}
+
+func TestDecodeHighlights(t *testing.T) {
+	got := DecodeHighlights(`{"title":"<em>go</em>","body":["a","b"],"empty":""}`)
+	want := map[string][]string{"title": {"<em>go</em>"}, "body": {"a", "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
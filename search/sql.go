@@ -0,0 +1,211 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tx7do/go-crud/dialect"
+)
+
+// ErrUnsupportedDialect 方言不支持全文检索
+var ErrUnsupportedDialect = errors.New("full-text search is not supported for dialect")
+
+// SQL 全文检索的 SQL 片段，参数使用 ? 占位
+type SQL struct {
+	// Where 匹配条件
+	Where     string
+	WhereArgs []any
+
+	// Score 相关度表达式，越大越相关
+	Score     string
+	ScoreArgs []any
+
+	// Highlight 返回 JSON 对象（字段名 -> 片段）的表达式；为空表示数据库不支持，由调用方使用 HighlightItem 在内存中生成
+	Highlight     string
+	HighlightArgs []any
+}
+
+// BuildSQL 生成指定方言的全文检索 SQL 片段：
+//   - Postgres：to_tsvector @@ websearch_to_tsquery，ts_rank 按字段权重打分，ts_headline 生成高亮；
+//   - MySQL：MATCH ... AGAINST (IN BOOLEAN MODE)，字段权重不同时按各列 MATCH 加权求和（需为每列建立 FULLTEXT 索引）；
+//   - SQLite：FTS5 虚拟表（rowid 与主表一致），bm25 按字段权重打分，snippet 生成高亮。
+func BuildSQL(sqlDialect, table string, req *Request) (*SQL, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if table != "" && !fieldNameRegexp.MatchString(table) {
+		return nil, errors.Join(ErrInvalidField, errors.New(table))
+	}
+
+	switch dialect.Normalize(sqlDialect) {
+	case dialect.Postgres:
+		return buildPostgres(table, req), nil
+	case dialect.MySQL:
+		return buildMySQL(table, req), nil
+	case dialect.SQLite:
+		if table == "" && req.FTSTable == "" {
+			return nil, errors.New("sqlite full-text search requires a table name")
+		}
+		if req.FTSTable != "" && !fieldNameRegexp.MatchString(req.FTSTable) {
+			return nil, errors.Join(ErrInvalidField, errors.New(req.FTSTable))
+		}
+		return buildSQLite(table, req), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedDialect, sqlDialect)
+}
+
+func quoteColumn(quote byte, table, column string) string {
+	q := string(quote)
+	if table == "" {
+		return q + column + q
+	}
+	return q + table + q + "." + q + column + q
+}
+
+func formatWeight(w float64) string {
+	return strconv.FormatFloat(w, 'g', -1, 64)
+}
+
+func buildPostgres(table string, req *Request) *SQL {
+	lang := req.Language
+	if lang == "" {
+		lang = "simple"
+	}
+
+	labels := req.weightLabels()
+
+	// 各字段按权重标签合并为一个 tsvector
+	vectors := make([]string, 0, len(req.Fields))
+	var vectorArgs []any
+	for i, f := range req.Fields {
+		vectors = append(vectors, fmt.Sprintf(
+			"setweight(to_tsvector(?::regconfig, coalesce(%s::text, '')), '%c')",
+			quoteColumn('"', table, f.Name), labels[i],
+		))
+		vectorArgs = append(vectorArgs, lang)
+	}
+	vector := "(" + strings.Join(vectors, " || ") + ")"
+	query := "websearch_to_tsquery(?::regconfig, ?)"
+
+	out := &SQL{
+		Where:     vector + " @@ " + query,
+		WhereArgs: append(append([]any{}, vectorArgs...), lang, req.Query),
+	}
+
+	// ts_rank 的权重数组顺序为 {D, C, B, A}，取值须在 [0, 1] 内
+	var maxWeight float64
+	for _, f := range req.Fields {
+		maxWeight = max(maxWeight, f.weight())
+	}
+	rankWeights := []float64{0.1, 0.2, 0.4, 1}
+	for i, f := range req.Fields {
+		rankWeights[3-int(labels[i]-'A')] = f.weight() / maxWeight
+	}
+	parts := make([]string, 0, len(rankWeights))
+	for _, w := range rankWeights {
+		parts = append(parts, formatWeight(w))
+	}
+	out.Score = "ts_rank(?::float4[], " + vector + ", " + query + ")"
+	out.ScoreArgs = append(append([]any{"{" + strings.Join(parts, ",") + "}"}, vectorArgs...), lang, req.Query)
+
+	if h := req.HighlightOptions(); h != nil {
+		maxWords := max(h.FragmentSize/6, 10)
+		options := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=%d, MinWords=%d, MaxFragments=%d`,
+			strings.ReplaceAll(h.PreTag, `"`, `""`), strings.ReplaceAll(h.PostTag, `"`, `""`),
+			maxWords, maxWords/2, h.MaxFragments,
+		)
+
+		items := make([]string, 0, len(req.Fields))
+		for _, f := range req.Fields {
+			items = append(items, fmt.Sprintf(
+				"'%s', ts_headline(?::regconfig, coalesce(%s::text, ''), %s, ?)",
+				f.Name, quoteColumn('"', table, f.Name), query,
+			))
+			out.HighlightArgs = append(out.HighlightArgs, lang, lang, req.Query, options)
+		}
+		out.Highlight = "json_build_object(" + strings.Join(items, ", ") + ")::text"
+	}
+
+	return out
+}
+
+func buildMySQL(table string, req *Request) *SQL {
+	columns := make([]string, 0, len(req.Fields))
+	for _, f := range req.Fields {
+		columns = append(columns, quoteColumn('`', table, f.Name))
+	}
+	match := "MATCH(" + strings.Join(columns, ", ") + ") AGAINST(? IN BOOLEAN MODE)"
+
+	out := &SQL{
+		Where:     match,
+		WhereArgs: []any{req.Query},
+		Score:     match,
+		ScoreArgs: []any{req.Query},
+	}
+
+	if !req.uniformWeights() {
+		terms := make([]string, 0, len(req.Fields))
+		out.ScoreArgs = nil
+		for i, f := range req.Fields {
+			terms = append(terms, fmt.Sprintf("%s * MATCH(%s) AGAINST(? IN BOOLEAN MODE)", formatWeight(f.weight()), columns[i]))
+			out.ScoreArgs = append(out.ScoreArgs, req.Query)
+		}
+		out.Score = "(" + strings.Join(terms, " + ") + ")"
+	}
+
+	return out
+}
+
+// ftsQuery 将检索文本转为 FTS5 查询：各词项按短语匹配并限定在检索字段内
+func ftsQuery(req *Request) string {
+	terms := Terms(req.Query)
+	phrases := make([]string, 0, len(terms))
+	for _, t := range terms {
+		phrases = append(phrases, `"`+strings.ReplaceAll(t, `"`, `""`)+`"`)
+	}
+	if len(phrases) == 0 {
+		phrases = append(phrases, `""`)
+	}
+	return "{" + strings.Join(req.FieldNames(), " ") + "} : (" + strings.Join(phrases, " ") + ")"
+}
+
+func buildSQLite(table string, req *Request) *SQL {
+	fts := req.FTSTable
+	if fts == "" {
+		fts = table + "_fts"
+	}
+	qfts := `"` + fts + `"`
+	rowid := "rowid"
+	if table != "" {
+		rowid = `"` + table + `".rowid`
+	}
+	match := ftsQuery(req)
+	correlated := " FROM " + qfts + " WHERE " + qfts + " MATCH ? AND " + qfts + ".rowid = " + rowid + ")"
+
+	weights := make([]string, 0, len(req.Fields))
+	for _, f := range req.Fields {
+		weights = append(weights, formatWeight(f.weight()))
+	}
+
+	out := &SQL{
+		Where:     rowid + " IN (SELECT rowid FROM " + qfts + " WHERE " + qfts + " MATCH ?)",
+		WhereArgs: []any{match},
+		Score:     "(SELECT -bm25(" + qfts + ", " + strings.Join(weights, ", ") + ")" + correlated,
+		ScoreArgs: []any{match},
+	}
+
+	if h := req.HighlightOptions(); h != nil {
+		tokens := min(max(h.FragmentSize/6, 1), 64)
+		items := make([]string, 0, len(req.Fields))
+		for i, f := range req.Fields {
+			items = append(items, fmt.Sprintf("'%s', snippet(%s, %d, ?, ?, '...', %d)", f.Name, qfts, i, tokens))
+			out.HighlightArgs = append(out.HighlightArgs, h.PreTag, h.PostTag)
+		}
+		out.Highlight = "(SELECT json_object(" + strings.Join(items, ", ") + ")" + correlated
+		out.HighlightArgs = append(out.HighlightArgs, match)
+	}
+
+	return out
+}
@@ -0,0 +1,236 @@
+package slowlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMaxFingerprints 聚合器默认最多保留的指纹数
+const DefaultMaxFingerprints = 1000
+
+// Stats 同一指纹的聚合统计
+type Stats struct {
+	ID          string
+	Fingerprint string
+	Backend     string
+	Entity      string
+	Method      string
+
+	Count     int64
+	SlowCount int64
+	Errors    int64
+	TotalTime time.Duration
+	MaxTime   time.Duration
+	LastSeen  time.Time
+}
+
+// AvgTime 平均耗时
+func (s Stats) AvgTime() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalTime / time.Duration(s.Count)
+}
+
+// Aggregator 按指纹聚合调用次数与耗时，超过容量后新的指纹不再统计（计入 Dropped）
+type Aggregator struct {
+	mu      sync.Mutex
+	max     int
+	stats   map[string]*Stats
+	dropped int64
+}
+
+// NewAggregator 创建聚合器，max 小于等于 0 时使用 DefaultMaxFingerprints
+func NewAggregator(max int) *Aggregator {
+	if max <= 0 {
+		max = DefaultMaxFingerprints
+	}
+	return &Aggregator{
+		max:   max,
+		stats: make(map[string]*Stats),
+	}
+}
+
+func (a *Aggregator) add(e Entry, slow bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.stats[e.ID]
+	if !ok {
+		if len(a.stats) >= a.max {
+			a.dropped++
+			return
+		}
+		s = &Stats{
+			ID:          e.ID,
+			Fingerprint: e.Fingerprint,
+			Backend:     e.Backend,
+			Entity:      e.Entity,
+			Method:      e.Method,
+		}
+		a.stats[e.ID] = s
+	}
+
+	s.Count++
+	s.TotalTime += e.Duration
+	if e.Duration > s.MaxTime {
+		s.MaxTime = e.Duration
+	}
+	if slow {
+		s.SlowCount++
+	}
+	if e.Err != nil {
+		s.Errors++
+	}
+	s.LastSeen = e.Time
+}
+
+// Snapshot 返回所有指纹统计的副本
+func (a *Aggregator) Snapshot() []Stats {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := make([]Stats, 0, len(a.stats))
+	for _, s := range a.stats {
+		out = append(out, *s)
+	}
+	return out
+}
+
+// TopByTotalTime 返回总耗时最高的 n 个指纹，n 小于等于 0 时返回全部
+func (a *Aggregator) TopByTotalTime(n int) []Stats {
+	return a.top(n, func(x, y Stats) bool {
+		if x.TotalTime != y.TotalTime {
+			return x.TotalTime > y.TotalTime
+		}
+		return x.Count > y.Count
+	})
+}
+
+// TopByCount 返回调用次数最多的 n 个指纹，n 小于等于 0 时返回全部
+func (a *Aggregator) TopByCount(n int) []Stats {
+	return a.top(n, func(x, y Stats) bool {
+		if x.Count != y.Count {
+			return x.Count > y.Count
+		}
+		return x.TotalTime > y.TotalTime
+	})
+}
+
+func (a *Aggregator) top(n int, less func(x, y Stats) bool) []Stats {
+	out := a.Snapshot()
+	sort.Slice(out, func(i, j int) bool {
+		if less(out[i], out[j]) {
+			return true
+		}
+		if less(out[j], out[i]) {
+			return false
+		}
+		return out[i].ID < out[j].ID
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// Dropped 因超过容量而未统计的调用次数
+func (a *Aggregator) Dropped() int64 {
+	if a == nil {
+		return 0
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.dropped
+}
+
+// Reset 清空统计
+func (a *Aggregator) Reset() {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stats = make(map[string]*Stats)
+	a.dropped = 0
+}
+
+type statsView struct {
+	ID          string  `json:"id"`
+	Fingerprint string  `json:"fingerprint"`
+	Backend     string  `json:"backend"`
+	Entity      string  `json:"entity"`
+	Method      string  `json:"method"`
+	Count       int64   `json:"count"`
+	SlowCount   int64   `json:"slow_count"`
+	Errors      int64   `json:"errors"`
+	TotalMs     float64 `json:"total_ms"`
+	AvgMs       float64 `json:"avg_ms"`
+	MaxMs       float64 `json:"max_ms"`
+	LastSeen    string  `json:"last_seen"`
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// ServeHTTP 调试接口：以 JSON 返回 top-N 指纹。
+// 查询参数：sort=time（默认，按总耗时）或 count（按次数），n 为返回条数（默认 20，0 表示全部）。
+func (a *Aggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := 20
+	if v := r.URL.Query().Get("n"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+		n = parsed
+	}
+
+	var top []Stats
+	switch r.URL.Query().Get("sort") {
+	case "", "time":
+		top = a.TopByTotalTime(n)
+	case "count":
+		top = a.TopByCount(n)
+	default:
+		http.Error(w, "invalid sort, expected time or count", http.StatusBadRequest)
+		return
+	}
+
+	views := make([]statsView, 0, len(top))
+	for _, s := range top {
+		views = append(views, statsView{
+			ID:          s.ID,
+			Fingerprint: s.Fingerprint,
+			Backend:     s.Backend,
+			Entity:      s.Entity,
+			Method:      s.Method,
+			Count:       s.Count,
+			SlowCount:   s.SlowCount,
+			Errors:      s.Errors,
+			TotalMs:     milliseconds(s.TotalTime),
+			AvgMs:       milliseconds(s.AvgTime()),
+			MaxMs:       milliseconds(s.MaxTime),
+			LastSeen:    s.LastSeen.Format(time.RFC3339Nano),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"dropped":      a.Dropped(),
+		"fingerprints": views,
+	})
+}
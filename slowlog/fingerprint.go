@@ -0,0 +1,42 @@
+package slowlog
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/tx7do/go-crud/tracing"
+)
+
+var (
+	whitespace       = regexp.MustCompile(`\s+`)
+	placeholderList  = regexp.MustCompile(`\?(?:\s*,\s*\?)+`)
+	placeholderTuple = regexp.MustCompile(`\(\?\)(?:\s*,\s*\(\?\))+`)
+	jsonValueList    = regexp.MustCompile(`"\?"(?:,"\?")+`)
+)
+
+// Fingerprint 归一化 SQL/InfluxQL/CQL 语句：去除字面量、合并空白，并将 IN (?, ?, ?) 与多行 VALUES 折叠为单个占位符，
+// 使仅参数不同的语句得到相同的指纹
+func Fingerprint(stmt string) string {
+	stmt = tracing.SanitizeSQL(stmt)
+	stmt = strings.TrimSpace(whitespace.ReplaceAllString(stmt, " "))
+	stmt = placeholderList.ReplaceAllString(stmt, "?")
+	return placeholderTuple.ReplaceAllString(stmt, "(?)")
+}
+
+// FingerprintJSON 归一化 JSON 查询（MongoDB 过滤条件、Elasticsearch Query DSL）：保留结构与键名，
+// 所有值替换为 "?" 并折叠数组；无法解析时返回空串
+func FingerprintJSON(doc []byte) string {
+	stmt := tracing.SanitizeJSON(doc)
+	return jsonValueList.ReplaceAllString(stmt, `"?"`)
+}
+
+// fingerprintID 指纹的短哈希，用于在日志与调试接口中引用同一类语句
+func fingerprintID(backend, fingerprint string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(backend))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(fingerprint))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
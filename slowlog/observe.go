@@ -0,0 +1,36 @@
+package slowlog
+
+import (
+	"github.com/tx7do/go-crud/metrics"
+)
+
+// Observation 一次仓库操作的观测，同时记录指标与慢查询。
+// 内嵌的 *Call 用于记录语句与分页请求，未启用慢查询日志时为 nil，方法均可安全调用
+type Observation struct {
+	*Call
+	metrics *metrics.Observation
+}
+
+// Observe 开始观测一次仓库操作，m 与 l 均可为 nil
+func Observe(m *metrics.Metrics, l *Logger, backend, entity, method string) *Observation {
+	return &Observation{
+		Call:    l.Start(backend, entity, method),
+		metrics: m.Start(backend, entity, method),
+	}
+}
+
+// ReturnedRows 记录返回的行数
+func (o *Observation) ReturnedRows(n int64) {
+	o.metrics.ReturnedRows(n)
+}
+
+// AffectedRows 记录写入/更新/删除影响的行数
+func (o *Observation) AffectedRows(n int64) {
+	o.metrics.AffectedRows(n)
+}
+
+// End 结束观测，记录耗时与错误
+func (o *Observation) End(err error) {
+	o.metrics.End(err)
+	o.Call.End(err)
+}
@@ -0,0 +1,294 @@
+package slowlog
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/attribute"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+	"github.com/tx7do/go-crud/tracing"
+)
+
+// DefaultThreshold 默认的慢查询阈值
+const DefaultThreshold = 200 * time.Millisecond
+
+// modulePrefix 解析调用方时跳过本模块内的栈帧
+const modulePrefix = "github.com/tx7do/go-crud"
+
+// Entry 一次慢查询记录，语句已去除字面量，参数只记录个数
+type Entry struct {
+	Time     time.Time
+	Backend  string
+	Entity   string
+	Method   string
+	Duration time.Duration
+	Err      error
+
+	// ID 指纹的短哈希
+	ID string
+	// Fingerprint 归一化后的语句；未记录语句时为方法名加分页请求的结构（过滤字段、排序）
+	Fingerprint string
+	// Args 绑定参数的个数，参数值不会被记录
+	Args int
+	// Paging 分页请求摘要，不包含过滤值
+	Paging string
+	// Caller 本模块之外的第一个调用方（function file:line）
+	Caller string
+}
+
+func (e Entry) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "slow query [%s] %s %s.%s took %s", e.ID, e.Backend, e.Entity, e.Method, e.Duration)
+	fmt.Fprintf(&sb, " fingerprint=%q", e.Fingerprint)
+	if e.Args > 0 {
+		fmt.Fprintf(&sb, " args=%d", e.Args)
+	}
+	if e.Paging != "" {
+		fmt.Fprintf(&sb, " paging=%q", e.Paging)
+	}
+	if e.Caller != "" {
+		fmt.Fprintf(&sb, " caller=%s", e.Caller)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&sb, " error=%q", e.Err.Error())
+	}
+	return sb.String()
+}
+
+// Option 配置 Logger
+type Option func(l *Logger)
+
+// WithThreshold 设置慢查询阈值，默认 DefaultThreshold；小于等于 0 时记录所有调用
+func WithThreshold(d time.Duration) Option {
+	return func(l *Logger) {
+		l.threshold = d
+	}
+}
+
+// WithLogger 设置默认 handler 使用的日志，默认为 log.DefaultLogger
+func WithLogger(logger log.Logger) Option {
+	return func(l *Logger) {
+		l.log = log.NewHelper(log.With(logger, "module", "slowlog"))
+	}
+}
+
+// WithHandler 设置慢查询的输出方式，默认以 Warn 级别输出 Entry.String()
+func WithHandler(handler func(e Entry)) Option {
+	return func(l *Logger) {
+		if handler != nil {
+			l.handler = handler
+		}
+	}
+}
+
+// WithMaxFingerprints 设置聚合器最多保留的指纹数，默认 DefaultMaxFingerprints
+func WithMaxFingerprints(n int) Option {
+	return func(l *Logger) {
+		l.aggregator = NewAggregator(n)
+	}
+}
+
+// Logger 慢查询日志：所有调用按指纹聚合，超过阈值的调用交给 handler 输出。
+// nil Logger 表示未启用，Start 返回 nil Call，Call 的方法均可安全调用。
+type Logger struct {
+	threshold  time.Duration
+	handler    func(e Entry)
+	aggregator *Aggregator
+	log        *log.Helper
+}
+
+// New 创建慢查询日志
+func New(opts ...Option) *Logger {
+	l := &Logger{
+		threshold: DefaultThreshold,
+		log:       log.NewHelper(log.With(log.DefaultLogger, "module", "slowlog")),
+	}
+	l.handler = func(e Entry) {
+		l.log.Warn(e.String())
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.aggregator == nil {
+		l.aggregator = NewAggregator(DefaultMaxFingerprints)
+	}
+	return l
+}
+
+// Aggregator 返回按指纹聚合的统计
+func (l *Logger) Aggregator() *Aggregator {
+	if l == nil {
+		return nil
+	}
+	return l.aggregator
+}
+
+// Handler 返回调试 HTTP 接口，见 Aggregator.ServeHTTP
+func (l *Logger) Handler() http.Handler {
+	return l.Aggregator()
+}
+
+// Start 开始记录一次仓库调用，结束时调用 Call.End
+func (l *Logger) Start(backend, entity, method string) *Call {
+	if l == nil {
+		return nil
+	}
+	return &Call{
+		logger:  l,
+		backend: backend,
+		entity:  entity,
+		method:  method,
+		start:   time.Now(),
+	}
+}
+
+// Call 一次仓库调用的慢查询记录，nil Call 的方法均为空操作
+type Call struct {
+	logger *Logger
+
+	backend string
+	entity  string
+	method  string
+	start   time.Time
+
+	fingerprint string
+	args        int
+	paging      string
+	shape       string
+	filter      string
+	filterShape string
+}
+
+// SetStatement 记录执行的 SQL/InfluxQL/CQL 语句，参数只记录个数
+func (c *Call) SetStatement(stmt string, args ...any) {
+	if c == nil || stmt == "" {
+		return
+	}
+	c.fingerprint = Fingerprint(stmt)
+	c.args = len(args)
+}
+
+// SetJSONStatement 记录执行的 JSON 查询（MongoDB 过滤条件、Elasticsearch Query DSL）
+func (c *Call) SetJSONStatement(doc []byte) {
+	if c == nil || len(doc) == 0 {
+		return
+	}
+	c.fingerprint = FingerprintJSON(doc)
+}
+
+// SetPagingRequest 记录分页请求摘要
+func (c *Call) SetPagingRequest(req *paginationV1.PagingRequest) {
+	if c == nil || req == nil {
+		return
+	}
+	c.paging, c.shape = summarize(tracing.PagingAttributes(req))
+}
+
+// SetPaginationRequest 记录 PaginationRequest 摘要
+func (c *Call) SetPaginationRequest(req *paginationV1.PaginationRequest) {
+	if c == nil || req == nil {
+		return
+	}
+	c.paging, c.shape = summarize(tracing.PaginationAttributes(req))
+}
+
+// SetFilterExpr 记录过滤条件摘要（字段与条件数），追加在分页摘要之后
+func (c *Call) SetFilterExpr(expr *paginationV1.FilterExpr) {
+	if c == nil || expr == nil {
+		return
+	}
+	c.filter, c.filterShape = summarize(tracing.FilterAttributes(expr))
+}
+
+// summarize 生成摘要；分页位置之外的部分（过滤字段、排序等）作为请求结构，用于未记录语句时的指纹
+func summarize(attrs []attribute.KeyValue) (summary, shape string) {
+	items := make([]string, 0, len(attrs))
+	var shapeItems []string
+	for _, kv := range attrs {
+		item := strings.TrimPrefix(string(kv.Key), "db.") + "=" + kv.Value.Emit()
+		items = append(items, item)
+
+		switch kv.Key {
+		case tracing.PagingPageKey, tracing.PagingPageSizeKey, tracing.PagingOffsetKey, tracing.PagingLimitKey, tracing.FilterConditionsKey:
+		default:
+			shapeItems = append(shapeItems, item)
+		}
+	}
+	return strings.Join(items, " "), strings.Join(shapeItems, " ")
+}
+
+// joinSummary 以空格连接非空的摘要片段
+func joinSummary(parts ...string) string {
+	out := parts[:0:0]
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// End 结束记录：计入聚合统计，耗时达到阈值时输出慢查询日志
+func (c *Call) End(err error) {
+	if c == nil {
+		return
+	}
+
+	d := time.Since(c.start)
+	slow := d >= c.logger.threshold
+
+	fingerprint := c.fingerprint
+	if fingerprint == "" {
+		fingerprint = joinSummary(c.method, c.shape, c.filterShape)
+	}
+
+	e := Entry{
+		Time:        c.start,
+		Backend:     c.backend,
+		Entity:      c.entity,
+		Method:      c.method,
+		Duration:    d,
+		Err:         err,
+		ID:          fingerprintID(c.backend, fingerprint),
+		Fingerprint: fingerprint,
+		Args:        c.args,
+		Paging:      joinSummary(c.paging, c.filter),
+	}
+
+	c.logger.aggregator.add(e, slow)
+
+	if slow {
+		e.Caller = caller()
+		c.logger.handler(e)
+	}
+}
+
+// caller 返回本模块之外（或测试文件中）的第一个栈帧
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !isModuleFunc(f.Function) || strings.HasSuffix(f.File, "_test.go") {
+			if f.Function != "" && !strings.HasPrefix(f.Function, "runtime.") {
+				return fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line)
+			}
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// isModuleFunc 判断函数是否属于本模块：根包为 modulePrefix + "."，子包为 modulePrefix + "/"，
+// 不匹配 github.com/tx7do/go-crud-example 等同前缀的其它模块
+func isModuleFunc(fn string) bool {
+	rest, ok := strings.CutPrefix(fn, modulePrefix)
+	return ok && (strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, "."))
+}
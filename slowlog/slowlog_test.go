@@ -0,0 +1,307 @@
+package slowlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+func TestFingerprint(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{
+			"SELECT *  FROM users\n WHERE name = 'bob' AND age > 30",
+			"SELECT * FROM users WHERE name = ? AND age > ?",
+		},
+		{
+			"SELECT * FROM users WHERE id IN (1, 2, 3)",
+			"SELECT * FROM users WHERE id IN (?)",
+		},
+		{
+			"SELECT * FROM users WHERE id IN (?,?)",
+			"SELECT * FROM users WHERE id IN (?)",
+		},
+		{
+			"INSERT INTO t (a) VALUES (1), (2), (3)",
+			"INSERT INTO t (a) VALUES (?)",
+		},
+	}
+	for _, c := range cases {
+		if got := Fingerprint(c.in); got != c.want {
+			t.Errorf("Fingerprint(%q): expected %q, got %q", c.in, c.want, got)
+		}
+	}
+
+	if Fingerprint("SELECT * FROM t WHERE id = 1") != Fingerprint("SELECT * FROM t WHERE id = 42") {
+		t.Error("statements differing only in literals should share a fingerprint")
+	}
+}
+
+func TestFingerprintJSON(t *testing.T) {
+	got := FingerprintJSON([]byte(`{"age":{"$in":[1,2,3]},"name":"bob"}`))
+	want := `{"age":{"$in":["?"]},"name":"?"}`
+	if got != want {
+		t.Errorf("FingerprintJSON: expected %q, got %q", want, got)
+	}
+}
+
+func TestLogger_SlowQuery(t *testing.T) {
+	var entries []Entry
+	l := New(WithThreshold(0), WithHandler(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	page, size := uint32(2), uint32(20)
+	call := l.Start("clickhouse", "User", "ListWithPaging")
+	call.SetStatement("SELECT * FROM users WHERE name = 'bob' LIMIT 20", "secret")
+	call.SetPagingRequest(&paginationV1.PagingRequest{Page: &page, PageSize: &size})
+	call.End(errors.New("boom"))
+
+	if got := len(entries); got != 1 {
+		t.Fatalf("len(entries): expected 1, got %d", got)
+	}
+	e := entries[0]
+	if got := e.Fingerprint; got != "SELECT * FROM users WHERE name = ? LIMIT ?" {
+		t.Errorf("Fingerprint: got %q", got)
+	}
+	if got := e.Args; got != 1 {
+		t.Errorf("Args: expected 1, got %d", got)
+	}
+	if !strings.Contains(e.Paging, "paging.page=2") || !strings.Contains(e.Paging, "paging.page_size=20") {
+		t.Errorf("Paging: got %q", e.Paging)
+	}
+	if !strings.Contains(e.Caller, "TestLogger_SlowQuery") {
+		t.Errorf("Caller: expected the test function, got %q", e.Caller)
+	}
+	if s := e.String(); strings.Contains(s, "secret") || strings.Contains(s, "bob") {
+		t.Errorf("entry leaks values: %s", s)
+	}
+}
+
+func TestCall_FilterExprKeepsPaging(t *testing.T) {
+	var entries []Entry
+	l := New(WithThreshold(0), WithHandler(func(e Entry) {
+		entries = append(entries, e)
+	}))
+
+	page := uint32(3)
+	value := "10"
+	call := l.Start("gorm", "User", "ListWithPaging")
+	call.SetPagingRequest(&paginationV1.PagingRequest{Page: &page})
+	call.SetFilterExpr(&paginationV1.FilterExpr{
+		Type:       paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{{Field: "age", Op: paginationV1.Operator_GT, Value: &value}},
+	})
+	call.End(nil)
+
+	if got := len(entries); got != 1 {
+		t.Fatalf("len(entries): expected 1, got %d", got)
+	}
+	if p := entries[0].Paging; !strings.Contains(p, "paging.page=3") || !strings.Contains(p, "filter.fields=") {
+		t.Errorf("Paging: expected paging and filter summary, got %q", p)
+	}
+}
+
+func TestLogger_Threshold(t *testing.T) {
+	logged := 0
+	l := New(WithThreshold(time.Hour), WithHandler(func(Entry) { logged++ }))
+
+	call := l.Start("gorm", "User", "Get")
+	call.End(nil)
+
+	if logged != 0 {
+		t.Errorf("logged: expected 0, got %d", logged)
+	}
+	stats := l.Aggregator().Snapshot()
+	if got := len(stats); got != 1 {
+		t.Fatalf("len(stats): expected 1, got %d", got)
+	}
+	if got := stats[0].Fingerprint; got != "Get" {
+		t.Errorf("Fingerprint: expected %q, got %q", "Get", got)
+	}
+	if got := stats[0].SlowCount; got != 0 {
+		t.Errorf("SlowCount: expected 0, got %d", got)
+	}
+}
+
+func TestLogger_ShapeFingerprint(t *testing.T) {
+	l := New(WithHandler(func(Entry) {}))
+
+	expr := func(value string) *paginationV1.FilterExpr {
+		return &paginationV1.FilterExpr{
+			Type: paginationV1.ExprType_AND,
+			Conditions: []*paginationV1.Condition{
+				{Field: "age", Op: paginationV1.Operator_GT, Value: &value},
+			},
+		}
+	}
+
+	for i, v := range []string{"10", "20"} {
+		page := uint32(i + 1)
+		call := l.Start("ent", "User", "ListWithPaging")
+		call.SetPagingRequest(&paginationV1.PagingRequest{Page: &page, FilterExpr: expr(v)})
+		call.End(nil)
+	}
+
+	stats := l.Aggregator().Snapshot()
+	if got := len(stats); got != 1 {
+		t.Fatalf("len(stats): expected 1, got %d: %+v", got, stats)
+	}
+	if got := stats[0].Count; got != 2 {
+		t.Errorf("Count: expected 2, got %d", got)
+	}
+	if !strings.Contains(stats[0].Fingerprint, "age") {
+		t.Errorf("Fingerprint: expected filter fields, got %q", stats[0].Fingerprint)
+	}
+}
+
+func TestAggregator_Top(t *testing.T) {
+	a := NewAggregator(2)
+	a.add(Entry{ID: "a", Duration: 10 * time.Millisecond}, false)
+	a.add(Entry{ID: "a", Duration: 10 * time.Millisecond}, false)
+	a.add(Entry{ID: "a", Duration: 10 * time.Millisecond}, false)
+	a.add(Entry{ID: "b", Duration: time.Second}, true)
+	a.add(Entry{ID: "c", Duration: time.Second}, true)
+
+	if got := a.Dropped(); got != 1 {
+		t.Errorf("Dropped: expected 1, got %d", got)
+	}
+
+	byTime := a.TopByTotalTime(1)
+	if len(byTime) != 1 || byTime[0].ID != "b" {
+		t.Errorf("TopByTotalTime: expected b, got %+v", byTime)
+	}
+	byCount := a.TopByCount(0)
+	if len(byCount) != 2 || byCount[0].ID != "a" {
+		t.Errorf("TopByCount: expected a first, got %+v", byCount)
+	}
+	if got := byCount[0].AvgTime(); got != 10*time.Millisecond {
+		t.Errorf("AvgTime: expected 10ms, got %s", got)
+	}
+
+	a.Reset()
+	if got := len(a.Snapshot()); got != 0 {
+		t.Errorf("len(Snapshot) after Reset: expected 0, got %d", got)
+	}
+}
+
+func TestAggregator_ServeHTTP(t *testing.T) {
+	l := New(WithThreshold(time.Hour))
+	for i := 0; i < 3; i++ {
+		call := l.Start("mongodb", "User", "Count")
+		call.SetJSONStatement([]byte(`{"age":1}`))
+		call.End(nil)
+	}
+	call := l.Start("mongodb", "User", "Get")
+	call.End(nil)
+
+	rec := httptest.NewRecorder()
+	l.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/slowlog?sort=count&n=1", nil))
+
+	var body struct {
+		Fingerprints []statsView `json:"fingerprints"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got := len(body.Fingerprints); got != 1 {
+		t.Fatalf("len(fingerprints): expected 1, got %d", got)
+	}
+	if got := body.Fingerprints[0].Count; got != 3 {
+		t.Errorf("count: expected 3, got %d", got)
+	}
+	if got := body.Fingerprints[0].Fingerprint; got != `{"age":"?"}` {
+		t.Errorf("fingerprint: expected %q, got %q", `{"age":"?"}`, got)
+	}
+
+	rec = httptest.NewRecorder()
+	l.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/slowlog?sort=bogus", nil))
+	if rec.Code != 400 {
+		t.Errorf("status: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestLogger_Nil(t *testing.T) {
+	var l *Logger
+	call := l.Start("gorm", "User", "Get")
+	call.SetStatement("SELECT 1")
+	call.SetPagingRequest(&paginationV1.PagingRequest{})
+	call.End(nil)
+
+	rec := httptest.NewRecorder()
+	l.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != 200 {
+		t.Errorf("status: expected 200, got %d", rec.Code)
+	}
+}
+
+type captureLogger struct {
+	lines []string
+}
+
+func (c *captureLogger) Log(level log.Level, keyvals ...any) error {
+	c.lines = append(c.lines, fmt.Sprint(append([]any{level.String()}, keyvals...)...))
+	return nil
+}
+
+func TestLogger_DefaultHandler(t *testing.T) {
+	logger := &captureLogger{}
+	l := New(WithThreshold(0), WithLogger(logger))
+
+	call := l.Start("gorm", "User", "Get")
+	call.End(nil)
+
+	if got := len(logger.lines); got != 1 {
+		t.Fatalf("len(lines): expected 1, got %d", got)
+	}
+	if line := logger.lines[0]; !strings.HasPrefix(line, "WARN") || !strings.Contains(line, "slow query") {
+		t.Errorf("line: got %q", line)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	// 未配置指标与慢查询日志时为空操作
+	obs := Observe(nil, nil, "gorm", "User", "Get")
+	obs.SetStatement("SELECT 1")
+	obs.ReturnedRows(1)
+	obs.End(nil)
+
+	var entries []Entry
+	l := New(WithThreshold(0), WithHandler(func(e Entry) { entries = append(entries, e) }))
+	obs = Observe(nil, l, "gorm", "User", "Get")
+	obs.SetStatement("SELECT * FROM users WHERE id = 1")
+	obs.End(nil)
+
+	if got := len(entries); got != 1 {
+		t.Fatalf("len(entries): expected 1, got %d", got)
+	}
+	if got := entries[0].Fingerprint; got != "SELECT * FROM users WHERE id = ?" {
+		t.Errorf("Fingerprint: got %q", got)
+	}
+}
+
+func TestIsModuleFunc(t *testing.T) {
+	cases := map[string]bool{
+		"github.com/tx7do/go-crud.Paging":                       true,
+		"github.com/tx7do/go-crud/gorm.(*Repository[...]).List": true,
+		"github.com/tx7do/go-crud/slowlog.(*Call).End":          true,
+		"github.com/tx7do/go-crud-example/service.(*User).List": false,
+		"github.com/tx7do/go-crudx.Handler":                     false,
+		"main.main":                                             false,
+	}
+	for fn, want := range cases {
+		if got := isModuleFunc(fn); got != want {
+			t.Errorf("isModuleFunc(%q): expected %v, got %v", fn, want, got)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+const (
+	// AffectedRowsKey 写入/更新/删除影响的行数
+	AffectedRowsKey = attribute.Key("db.response.affected_rows")
+
+	PagingPageKey     = attribute.Key("db.paging.page")
+	PagingPageSizeKey = attribute.Key("db.paging.page_size")
+	PagingOffsetKey   = attribute.Key("db.paging.offset")
+	PagingLimitKey    = attribute.Key("db.paging.limit")
+	PagingNoPagingKey = attribute.Key("db.paging.no_paging")
+	PagingSortingKey  = attribute.Key("db.paging.sorting")
+
+	// FilterQueryKey 是否使用了 Query/OrQuery 查询串
+	FilterQueryKey = attribute.Key("db.filter.query")
+	// FilterFieldsKey FilterExpr 中出现的字段（去重排序，不含值）
+	FilterFieldsKey = attribute.Key("db.filter.fields")
+	// FilterConditionsKey FilterExpr 中的条件总数
+	FilterConditionsKey = attribute.Key("db.filter.conditions")
+)
+
+// PagingAttributes 汇总分页请求的分页、排序与过滤信息，不包含任何过滤值
+func PagingAttributes(req *paginationV1.PagingRequest) []attribute.KeyValue {
+	if req == nil {
+		return nil
+	}
+
+	var attrs []attribute.KeyValue
+	if req.GetNoPaging() {
+		attrs = append(attrs, PagingNoPagingKey.Bool(true))
+	}
+	if req.Page != nil {
+		attrs = append(attrs, PagingPageKey.Int64(int64(req.GetPage())))
+	}
+	if req.PageSize != nil {
+		attrs = append(attrs, PagingPageSizeKey.Int64(int64(req.GetPageSize())))
+	}
+	if req.Offset != nil {
+		attrs = append(attrs, PagingOffsetKey.Int64(int64(req.GetOffset())))
+	}
+	if req.Limit != nil {
+		attrs = append(attrs, PagingLimitKey.Int64(int64(req.GetLimit())))
+	}
+
+	return append(attrs, queryAttributes(req.GetSorting(), req.GetOrderBy(), req.GetQuery(), req.GetOrQuery(), req.GetFilterExpr())...)
+}
+
+// PaginationAttributes 汇总 PaginationRequest 的分页、排序与过滤信息，不包含任何过滤值
+func PaginationAttributes(req *paginationV1.PaginationRequest) []attribute.KeyValue {
+	if req == nil {
+		return nil
+	}
+
+	var attrs []attribute.KeyValue
+	switch req.GetPaginationType().(type) {
+	case *paginationV1.PaginationRequest_PageBased:
+		attrs = append(attrs,
+			PagingPageKey.Int64(int64(req.GetPageBased().GetPage())),
+			PagingPageSizeKey.Int64(int64(req.GetPageBased().GetPageSize())),
+		)
+	case *paginationV1.PaginationRequest_OffsetBased:
+		attrs = append(attrs,
+			PagingOffsetKey.Int64(int64(req.GetOffsetBased().GetOffset())),
+			PagingLimitKey.Int64(int64(req.GetOffsetBased().GetLimit())),
+		)
+	case *paginationV1.PaginationRequest_TokenBased:
+		attrs = append(attrs, PagingPageSizeKey.Int64(int64(req.GetTokenBased().GetPageSize())))
+	case *paginationV1.PaginationRequest_NoPaging:
+		attrs = append(attrs, PagingNoPagingKey.Bool(true))
+	}
+
+	return append(attrs, queryAttributes(req.GetSorting(), req.GetOrderBy(), req.GetQuery(), req.GetOrQuery(), req.GetFilterExpr())...)
+}
+
+func queryAttributes(sorting []*paginationV1.Sorting, orderBy []string, query, orQuery string, expr *paginationV1.FilterExpr) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+
+	var sorts []string
+	for _, s := range sorting {
+		sorts = append(sorts, s.GetField()+":"+s.GetOrder().String())
+	}
+	sorts = append(sorts, orderBy...)
+	if len(sorts) > 0 {
+		attrs = append(attrs, PagingSortingKey.StringSlice(sorts))
+	}
+
+	if query != "" || orQuery != "" {
+		attrs = append(attrs, FilterQueryKey.Bool(true))
+	}
+
+	return append(attrs, FilterAttributes(expr)...)
+}
+
+// FilterAttributes 汇总 FilterExpr 的字段与条件数，不包含任何过滤值
+func FilterAttributes(expr *paginationV1.FilterExpr) []attribute.KeyValue {
+	if expr == nil {
+		return nil
+	}
+
+	fields := map[string]struct{}{}
+	var count int64
+
+	var walk func(e *paginationV1.FilterExpr)
+	walk = func(e *paginationV1.FilterExpr) {
+		if e == nil {
+			return
+		}
+		for _, cond := range e.GetConditions() {
+			if cond.GetField() == "" {
+				continue
+			}
+			fields[cond.GetField()] = struct{}{}
+			count++
+		}
+		for _, g := range e.GetGroups() {
+			walk(g)
+		}
+	}
+	walk(expr)
+
+	if count == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(fields))
+	for f := range fields {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+
+	return []attribute.KeyValue{
+		FilterFieldsKey.StringSlice(names),
+		FilterConditionsKey.Int64(count),
+	}
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var (
+	sqlStringLiteral  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	sqlNumericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+)
+
+// SanitizeSQL 将语句中的字符串与数字字面量替换为 ?，避免在 span 中泄露数据
+func SanitizeSQL(stmt string) string {
+	stmt = sqlStringLiteral.ReplaceAllString(stmt, "?")
+	return sqlNumericLiteral.ReplaceAllString(stmt, "?")
+}
+
+// SanitizeJSON 保留 JSON 文档的结构与键名，将所有标量值替换为 "?"；无法解析时返回空串
+func SanitizeJSON(doc []byte) string {
+	var v any
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return ""
+	}
+
+	out, err := json.Marshal(sanitizeValue(v))
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+func sanitizeValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, item := range t {
+			t[k] = sanitizeValue(item)
+		}
+		return t
+	case []any:
+		for i, item := range t {
+			t[i] = sanitizeValue(item)
+		}
+		return t
+	default:
+		return "?"
+	}
+}
+
+var identQuotes = strings.NewReplacer("`", "", `"`, "")
+
+var sqlTarget = regexp.MustCompile("(?i)\\b(?:FROM|INTO|UPDATE|TABLE)\\s+([`\"\\w.]+)")
+
+// ParseStatement 从 SQL/InfluxQL/CQL 语句中解析操作（首个关键字）与目标表名，无法解析时返回空串
+func ParseStatement(stmt string) (operation, collection string) {
+	fields := strings.Fields(stmt)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	operation = strings.ToUpper(fields[0])
+
+	if m := sqlTarget.FindStringSubmatch(stmt); m != nil {
+		collection = identQuotes.Replace(m[1])
+	}
+	return operation, collection
+}
+
+// SliceLen 返回切片（或指向切片的指针）的长度，用于记录返回的行数；非切片时返回 0
+func SliceLen(v any) int64 {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice {
+		return 0
+	}
+	return int64(rv.Len())
+}
@@ -0,0 +1,194 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName 创建 Tracer 使用的 instrumentation 名称
+const InstrumentationName = "github.com/tx7do/go-crud"
+
+// Option 配置 Tracer
+type Option func(t *Tracer)
+
+// WithTracerProvider 指定 TracerProvider，未指定时使用 otel 全局 TracerProvider
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(t *Tracer) {
+		if provider != nil {
+			t.provider = provider
+		}
+	}
+}
+
+// WithAttributes 为每个 span 附加固定属性
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(t *Tracer) {
+		t.attrs = append(t.attrs, attrs...)
+	}
+}
+
+// WithDBSystem 覆盖 db.system.name 属性
+func WithDBSystem(name string) Option {
+	return func(t *Tracer) {
+		t.dbSystem = name
+	}
+}
+
+// WithNamespace 设置 db.namespace 属性（数据库/keyspace 名称）
+func WithNamespace(name string) Option {
+	return func(t *Tracer) {
+		if name != "" {
+			t.attrs = append(t.attrs, semconv.DBNamespaceKey.String(name))
+		}
+	}
+}
+
+// WithoutStatement 不记录 db.query.text
+func WithoutStatement() Option {
+	return func(t *Tracer) {
+		t.withoutStatement = true
+	}
+}
+
+// Tracer 为数据库客户端与仓库创建统一格式的 span。
+// nil Tracer 表示未启用追踪，Start 返回 nil Span，Span 的方法均可安全调用。
+type Tracer struct {
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+
+	dbSystem         string
+	attrs            []attribute.KeyValue
+	withoutStatement bool
+}
+
+// NewTracer 创建 Tracer，dbSystem 为 db.system.name 的默认值（如 mongodb、clickhouse）
+func NewTracer(dbSystem string, opts ...Option) *Tracer {
+	t := &Tracer{
+		dbSystem: dbSystem,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.provider == nil {
+		t.provider = otel.GetTracerProvider()
+	}
+	t.tracer = t.provider.Tracer(InstrumentationName)
+
+	return t
+}
+
+// Start 开始一个 span，名称为 "operation collection"（collection 为空时仅为 operation）
+func (t *Tracer) Start(ctx context.Context, operation, collection string, attrs ...attribute.KeyValue) (context.Context, *Span) {
+	return t.start(ctx, operation, collection, attrs)
+}
+
+// StartAt 以指定的开始时间开始一个 span，用于由驱动回调（如 gocql QueryObserver）事后补记的操作
+func (t *Tracer) StartAt(ctx context.Context, start time.Time, operation, collection string, attrs ...attribute.KeyValue) (context.Context, *Span) {
+	return t.start(ctx, operation, collection, attrs, trace.WithTimestamp(start))
+}
+
+func (t *Tracer) start(ctx context.Context, operation, collection string, attrs []attribute.KeyValue, extra ...trace.SpanStartOption) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	name := operation
+	if collection != "" {
+		name = operation + " " + collection
+	}
+
+	all := make([]attribute.KeyValue, 0, len(t.attrs)+len(attrs)+3)
+	all = append(all,
+		semconv.DBSystemNameKey.String(t.dbSystem),
+		semconv.DBOperationNameKey.String(operation),
+	)
+	if collection != "" {
+		all = append(all, semconv.DBCollectionNameKey.String(collection))
+	}
+	all = append(all, t.attrs...)
+	all = append(all, attrs...)
+
+	startOpts := append([]trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(all...),
+	}, extra...)
+
+	ctx, span := t.tracer.Start(ctx, name, startOpts...)
+	return ctx, &Span{span: span, tracer: t}
+}
+
+// Span 对 trace.Span 的封装，nil Span 的方法均为空操作
+type Span struct {
+	span   trace.Span
+	tracer *Tracer
+}
+
+// SetStatement 记录脱敏后的 SQL/InfluxQL/CQL 语句
+func (s *Span) SetStatement(stmt string) {
+	if s == nil || s.tracer.withoutStatement || stmt == "" {
+		return
+	}
+	s.span.SetAttributes(semconv.DBQueryTextKey.String(SanitizeSQL(stmt)))
+}
+
+// SetJSONStatement 记录脱敏后的 JSON 查询（MongoDB 过滤条件、Elasticsearch Query DSL）
+func (s *Span) SetJSONStatement(doc []byte) {
+	if s == nil || s.tracer.withoutStatement || len(doc) == 0 {
+		return
+	}
+	if stmt := SanitizeJSON(doc); stmt != "" {
+		s.span.SetAttributes(semconv.DBQueryTextKey.String(stmt))
+	}
+}
+
+// SetReturnedRows 记录返回的行数
+func (s *Span) SetReturnedRows(n int64) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(semconv.DBResponseReturnedRowsKey.Int64(n))
+}
+
+// SetAffectedRows 记录写入/更新/删除影响的行数
+func (s *Span) SetAffectedRows(n int64) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(AffectedRowsKey.Int64(n))
+}
+
+// SetAttributes 设置额外属性
+func (s *Span) SetAttributes(attrs ...attribute.KeyValue) {
+	if s == nil || len(attrs) == 0 {
+		return
+	}
+	s.span.SetAttributes(attrs...)
+}
+
+// End 结束 span，err 不为空时记录错误并将状态置为 Error
+func (s *Span) End(err error) {
+	s.end(err)
+}
+
+// EndAt 以指定的结束时间结束 span
+func (s *Span) EndAt(err error, end time.Time) {
+	s.end(err, trace.WithTimestamp(end))
+}
+
+func (s *Span) end(err error, opts ...trace.SpanEndOption) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End(opts...)
+}
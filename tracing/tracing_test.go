@@ -0,0 +1,255 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	paginationV1 "github.com/tx7do/go-crud/api/gen/go/pagination/v1"
+)
+
+type recordedSpan struct {
+	noop.Span
+	name   string
+	attrs  map[attribute.Key]attribute.Value
+	status codes.Code
+	ended  bool
+	start  time.Time
+	end    time.Time
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, a := range kv {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *recordedSpan) End(opts ...trace.SpanEndOption) {
+	s.ended = true
+	cfg := trace.NewSpanEndConfig(opts...)
+	s.end = cfg.Timestamp()
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	spans *[]*recordedSpan
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	s := &recordedSpan{name: name, attrs: map[attribute.Key]attribute.Value{}}
+	cfg := trace.NewSpanStartConfig(opts...)
+	s.SetAttributes(cfg.Attributes()...)
+	s.start = cfg.Timestamp()
+	*t.spans = append(*t.spans, s)
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+type recordingProvider struct {
+	noop.TracerProvider
+	spans []*recordedSpan
+}
+
+func (p *recordingProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{spans: &p.spans}
+}
+
+func TestTracer_Span(t *testing.T) {
+	provider := &recordingProvider{}
+	tracer := NewTracer("clickhouse",
+		WithTracerProvider(provider),
+		WithAttributes(attribute.String("service", "test")),
+	)
+
+	_, span := tracer.Start(context.Background(), "SELECT", "users", PagingAttributes(&paginationV1.PagingRequest{
+		Page:     ptr(uint32(2)),
+		PageSize: ptr(uint32(10)),
+	})...)
+	span.SetStatement("SELECT * FROM users WHERE name = 'alice' AND age > 30 LIMIT 10")
+	span.SetReturnedRows(3)
+	span.End(errors.New("boom"))
+
+	if got := len(provider.spans); got != 1 {
+		t.Fatalf("len(spans): expected 1, got %d", got)
+	}
+	s := provider.spans[0]
+	if s.name != "SELECT users" {
+		t.Errorf("span name: expected %q, got %q", "SELECT users", s.name)
+	}
+	if !s.ended {
+		t.Errorf("span should be ended")
+	}
+	if s.status != codes.Error {
+		t.Errorf("span status: expected %v, got %v", codes.Error, s.status)
+	}
+
+	expected := map[attribute.Key]string{
+		"db.system.name":     "clickhouse",
+		"db.operation.name":  "SELECT",
+		"db.collection.name": "users",
+		"db.query.text":      "SELECT * FROM users WHERE name = ? AND age > ? LIMIT ?",
+		"service":            "test",
+	}
+	for k, v := range expected {
+		if got := s.attrs[k].AsString(); got != v {
+			t.Errorf("%s: expected %q, got %q", k, v, got)
+		}
+	}
+	if got := s.attrs[PagingPageKey].AsInt64(); got != 2 {
+		t.Errorf("%s: expected 2, got %d", PagingPageKey, got)
+	}
+	if got := s.attrs["db.response.returned_rows"].AsInt64(); got != 3 {
+		t.Errorf("db.response.returned_rows: expected 3, got %d", got)
+	}
+}
+
+func TestTracer_StartAtEndAt(t *testing.T) {
+	provider := &recordingProvider{}
+	tracer := NewTracer("cassandra", WithTracerProvider(provider))
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	end := start.Add(time.Second)
+
+	_, span := tracer.StartAt(context.Background(), start, "SELECT", "users")
+	span.EndAt(nil, end)
+
+	if got := len(provider.spans); got != 1 {
+		t.Fatalf("len(spans): expected 1, got %d", got)
+	}
+	s := provider.spans[0]
+	if got := s.name; got != "SELECT users" {
+		t.Errorf("name: expected %q, got %q", "SELECT users", got)
+	}
+	if !s.start.Equal(start) {
+		t.Errorf("start: expected %v, got %v", start, s.start)
+	}
+	if !s.end.Equal(end) {
+		t.Errorf("end: expected %v, got %v", end, s.end)
+	}
+}
+
+func TestTracer_Nil(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "find", "users")
+	if ctx == nil {
+		t.Fatal("ctx should not be nil")
+	}
+	if span != nil {
+		t.Fatal("span should be nil when tracing is disabled")
+	}
+
+	span.SetStatement("SELECT 1")
+	span.SetAffectedRows(1)
+	span.End(nil)
+}
+
+func TestTracer_WithoutStatement(t *testing.T) {
+	provider := &recordingProvider{}
+	tracer := NewTracer("mongodb", WithTracerProvider(provider), WithoutStatement(), WithDBSystem("mongo"))
+
+	_, span := tracer.Start(context.Background(), "find", "")
+	span.SetJSONStatement([]byte(`{"name":"alice"}`))
+	span.End(nil)
+
+	s := provider.spans[0]
+	if s.name != "find" {
+		t.Errorf("span name: expected %q, got %q", "find", s.name)
+	}
+	if _, ok := s.attrs["db.query.text"]; ok {
+		t.Errorf("db.query.text should not be recorded")
+	}
+	if got := s.attrs["db.system.name"].AsString(); got != "mongo" {
+		t.Errorf("db.system.name: expected %q, got %q", "mongo", got)
+	}
+	if s.status != codes.Unset {
+		t.Errorf("span status: expected %v, got %v", codes.Unset, s.status)
+	}
+}
+
+func TestSanitizeJSON(t *testing.T) {
+	got := SanitizeJSON([]byte(`{"name":"alice","age":{"$gt":30},"tags":["a","b"]}`))
+	expected := `{"age":{"$gt":"?"},"name":"?","tags":["?","?"]}`
+	if got != expected {
+		t.Errorf("SanitizeJSON: expected %s, got %s", expected, got)
+	}
+
+	if got = SanitizeJSON([]byte("not json")); got != "" {
+		t.Errorf("SanitizeJSON(invalid): expected empty, got %s", got)
+	}
+}
+
+func TestParseStatement(t *testing.T) {
+	cases := []struct {
+		stmt       string
+		operation  string
+		collection string
+	}{
+		{"SELECT * FROM users WHERE id = ?", "SELECT", "users"},
+		{"insert into `db`.`events` (a, b) VALUES (?, ?)", "INSERT", "db.events"},
+		{"ALTER TABLE candles DELETE WHERE ts < ?", "ALTER", "candles"},
+		{"UPDATE ks.users SET name = ? WHERE id = ?", "UPDATE", "ks.users"},
+		{"SHOW DATABASES", "SHOW", ""},
+		{"", "", ""},
+	}
+	for _, c := range cases {
+		op, coll := ParseStatement(c.stmt)
+		if op != c.operation || coll != c.collection {
+			t.Errorf("ParseStatement(%q): expected (%q, %q), got (%q, %q)", c.stmt, c.operation, c.collection, op, coll)
+		}
+	}
+}
+
+func TestSliceLen(t *testing.T) {
+	items := []int{1, 2, 3}
+	if got := SliceLen(&items); got != 3 {
+		t.Errorf("SliceLen(&items): expected 3, got %d", got)
+	}
+	if got := SliceLen(items); got != 3 {
+		t.Errorf("SliceLen(items): expected 3, got %d", got)
+	}
+	if got := SliceLen(1); got != 0 {
+		t.Errorf("SliceLen(1): expected 0, got %d", got)
+	}
+}
+
+func TestFilterAttributes(t *testing.T) {
+	attrs := FilterAttributes(&paginationV1.FilterExpr{
+		Type: paginationV1.ExprType_AND,
+		Conditions: []*paginationV1.Condition{
+			{Field: "name", Op: paginationV1.Operator_EQ, Value: ptr("alice")},
+			{Field: "age", Op: paginationV1.Operator_GT, Value: ptr("30")},
+		},
+		Groups: []*paginationV1.FilterExpr{
+			{
+				Type: paginationV1.ExprType_OR,
+				Conditions: []*paginationV1.Condition{
+					{Field: "name", Op: paginationV1.Operator_EQ, Value: ptr("bob")},
+				},
+			},
+		},
+	})
+	if got := len(attrs); got != 2 {
+		t.Fatalf("len(attrs): expected 2, got %d", got)
+	}
+
+	fields := attrs[0].Value.AsStringSlice()
+	if len(fields) != 2 || fields[0] != "age" || fields[1] != "name" {
+		t.Errorf("%s: expected [age name], got %v", FilterFieldsKey, fields)
+	}
+	if got := attrs[1].Value.AsInt64(); got != 3 {
+		t.Errorf("%s: expected 3, got %d", FilterConditionsKey, got)
+	}
+
+	if attrs = FilterAttributes(nil); attrs != nil {
+		t.Errorf("FilterAttributes(nil): expected nil, got %v", attrs)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }